@@ -1,14 +1,27 @@
 package app
 
 import (
+	"bufio"
 	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aphistic/gomol"
 	gomolconsole "github.com/aphistic/gomol-console"
+	"github.com/efritz/glock"
+	"google.golang.org/grpc"
+
+	"github.com/demosdemon/golang-app-framework/credstore"
+	"github.com/demosdemon/golang-app-framework/i18n"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/secrets"
 )
 
 const (
@@ -18,38 +31,234 @@ const (
 
 // App represents a core application instance. Values can be mocked for testing.
 type App struct {
-	Arguments   []string        // Command Line arguments
-	Environment []string        // OS Environment Variables
-	Context     context.Context // Application context
-	Stdin       io.Reader       // fd0 /dev/stdin
-	Stdout      io.Writer       // fd1 /dev/stdout
-	Stderr      io.Writer       // fd2 /dev/stderr
-	ExitHandler func(int)       // handler for calls to os.Exit
+	Name        string             // program name shown in usage text
+	Arguments   []string           // Command Line arguments
+	Environment []string           // OS Environment Variables
+	Context     context.Context    // Application context
+	Stdin       io.Reader          // fd0 /dev/stdin
+	Stdout      io.Writer          // fd1 /dev/stdout
+	Stderr      io.Writer          // fd2 /dev/stderr
+	ExitHandler func(int)          // handler for calls to os.Exit
+	Clock       glock.Clock        // time source for scheduled tasks
+	Version     string             // application version, recorded in State
+	Identity    *Identity          // host and user identity; looked up from the OS where unset
+	Container   *Container         // container the app runs in; detected from the OS where unset
+	Clipboard   Clipboard          // clipboard of the user; the one of the platform, or OSC 52, where unset
+	Browser     func(string) error // opens URLs for OpenURL; the browser of the platform where unset
+
+	CredentialStore credstore.Store // secrets of the user; the keychain, or an encrypted file, where unset
+	OutputBuffer    int             // bytes buffered by Output and ErrOutput until Flush; zero writes through
+	DryRunShort     string          // one letter name of the --dry-run flag, such as "n"; none where unset
+	YesShort        string          // one letter name of the --yes flag, such as "y"; none where unset
 
 	loggerMu sync.Mutex
 	logger   *gomol.Base
 
 	errchMu sync.Mutex
 	errch   chan error
+
+	servicesMu sync.Mutex
+	services   map[interface{}]*service
+
+	listenersMu sync.Mutex
+	listeners   []trackedListener
+	inherited   map[string]int
+
+	upgradeMu sync.Mutex
+
+	serversMu sync.Mutex
+	servers   []namedServer
+
+	deprecationsMu sync.Mutex
+	deprecations   map[string]int
+
+	wrapMu      sync.Mutex
+	serverWraps []func(http.Handler) http.Handler
+	clientWraps []func(http.RoundTripper) http.RoundTripper
+
+	shutdownMu   sync.Mutex
+	shutdownOnce sync.Once
+	shuttingDown chan struct{}
+	stopOnce     sync.Once
+	stopped      chan struct{}
+
+	preflightMu      sync.Mutex
+	preflight        []namedCheck
+	preflightResults []PreflightResult
+
+	metricsMu sync.Mutex
+	metrics   *metrics.Registry
+
+	redMu     sync.Mutex
+	redLabels map[string]map[string]struct{}
+
+	grpcMu      sync.Mutex
+	grpcConns   []*grpc.ClientConn
+	grpcWatches []context.CancelFunc // stop watching the client certificates of grpcConns
+
+	tasks taskSet
+
+	cancel       context.CancelFunc
+	pipeMu       sync.Mutex
+	pipeOnce     sync.Once
+	stdoutClosed chan struct{}
+
+	outputMu       sync.Mutex
+	stdout         *syncWriter
+	stderr         *syncWriter
+	sessionChecked bool
+	sessionFile    *os.File
+	session        *ansiStripper
+
+	summaryMu sync.Mutex
+	summary   *Summary
+
+	started   time.Time
+	reportMu  sync.Mutex
+	reportErr error
+
+	catalogMu sync.Mutex
+	catalog   *i18n.Catalog
+
+	exitMu    sync.Mutex
+	exitHooks []*func(code int)
+	exitCode  int
+
+	stateMu sync.Mutex
+	state   *State
+
+	projectMu   sync.Mutex
+	projectRoot string
+
+	children childSet
+
+	credMu sync.Mutex
+	cred   *credential
+
+	credstoreMu sync.Mutex
+
+	prepareOnce sync.Once
+	prepareErr  error
+
+	identityMu sync.Mutex
+
+	containerMu sync.Mutex
+
+	namespaceMu sync.Mutex
+	namespaces  map[string]*Namespace
+
+	schemaMu   sync.Mutex
+	schema     map[string]ConfigKey
+	configErrs ConfigErrors
+
+	secretsMu  sync.Mutex
+	identities []*secrets.Identity
+	decrypted  map[string]string
+	undecrypt  map[string]error // the errors of decrypting the variables LookupEnv was asked for
+	fileEnv    map[string]string
+	sealedEnv  map[string]bool
+
+	envMu     sync.RWMutex
+	remoteEnv map[string]string
+
+	layersMu sync.Mutex
+	defaults map[string]string
+	flags    map[string]string
+	expand   map[string]bool
+
+	updatesMu sync.Mutex
+	updates   map[*configSub]struct{}
+
+	commandsMu sync.Mutex
+	commands   []*Command
+	inShell    bool
+
+	verbosityMu  sync.Mutex
+	verbosity    Verbosity
+	verbositySet bool
+
+	interactive *bool
+
+	commandCtxMu sync.Mutex
+	commandCtx   context.Context
+
+	stdinMu  sync.Mutex
+	stdin    *bufio.Reader
+	stdinSrc io.Reader
 }
 
 // New returns a new App instance. The values are take directly from the environment. Manually construct
-// an App instance in order to mock these values.
+// an App instance in order to mock these values. The Context is canceled when the reader of Stdout goes away, see
+// Output.
 func New() *App {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &App{
+		Name:        filepath.Base(os.Args[0]),
 		Arguments:   os.Args[1:],
 		Environment: os.Environ(),
-		Context:     context.Background(),
+		Context:     ctx,
 		Stdin:       os.Stdin,
 		Stdout:      os.Stdout,
 		Stderr:      os.Stderr,
 		ExitHandler: os.Exit,
+		Clock:       glock.NewRealClock(),
+		Version:     buildVersion(),
+		cancel:      cancel,
+		started:     time.Now(),
 	}
 }
 
-// Exit calls the app ExitHandler. If no ExitHandler is set, calls os.Exit. This method properly shuts down the app
-// logger if it has been initialized.
+// OnExit registers a function that Exit calls with the exit code before it releases any resource. The functions are
+// called in the reverse order they were registered, each at most once.
+func (a *App) OnExit(fn func(code int)) {
+	a.onExit(fn)
+}
+
+// onExit registers fn as OnExit does, returning the function that unregisters it, for the calls that only need fn while
+// they run.
+func (a *App) onExit(fn func(code int)) (remove func()) {
+	a.exitMu.Lock()
+	defer a.exitMu.Unlock()
+
+	hook := &fn
+	a.exitHooks = append(a.exitHooks, hook)
+	return func() {
+		a.exitMu.Lock()
+		defer a.exitMu.Unlock()
+		for idx, h := range a.exitHooks {
+			if h == hook {
+				a.exitHooks = append(a.exitHooks[:idx:idx], a.exitHooks[idx+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (a *App) runExitHooks(code int) {
+	a.exitMu.Lock()
+	hooks := a.exitHooks
+	a.exitHooks = nil
+	a.exitMu.Unlock()
+
+	for idx := len(hooks) - 1; idx >= 0; idx-- {
+		(*hooks[idx])(code)
+	}
+}
+
+// Exit calls the app ExitHandler. If no ExitHandler is set, calls os.Exit. This method runs the OnExit functions,
+// stops every Child still running, closes any listeners opened with Listen and clients created with GRPCClient,
+// renders the Summary if one was started, and properly shuts down the app logger if it has been initialized. Output
+// buffered by Output and ErrOutput is flushed last, the exit report is written to APP_EXIT_REPORT if set, and the
+// SessionLog closed. The code is replaced with ExitBrokenPipe once a write to Output has found the reader of Stdout
+// gone, and a failure code with ExitTimeout once a command has run out of time or ExitNotConfirmed once a Confirm
+// was refused.
 func (a *App) Exit(code int) {
+	a.runExitHooks(code)
+	a.stopChildren()
+	a.closeListeners()
+	a.closeGRPCClients()
+	a.writeSummary()
+
 	a.loggerMu.Lock()
 	if a.logger != nil {
 		if a.logger.IsInitialized() {
@@ -61,6 +270,16 @@ func (a *App) Exit(code int) {
 	}
 	a.loggerMu.Unlock()
 
+	_ = a.Flush()
+	if code != 0 && a.failureCode() != 0 {
+		code = a.failureCode()
+	}
+	if a.isStdoutClosed() {
+		code = ExitBrokenPipe
+	}
+	a.writeExitReport(code)
+	a.closeSessionLog()
+
 	if a.ExitHandler == nil {
 		os.Exit(code)
 	} else {
@@ -75,20 +294,32 @@ func (a *App) Logger() *gomol.Base {
 	defer a.loggerMu.Unlock()
 
 	if a.logger == nil {
+		// in a container, log messages are read by a log collector rather than a person
+		format := "text"
+		if a.InContainer() {
+			format = "json"
+		}
+		if v := a.trimmedEnv("APP_LOG_FORMAT"); v == "text" || v == "json" {
+			format = v
+		}
+		_, noColor := a.lookupEnv("NO_COLOR")
+
 		consoleConfig := gomolconsole.ConsoleLoggerConfig{
-			Colorize: true,
-			Writer:   a.Stderr,
+			Colorize: format == "text" && !noColor,
+			Writer:   a.ErrOutput(),
 		}
 
 		// err is always nil
 		consoleLogger, _ := gomolconsole.NewConsoleLogger(&consoleConfig)
 
 		template := logTemplate
-		if a.Stderr == os.Stderr {
+		if format == "json" {
+			template = `{{logJSON .}}`
+		} else if a.Stderr == os.Stderr {
 			template = timestamp + template
 		}
-		// err is always nil because the template is not dynamic and I tested it at least once
-		tpl, _ := gomol.NewTemplate(template)
+		// err is always nil because the templates are not dynamic and I tested them at least once
+		tpl, _ := gomol.NewTemplateWithFuncMap(template, map[string]interface{}{"logJSON": logJSON})
 
 		// err is always nil if the template is non-nil
 		_ = consoleLogger.SetTemplate(tpl)
@@ -108,6 +339,13 @@ func (a *App) Logger() *gomol.Base {
 
 		// err is always nil since we're not reusing objects
 		_ = logger.AddLogger(consoleLogger)
+		logger.SetLogLevel(a.Verbosity().logLevel())
+
+		if pod := a.Pod(); pod != nil {
+			for key, v := range pod.Attrs() {
+				logger.SetAttr(key, v)
+			}
+		}
 
 		a.logger = logger
 
@@ -140,15 +378,42 @@ func (a *App) HandleError(err error) {
 }
 
 // LookupEnv searches the app environment variables for the specified key. If the key is found, returns a tuple of the
-// value and true. If not found, returns the zero string and false.
+// value and true. If not found, returns the zero string and false. Flags set with SetFlag take precedence over the
+// environment, and defaults registered with SetDefault or DeclareConfig are returned for variables that are not set.
+// Values are returned literally, except for those of the variables passed to ExpandVars, which are expanded as by
+// ExpandEnv; a variable that fails to expand, or whose encrypted value fails to decrypt, is reported by ConfigErrors
+// and is not found.
 func (a *App) LookupEnv(key string) (string, bool) {
+	a.layersMu.Lock()
+	expand := a.expand[key]
+	a.layersMu.Unlock()
+
+	if expand {
+		v, ok, err := a.ExpandEnv(key)
+		if err != nil {
+			return "", false
+		}
+		return v, ok
+	}
+
+	v, ok, err := a.lookupDecrypted(key)
+	if err != nil {
+		a.recordDecryptError(key, err)
+		return "", false
+	}
+	return v, ok
+}
+
+// lookupEnv returns the value of the environment variable key without expanding it.
+func (a *App) lookupEnv(key string) (string, bool) {
 	ch := make(chan string)
+	env := a.environ()
 
 	wg := sync.WaitGroup{}
-	wg.Add(len(a.Environment))
+	wg.Add(len(env))
 
 	go func() {
-		for _, line := range a.Environment {
+		for _, line := range env {
 			line := line
 			go func() {
 				defer wg.Done()
@@ -173,3 +438,30 @@ func (a *App) LookupEnv(key string) (string, bool) {
 	v, ok := <-ch
 	return v, ok
 }
+
+func (a *App) lookupBool(key string) (bool, error) {
+	v, ok := a.LookupEnv(key)
+	if !ok || v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return b, nil
+}
+
+// lookupDuration returns the duration in the environment variable key, or def if it is not set.
+func (a *App) lookupDuration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := a.LookupEnv(key)
+	if !ok || v == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return d, nil
+}
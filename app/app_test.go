@@ -21,6 +21,7 @@ func newApp(environ []string, args ...string) *app.App {
 		Arguments:   args,
 		Environment: environ,
 		Context:     context.Background(),
+		Container:   &app.Container{},
 		Stdin:       new(bytes.Buffer),
 		Stdout:      new(bytes.Buffer),
 		Stderr:      new(bytes.Buffer),
@@ -204,3 +205,19 @@ func BenchmarkApp_LookupEnv(b *testing.B) {
 	assert.True(b, ok)
 	assert.Equal(b, "/run/test", v)
 }
+
+func TestApp_OnExit(t *testing.T) {
+	a := newApp(nil)
+
+	var calls []string
+	a.OnExit(func(code int) { calls = append(calls, fmt.Sprintf("first %d", code)) })
+	a.OnExit(func(code int) { calls = append(calls, fmt.Sprintf("second %d", code)) })
+
+	assert.PanicsWithValue(t, "system exit 4", func() {
+		a.Exit(4)
+	})
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+	assert.Equal(t, []string{"second 4", "first 4"}, calls)
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Arg describes a positional argument declared by a struct field, as shown in usage text.
+type Arg struct {
+	Name     string `json:"name"`               // the name shown in usage text and errors
+	Usage    string `json:"usage,omitempty"`    // what the argument is
+	Type     string `json:"type"`               // the kind of value, such as string, int, or duration
+	Optional bool   `json:"optional,omitempty"` // the argument may be left out
+	Variadic bool   `json:"variadic,omitempty"` // the argument takes every remaining value
+	Default  string `json:"default,omitempty"`  // the value of an optional argument left out
+
+	field []int
+	path  string
+}
+
+// String returns the argument as shown in usage text: <name> or [name], followed by ... if it is variadic.
+func (arg *Arg) String() string {
+	switch {
+	case arg.Optional && arg.Variadic:
+		return "[" + arg.Name + "...]"
+	case arg.Optional:
+		return "[" + arg.Name + "]"
+	case arg.Variadic:
+		return "<" + arg.Name + ">..."
+	default:
+		return "<" + arg.Name + ">"
+	}
+}
+
+// newArg returns the positional argument declared by field with the arg tag, which holds the name and the usage
+// text. The name is in brackets, as [name], for an optional argument; a slice takes every remaining value:
+//
+//	type copyOptions struct {
+//		Sources []string `arg:"src,the files to copy"`
+//		Dest    string   `arg:"[dest],the directory to copy to" default:"."`
+//	}
+func newArg(field reflect.StructField, tag string, index []int, path string) *Arg {
+	name, usage, _ := strings.Cut(tag, ",")
+	arg := &Arg{
+		Name:    strings.TrimSpace(name),
+		Usage:   strings.TrimSpace(usage),
+		Type:    flagType(field.Type),
+		Default: field.Tag.Get("default"),
+		field:   index,
+		path:    path,
+	}
+	if inner, ok := strings.CutPrefix(arg.Name, "["); ok {
+		arg.Name, arg.Optional = strings.TrimSuffix(inner, "]"), true
+	}
+	if arg.Name == "" || strings.ContainsAny(arg.Name, "[]<> ") {
+		panic(fmt.Sprintf("app: invalid argument name %q of %s", name, path))
+	}
+	if !field.IsExported() {
+		panic(fmt.Sprintf("app: argument field %s is not exported", path))
+	}
+	if arg.Type == "" {
+		panic(fmt.Sprintf("app: argument %s has unsupported type %s", arg.Name, field.Type))
+	}
+	if elem, ok := strings.CutSuffix(arg.Type, "s"); ok {
+		arg.Type, arg.Variadic = elem, true
+	}
+
+	if arg.Default != "" {
+		if !arg.Optional {
+			panic(fmt.Sprintf("app: required argument %s has a default", arg.Name))
+		}
+		if err := setOptionValue(reflect.New(field.Type).Elem(), arg.Default, true); err != nil {
+			panic(fmt.Sprintf("app: invalid default %q of argument %s", arg.Default, arg.Name))
+		}
+	}
+	return arg
+}
+
+// bindArgs checks that the number of positional arguments in rest suits args and sets them in the struct v. Nothing
+// is checked when no argument is declared.
+func bindArgs(args []*Arg, v reflect.Value, rest []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	least, most := 0, len(args)
+	for _, arg := range args {
+		if !arg.Optional {
+			least++
+		}
+		if arg.Variadic {
+			most = -1
+		}
+	}
+	switch n := len(rest); {
+	case least == most && n != least:
+		return fmt.Errorf("expected %s, got %d", countArgs(least), n)
+	case n < least:
+		return fmt.Errorf("expected at least %s, got %d", countArgs(least), n)
+	case most >= 0 && n > most:
+		return fmt.Errorf("expected at most %s, got %d", countArgs(most), n)
+	}
+
+	for idx, arg := range args {
+		field := v.FieldByIndex(arg.field)
+		values := rest[min(idx, len(rest)):]
+		if !arg.Variadic && len(values) > 1 {
+			values = values[:1]
+		}
+		if len(values) == 0 {
+			if arg.Default != "" {
+				_ = setOptionValue(field, arg.Default, true)
+			}
+			continue
+		}
+		for _, value := range values {
+			if err := setOptionValue(field, value, false); err != nil {
+				return fmt.Errorf("invalid %s %q", arg.Name, value)
+			}
+		}
+	}
+	return nil
+}
+
+func countArgs(n int) string {
+	if n == 1 {
+		return "1 arg"
+	}
+	return fmt.Sprintf("%d args", n)
+}
@@ -0,0 +1,100 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type copyOptions struct {
+	Force   bool     `flag:"force,f,overwrite existing files" env:"-"`
+	Sources []string `arg:"src,the files to copy"`
+}
+
+type moveOptions struct {
+	Source string        `arg:"src,the file to move"`
+	Dest   string        `arg:"[dest],where to move it" default:"."`
+	Wait   time.Duration `arg:"[wait],how long to wait" validate:"max=1m"`
+}
+
+func TestParseFlags_args(t *testing.T) {
+	opts, rest, err := app.ParseFlags[copyOptions](newApp(nil), []string{"a", "-f", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, &copyOptions{Force: true, Sources: []string{"a", "b"}}, opts)
+	assert.Equal(t, []string{"a", "b"}, rest)
+
+	move, _, err := app.ParseFlags[moveOptions](newApp(nil), []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, &moveOptions{Source: "a", Dest: "."}, move)
+	move, _, err = app.ParseFlags[moveOptions](newApp(nil), []string{"a", "b", "5s"})
+	require.NoError(t, err)
+	assert.Equal(t, &moveOptions{Source: "a", Dest: "b", Wait: 5 * time.Second}, move)
+
+	for expected, args := range map[string][]string{
+		"expected at least 1 arg, got 0":                  nil,
+		"expected at most 3 args, got 4":                  {"a", "b", "1s", "d"},
+		`invalid wait "soon"`:                             {"a", "b", "soon"},
+		"invalid configuration: wait: must be at most 1m": {"a", "b", "1h"},
+	} {
+		if args == nil {
+			_, _, err = app.ParseFlags[copyOptions](newApp(nil), args)
+		} else {
+			_, _, err = app.ParseFlags[moveOptions](newApp(nil), args)
+		}
+		assert.EqualError(t, err, expected)
+	}
+
+	_, _, err = app.ParseFlags[struct {
+		From string `arg:"from,"`
+		To   string `arg:"to,"`
+	}](newApp(nil), []string{"a"})
+	assert.EqualError(t, err, "expected 2 args, got 1")
+
+	assert.PanicsWithValue(t, "app: variadic argument src is not the last", func() {
+		_, _, _ = app.ParseFlags[struct {
+			Sources []string `arg:"src,"`
+			Dest    string   `arg:"dest,"`
+		}](newApp(nil), nil)
+	})
+	assert.PanicsWithValue(t, "app: required argument dest follows an optional one", func() {
+		_, _, _ = app.ParseFlags[struct {
+			Source string `arg:"[src],"`
+			Dest   string `arg:"dest,"`
+		}](newApp(nil), nil)
+	})
+}
+
+func TestApp_Dispatch_args(t *testing.T) {
+	a := newApp(nil, "help", "mv")
+	a.Name = "prog"
+	cmd := app.NewCommand("mv", "move a file", func(*app.App, *moveOptions, []string) error { return nil })
+	a.AddCommand(cmd)
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, `Usage: prog mv [flags] <src> [dest] [wait]
+
+move a file
+
+Arguments:
+  src   the file to move
+  dest  where to move it (default .)
+  wait  how long to wait
+
+Flags:
+  -h, --help              show this help
+  -q, --quiet             print only warnings, errors, and results
+  -v, --verbose           print more, or everything with -vv or --debug
+      --timeout duration  stop the command after the duration (env APP_TIMEOUT)
+      --dry-run           show the changes without making them (env APP_DRY_RUN)
+      --yes               confirm dangerous changes without asking (env APP_YES)
+`, a.Stdout.(*bytes.Buffer).String())
+
+	args := cmd.Args()
+	require.Len(t, args, 3)
+	assert.Equal(t, "duration", args[2].Type)
+	assert.True(t, args[2].Optional)
+}
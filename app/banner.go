@@ -0,0 +1,166 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/i18n"
+)
+
+// Banner is the summary of the app Run prints on a terminal, or logs, once its servers are bound and the pre-flight
+// checks pass.
+type Banner struct {
+	Name     string            `json:"name"`
+	Version  string            `json:"version,omitempty"`
+	Commit   string            `json:"commit,omitempty"` // the VCS revision the app was built from
+	Listen   map[string]string `json:"listen,omitempty"` // the addresses of the servers, by name
+	LogLevel string            `json:"log_level"`        // debug, info, or warning
+	Modules  []string          `json:"modules"`          // the registered servers, in order
+	Config   map[string]string `json:"config,omitempty"` // the framework variables set, but the secret ones
+}
+
+// Banner returns the startup Banner of the app. The addresses are those of the servers with a ListenerAddr method,
+// such as HTTPServer and GRPCServer, once bound.
+func (a *App) Banner() Banner {
+	a.serversMu.Lock()
+	servers := append([]namedServer(nil), a.servers...)
+	a.serversMu.Unlock()
+
+	b := Banner{
+		Name:     a.Name,
+		Version:  a.Version,
+		Commit:   buildCommit(),
+		Listen:   map[string]string{},
+		LogLevel: levelName(a.Verbosity().logLevel()),
+		Modules:  []string{},
+		Config:   map[string]string{},
+	}
+	if b.Name == "" {
+		b.Name = executableName()
+	}
+
+	for _, s := range servers {
+		b.Modules = append(b.Modules, s.name)
+		if l, ok := s.server.(interface{ ListenerAddr() net.Addr }); ok {
+			if addr := l.ListenerAddr(); addr != nil {
+				b.Listen[s.name] = addr.String()
+			}
+		}
+	}
+
+	for _, v := range a.EffectiveConfig() {
+		if v.Module == "app" && v.Source != SourceDefault && v.Value != Redacted {
+			b.Config[v.Name] = v.Value
+		}
+	}
+	return b
+}
+
+// writeBanner prints the Banner on ErrOutput as a table when it is a terminal, unless APP_BANNER is false, the
+// Verbosity is quiet, or APP_OUTPUT_FORMAT is json. Otherwise the Banner is logged, once, as the startup record.
+func (a *App) writeBanner() {
+	b := a.Banner()
+
+	show := true
+	if v, ok := a.LookupEnv("APP_BANNER"); ok {
+		if parsed, err := strconv.ParseBool(v); err != nil {
+			_ = a.Logger().Warnf("invalid APP_BANNER %q, using true", v)
+		} else {
+			show = parsed
+		}
+	}
+	if show && a.Verbosity() > VerbosityQuiet && a.outputFormat() != "json" && isTerminal(a.Stderr) {
+		writeBannerTable(a.ErrOutput(), a.Localizer(), b)
+		return
+	}
+
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(map[string]interface{}{
+		"name":      b.Name,
+		"version":   b.Version,
+		"commit":    b.Commit,
+		"listen":    b.Listen,
+		"log_level": b.LogLevel,
+		"modules":   b.Modules,
+		"config":    b.Config,
+	}), "startup")
+}
+
+func writeBannerTable(w io.Writer, l *i18n.Localizer, b Banner) {
+	title := b.Name
+	if b.Version != "" {
+		title += " " + b.Version
+	}
+	if b.Commit != "" {
+		title += " (" + b.Commit + ")"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, l.T("app.banner", title))
+	for _, name := range b.Modules {
+		if addr, ok := b.Listen[name]; ok {
+			_, _ = fmt.Fprintf(tw, "  %s\t%s\n", name, addr)
+		}
+	}
+	_, _ = fmt.Fprintf(tw, "  %s\t%s\n", l.T("app.banner.log_level"), b.LogLevel)
+	_, _ = fmt.Fprintf(tw, "  %s\t%s\n", l.T("app.banner.modules"), strings.Join(b.Modules, ", "))
+	names := make([]string, 0, len(b.Config))
+	for name := range b.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", name, b.Config[name])
+	}
+	_ = tw.Flush()
+}
+
+// buildCommit returns the VCS revision the app was built from, shortened, and suffixed with -dirty if there were
+// uncommitted changes, or the empty string.
+func buildCommit() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// levelName returns the name of a log level.
+func levelName(level gomol.LogLevel) string {
+	switch level {
+	case gomol.LevelDebug:
+		return "debug"
+	case gomol.LevelInfo:
+		return "info"
+	case gomol.LevelWarning:
+		return "warning"
+	case gomol.LevelError:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
@@ -0,0 +1,75 @@
+package app_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Banner(t *testing.T) {
+	r := new(recorder)
+	a := newApp([]string{"APP_ENV=production", "APP_AGE_KEY=AGE-SECRET-KEY-1", "APP_DB_URL=postgres://db"})
+	a.Name, a.Version = "myapp", "v1.2.3"
+	a.DeclareConfig("db", app.ConfigKey{Name: "APP_DB_URL"})
+	web := app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+	a.Register("web", web)
+	a.Register("worker", newFakeServer("worker", r))
+	a.SetVerbosity(app.VerbosityVerbose)
+
+	go a.HandleError(nil)
+	require.NoError(t, a.Run())
+
+	_ = a.Logger().ShutdownLoggers()
+	logs := a.Stderr.(*bytes.Buffer).String()
+	// not a terminal, so the banner is only logged, once
+	assert.Equal(t, 1, strings.Count(logs, "] startup {"), logs)
+	assert.NotContains(t, logs, "\n{")
+	for _, attr := range []string{
+		`"name":"myapp"`,
+		`"version":"v1.2.3"`,
+		`"listen":{"web":"` + web.ListenerAddr().String() + `"}`,
+		`"log_level":"debug"`,
+		`"modules":["web","worker"]`,
+		`"config":{"APP_ENV":"production"}`,
+	} {
+		assert.Contains(t, logs, attr)
+	}
+	assert.NotContains(t, logs, "AGE-SECRET-KEY")
+}
+
+func TestApp_Banner_Disabled(t *testing.T) {
+	a := newApp([]string{"APP_BANNER=false"})
+	go a.HandleError(nil)
+	require.NoError(t, a.Run())
+
+	_ = a.Logger().ShutdownLoggers()
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, logs, "] startup {")
+	assert.NotContains(t, logs, "\n{")
+}
+
+func TestWriteBannerTable(t *testing.T) {
+	buf := new(bytes.Buffer)
+	app.WriteBannerTable(newApp(nil), buf, app.Banner{
+		Name:     "myapp",
+		Version:  "v1.2.3",
+		Commit:   "0123456789ab-dirty",
+		Listen:   map[string]string{"web": "127.0.0.1:8080"},
+		LogLevel: "info",
+		Modules:  []string{"web", "worker"},
+		Config:   map[string]string{"APP_LOG_FORMAT": "json", "APP_ENV": "production"},
+	})
+	assert.Equal(t, `myapp v1.2.3 (0123456789ab-dirty) started
+  web             127.0.0.1:8080
+  log level       info
+  modules         web, worker
+  APP_ENV         production
+  APP_LOG_FORMAT  json
+`, buf.String())
+}
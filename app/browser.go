@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+
+	"github.com/aphistic/gomol"
+)
+
+// OpenURL opens rawURL, an http or https URL such as the verification page of an OAuth device flow, in the browser
+// of the user. When the app runs without a display, as in an SSH session, or the browser cannot be started, the URL
+// is printed on ErrOutput for the user to open instead. Set App.Browser to mock the browser.
+func (a *App) OpenURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"url": u.Redacted()})
+	if a.Browser == nil && (a.SSHSession() || !a.hasDisplay()) {
+		_ = a.Logger().Debugm(attrs, "no display to open a browser on")
+		return a.printURL(rawURL)
+	}
+
+	open := a.Browser
+	if open == nil {
+		open = startBrowser
+	}
+	if err := open(rawURL); err != nil {
+		_ = a.Logger().Debugm(attrs, "unable to open a browser: %v", err)
+		return a.printURL(rawURL)
+	}
+	_ = a.Logger().Debugm(attrs, "opened a browser")
+	return nil
+}
+
+func (a *App) printURL(rawURL string) error {
+	_, err := fmt.Fprintf(a.ErrOutput(), "Open this URL in your browser:\n\n  %s\n\n", rawURL)
+	return err
+}
+
+// startBrowser starts the browser command of the platform on rawURL without waiting for it to exit, as some only do
+// once the browser is closed.
+func startBrowser(rawURL string) error {
+	args := browserCommand(rawURL)
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
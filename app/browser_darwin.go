@@ -0,0 +1,9 @@
+package app
+
+func browserCommand(url string) []string {
+	return []string{"open", url}
+}
+
+func (a *App) hasDisplay() bool {
+	return true
+}
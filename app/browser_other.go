@@ -0,0 +1,18 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package app
+
+func browserCommand(url string) []string {
+	return []string{"xdg-open", url}
+}
+
+// hasDisplay reports whether an X or Wayland display is available to open a browser on.
+func (a *App) hasDisplay() bool {
+	for _, key := range [...]string{"DISPLAY", "WAYLAND_DISPLAY"} {
+		if v, _ := a.LookupEnv(key); v != "" {
+			return true
+		}
+	}
+	return false
+}
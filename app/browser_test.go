@@ -0,0 +1,38 @@
+package app_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_OpenURL(t *testing.T) {
+	const link = "https://example.com/device?code=ABCD"
+	printed := "Open this URL in your browser:\n\n  " + link + "\n\n"
+
+	a := newApp(nil)
+	var opened []string
+	a.Browser = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	require.NoError(t, a.OpenURL(link))
+	assert.Equal(t, []string{link}, opened)
+	assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+
+	a = newApp(nil)
+	a.Browser = func(string) error { return errors.New("no browser") }
+	require.NoError(t, a.OpenURL(link))
+	assert.Equal(t, printed, a.Stderr.(*bytes.Buffer).String())
+
+	a = newApp([]string{"SSH_TTY=/dev/pts/1", "DISPLAY=:0"})
+	require.NoError(t, a.OpenURL(link))
+	assert.Equal(t, printed, a.Stderr.(*bytes.Buffer).String())
+
+	for _, url := range []string{"example.com", "file:///etc/passwd", "https://", ":"} {
+		assert.EqualError(t, newApp(nil).OpenURL(url), `invalid URL "`+url+`"`)
+	}
+}
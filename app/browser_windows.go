@@ -0,0 +1,9 @@
+package app
+
+func browserCommand(url string) []string {
+	return []string{"rundll32.exe", "url.dll,FileProtocolHandler", url}
+}
+
+func (a *App) hasDisplay() bool {
+	return true
+}
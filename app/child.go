@@ -0,0 +1,174 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// DefaultChildStopTimeout is how long Exit waits for child processes to stop when APP_CHILD_STOP_TIMEOUT is not set.
+const DefaultChildStopTimeout = 5 * time.Second
+
+var terminateSignal os.Signal = syscall.SIGTERM
+
+// Child is a process started by the app. While any Child runs, the signals the app receives are forwarded to it, and
+// Exit stops every Child still running so that none outlives the app.
+type Child struct {
+	Cmd        *exec.Cmd // the command; set its fields before calling Start
+	StopSignal os.Signal // sent instead of SIGTERM, including by Exit; SIGTERM if nil
+
+	app  *App
+	done chan struct{}
+	err  error
+}
+
+type childSet struct {
+	mu      sync.Mutex
+	running map[*Child]struct{}
+	sigch   chan os.Signal
+	closed  bool
+}
+
+// Command returns a Child running the program with the app Environment, Stdin, Stdout, and Stderr.
+func (a *App) Command(name string, args ...string) *Child {
+	cmd := exec.Command(name, args...)
+	cmd.Env = a.environ()
+	cmd.Stdin = a.Stdin
+	cmd.Stdout = a.Stdout
+	cmd.Stderr = a.Stderr
+	return &Child{Cmd: cmd, app: a, done: make(chan struct{})}
+}
+
+// Start starts the Child in its own process group, so that it and the processes it starts are signaled and stopped
+// together; it is therefore not in the foreground of a terminal. The Child is waited for in the background, so it
+// never lingers as a zombie; use Wait or Done to learn when it exits.
+func (c *Child) Start() error {
+	s := &c.app.children
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("app is exiting")
+	}
+
+	setProcessGroup(c.Cmd)
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+
+	if s.running == nil {
+		s.running = make(map[*Child]struct{})
+	}
+	s.running[c] = struct{}{}
+	if s.sigch == nil && len(forwardedSignals) > 0 {
+		s.sigch = make(chan os.Signal, len(forwardedSignals))
+		signal.Notify(s.sigch, forwardedSignals...)
+		go c.app.forwardSignals(s.sigch)
+	}
+
+	go func() {
+		c.err = c.Cmd.Wait()
+		c.app.childExited(c)
+		close(c.done)
+	}()
+	return nil
+}
+
+// Done returns a channel that is closed once the started Child has exited.
+func (c *Child) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait waits for the started Child to exit and returns its error, as exec.Cmd.Wait does.
+func (c *Child) Wait() error {
+	<-c.done
+	return c.err
+}
+
+// Signal sends the signal to the process group of the Child. SIGTERM is replaced with the StopSignal.
+func (c *Child) Signal(sig os.Signal) error {
+	if sig == terminateSignal && c.StopSignal != nil {
+		sig = c.StopSignal
+	}
+	return signalGroup(c.Cmd.Process, sig)
+}
+
+func (a *App) forwardSignals(ch <-chan os.Signal) {
+	for sig := range ch {
+		a.children.mu.Lock()
+		for c := range a.children.running {
+			_ = c.Signal(sig)
+		}
+		a.children.mu.Unlock()
+	}
+}
+
+func (a *App) childExited(c *Child) {
+	s := &a.children
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, c)
+	if len(s.running) == 0 && s.sigch != nil {
+		// the app gets its default signal handling back
+		signal.Stop(s.sigch)
+		close(s.sigch)
+		s.sigch = nil
+	}
+}
+
+// stopChildren sends every running Child its StopSignal, kills those still running after APP_CHILD_STOP_TIMEOUT,
+// and kills what remains of their process groups.
+func (a *App) stopChildren() {
+	s := &a.children
+	s.mu.Lock()
+	s.closed = true
+	children := make([]*Child, 0, len(s.running))
+	for c := range s.running {
+		children = append(children, c)
+	}
+	s.mu.Unlock()
+
+	if len(children) == 0 {
+		return
+	}
+
+	timeout, err := a.lookupDuration("APP_CHILD_STOP_TIMEOUT", DefaultChildStopTimeout)
+	if err != nil {
+		_ = a.Logger().Warnf("%v, using %s", err, DefaultChildStopTimeout)
+		timeout = DefaultChildStopTimeout
+	}
+
+	for _, c := range children {
+		_ = c.Signal(terminateSignal)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+wait:
+	for _, c := range children {
+		select {
+		case <-c.done:
+		case <-deadline.C:
+			break wait
+		}
+	}
+
+	for _, c := range children {
+		select {
+		case <-c.done:
+		default:
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"pid": c.Cmd.Process.Pid})
+			_ = a.Logger().Warnm(attrs, "child still running after %s, killing it", timeout)
+		}
+		_ = killGroup(c.Cmd.Process)
+		<-c.done
+	}
+}
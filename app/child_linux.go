@@ -0,0 +1,8 @@
+package app
+
+import "syscall"
+
+// setDeathSignal has the kernel kill the child if the app dies without stopping it.
+func setDeathSignal(attr *syscall.SysProcAttr) {
+	attr.Pdeathsig = syscall.SIGKILL
+}
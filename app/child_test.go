@@ -0,0 +1,80 @@
+//go:build !windows
+// +build !windows
+
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// startChild starts the shell script as a Child writing to a file, and waits for it to print ready.
+func startChild(t *testing.T, a *app.App, script string, stop os.Signal) (*app.Child, func() string) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	output := func() string {
+		b, _ := os.ReadFile(f.Name())
+		return string(b)
+	}
+
+	c := a.Command("sh", "-c", script)
+	c.Cmd.Stdin = nil
+	c.Cmd.Stdout, c.Cmd.Stderr = f, f
+	c.StopSignal = stop
+	require.NoError(t, c.Start())
+	require.Eventually(t, func() bool {
+		return strings.Contains(output(), "ready\n")
+	}, 5*time.Second, 10*time.Millisecond)
+	return c, output
+}
+
+func TestChild_ForwardSignals(t *testing.T) {
+	a := newApp(nil)
+	c, output := startChild(t, a, `
+		trap 'echo got USR1' USR1
+		trap 'echo got INT; exit 0' INT
+		echo ready
+		while :; do sleep 0.05; done
+	`, syscall.SIGINT)
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(output(), "got USR1\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// SIGTERM reaches the child as its StopSignal
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+	assert.NoError(t, c.Wait())
+	assert.Contains(t, output(), "got INT\n")
+}
+
+func TestApp_Exit_StopsChildren(t *testing.T) {
+	a := newApp([]string{"APP_CHILD_STOP_TIMEOUT=200ms"})
+	stubborn, _ := startChild(t, a, `trap '' TERM; echo ready; while :; do sleep 0.05; done`, nil)
+	polite, _ := startChild(t, a, `trap 'exit 3' TERM; echo ready; while :; do sleep 0.05; done`, nil)
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	select {
+	case <-stubborn.Done():
+	default:
+		t.Fatal("child still running after Exit")
+	}
+	assert.EqualError(t, stubborn.Wait(), "signal: killed")
+	assert.EqualError(t, polite.Wait(), "exit status 3")
+
+	assert.EqualError(t, a.Command("true").Start(), "app is exiting")
+}
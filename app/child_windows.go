@@ -0,0 +1,23 @@
+package app
+
+import (
+	"os"
+	"os/exec"
+)
+
+// forwardedSignals is empty, Windows cannot send signals to other processes.
+var forwardedSignals []os.Signal
+
+func setProcessGroup(*exec.Cmd) {}
+
+// signalGroup kills the process for SIGTERM, the only way to stop it.
+func signalGroup(p *os.Process, sig os.Signal) error {
+	if sig == terminateSignal || sig == os.Kill {
+		return p.Kill()
+	}
+	return p.Signal(sig)
+}
+
+func killGroup(p *os.Process) error {
+	return p.Kill()
+}
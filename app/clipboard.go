@@ -0,0 +1,160 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/aphistic/gomol"
+)
+
+// Clipboard copies text to a clipboard and pastes it back. Set App.Clipboard to a MemoryClipboard to mock it.
+type Clipboard interface {
+	Copy(text string) error
+	Paste() (string, error)
+}
+
+// MemoryClipboard is a Clipboard that keeps the text in memory, for tests.
+type MemoryClipboard struct {
+	mu   sync.Mutex
+	text string
+}
+
+// Copy keeps text.
+func (c *MemoryClipboard) Copy(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.text = text
+	return nil
+}
+
+// Paste returns the text kept by the last Copy.
+func (c *MemoryClipboard) Paste() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.text, nil
+}
+
+// CopyToClipboard copies text, such as a generated token, to the clipboard of the user, so that it need not be
+// printed. The clipboard is App.Clipboard if set. Otherwise, in an SSH session, the text is sent to the terminal of
+// the user with an OSC 52 escape sequence, which most terminals copy to their clipboard. Elsewhere the clipboard
+// command of the platform is used: pbcopy, clip, wl-copy, xclip, or xsel, falling back to OSC 52 when there is none.
+//
+// The text is never logged.
+func (a *App) CopyToClipboard(text string) error {
+	clipboard, err := a.clipboard()
+	if err == nil {
+		err = clipboard.Copy(text)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to copy to the clipboard: %w", err)
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"bytes": len(text)})
+	_ = a.Logger().Debugm(attrs, "copied to the clipboard")
+	return nil
+}
+
+// PasteFromClipboard returns the text on the clipboard of the user, found as by CopyToClipboard. Pasting is not
+// possible with OSC 52, so it fails in SSH sessions unless App.Clipboard is set.
+func (a *App) PasteFromClipboard() (string, error) {
+	clipboard, err := a.clipboard()
+	if err != nil {
+		return "", fmt.Errorf("unable to paste from the clipboard: %w", err)
+	}
+	text, err := clipboard.Paste()
+	if err != nil {
+		return "", fmt.Errorf("unable to paste from the clipboard: %w", err)
+	}
+	return text, nil
+}
+
+func (a *App) clipboard() (Clipboard, error) {
+	if a.Clipboard != nil {
+		return a.Clipboard, nil
+	}
+	if a.SSHSession() {
+		return &osc52Clipboard{a: a}, nil
+	}
+
+	for _, c := range a.clipboardCommands() {
+		if _, err := exec.LookPath(c.copy[0]); err == nil {
+			return c, nil
+		}
+	}
+	if isTerminal(a.Stderr) {
+		return &osc52Clipboard{a: a}, nil
+	}
+	return nil, errors.New("no clipboard command found, install xclip, xsel, or wl-clipboard")
+}
+
+// SSHSession reports whether the app runs in an SSH session, as told by the variables sshd sets.
+func (a *App) SSHSession() bool {
+	for _, key := range [...]string{"SSH_TTY", "SSH_CONNECTION", "SSH_CLIENT"} {
+		if v, _ := a.LookupEnv(key); v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// commandClipboard copies with the command copy, which reads the text on its stdin, and pastes with paste, which
+// writes it on its stdout.
+type commandClipboard struct {
+	copy, paste []string
+}
+
+func (c *commandClipboard) Copy(text string) error {
+	cmd := exec.Command(c.copy[0], c.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return commandError(c.copy[0], err, stderr.String())
+	}
+	return nil
+}
+
+func (c *commandClipboard) Paste() (string, error) {
+	cmd := exec.Command(c.paste[0], c.paste[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", commandError(c.paste[0], err, stderr.String())
+	}
+	return string(out), nil
+}
+
+func commandError(name string, err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("%s: %w: %s", name, err, stderr)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// osc52Clipboard asks the terminal on ErrOutput to copy the text, which works across SSH. Inside tmux the sequence is
+// passed through to the terminal running tmux.
+type osc52Clipboard struct {
+	a *App
+}
+
+func (c *osc52Clipboard) Copy(text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if v, _ := c.a.LookupEnv("TMUX"); v != "" {
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	if _, err := io.WriteString(c.a.ErrOutput(), seq); err != nil {
+		return err
+	}
+	return c.a.Flush()
+}
+
+func (c *osc52Clipboard) Paste() (string, error) {
+	return "", errors.New("pasting is not supported over OSC 52")
+}
@@ -0,0 +1,5 @@
+package app
+
+func (a *App) clipboardCommands() []*commandClipboard {
+	return []*commandClipboard{{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}}
+}
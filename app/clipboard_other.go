@@ -0,0 +1,22 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package app
+
+// clipboardCommands returns the clipboard commands of Wayland, when it runs, then of X.
+func (a *App) clipboardCommands() []*commandClipboard {
+	var commands []*commandClipboard
+	if v, _ := a.LookupEnv("WAYLAND_DISPLAY"); v != "" {
+		commands = append(commands, &commandClipboard{copy: []string{"wl-copy"}, paste: []string{"wl-paste", "-n"}})
+	}
+	return append(commands,
+		&commandClipboard{
+			copy:  []string{"xclip", "-selection", "clipboard"},
+			paste: []string{"xclip", "-selection", "clipboard", "-o"},
+		},
+		&commandClipboard{
+			copy:  []string{"xsel", "--clipboard", "--input"},
+			paste: []string{"xsel", "--clipboard", "--output"},
+		},
+	)
+}
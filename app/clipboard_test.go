@@ -0,0 +1,32 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_CopyToClipboard(t *testing.T) {
+	a := newApp(nil)
+	a.Clipboard = new(app.MemoryClipboard)
+	require.NoError(t, a.CopyToClipboard("s3cret"))
+	text, err := a.PasteFromClipboard()
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", text)
+	a.Logger().ShutdownLoggers()
+	assert.NotContains(t, a.Stderr.(*bytes.Buffer).String(), "s3cret")
+
+	a = newApp([]string{"SSH_TTY=/dev/pts/1"})
+	require.NoError(t, a.CopyToClipboard("s3cret"))
+	assert.Equal(t, "\x1b]52;c;czNjcmV0\a", a.Stderr.(*bytes.Buffer).String())
+	_, err = a.PasteFromClipboard()
+	assert.EqualError(t, err, "unable to paste from the clipboard: pasting is not supported over OSC 52")
+
+	a = newApp([]string{"SSH_CONNECTION=10.0.0.1 22 10.0.0.2 22", "TMUX=/tmp/tmux-0/default,1,0"})
+	require.NoError(t, a.CopyToClipboard("s3cret"))
+	assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;czNjcmV0\a\x1b\\", a.Stderr.(*bytes.Buffer).String())
+}
@@ -0,0 +1,8 @@
+package app
+
+func (a *App) clipboardCommands() []*commandClipboard {
+	return []*commandClipboard{{
+		copy:  []string{"clip.exe"},
+		paste: []string{"powershell.exe", "-NoProfile", "-Command", "[Console]::Out.Write((Get-Clipboard -Raw))"},
+	}}
+}
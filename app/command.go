@@ -0,0 +1,466 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Command is a subcommand of the app, run by Dispatch. Create one with NewCommand.
+type Command struct {
+	Name    string // the word that selects the command
+	Summary string // a line on what the command does
+
+	Hidden       bool   // the command is left out of usage text and completions
+	Deprecated   string // the advice given when the command is run, such as "use serve instead"
+	Experimental bool   // the command is refused unless APP_EXPERIMENTAL is set
+
+	opts *options
+	run  func(a *App, args []string) error
+}
+
+// NewCommand returns the command name, which parses its flags into a new T, as ParseFlags, then calls run with the
+// options and the arguments that are not flags:
+//
+//	type serveOptions struct {
+//		Addr    string `flag:"addr,a,the address to listen on" default:":8080"`
+//		Workers int    `flag:"workers,w,the number of workers" default:"4" validate:"min=1"`
+//	}
+//
+//	a.AddCommand(app.NewCommand("serve", "run the server", func(a *app.App, opts *serveOptions, args []string) error {
+//		return serve(a, opts.Addr, opts.Workers)
+//	}))
+//	return a.Dispatch()
+//
+// NewCommand panics if T does not declare its flags correctly.
+func NewCommand[T any](name, summary string, run func(a *App, opts *T, args []string) error) *Command {
+	declared := optionsOf(reflect.TypeOf((*T)(nil)).Elem(), "APP_"+envName(name)+"_")
+	return &Command{
+		Name:    name,
+		Summary: summary,
+		opts:    declared,
+		run: func(a *App, args []string) error {
+			opts := new(T)
+			rest, err := bindOptions(a, declared, reflect.ValueOf(opts).Elem(), args)
+			if err != nil {
+				return err
+			}
+			return a.withTimeout(func() error {
+				return run(a, opts, rest)
+			})
+		},
+	}
+}
+
+// Flags returns the flags of the command, in the order they are declared.
+func (c *Command) Flags() []Flag {
+	flags := make([]Flag, len(c.opts.flags))
+	for idx, f := range c.opts.flags {
+		flags[idx] = *f
+	}
+	return flags
+}
+
+// Args returns the positional arguments of the command, in the order they are declared.
+func (c *Command) Args() []Arg {
+	args := make([]Arg, len(c.opts.args))
+	for idx, arg := range c.opts.args {
+		args[idx] = *arg
+	}
+	return args
+}
+
+// CommandContext returns the Context of the running command: the app Context, bounded by --timeout and canceled
+// by Ctrl-C in the Shell. Commands wait on it, rather than on the app Context, to stop in time. Outside of a command,
+// it is the app Context, or the background context if that is nil.
+func (a *App) CommandContext() context.Context {
+	a.commandCtxMu.Lock()
+	defer a.commandCtxMu.Unlock()
+	switch {
+	case a.commandCtx != nil:
+		return a.commandCtx
+	case a.Context != nil:
+		return a.Context
+	default:
+		return context.Background()
+	}
+}
+
+// withCommandContext calls fn with ctx as the CommandContext, then restores the one before it.
+func (a *App) withCommandContext(ctx context.Context, fn func() error) error {
+	a.commandCtxMu.Lock()
+	prev := a.commandCtx
+	a.commandCtx = ctx
+	a.commandCtxMu.Unlock()
+
+	defer func() {
+		a.commandCtxMu.Lock()
+		a.commandCtx = prev
+		a.commandCtxMu.Unlock()
+	}()
+	return fn()
+}
+
+// AddCommand adds commands for Dispatch to run, and declares the variables their flags fall back to in the
+// ConfigSchema. The variables named by env tags are left to the modules that read them.
+func (a *App) AddCommand(cmds ...*Command) {
+	for _, cmd := range cmds {
+		keys := make([]ConfigKey, 0, len(cmd.opts.flags))
+		for _, f := range cmd.opts.flags {
+			if f.Env != "" && !f.shared {
+				keys = append(keys, ConfigKey{
+					Name:        f.Env,
+					Type:        strings.TrimSuffix(f.Type, "s"),
+					Default:     f.Default,
+					Description: fmt.Sprintf("The default of the --%s flag: %s.", f.Name, f.Usage),
+				})
+			}
+		}
+		a.DeclareConfig(cmd.Name, keys...)
+	}
+
+	a.commandsMu.Lock()
+	defer a.commandsMu.Unlock()
+	a.commands = append(a.commands, cmds...)
+}
+
+// Commands returns the commands added with AddCommand, in the order they were added.
+func (a *App) Commands() []*Command {
+	a.commandsMu.Lock()
+	defer a.commandsMu.Unlock()
+	return append([]*Command(nil), a.commands...)
+}
+
+func (a *App) command(name string) *Command {
+	for _, cmd := range a.Commands() {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Dispatch runs the command named by the first of the Arguments with the arguments that follow it. It prints the
+// usage of the app with no arguments or help, and that of a command with help followed by its name or with -h or
+// --help among its flags. The -q, -v, -vv, and --debug flags set the Verbosity, before the command or among its
+// flags. The --timeout flag, or APP_TIMEOUT, bounds the CommandContext: once the time is up, the error of the command
+// wraps ErrTimeout and Exit uses ExitTimeout, so that a stuck job fails rather than hangs. The --dry-run flag, or
+// its DryRunShort name, turns on DryRun, and the --yes flag, or its YesShort name, answers Confirm.
+//
+// The error of the command is returned, and reported for the exit report. A clierror.Error is also rendered on
+// ErrOutput, and its exit status used by Exit; print other errors as you see fit.
+//
+// Hidden and deprecated commands and flags are left out of the usage and the completions, but still run; a
+// deprecated one warns on ErrOutput with its advice when it is used. Experimental ones fail unless APP_EXPERIMENTAL
+// is set.
+//
+// The hidden __complete command prints the completions of the last of the arguments that follow it, one per line
+// with a tab before its description, for shell completion scripts to offer: the commands, the flags of a command,
+// or the values of the flag before it.
+func (a *App) Dispatch() error {
+	err := a.dispatch(a.Arguments)
+	if err != nil {
+		a.reportFailure(err)
+	}
+	return err
+}
+
+func (a *App) dispatch(args []string) error {
+	for len(args) > 0 {
+		n, err := a.builtinFlag(args)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		args = args[n:]
+	}
+	if len(args) == 0 {
+		return a.writeUsage(a.Output())
+	}
+
+	switch args[0] {
+	case "help", "-h", "--help":
+		if len(args) == 1 {
+			return a.writeUsage(a.Output())
+		}
+		cmd := a.command(args[1])
+		if cmd == nil {
+			return a.unknownCommand(args[1])
+		}
+		return a.writeCommandUsage(a.Output(), cmd)
+	case "__complete":
+		return a.writeCompletions(a.Output(), args[1:])
+	}
+
+	cmd := a.command(args[0])
+	if cmd == nil {
+		return a.unknownCommand(args[0])
+	}
+	if cmd.Experimental && !a.experimental() {
+		return errors.New(a.Localizer().T("app.experimental", "command "+cmd.Name))
+	}
+	if cmd.Deprecated != "" {
+		a.warn(a.Localizer().T("app.deprecated", "command "+cmd.Name, cmd.Deprecated))
+	}
+	err := cmd.run(a, args[1:])
+	if errors.Is(err, ErrHelp) {
+		return a.writeCommandUsage(a.Output(), cmd)
+	}
+	return err
+}
+
+// builtinFlag applies the flag every command accepts at the start of args, returning the number of arguments it
+// took, or zero if args do not start with one.
+func (a *App) builtinFlag(args []string) (int, error) {
+	switch {
+	case a.verbosityFlag(args[0]):
+		return 1, nil
+	case isFlag(args[0], "dry-run", a.DryRunShort):
+		a.SetFlag("APP_DRY_RUN", "true")
+		return 1, nil
+	case isFlag(args[0], "yes", a.YesShort):
+		a.SetFlag("APP_YES", "true")
+		return 1, nil
+	}
+
+	value, ok := strings.CutPrefix(args[0], "--timeout=")
+	n := 1
+	switch {
+	case ok:
+	case args[0] != "--timeout":
+		return 0, nil
+	case len(args) < 2:
+		return 0, errors.New("flag --timeout needs a value")
+	default:
+		value, n = args[1], 2
+	}
+
+	if d, err := time.ParseDuration(value); err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --timeout %q", value)
+	}
+	a.SetFlag("APP_TIMEOUT", value)
+	return n, nil
+}
+
+func (a *App) unknownCommand(name string) error {
+	best, bestDistance := "", 3
+	for _, cmd := range a.visibleCommands() {
+		if d := editDistance(name, cmd.Name); d < bestDistance {
+			best, bestDistance = cmd.Name, d
+		}
+	}
+	if best != "" {
+		return fmt.Errorf("unknown command %q, did you mean %q?", name, best)
+	}
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// visibleCommands returns the commands shown in usage text and completions.
+func (a *App) visibleCommands() []*Command {
+	var cmds []*Command
+	for _, cmd := range a.Commands() {
+		if !cmd.Hidden && cmd.Deprecated == "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds
+}
+
+func (a *App) experimental() bool {
+	on, _ := a.lookupBool("APP_EXPERIMENTAL")
+	return on
+}
+
+// warn writes the warning msg to ErrOutput.
+func (a *App) warn(msg string) {
+	_, _ = fmt.Fprintln(a.ErrOutput(), a.Localizer().T("app.warning", msg))
+}
+
+func (a *App) programName() string {
+	if a.Name == "" {
+		return "app"
+	}
+	return a.Name
+}
+
+func (a *App) writeUsage(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "Usage: %s <command> [flags] [args]\n\nCommands:\n", a.programName())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range a.visibleCommands() {
+		summary := cmd.Summary
+		if cmd.Experimental {
+			summary = strings.TrimSpace(summary + " (experimental)")
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name, summary)
+	}
+	_ = tw.Flush()
+	_, err := fmt.Fprintf(w, "\nRun '%s help <command>' for the flags of a command.\n", a.programName())
+	return err
+}
+
+func (a *App) writeCommandUsage(w io.Writer, cmd *Command) error {
+	synopsis := "[args]"
+	if len(cmd.opts.args) > 0 {
+		names := make([]string, len(cmd.opts.args))
+		for idx, arg := range cmd.opts.args {
+			names[idx] = arg.String()
+		}
+		synopsis = strings.Join(names, " ")
+	}
+	_, _ = fmt.Fprintf(w, "Usage: %s %s [flags] %s\n", a.programName(), cmd.Name, synopsis)
+	if cmd.Summary != "" {
+		_, _ = fmt.Fprintf(w, "\n%s\n", cmd.Summary)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(cmd.opts.args) > 0 {
+		_, _ = fmt.Fprintln(w, "\nArguments:")
+		for _, arg := range cmd.opts.args {
+			usage := arg.Usage
+			if arg.Default != "" {
+				usage = strings.TrimSpace(usage + " (default " + arg.Default + ")")
+			}
+			_, _ = fmt.Fprintf(tw, "  %s\t%s\n", arg.Name, usage)
+		}
+		_ = tw.Flush()
+	}
+
+	_, _ = fmt.Fprintln(w, "\nFlags:")
+	for _, f := range cmd.opts.flags {
+		if !f.visible() {
+			continue
+		}
+		name := "    --" + f.Name
+		if f.Short != "" {
+			name = "-" + f.Short + ", --" + f.Name
+		}
+		if !f.isBool() {
+			name += " " + f.Type
+		}
+
+		var notes []string
+		if f.Experimental {
+			notes = append(notes, "experimental")
+		}
+		if len(f.Values) > 0 {
+			notes = append(notes, "one of "+strings.Join(f.Values, ", "))
+		}
+		if f.Default != "" {
+			notes = append(notes, "default "+f.Default)
+		}
+		if f.Env != "" {
+			notes = append(notes, "env "+f.Env)
+		}
+		usage := f.Usage
+		if len(notes) > 0 {
+			usage = strings.TrimSpace(usage + " (" + strings.Join(notes, ", ") + ")")
+		}
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", name, usage)
+	}
+	_, _ = fmt.Fprintf(tw, "  -h, --help\tshow this help\n")
+	_, _ = fmt.Fprintf(tw, "  -q, --quiet\tprint only warnings, errors, and results\n")
+	_, _ = fmt.Fprintf(tw, "  -v, --verbose\tprint more, or everything with -vv or --debug\n")
+	_, _ = fmt.Fprintf(tw, "      --timeout duration\tstop the command after the duration (env APP_TIMEOUT)\n")
+	_, _ = fmt.Fprintf(tw, "  %s--dry-run\tshow the changes without making them (env APP_DRY_RUN)\n",
+		shortPrefix(a.DryRunShort))
+	_, _ = fmt.Fprintf(tw, "  %s--yes\tconfirm dangerous changes without asking (env APP_YES)\n",
+		shortPrefix(a.YesShort))
+	return tw.Flush()
+}
+
+// isFlag reports whether arg is the flag --name, or -short if short is set.
+func isFlag(arg, name, short string) bool {
+	return arg == "--"+name || (short != "" && arg == "-"+short)
+}
+
+// shortPrefix returns the one letter name short as it precedes the long name in usage text, or the padding in its
+// place if short is not set.
+func shortPrefix(short string) string {
+	if short == "" {
+		return "    "
+	}
+	return "-" + short + ", "
+}
+
+func (a *App) writeCompletions(w io.Writer, words []string) error {
+	last := ""
+	if len(words) > 0 {
+		last = words[len(words)-1]
+	}
+
+	var candidates [][2]string
+	switch {
+	case len(words) <= 1:
+		for _, cmd := range a.visibleCommands() {
+			candidates = append(candidates, [2]string{cmd.Name, cmd.Summary})
+		}
+		candidates = append(candidates, [2]string{"help", "show the usage of a command"})
+	case words[0] == "help" && len(words) == 2:
+		for _, cmd := range a.visibleCommands() {
+			candidates = append(candidates, [2]string{cmd.Name, cmd.Summary})
+		}
+	default:
+		cmd := a.command(words[0])
+		if cmd == nil {
+			return nil
+		}
+		if prev := flagNamed(cmd.opts.flags, words[len(words)-2]); prev != nil && !prev.isBool() {
+			for _, value := range prev.Values {
+				candidates = append(candidates, [2]string{value, ""})
+			}
+			break
+		}
+		if !strings.HasPrefix(last, "-") {
+			return nil
+		}
+		for _, f := range cmd.opts.flags {
+			if !f.visible() {
+				continue
+			}
+			candidates = append(candidates, [2]string{"--" + f.Name, f.Usage})
+			if f.Short != "" {
+				candidates = append(candidates, [2]string{"-" + f.Short, f.Usage})
+			}
+		}
+		candidates = append(candidates,
+			[2]string{"--help", "show this help"},
+			[2]string{"--quiet", "print only warnings, errors, and results"},
+			[2]string{"--verbose", "print more"},
+			[2]string{"--debug", "print everything"},
+			[2]string{"--timeout", "stop the command after the duration"},
+			[2]string{"--dry-run", "show the changes without making them"},
+			[2]string{"--yes", "confirm dangerous changes without asking"},
+		)
+	}
+
+	for _, c := range candidates {
+		if !strings.HasPrefix(c[0], last) {
+			continue
+		}
+		line := c[0]
+		if c[1] != "" {
+			line += "\t" + c[1]
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flagNamed returns the flag named by the argument arg, if it is a flag given without a value.
+func flagNamed(flags []*Flag, arg string) *Flag {
+	for _, f := range flags {
+		if arg == "--"+f.Name || f.Short != "" && arg == "-"+f.Short {
+			return f
+		}
+	}
+	return nil
+}
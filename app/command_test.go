@@ -0,0 +1,221 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func newCommandApp(args ...string) (*app.App, *[]string) {
+	a := newApp(nil, args...)
+	a.Name = "prog"
+
+	var ran []string
+	a.AddCommand(
+		app.NewCommand("serve", "run the server", func(a *app.App, opts *serveOptions, args []string) error {
+			ran = append(ran, opts.Addr)
+			ran = append(ran, args...)
+			return nil
+		}),
+		app.NewCommand("migrate", "migrate the database", func(*app.App, *struct{}, []string) error {
+			ran = append(ran, "migrate")
+			return nil
+		}),
+	)
+	return a, &ran
+}
+
+func TestApp_Dispatch(t *testing.T) {
+	a, ran := newCommandApp("serve", "-a", ":1", "extra")
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, []string{":1", "extra"}, *ran)
+
+	a, _ = newCommandApp("serv")
+	assert.EqualError(t, a.Dispatch(), `unknown command "serv", did you mean "serve"?`)
+
+	a, _ = newCommandApp("serve", "--port", "1")
+	assert.EqualError(t, a.Dispatch(), "unknown flag --port")
+
+	a, ran = newCommandApp()
+	require.NoError(t, a.Dispatch())
+	assert.Empty(t, *ran)
+	assert.Equal(t, `Usage: prog <command> [flags] [args]
+
+Commands:
+  serve    run the server
+  migrate  migrate the database
+
+Run 'prog help <command>' for the flags of a command.
+`, a.Stdout.(*bytes.Buffer).String())
+
+	usage := `Usage: prog serve [flags] [args]
+
+run the server
+
+Flags:
+  -k, --insecure          skip TLS verification
+  -a, --addr string       the address to listen on (default :8080, env APP_SERVE_ADDR)
+  -w, --workers int       the number of workers (default 4, env APP_SERVE_WORKERS)
+      --grace duration    how long requests get to finish (env APP_SHUTDOWN_TIMEOUT)
+  -t, --tag strings       a tag added to every metric (env APP_SERVE_TAG)
+      --mode string       the mode (one of fast, safe, env APP_SERVE_MODE)
+      --bind value        the interface (env APP_SERVE_BIND)
+  -h, --help              show this help
+  -q, --quiet             print only warnings, errors, and results
+  -v, --verbose           print more, or everything with -vv or --debug
+      --timeout duration  stop the command after the duration (env APP_TIMEOUT)
+      --dry-run           show the changes without making them (env APP_DRY_RUN)
+      --yes               confirm dangerous changes without asking (env APP_YES)
+`
+	for _, args := range [][]string{{"help", "serve"}, {"serve", "-h"}} {
+		a, ran = newCommandApp(args...)
+		require.NoError(t, a.Dispatch())
+		assert.Empty(t, *ran)
+		assert.Equal(t, usage, a.Stdout.(*bytes.Buffer).String())
+	}
+
+	a, _ = newCommandApp()
+	var workers app.ConfigKey
+	for _, key := range a.ConfigSchema() {
+		if key.Name == "APP_SERVE_WORKERS" {
+			workers = key
+		}
+	}
+	assert.Equal(t, app.ConfigKey{
+		Module:      "serve",
+		Name:        "APP_SERVE_WORKERS",
+		Type:        "int",
+		Default:     "4",
+		Description: "The default of the --workers flag: the number of workers.",
+	}, workers)
+}
+
+func TestApp_Dispatch_ownFlags(t *testing.T) {
+	type serve struct {
+		Addr string `flag:"addr,,the address" default:":8080"`
+		Env  string `flag:"env,,the target environment"`
+	}
+	type admin struct {
+		Addr string `flag:"addr,,the address" default:":9090"`
+	}
+
+	var got []string
+	a := newApp([]string{"APP_ENV=production", "APP_ADMIN_ADDR=:7070"}, "serve", "--env", "staging")
+	a.AddCommand(
+		app.NewCommand("serve", "", func(_ *app.App, opts *serve, _ []string) error {
+			got = append(got, opts.Addr, opts.Env)
+			return nil
+		}),
+		app.NewCommand("admin", "", func(_ *app.App, opts *admin, _ []string) error {
+			got = append(got, opts.Addr)
+			return nil
+		}),
+	)
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, []string{":8080", "staging"}, got, "each command has its own defaults")
+	v, _ := a.LookupEnv("APP_ENV")
+	assert.Equal(t, "production", v, "command flags stay out of the environment")
+
+	a.Arguments = []string{"admin"}
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, []string{":8080", "staging", ":7070"}, got)
+}
+
+func TestApp_Dispatch_complete(t *testing.T) {
+	for expected, args := range map[string][]string{
+		"serve\trun the server\n": {"s"},
+		"serve\trun the server\nmigrate\tmigrate the database\nhelp\tshow the usage of a command\n": {""},
+		"migrate\tmigrate the database\n":    {"help", "m"},
+		"--workers\tthe number of workers\n": {"serve", "--w"},
+		"fast\nsafe\n":                       {"serve", "--mode", ""},
+		"":                                   {"serve", "x"},
+	} {
+		a, _ := newCommandApp(append([]string{"__complete"}, args...)...)
+		require.NoError(t, a.Dispatch())
+		assert.Equal(t, expected, a.Stdout.(*bytes.Buffer).String(), args)
+	}
+}
+
+func TestApp_Dispatch_annotations(t *testing.T) {
+	type options struct {
+		Bind  string `flag:"bind,,the address" deprecated:"use --addr instead" env:"-"`
+		Addr  string `flag:"addr,,the address" env:"-"`
+		Trace bool   `flag:"trace,,trace everything" hidden:"true" env:"-"`
+		Fast  bool   `flag:"fast,,skip the checks" experimental:"true" env:"-"`
+	}
+	newAnnotatedApp := func(environ []string, args ...string) (*app.App, *[]string) {
+		a := newApp(environ, args...)
+		a.Name = "prog"
+		var ran []string
+		run := func(a *app.App, opts *options, args []string) error {
+			ran = append(ran, fmt.Sprintf("%s %t %t", opts.Bind, opts.Trace, opts.Fast))
+			return nil
+		}
+		old := app.NewCommand("old", "the old run", run)
+		old.Deprecated = "use run instead"
+		secret := app.NewCommand("secret", "", run)
+		secret.Hidden = true
+		beta := app.NewCommand("beta", "the new run", run)
+		beta.Experimental = true
+		a.AddCommand(app.NewCommand("run", "run", run), old, secret, beta)
+		return a, &ran
+	}
+
+	a, _ := newAnnotatedApp(nil, "help")
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, `Usage: prog <command> [flags] [args]
+
+Commands:
+  run   run
+  beta  the new run (experimental)
+
+Run 'prog help <command>' for the flags of a command.
+`, a.Stdout.(*bytes.Buffer).String())
+
+	a, _ = newAnnotatedApp(nil, "help", "run")
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, `Usage: prog run [flags] [args]
+
+run
+
+Flags:
+      --addr string       the address
+      --fast              skip the checks (experimental)
+  -h, --help              show this help
+  -q, --quiet             print only warnings, errors, and results
+  -v, --verbose           print more, or everything with -vv or --debug
+      --timeout duration  stop the command after the duration (env APP_TIMEOUT)
+      --dry-run           show the changes without making them (env APP_DRY_RUN)
+      --yes               confirm dangerous changes without asking (env APP_YES)
+`, a.Stdout.(*bytes.Buffer).String())
+
+	a, _ = newAnnotatedApp(nil, "__complete", "run", "--")
+	require.NoError(t, a.Dispatch())
+	assert.NotContains(t, a.Stdout.(*bytes.Buffer).String(), "--bind")
+	assert.NotContains(t, a.Stdout.(*bytes.Buffer).String(), "--trace")
+
+	a, ran := newAnnotatedApp(nil, "old", "--bind", ":1", "--trace")
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, []string{":1 true false"}, *ran)
+	assert.Equal(t, "warning: command old is deprecated, use run instead\n"+
+		"warning: flag --bind is deprecated, use --addr instead\n", a.Stderr.(*bytes.Buffer).String())
+
+	a, _ = newAnnotatedApp(nil, "secre")
+	assert.EqualError(t, a.Dispatch(), `unknown command "secre"`, "hidden commands are not suggested")
+	a, ran = newAnnotatedApp(nil, "secret")
+	require.NoError(t, a.Dispatch())
+	assert.Len(t, *ran, 1)
+
+	a, _ = newAnnotatedApp(nil, "beta")
+	assert.EqualError(t, a.Dispatch(), "command beta is experimental, set APP_EXPERIMENTAL=1 to use it")
+	a, _ = newAnnotatedApp(nil, "run", "--fast")
+	assert.EqualError(t, a.Dispatch(), "flag --fast is experimental, set APP_EXPERIMENTAL=1 to use it")
+	a, ran = newAnnotatedApp([]string{"APP_EXPERIMENTAL=1"}, "beta", "--fast")
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, []string{" false true"}, *ran)
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aphistic/gomol"
+	"github.com/mattn/go-isatty"
+)
+
+// ExitNotConfirmed is the status Exit uses once a Confirm was refused, so that scripts can tell a refusal from a
+// failure.
+const ExitNotConfirmed = 3
+
+// ErrNotConfirmed is returned by Confirm when the change was not confirmed.
+var ErrNotConfirmed = errors.New("not confirmed")
+
+// ConfirmOptions change how Confirm asks.
+type ConfirmOptions struct {
+	// Resource, if set, must be typed in full to confirm, rather than y, as for the name of a database to drop.
+	Resource string
+}
+
+// Interactive reports whether the app can ask the user questions: Stdin and Stderr are terminals.
+func (a *App) Interactive() bool {
+	if a.interactive != nil {
+		return *a.interactive
+	}
+	return isTerminal(a.Stdin) && isTerminal(a.Stderr)
+}
+
+// stdinReader returns the buffered reader of Stdin, kept for as long as Stdin is not replaced so that the input it
+// reads ahead is not lost between questions.
+func (a *App) stdinReader() *bufio.Reader {
+	a.stdinMu.Lock()
+	defer a.stdinMu.Unlock()
+	if a.stdin == nil || a.stdinSrc != a.Stdin {
+		a.stdin, a.stdinSrc = bufio.NewReader(a.Stdin), a.Stdin
+	}
+	return a.stdin
+}
+
+func isTerminal(v interface{}) bool {
+	f, ok := v.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+// Confirm asks the user to confirm the dangerous change described by prompt, such as "Drop the database prod?",
+// before a command makes it:
+//
+//	if err := a.Confirm("Drop the database "+name+"?", app.ConfirmOptions{Resource: name}); err != nil {
+//		return err
+//	}
+//
+// The change is confirmed without asking with the --yes flag, its YesShort name, or APP_YES, and in a DryRun, which
+// makes no change. Otherwise the prompt is written to ErrOutput and the answer read from Stdin: y or yes, or the
+// Resource if set. When the app is not Interactive, nobody can answer, and Confirm refuses rather than wait.
+//
+// A refusal returns an error that wraps ErrNotConfirmed, and makes Exit use ExitNotConfirmed.
+func (a *App) Confirm(prompt string, opts ConfirmOptions) error {
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"prompt": prompt})
+	if yes, _ := a.lookupBool("APP_YES"); yes || a.DryRun() {
+		_ = a.Logger().Infom(attrs, "confirmed without asking")
+		return nil
+	}
+
+	err := a.askConfirm(prompt, opts)
+	if err != nil {
+		a.setFailureCode(ExitNotConfirmed)
+		_ = a.Logger().Warnm(attrs, "%v", err)
+	}
+	return err
+}
+
+func (a *App) askConfirm(prompt string, opts ConfirmOptions) error {
+	if !a.Interactive() {
+		return fmt.Errorf("%w, pass --yes to confirm without a terminal", ErrNotConfirmed)
+	}
+
+	w := a.ErrOutput()
+	if opts.Resource != "" {
+		_, _ = fmt.Fprintf(w, "%s\nType %s to confirm: ", prompt, opts.Resource)
+	} else {
+		_, _ = fmt.Fprintf(w, "%s [y/N] ", prompt)
+	}
+	_ = a.Flush()
+
+	line, err := a.stdinReader().ReadString('\n')
+	if err != nil && line == "" {
+		_, _ = fmt.Fprintln(w)
+		return fmt.Errorf("%w, no answer", ErrNotConfirmed)
+	}
+	answer := strings.TrimSpace(line)
+
+	switch {
+	case opts.Resource != "" && answer == opts.Resource:
+		return nil
+	case opts.Resource != "":
+		return fmt.Errorf("%w, %q does not match %q", ErrNotConfirmed, answer, opts.Resource)
+	case strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes"):
+		return nil
+	default:
+		return ErrNotConfirmed
+	}
+}
@@ -0,0 +1,81 @@
+package app_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Confirm(t *testing.T) {
+	a := newApp(nil)
+	assert.False(t, a.Interactive())
+	err := a.Confirm("Drop the database?", app.ConfirmOptions{})
+	assert.ErrorIs(t, err, app.ErrNotConfirmed)
+	assert.EqualError(t, err, "not confirmed, pass --yes to confirm without a terminal")
+	assert.PanicsWithValue(t, "system exit 3", func() { a.Exit(1) })
+
+	for _, a := range []*app.App{newApp([]string{"APP_YES=true"}), newApp([]string{"APP_DRY_RUN=true"})} {
+		assert.NoError(t, a.Confirm("Drop the database?", app.ConfirmOptions{Resource: "prod"}))
+	}
+
+	a = newApp(nil, "drop", "--yes")
+	confirmed := false
+	a.AddCommand(app.NewCommand("drop", "", func(a *app.App, _ *struct{}, _ []string) error {
+		confirmed = a.Confirm("Drop the database?", app.ConfirmOptions{}) == nil
+		return nil
+	}))
+	require.NoError(t, a.Dispatch())
+	assert.True(t, confirmed)
+
+	a = newApp(nil, "-y", "drop")
+	a.AddCommand(app.NewCommand("drop", "", func(a *app.App, _ *struct{}, _ []string) error {
+		return a.Confirm("Drop the database?", app.ConfirmOptions{})
+	}))
+	assert.EqualError(t, a.Dispatch(), `unknown command "-y"`)
+	a.YesShort = "y"
+	assert.NoError(t, a.Dispatch())
+
+	for input, expected := range map[string]string{
+		"y\n":   "",
+		"YES\n": "",
+		"\n":    "not confirmed",
+		"no\n":  "not confirmed",
+		"":      "not confirmed, no answer",
+	} {
+		a := newApp(nil)
+		app.SetInteractive(a, true)
+		a.Stdin = strings.NewReader(input)
+		err := a.Confirm("Drop the database?", app.ConfirmOptions{})
+		if expected == "" {
+			assert.NoError(t, err, input)
+		} else {
+			assert.EqualError(t, err, expected, input)
+		}
+		// the refusal is logged to Stderr too, in the background
+		_ = a.Logger().ShutdownLoggers()
+		assert.True(t, strings.HasPrefix(a.Stderr.(*bytes.Buffer).String(), "Drop the database? [y/N] "))
+	}
+
+	a = newApp(nil)
+	app.SetInteractive(a, true)
+	a.Stdin = strings.NewReader("prod\n")
+	require.NoError(t, a.Confirm("Drop the database?", app.ConfirmOptions{Resource: "prod"}))
+	assert.Equal(t, "Drop the database?\nType prod to confirm: ", a.Stderr.(*bytes.Buffer).String())
+
+	a.Stdin = strings.NewReader("y\n")
+	assert.EqualError(t, a.Confirm("Drop the database?", app.ConfirmOptions{Resource: "prod"}),
+		`not confirmed, "y" does not match "prod"`)
+	_ = a.Logger().ShutdownLoggers()
+
+	// the answers typed ahead are kept for the questions that follow
+	a = newApp(nil)
+	app.SetInteractive(a, true)
+	a.Stdin = strings.NewReader("y\nprod\n")
+	require.NoError(t, a.Confirm("Restart?", app.ConfirmOptions{}))
+	require.NoError(t, a.Confirm("Drop the database?", app.ConfirmOptions{Resource: "prod"}))
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// DefaultHTTPPort is the port an HTTPServer without a Spec listens on when PORT does not say otherwise.
+const DefaultHTTPPort = 8080
+
+// containerIDPattern matches the IDs of Docker, containerd, CRI-O, and Podman containers in cgroup paths and mounts.
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// Container describes the container the app runs in. Set App.Container to mock it; it is detected from the OS on
+// first use otherwise. The zero Container describes an app that does not run in a container.
+//
+// In a container, the app adjusts its defaults to the way containers are run: log messages are JSON objects without
+// colors, unless APP_LOG_FORMAT is text, and an HTTPServer without a Spec listens on all interfaces, on the port in
+// PORT as platforms like Cloud Run and Heroku set it.
+type Container struct {
+	Runtime     string  // docker, podman, kubernetes, containerd, lxc, or the value of the container variable
+	ID          string  // the container ID, if the cgroups or mounts show it
+	CPULimit    float64 // the CPUs the container may use, 0 if unlimited
+	MemoryLimit int64   // the bytes of memory the container may use, 0 if unlimited
+}
+
+// container returns the Container, detecting it if needed.
+func (a *App) container() *Container {
+	a.containerMu.Lock()
+	defer a.containerMu.Unlock()
+
+	if a.Container == nil {
+		c := detectContainer("/", a.lookupEnv)
+		a.Container = &c
+	}
+	return a.Container
+}
+
+// InContainer reports whether the app runs in a container.
+func (a *App) InContainer() bool {
+	return a.container().Runtime != ""
+}
+
+// ContainerID returns the ID of the container the app runs in, empty if it is unknown or the app does not run in
+// one.
+func (a *App) ContainerID() string {
+	return a.container().ID
+}
+
+// CPULimit returns the CPUs the container the app runs in may use, such as 0.5 or 2, or 0 if it is unlimited.
+// GOMAXPROCS should not exceed it.
+func (a *App) CPULimit() float64 {
+	return a.container().CPULimit
+}
+
+// MemoryLimit returns the bytes of memory the container the app runs in may use, or 0 if it is unlimited. A
+// GOMEMLIMIT a little below it keeps the app from being killed.
+func (a *App) MemoryLimit() int64 {
+	return a.container().MemoryLimit
+}
+
+// detectContainer inspects the file system at root and the environment for signs of a container runtime.
+func detectContainer(root string, lookup func(string) (string, bool)) Container {
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+
+	var c Container
+	cgroups := read("proc/1/cgroup")
+	_, kubernetes := lookup("KUBERNETES_SERVICE_HOST")
+	switch v, _ := lookup("container"); {
+	case v != "":
+		c.Runtime = v
+	case kubernetes, strings.Contains(cgroups, "kubepods"):
+		c.Runtime = "kubernetes"
+	case exists("run/.containerenv"):
+		c.Runtime = "podman"
+	case exists(".dockerenv"), strings.Contains(cgroups, "docker"):
+		c.Runtime = "docker"
+	case strings.Contains(cgroups, "containerd"):
+		c.Runtime = "containerd"
+	case strings.Contains(cgroups, "lxc"):
+		c.Runtime = "lxc"
+	}
+	if c.Runtime == "" {
+		return c
+	}
+
+	// cgroup v1 paths name the container; with cgroup v2, the hostname and resolv.conf mounts do
+	c.ID = containerIDPattern.FindString(read("proc/self/cgroup"))
+	if c.ID == "" {
+		c.ID = containerIDPattern.FindString(read("proc/self/mountinfo"))
+	}
+
+	if quota, period, ok := strings.Cut(read("sys/fs/cgroup/cpu.max"), " "); ok {
+		c.CPULimit = cpuLimit(quota, period)
+	} else {
+		c.CPULimit = cpuLimit(read("sys/fs/cgroup/cpu/cpu.cfs_quota_us"), read("sys/fs/cgroup/cpu/cpu.cfs_period_us"))
+	}
+
+	memory := read("sys/fs/cgroup/memory.max")
+	if memory == "" {
+		memory = read("sys/fs/cgroup/memory/memory.limit_in_bytes")
+	}
+	// cgroup v1 reports no limit as the largest multiple of the page size
+	if n, err := strconv.ParseInt(memory, 10, 64); err == nil && n > 0 && n < 1<<62 {
+		c.MemoryLimit = n
+	}
+	return c
+}
+
+// cpuLimit returns the CPUs a CFS quota and period allow, 0 if the quota is max or -1.
+func cpuLimit(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// logJSON is the template function rendering a log message as a JSON object. The attributes named as the time, level,
+// and msg fields are prefixed with "attr.".
+func logJSON(msg *gomol.TemplateMsg) (string, error) {
+	record := make(map[string]interface{}, len(msg.Attrs)+3)
+	record["time"] = msg.Timestamp.Format(time.RFC3339Nano)
+	record["level"] = msg.LevelName
+	record["msg"] = msg.Message
+	for key, v := range msg.Attrs {
+		for _, taken := record[key]; taken; _, taken = record[key] {
+			key = "attr." + key
+		}
+		record[key] = v
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// defaultHTTPSpec returns the listen spec of an HTTPServer without a Spec: all interfaces in a container, on the port
+// in PORT if it is set, or localhost otherwise, on DefaultHTTPPort.
+func (a *App) defaultHTTPSpec() (string, error) {
+	if !a.InContainer() {
+		return fmt.Sprintf("tcp://localhost:%d", DefaultHTTPPort), nil
+	}
+
+	port := DefaultHTTPPort
+	if v := a.trimmedEnv("PORT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 65535 {
+			return "", fmt.Errorf("invalid PORT %q", v)
+		}
+		port = n
+	}
+	return fmt.Sprintf("tcp://:%d", port), nil
+}
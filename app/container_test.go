@@ -0,0 +1,124 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aphistic/gomol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const containerID = "3f4e8a1b2c9d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+
+func TestDetectContainer(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files    map[string]string
+		env      map[string]string
+		expected app.Container
+	}{
+		"none": {
+			files: map[string]string{"proc/1/cgroup": "0::/init.scope\n"},
+		},
+		"docker cgroup v2": {
+			files: map[string]string{
+				".dockerenv":               "",
+				"proc/1/cgroup":            "0::/\n",
+				"proc/self/mountinfo":      "612 590 254:1 /docker/containers/" + containerID + "/hostname /etc/hostname rw\n",
+				"sys/fs/cgroup/cpu.max":    "150000 100000\n",
+				"sys/fs/cgroup/memory.max": "536870912\n",
+			},
+			expected: app.Container{Runtime: "docker", ID: containerID, CPULimit: 1.5, MemoryLimit: 536870912},
+		},
+		"kubernetes cgroup v1": {
+			files: map[string]string{
+				"proc/1/cgroup":                              "4:memory:/kubepods/burstable/pod1/" + containerID + "\n",
+				"proc/self/cgroup":                           "4:memory:/kubepods/burstable/pod1/" + containerID + "\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			expected: app.Container{Runtime: "kubernetes", ID: containerID},
+		},
+		"podman": {
+			files: map[string]string{
+				"run/.containerenv":     "",
+				"sys/fs/cgroup/cpu.max": "max 100000\n",
+			},
+			expected: app.Container{Runtime: "podman"},
+		},
+		"variable": {
+			env:      map[string]string{"container": "systemd-nspawn"},
+			expected: app.Container{Runtime: "systemd-nspawn"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			for file, content := range tc.files {
+				path := filepath.Join(root, filepath.FromSlash(file))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+				require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			}
+			assert.Equal(t, tc.expected, app.DetectContainer(root, tc.env))
+		})
+	}
+}
+
+func TestApp_Container(t *testing.T) {
+	a := newApp(nil)
+	a.Container = &app.Container{Runtime: "docker", ID: containerID, CPULimit: 2, MemoryLimit: 1 << 30}
+	assert.True(t, a.InContainer())
+	assert.Equal(t, containerID, a.ContainerID())
+	assert.Equal(t, 2.0, a.CPULimit())
+	assert.Equal(t, int64(1<<30), a.MemoryLimit())
+
+	assert.False(t, newApp(nil).InContainer())
+}
+
+func TestApp_Container_Logger(t *testing.T) {
+	a := newApp(nil)
+	a.Container = &app.Container{Runtime: "docker"}
+	_ = a.Logger().Infof("hello %s", "world")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Regexp(t, `^\{"filename":".*","level":"info","lineno":\d+,"msg":"hello world","seq":1,"time":"[^"]+"\}\n$`,
+		a.Stderr.(*bytes.Buffer).String())
+
+	// the attributes do not overwrite the fields of the record
+	a = newApp(nil)
+	a.Container = &app.Container{Runtime: "docker"}
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(map[string]interface{}{"msg": "attr", "level": 3}), "hello")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Regexp(t, `"attr.level":3,"attr.msg":"attr",.*"level":"info",.*"msg":"hello",`,
+		a.Stderr.(*bytes.Buffer).String())
+
+	// text, but without colors
+	a = newApp([]string{"APP_LOG_FORMAT=text", "NO_COLOR=1"})
+	a.Container = &app.Container{Runtime: "docker"}
+	_ = a.Logger().Infof("hello")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Regexp(t, `^\[INFO\] hello \{`, a.Stderr.(*bytes.Buffer).String())
+}
+
+func TestHTTPServer_DefaultSpec(t *testing.T) {
+	a := newApp(nil)
+	port, err := a.FreePort()
+	require.NoError(t, err)
+
+	a = newApp([]string{fmt.Sprintf("PORT=%d", port)})
+	a.Container = &app.Container{Runtime: "docker"}
+	s := app.NewHTTPServer("", http.NotFoundHandler())
+	require.NoError(t, s.Bind(a))
+	assert.Equal(t, fmt.Sprintf("tcp://:%d", port), s.Spec)
+	assert.NoError(t, s.Shutdown(context.Background()))
+
+	a = newApp([]string{"PORT=http"})
+	a.Container = &app.Container{Runtime: "docker"}
+	assert.EqualError(t, app.NewHTTPServer("", http.NotFoundHandler()).Bind(a), `invalid PORT "http"`)
+}
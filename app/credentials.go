@@ -0,0 +1,40 @@
+package app
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/demosdemon/golang-app-framework/credstore"
+)
+
+// Credentials returns the store for the secrets of the user, such as the tokens of the services a command signs in
+// to: App.CredentialStore if set, else the keychain of the platform, keeping the secrets under the Name of the app.
+// Where there is no keychain, the secrets are kept in a file in the StateDir, encrypted with APP_CREDENTIALS_KEY, which
+// must then be set: a key derived from what else is on the host would be readable by whoever can read the file. The
+// store is chosen on first use and kept in App.CredentialStore.
+func (a *App) Credentials() (credstore.Store, error) {
+	a.credstoreMu.Lock()
+	defer a.credstoreMu.Unlock()
+
+	if a.CredentialStore != nil {
+		return a.CredentialStore, nil
+	}
+
+	service := a.programName()
+	if store := credstore.Keychain(service, a.LookupEnv); store != nil {
+		a.CredentialStore = store
+		return store, nil
+	}
+
+	dir, err := a.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := a.LookupEnv("APP_CREDENTIALS_KEY")
+	if !ok || key == "" {
+		return nil, errors.New("no keychain to keep the credentials in, set APP_CREDENTIALS_KEY to keep them in a file")
+	}
+
+	a.CredentialStore = credstore.NewFileStore(filepath.Join(dir, "credentials"), []byte(key))
+	return a.CredentialStore, nil
+}
@@ -0,0 +1,44 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/credstore"
+)
+
+func TestApp_Credentials(t *testing.T) {
+	a := newApp(nil)
+	memory := new(credstore.MemoryStore)
+	a.CredentialStore = memory
+	store, err := a.Credentials()
+	require.NoError(t, err)
+	assert.Same(t, memory, store)
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the keychain of the platform is used")
+	}
+	dir := t.TempDir()
+	_, err = newApp([]string{"APP_STATE_DIR=" + dir}).Credentials()
+	assert.EqualError(t, err, "no keychain to keep the credentials in, set APP_CREDENTIALS_KEY to keep them in a file")
+
+	a = newApp([]string{"APP_STATE_DIR=" + dir, "APP_CREDENTIALS_KEY=k"})
+	store, err = a.Credentials()
+	require.NoError(t, err)
+	assert.IsType(t, &credstore.FileStore{}, store)
+	require.NoError(t, store.Set("token", "s3cret"))
+
+	again, err := a.Credentials()
+	require.NoError(t, err)
+	assert.Same(t, store, again)
+	v, err := credstore.NewFileStore(filepath.Join(dir, "credentials"), []byte("k")).Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+	_, err = os.Stat(filepath.Join(dir, "credentials"))
+	assert.NoError(t, err)
+}
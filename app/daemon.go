@@ -0,0 +1,34 @@
+package app
+
+import "time"
+
+const (
+	// DaemonReadyEnv names the environment variable holding the file descriptor a daemon started by Daemonize
+	// reports its start up on.
+	DaemonReadyEnv = "APP_DAEMON_READY_FD"
+
+	// DaemonTimeout is how long Daemonize waits for the daemon to start.
+	DaemonTimeout = 30 * time.Second
+)
+
+// Daemonized reports whether this app is a daemon started by Daemonize.
+func (a *App) Daemonized() bool {
+	_, ok := a.LookupEnv(DaemonReadyEnv)
+	return ok
+}
+
+// daemonRequested reports whether the app was started with the --daemon argument or APP_DAEMON=true.
+func (a *App) daemonRequested() (bool, error) {
+	return a.modeRequested("--daemon", "APP_DAEMON")
+}
+
+// modeRequested reports whether the app was started with the argument arg or with the environment variable key set
+// to true.
+func (a *App) modeRequested(arg, key string) (bool, error) {
+	for _, v := range a.Arguments {
+		if v == arg {
+			return true, nil
+		}
+	}
+	return a.lookupBool(key)
+}
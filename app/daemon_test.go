@@ -0,0 +1,87 @@
+//go:build !windows
+// +build !windows
+
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const daemonChildEnv = "APP_TEST_DAEMON_CHILD"
+
+func TestApp_Daemonize(t *testing.T) {
+	if _, ok := os.LookupEnv(daemonChildEnv); ok {
+		daemonChild()
+		return
+	}
+
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "app.pid")
+	output := filepath.Join(dir, "app.log")
+	env := append(os.Environ(), daemonChildEnv+"=1", "APP_PID_FILE="+pidFile, "APP_DAEMON_OUTPUT="+output)
+
+	a := newApp(env, "-test.run=^TestApp_Daemonize$", "--daemon")
+	assert.False(t, a.Daemonized())
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.Daemonize()
+	})
+
+	pid, err := strconv.Atoi(strings.TrimSpace(a.Stdout.(*bytes.Buffer).String()))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		b, _ := os.ReadFile(output)
+		return strings.Contains(string(b), fmt.Sprintf("daemon %d in session %d\n", pid, pid))
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the daemon removes its pid file when it exits
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(pidFile)
+		return os.IsNotExist(err)
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestApp_Daemonize_AlreadyRunning(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "app.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644))
+
+	env := append(os.Environ(), daemonChildEnv+"=1", "APP_PID_FILE="+pidFile, "APP_DAEMON=true")
+	a := newApp(env, "-test.run=^TestApp_Daemonize$")
+	err := a.Daemonize()
+	assert.EqualError(t, err, fmt.Sprintf("daemon: already running as pid %d, see %s", os.Getpid(), pidFile))
+}
+
+func TestApp_Daemonize_NotRequested(t *testing.T) {
+	a := newApp(nil)
+	assert.NoError(t, a.Daemonize())
+	assert.Empty(t, a.Stdout.(*bytes.Buffer).String())
+
+	assert.EqualError(t, newApp([]string{"APP_DAEMON=sure"}).Daemonize(), `invalid APP_DAEMON "sure"`)
+}
+
+func daemonChild() {
+	a := app.New()
+	if err := a.Daemonize(); err != nil {
+		os.Exit(1)
+	}
+
+	sid, _, _ := syscall.RawSyscall(syscall.SYS_GETSID, 0, 0, 0)
+	b, _ := os.ReadFile(os.Getenv("APP_PID_FILE"))
+	if strings.TrimSpace(string(b)) == strconv.Itoa(os.Getpid()) {
+		_, _ = fmt.Fprintf(a.Stdout, "daemon %d in session %d\n", os.Getpid(), sid)
+	}
+	a.Exit(0)
+}
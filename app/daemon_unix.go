@@ -0,0 +1,215 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// Daemonize detaches the app from its terminal and session when it was started with the --daemon argument or
+// APP_DAEMON=true, and should be called first thing, before any output. It is configured by the environment:
+//
+//	APP_DAEMON_OUTPUT  where the daemon writes Stdout and Stderr: a file to append to, syslog, or /dev/null when unset
+//	APP_PID_FILE       the file the daemon writes its process ID to, removed when it exits
+//
+// Daemonize starts the daemon as a copy of the running executable in a new session, without the --daemon argument
+// and with Stdin reading from /dev/null, and waits for it to start. The parent then prints the daemon process ID on
+// Stdout and exits. In the daemon, Daemonize redirects Stdout and Stderr to syslog if configured, writes the PID file,
+// and returns. An error in the daemon is returned by both processes.
+func (a *App) Daemonize() error {
+	if a.Daemonized() {
+		return a.startedAsDaemon()
+	}
+
+	ok, err := a.daemonRequested()
+	if err != nil || !ok {
+		return err
+	}
+
+	pid, err := a.startDaemon()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(a.Output(), pid)
+	a.Exit(0)
+	return nil
+}
+
+func (a *App) startDaemon() (int, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(path)
+	for _, arg := range a.Arguments {
+		if arg != "--daemon" {
+			cmd.Args = append(cmd.Args, arg)
+		}
+	}
+	for _, line := range a.Environment {
+		if !strings.HasPrefix(line, "APP_DAEMON=") && !strings.HasPrefix(line, DaemonReadyEnv+"=") {
+			cmd.Env = append(cmd.Env, line)
+		}
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", DaemonReadyEnv, listenFDsStart))
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if output := a.trimmedEnv("APP_DAEMON_OUTPUT"); output != "" && output != "syslog" {
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+		if err != nil {
+			return 0, fmt.Errorf("invalid APP_DAEMON_OUTPUT: %v", err)
+		}
+		defer f.Close()
+		cmd.Stdout, cmd.Stderr = f, f
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	cmd.ExtraFiles = []*os.File{w}
+
+	err = cmd.Start()
+	// closing our copy lets the read below see EOF if the daemon dies
+	_ = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	status := make(chan string, 1)
+	go func() {
+		b, _ := io.ReadAll(r)
+		status <- string(b)
+	}()
+
+	timer := time.NewTimer(DaemonTimeout)
+	defer timer.Stop()
+
+	select {
+	case s := <-status:
+		switch {
+		case s == "ok":
+		case strings.HasPrefix(s, "error: "):
+			_ = cmd.Wait()
+			return 0, fmt.Errorf("daemon: %s", strings.TrimPrefix(s, "error: "))
+		default:
+			_ = cmd.Wait()
+			return 0, errors.New("daemon exited before it started")
+		}
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return 0, errors.New("timed out waiting for the daemon to start")
+	}
+
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	return pid, nil
+}
+
+// startedAsDaemon finishes starting the daemon and reports the result to the process that started it.
+func (a *App) startedAsDaemon() error {
+	v, _ := a.LookupEnv(DaemonReadyEnv)
+
+	// processes started by the daemon, e.g. by Upgrade, are not daemons themselves
+	env := make([]string, 0, len(a.Environment))
+	for _, line := range a.Environment {
+		if !strings.HasPrefix(line, DaemonReadyEnv+"=") {
+			env = append(env, line)
+		}
+	}
+	a.Environment = env
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %v", DaemonReadyEnv, v, err)
+	}
+	f := os.NewFile(uintptr(fd), "daemon-ready")
+	if f == nil {
+		return fmt.Errorf("invalid %s %q", DaemonReadyEnv, v)
+	}
+	defer f.Close()
+
+	if err := a.setupDaemon(); err != nil {
+		_, _ = fmt.Fprintf(f, "error: %v", err)
+		return err
+	}
+
+	_, err = f.WriteString("ok")
+	return err
+}
+
+func (a *App) setupDaemon() error {
+	if a.trimmedEnv("APP_DAEMON_OUTPUT") == "syslog" {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		tag := filepath.Base(exe)
+
+		stdout, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_NOTICE, tag)
+		if err != nil {
+			return fmt.Errorf("unable to connect to syslog: %v", err)
+		}
+		stderr, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_ERR, tag)
+		if err != nil {
+			_ = stdout.Close()
+			return fmt.Errorf("unable to connect to syslog: %v", err)
+		}
+		a.Stdout, a.Stderr = stdout, stderr
+	}
+
+	if path := a.trimmedEnv("APP_PID_FILE"); path != "" {
+		return a.writePIDFile(path)
+	}
+	return nil
+}
+
+// writePIDFile writes the process ID to path, refusing to replace the PID file of another running process, and
+// removes the file when the app exits.
+func (a *App) writePIDFile(path string) error {
+	pid := os.Getpid()
+	if b, err := os.ReadFile(path); err == nil {
+		other, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err == nil && other != pid && processExists(other) {
+			return fmt.Errorf("already running as pid %d, see %s", other, path)
+		}
+	}
+
+	if err := atomicfile.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("unable to write pid file: %v", err)
+	}
+
+	a.OnExit(func(int) {
+		// the file may have been taken over by another process since
+		if b, err := os.ReadFile(path); err == nil && strings.TrimSpace(string(b)) == strconv.Itoa(pid) {
+			_ = os.Remove(path)
+		}
+	})
+	return nil
+}
+
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
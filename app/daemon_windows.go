@@ -0,0 +1,13 @@
+package app
+
+import "errors"
+
+// Daemonize returns an error when the app was started with the --daemon argument or APP_DAEMON=true, since Windows
+// services are not started that way.
+func (a *App) Daemonize() error {
+	ok, err := a.daemonRequested()
+	if err != nil || !ok {
+		return err
+	}
+	return errors.New("daemon mode is not supported on windows")
+}
@@ -0,0 +1,84 @@
+package app
+
+// SetDefault registers the default value of the variable key, returned by LookupEnv when the variable is not set.
+// Defaults declared with DeclareConfig are used the same way; SetDefault takes precedence over them.
+func (a *App) SetDefault(key, value string) {
+	a.layersMu.Lock()
+	defer a.layersMu.Unlock()
+
+	if a.defaults == nil {
+		a.defaults = make(map[string]string)
+	}
+	a.defaults[key] = value
+}
+
+// SetDefaults registers the default values of several variables, as SetDefault.
+func (a *App) SetDefaults(defaults map[string]string) {
+	for key, value := range defaults {
+		a.SetDefault(key, value)
+	}
+}
+
+// SetFlag sets the variable key to the value of a command line flag, which LookupEnv returns in preference to the
+// environment. Subscribers of ConfigUpdates are told when the value changes.
+func (a *App) SetFlag(key, value string) {
+	a.layersMu.Lock()
+	if a.flags == nil {
+		a.flags = make(map[string]string)
+	}
+	old, ok := a.flags[key]
+	a.flags[key] = value
+	a.layersMu.Unlock()
+
+	if !ok || old != value {
+		a.publishConfig(string(SourceFlag), []string{key})
+	}
+}
+
+// Provenance returns where the value LookupEnv returns for key comes from: a flag, a file loaded into the
+// environment, a remote store, the environment itself, or a default. It returns an empty source if the variable is
+// not set and has no default.
+func (a *App) Provenance(key string) ConfigSource {
+	_, source, _ := a.lookupLayered(key)
+	if source != SourceEnv {
+		return source
+	}
+	switch {
+	case a.envFile(key) != "":
+		return SourceFile
+	case a.remoteSource(key) != "":
+		return SourceRemote
+	default:
+		return SourceEnv
+	}
+}
+
+// lookupLayered returns the value of the variable key, without expanding it, from the first layer that sets it: the
+// flags, the environment, then the defaults.
+func (a *App) lookupLayered(key string) (string, ConfigSource, bool) {
+	a.layersMu.Lock()
+	v, ok := a.flags[key]
+	a.layersMu.Unlock()
+	if ok {
+		return v, SourceFlag, true
+	}
+
+	if v, ok := a.lookupEnv(key); ok {
+		return v, SourceEnv, true
+	}
+
+	a.layersMu.Lock()
+	v, ok = a.defaults[key]
+	a.layersMu.Unlock()
+	if ok {
+		return v, SourceDefault, true
+	}
+
+	a.schemaMu.Lock()
+	declared, ok := a.schema[key]
+	a.schemaMu.Unlock()
+	if ok && declared.Default != "" {
+		return declared.Default, SourceDefault, true
+	}
+	return "", "", false
+}
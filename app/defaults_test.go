@@ -0,0 +1,51 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_SetDefault(t *testing.T) {
+	a := newApp([]string{"DB_HOST=db", "DB_URL=postgres://${DB_HOST}:${DB_PORT}/app"})
+	a.DeclareConfig("db",
+		app.ConfigKey{Name: "DB_HOST", Default: "localhost"},
+		app.ConfigKey{Name: "DB_PORT", Default: "5432"},
+		app.ConfigKey{Name: "DB_POOL", Default: "4"},
+		app.ConfigKey{Name: "DB_URL"},
+	)
+	a.SetDefaults(map[string]string{"DB_POOL": "8", "DB_TIMEOUT": "5s"})
+	a.ExpandVars("DB_URL")
+
+	env := func(key string) string {
+		v, ok := a.LookupEnv(key)
+		assert.True(t, ok, key)
+		return v
+	}
+	assert.Equal(t, "db", env("DB_HOST"))
+	assert.Equal(t, "5432", env("DB_PORT"), "defaults declared in the schema are used")
+	assert.Equal(t, "8", env("DB_POOL"), "SetDefault takes precedence over the schema")
+	assert.Equal(t, "5s", env("DB_TIMEOUT"))
+	assert.Equal(t, "postgres://db:5432/app", env("DB_URL"), "references resolve defaults")
+	_, ok := a.LookupEnv("DB_NAME")
+	assert.False(t, ok)
+
+	a.SetFlag("DB_HOST", "flagged")
+	assert.Equal(t, "flagged", env("DB_HOST"))
+	assert.Equal(t, "postgres://flagged:5432/app", env("DB_URL"))
+
+	assert.Equal(t, app.SourceFlag, a.Provenance("DB_HOST"))
+	assert.Equal(t, app.SourceEnv, a.Provenance("DB_URL"))
+	assert.Equal(t, app.SourceDefault, a.Provenance("DB_PORT"))
+	assert.Equal(t, app.SourceDefault, a.Provenance("DB_TIMEOUT"))
+	assert.Equal(t, app.ConfigSource(""), a.Provenance("DB_NAME"))
+
+	require.NoError(t, a.ConfigCommand([]string{"show"}))
+	out := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, out, "- module: db\n  name: DB_HOST\n  value: flagged\n  source: flag\n")
+	assert.Contains(t, out, "- module: db\n  name: DB_POOL\n  value: \"8\"\n  source: default\n")
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// deprecationState is the key of the State value recording when each deprecated feature was last warned about.
+const deprecationState = "deprecations"
+
+// Deprecate records a use of the deprecated feature, such as "the --bind flag" or "config key listen", to be removed
+// in removalVersion, if known, with a hint on what to do instead, such as "use --addr instead":
+//
+//	if opts.Bind != "" {
+//		if err := a.Deprecate("the --bind flag", "v3.0.0", "use --addr instead"); err != nil {
+//			return err
+//		}
+//	}
+//
+// The first use in a run is warned about: logged if the app registered servers, or else written to ErrOutput, at most
+// once a day, as recorded in the app State, so that a CLI run often does not nag its user. Every use is counted, see
+// Deprecations, and in the app_deprecated_uses_total{feature} counter. When APP_DEPRECATION_ERRORS is true, as in CI,
+// Deprecate returns the warning as an error instead.
+func (a *App) Deprecate(feature, removalVersion, hint string) error {
+	l := a.Localizer()
+	msg := l.T("app.deprecated", feature, hint)
+	if removalVersion != "" {
+		msg = l.T("app.deprecated.removal", feature, removalVersion, hint)
+	}
+
+	a.Metrics().Counter("app_deprecated_uses_total", metrics.Labels{"feature": feature}).Inc()
+	a.deprecationsMu.Lock()
+	if a.deprecations == nil {
+		a.deprecations = make(map[string]int)
+	}
+	a.deprecations[feature]++
+	first := a.deprecations[feature] == 1
+	a.deprecationsMu.Unlock()
+
+	if fatal, _ := a.lookupBool("APP_DEPRECATION_ERRORS"); fatal {
+		return errors.New(msg)
+	}
+	if !first {
+		return nil
+	}
+
+	a.serversMu.Lock()
+	server := len(a.servers) > 0
+	a.serversMu.Unlock()
+	if server {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"feature": feature, "removal": removalVersion})
+		_ = a.Logger().Warnm(attrs, "%s", msg)
+		return nil
+	}
+
+	if a.warnedToday(feature) {
+		return nil
+	}
+	a.warn(msg)
+	return nil
+}
+
+// warnedToday reports whether feature was warned about in the last day, recording that it is now otherwise. Without
+// a State, every run warns.
+func (a *App) warnedToday(feature string) bool {
+	s, err := a.State()
+	if err != nil {
+		return false
+	}
+
+	var warned map[string]time.Time
+	if _, err := s.Get(deprecationState, &warned); err != nil || warned == nil {
+		warned = make(map[string]time.Time)
+	}
+	now := a.clock().Now().UTC()
+	if last, ok := warned[feature]; ok && now.Sub(last) < 24*time.Hour {
+		return true
+	}
+	warned[feature] = now
+	_ = s.Set(deprecationState, warned)
+	return false
+}
+
+// Deprecations returns how many times each deprecated feature was used in this run, see Deprecate.
+func (a *App) Deprecations() map[string]int {
+	a.deprecationsMu.Lock()
+	defer a.deprecationsMu.Unlock()
+
+	res := make(map[string]int, len(a.deprecations))
+	for feature, n := range a.deprecations {
+		res[feature] = n
+	}
+	return res
+}
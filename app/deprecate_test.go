@@ -0,0 +1,59 @@
+package app_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efritz/glock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+func TestApp_Deprecate(t *testing.T) {
+	dir := t.TempDir()
+	clock := glock.NewMockClockAt(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+	run := func() string {
+		a := newApp([]string{"APP_STATE_DIR=" + dir})
+		a.Clock = clock
+		assert.NoError(t, a.Deprecate("the --bind flag", "v3.0.0", "use --addr instead"))
+		assert.NoError(t, a.Deprecate("the --bind flag", "v3.0.0", "use --addr instead"))
+		assert.Equal(t, map[string]int{"the --bind flag": 2}, a.Deprecations())
+		assert.Equal(t, 2.0, a.Metrics().Counter("app_deprecated_uses_total",
+			metrics.Labels{"feature": "the --bind flag"}).Value())
+		return a.Stderr.(*bytes.Buffer).String()
+	}
+
+	const warning = "warning: the --bind flag is deprecated and will be removed in v3.0.0, use --addr instead\n"
+	assert.Equal(t, warning, run())
+
+	// the user was warned today
+	clock.Advance(23 * time.Hour)
+	assert.Empty(t, run())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, warning, run())
+}
+
+func TestApp_Deprecate_Server(t *testing.T) {
+	a := newApp(nil)
+	a.Register("worker", newFakeServer("worker", new(recorder)))
+	assert.NoError(t, a.Deprecate("config key listen", "", "set APP_ADDR instead"))
+	assert.NoError(t, a.Deprecate("config key listen", "", "set APP_ADDR instead"))
+
+	_ = a.Logger().ShutdownLoggers()
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Equal(t, 1, strings.Count(logs, "config key listen is deprecated, set APP_ADDR instead"))
+	assert.Regexp(t, `WARN.*\] config key listen is deprecated, set APP_ADDR instead \{.*"feature":"config key listen"`,
+		logs)
+}
+
+func TestApp_Deprecate_Errors(t *testing.T) {
+	a := newApp([]string{"APP_DEPRECATION_ERRORS=true"})
+	assert.EqualError(t, a.Deprecate("the --bind flag", "", "use --addr instead"),
+		"the --bind flag is deprecated, use --addr instead")
+	assert.Equal(t, map[string]int{"the --bind flag": 1}, a.Deprecations())
+	assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/devcert"
+)
+
+// Development reports whether the app runs for local development: APP_ENV is development.
+func (a *App) Development() bool {
+	v, _ := a.LookupEnv("APP_ENV")
+	return strings.EqualFold(strings.TrimSpace(v), "development")
+}
+
+// useDevCert sets the TLSConfig of the server to present a development certificate for the local machine, issued by
+// the devcert.Authority in the DataDir, unless APP_HTTP_DEVCERT is false.
+func (s *HTTPServer) useDevCert(a *App) error {
+	if v, ok := a.LookupEnv("APP_HTTP_DEVCERT"); ok && v != "" {
+		on, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid APP_HTTP_DEVCERT %q", v)
+		}
+		if !on {
+			return nil
+		}
+	}
+
+	dir, err := a.DataDir()
+	if err != nil {
+		return err
+	}
+	ca, err := devcert.Load(filepath.Join(dir, "devcert"))
+	if err != nil {
+		return err
+	}
+	hosts := append([]string(nil), devcert.DefaultHosts...)
+	if host, err := os.Hostname(); err == nil {
+		hosts = append(hosts, host)
+	}
+	config, err := ca.TLSConfig(hosts...)
+	if err != nil {
+		return err
+	}
+	s.TLSConfig = config
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"ca": ca.CertFile(), "hosts": hosts})
+	_ = a.Logger().Infom(attrs, "serving HTTPS with a development certificate, trust %s to avoid warnings",
+		ca.CertFile())
+	return nil
+}
@@ -0,0 +1,61 @@
+package app_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/devcert"
+)
+
+func TestHTTPServer_DevCert(t *testing.T) {
+	dataDir := t.TempDir()
+	a := newApp([]string{"APP_ENV=development", "APP_DATA_DIR=" + dataDir})
+	assert.True(t, a.Development())
+
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	require.NoError(t, s.Bind(a))
+	require.NotNil(t, s.TLSConfig)
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	ca, err := devcert.Load(filepath.Join(dataDir, "devcert"))
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: ca.CertPool()},
+		ForceAttemptHTTP2: true,
+	}}
+	res, err := client.Get("https://" + s.ListenerAddr().String())
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+
+	// turned off, or not in development
+	for _, env := range [][]string{
+		{"APP_ENV=development", "APP_HTTP_DEVCERT=false", "APP_DATA_DIR=" + dataDir},
+		{"APP_ENV=production", "APP_DATA_DIR=" + dataDir},
+	} {
+		s := app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+		require.NoError(t, s.Bind(newApp(env)))
+		assert.Nil(t, s.TLSConfig)
+		assert.NoError(t, s.Shutdown(context.Background()))
+	}
+
+	s = app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+	err = s.Bind(newApp([]string{"APP_ENV=development", "APP_HTTP_DEVCERT=maybe"}))
+	assert.EqualError(t, err, `invalid APP_HTTP_DEVCERT "maybe"`)
+}
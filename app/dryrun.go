@@ -0,0 +1,37 @@
+package app
+
+import (
+	"github.com/aphistic/gomol"
+)
+
+// DryRun reports whether the app was asked, with the --dry-run flag, its DryRunShort name, or APP_DRY_RUN, to report
+// the changes it would make rather than make them. Commands make their changes with Mutate to honor it.
+func (a *App) DryRun() bool {
+	on, _ := a.lookupBool("APP_DRY_RUN")
+	return on
+}
+
+// Mutate calls fn, which makes the change desc describes, such as "delete 3 stale backups", and returns its error.
+// In a DryRun, fn is not called; the change is logged instead, and also written to InfoOutput:
+//
+//	for _, backup := range stale {
+//		err := a.Mutate("delete backup "+backup.Name, func() error {
+//			return os.Remove(backup.Path)
+//		})
+//		if err != nil {
+//			return err
+//		}
+//	}
+//
+// Read only work, such as finding the stale backups, stays outside of Mutate so that a dry run shows what a real one
+// would do.
+func (a *App) Mutate(desc string, fn func() error) error {
+	if !a.DryRun() {
+		return fn()
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"dry_run": true})
+	_ = a.Logger().Infom(attrs, "dry run, skipped: %s", desc)
+	_, _ = a.InfoOutput().Write([]byte("would " + desc + "\n"))
+	return nil
+}
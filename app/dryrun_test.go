@@ -0,0 +1,69 @@
+package app_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Mutate(t *testing.T) {
+	for _, args := range [][]string{{"prune"}, {"-n", "prune"}, {"prune", "--dry-run"}} {
+		a := newApp(nil, args...)
+		a.DryRunShort = "n"
+		var deleted []string
+		a.AddCommand(app.NewCommand("prune", "", func(a *app.App, _ *struct{}, _ []string) error {
+			for _, name := range []string{"a", "b"} {
+				err := a.Mutate("delete backup "+name, func() error {
+					deleted = append(deleted, name)
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		require.NoError(t, a.Dispatch())
+		a.Logger().ShutdownLoggers()
+
+		if len(args) == 1 {
+			assert.False(t, a.DryRun())
+			assert.Equal(t, []string{"a", "b"}, deleted)
+			assert.Empty(t, a.Stdout.(*bytes.Buffer).String())
+			continue
+		}
+		assert.True(t, a.DryRun())
+		assert.Empty(t, deleted)
+		assert.Equal(t, "would delete backup a\nwould delete backup b\n", a.Stdout.(*bytes.Buffer).String())
+		assert.Regexp(t, regexp.MustCompile(`(?m)INFO.*\] dry run, skipped: delete backup b {"dry_run":true`),
+			a.Stderr.(*bytes.Buffer).String())
+	}
+
+	assert.True(t, newApp([]string{"APP_DRY_RUN=1"}).DryRun())
+}
+
+func TestApp_DryRunShort(t *testing.T) {
+	a := newApp(nil, "prune", "-n")
+	a.AddCommand(app.NewCommand("prune", "", func(*app.App, *struct{}, []string) error { return nil }))
+	assert.EqualError(t, a.Dispatch(), "unknown flag -n")
+	assert.False(t, a.DryRun())
+
+	type options struct {
+		Name string `flag:"name,n,the backup to prune"`
+	}
+	a = newApp(nil, "-n", "prune", "-n", "daily")
+	a.DryRunShort = "n"
+	var name string
+	a.AddCommand(app.NewCommand("prune", "", func(_ *app.App, opts *options, _ []string) error {
+		name = opts.Name
+		return nil
+	}))
+	require.NoError(t, a.Dispatch())
+	assert.True(t, a.DryRun())
+	assert.Equal(t, "daily", name, "a flag of the command takes precedence")
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigSource is where the effective value of a variable came from.
+type ConfigSource string
+
+const (
+	// SourceDefault is the default value in the ConfigSchema, used when the variable is not set.
+	SourceDefault ConfigSource = "default"
+
+	// SourceEnv is the app Environment.
+	SourceEnv ConfigSource = "env"
+
+	// SourceFile is a file loaded into the app Environment by LoadEnvFile.
+	SourceFile ConfigSource = "file"
+
+	// SourceRemote is a remote configuration store whose values were applied with Reload.
+	SourceRemote ConfigSource = "remote"
+
+	// SourceFlag is a command line flag, set with SetFlag.
+	SourceFlag ConfigSource = "flag"
+)
+
+// ConfigValue is the effective value of a variable in the ConfigSchema.
+type ConfigValue struct {
+	Module string       `json:"module" yaml:"module"`
+	Name   string       `json:"name" yaml:"name"`
+	Value  string       `json:"value" yaml:"value"`
+	Source ConfigSource `json:"source" yaml:"source"`
+}
+
+// EffectiveConfig returns the value of every variable in the ConfigSchema that is set or has a default, in the order
+// of the schema, with references expanded and secrets redacted: the values of variables declared Secret or encrypted,
+// and those Environ.Redacted would hide. A schema entry ending in * stands for every variable set with its prefix
+// that is not declared itself.
+func (a *App) EffectiveConfig() []ConfigValue {
+	env := a.environ().Snapshot()
+
+	values := []ConfigValue{}
+	add := func(key ConfigKey, name string) {
+		v, ok := a.LookupEnv(name)
+		source := a.Provenance(name)
+		switch {
+		case !ok && key.Default == "":
+			return
+		case !ok:
+			v, source = key.Default, SourceDefault
+		}
+		if key.Secret || a.encrypted(name) {
+			v = Redacted
+		}
+		values = append(values, ConfigValue{Module: key.Module, Name: name, Value: redactEnv(name, v), Source: source})
+	}
+
+	schema := a.ConfigSchema()
+	declared := make(map[string]bool, len(schema))
+	for _, key := range schema {
+		declared[key.Name] = true
+	}
+
+	for _, key := range schema {
+		prefix, ok := strings.CutSuffix(key.Name, "*")
+		if !ok {
+			add(key, key.Name)
+			continue
+		}
+		for _, line := range env {
+			if name, _ := splitEnv(line); strings.HasPrefix(name, prefix) && !declared[name] {
+				add(key, name)
+			}
+		}
+	}
+	return values
+}
+
+func writeEffectiveConfig(w io.Writer, values []ConfigValue, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(values)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(values); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,70 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_EffectiveConfig(t *testing.T) {
+	a := newApp([]string{
+		"APP_SHUTDOWN_TIMEOUT=${TIMEOUT}",
+		"TIMEOUT=5s",
+		"APP_CACHE_TTL=1m",
+		"APP_CACHE_PASSWORD=hunter2",
+		"APP_DB_URL=postgres://db/app",
+	})
+	a.ExpandVars("APP_SHUTDOWN_TIMEOUT")
+	a.DeclareConfig("db",
+		app.ConfigKey{Name: "APP_DB_URL", Secret: true},
+		app.ConfigKey{Name: "APP_DB_POOL", Default: "4"},
+		app.ConfigKey{Name: "APP_DB_NAME"},
+	)
+	a.DeclareConfig("cache", app.ConfigKey{Name: "APP_CACHE_*"})
+
+	values := a.EffectiveConfig()
+	find := func(name string) *app.ConfigValue {
+		for idx := range values {
+			if values[idx].Name == name {
+				return &values[idx]
+			}
+		}
+		return nil
+	}
+
+	assert.Equal(t, &app.ConfigValue{Module: "app", Name: "APP_SHUTDOWN_TIMEOUT", Value: "5s", Source: app.SourceEnv},
+		find("APP_SHUTDOWN_TIMEOUT"))
+	assert.Equal(t, &app.ConfigValue{Module: "app", Name: "APP_CHILD_STOP_TIMEOUT", Value: "5s",
+		Source: app.SourceDefault}, find("APP_CHILD_STOP_TIMEOUT"))
+	assert.Equal(t, &app.ConfigValue{Module: "cache", Name: "APP_CACHE_TTL", Value: "1m", Source: app.SourceEnv},
+		find("APP_CACHE_TTL"))
+	assert.Equal(t, app.Redacted, find("APP_CACHE_PASSWORD").Value)
+	assert.Equal(t, app.Redacted, find("APP_DB_URL").Value)
+	assert.Equal(t, &app.ConfigValue{Module: "db", Name: "APP_DB_POOL", Value: "4", Source: app.SourceDefault},
+		find("APP_DB_POOL"))
+	assert.Nil(t, find("APP_DB_NAME"))
+	assert.Nil(t, find("TIMEOUT"))
+}
+
+func TestApp_ConfigCommand_Show(t *testing.T) {
+	env := []string{"APP_DB_URL=postgres://db/app", "APP_CACHE_TTL=1m"}
+	a := newApp(env)
+	a.DeclareConfig("db", app.ConfigKey{Name: "APP_DB_URL", Secret: true})
+	a.DeclareConfig("cache", app.ConfigKey{Name: "APP_CACHE_*"})
+	require.NoError(t, a.ConfigCommand([]string{"show"}))
+	out := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, out, "- module: cache\n  name: APP_CACHE_TTL\n  value: 1m\n  source: env\n")
+	assert.Contains(t, out, "- module: db\n  name: APP_DB_URL\n  value: '[REDACTED]'\n  source: env\n")
+	assert.NotContains(t, out, "postgres")
+
+	a = newApp(env)
+	require.NoError(t, a.ConfigCommand([]string{"show", "--json"}))
+	var decoded []app.ConfigValue
+	require.NoError(t, json.Unmarshal(a.Stdout.(*bytes.Buffer).Bytes(), &decoded))
+	assert.Equal(t, a.EffectiveConfig(), decoded)
+}
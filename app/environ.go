@@ -0,0 +1,181 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the values of secret environment variables when they are printed.
+const Redacted = "[REDACTED]"
+
+// secretWords are the words of an environment variable name, separated by underscores, that mark its value secret.
+var secretWords = map[string]bool{
+	"APIKEY":      true,
+	"CREDENTIAL":  true,
+	"CREDENTIALS": true,
+	"KEY":         true,
+	"PASS":        true,
+	"PASSWD":      true,
+	"PASSWORD":    true,
+	"PRIVATE":     true,
+	"SECRET":      true,
+	"TOKEN":       true,
+}
+
+// Environ is a list of environment variables in the form KEY=value, as in App.Environment.
+type Environ []string
+
+// EnvChange is an environment variable that differs between two Environs. Old is empty for an added variable and
+// New for a removed one.
+type EnvChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// EnvDiff is the difference between two Environs, each part sorted by key.
+type EnvDiff struct {
+	Added   []EnvChange
+	Removed []EnvChange
+	Changed []EnvChange
+}
+
+// Snapshot returns a copy of the environment sorted by key, keeping only the last of repeated keys, as a Child
+// would see them, and dropping entries without an equals sign.
+func (e Environ) Snapshot() Environ {
+	m := e.values()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	snapshot := make(Environ, len(keys))
+	for idx, k := range keys {
+		snapshot[idx] = k + "=" + m[k]
+	}
+	return snapshot
+}
+
+// Redacted returns the Snapshot with the values of secret variables replaced: those with a name containing a word
+// such as KEY, PASSWORD, SECRET, or TOKEN, and the password in those holding a URL.
+func (e Environ) Redacted() Environ {
+	snapshot := e.Snapshot()
+	for idx, line := range snapshot {
+		k, v := splitEnv(line)
+		snapshot[idx] = k + "=" + redactEnv(k, v)
+	}
+	return snapshot
+}
+
+// Diff returns the variables added, removed, and changed in other compared to e, with secret values redacted. A
+// secret that changed is reported as changed even though both values read Redacted.
+func (e Environ) Diff(other Environ) EnvDiff {
+	before, after := e.values(), other.values()
+
+	var d EnvDiff
+	for k, v := range after {
+		old, ok := before[k]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, EnvChange{Key: k, New: redactEnv(k, v)})
+		case old != v:
+			d.Changed = append(d.Changed, EnvChange{Key: k, Old: redactEnv(k, old), New: redactEnv(k, v)})
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			d.Removed = append(d.Removed, EnvChange{Key: k, Old: redactEnv(k, v)})
+		}
+	}
+
+	for _, changes := range [][]EnvChange{d.Added, d.Removed, d.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	}
+	return d
+}
+
+// Empty reports whether the Environs were the same.
+func (d EnvDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns the difference one variable per line: +KEY=value when added, -KEY=value when removed, and
+// ~KEY=old -> new when changed.
+func (d EnvDiff) String() string {
+	var b strings.Builder
+	for _, c := range d.Added {
+		_, _ = fmt.Fprintf(&b, "+%s=%s\n", c.Key, c.New)
+	}
+	for _, c := range d.Removed {
+		_, _ = fmt.Fprintf(&b, "-%s=%s\n", c.Key, c.Old)
+	}
+	for _, c := range d.Changed {
+		_, _ = fmt.Fprintf(&b, "~%s=%s -> %s\n", c.Key, c.Old, c.New)
+	}
+	return b.String()
+}
+
+// PrintEnvironment prints the Redacted app Environment to Output, one variable per line, with the variables declared
+// Secret or encrypted redacted too, and exits when the app was started with the --print-env argument or
+// APP_PRINT_ENV=true. Call it early, after any configuration of the environment, to see what the app sees. When not
+// requested, PrintEnvironment returns nil.
+func (a *App) PrintEnvironment() error {
+	ok, err := a.modeRequested("--print-env", "APP_PRINT_ENV")
+	if err != nil || !ok {
+		return err
+	}
+
+	secret := make(map[string]bool)
+	for _, key := range a.ConfigSchema() {
+		secret[key.Name] = key.Secret
+	}
+
+	w := a.Output()
+	for _, line := range a.environ().Redacted() {
+		if k, _ := splitEnv(line); secret[k] || a.encrypted(k) {
+			line = k + "=" + Redacted
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	a.Exit(0)
+	return nil
+}
+
+func (e Environ) values() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, line := range e {
+		if k, v := splitEnv(line); k != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// splitEnv splits KEY=value, returning an empty key if there is no equals sign.
+func splitEnv(line string) (string, string) {
+	idx := strings.IndexByte(line, '=')
+	if idx <= 0 {
+		return "", ""
+	}
+	return line[:idx], line[idx+1:]
+}
+
+func redactEnv(key, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, word := range strings.Split(strings.ToUpper(key), "_") {
+		if secretWords[word] {
+			return Redacted
+		}
+	}
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+	}
+	return value
+}
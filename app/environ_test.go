@@ -0,0 +1,65 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestEnviron_Snapshot(t *testing.T) {
+	env := app.Environ{"B=2", "A=1", "invalid", "=x", "B=3", "EMPTY="}
+	assert.Equal(t, app.Environ{"A=1", "B=3", "EMPTY="}, env.Snapshot())
+	assert.Equal(t, "B=2", env[0], "original is unchanged")
+}
+
+func TestEnviron_Redacted(t *testing.T) {
+	env := app.Environ{
+		"API_TOKEN=abc",
+		"DB_PASSWORD=hunter2",
+		"DATABASE_URL=postgres://user:hunter2@db:5432/app",
+		"KEYBOARD=us",
+		"NO_SECRET=",
+		"PLAIN_URL=https://example.com/path",
+	}
+	assert.Equal(t, app.Environ{
+		"API_TOKEN=[REDACTED]",
+		"DATABASE_URL=postgres://user:xxxxx@db:5432/app",
+		"DB_PASSWORD=[REDACTED]",
+		"KEYBOARD=us",
+		"NO_SECRET=",
+		"PLAIN_URL=https://example.com/path",
+	}, env.Redacted())
+}
+
+func TestEnviron_Diff(t *testing.T) {
+	before := app.Environ{"A=1", "B=2", "SECRET_KEY=old", "GONE=x"}
+	after := app.Environ{"B=3", "A=1", "SECRET_KEY=new", "NEW=y"}
+
+	d := before.Diff(after)
+	assert.Equal(t, app.EnvDiff{
+		Added:   []app.EnvChange{{Key: "NEW", New: "y"}},
+		Removed: []app.EnvChange{{Key: "GONE", Old: "x"}},
+		Changed: []app.EnvChange{
+			{Key: "B", Old: "2", New: "3"},
+			{Key: "SECRET_KEY", Old: app.Redacted, New: app.Redacted},
+		},
+	}, d)
+	assert.False(t, d.Empty())
+	assert.Equal(t, "+NEW=y\n-GONE=x\n~B=2 -> 3\n~SECRET_KEY=[REDACTED] -> [REDACTED]\n", d.String())
+
+	assert.True(t, before.Diff(before.Snapshot()).Empty())
+}
+
+func TestApp_PrintEnvironment(t *testing.T) {
+	assert.NoError(t, newApp([]string{"A=1"}).PrintEnvironment())
+	assert.EqualError(t, newApp([]string{"APP_PRINT_ENV=often"}).PrintEnvironment(), `invalid APP_PRINT_ENV "often"`)
+
+	a := newApp([]string{"B=2", "A_TOKEN=abc"}, "--print-env")
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.PrintEnvironment()
+	})
+	assert.Equal(t, "A_TOKEN=[REDACTED]\nB=2\n", a.Stdout.(*bytes.Buffer).String())
+}
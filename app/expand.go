@@ -0,0 +1,212 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Expand replaces references to variables in s with their values from lookup, as docker-compose does:
+//
+//	$NAME, ${NAME}   the value of NAME, or empty if it is not set
+//	${NAME:-value}   the value of NAME, or value if it is not set or empty
+//	${NAME-value}    the value of NAME, or value if it is not set
+//	${NAME:?error}   the value of NAME, or an error if it is not set or empty
+//	${NAME?error}    the value of NAME, or an error if it is not set
+//	$$               a literal $
+//
+// The default values and errors are expanded too. The values from lookup are taken literally; use App.ExpandEnv to
+// expand the references in them as well.
+func Expand(s string, lookup func(string) (string, bool)) (string, error) {
+	e := &expander{lookup: func(name string) (string, bool, error) {
+		v, ok := lookup(name)
+		return v, ok, nil
+	}}
+	return e.expand(s)
+}
+
+// ExpandEnv returns the value of the environment variable key with the references in it expanded by Expand, and the
+// references in the values of those variables too. A variable that refers back to itself, directly or through
+// others, is an error. Values encrypted with age, written as age: and the base64 encoded age file, are decrypted with
+// the key in APP_AGE_KEY or APP_AGE_KEY_FILE.
+func (a *App) ExpandEnv(key string) (string, bool, error) {
+	v, ok, err := a.lookupDecrypted(key)
+	if err != nil || !ok || !strings.Contains(v, "$") {
+		return v, ok, err
+	}
+
+	e := &expander{stack: []string{key}}
+	e.lookup = func(name string) (string, bool, error) {
+		for idx, seen := range e.stack {
+			if seen == name {
+				cycle := append(append([]string(nil), e.stack[idx:]...), name)
+				return "", false, fmt.Errorf("cycle %s", strings.Join(cycle, " -> "))
+			}
+		}
+
+		v, ok, err := a.lookupDecrypted(name)
+		if err != nil || !ok {
+			return "", false, err
+		}
+
+		e.stack = append(e.stack, name)
+		defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+		v, err = e.expand(v)
+		return v, true, err
+	}
+
+	v, err = e.expand(v)
+	if err != nil {
+		return "", false, fmt.Errorf("expand %s: %w", key, err)
+	}
+	return v, true, nil
+}
+
+// ExpandVars marks the variables whose values LookupEnv expands as by ExpandEnv. The values of other variables are
+// taken literally, so that a password such as pa$word is not mangled. A variable that fails to expand, because of a
+// ${NAME:?error} reference or a cycle, is reported by ConfigErrors.
+func (a *App) ExpandVars(keys ...string) {
+	a.layersMu.Lock()
+	defer a.layersMu.Unlock()
+	if a.expand == nil {
+		a.expand = make(map[string]bool)
+	}
+	for _, key := range keys {
+		a.expand[key] = true
+	}
+}
+
+// expandErrors returns the errors of expanding the variables passed to ExpandVars.
+func (a *App) expandErrors() ConfigErrors {
+	a.layersMu.Lock()
+	keys := make([]string, 0, len(a.expand))
+	for key := range a.expand {
+		keys = append(keys, key)
+	}
+	a.layersMu.Unlock()
+	sort.Strings(keys)
+
+	var errs ConfigErrors
+	for _, key := range keys {
+		if _, _, err := a.ExpandEnv(key); err != nil {
+			errs = append(errs, &FieldError{Module: "app", Err: err})
+		}
+	}
+	return errs
+}
+
+type expander struct {
+	lookup func(name string) (string, bool, error)
+	stack  []string
+}
+
+func (e *expander) expand(s string) (string, error) {
+	var b strings.Builder
+	for {
+		idx := strings.IndexByte(s, '$')
+		if idx < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:idx])
+		s = s[idx+1:]
+
+		switch {
+		case strings.HasPrefix(s, "$"):
+			b.WriteByte('$')
+			s = s[1:]
+		case strings.HasPrefix(s, "{"):
+			end := closingBrace(s)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", "$"+s)
+			}
+			v, err := e.braced(s[1:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			s = s[end+1:]
+		default:
+			n := nameLength(s)
+			if n == 0 {
+				b.WriteByte('$')
+				continue
+			}
+			v, _, err := e.lookup(s[:n])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(v)
+			s = s[n:]
+		}
+	}
+}
+
+// braced expands the reference between the braces of ${...}.
+func (e *expander) braced(ref string) (string, error) {
+	n := nameLength(ref)
+	if n == 0 {
+		return "", fmt.Errorf("invalid reference ${%s}", ref)
+	}
+	name, op := ref[:n], ref[n:]
+
+	v, ok, err := e.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	if op == "" {
+		return v, nil
+	}
+	if strings.HasPrefix(op, ":") {
+		ok = ok && v != ""
+		op = op[1:]
+	}
+	if op == "" || op[0] != '-' && op[0] != '?' {
+		return "", fmt.Errorf("invalid reference ${%s}", ref)
+	}
+	if ok {
+		return v, nil
+	}
+
+	arg, err := e.expand(op[1:])
+	switch {
+	case err != nil:
+		return "", err
+	case op[0] == '-':
+		return arg, nil
+	case arg == "":
+		return "", fmt.Errorf("%s is not set", name)
+	default:
+		return "", errors.New(name + ": " + arg)
+	}
+}
+
+// closingBrace returns the index of the brace closing the one s starts with, or -1.
+func closingBrace(s string) int {
+	depth := 0
+	for idx := 0; idx < len(s); idx++ {
+		switch s[idx] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// nameLength returns the length of the variable name s starts with.
+func nameLength(s string) int {
+	for idx := 0; idx < len(s); idx++ {
+		c := s[idx]
+		if c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || idx > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+		return idx
+	}
+	return len(s)
+}
@@ -0,0 +1,122 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{"HOST": "db", "PORT": "5432", "EMPTY": "", "REF": "${HOST}"}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := map[string]string{
+		"plain":                   "plain",
+		"$HOST:$PORT":             "db:5432",
+		"${HOST}_x":               "db_x",
+		"$HOST_x":                 "",
+		"${MISSING}":              "",
+		"${MISSING:-fallback}":    "fallback",
+		"${EMPTY:-fallback}":      "fallback",
+		"${EMPTY-fallback}":       "",
+		"${MISSING-${HOST}:1}":    "db:1",
+		"${HOST:?required}":       "db",
+		"$$HOST costs $5 and $":   "$HOST costs $5 and $",
+		"${REF}":                  "${HOST}",
+		"postgres://$HOST/${X:-}": "postgres://db/",
+	}
+	for in, expected := range tests {
+		actual, err := app.Expand(in, lookup)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, actual, in)
+	}
+
+	errs := map[string]string{
+		"${HOST":              `unterminated ${ in "${HOST"`,
+		"${}":                 "invalid reference ${}",
+		"${1X}":               "invalid reference ${1X}",
+		"${HOST:+alt}":        "invalid reference ${HOST:+alt}",
+		"${MISSING?}":         "MISSING is not set",
+		"${EMPTY:?set EMPTY}": "EMPTY: set EMPTY",
+	}
+	for in, expected := range errs {
+		_, err := app.Expand(in, lookup)
+		assert.EqualError(t, err, expected, in)
+	}
+}
+
+func TestApp_ExpandEnv(t *testing.T) {
+	a := newApp([]string{
+		"DB_HOST=db",
+		"DB_URL=postgres://${DB_HOST}:${DB_PORT:-5432}/app",
+		"APP_DSN=$DB_URL?sslmode=disable",
+		"PRICE=$$5",
+		"A=${B}",
+		"B=x${C:-$A}",
+		"SELF=${SELF:-x}",
+	})
+
+	v, ok, err := a.ExpandEnv("APP_DSN")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "postgres://db:5432/app?sslmode=disable", v)
+
+	v, ok, err = a.ExpandEnv("PRICE")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "$5", v)
+
+	_, ok, err = a.ExpandEnv("MISSING")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = a.ExpandEnv("A")
+	assert.EqualError(t, err, "expand A: cycle A -> B -> A")
+	_, _, err = a.ExpandEnv("SELF")
+	assert.EqualError(t, err, "expand SELF: cycle SELF -> SELF")
+
+	// LookupEnv takes the values literally unless asked to expand them
+	v, ok = a.LookupEnv("APP_DSN")
+	assert.True(t, ok)
+	assert.Equal(t, "$DB_URL?sslmode=disable", v)
+	assert.NoError(t, a.ConfigErrors())
+}
+
+func TestApp_ExpandVars(t *testing.T) {
+	a := newApp([]string{
+		"DB_HOST=db",
+		"DB_URL=postgres://${DB_HOST}/app",
+		"PW=pa$word",
+		"PRICE=$$5",
+		"A=${B}",
+		"B=x${C:-$A}",
+		"REQUIRED=${UNSET:?set UNSET}",
+	})
+	a.ExpandVars("DB_URL", "PRICE", "A", "REQUIRED")
+
+	v, ok := a.LookupEnv("DB_URL")
+	assert.True(t, ok)
+	assert.Equal(t, "postgres://db/app", v)
+
+	v, ok = a.LookupEnv("PRICE")
+	assert.True(t, ok)
+	assert.Equal(t, "$5", v)
+
+	v, ok = a.LookupEnv("PW")
+	assert.True(t, ok)
+	assert.Equal(t, "pa$word", v)
+
+	_, ok = a.LookupEnv("A")
+	assert.False(t, ok)
+	_, ok = a.LookupEnv("REQUIRED")
+	assert.False(t, ok)
+
+	assert.EqualError(t, a.ConfigErrors(), "invalid configuration, 2 errors:\n"+
+		"\tapp: expand A: cycle A -> B -> A\n"+
+		"\tapp: expand REQUIRED: UNSET: set UNSET")
+}
@@ -0,0 +1,28 @@
+package app
+
+import "io"
+
+// SetInteractive makes Interactive report on, as if Stdin and Stderr were terminals or not.
+func SetInteractive(a *App, on bool) {
+	a.interactive = &on
+}
+
+// DetectContainer detects the container from the file system at root and the variables in env.
+func DetectContainer(root string, env map[string]string) Container {
+	return detectContainer(root, func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+}
+
+// WriteBannerTable renders b on w as Run does on a terminal.
+func WriteBannerTable(a *App, w io.Writer, b Banner) {
+	writeBannerTable(w, a.Localizer(), b)
+}
+
+// ExitHooks returns the number of OnExit functions registered.
+func ExitHooks(a *App) int {
+	a.exitMu.Lock()
+	defer a.exitMu.Unlock()
+	return len(a.exitHooks)
+}
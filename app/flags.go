@@ -0,0 +1,439 @@
+package app
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrHelp is returned by ParseFlags when the arguments ask for help with -h or --help.
+var ErrHelp = errors.New("help requested")
+
+// Flag describes a command line flag declared by a struct field, as shown in usage text and offered as a
+// completion.
+type Flag struct {
+	Name    string   `json:"name"`              // the long name, used as --name
+	Short   string   `json:"short,omitempty"`   // the one letter name, used as -s
+	Usage   string   `json:"usage,omitempty"`   // what the flag does
+	Type    string   `json:"type"`              // the kind of value, such as string, bool, int, duration, or strings
+	Env     string   `json:"env,omitempty"`     // the variable read when the flag is not given
+	Default string   `json:"default,omitempty"` // the value used when neither the flag nor the variable is set
+	Values  []string `json:"values,omitempty"`  // the allowed values, from a oneof validate rule
+
+	Hidden       bool   `json:"hidden,omitempty"`       // the flag is left out of usage text and completions
+	Deprecated   string `json:"deprecated,omitempty"`   // the advice given when the flag is used, such as "use --x"
+	Experimental bool   `json:"experimental,omitempty"` // the flag is refused unless APP_EXPERIMENTAL is set
+
+	field  []int
+	path   string
+	shared bool // the env tag names the variable, which the flag is set on when given
+}
+
+func (f *Flag) isBool() bool {
+	return f.Type == "bool"
+}
+
+func (f *Flag) visible() bool {
+	return !f.Hidden && f.Deprecated == ""
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// ParseFlags parses the flags in args into a new T, a struct that declares its flags with field tags, and returns it
+// with the arguments that are not flags:
+//
+//	type serveOptions struct {
+//		Addr    string        `flag:"addr,a,the address to listen on" default:":8080"`
+//		Workers int           `flag:"workers,w,the number of workers" validate:"min=1"`
+//		Grace   time.Duration `flag:"grace,,how long requests get to finish" env:"APP_SHUTDOWN_TIMEOUT"`
+//		Tags    []string      `flag:"tag,t,a tag added to every metric; repeatable"`
+//	}
+//
+// The flag tag holds the long name, the optional one letter name, and the usage text. Flags are given as --name
+// value, --name=value, -s value, or -s=value; bool flags need no value. A flag that is not given falls back to the
+// variable named by the env tag, APP_ followed by the name in upper case by default, or "-" for none, then to the
+// default tag. The flags of a command added with NewCommand fall back to APP_ followed by the name of the command
+// and that of the flag, such as APP_SERVE_ADDR, so that commands do not share their variables or their defaults.
+// A flag whose env tag names a variable is set with SetFlag when it is given, so that LookupEnv and EffectiveConfig
+// see it too; the others stay out of the environment. Fields of embedded structs are flags as well, for options
+// that several commands share.
+//
+// Strings, bools, numbers, durations, types that implement encoding.TextUnmarshaler, and slices of those are
+// supported; slices collect every value given, or the comma separated values of the variable. The options are then
+// checked with Validate. Arguments after -- are never flags. ParseFlags returns ErrHelp if args hold -h or --help,
+// and applies the flags every command accepts: -q, -v, -vv, and --debug with SetVerbosity, --timeout, which sets
+// APP_TIMEOUT, --dry-run, or its DryRunShort name, which sets APP_DRY_RUN, and --yes, or its YesShort name, which
+// sets APP_YES. A flag of the struct takes precedence over a DryRunShort or YesShort of the same name.
+//
+// The hidden, deprecated, and experimental tags annotate a flag, see Flag:
+//
+//	Bind string `flag:"bind,,the address to listen on" deprecated:"use --addr instead" env:"-"`
+//	Fast bool   `flag:"fast,,skip the safety checks" experimental:"true"`
+//
+// Fields with an arg tag, rather than a flag tag, are positional arguments, set in the order they are declared from
+// the arguments that are not flags. Their number is checked, so an error reports "expected 2 args, got 1" before
+// the options are used:
+//
+//	type copyOptions struct {
+//		Sources []string `arg:"src,the files to copy"`
+//		Dest    string   `arg:"[dest],the directory to copy to" default:"."`
+//	}
+//
+// The name is in brackets for an optional argument, and a slice takes every remaining argument, so only the last
+// argument may be a slice and no required argument may follow an optional one.
+func ParseFlags[T any](a *App, args []string) (*T, []string, error) {
+	opts := new(T)
+	rest, err := bindOptions(a, optionsOf(reflect.TypeOf(opts).Elem(), "APP_"), reflect.ValueOf(opts).Elem(), args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return opts, rest, nil
+}
+
+// options are the flags and the positional arguments declared by a struct.
+type options struct {
+	flags []*Flag
+	args  []*Arg
+}
+
+// optionsOf returns the options declared by the struct type t, whose flags fall back to the variables named by
+// envPrefix followed by their names. It panics if the declarations are invalid.
+func optionsOf(t reflect.Type, envPrefix string) *options {
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("app: flags are declared by a struct, not %s", t))
+	}
+
+	opts := new(options)
+	collectOptions(t, nil, "", envPrefix, opts)
+
+	names := map[string]bool{"help": true, "h": true, "quiet": true, "q": true, "verbose": true, "v": true,
+		"debug": true, "timeout": true, "dry-run": true, "yes": true}
+	for _, f := range opts.flags {
+		for _, name := range []string{f.Name, f.Short} {
+			if name == "" {
+				continue
+			}
+			if names[name] {
+				panic(fmt.Sprintf("app: flag %q is declared twice", name))
+			}
+			names[name] = true
+		}
+	}
+
+	for idx, arg := range opts.args {
+		switch {
+		case arg.Variadic && idx < len(opts.args)-1:
+			panic(fmt.Sprintf("app: variadic argument %s is not the last", arg.Name))
+		case !arg.Optional && idx > 0 && opts.args[idx-1].Optional:
+			panic(fmt.Sprintf("app: required argument %s follows an optional one", arg.Name))
+		}
+	}
+	return opts
+}
+
+func collectOptions(t reflect.Type, index []int, path, envPrefix string, opts *options) {
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		fieldIndex := append(append([]int(nil), index...), idx)
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+
+		if tag, ok := field.Tag.Lookup("arg"); ok {
+			opts.args = append(opts.args, newArg(field, tag, fieldIndex, fieldPath))
+			continue
+		}
+		tag, ok := field.Tag.Lookup("flag")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				collectOptions(field.Type, fieldIndex, fieldPath, envPrefix, opts)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			panic(fmt.Sprintf("app: flag field %s is not exported", fieldPath))
+		}
+
+		parts := strings.SplitN(tag, ",", 3)
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		f := &Flag{
+			Name:         strings.TrimSpace(parts[0]),
+			Short:        strings.TrimSpace(parts[1]),
+			Usage:        strings.TrimSpace(parts[2]),
+			Type:         flagType(field.Type),
+			Default:      field.Tag.Get("default"),
+			Hidden:       boolTag(field, "hidden"),
+			Deprecated:   field.Tag.Get("deprecated"),
+			Experimental: boolTag(field, "experimental"),
+			field:        fieldIndex,
+			path:         fieldPath,
+		}
+		if f.Name == "" || strings.HasPrefix(f.Name, "-") || strings.ContainsAny(f.Name, "= ") {
+			panic(fmt.Sprintf("app: invalid flag name %q of %s", f.Name, fieldPath))
+		}
+		if f.Short != "" && (utf8.RuneCountInString(f.Short) != 1 || f.Short == "-" || f.Short == "=") {
+			panic(fmt.Sprintf("app: invalid short flag %q of %s", f.Short, fieldPath))
+		}
+		if f.Type == "" {
+			panic(fmt.Sprintf("app: flag %s has unsupported type %s", f.Name, field.Type))
+		}
+
+		switch env := field.Tag.Get("env"); env {
+		case "-":
+		case "":
+			f.Env = envPrefix + envName(f.Name)
+		default:
+			f.Env, f.shared = env, true
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if values, ok := strings.CutPrefix(strings.TrimSpace(rule), "oneof="); ok {
+				f.Values = strings.Fields(values)
+			}
+		}
+
+		if f.Default != "" {
+			if err := setOptionValue(reflect.New(field.Type).Elem(), f.Default, true); err != nil {
+				panic(fmt.Sprintf("app: invalid default %q of flag %s", f.Default, f.Name))
+			}
+		}
+		opts.flags = append(opts.flags, f)
+	}
+}
+
+// envName returns the name in upper case, with dashes replaced by underscores, as used in variable names.
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// boolTag returns the value of the bool tag key of field. It panics if the value is not a bool.
+func boolTag(field reflect.StructField, key string) bool {
+	tag, ok := field.Tag.Lookup(key)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(tag)
+	if err != nil {
+		panic(fmt.Sprintf("app: invalid %s tag %q of %s", key, tag, field.Name))
+	}
+	return b
+}
+
+// flagType returns the Flag Type of values of t, or an empty string if t is not supported.
+func flagType(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "value"
+	}
+	switch {
+	case t == durationType:
+		return "duration"
+	case t.Kind() == reflect.Bool:
+		return "bool"
+	case t.Kind() == reflect.String:
+		return "string"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return "int"
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return "uint"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "float"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Slice:
+		if elem := flagType(t.Elem()); elem != "" && elem != "bool" {
+			return elem + "s"
+		}
+	}
+	return ""
+}
+
+// bindOptions sets the flags of the struct v from args, then from the environment and the defaults, binds the
+// positional arguments, and returns the arguments that are not flags.
+func bindOptions(a *App, opts *options, v reflect.Value, args []string) ([]string, error) {
+	long := make(map[string]*Flag, len(opts.flags))
+	short := make(map[string]*Flag, len(opts.flags))
+	for _, f := range opts.flags {
+		long[f.Name] = f
+		if f.Short != "" {
+			short[f.Short] = f
+		}
+	}
+
+	var rest []string
+	given := make(map[*Flag][]string)
+	for idx := 0; idx < len(args); idx++ {
+		arg := args[idx]
+		if arg == "--" {
+			rest = append(rest, args[idx+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			rest = append(rest, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		var f *Flag
+		if strings.HasPrefix(name, "--") {
+			f = long[name[2:]]
+		} else {
+			f = short[name[1:]]
+		}
+		if f == nil {
+			if name == "-h" || name == "--help" {
+				return nil, ErrHelp
+			}
+			n, err := a.builtinFlag(args[idx:])
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				idx += n - 1
+				continue
+			}
+			return nil, fmt.Errorf("unknown flag %s", name)
+		}
+
+		switch {
+		case hasValue:
+		case f.isBool():
+			value = "true"
+		case idx+1 < len(args):
+			idx++
+			value = args[idx]
+		default:
+			return nil, fmt.Errorf("flag %s needs a value", name)
+		}
+
+		if err := setOptionValue(v.FieldByIndex(f.field), value, false); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", name, value)
+		}
+		given[f] = append(given[f], value)
+	}
+
+	for _, f := range opts.flags {
+		if values, ok := given[f]; ok {
+			if f.Experimental && !a.experimental() {
+				return nil, errors.New(a.Localizer().T("app.experimental", "flag --"+f.Name))
+			}
+			if f.Deprecated != "" {
+				a.warn(a.Localizer().T("app.deprecated", "flag --"+f.Name, f.Deprecated))
+			}
+			if f.shared {
+				a.SetFlag(f.Env, strings.Join(values, ","))
+			}
+			continue
+		}
+
+		if f.Env != "" {
+			if value, ok := a.LookupEnv(f.Env); ok && value != "" {
+				if err := setOptionValue(v.FieldByIndex(f.field), value, true); err != nil {
+					return nil, fmt.Errorf("invalid %s %q", f.Env, value)
+				}
+				continue
+			}
+		}
+		if f.Default != "" {
+			_ = setOptionValue(v.FieldByIndex(f.field), f.Default, true)
+		}
+	}
+
+	if err := bindArgs(opts.args, v, rest); err != nil {
+		return nil, err
+	}
+	if err := Validate(v.Addr().Interface()); err != nil {
+		return nil, renameOptionErrors(opts, err.(ConfigErrors))
+	}
+	return rest, nil
+}
+
+// renameOptionErrors names the flags and arguments, rather than the struct fields, in errors returned by Validate.
+func renameOptionErrors(opts *options, errs ConfigErrors) ConfigErrors {
+	names := make(map[string]string, len(opts.flags)+len(opts.args))
+	for _, f := range opts.flags {
+		names[f.path] = "--" + f.Name
+	}
+	for _, arg := range opts.args {
+		names[arg.path] = arg.Name
+	}
+
+	renamed := make(ConfigErrors, len(errs))
+	for idx, err := range errs {
+		renamed[idx] = err
+		if name, ok := names[err.Field]; ok {
+			renamed[idx] = &FieldError{Field: name, Err: err.Err}
+		}
+	}
+	return renamed
+}
+
+// setOptionValue parses s into the field v of a flag or argument. Slices collect the values given one at a time, or
+// split s on commas when split is set.
+func setOptionValue(v reflect.Value, s string, split bool) error {
+	if !strings.HasSuffix(flagType(v.Type()), "s") {
+		return parseFlagValue(v, s)
+	}
+
+	values := []string{s}
+	if split {
+		values = strings.Split(s, ",")
+	}
+	for _, value := range values {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := parseFlagValue(elem, strings.TrimSpace(value)); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, elem))
+	}
+	return nil
+}
+
+func parseFlagValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.CanInt():
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.CanUint():
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case v.CanFloat():
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
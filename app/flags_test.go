@@ -0,0 +1,92 @@
+package app_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type commonOptions struct {
+	Insecure bool `flag:"insecure,k,skip TLS verification" env:"-"`
+}
+
+type serveOptions struct {
+	commonOptions
+	Addr    string        `flag:"addr,a,the address to listen on" default:":8080"`
+	Workers int           `flag:"workers,w,the number of workers" default:"4" validate:"min=1"`
+	Grace   time.Duration `flag:"grace,,how long requests get to finish" env:"APP_SHUTDOWN_TIMEOUT"`
+	Tags    []string      `flag:"tag,t,a tag added to every metric"`
+	Mode    string        `flag:"mode,,the mode" validate:"oneof=fast safe"`
+	Bind    net.IP        `flag:"bind,,the interface"`
+	Other   string
+}
+
+func TestParseFlags(t *testing.T) {
+	a := newApp([]string{"APP_WORKERS=8", "APP_SHUTDOWN_TIMEOUT=3s", "APP_TAG=a, b", "APP_INSECURE=true"})
+	opts, rest, err := app.ParseFlags[serveOptions](a, []string{"one", "--addr", ":9090", "two"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, rest)
+	assert.Equal(t, &serveOptions{
+		Addr:    ":9090",
+		Workers: 8,
+		Grace:   3 * time.Second,
+		Tags:    []string{"a", "b"},
+	}, opts, "flags fall back to the environment, then to the defaults")
+
+	_, ok := a.LookupEnv("APP_ADDR")
+	assert.False(t, ok, "flags stay out of the environment")
+
+	a = newApp(nil)
+	opts, rest, err = app.ParseFlags[serveOptions](a, []string{
+		"-k", "-w=2", "-t", "x", "--tag=y", "--grace", "1m", "--mode", "safe", "--bind", "127.0.0.1", "--", "-w",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-w"}, rest)
+	assert.Equal(t, &serveOptions{
+		commonOptions: commonOptions{Insecure: true},
+		Addr:          ":8080",
+		Workers:       2,
+		Grace:         time.Minute,
+		Tags:          []string{"x", "y"},
+		Mode:          "safe",
+		Bind:          net.IPv4(127, 0, 0, 1),
+	}, opts)
+	v, _ := a.LookupEnv("APP_SHUTDOWN_TIMEOUT")
+	assert.Equal(t, "1m", v, "flags with an env tag set the variable")
+	assert.Equal(t, app.SourceFlag, a.Provenance("APP_SHUTDOWN_TIMEOUT"))
+	_, ok = a.LookupEnv("APP_TAG")
+	assert.False(t, ok)
+
+	for expected, args := range map[string][]string{
+		"unknown flag --port":                                      {"--port", "80"},
+		"flag -a needs a value":                                    {"-a"},
+		`invalid --workers "many"`:                                 {"--workers", "many"},
+		"invalid configuration: --workers: must be at least 1":     {"-w", "-1"},
+		"invalid configuration: --mode: must be one of fast, safe": {"--mode=slow"},
+	} {
+		_, _, err := app.ParseFlags[serveOptions](newApp(nil), args)
+		assert.EqualError(t, err, expected)
+	}
+
+	_, _, err = app.ParseFlags[serveOptions](newApp([]string{"APP_WORKERS=many"}), nil)
+	assert.EqualError(t, err, `invalid APP_WORKERS "many"`)
+
+	_, _, err = app.ParseFlags[serveOptions](newApp(nil), []string{"--help"})
+	assert.ErrorIs(t, err, app.ErrHelp)
+
+	assert.PanicsWithValue(t, `app: flag "h" is declared twice`, func() {
+		_, _, _ = app.ParseFlags[struct {
+			Host string `flag:"host,h,the host"`
+		}](newApp(nil), nil)
+	})
+	assert.PanicsWithValue(t, `app: invalid default "soon" of flag wait`, func() {
+		_, _, _ = app.ParseFlags[struct {
+			Wait time.Duration `flag:"wait,,the wait" default:"soon"`
+		}](newApp(nil), nil)
+	})
+}
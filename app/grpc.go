@@ -0,0 +1,323 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+)
+
+// Defaults for the clients created by GRPCClient, overridden by the APP_GRPC_* environment variables.
+const (
+	DefaultGRPCKeepaliveTime    = 5 * time.Minute
+	DefaultGRPCKeepaliveTimeout = 20 * time.Second
+	DefaultGRPCRetries          = 2
+	DefaultGRPCRetryBackoff     = 100 * time.Millisecond
+)
+
+// GRPCClient returns a client connection to target, closed when the app exits. The connection pings the server
+// every APP_GRPC_KEEPALIVE_TIME and drops it after APP_GRPC_KEEPALIVE_TIMEOUT without an answer. Unary calls failing
+// with Unavailable are retried APP_GRPC_RETRIES times, waiting APP_GRPC_RETRY_BACKOFF before the first retry and
+// twice as long before each following one. Calls are recorded in the grpc_client_requests_total and
+// grpc_client_request_duration_seconds metrics, unless APP_METRICS_RED is false, and send the request ID their
+// context carries, see correlation.
+//
+// Calls are traced with the OpenTelemetry TracerProvider and TextMapPropagator set with otel.SetTracerProvider and
+// otel.SetTextMapPropagator: each call is a client span, retries included, whose context is sent in the metadata.
+// Both do nothing until the app sets them.
+//
+// The connection uses TLS configured by the APP_GRPC_TLS_* variables (see tlsconfig.FromEnv and
+// tlsconfig.ClientConfig) unless APP_GRPC_INSECURE=true. A client certificate is reloaded when its files change, as a
+// tlsconfig.Loader does, until the app exits. The opts are applied last, so they can override any of the defaults.
+func (a *App) GRPCClient(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOpts, loader, err := a.grpcDialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(target, append(dialOpts, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	a.grpcMu.Lock()
+	a.grpcConns = append(a.grpcConns, conn)
+	if loader != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		a.grpcWatches = append(a.grpcWatches, cancel)
+		go loader.Watch(ctx)
+	}
+	a.grpcMu.Unlock()
+
+	return conn, nil
+}
+
+// grpcDialOptions returns the options of the connections made by GRPCClient, and the Loader of the client certificate
+// they present, if any.
+func (a *App) grpcDialOptions() ([]grpc.DialOption, *tlsconfig.Loader, error) {
+	insecureConn, err := a.lookupBool("APP_GRPC_INSECURE")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	creds := insecure.NewCredentials()
+	var loader *tlsconfig.Loader
+	if !insecureConn {
+		config, err := tlsconfig.FromEnv(a.LookupEnv, "APP_GRPC_TLS_")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var tlsConfig *tls.Config
+		if config.CertFile != "" || config.KeyFile != "" {
+			// the Loader is kept to be watched, so that a renewed certificate is presented
+			if loader, err = tlsconfig.NewLoader(config, a.Logger()); err != nil {
+				return nil, nil, err
+			}
+			tlsConfig = loader.ClientTLSConfig()
+		} else if tlsConfig, err = tlsconfig.ClientConfig(config, a.Logger()); err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	keepaliveTime, err := a.lookupDuration("APP_GRPC_KEEPALIVE_TIME", DefaultGRPCKeepaliveTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keepaliveTimeout, err := a.lookupDuration("APP_GRPC_KEEPALIVE_TIMEOUT", DefaultGRPCKeepaliveTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	backoff, err := a.lookupDuration("APP_GRPC_RETRY_BACKOFF", DefaultGRPCRetryBackoff)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	retries := DefaultGRPCRetries
+	if v, ok := a.LookupEnv("APP_GRPC_RETRIES"); ok && v != "" {
+		retries, err = strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			return nil, nil, fmt.Errorf("invalid APP_GRPC_RETRIES %q", v)
+		}
+	}
+
+	registry := a.Metrics()
+	unary := []grpc.UnaryClientInterceptor{grpcTracingInterceptor(), correlation.UnaryClientInterceptor()}
+	stream := []grpc.StreamClientInterceptor{grpcStreamTracingInterceptor(), correlation.StreamClientInterceptor()}
+	if a.redMetrics() {
+		unary = append(unary, grpcMetricsInterceptor(registry))
+		stream = append(stream, grpcStreamMetricsInterceptor(registry))
+	}
+	unary = append(unary, grpcRetryInterceptor(retries, backoff, registry))
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: keepaliveTime, Timeout: keepaliveTimeout}),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}, loader, nil
+}
+
+func (a *App) closeGRPCClients() {
+	a.grpcMu.Lock()
+	defer a.grpcMu.Unlock()
+
+	for _, conn := range a.grpcConns {
+		if err := conn.Close(); err != nil {
+			_ = a.Logger().Warnm(gomol.NewAttrsFromMap(map[string]interface{}{
+				"target": conn.Target(),
+			}), "error closing gRPC client: %v", err)
+		}
+	}
+
+	a.grpcConns = nil
+
+	for _, cancel := range a.grpcWatches {
+		cancel()
+	}
+	a.grpcWatches = nil
+}
+
+func grpcMetricsInterceptor(registry *metrics.Registry) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		observeGRPCCall(registry, method, err, time.Since(start))
+		return err
+	}
+}
+
+func grpcStreamMetricsInterceptor(registry *metrics.Registry) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		start := time.Now()
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			observeGRPCCall(registry, method, err, time.Since(start))
+		}
+		return s, err
+	}
+}
+
+func observeGRPCCall(registry *metrics.Registry, method string, err error, elapsed time.Duration) {
+	registry.Counter("grpc_client_requests_total", metrics.Labels{
+		"method": method,
+		"code":   status.Code(err).String(),
+	}).Inc()
+	registry.Histogram("grpc_client_request_duration_seconds", nil, metrics.Labels{
+		"method": method,
+	}).Observe(elapsed.Seconds())
+}
+
+func grpcRetryInterceptor(retries int, backoff time.Duration, registry *metrics.Registry) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		for attempt := 0; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt >= retries || status.Code(err) != codes.Unavailable {
+				return err
+			}
+
+			timer := time.NewTimer(backoff << uint(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+
+			registry.Counter("grpc_client_retries_total", metrics.Labels{"method": method}).Inc()
+		}
+	}
+}
+
+// grpcTracerName names the tracer of the gRPC client spans.
+const grpcTracerName = "github.com/demosdemon/golang-app-framework/app"
+
+func grpcTracingInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		ctx, span := startGRPCSpan(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		endGRPCSpan(span, err)
+		return err
+	}
+}
+
+func grpcStreamTracingInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, span := startGRPCSpan(ctx, method)
+		s, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endGRPCSpan(span, err)
+			return nil, err
+		}
+		return &tracedStream{ClientStream: s, span: span}, nil
+	}
+}
+
+// startGRPCSpan starts the client span of a call to method, and returns a context sending it in the metadata.
+func startGRPCSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, span := otel.Tracer(grpcTracerName).Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.service", service),
+			attribute.String("rpc.method", name),
+		))
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endGRPCSpan ends span with the status of err.
+func endGRPCSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.SetStatus(otelcodes.Error, status.Convert(err).Message())
+	}
+	span.End()
+}
+
+// tracedStream ends the span of a stream once the stream is over.
+type tracedStream struct {
+	grpc.ClientStream
+	span trace.Span
+	once sync.Once
+}
+
+func (s *tracedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if err == io.EOF {
+				endGRPCSpan(s.span, nil)
+			} else {
+				endGRPCSpan(s.span, err)
+			}
+		})
+	}
+	return err
+}
+
+// metadataCarrier carries the trace context in gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/aphistic/gomol"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// GRPCServer is a Server that serves gRPC on the listener described by Spec. The grpc.health.v1.Health service is
+// always registered: the overall status is SERVING while the server runs and NOT_SERVING once it begins shutting
+// down, and Health may be used to report the status of individual services. The reflection service is registered
+// when Reflection or APP_GRPC_REFLECTION=true is set, so tools such as grpcurl can discover the services. The contexts
+// of the calls carry the ShuttingDown channel of the app, so that long running streams can end early. The calls are
+// recorded in the grpc_server_requests_total metric, by method and status code, and in the
+// grpc_server_request_duration_seconds metric, unless NoMetrics is set or APP_METRICS_RED is false.
+type GRPCServer struct {
+	*grpc.Server
+
+	Spec       string         // listen spec, see App.Listen
+	Reflection bool           // register the reflection service
+	Health     *health.Server // health service registered on the server
+	NoMetrics  bool           // do not record the calls in the metrics
+
+	listener net.Listener
+	app      *App
+	registry *metrics.Registry // nil unless the calls are recorded
+}
+
+// NewGRPCServer returns a GRPCServer created with opts, serving on the listener described by spec. Register the
+// application services on the embedded *grpc.Server before calling App.Run.
+func NewGRPCServer(spec string, opts ...grpc.ServerOption) *GRPCServer {
+	s := &GRPCServer{
+		Spec:   spec,
+		Health: health.NewServer(),
+	}
+	s.Server = grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryShutdownInterceptor, s.unaryMetricsInterceptor),
+		grpc.ChainStreamInterceptor(s.streamShutdownInterceptor, s.streamMetricsInterceptor),
+	}, opts...)...)
+
+	s.Health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s.Server, s.Health)
+
+	return s
+}
+
+// Bind opens the server listener and registers the reflection service if enabled.
+func (s *GRPCServer) Bind(a *App) error {
+	enabled, err := a.lookupBool("APP_GRPC_REFLECTION")
+	if err != nil {
+		return err
+	}
+	s.Reflection = s.Reflection || enabled
+
+	l, err := a.Listen(s.Spec)
+	if err != nil {
+		return err
+	}
+
+	if s.Reflection {
+		reflection.Register(s.Server)
+	}
+
+	s.listener = l
+	s.app = a
+	if !s.NoMetrics && a.redMetrics() {
+		s.registry = a.Metrics()
+	}
+
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(map[string]interface{}{
+		"addr":       l.Addr().String(),
+		"reflection": s.Reflection,
+	}), "gRPC server bound")
+
+	return nil
+}
+
+// ListenerAddr returns the address the server is bound to, or nil if the server is not bound.
+func (s *GRPCServer) ListenerAddr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Serve reports the server as SERVING and serves gRPC requests until the server is shut down.
+func (s *GRPCServer) Serve() error {
+	s.Health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	return s.Server.Serve(s.listener)
+}
+
+// Shutdown reports every service as NOT_SERVING and gracefully stops the server, waiting for active RPCs until the
+// context is done, after which they are canceled.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	if s.listener == nil {
+		return nil
+	}
+
+	s.Health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// withShutdown returns ctx carrying the ShuttingDown channel of the app, once the server is bound.
+func (s *GRPCServer) withShutdown(ctx context.Context) context.Context {
+	if s.app == nil {
+		return ctx
+	}
+	return s.app.WithShutdown(ctx)
+}
+
+func (s *GRPCServer) unaryShutdownInterceptor(
+	ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	return handler(s.withShutdown(ctx), req)
+}
+
+func (s *GRPCServer) streamShutdownInterceptor(
+	srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	return handler(srv, &shutdownStream{ServerStream: ss, ctx: s.withShutdown(ss.Context())})
+}
+
+func (s *GRPCServer) unaryMetricsInterceptor(
+	ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	s.observe(info.FullMethod, err, time.Since(start))
+	return res, err
+}
+
+func (s *GRPCServer) streamMetricsInterceptor(
+	srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.observe(info.FullMethod, err, time.Since(start))
+	return err
+}
+
+// observe records a call in the metrics.
+func (s *GRPCServer) observe(method string, err error, elapsed time.Duration) {
+	if s.registry == nil {
+		return
+	}
+	s.registry.Counter("grpc_server_requests_total", metrics.Labels{
+		"method": method,
+		"code":   status.Code(err).String(),
+	}).Inc()
+	s.registry.Histogram("grpc_server_request_duration_seconds", nil, metrics.Labels{
+		"method": method,
+	}).Observe(elapsed.Seconds())
+}
+
+type shutdownStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *shutdownStream) Context() context.Context {
+	return s.ctx
+}
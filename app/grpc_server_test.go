@@ -0,0 +1,90 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestGRPCServer(t *testing.T) {
+	a := newApp([]string{"APP_GRPC_INSECURE=true", "APP_GRPC_REFLECTION=true"})
+	s := app.NewGRPCServer("tcp://127.0.0.1:0")
+
+	assert.Nil(t, s.ListenerAddr())
+	assert.NoError(t, s.Shutdown(context.Background()))
+
+	require.NoError(t, s.Bind(a))
+	assert.True(t, s.Reflection)
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	conn, err := a.GRPCClient(s.ListenerAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	health := healthpb.NewHealthClient(conn)
+	res, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	s.Health.SetServingStatus("app.v1.Widgets", healthpb.HealthCheckResponse_NOT_SERVING)
+	res, err = health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "app.v1.Widgets"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.Status)
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	reply, err := stream.Recv()
+	require.NoError(t, err)
+	require.NoError(t, stream.CloseSend())
+
+	var services []string
+	for _, svc := range reply.GetListServicesResponse().GetService() {
+		services = append(services, svc.GetName())
+	}
+	assert.Contains(t, services, "grpc.health.v1.Health")
+	assert.Contains(t, services, "grpc.reflection.v1.ServerReflection")
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+
+	res, err = s.Health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, res.Status)
+}
+
+func TestGRPCServer_NoReflection(t *testing.T) {
+	a := newApp([]string{"APP_GRPC_INSECURE=true"})
+	s := app.NewGRPCServer("tcp://127.0.0.1:0")
+	require.NoError(t, s.Bind(a))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	conn, err := a.GRPCClient(s.ListenerAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+
+	s = app.NewGRPCServer("tcp://127.0.0.1:0")
+	assert.EqualError(t, s.Bind(newApp([]string{"APP_GRPC_REFLECTION=on"})), `invalid APP_GRPC_REFLECTION "on"`)
+}
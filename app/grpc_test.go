@@ -0,0 +1,142 @@
+package app_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+type flakyHealth struct {
+	*health.Server
+	failures int32
+}
+
+func (h *flakyHealth) Check(
+	ctx context.Context, req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	if atomic.AddInt32(&h.failures, -1) >= 0 {
+		return nil, status.Error(codes.Unavailable, "warming up")
+	}
+	return h.Server.Check(ctx, req)
+}
+
+func startGRPC(t *testing.T, h healthpb.HealthServer) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, h)
+	go func() { _ = s.Serve(l) }()
+
+	return l.Addr().String(), s.Stop
+}
+
+func TestApp_GRPCClient(t *testing.T) {
+	h := &flakyHealth{Server: health.NewServer(), failures: 2}
+	addr, stop := startGRPC(t, h)
+	defer stop()
+
+	a := newApp([]string{"APP_GRPC_INSECURE=true", "APP_GRPC_RETRY_BACKOFF=1ms"})
+	conn, err := a.GRPCClient(addr)
+	require.NoError(t, err)
+
+	client := healthpb.NewHealthClient(conn)
+	res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	method := "/grpc.health.v1.Health/Check"
+	registry := a.Metrics()
+	assert.Equal(t, 2.0, registry.Counter("grpc_client_retries_total", metrics.Labels{"method": method}).Value())
+	assert.Equal(t, 1.0, registry.Counter("grpc_client_requests_total", metrics.Labels{
+		"method": method,
+		"code":   "OK",
+	}).Value())
+
+	// retries are exhausted
+	atomic.StoreInt32(&h.failures, 3)
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1.0, registry.Counter("grpc_client_requests_total", metrics.Labels{
+		"method": method,
+		"code":   "Unavailable",
+	}).Value())
+
+	// other errors are not retried
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, 4.0, registry.Counter("grpc_client_retries_total", metrics.Labels{"method": method}).Value())
+
+	assert.PanicsWithValue(t, "system exit 0", func() { a.Exit(0) })
+	assert.Equal(t, connectivity.Shutdown, conn.GetState())
+}
+
+func TestApp_GRPCClient_Invalid(t *testing.T) {
+	for env, msg := range map[string]string{
+		"APP_GRPC_INSECURE=maybe":       `invalid APP_GRPC_INSECURE "maybe"`,
+		"APP_GRPC_KEEPALIVE_TIME=often": `invalid APP_GRPC_KEEPALIVE_TIME "often"`,
+		"APP_GRPC_RETRIES=-1":           `invalid APP_GRPC_RETRIES "-1"`,
+		"APP_GRPC_TLS_CA_FILE=/missing": "tlsconfig: reading CA file: open /missing: no such file or directory",
+	} {
+		conn, err := newApp([]string{env}).GRPCClient("localhost:1")
+		assert.Nil(t, conn)
+		assert.EqualError(t, err, msg)
+	}
+}
+
+type traceHealth struct {
+	*health.Server
+	traceparent chan string
+}
+
+func (h *traceHealth) Check(
+	ctx context.Context, req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h.traceparent <- strings.Join(md.Get("traceparent"), ",")
+	return h.Server.Check(ctx, req)
+}
+
+func TestApp_GRPCClient_Tracing(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(prev)
+
+	h := &traceHealth{Server: health.NewServer(), traceparent: make(chan string, 1)}
+	addr, stop := startGRPC(t, h)
+	defer stop()
+
+	a := newApp([]string{"APP_GRPC_INSECURE=true"})
+	conn, err := a.GRPCClient(addr)
+	require.NoError(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x02},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "00-01000000000000000000000000000000-0200000000000000-01", <-h.traceparent)
+
+	assert.PanicsWithValue(t, "system exit 0", func() { a.Exit(0) })
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/aphistic/gomol"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTPServer is a Server that serves HTTP on the listener described by Spec. Without a Spec, it listens on port
+// DefaultHTTPPort of localhost, or of all interfaces in a container, where the PORT variable sets the port. The
+// embedded *http.Server may be configured as usual; if its TLSConfig is set, the server serves HTTPS using the
+// certificates from the TLSConfig.
+//
+// When APP_ENV is development and no TLSConfig is set, the server serves HTTPS with a certificate for the local
+// machine from a devcert.Authority in the DataDir, unless APP_HTTP_DEVCERT is false. Trust the certificate of the
+// authority, logged when the server is bound, for browsers to accept it.
+//
+// Cleartext HTTP/2 for clients with prior knowledge is enabled by H2C or APP_HTTP_H2C=true. Experimental HTTP/3 is
+// enabled by HTTP3 or APP_HTTP_HTTP3=true; it requires a TLSConfig and serves QUIC on the UDP port matching the TCP
+// listener, advertising itself to TCP clients with an Alt-Svc header.
+//
+// Shutdown drains the server: it stops accepting connections, disables keep-alives, and waits for in-flight requests
+// until its context is done, after which the remaining connections are force-closed. The contexts of the requests
+// carry the ShuttingDown channel of the app, so that long running handlers can end early; see ShutdownContext.
+//
+// The requests are recorded in the http_server_requests_total metric, by method, route, and status code, and in the
+// http_server_request_duration_seconds metric, unless NoMetrics is set or APP_METRICS_RED is false. The route is the
+// pattern of the http.ServeMux handling the request, or the one named with SetRoute; up to APP_METRICS_MAX_ROUTES
+// routes are told apart, the others, and the requests matching no route, are recorded as other. The middleware added
+// with App.WrapHTTPServers is applied to the handler too.
+type HTTPServer struct {
+	*http.Server
+
+	Spec      string // listen spec, see App.Listen
+	H2C       bool   // serve HTTP/2 without TLS to clients with prior knowledge
+	HTTP3     bool   // also serve HTTP/3 over QUIC
+	NoMetrics bool   // do not record the requests in the metrics
+
+	handler   http.Handler                   // the Handler before Bind wrapped it
+	connState func(net.Conn, http.ConnState) // the ConnState before Bind wrapped it
+	wrapped   bool                           // whether handler and connState are set
+
+	listener   net.Listener
+	packetConn net.PacketConn
+	http3      *http3.Server
+
+	connsMu sync.Mutex
+	conns   map[net.Conn]http.ConnState
+}
+
+// NewHTTPServer returns an HTTPServer serving handler on the listener described by spec.
+func NewHTTPServer(spec string, handler http.Handler) *HTTPServer {
+	return &HTTPServer{
+		Server: &http.Server{Handler: handler},
+		Spec:   spec,
+	}
+}
+
+// Bind opens the server listeners and logs the protocols the server offers.
+func (s *HTTPServer) Bind(a *App) error {
+	for key, dst := range map[string]*bool{"APP_HTTP_H2C": &s.H2C, "APP_HTTP_HTTP3": &s.HTTP3} {
+		v, err := a.lookupBool(key)
+		if err != nil {
+			return err
+		}
+		*dst = *dst || v
+	}
+
+	if s.Spec == "" {
+		spec, err := a.defaultHTTPSpec()
+		if err != nil {
+			return err
+		}
+		s.Spec = spec
+	}
+
+	// binding again wraps the Handler and ConnState as they were before the first Bind, not what it made of them
+	if !s.wrapped {
+		s.handler, s.connState, s.wrapped = s.Handler, s.ConnState, true
+	}
+
+	// the requests carry the ShuttingDown channel, see ShutdownContext
+	handler := s.handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	handler = a.wrapHandler(handler)
+	if !s.NoMetrics && a.redMetrics() {
+		handler = a.redHandler(handler)
+	}
+	s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(a.WithShutdown(r.Context())))
+	})
+
+	if s.TLSConfig == nil && a.Development() {
+		if err := s.useDevCert(a); err != nil {
+			return err
+		}
+	}
+
+	if s.HTTP3 && s.TLSConfig == nil {
+		return errors.New("HTTP/3 requires a TLSConfig")
+	}
+
+	l, err := a.Listen(s.Spec)
+	if err != nil {
+		return err
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	names := []string{"http/1.1"}
+
+	if s.TLSConfig != nil {
+		protocols.SetHTTP2(true)
+		names = append(names, "h2")
+	} else if s.H2C {
+		protocols.SetUnencryptedHTTP2(true)
+		names = append(names, "h2c")
+	}
+
+	s.Server.Protocols = protocols
+	s.trackConns()
+
+	if s.HTTP3 {
+		if err := s.bindHTTP3(l); err != nil {
+			_ = l.Close()
+			return err
+		}
+		names = append(names, "h3")
+	}
+
+	s.listener = l
+
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(map[string]interface{}{
+		"addr":      l.Addr().String(),
+		"protocols": names,
+	}), "HTTP server bound")
+
+	return nil
+}
+
+func (s *HTTPServer) bindHTTP3(l net.Listener) error {
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok {
+		return errors.New("HTTP/3 requires a TCP listener")
+	}
+
+	pc, err := net.ListenUDP("udp", &net.UDPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone})
+	if err != nil {
+		return err
+	}
+
+	handler := s.Handler
+	s.packetConn = pc
+	s.http3 = &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(s.TLSConfig),
+	}
+
+	s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = s.http3.SetQUICHeaders(w.Header())
+		handler.ServeHTTP(w, r)
+	})
+
+	return nil
+}
+
+// trackConns records the state of every connection so Shutdown can report the connections it force-closes.
+func (s *HTTPServer) trackConns() {
+	s.conns = make(map[net.Conn]http.ConnState)
+
+	next := s.connState
+	s.Server.ConnState = func(c net.Conn, state http.ConnState) {
+		s.connsMu.Lock()
+		switch state {
+		case http.StateHijacked, http.StateClosed:
+			delete(s.conns, c)
+		default:
+			s.conns[c] = state
+		}
+		s.connsMu.Unlock()
+
+		if next != nil {
+			next(c, state)
+		}
+	}
+}
+
+func (s *HTTPServer) openConns() int {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	return len(s.conns)
+}
+
+// ListenerAddr returns the address the server is bound to, or nil if the server is not bound.
+func (s *HTTPServer) ListenerAddr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Serve serves HTTP requests until the server is shut down.
+func (s *HTTPServer) Serve() error {
+	if s.http3 == nil {
+		return s.serve()
+	}
+
+	errch := make(chan error, 2)
+	go func() { errch <- s.serve() }()
+	go func() { errch <- ignoreServerClosed(s.http3.Serve(s.packetConn)) }()
+
+	if err := <-errch; err != nil {
+		return err
+	}
+	return <-errch
+}
+
+func (s *HTTPServer) serve() error {
+	if s.TLSConfig != nil {
+		return ignoreServerClosed(s.Server.ServeTLS(s.listener, "", ""))
+	}
+	return ignoreServerClosed(s.Server.Serve(s.listener))
+}
+
+// Shutdown gracefully stops the server, waiting for active requests until the context is done. Connections still
+// open when the context is done are closed and counted in the returned error.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	if s.listener == nil {
+		return nil
+	}
+
+	var err error
+	if s.http3 != nil {
+		err = s.http3.Shutdown(ctx)
+		if cerr := s.packetConn.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	s.Server.SetKeepAlivesEnabled(false)
+
+	serr := s.Server.Shutdown(ctx)
+	if serr != nil && serr == ctx.Err() {
+		forced := s.openConns()
+		_ = s.Server.Close()
+		serr = fmt.Errorf("drain timed out, force-closed %d connections: %w", forced, serr)
+	}
+
+	if err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,217 @@
+package app_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestHTTPServer(t *testing.T) {
+	a := newApp(nil)
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.URL.Path))
+	}))
+
+	assert.Nil(t, s.ListenerAddr())
+	assert.NoError(t, s.Shutdown(context.Background()))
+
+	require.NoError(t, s.Bind(a))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	res, err := http.Get("http://" + s.ListenerAddr().String() + "/world")
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, "hello /world", string(body))
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+
+	s = app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+	s.Spec = "udp://127.0.0.1:0"
+	assert.Error(t, s.Bind(a))
+}
+
+func TestHTTPServer_Rebind(t *testing.T) {
+	a := newApp(nil)
+	wrapped := 0
+	a.WrapHTTPServers(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped++
+			next.ServeHTTP(w, r)
+		})
+	})
+	states := 0
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+	s.ConnState = func(net.Conn, http.ConnState) { states++ }
+
+	// a second Bind wraps the handler and the ConnState once, as the first did
+	require.NoError(t, s.Bind(a))
+	first := s.ListenerAddr()
+	require.NoError(t, s.Bind(a))
+	assert.NotEqual(t, first, s.ListenerAddr())
+
+	rec := httptest.NewRecorder()
+	s.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, 1, wrapped)
+	s.ConnState(nil, http.StateNew)
+	assert.Equal(t, 1, states)
+}
+
+func TestHTTPServer_Drain(t *testing.T) {
+	a := newApp(nil)
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		if r.URL.Path == "/stuck" {
+			<-r.Context().Done()
+			return
+		}
+		<-release
+		_, _ = w.Write([]byte("drained"))
+	}))
+
+	require.NoError(t, s.Bind(a))
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	url := "http://" + s.ListenerAddr().String()
+	slow := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		assert.NoError(t, err)
+		slow <- res
+	}()
+	<-entered
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	assert.Eventually(t, func() bool {
+		_, err := http.Get(url)
+		return err != nil
+	}, time.Second, time.Millisecond, "new connections are refused while draining")
+
+	close(release)
+	res := <-slow
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, "drained", string(body))
+	assert.True(t, res.Close, "keep-alives are disabled while draining")
+	assert.NoError(t, <-shutdown)
+	assert.NoError(t, <-done)
+
+	s = app.NewHTTPServer("tcp://127.0.0.1:0", s.Handler)
+	require.NoError(t, s.Bind(a))
+	go func() { done <- s.Serve() }()
+
+	go func() {
+		_, err := http.Get("http://" + s.ListenerAddr().String() + "/stuck")
+		assert.Error(t, err)
+	}()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = s.Shutdown(ctx)
+	assert.EqualError(t, err, "drain timed out, force-closed 1 connections: context deadline exceeded")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, <-done)
+}
+
+func serve(t *testing.T, a *app.App, s *app.HTTPServer) func() {
+	require.NoError(t, s.Bind(a))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	return func() {
+		assert.NoError(t, s.Shutdown(context.Background()))
+		assert.NoError(t, <-done)
+	}
+}
+
+func protoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	res, err := client.Get(url)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	return res, string(body)
+}
+
+func TestHTTPServer_H2C(t *testing.T) {
+	a := newApp([]string{"APP_HTTP_H2C=true"})
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", protoHandler())
+	defer serve(t, a, s)()
+
+	url := "http://" + s.ListenerAddr().String() + "/"
+
+	_, body := get(t, http.DefaultClient, url)
+	assert.Equal(t, "HTTP/1.1", body)
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	defer client.CloseIdleConnections()
+
+	_, body = get(t, client, url)
+	assert.Equal(t, "HTTP/2.0", body)
+
+	a = newApp([]string{"APP_HTTP_H2C=maybe"})
+	assert.EqualError(t, app.NewHTTPServer("tcp://127.0.0.1:0", nil).Bind(a), "invalid APP_HTTP_H2C \"maybe\"")
+}
+
+func TestHTTPServer_HTTP3(t *testing.T) {
+	a := newApp([]string{"APP_HTTP_HTTP3=true"})
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", protoHandler())
+	assert.EqualError(t, s.Bind(a), "HTTP/3 requires a TLSConfig")
+
+	// borrow the test certificate from httptest
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	ts.Close()
+
+	s.TLSConfig = &tls.Config{Certificates: ts.TLS.Certificates, MinVersion: tls.VersionTLS12}
+	defer serve(t, a, s)()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+	clientTLS := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	url := "https://" + s.ListenerAddr().String() + "/"
+
+	tcpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS, ForceAttemptHTTP2: true}}
+	defer tcpClient.CloseIdleConnections()
+
+	res, body := get(t, tcpClient, url)
+	assert.Equal(t, "HTTP/2.0", body)
+	assert.Contains(t, res.Header.Get("Alt-Svc"), "h3=")
+
+	quicTransport := &http3.Transport{TLSClientConfig: clientTLS}
+	defer quicTransport.Close()
+
+	_, body = get(t, &http.Client{Transport: quicTransport}, url)
+	assert.Equal(t, "HTTP/3.0", body)
+}
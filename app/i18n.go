@@ -0,0 +1,34 @@
+package app
+
+import "github.com/demosdemon/golang-app-framework/i18n"
+
+// messages are the framework messages shown to users, in English.
+var messages = map[string]string{
+	"app.banner":             "%s started",
+	"app.banner.log_level":   "log level",
+	"app.banner.modules":     "modules",
+	"app.deprecated":         "%s is deprecated, %s",
+	"app.deprecated.removal": "%s is deprecated and will be removed in %s, %s",
+	"app.experimental":       "%s is experimental, set APP_EXPERIMENTAL=1 to use it",
+	"app.summary":            "Summary",
+	"app.summary.warning":    "warning: %s",
+	"app.warning":            "warning: %s",
+}
+
+// Catalog returns the message catalog of the app, which holds the framework messages. Commands add their own messages
+// and translations of the framework messages to it.
+func (a *App) Catalog() *i18n.Catalog {
+	a.catalogMu.Lock()
+	defer a.catalogMu.Unlock()
+
+	if a.catalog == nil {
+		a.catalog = i18n.NewCatalog()
+		a.catalog.AddStrings(i18n.DefaultLocale, messages)
+	}
+	return a.catalog
+}
+
+// Localizer returns a Localizer of the app Catalog for the locales detected from the app Environment.
+func (a *App) Localizer() *i18n.Localizer {
+	return a.Catalog().Localizer(i18n.Detect(a.LookupEnv)...)
+}
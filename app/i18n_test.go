@@ -0,0 +1,18 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApp_Localizer(t *testing.T) {
+	a := newApp([]string{"LANG=de_AT.UTF-8"})
+	assert.Equal(t, "Summary", a.Localizer().T("app.summary"))
+
+	a.Catalog().AddStrings("de", map[string]string{"app.summary": "Zusammenfassung"})
+	l := a.Localizer()
+	assert.Equal(t, "de-AT", l.Locale())
+	assert.Equal(t, "Zusammenfassung", l.T("app.summary"))
+	assert.Equal(t, "warning: disk full", l.T("app.summary.warning", "disk full"))
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"os"
+	"os/user"
+	"strings"
+)
+
+// Identity is what the app knows about the host and user it runs as. Set the fields of App.Identity to mock them;
+// empty fields are looked up from the OS on first use and cached.
+type Identity struct {
+	Hostname  string
+	User      *user.User
+	MachineID string
+}
+
+// identity returns the Identity, creating it if needed. a.identityMu must be held.
+func (a *App) identity() *Identity {
+	if a.Identity == nil {
+		a.Identity = &Identity{}
+	}
+	return a.Identity
+}
+
+// Hostname returns the host name, as reported by the kernel.
+func (a *App) Hostname() (string, error) {
+	a.identityMu.Lock()
+	defer a.identityMu.Unlock()
+
+	id := a.identity()
+	if id.Hostname == "" {
+		name, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		id.Hostname = name
+	}
+	return id.Hostname, nil
+}
+
+// CurrentUser returns the user the app runs as.
+func (a *App) CurrentUser() (*user.User, error) {
+	a.identityMu.Lock()
+	defer a.identityMu.Unlock()
+
+	id := a.identity()
+	if id.User == nil {
+		u, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		id.User = u
+	}
+	return id.User, nil
+}
+
+// MachineID returns the unique ID of the host: the systemd machine ID on Linux, the hardware UUID on macOS, and the
+// MachineGuid on Windows. The ID identifies the host to anyone who sees it, so send a hash of it, keyed by the app,
+// rather than the ID itself.
+func (a *App) MachineID() (string, error) {
+	a.identityMu.Lock()
+	defer a.identityMu.Unlock()
+
+	id := a.identity()
+	if id.MachineID == "" {
+		v, err := machineID()
+		if err != nil {
+			return "", err
+		}
+		id.MachineID = strings.TrimSpace(v)
+	}
+	return id.MachineID, nil
+}
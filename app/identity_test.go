@@ -0,0 +1,62 @@
+package app_test
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Identity(t *testing.T) {
+	a := newApp(nil)
+
+	expected, err := os.Hostname()
+	require.NoError(t, err)
+	host, err := a.Hostname()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, host)
+
+	current, err := user.Current()
+	require.NoError(t, err)
+	u, err := a.CurrentUser()
+	assert.NoError(t, err)
+	assert.Equal(t, current.Uid, u.Uid)
+
+	// the lookups are cached
+	require.NotNil(t, a.Identity)
+	assert.Equal(t, host, a.Identity.Hostname)
+	assert.Same(t, u, a.Identity.User)
+
+	if runtime.GOOS == "linux" {
+		if _, err := os.Stat("/etc/machine-id"); err != nil {
+			t.Skip("no machine ID")
+		}
+		id, err := a.MachineID()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, id)
+		assert.Equal(t, id, a.Identity.MachineID)
+	}
+}
+
+func TestApp_Identity_Mock(t *testing.T) {
+	a := newApp(nil)
+	mock := &user.User{Uid: "1000", Username: "someone"}
+	a.Identity = &app.Identity{Hostname: "example", User: mock, MachineID: "abc123"}
+
+	host, err := a.Hostname()
+	assert.NoError(t, err)
+	assert.Equal(t, "example", host)
+
+	u, err := a.CurrentUser()
+	assert.NoError(t, err)
+	assert.Same(t, mock, u)
+
+	id, err := a.MachineID()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", id)
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// DefaultConfigDirInterval is how often a directory loaded with LoadConfigDir is checked for updates when
+// APP_CONFIG_DIR_INTERVAL is not set.
+const DefaultConfigDirInterval = 10 * time.Second
+
+// serviceAccountNamespace is the file Kubernetes mounts in every pod with the namespace of the pod.
+const serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Pod describes the Kubernetes pod the app runs in, from the downward API. Kubernetes does not set these variables
+// on its own; map them in the container spec:
+//
+//	env:
+//	  - name: POD_NAME
+//	    valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	  - name: POD_NAMESPACE
+//	    valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+type Pod struct {
+	Name           string // POD_NAME
+	Namespace      string // POD_NAMESPACE, or the namespace of the service account
+	UID            string // POD_UID
+	IP             string // POD_IP
+	NodeName       string // NODE_NAME
+	ServiceAccount string // POD_SERVICE_ACCOUNT
+}
+
+// Pod returns the pod the app runs in, or nil if it does not run in Kubernetes.
+func (a *App) Pod() *Pod {
+	if _, ok := a.lookupEnv("KUBERNETES_SERVICE_HOST"); !ok {
+		return nil
+	}
+
+	get := func(key string) string {
+		v, _ := a.lookupEnv(key)
+		return strings.TrimSpace(v)
+	}
+	pod := &Pod{
+		Name:           get("POD_NAME"),
+		Namespace:      get("POD_NAMESPACE"),
+		UID:            get("POD_UID"),
+		IP:             get("POD_IP"),
+		NodeName:       get("NODE_NAME"),
+		ServiceAccount: get("POD_SERVICE_ACCOUNT"),
+	}
+	if pod.Namespace == "" {
+		if b, err := os.ReadFile(serviceAccountNamespace); err == nil {
+			pod.Namespace = strings.TrimSpace(string(b))
+		}
+	}
+	return pod
+}
+
+// Attrs returns the fields of the pod that are known as log attributes, named after the OpenTelemetry semantic
+// conventions. The app Logger adds them to every message.
+func (p *Pod) Attrs() map[string]interface{} {
+	attrs := make(map[string]interface{})
+	for key, v := range map[string]string{
+		"k8s.pod.name":            p.Name,
+		"k8s.namespace.name":      p.Namespace,
+		"k8s.pod.uid":             p.UID,
+		"k8s.pod.ip":              p.IP,
+		"k8s.node.name":           p.NodeName,
+		"k8s.serviceaccount.name": p.ServiceAccount,
+	} {
+		if v != "" {
+			attrs[key] = v
+		}
+	}
+	return attrs
+}
+
+// LoadConfigDir adds the files in a directory to the app Environment, one variable per file named after the file,
+// as Kubernetes mounts a ConfigMap volume. A single trailing newline is trimmed from the values, and file names that
+// are not valid variable names are upper cased with dots and dashes replaced by underscores, so that app.log-level
+// is APP_LOG_LEVEL. Variables already set in the process environment take precedence.
+//
+// The directory is checked for updates every APP_CONFIG_DIR_INTERVAL until the app Context is done. Kubernetes
+// updates mounted volumes atomically by swapping the ..data symbolic link; the files are read again when it changes,
+// and the variables are replaced with Reload.
+func (a *App) LoadConfigDir(dir string) error {
+	return a.loadConfigDir(dir, false)
+}
+
+// LoadSecretDir is LoadConfigDir for a directory holding secrets, such as a mounted Kubernetes Secret. The values
+// are redacted wherever the environment is shown.
+func (a *App) LoadSecretDir(dir string) error {
+	return a.loadConfigDir(dir, true)
+}
+
+func (a *App) loadConfigDir(dir string, secret bool) error {
+	interval, err := a.lookupDuration("APP_CONFIG_DIR_INTERVAL", DefaultConfigDirInterval)
+	if err != nil {
+		return err
+	}
+
+	stamp, err := dirStamp(dir)
+	if err != nil {
+		return err
+	}
+	if err := a.reloadConfigDir(dir, secret); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.Context.Done():
+				return
+			case <-ticker.C:
+			}
+
+			next, err := dirStamp(dir)
+			if err == nil && next == stamp {
+				continue
+			}
+			if err == nil {
+				err = a.reloadConfigDir(dir, secret)
+			}
+			if err != nil {
+				attrs := gomol.NewAttrsFromMap(map[string]interface{}{"dir": dir, "error": err.Error()})
+				_ = a.Logger().Warnm(attrs, "unable to reload configuration directory")
+				continue
+			}
+			stamp = next
+		}
+	}()
+	return nil
+}
+
+// reloadConfigDir reads the files in dir and replaces the variables previously loaded from it.
+func (a *App) reloadConfigDir(dir string, secret bool) error {
+	values, err := readConfigDir(dir)
+	if err != nil {
+		return err
+	}
+	changed := a.Reload(dir, values)
+
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+	if a.fileEnv == nil {
+		a.fileEnv = make(map[string]string)
+		a.sealedEnv = make(map[string]bool)
+	}
+	for _, k := range changed {
+		if _, ok := values[k]; !ok && a.fileEnv[k] == dir {
+			delete(a.fileEnv, k)
+			delete(a.sealedEnv, k)
+		}
+	}
+	for k := range values {
+		if a.remoteSource(k) == dir {
+			a.fileEnv[k] = dir
+			a.sealedEnv[k] = secret
+		}
+	}
+	return nil
+}
+
+// readConfigDir returns the variables in the files of dir, skipping hidden files and directories, such as the ..data
+// link and the timestamped directories Kubernetes keeps the files in.
+func readConfigDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		// the files are symbolic links into ..data, left dangling for a moment when a file is removed
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		name := entry.Name()
+		if !envNamePattern.MatchString(name) {
+			name = strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+		}
+		values[name] = strings.TrimSuffix(string(b), "\n")
+	}
+	return values, nil
+}
+
+// dirStamp identifies the contents of dir: the target of the ..data link Kubernetes swaps on updates, or the names,
+// sizes and modification times of the files in other directories.
+func dirStamp(dir string) (string, error) {
+	if target, err := os.Readlink(filepath.Join(dir, "..data")); err == nil {
+		return target, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	stamps := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fi, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		stamps = append(stamps, fmt.Sprintf("%s:%d:%d", entry.Name(), fi.ModTime().UnixNano(), fi.Size()))
+	}
+	sort.Strings(stamps)
+	return strings.Join(stamps, ";"), nil
+}
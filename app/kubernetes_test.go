@@ -0,0 +1,104 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// mountVolume lays out files as Kubernetes mounts a ConfigMap or Secret volume: in a timestamped directory linked
+// from ..data, with a link per file into ..data. Mounting again swaps ..data atomically.
+func mountVolume(t *testing.T, dir, version string, files map[string]string) {
+	data := filepath.Join(dir, "..2024_01_02_03_04_05."+version)
+	require.NoError(t, os.Mkdir(data, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(data, name), []byte(content), 0o644))
+		_ = os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name))
+	}
+	require.NoError(t, os.Symlink(filepath.Base(data), filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+}
+
+func TestApp_LoadConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	mountVolume(t, dir, "1", map[string]string{"DB_HOST": "db\n", "log.level": "debug", "FEATURE_X": "on"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := newApp([]string{"DB_PORT=5432", "FEATURE_X=off", "APP_CONFIG_DIR_INTERVAL=5ms"})
+	a.Context = ctx
+	require.NoError(t, a.LoadConfigDir(dir))
+
+	env := func(key string) string {
+		v, _ := a.LookupEnv(key)
+		return v
+	}
+	assert.Equal(t, "db", env("DB_HOST"))
+	assert.Equal(t, "debug", env("LOG_LEVEL"))
+	assert.Equal(t, "off", env("FEATURE_X"), "the process environment takes precedence")
+
+	a.DeclareConfig("db", app.ConfigKey{Name: "DB_HOST"})
+	for _, v := range a.EffectiveConfig() {
+		if v.Name == "DB_HOST" {
+			assert.Equal(t, app.SourceFile, v.Source)
+			assert.Equal(t, "db", v.Value)
+		}
+	}
+
+	mountVolume(t, dir, "2", map[string]string{"DB_HOST": "db2"})
+	assert.Eventually(t, func() bool { return env("DB_HOST") == "db2" }, 5*time.Second, 5*time.Millisecond)
+	_, ok := a.LookupEnv("LOG_LEVEL")
+	assert.False(t, ok, "variables removed from the volume are unset")
+
+	assert.Error(t, a.LoadConfigDir(filepath.Join(dir, "missing")))
+}
+
+func TestApp_LoadSecretDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "DB_PASSWORD"), []byte("hunter2\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "DB_USER"), []byte("app"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+
+	a := newApp([]string{"APP_CONFIG_DIR_INTERVAL=1h"}, "--print-env")
+	require.NoError(t, a.LoadSecretDir(dir))
+	v, _ := a.LookupEnv("DB_PASSWORD")
+	assert.Equal(t, "hunter2", v)
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.PrintEnvironment()
+	})
+	out := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, out, "DB_USER="+app.Redacted+"\n")
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestApp_Pod(t *testing.T) {
+	assert.Nil(t, newApp(nil).Pod())
+
+	a := newApp([]string{
+		"KUBERNETES_SERVICE_HOST=10.0.0.1",
+		"POD_NAME=web-7d9f8-abcde",
+		"POD_NAMESPACE=prod",
+		"NODE_NAME=node-1",
+	})
+	pod := a.Pod()
+	require.NotNil(t, pod)
+	assert.Equal(t, &app.Pod{Name: "web-7d9f8-abcde", Namespace: "prod", NodeName: "node-1"}, pod)
+	assert.Equal(t, map[string]interface{}{
+		"k8s.pod.name":       "web-7d9f8-abcde",
+		"k8s.namespace.name": "prod",
+		"k8s.node.name":      "node-1",
+	}, pod.Attrs())
+
+	_ = a.Logger().Info("hello")
+	_ = a.Logger().ShutdownLoggers()
+	assert.Regexp(t, `INFO.*\] hello \{.*"k8s.pod.name":"web-7d9f8-abcde"`, a.Stderr.(*bytes.Buffer).String())
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Listen opens the listener described by spec and tracks it so that it is closed when the app exits. The supported
+// specs are:
+//
+//	tcp://host:port        a TCP listener; tcp4:// and tcp6:// restrict the address family
+//	unix:///path/app.sock  a unix socket; a stale socket file is removed first and the file is removed on close,
+//	                       the optional mode query parameter (e.g. ?mode=0660) sets the socket file permissions
+//	fd://3                 an inherited file descriptor, e.g. from systemd socket activation; fd://name looks the
+//	                       descriptor up by name in LISTEN_FDNAMES
+func (a *App) Listen(spec string) (net.Listener, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen spec %q: %v", spec, err)
+	}
+
+	l, err := a.inheritedListener(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case l != nil:
+		// handed over by the process that started us during an upgrade
+	case u.Scheme == "tcp", u.Scheme == "tcp4", u.Scheme == "tcp6":
+		l, err = net.Listen(u.Scheme, u.Host)
+	case u.Scheme == "unix":
+		l, err = listenUnix(u)
+	case u.Scheme == "fd":
+		l, err = a.listenFD(u)
+	default:
+		return nil, fmt.Errorf("invalid listen spec %q: unsupported scheme %q", spec, u.Scheme)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.listenersMu.Lock()
+	a.listeners = append(a.listeners, trackedListener{spec: spec, listener: l})
+	a.listenersMu.Unlock()
+
+	return l, nil
+}
+
+type trackedListener struct {
+	spec     string
+	listener net.Listener
+}
+
+func (a *App) closeListeners() {
+	a.listenersMu.Lock()
+	defer a.listenersMu.Unlock()
+
+	for _, t := range a.listeners {
+		// the listener may have already been closed by its owner
+		_ = t.listener.Close()
+	}
+	a.listeners = nil
+}
+
+func listenUnix(u *url.URL) (net.Listener, error) {
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("listen unix %s: address already in use", path)
+		}
+
+		// nobody is listening on the other end, so the socket file was left behind by a previous process
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode := u.Query().Get("mode"); mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err == nil {
+			err = os.Chmod(path, os.FileMode(perm))
+		}
+
+		if err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("invalid socket mode %q: %v", mode, err)
+		}
+	}
+
+	return l, nil
+}
+
+const listenFDsStart = 3
+
+func (a *App) listenFD(u *url.URL) (net.Listener, error) {
+	name := u.Host
+	if name == "" {
+		name = u.Opaque
+	}
+
+	if pid, ok := a.LookupEnv("LISTEN_PID"); ok && pid != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("listen fd %s: LISTEN_PID %s does not match this process", name, pid)
+	}
+
+	fd, err := strconv.Atoi(name)
+	if err != nil {
+		names, _ := a.LookupEnv("LISTEN_FDNAMES")
+		fd = -1
+		for idx, v := range strings.Split(names, ":") {
+			if v == name {
+				fd = listenFDsStart + idx
+				break
+			}
+		}
+
+		if fd < 0 {
+			return nil, fmt.Errorf("listen fd %s: no such descriptor in LISTEN_FDNAMES", name)
+		}
+	}
+
+	return fileListener(fd, "fd://"+name)
+}
+
+func fileListener(fd int, name string) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), name)
+	if f == nil {
+		return nil, fmt.Errorf("listen %s: invalid descriptor", name)
+	}
+	defer f.Close()
+
+	// FileListener duplicates the descriptor so the original can be closed
+	return net.FileListener(f)
+}
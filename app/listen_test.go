@@ -0,0 +1,100 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Listen(t *testing.T) {
+	a := newApp(nil)
+
+	l, err := a.Listen("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	_, err = a.Listen("udp://127.0.0.1:0")
+	assert.EqualError(t, err, "invalid listen spec \"udp://127.0.0.1:0\": unsupported scheme \"udp\"")
+
+	_, err = a.Listen("://")
+	assert.Error(t, err)
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	_, err = l.Accept()
+	assert.Error(t, err)
+}
+
+func TestApp_Listen_Unix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.sock")
+
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	a := newApp(nil)
+
+	l, err := a.Listen("unix://" + path + "?mode=0600")
+	require.NoError(t, err)
+	assert.Equal(t, path, l.Addr().String())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = a.Listen("unix://" + path)
+	assert.EqualError(t, err, "listen unix "+path+": address already in use")
+
+	_, err = a.Listen("unix://" + filepath.Join(dir, "other.sock") + "?mode=rw")
+	assert.Error(t, err)
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestApp_Listen_FD(t *testing.T) {
+	inherited, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer inherited.Close()
+
+	f, err := inherited.(*net.TCPListener).File()
+	require.NoError(t, err)
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	a := newApp([]string{"LISTEN_FDNAMES=http:https"})
+
+	l, err := a.Listen("fd://" + strconv.Itoa(fd))
+	require.NoError(t, err)
+	assert.Equal(t, inherited.Addr().String(), l.Addr().String())
+	assert.NoError(t, l.Close())
+
+	_, err = a.Listen("fd://admin")
+	assert.EqualError(t, err, "listen fd admin: no such descriptor in LISTEN_FDNAMES")
+
+	a = newApp([]string{"LISTEN_PID=1"})
+	_, err = a.Listen("fd://3")
+	assert.EqualError(t, err, "listen fd 3: LISTEN_PID 1 does not match this process")
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+)
+
+var platformUUID = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+func machineID() (string, error) {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return "", err
+	}
+	m := platformUUID.FindSubmatch(out)
+	if m == nil {
+		return "", errors.New("IOPlatformUUID not found")
+	}
+	return string(m[1]), nil
+}
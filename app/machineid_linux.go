@@ -0,0 +1,22 @@
+package app
+
+import (
+	"errors"
+	"os"
+)
+
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+func machineID() (string, error) {
+	var err error
+	for _, path := range machineIDFiles {
+		var b []byte
+		if b, err = os.ReadFile(path); err == nil && len(b) > 0 {
+			return string(b), nil
+		}
+	}
+	if err == nil {
+		err = errors.New("machine ID is empty")
+	}
+	return "", err
+}
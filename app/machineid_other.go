@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package app
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func machineID() (string, error) {
+	return "", fmt.Errorf("machine ID is not supported on %s", runtime.GOOS)
+}
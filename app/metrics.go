@@ -0,0 +1,18 @@
+package app
+
+import (
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// Metrics returns the app metrics registry, creating it on first use. The registry is an http.Handler and can be
+// served with an HTTPServer to expose the metrics to Prometheus.
+func (a *App) Metrics() *metrics.Registry {
+	a.metricsMu.Lock()
+	defer a.metricsMu.Unlock()
+
+	if a.metrics == nil {
+		a.metrics = metrics.NewRegistry()
+	}
+
+	return a.metrics
+}
@@ -0,0 +1,35 @@
+package app_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+func TestApp_Metrics(t *testing.T) {
+	a := newApp(nil)
+
+	n := rand.Intn(8) + 2
+	ch := make(chan *metrics.Registry, n)
+
+	wg := new(sync.WaitGroup)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			ch <- a.Metrics()
+		}()
+	}
+	wg.Wait()
+	close(ch)
+
+	r := <-ch
+	assert.NotNil(t, r)
+	for o := range ch {
+		assert.Same(t, r, o)
+	}
+}
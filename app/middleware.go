@@ -0,0 +1,44 @@
+package app
+
+import "net/http"
+
+// WrapHTTPServers adds middleware every HTTPServer applies to its handler when it is bound, inside the recording of
+// the RED metrics, so that the responses of the middleware are recorded too. The middleware added first wraps the
+// others.
+func (a *App) WrapHTTPServers(mw func(http.Handler) http.Handler) {
+	a.wrapMu.Lock()
+	defer a.wrapMu.Unlock()
+
+	a.serverWraps = append(a.serverWraps, mw)
+}
+
+// WrapHTTPClients adds middleware HTTPTransport applies to the RoundTrippers it returns, inside the recording of the
+// RED metrics. The middleware added first wraps the others.
+func (a *App) WrapHTTPClients(mw func(http.RoundTripper) http.RoundTripper) {
+	a.wrapMu.Lock()
+	defer a.wrapMu.Unlock()
+
+	a.clientWraps = append(a.clientWraps, mw)
+}
+
+// wrapHandler applies the middleware added with WrapHTTPServers to h.
+func (a *App) wrapHandler(h http.Handler) http.Handler {
+	a.wrapMu.Lock()
+	defer a.wrapMu.Unlock()
+
+	for idx := len(a.serverWraps) - 1; idx >= 0; idx-- {
+		h = a.serverWraps[idx](h)
+	}
+	return h
+}
+
+// wrapTransport applies the middleware added with WrapHTTPClients to rt.
+func (a *App) wrapTransport(rt http.RoundTripper) http.RoundTripper {
+	a.wrapMu.Lock()
+	defer a.wrapMu.Unlock()
+
+	for idx := len(a.clientWraps) - 1; idx >= 0; idx-- {
+		rt = a.clientWraps[idx](rt)
+	}
+	return rt
+}
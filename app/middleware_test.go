@@ -0,0 +1,61 @@
+package app_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestApp_WrapHTTPServers(t *testing.T) {
+	a := newApp(nil)
+	var order []string
+	wrap := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				if name == "inner" {
+					w.WriteHeader(http.StatusTeapot)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	a.WrapHTTPServers(wrap("outer"))
+	a.WrapHTTPServers(wrap("inner"))
+
+	url, shutdown := serveHTTP(t, a, app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler()))
+	defer shutdown()
+
+	assert.Equal(t, http.StatusTeapot, getStatus(t, url+"/teapot"))
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	// the responses of the middleware are recorded
+	assert.Equal(t, 1.0, a.Metrics().Counter("http_server_requests_total",
+		metrics.Labels{"method": "GET", "route": "other", "code": "418"}).Value())
+}
+
+func TestApp_WrapHTTPClients(t *testing.T) {
+	a := newApp(nil)
+	a.WrapHTTPClients(func(http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: r}, nil
+		})
+	})
+
+	res, err := (&http.Client{Transport: a.HTTPTransport(nil)}).Get("http://example.invalid/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	assert.Equal(t, 1.0, a.Metrics().Counter("http_client_requests_total",
+		metrics.Labels{"method": "GET", "host": "example.invalid", "code": "418"}).Value())
+}
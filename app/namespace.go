@@ -0,0 +1,102 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/aphistic/gomol"
+)
+
+// Namespace is a view over the app Environment in which a key such as HOST is looked up as the variable with the
+// namespace name and an underscore in front, such as DB_HOST, so that a reusable module reads its own settings
+// wherever it is used. Its LookupEnv can be passed to FromEnv functions: with the prefix APP_CACHE_, a cache reads
+// SESSIONS_APP_CACHE_TTL in the SESSIONS namespace.
+type Namespace struct {
+	app  *App
+	name string
+
+	warned map[string]bool
+}
+
+// Namespace returns the namespace with the name, the same one for every call with the name. Namespaces may nest, as
+// DB and DB_REPLICA do, but a variable belongs to the most specific namespace: DB_REPLICA_HOST is in DB_REPLICA. A
+// lookup in DB of REPLICA_HOST still returns the variable, but logs a warning, as the two namespaces conflict.
+func (a *App) Namespace(name string) *Namespace {
+	name = strings.Trim(name, "_")
+
+	a.namespaceMu.Lock()
+	defer a.namespaceMu.Unlock()
+
+	if a.namespaces == nil {
+		a.namespaces = make(map[string]*Namespace)
+	}
+	n, ok := a.namespaces[name]
+	if !ok {
+		n = &Namespace{app: a, name: name, warned: make(map[string]bool)}
+		a.namespaces[name] = n
+	}
+	return n
+}
+
+// Name returns the name of the namespace.
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Prefix returns the prefix of the variables in the namespace: its name and an underscore.
+func (n *Namespace) Prefix() string {
+	return n.name + "_"
+}
+
+// Namespace returns the namespace nested in this one, named with both names.
+func (n *Namespace) Namespace(name string) *Namespace {
+	return n.app.Namespace(n.Prefix() + strings.Trim(name, "_"))
+}
+
+// LookupEnv looks up the key in the namespace with App.LookupEnv.
+func (n *Namespace) LookupEnv(key string) (string, bool) {
+	variable := n.Prefix() + key
+	if owner := n.app.namespaceOf(variable); owner != n {
+		n.conflict(variable, owner)
+	}
+	return n.app.LookupEnv(variable)
+}
+
+// Environ returns the variables in the namespace, with the prefix removed from their names. Variables that belong to
+// a namespace nested in this one are left out.
+func (n *Namespace) Environ() Environ {
+	var env Environ
+	for _, line := range n.app.environ().Snapshot() {
+		k, _ := splitEnv(line)
+		if strings.HasPrefix(k, n.Prefix()) && n.app.namespaceOf(k) == n {
+			v, _ := n.app.LookupEnv(k)
+			env = append(env, strings.TrimPrefix(k, n.Prefix())+"="+v)
+		}
+	}
+	return env
+}
+
+// namespaceOf returns the most specific namespace the variable belongs to, or nil.
+func (a *App) namespaceOf(variable string) *Namespace {
+	a.namespaceMu.Lock()
+	defer a.namespaceMu.Unlock()
+
+	var owner *Namespace
+	for _, n := range a.namespaces {
+		if strings.HasPrefix(variable, n.Prefix()) && (owner == nil || len(n.name) > len(owner.name)) {
+			owner = n
+		}
+	}
+	return owner
+}
+
+func (n *Namespace) conflict(variable string, owner *Namespace) {
+	n.app.namespaceMu.Lock()
+	warned := n.warned[variable]
+	n.warned[variable] = true
+	n.app.namespaceMu.Unlock()
+
+	if !warned {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"namespace": n.name, "owner": owner.name})
+		_ = n.app.Logger().Warnm(attrs, "%s read in namespace %s belongs to namespace %s", variable, n.name, owner.name)
+	}
+}
@@ -0,0 +1,63 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/cache"
+)
+
+func TestApp_Namespace(t *testing.T) {
+	a := newApp([]string{
+		"HOST=global",
+		"DB_HOST=primary",
+		"DB_PORT=5432",
+		"DB_REPLICA_HOST=replica",
+		"SESSIONS_APP_CACHE_TTL=1m",
+	})
+
+	db := a.Namespace("DB")
+	assert.Same(t, db, a.Namespace("DB_"))
+	assert.Equal(t, "DB", db.Name())
+	assert.Equal(t, "DB_", db.Prefix())
+
+	v, ok := db.LookupEnv("HOST")
+	assert.True(t, ok)
+	assert.Equal(t, "primary", v)
+
+	_, ok = db.LookupEnv("MISSING")
+	assert.False(t, ok)
+
+	replica := db.Namespace("REPLICA")
+	assert.Same(t, replica, a.Namespace("DB_REPLICA"))
+	v, _ = replica.LookupEnv("HOST")
+	assert.Equal(t, "replica", v)
+
+	assert.Equal(t, app.Environ{"HOST=primary", "PORT=5432"}, db.Environ())
+	assert.Equal(t, app.Environ{"HOST=replica"}, replica.Environ())
+
+	config, err := cache.FromEnv(a.Namespace("SESSIONS").LookupEnv, cache.DefaultPrefix)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, config.TTL)
+}
+
+func TestNamespace_Conflict(t *testing.T) {
+	a := newApp([]string{"DB_REPLICA_HOST=replica"})
+	db := a.Namespace("DB")
+	a.Namespace("DB_REPLICA")
+
+	for range 2 {
+		v, ok := db.LookupEnv("REPLICA_HOST")
+		assert.True(t, ok)
+		assert.Equal(t, "replica", v)
+	}
+
+	_ = a.Logger().ShutdownLoggers()
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Regexp(t, `WARN.*\] DB_REPLICA_HOST read in namespace DB belongs to namespace DB_REPLICA \{`, logs)
+	assert.Equal(t, 1, bytes.Count([]byte(logs), []byte("belongs to namespace")))
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// syncWriter serializes writes with the other app output, so output from concurrent goroutines and the logger never
+// interleaves mid-write. When OutputBuffer is set, writes are held until Flush or the buffer fills.
+type syncWriter struct {
+	a    *App
+	w    io.Writer
+	buf  *bufio.Writer
+	held *bytes.Buffer // set while the writes are held whatever the OutputBuffer, see holdErrOutput
+}
+
+func (a *App) newSyncWriter(w io.Writer) *syncWriter {
+	s := &syncWriter{a: a, w: w}
+	if a.OutputBuffer > 0 {
+		s.buf = bufio.NewWriterSize(w, a.OutputBuffer)
+	}
+	return s
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.a.outputMu.Lock()
+	defer s.a.outputMu.Unlock()
+
+	// flush the other stream first so a terminal showing both sees them in the order they were written
+	for _, other := range [...]*syncWriter{s.a.stdout, s.a.stderr} {
+		if other != nil && other != s {
+			_ = other.flush()
+		}
+	}
+
+	if s.a.session != nil {
+		_, _ = s.a.session.Write(p)
+	}
+
+	if s.held != nil {
+		return s.held.Write(p)
+	}
+	return s.write(p)
+}
+
+func (s *syncWriter) write(p []byte) (int, error) {
+	if s.buf == nil {
+		return s.w.Write(p)
+	}
+	return s.buf.Write(p)
+}
+
+func (s *syncWriter) flush() error {
+	if s.buf == nil {
+		return nil
+	}
+	return s.buf.Flush()
+}
+
+// Output returns the writer for command output on Stdout. Writes are synchronized with ErrOutput and buffered when
+// OutputBuffer is set.
+//
+// Output also handles the reader going away, as when the output is piped to head. The first write that fails with
+// EPIPE cancels the Context created by New, makes Run return without an error, and makes Exit use ExitBrokenPipe.
+// That write and every later one return ErrStdoutClosed instead of the write error.
+func (a *App) Output() io.Writer {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	if a.stdout == nil {
+		a.openSessionLog()
+		if f, ok := a.Stdout.(*os.File); ok && f.Fd() == 1 {
+			// the runtime kills the process on EPIPE from fd 1 unless SIGPIPE is being watched
+			signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+		}
+		a.stdout = a.newSyncWriter(&pipeWriter{a: a, w: a.Stdout})
+	}
+	return a.stdout
+}
+
+// ErrOutput returns the writer for diagnostics on Stderr, which the app Logger also writes to. Writes are synchronized
+// with Output and buffered when OutputBuffer is set. Both writers copy their output to the SessionLog, if any.
+func (a *App) ErrOutput() io.Writer {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	if a.stderr == nil {
+		a.openSessionLog()
+		a.stderr = a.newSyncWriter(a.Stderr)
+	}
+	return a.stderr
+}
+
+// holdErrOutput makes ErrOutput, and so the app Logger, keep what is written to it until the returned function is
+// called, which writes it.
+func (a *App) holdErrOutput() func() {
+	s := a.ErrOutput().(*syncWriter)
+	a.outputMu.Lock()
+	s.held = new(bytes.Buffer)
+	a.outputMu.Unlock()
+
+	return func() {
+		a.outputMu.Lock()
+		defer a.outputMu.Unlock()
+
+		held := s.held
+		s.held = nil
+		if held != nil && held.Len() > 0 {
+			_, _ = s.write(held.Bytes())
+		}
+	}
+}
+
+// Flush writes any output held by Output and ErrOutput. Exit calls Flush.
+func (a *App) Flush() error {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	var errs []error
+	for _, s := range [...]*syncWriter{a.stdout, a.stderr} {
+		if s == nil {
+			continue
+		}
+		if err := s.flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
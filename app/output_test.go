@@ -0,0 +1,82 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// terminal records the writes of both streams in the order they arrive, as a terminal showing both would.
+type terminal struct {
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+type stream struct {
+	t    *terminal
+	name string
+}
+
+func (s stream) Write(p []byte) (int, error) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	fmt.Fprintf(&s.t.out, "%s:%s", s.name, p)
+	return len(p), nil
+}
+
+func TestApp_Output_Sync(t *testing.T) {
+	a := newApp(nil)
+	var buf bytes.Buffer
+	a.Stdout = &buf
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// separate writes for the same line interleave without the lock
+				_, _ = fmt.Fprintf(a.Output(), "%d:%d %s\n", i, j, strings.Repeat("x", 64))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 800)
+	for _, line := range lines {
+		assert.Regexp(t, `^\d:\d+ x{64}$`, line)
+	}
+}
+
+func TestApp_Output_Buffer(t *testing.T) {
+	term := new(terminal)
+	a := newApp(nil)
+	a.Stdout = stream{term, "out"}
+	a.Stderr = stream{term, "err"}
+	a.OutputBuffer = 4096
+
+	_, err := fmt.Fprint(a.Output(), "one ")
+	require.NoError(t, err)
+	_, err = fmt.Fprint(a.Output(), "two\n")
+	require.NoError(t, err)
+	assert.Empty(t, term.out.String())
+
+	// each stream flushes the other before buffering its own output
+	_, err = fmt.Fprint(a.ErrOutput(), "warning\n")
+	require.NoError(t, err)
+	assert.Equal(t, "out:one two\n", term.out.String())
+	_, err = fmt.Fprint(a.Output(), "three\n")
+	require.NoError(t, err)
+	assert.Equal(t, "out:one two\nerr:warning\n", term.out.String())
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+	assert.Equal(t, "out:one two\nerr:warning\nout:three\n", term.out.String())
+}
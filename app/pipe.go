@@ -0,0 +1,74 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+)
+
+// ExitBrokenPipe is the status Exit uses once the reader of Stdout has gone away. Shells report the same status for
+// a process killed by SIGPIPE.
+const ExitBrokenPipe = 128 + 13
+
+// ErrStdoutClosed is returned by writes to Output once the reader of Stdout has gone away. It matches syscall.EPIPE.
+var ErrStdoutClosed = fmt.Errorf("stdout: %w", syscall.EPIPE)
+
+// IsBrokenPipe reports whether err was caused by writing to a pipe or socket whose reader has gone away.
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrClosedPipe)
+}
+
+type pipeWriter struct {
+	a *App
+	w io.Writer
+}
+
+func (p *pipeWriter) Write(b []byte) (int, error) {
+	select {
+	case <-p.a.StdoutClosed():
+		return 0, ErrStdoutClosed
+	default:
+	}
+
+	n, err := p.w.Write(b)
+	if err != nil && IsBrokenPipe(err) {
+		p.a.closeStdout()
+		return n, ErrStdoutClosed
+	}
+	return n, err
+}
+
+func (a *App) ensureStdoutClosed() chan struct{} {
+	a.pipeMu.Lock()
+	defer a.pipeMu.Unlock()
+
+	if a.stdoutClosed == nil {
+		a.stdoutClosed = make(chan struct{})
+	}
+	return a.stdoutClosed
+}
+
+// StdoutClosed returns a channel that is closed once a write to Output finds the reader of Stdout has gone away.
+func (a *App) StdoutClosed() <-chan struct{} {
+	return a.ensureStdoutClosed()
+}
+
+func (a *App) closeStdout() {
+	ch := a.ensureStdoutClosed()
+	a.pipeOnce.Do(func() {
+		close(ch)
+		if a.cancel != nil {
+			a.cancel()
+		}
+	})
+}
+
+func (a *App) isStdoutClosed() bool {
+	select {
+	case <-a.StdoutClosed():
+		return true
+	default:
+		return false
+	}
+}
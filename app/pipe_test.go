@@ -0,0 +1,66 @@
+package app_test
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Output(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+
+	a := app.New()
+	a.Stdout = w
+	out := a.Output()
+	assert.Same(t, out, a.Output())
+
+	_, err = io.WriteString(out, "hello\n")
+	assert.NoError(t, err)
+	assert.NoError(t, a.Context.Err())
+
+	require.NoError(t, r.Close())
+	_, err = io.WriteString(out, "world\n")
+	assert.Equal(t, app.ErrStdoutClosed, err)
+	assert.ErrorIs(t, err, syscall.EPIPE)
+
+	<-a.StdoutClosed()
+	<-a.Context.Done()
+
+	_, err = io.WriteString(out, "again\n")
+	assert.Equal(t, app.ErrStdoutClosed, err)
+}
+
+func TestApp_Output_Exit(t *testing.T) {
+	r, w := io.Pipe()
+	require.NoError(t, r.Close())
+
+	a := newApp(nil)
+	a.Stdout = w
+	a.Register("public", newFakeServer("public", new(recorder)))
+
+	done := make(chan error, 1)
+	go func() { done <- a.Run() }()
+
+	_, err := a.Output().Write([]byte("hello\n"))
+	assert.True(t, app.IsBrokenPipe(err))
+	assert.NoError(t, <-done)
+
+	assert.PanicsWithValue(t, "system exit 141", func() {
+		a.Exit(1)
+	})
+}
+
+func TestIsBrokenPipe(t *testing.T) {
+	assert.True(t, app.IsBrokenPipe(&os.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}))
+	assert.True(t, app.IsBrokenPipe(io.ErrClosedPipe))
+	assert.False(t, app.IsBrokenPipe(io.ErrShortWrite))
+	assert.False(t, app.IsBrokenPipe(nil))
+}
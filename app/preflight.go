@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// DefaultPreflightTimeout is how long the pre-flight checks may take when APP_PREFLIGHT_TIMEOUT is not set.
+const DefaultPreflightTimeout = time.Minute
+
+type namedCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// PreflightResult is the outcome of a pre-flight check.
+type PreflightResult struct {
+	Name    string
+	Elapsed time.Duration
+	Err     error // nil if the check passed
+}
+
+// Preflight adds a check Run makes once every server is bound, before serving them: the pre-flight phase, verifying
+// the external dependencies of the app, such as the migrations of its database being applied, its cache being
+// reachable, or its buckets existing, and warming up its connection pools. The gRPC health service, and the parent
+// process of an upgrade, only learn that the app is ready once every check passes; if any fails, Run shuts the
+// servers down and returns the failures. Checks a module needs are added when it is set up:
+//
+//	a.Preflight("database", db.PingContext)
+//	a.Preflight("kubernetes", client.Check)
+//	a.Preflight("dependencies", func(ctx context.Context) error { return a.WaitFor(ctx) })
+func (a *App) Preflight(name string, check func(ctx context.Context) error) {
+	a.preflightMu.Lock()
+	defer a.preflightMu.Unlock()
+
+	a.preflight = append(a.preflight, namedCheck{name: name, check: check})
+}
+
+// PreflightResults returns the outcome of the pre-flight checks of the last Run, in the order they were added.
+func (a *App) PreflightResults() []PreflightResult {
+	a.preflightMu.Lock()
+	defer a.preflightMu.Unlock()
+
+	return append([]PreflightResult(nil), a.preflightResults...)
+}
+
+// runPreflight makes the pre-flight checks together, giving up on those still running once APP_PREFLIGHT_TIMEOUT
+// elapses. Each outcome is logged, followed by a report of the phase.
+func (a *App) runPreflight() error {
+	a.preflightMu.Lock()
+	checks := append([]namedCheck(nil), a.preflight...)
+	a.preflightMu.Unlock()
+	if len(checks) == 0 {
+		return nil
+	}
+
+	timeout, err := a.lookupDuration("APP_PREFLIGHT_TIMEOUT", DefaultPreflightTimeout)
+	if err != nil {
+		return err
+	}
+	ctx := a.Context
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		idx    int
+		result PreflightResult
+	}
+	started := a.clock().Now()
+	done := make(chan outcome, len(checks))
+	for i, c := range checks {
+		go func(i int, c namedCheck) {
+			began := a.clock().Now()
+			err := c.check(ctx)
+			done <- outcome{idx: i, result: PreflightResult{Name: c.name, Elapsed: a.clock().Since(began), Err: err}}
+		}(i, c)
+	}
+
+	results := make([]PreflightResult, len(checks))
+	finished := make([]bool, len(checks))
+	for range checks {
+		select {
+		case o := <-done:
+			results[o.idx], finished[o.idx] = o.result, true
+			continue
+		case <-ctx.Done():
+		}
+		// the checks still running are given up on, they fail with the error of the context
+		for i, c := range checks {
+			if !finished[i] {
+				results[i] = PreflightResult{Name: c.name, Elapsed: a.clock().Since(started), Err: ctx.Err()}
+			}
+		}
+		break
+	}
+
+	var failed []error
+	for _, r := range results {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"check": r.Name, "elapsed": r.Elapsed.String()})
+		if r.Err != nil {
+			_ = a.Logger().Errorm(attrs, "pre-flight check %s failed: %v", r.Name, r.Err)
+			failed = append(failed, fmt.Errorf("pre-flight check %s: %v", r.Name, r.Err))
+		} else {
+			_ = a.Logger().Infom(attrs, "pre-flight check %s passed", r.Name)
+		}
+	}
+
+	a.preflightMu.Lock()
+	a.preflightResults = results
+	a.preflightMu.Unlock()
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+		"passed":  len(checks) - len(failed),
+		"failed":  len(failed),
+		"elapsed": a.clock().Since(started).String(),
+	})
+	if len(failed) > 0 {
+		_ = a.Logger().Errorm(attrs, "%d of %d pre-flight checks failed", len(failed), len(checks))
+		return errors.Join(failed...)
+	}
+	_ = a.Logger().Infom(attrs, "%d pre-flight checks passed", len(checks))
+	return nil
+}
@@ -0,0 +1,76 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Preflight(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+	a.Preflight("database", func(context.Context) error {
+		r.record("check database")
+		return nil
+	})
+
+	go a.HandleError(nil)
+
+	require.NoError(t, a.Run())
+	assert.Equal(t, []string{"bind public", "check database", "shutdown public"}, r.Events())
+
+	results := a.PreflightResults()
+	require.Len(t, results, 1)
+	assert.Equal(t, "database", results[0].Name)
+	assert.NoError(t, results[0].Err)
+}
+
+func TestApp_Preflight_Failed(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+	a.Preflight("database", func(context.Context) error { return errors.New("migrations not applied") })
+	a.Preflight("cache", func(context.Context) error { return nil })
+
+	err := a.Run()
+	assert.EqualError(t, err, "pre-flight check database: migrations not applied")
+	assert.Equal(t, []string{"bind public", "shutdown public"}, r.Events())
+
+	results := a.PreflightResults()
+	require.Len(t, results, 2)
+	assert.Equal(t, "database", results[0].Name)
+	assert.Error(t, results[0].Err)
+	assert.Equal(t, "cache", results[1].Name)
+	assert.NoError(t, results[1].Err)
+}
+
+func TestApp_Preflight_Timeout(t *testing.T) {
+	r := new(recorder)
+	a := newApp([]string{"APP_PREFLIGHT_TIMEOUT=10ms"})
+	a.Register("public", newFakeServer("public", r))
+
+	release := make(chan struct{})
+	defer close(release)
+	a.Preflight("bucket", func(context.Context) error {
+		<-release // a check ignoring its context is given up on all the same
+		return nil
+	})
+
+	err := a.Run()
+	assert.EqualError(t, err, "pre-flight check bucket: context deadline exceeded")
+	assert.Equal(t, []string{"bind public", "shutdown public"}, r.Events())
+	assert.ErrorIs(t, a.PreflightResults()[0].Err, context.DeadlineExceeded)
+}
+
+func TestApp_Preflight_InvalidTimeout(t *testing.T) {
+	a := newApp([]string{"APP_PREFLIGHT_TIMEOUT=soon"})
+	a.Register("public", newFakeServer("public", new(recorder)))
+	a.Preflight("database", func(context.Context) error { return nil })
+
+	assert.EqualError(t, a.Run(), `invalid APP_PREFLIGHT_TIMEOUT "soon"`)
+	assert.Empty(t, a.PreflightResults())
+}
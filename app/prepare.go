@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aphistic/gomol"
+)
+
+// Prepare sets up the process before the main body of the app runs. It is configured by the environment:
+//
+//	APP_UMASK    the file mode creation mask, in octal, e.g. 027
+//	APP_CHROOT   the directory to change the root directory to; this requires root
+//	APP_WORKDIR  the directory to change the working directory to, inside APP_CHROOT if set, / by default when
+//	             APP_CHROOT is set
+//
+// Every directory is checked before any change is made. The user named by APP_USER is resolved before entering the
+// chroot, which usually lacks the user database, for DropPrivileges to switch to afterwards. Prepare runs once; later
+// calls return the result of the first. Run calls Prepare after binding every server, so listeners may use paths
+// outside the chroot.
+func (a *App) Prepare() error {
+	a.prepareOnce.Do(func() {
+		a.prepareErr = a.prepare()
+	})
+	return a.prepareErr
+}
+
+func (a *App) prepare() error {
+	umask, root, dir := a.trimmedEnv("APP_UMASK"), a.trimmedEnv("APP_CHROOT"), a.trimmedEnv("APP_WORKDIR")
+	if umask == "" && root == "" && dir == "" {
+		return nil
+	}
+
+	mask := -1
+	if umask != "" {
+		m, err := strconv.ParseUint(umask, 8, 32)
+		if err != nil || m > 0o777 {
+			return fmt.Errorf("invalid APP_UMASK %q", umask)
+		}
+		mask = int(m)
+	}
+
+	if root != "" {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("invalid APP_CHROOT %q: not an absolute path", root)
+		}
+		if err := checkDir("APP_CHROOT", root, root); err != nil {
+			return err
+		}
+		if dir == "" {
+			dir = "/"
+		}
+	}
+
+	if dir != "" {
+		path := dir
+		if root != "" {
+			if !filepath.IsAbs(dir) {
+				return fmt.Errorf("invalid APP_WORKDIR %q: not an absolute path inside APP_CHROOT", dir)
+			}
+			path = filepath.Join(root, dir)
+		}
+		if err := checkDir("APP_WORKDIR", dir, path); err != nil {
+			return err
+		}
+	}
+
+	if mask >= 0 {
+		if err := setUmask(mask); err != nil {
+			return err
+		}
+	}
+
+	if root != "" {
+		if _, err := a.credential(); err != nil {
+			return err
+		}
+		if err := chroot(root); err != nil {
+			return fmt.Errorf("chroot %s: %v", root, err)
+		}
+	}
+
+	if dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			return err
+		}
+	}
+
+	attrs := map[string]interface{}{"umask": umask, "chroot": root, "workdir": dir}
+	_ = a.Logger().Debugm(gomol.NewAttrsFromMap(attrs), "prepared process")
+	return nil
+}
+
+// checkDir returns an error for the variable key with the value v if path is not an existing directory.
+func checkDir(key, v, path string) error {
+	fi, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("invalid %s %q: no such directory", key, v)
+	case err != nil:
+		return fmt.Errorf("invalid %s %q: %v", key, v, err)
+	case !fi.IsDir():
+		return fmt.Errorf("invalid %s %q: not a directory", key, v)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+//go:build !windows
+// +build !windows
+
+package app_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Prepare(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	t.Chdir(".")
+	defer syscall.Umask(syscall.Umask(0o022))
+
+	a := newApp([]string{"APP_UMASK=027", "APP_WORKDIR=" + dir})
+	require.NoError(t, a.Prepare())
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, dir, wd)
+	assert.Equal(t, 0o027, syscall.Umask(0o027))
+
+	require.NoError(t, os.WriteFile("file", nil, 0o666))
+	fi, err := os.Stat("file")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	// a second call does nothing
+	require.NoError(t, os.Chdir("/"))
+	require.NoError(t, a.Prepare())
+	wd, err = os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, "/", wd)
+}
+
+func TestApp_Prepare_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		env []string
+		err string
+	}{
+		{[]string{"APP_UMASK=0999"}, `invalid APP_UMASK "0999"`},
+		{[]string{"APP_UMASK=01777"}, `invalid APP_UMASK "01777"`},
+		{[]string{"APP_WORKDIR=" + missing}, `invalid APP_WORKDIR "` + missing + `": no such directory`},
+		{[]string{"APP_WORKDIR=" + file}, `invalid APP_WORKDIR "` + file + `": not a directory`},
+		{[]string{"APP_CHROOT=relative"}, `invalid APP_CHROOT "relative": not an absolute path`},
+		{[]string{"APP_CHROOT=" + missing}, `invalid APP_CHROOT "` + missing + `": no such directory`},
+		{
+			[]string{"APP_CHROOT=" + dir, "APP_WORKDIR=srv"},
+			`invalid APP_WORKDIR "srv": not an absolute path inside APP_CHROOT`,
+		},
+		{[]string{"APP_CHROOT=" + dir, "APP_WORKDIR=/srv"}, `invalid APP_WORKDIR "/srv": no such directory`},
+	}
+
+	old := syscall.Umask(0o022)
+	defer syscall.Umask(old)
+
+	for _, tt := range tests {
+		assert.EqualError(t, newApp(tt.env).Prepare(), tt.err)
+		// nothing changes when the configuration is invalid
+		assert.Equal(t, 0o022, syscall.Umask(0o022))
+	}
+}
+
+// TestPrepareHelper enters a chroot in a child process started by TestApp_Prepare_Chroot, since a process cannot
+// leave it.
+func TestPrepareHelper(t *testing.T) {
+	if os.Getenv("PREPARE_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	require.NoError(t, newApp(os.Environ()).Prepare())
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	t.Log("wd:", wd)
+	_, err = os.Stat("/marker")
+	t.Log("marker:", err == nil)
+}
+
+func TestApp_Prepare_Chroot(t *testing.T) {
+	if syscall.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "srv"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "marker"), nil, 0o600))
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPrepareHelper$", "-test.v")
+	cmd.Env = append(os.Environ(), "PREPARE_HELPER=1", "APP_CHROOT="+root, "APP_WORKDIR=/srv")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Contains(t, string(out), "wd: /srv")
+	assert.Contains(t, string(out), "marker: true")
+}
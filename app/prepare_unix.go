@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import "syscall"
+
+func setUmask(mask int) error {
+	syscall.Umask(mask)
+	return nil
+}
+
+func chroot(path string) error {
+	return syscall.Chroot(path)
+}
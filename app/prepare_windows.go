@@ -0,0 +1,11 @@
+package app
+
+import "errors"
+
+func setUmask(int) error {
+	return errors.New("APP_UMASK is not supported on windows")
+}
+
+func chroot(string) error {
+	return errors.New("not supported on windows")
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/aphistic/gomol"
+)
+
+// credential is the user, group, and supplementary groups the process switches to.
+type credential struct {
+	uid    int
+	gid    int
+	groups []int
+}
+
+// DropPrivileges switches a process started as root to an unprivileged user once it has bound its privileged ports.
+// Run calls it after binding every server. It is configured by the environment:
+//
+//	APP_USER       the user name or ID to switch to; nothing is dropped when it is empty
+//	APP_GROUP      the group name or ID to switch to, the primary group of APP_USER by default
+//	APP_KEEP_CAPS  Linux capabilities to keep, e.g. CAP_NET_BIND_SERVICE,CAP_NET_RAW; every other is dropped
+//
+// The supplementary groups of the user replace those of the process. After switching, DropPrivileges verifies that
+// the process can no longer regain root and holds no capability beyond APP_KEEP_CAPS, and returns an error
+// otherwise. APP_KEEP_CAPS without APP_USER only drops capabilities. Keeping capabilities across the switch requires
+// a binary built with CGO_ENABLED=0.
+func (a *App) DropPrivileges() error {
+	name := a.trimmedEnv("APP_USER")
+	keep := a.trimmedEnv("APP_KEEP_CAPS")
+	if name == "" && keep == "" {
+		return nil
+	}
+
+	cred, err := a.credential()
+	if err != nil {
+		return err
+	}
+
+	if err := dropPrivileges(cred, keep); err != nil {
+		if name == "" {
+			return fmt.Errorf("drop privileges: %v", err)
+		}
+		return fmt.Errorf("drop privileges to %s: %v", name, err)
+	}
+
+	attrs := map[string]interface{}{"capabilities": keep}
+	if cred != nil {
+		attrs["uid"], attrs["gid"] = cred.uid, cred.gid
+	}
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(attrs), "dropped privileges")
+	return nil
+}
+
+func (a *App) trimmedEnv(key string) string {
+	v, _ := a.LookupEnv(key)
+	return strings.TrimSpace(v)
+}
+
+// credential returns the credential of APP_USER, or nil if it is not set. The credential is resolved once, so that
+// Prepare can resolve it before entering a chroot without the user database.
+func (a *App) credential() (*credential, error) {
+	a.credMu.Lock()
+	defer a.credMu.Unlock()
+
+	if a.cred == nil {
+		name := a.trimmedEnv("APP_USER")
+		if name == "" {
+			return nil, nil
+		}
+
+		cred, err := lookupCredential(name, a.trimmedEnv("APP_GROUP"))
+		if err != nil {
+			return nil, err
+		}
+		a.cred = cred
+	}
+	return a.cred, nil
+}
+
+// lookupCredential resolves the user and group, each given by name or ID, and the supplementary groups of the user.
+func lookupCredential(name, group string) (*credential, error) {
+	lookupUser := user.Lookup
+	if _, err := strconv.Atoi(name); err == nil {
+		lookupUser = user.LookupId
+	}
+	u, err := lookupUser(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APP_USER %q: %v", name, err)
+	}
+
+	cred := &credential{}
+	if cred.uid, err = strconv.Atoi(u.Uid); err != nil {
+		return nil, fmt.Errorf("invalid APP_USER %q: user ID %q is not numeric", name, u.Uid)
+	}
+
+	gid := u.Gid
+	if group != "" {
+		lookupGroup := user.LookupGroup
+		if _, err := strconv.Atoi(group); err == nil {
+			lookupGroup = user.LookupGroupId
+		}
+		g, err := lookupGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("invalid APP_GROUP %q: %v", group, err)
+		}
+		gid = g.Gid
+	}
+	if cred.gid, err = strconv.Atoi(gid); err != nil {
+		return nil, fmt.Errorf("invalid group ID %q", gid)
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the groups of %s: %v", name, err)
+	}
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil {
+			cred.groups = append(cred.groups, n)
+		}
+	}
+	return cred, nil
+}
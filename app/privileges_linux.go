@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	linuxCapabilityVersion3 = 0x20080522
+	prSetKeepCaps           = 8
+)
+
+// capabilities maps the Linux capability names to their numbers.
+var capabilities = map[string]uint{
+	"CAP_CHOWN":              0,
+	"CAP_DAC_OVERRIDE":       1,
+	"CAP_DAC_READ_SEARCH":    2,
+	"CAP_FOWNER":             3,
+	"CAP_FSETID":             4,
+	"CAP_KILL":               5,
+	"CAP_SETGID":             6,
+	"CAP_SETUID":             7,
+	"CAP_SETPCAP":            8,
+	"CAP_LINUX_IMMUTABLE":    9,
+	"CAP_NET_BIND_SERVICE":   10,
+	"CAP_NET_BROADCAST":      11,
+	"CAP_NET_ADMIN":          12,
+	"CAP_NET_RAW":            13,
+	"CAP_IPC_LOCK":           14,
+	"CAP_IPC_OWNER":          15,
+	"CAP_SYS_MODULE":         16,
+	"CAP_SYS_RAWIO":          17,
+	"CAP_SYS_CHROOT":         18,
+	"CAP_SYS_PTRACE":         19,
+	"CAP_SYS_PACCT":          20,
+	"CAP_SYS_ADMIN":          21,
+	"CAP_SYS_BOOT":           22,
+	"CAP_SYS_NICE":           23,
+	"CAP_SYS_RESOURCE":       24,
+	"CAP_SYS_TIME":           25,
+	"CAP_SYS_TTY_CONFIG":     26,
+	"CAP_MKNOD":              27,
+	"CAP_LEASE":              28,
+	"CAP_AUDIT_WRITE":        29,
+	"CAP_AUDIT_CONTROL":      30,
+	"CAP_SETFCAP":            31,
+	"CAP_MAC_OVERRIDE":       32,
+	"CAP_MAC_ADMIN":          33,
+	"CAP_SYSLOG":             34,
+	"CAP_WAKE_ALARM":         35,
+	"CAP_BLOCK_SUSPEND":      36,
+	"CAP_AUDIT_READ":         37,
+	"CAP_PERFMON":            38,
+	"CAP_BPF":                39,
+	"CAP_CHECKPOINT_RESTORE": 40,
+}
+
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+// parseCapabilities returns the bit mask of the comma separated capability names. The CAP_ prefix is optional.
+func parseCapabilities(s string) (uint64, error) {
+	var mask uint64
+	for _, name := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "CAP_") {
+			name = "CAP_" + name
+		}
+		n, ok := capabilities[name]
+		if !ok {
+			return 0, fmt.Errorf("invalid APP_KEEP_CAPS %q: unknown capability %s", s, name)
+		}
+		mask |= 1 << n
+	}
+	return mask, nil
+}
+
+// keepCapabilities sets whether every thread keeps its permitted capabilities when switching away from root.
+func keepCapabilities(keep bool) error {
+	var v uintptr
+	if keep {
+		v = 1
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, prSetKeepCaps, v, 0); errno != 0 {
+		return capabilityError("prctl(PR_SET_KEEPCAPS)", errno)
+	}
+	return nil
+}
+
+// setCapabilities limits the effective and permitted capabilities of every thread to mask and clears the inheritable
+// ones.
+func setCapabilities(mask uint64) error {
+	hdr := capHeader{version: linuxCapabilityVersion3}
+	data := [2]capData{
+		{effective: uint32(mask), permitted: uint32(mask)},
+		{effective: uint32(mask >> 32), permitted: uint32(mask >> 32)},
+	}
+	_, _, errno := syscall.AllThreadsSyscall(
+		syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0,
+	)
+	if errno != 0 {
+		return capabilityError("capset", errno)
+	}
+	return nil
+}
+
+// heldCapabilities returns the effective and permitted capabilities of the calling thread.
+func heldCapabilities() (uint64, error) {
+	hdr := capHeader{version: linuxCapabilityVersion3}
+	var data [2]capData
+	_, _, errno := syscall.RawSyscall(
+		syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0,
+	)
+	if errno != 0 {
+		return 0, fmt.Errorf("capget: %v", errno)
+	}
+
+	held := uint64(data[0].effective|data[0].permitted) | uint64(data[1].effective|data[1].permitted)<<32
+	return held, nil
+}
+
+func capabilityError(op string, errno syscall.Errno) error {
+	if errno == syscall.ENOTSUP {
+		return fmt.Errorf("%s: changing the capabilities of every thread requires a binary built with CGO_ENABLED=0",
+			op)
+	}
+	return fmt.Errorf("%s: %v", op, errno)
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package app
+
+import "errors"
+
+var errNoCapabilities = errors.New("APP_KEEP_CAPS is only supported on linux")
+
+func parseCapabilities(s string) (uint64, error) {
+	if s != "" {
+		return 0, errNoCapabilities
+	}
+	return 0, nil
+}
+
+func keepCapabilities(bool) error { return errNoCapabilities }
+
+func setCapabilities(uint64) error { return errNoCapabilities }
+
+// heldCapabilities reports no capabilities, the platform has none.
+func heldCapabilities() (uint64, error) { return 0, nil }
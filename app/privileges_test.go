@@ -0,0 +1,77 @@
+//go:build linux
+// +build linux
+
+package app_test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_DropPrivileges_Disabled(t *testing.T) {
+	assert.NoError(t, newApp(nil).DropPrivileges())
+}
+
+func TestApp_DropPrivileges_Invalid(t *testing.T) {
+	err := newApp([]string{"APP_USER=no-such-user-here"}).DropPrivileges()
+	assert.EqualError(t, err, `invalid APP_USER "no-such-user-here": user: unknown user no-such-user-here`)
+
+	err = newApp([]string{"APP_USER=nobody", "APP_GROUP=no-such-group-here"}).DropPrivileges()
+	assert.EqualError(t, err, `invalid APP_GROUP "no-such-group-here": group: unknown group no-such-group-here`)
+
+	err = newApp([]string{"APP_KEEP_CAPS=net_bind_service,CAP_BOGUS"}).DropPrivileges()
+	assert.EqualError(t, err, `drop privileges: invalid APP_KEEP_CAPS "net_bind_service,CAP_BOGUS": `+
+		`unknown capability CAP_BOGUS`)
+}
+
+// TestDropPrivilegesHelper drops the privileges of a child process started by TestApp_DropPrivileges, since a
+// process cannot get them back.
+func TestDropPrivilegesHelper(t *testing.T) {
+	if os.Getenv("DROP_PRIVILEGES_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	if err := newApp(os.Environ()).DropPrivileges(); err != nil {
+		t.Fatal(err)
+	}
+	status, err := os.ReadFile("/proc/self/status")
+	require.NoError(t, err)
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "Uid:") || strings.HasPrefix(line, "Gid:") || strings.HasPrefix(line, "CapEff:") {
+			t.Log(line)
+		}
+	}
+}
+
+func runDropPrivilegesHelper(env ...string) (string, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestDropPrivilegesHelper$", "-test.v")
+	cmd.Env = append(os.Environ(), append(env, "DROP_PRIVILEGES_HELPER=1")...)
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestApp_DropPrivileges(t *testing.T) {
+	if syscall.Getuid() != 0 {
+		t.Skip("requires root")
+	}
+
+	out, err := runDropPrivilegesHelper("APP_USER=nobody", "APP_GROUP=65534")
+	require.NoError(t, err, out)
+	assert.Regexp(t, `Uid:\s+65534\s+65534\s+65534\s+65534`, out)
+	assert.Regexp(t, `Gid:\s+65534\s+65534\s+65534\s+65534`, out)
+	assert.Regexp(t, `CapEff:\s+0000000000000000`, out)
+
+	out, err = runDropPrivilegesHelper("APP_USER=nobody", "APP_GROUP=65534", "APP_KEEP_CAPS=CAP_NET_BIND_SERVICE")
+	if strings.Contains(out, "CGO_ENABLED=0") {
+		t.Skip("keeping capabilities requires CGO_ENABLED=0")
+	}
+	require.NoError(t, err, out)
+	assert.Regexp(t, `Uid:\s+65534\s+65534\s+65534\s+65534`, out)
+	assert.Regexp(t, `CapEff:\s+0000000000000400`, out)
+}
@@ -0,0 +1,83 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// dropPrivileges switches every thread of the process to cred, if not nil, keeping only the capabilities named in
+// keep, and verifies the result.
+func dropPrivileges(cred *credential, keep string) error {
+	mask, err := parseCapabilities(keep)
+	if err != nil {
+		return err
+	}
+
+	if cred != nil && (syscall.Getuid() != cred.uid || syscall.Getgid() != cred.gid) {
+		if keep != "" {
+			if err := keepCapabilities(true); err != nil {
+				return err
+			}
+		}
+
+		if err := syscall.Setgroups(cred.groups); err != nil {
+			return fmt.Errorf("setgroups: %v", err)
+		}
+		if err := syscall.Setgid(cred.gid); err != nil {
+			return fmt.Errorf("setgid %d: %v", cred.gid, err)
+		}
+		if err := syscall.Setuid(cred.uid); err != nil {
+			return fmt.Errorf("setuid %d: %v", cred.uid, err)
+		}
+
+		if keep != "" {
+			if err := keepCapabilities(false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if keep != "" {
+		if err := setCapabilities(mask); err != nil {
+			return err
+		}
+	}
+
+	// root keeps its capabilities unless told which to keep
+	checkCaps := keep != "" || cred != nil && cred.uid != 0
+	return verifyPrivileges(cred, mask, checkCaps)
+}
+
+// verifyPrivileges checks that the process runs as cred, cannot become root again, and, if checkCaps is set, holds
+// no capability outside mask.
+func verifyPrivileges(cred *credential, mask uint64, checkCaps bool) error {
+	if cred != nil {
+		if uid, euid := syscall.Getuid(), syscall.Geteuid(); uid != cred.uid || euid != cred.uid {
+			return fmt.Errorf("running as uid %d, euid %d", uid, euid)
+		}
+		if gid, egid := syscall.Getgid(), syscall.Getegid(); gid != cred.gid || egid != cred.gid {
+			return fmt.Errorf("running as gid %d, egid %d", gid, egid)
+		}
+		if cred.uid != 0 {
+			if err := syscall.Setuid(0); err == nil {
+				return errors.New("able to regain root")
+			}
+		}
+	}
+
+	if !checkCaps {
+		return nil
+	}
+	held, err := heldCapabilities()
+	if err != nil {
+		return err
+	}
+	if extra := held &^ mask; extra != 0 {
+		return fmt.Errorf("still holding capabilities %#x", extra)
+	}
+	return nil
+}
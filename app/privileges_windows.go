@@ -0,0 +1,7 @@
+package app
+
+import "errors"
+
+func dropPrivileges(*credential, string) error {
+	return errors.New("not supported on windows")
+}
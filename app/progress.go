@@ -0,0 +1,137 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// progressInterval is how often a Progress redraws its line at most.
+	progressInterval = 100 * time.Millisecond
+
+	// progressWidth is the number of cells of the bar of a Progress.
+	progressWidth = 24
+)
+
+// Progress reports the progress of a long operation, such as a download, on a line of ErrOutput that is redrawn in
+// place as it advances:
+//
+//	p := a.NewProgress("app.tar.gz", res.ContentLength)
+//	defer p.Done()
+//	_, err = io.Copy(io.MultiWriter(f, p), res.Body)
+//
+// The line is only drawn when Stderr is a terminal and the app is not quiet. A Progress is safe for concurrent use.
+type Progress struct {
+	a     *App
+	label string
+	show  bool
+
+	mu             sync.Mutex
+	current, total int64
+	drawn          time.Time
+	done           bool
+}
+
+// NewProgress returns a Progress of total bytes, or of an unknown amount if total is not positive, labeled label.
+func (a *App) NewProgress(label string, total int64) *Progress {
+	show := isTerminal(a.Stderr)
+	if a.interactive != nil {
+		show = *a.interactive
+	}
+	return &Progress{a: a, label: label, total: total, show: show && a.Verbosity() > VerbosityQuiet}
+}
+
+// Write counts len(b) bytes done, so that a Progress can be the destination of an io.Copy, or one of the writers of
+// an io.MultiWriter.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// Add counts n more bytes done.
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current += n
+	p.draw(false)
+}
+
+// Set sets the bytes done to n, such as the size of a download being resumed, and the total to total, unless it is
+// not positive.
+func (p *Progress) Set(n, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = n
+	if total > 0 {
+		p.total = total
+	}
+	p.draw(false)
+}
+
+// Done draws the line a last time and ends it. The Progress ignores the changes made after Done.
+func (p *Progress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+	p.draw(true)
+	p.done = true
+}
+
+// String returns the line of the Progress, such as "app.tar.gz  42% [##########..............] 4.2 MiB / 10.0 MiB".
+func (p *Progress) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.line()
+}
+
+// draw redraws the line if it is shown and was not redrawn recently, or the Progress is done. p.mu must be held.
+func (p *Progress) draw(final bool) {
+	if !p.show || p.done {
+		return
+	}
+	now := time.Now()
+	if !final && now.Sub(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = now
+
+	line := "\r\x1b[K" + p.line()
+	if final {
+		line += "\n"
+	}
+	_, _ = io.WriteString(p.a.ErrOutput(), line)
+	_ = p.a.Flush()
+}
+
+func (p *Progress) line() string {
+	if p.total <= 0 {
+		return p.label + " " + formatBytes(p.current)
+	}
+
+	frac := float64(p.current) / float64(p.total)
+	if frac > 1 {
+		frac = 1
+	}
+	cells := int(frac * progressWidth)
+	return fmt.Sprintf("%s %3d%% [%s%s] %s / %s", p.label, int(frac*100), strings.Repeat("#", cells),
+		strings.Repeat(".", progressWidth-cells), formatBytes(p.current), formatBytes(p.total))
+}
+
+// formatBytes returns n in bytes, or in the largest binary unit it is at least one of, such as 4.2 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit && exp < 5; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestProgress(t *testing.T) {
+	a := newApp(nil)
+	app.SetInteractive(a, true)
+
+	p := a.NewProgress("app.tar.gz", 10<<20)
+	assert.Equal(t, "app.tar.gz   0% [........................] 0 B / 10.0 MiB", p.String())
+
+	_, err := io.Copy(p, bytes.NewReader(make([]byte, 4<<20+512<<10)))
+	require.NoError(t, err)
+	assert.Equal(t, "app.tar.gz  45% [##########..............] 4.5 MiB / 10.0 MiB", p.String())
+
+	p.Set(10<<20, 0)
+	p.Done()
+	p.Add(1)
+	stderr := a.Stderr.(*bytes.Buffer).String()
+	assert.True(t, strings.HasPrefix(stderr, "\r\x1b[Kapp.tar.gz "), stderr)
+	assert.True(t, strings.HasSuffix(stderr, "\r\x1b[Kapp.tar.gz 100% [########################] 10.0 MiB / 10.0 MiB\n"),
+		stderr)
+
+	p = a.NewProgress("data", 0)
+	p.Add(1500)
+	assert.Equal(t, "data 1.5 KiB", p.String())
+}
+
+func TestProgress_NotShown(t *testing.T) {
+	a := newApp(nil)
+	p := a.NewProgress("app.tar.gz", 100)
+	p.Add(100)
+	p.Done()
+	assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+
+	a = newApp(nil)
+	app.SetInteractive(a, true)
+	a.SetVerbosity(app.VerbosityQuiet)
+	p = a.NewProgress("app.tar.gz", 100)
+	p.Done()
+	assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+}
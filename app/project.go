@@ -0,0 +1,114 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/aphistic/gomol"
+)
+
+// ProjectOptions describe the project LoadProject looks for.
+type ProjectOptions struct {
+	// ConfigFile is the name of the dotenv file of project settings that marks the root of a project, such as
+	// .toolrc. It defaults to a dot, the name of the executable, and rc.
+	ConfigFile string
+
+	// Markers are the names of other files or directories that mark the root of a project, such as go.mod or .git.
+	Markers []string
+}
+
+// LoadProject finds the project the app is run in, as development tools do, and loads its settings, layered under
+// the environment and over the settings of the user:
+//
+//	root, err := a.LoadProject(app.ProjectOptions{ConfigFile: ".toolrc", Markers: []string{"go.mod"}})
+//
+// The root of the project is APP_PROJECT_DIR when set, otherwise the nearest directory, from the working directory
+// up, with the ConfigFile or one of the Markers. The ConfigFile of the root, if any, is loaded with LoadEnvFile, then
+// the user config file, APP_USER_CONFIG when set, otherwise the file config in a directory named after the executable
+// in $XDG_CONFIG_HOME, which defaults to ~/.config. Variables already set take precedence over both, and those of the
+// project over those of the user.
+//
+// LoadProject returns the root, also returned by ProjectRoot, or the empty string if the app is not run in a
+// project. Call it first thing, before the environment is read.
+func (a *App) LoadProject(opts ProjectOptions) (string, error) {
+	if opts.ConfigFile == "" {
+		opts.ConfigFile = "." + executableName() + "rc"
+	}
+
+	root, _ := a.LookupEnv("APP_PROJECT_DIR")
+	if root == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		root = findProject(dir, append([]string{opts.ConfigFile}, opts.Markers...))
+	}
+	if root != "" {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return "", err
+		}
+		root = abs
+		if err := a.loadOptionalEnvFile(filepath.Join(root, opts.ConfigFile)); err != nil {
+			return "", err
+		}
+	}
+
+	a.projectMu.Lock()
+	a.projectRoot = root
+	a.projectMu.Unlock()
+
+	path, _ := a.LookupEnv("APP_USER_CONFIG")
+	if path == "" {
+		// without a home directory, there is no user config
+		if dir, err := a.userPath("config", "APP_USER_CONFIG", "XDG_CONFIG_HOME", ".config"); err == nil {
+			path = filepath.Join(dir, "config")
+		}
+	}
+	if path != "" {
+		if err := a.loadOptionalEnvFile(path); err != nil {
+			return "", err
+		}
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"root": root})
+	_ = a.Logger().Debugm(attrs, "loaded project")
+	return root, nil
+}
+
+// ProjectRoot returns the root of the project found by LoadProject, or the empty string if there is none.
+func (a *App) ProjectRoot() string {
+	a.projectMu.Lock()
+	defer a.projectMu.Unlock()
+	return a.projectRoot
+}
+
+// findProject returns the nearest directory, from dir up, that has one of the files markers, or the empty string.
+func findProject(dir string, markers []string) string {
+	for {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadOptionalEnvFile loads the dotenv file at path with LoadEnvFile, unless there is no such regular file.
+func (a *App) loadOptionalEnvFile(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.LoadEnvFile(path)
+}
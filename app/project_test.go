@@ -0,0 +1,66 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_LoadProject(t *testing.T) {
+	home := t.TempDir()
+	root := filepath.Join(t.TempDir(), "project")
+	work := filepath.Join(root, "cmd", "tool")
+	require.NoError(t, os.MkdirAll(work, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".toolrc"), []byte("REGION=eu-west-1\nPROFILE=project\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "config", "app.test"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "config", "app.test", "config"),
+		[]byte("PROFILE=user\nEDITOR=vi\nREGION=us-east-1\n"), 0o644))
+	t.Chdir(work)
+
+	a := newApp([]string{"XDG_CONFIG_HOME=" + filepath.Join(home, "config"), "EDITOR=nano"})
+	got, err := a.LoadProject(app.ProjectOptions{ConfigFile: ".toolrc"})
+	require.NoError(t, err)
+	assert.Equal(t, root, got)
+	assert.Equal(t, root, a.ProjectRoot())
+
+	for key, expected := range map[string]string{"REGION": "eu-west-1", "PROFILE": "project", "EDITOR": "nano"} {
+		v, _ := a.LookupEnv(key)
+		assert.Equal(t, expected, v, key)
+	}
+}
+
+func TestApp_LoadProject_Markers(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "internal")
+	require.NoError(t, os.MkdirAll(work, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/x\n"), 0o644))
+	userConfig := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(userConfig, []byte("EDITOR=vi\n"), 0o644))
+	t.Chdir(work)
+
+	a := newApp([]string{"APP_USER_CONFIG=" + userConfig})
+	got, err := a.LoadProject(app.ProjectOptions{ConfigFile: ".toolrc", Markers: []string{"go.mod"}})
+	require.NoError(t, err)
+	assert.Equal(t, root, got)
+	v, _ := a.LookupEnv("EDITOR")
+	assert.Equal(t, "vi", v)
+
+	// outside of a project
+	t.Chdir(t.TempDir())
+	a = newApp([]string{"APP_USER_CONFIG=" + userConfig})
+	got, err = a.LoadProject(app.ProjectOptions{ConfigFile: ".toolrc"})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	assert.Empty(t, a.ProjectRoot())
+
+	// the project named by APP_PROJECT_DIR
+	a = newApp([]string{"APP_PROJECT_DIR=" + root, "HOME="})
+	got, err = a.LoadProject(app.ProjectOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, root, got)
+}
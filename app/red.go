@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// DefaultMetricsMaxRoutes is how many routes, and hosts, the RED metrics tell apart when APP_METRICS_MAX_ROUTES is
+// not set.
+const DefaultMetricsMaxRoutes = 100
+
+// otherLabel is the value of the labels past the bound of the RED metrics, and the route of unmatched requests.
+const otherLabel = "other"
+
+type routeKey struct{}
+
+// SetRoute names the route of r, such as "GET /users/{id}", in the RED metrics of the HTTPServer. The pattern of the
+// http.ServeMux handling r is used unless a handler, or the middleware of another router, names the route.
+func SetRoute(r *http.Request, route string) {
+	if p, ok := r.Context().Value(routeKey{}).(*string); ok {
+		*p = route
+	}
+}
+
+// redMetrics reports whether the RED metrics, the rate, errors, and duration of the requests, are recorded: unless
+// APP_METRICS_RED is false.
+func (a *App) redMetrics() bool {
+	v, ok := a.LookupEnv("APP_METRICS_RED")
+	enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+	return !ok || err != nil || enabled
+}
+
+// boundLabel returns value unless the label name of the RED metrics already has APP_METRICS_MAX_ROUTES values
+// other than value, in which case it returns otherLabel, so that a client requesting random paths, or a service
+// calling random hosts, cannot grow the metrics without bound.
+func (a *App) boundLabel(name, value string) string {
+	max := DefaultMetricsMaxRoutes
+	if v, ok := a.LookupEnv("APP_METRICS_MAX_ROUTES"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			max = n
+		}
+	}
+
+	a.redMu.Lock()
+	defer a.redMu.Unlock()
+
+	if a.redLabels == nil {
+		a.redLabels = make(map[string]map[string]struct{})
+	}
+	seen := a.redLabels[name]
+	if seen == nil {
+		seen = make(map[string]struct{})
+		a.redLabels[name] = seen
+	}
+	if _, ok := seen[value]; !ok {
+		if len(seen) >= max {
+			return otherLabel
+		}
+		seen[value] = struct{}{}
+	}
+	return value
+}
+
+// redMethod returns the method, or OTHER if it is not a standard one.
+func redMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// redHandler records the requests handled by next in the http_server_requests_total and
+// http_server_request_duration_seconds metrics.
+func (a *App) redHandler(next http.Handler) http.Handler {
+	registry := a.Metrics()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := new(string)
+		r = r.WithContext(context.WithValue(r.Context(), routeKey{}, route))
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		if *route == "" {
+			// set by the http.ServeMux r was given to
+			*route = r.Pattern
+		}
+		if *route == "" {
+			*route = otherLabel
+		} else {
+			*route = a.boundLabel("route", *route)
+		}
+		labels := metrics.Labels{"method": redMethod(r.Method), "route": *route}
+		registry.Histogram("http_server_request_duration_seconds", nil, labels).Observe(time.Since(start).Seconds())
+		labels["code"] = strconv.Itoa(rec.status)
+		registry.Counter("http_server_requests_total", labels).Inc()
+	})
+}
+
+// statusRecorder records the status of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader && status >= 200 {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush flushes the response, for the handlers asserting that their writer is an http.Flusher.
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer, e.g. to hijack the connection.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// HTTPTransport returns a RoundTripper sending the requests with next, or http.DefaultTransport if nil, through the
+// middleware added with WrapHTTPClients, and recording them in the http_client_requests_total metric, by method, host,
+// and status code, or error when no response was received, and in the http_client_request_duration_seconds metric,
+// unless APP_METRICS_RED is false. Up to APP_METRICS_MAX_ROUTES hosts are told apart, the others are recorded as
+// other.
+func (a *App) HTTPTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	next = a.wrapTransport(next)
+	if !a.redMetrics() {
+		return next
+	}
+	return &redTransport{app: a, registry: a.Metrics(), next: next}
+}
+
+type redTransport struct {
+	app      *App
+	registry *metrics.Registry
+	next     http.RoundTripper
+}
+
+func (t *redTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+
+	labels := metrics.Labels{"method": redMethod(req.Method), "host": t.app.boundLabel("host", req.URL.Host)}
+	t.registry.Histogram("http_client_request_duration_seconds", nil, labels).Observe(time.Since(start).Seconds())
+	labels["code"] = "error"
+	if err == nil {
+		labels["code"] = strconv.Itoa(res.StatusCode)
+	}
+	t.registry.Counter("http_client_requests_total", labels).Inc()
+	return res, err
+}
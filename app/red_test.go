@@ -0,0 +1,172 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// serveHTTP binds and serves s, returning its base URL and a function shutting it down.
+func serveHTTP(t *testing.T, a *app.App, s *app.HTTPServer) (string, func()) {
+	require.NoError(t, s.Bind(a))
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+	return "http://" + s.ListenerAddr().String(), func() {
+		assert.NoError(t, s.Shutdown(context.Background()))
+		assert.NoError(t, <-done)
+	}
+}
+
+func getStatus(t *testing.T, url string) int {
+	req, err := http.NewRequest(http.MethodGet, url, http.NoBody)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	return res.StatusCode
+}
+
+func TestHTTPServer_Metrics(t *testing.T) {
+	a := newApp([]string{"APP_METRICS_MAX_ROUTES=2"})
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusInternalServerError) })
+	mux.HandleFunc("/extra", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusAccepted) })
+	url, shutdown := serveHTTP(t, a, app.NewHTTPServer("tcp://127.0.0.1:0", mux))
+	defer shutdown()
+
+	assert.Equal(t, http.StatusOK, getStatus(t, url+"/users/1"))
+	assert.Equal(t, http.StatusOK, getStatus(t, url+"/users/2"))
+	assert.Equal(t, http.StatusInternalServerError, getStatus(t, url+"/fail"))
+	assert.Equal(t, http.StatusNotFound, getStatus(t, url+"/missing"))
+	// past APP_METRICS_MAX_ROUTES, the routes are recorded as other
+	assert.Equal(t, http.StatusAccepted, getStatus(t, url+"/extra"))
+
+	registry := a.Metrics()
+	for labels, count := range map[[3]string]float64{
+		{"GET", "GET /users/{id}", "200"}: 2,
+		{"GET", "/fail", "500"}:           1,
+		{"GET", "other", "404"}:           1,
+		{"GET", "other", "202"}:           1,
+	} {
+		assert.Equal(t, count, registry.Counter("http_server_requests_total", metrics.Labels{
+			"method": labels[0],
+			"route":  labels[1],
+			"code":   labels[2],
+		}).Value(), labels)
+	}
+	duration := registry.Histogram("http_server_request_duration_seconds", nil, metrics.Labels{
+		"method": "GET",
+		"route":  "GET /users/{id}",
+	})
+	assert.Equal(t, uint64(2), duration.Count())
+}
+
+func TestHTTPServer_Metrics_SetRoute(t *testing.T) {
+	a := newApp(nil)
+	url, shutdown := serveHTTP(t, a, app.NewHTTPServer("tcp://127.0.0.1:0", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// a router of its own, copying the request, names the route
+			r = r.WithContext(context.WithValue(r.Context(), struct{}{}, "routed"))
+			app.SetRoute(r, "/widgets/:id")
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	defer shutdown()
+
+	assert.Equal(t, http.StatusNoContent, getStatus(t, url+"/widgets/42"))
+	assert.Equal(t, 1.0, a.Metrics().Counter("http_server_requests_total", metrics.Labels{
+		"method": "GET",
+		"route":  "/widgets/:id",
+		"code":   "204",
+	}).Value())
+}
+
+func TestHTTPServer_NoMetrics(t *testing.T) {
+	for _, tt := range []struct {
+		environ   []string
+		noMetrics bool
+	}{
+		{[]string{"APP_METRICS_RED=false"}, false},
+		{nil, true},
+	} {
+		a := newApp(tt.environ)
+		s := app.NewHTTPServer("tcp://127.0.0.1:0", http.NotFoundHandler())
+		s.NoMetrics = tt.noMetrics
+		url, shutdown := serveHTTP(t, a, s)
+		assert.Equal(t, http.StatusNotFound, getStatus(t, url))
+		shutdown()
+
+		assert.Empty(t, a.Metrics().Gather())
+	}
+}
+
+func TestGRPCServer_Metrics(t *testing.T) {
+	a := newApp([]string{"APP_GRPC_INSECURE=true", "APP_METRICS_RED=true"})
+	s := app.NewGRPCServer("tcp://127.0.0.1:0")
+	require.NoError(t, s.Bind(a))
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	conn, err := a.GRPCClient(s.ListenerAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.Error(t, err)
+
+	assert.NoError(t, s.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+
+	method := "/grpc.health.v1.Health/Check"
+	registry := a.Metrics()
+	assert.Equal(t, 1.0, registry.Counter("grpc_server_requests_total", metrics.Labels{
+		"method": method,
+		"code":   "NotFound",
+	}).Value())
+	assert.Equal(t, uint64(1), registry.Histogram("grpc_server_request_duration_seconds", nil, metrics.Labels{
+		"method": method,
+	}).Count())
+}
+
+func TestApp_HTTPTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	host := srv.Listener.Addr().String()
+
+	a := newApp(nil)
+	client := &http.Client{Transport: a.HTTPTransport(nil)}
+	for _, path := range []string{"/", "/", "/fail"} {
+		res, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+	}
+	srv.Close()
+	_, err := client.Get(srv.URL)
+	assert.Error(t, err)
+
+	registry := a.Metrics()
+	for code, count := range map[string]float64{"200": 2, "502": 1, "error": 1} {
+		assert.Equal(t, count, registry.Counter("http_client_requests_total", metrics.Labels{
+			"method": "GET",
+			"host":   host,
+			"code":   code,
+		}).Value(), code)
+	}
+	assert.Equal(t, uint64(4), registry.Histogram("http_client_request_duration_seconds", nil, metrics.Labels{
+		"method": "GET",
+		"host":   host,
+	}).Count())
+
+	disabled := newApp([]string{"APP_METRICS_RED=false"})
+	assert.Equal(t, http.DefaultTransport, disabled.HTTPTransport(nil))
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aphistic/gomol"
+)
+
+// environ returns the app Environment. The slice is never modified in place once returned, so it is safe to read
+// while Reload replaces variables.
+func (a *App) environ() Environ {
+	a.envMu.RLock()
+	defer a.envMu.RUnlock()
+	return a.Environment
+}
+
+// Reload replaces the variables previously set by source, such as a remote configuration store, with values,
+// returning the names of the variables that were added, changed, or removed, sorted. Variables set in the process
+// environment or loaded by LoadEnvFile take precedence and are never replaced. Values are read with LookupEnv as
+// usual, so code that reads its configuration on each use sees the new values right away; components that keep
+// their configuration learn of the changes from ConfigUpdates.
+func (a *App) Reload(source string, values map[string]string) []string {
+	a.envMu.Lock()
+	current := Environ(a.Environment).values()
+	if a.remoteEnv == nil {
+		a.remoteEnv = make(map[string]string)
+	}
+
+	changed := []string{}
+	replaced := make(map[string]bool)
+	for k, owner := range a.remoteEnv {
+		if _, ok := values[k]; !ok && owner == source {
+			changed = append(changed, k)
+			replaced[k] = true
+			delete(a.remoteEnv, k)
+		}
+	}
+
+	var added []string
+	for k, v := range values {
+		old, ok := current[k]
+		if ok && a.remoteEnv[k] == "" {
+			continue
+		}
+		a.remoteEnv[k] = source
+		if ok && old == v {
+			continue
+		}
+		changed = append(changed, k)
+		replaced[k] = true
+		added = append(added, k+"="+v)
+	}
+	sort.Strings(changed)
+	sort.Strings(added)
+
+	if len(changed) > 0 {
+		env := make([]string, 0, len(a.Environment)+len(added))
+		for _, line := range a.Environment {
+			if k, _ := splitEnv(line); !replaced[k] {
+				env = append(env, line)
+			}
+		}
+		a.Environment = append(env, added...)
+	}
+	a.envMu.Unlock()
+
+	if len(changed) > 0 {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+			"source":    source,
+			"variables": strings.Join(changed, ","),
+		})
+		_ = a.Logger().Infom(attrs, "reloaded configuration")
+		a.publishConfig(source, changed)
+	}
+	return changed
+}
+
+// remoteSource returns the source that set the environment variable key with Reload, if any.
+func (a *App) remoteSource(key string) string {
+	a.envMu.RLock()
+	defer a.envMu.RUnlock()
+	return a.remoteEnv[key]
+}
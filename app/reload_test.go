@@ -0,0 +1,68 @@
+package app_test
+
+import (
+	"bytes"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Reload(t *testing.T) {
+	a := newApp([]string{"DB_HOST=localhost"})
+
+	changed := a.Reload("etcd", map[string]string{"DB_HOST": "db", "DB_PORT": "5432", "FEATURE_X": "on"})
+	assert.Equal(t, []string{"DB_PORT", "FEATURE_X"}, changed, "the process environment takes precedence")
+	v, _ := a.LookupEnv("DB_HOST")
+	assert.Equal(t, "localhost", v)
+	v, _ = a.LookupEnv("DB_PORT")
+	assert.Equal(t, "5432", v)
+
+	changed = a.Reload("etcd", map[string]string{"DB_HOST": "db", "DB_PORT": "6432"})
+	assert.Equal(t, []string{"DB_PORT", "FEATURE_X"}, changed)
+	v, _ = a.LookupEnv("DB_PORT")
+	assert.Equal(t, "6432", v)
+	_, ok := a.LookupEnv("FEATURE_X")
+	assert.False(t, ok, "variables removed from the source are unset")
+
+	assert.Empty(t, a.Reload("etcd", map[string]string{"DB_HOST": "db", "DB_PORT": "6432"}))
+	assert.Len(t, a.Environment, 2)
+
+	a.DeclareConfig("db", app.ConfigKey{Name: "DB_HOST"}, app.ConfigKey{Name: "DB_PORT"})
+	sources := map[string]app.ConfigSource{}
+	for _, v := range a.EffectiveConfig() {
+		sources[v.Name] = v.Source
+	}
+	assert.Equal(t, app.SourceEnv, sources["DB_HOST"])
+	assert.Equal(t, app.SourceRemote, sources["DB_PORT"])
+
+	_ = a.Logger().ShutdownLoggers()
+	assert.Regexp(t, regexp.MustCompile(`INFO.*\] reloaded configuration \{.*"variables":"DB_PORT,FEATURE_X"`),
+		a.Stderr.(*bytes.Buffer).String())
+}
+
+func TestApp_Reload_Concurrent(t *testing.T) {
+	a := newApp([]string{"A=1"})
+
+	wg := sync.WaitGroup{}
+	for idx := 0; idx < 4; idx++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				a.Reload("consul", map[string]string{"B": string(rune('a' + n%26))})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				v, _ := a.LookupEnv("A")
+				assert.Equal(t, "1", v)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/clierror"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// exitReport is the document Exit writes to APP_EXIT_REPORT.
+type exitReport struct {
+	Code     int                    `json:"code"`
+	Error    string                 `json:"error,omitempty"`
+	Started  *time.Time             `json:"started,omitempty"`
+	Duration float64                `json:"duration_seconds,omitempty"`
+	Facts    map[string]interface{} `json:"facts,omitempty"`
+	Warnings []string               `json:"warnings,omitempty"`
+}
+
+// ReportError records the error that made the command fail for the exit report. Only the first error is kept. Run
+// reports the error it returns.
+func (a *App) ReportError(err error) {
+	a.reportMu.Lock()
+	defer a.reportMu.Unlock()
+
+	if a.reportErr == nil {
+		a.reportErr = err
+	}
+}
+
+// reportFailure reports err with ReportError. A clierror.Error is also rendered on ErrOutput for the user, with its
+// stack trace when the Verbosity is debug, and logged with all of its details at the debug level; Exit then uses
+// its exit status.
+func (a *App) reportFailure(err error) {
+	a.ReportError(err)
+	e := clierror.As(err)
+	if e == nil {
+		return
+	}
+
+	a.setFailureCode(e.ExitCode())
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+		"code":  e.ExitCode(),
+		"hint":  e.Hint,
+		"stack": e.StackTrace(),
+	})
+	if e.Cause != nil {
+		attrs.SetAttr("cause", e.Cause.Error())
+	}
+	_ = a.Logger().Debugm(attrs, "command failed: %s", e.Message)
+	_, _ = io.WriteString(a.ErrOutput(), clierror.Render(err, a.Verbosity() >= VerbosityDebug))
+}
+
+// writeExitReport writes a JSON report of the run to the file named by APP_EXIT_REPORT, for orchestrators and CI
+// systems. The report holds the exit code, the error given to ReportError, the duration since New, and the facts and
+// warnings of the Summary. It is called after the logger shut down, so problems are reported on Stderr directly.
+func (a *App) writeExitReport(code int) {
+	path, _ := a.LookupEnv("APP_EXIT_REPORT")
+	if path == "" {
+		return
+	}
+
+	report := exitReport{Code: code}
+
+	a.reportMu.Lock()
+	if a.reportErr != nil {
+		report.Error = a.reportErr.Error()
+	}
+	a.reportMu.Unlock()
+
+	if !a.started.IsZero() {
+		started := a.started.UTC()
+		report.Started = &started
+		report.Duration = time.Since(a.started).Seconds()
+	}
+
+	a.summaryMu.Lock()
+	s := a.summary
+	a.summaryMu.Unlock()
+	if s != nil {
+		_, report.Facts, report.Warnings = s.snapshot()
+	}
+
+	if err := writeJSONFile(path, report, 0o644); err != nil {
+		_, _ = fmt.Fprintf(a.Stderr, "unable to write exit report: %v\n", err)
+	}
+}
+
+// writeJSONFile replaces the file at path with the JSON encoding of v, so readers never see a partial document.
+func writeJSONFile(path string, v interface{}, perm os.FileMode) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, append(b, '\n'), perm)
+}
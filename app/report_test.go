@@ -0,0 +1,86 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/clierror"
+)
+
+func TestApp_ExitReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	a := app.New()
+	a.Environment = []string{"APP_EXIT_REPORT=" + path}
+	a.Stderr = io.Discard
+	a.ExitHandler = func(code int) {}
+
+	admin := newFakeServer("admin", new(recorder))
+	admin.serveErr = errors.New("accept failed")
+	a.Register("admin", admin)
+	assert.Error(t, a.Run())
+	a.ReportError(errors.New("ignored"))
+
+	a.Summary().Add("rows", 12)
+	a.Summary().Warn("slow")
+
+	assert.PanicsWithValue(t, "exit handler returned", func() {
+		a.Exit(3)
+	})
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &report))
+	assert.Equal(t, 3.0, report["code"])
+	assert.Equal(t, "server admin: accept failed", report["error"])
+	assert.Contains(t, report, "started")
+	assert.GreaterOrEqual(t, report["duration_seconds"], 0.0)
+	assert.Equal(t, map[string]interface{}{"rows": 12.0}, report["facts"])
+	assert.Equal(t, []interface{}{"slow"}, report["warnings"])
+}
+
+func TestApp_ExitReport_Minimal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	a := newApp([]string{"APP_EXIT_REPORT=" + path})
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"code":0}`, string(b))
+}
+
+func TestApp_Dispatch_clierror(t *testing.T) {
+	for _, verbosity := range []string{"", "--debug"} {
+		a := newApp(nil, "load", verbosity)
+		a.AddCommand(app.NewCommand("load", "", func(*app.App, *struct{}, []string) error {
+			return clierror.Wrap(os.ErrNotExist, 66, "unable to read config.yaml").WithHint("run init")
+		}))
+		err := a.Dispatch()
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.PanicsWithValue(t, "system exit 66", func() { a.Exit(1) })
+
+		stderr := a.Stderr.(*bytes.Buffer).String()
+		assert.Contains(t, stderr, "error: unable to read config.yaml\n  cause: file does not exist\n  hint: run init\n")
+		if verbosity == "" {
+			assert.NotContains(t, stderr, "TestApp_Dispatch_clierror")
+			assert.NotContains(t, stderr, "command failed")
+		} else {
+			assert.Contains(t, stderr, "app_test.TestApp_Dispatch_clierror.func1\n\t")
+			assert.Contains(t, stderr, `command failed: unable to read config.yaml {"cause":"file does not exist","code":66`)
+		}
+	}
+}
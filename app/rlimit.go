@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// resourceLimits are the limits set by SetResourceLimits, in the order they are applied.
+var resourceLimits = []struct {
+	name  string
+	parse func(string) (uint64, error)
+}{
+	{"NOFILE", func(v string) (uint64, error) { return strconv.ParseUint(v, 10, 64) }},
+	{"AS", parseSize},
+	{"CPU", func(v string) (uint64, error) {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		return uint64(math.Ceil(d.Seconds())), nil
+	}},
+}
+
+// SetResourceLimits sets the resource limits of the process configured by the environment:
+//
+//	APP_RLIMIT_NOFILE  the number of open files
+//	APP_RLIMIT_AS      the size of the address space in bytes, with an optional KB, MB, or GB suffix
+//	APP_RLIMIT_CPU     the CPU time as a duration, rounded up to whole seconds
+//
+// Each sets the soft limit, raising the hard limit as well when it is lower, which requires root. The value max
+// raises the soft limit to the hard limit. The limits set are logged. Run calls SetResourceLimits before binding any
+// server; commands without servers should call it before starting any component.
+func (a *App) SetResourceLimits() error {
+	attrs := make(map[string]interface{})
+	for _, limit := range resourceLimits {
+		key := "APP_RLIMIT_" + limit.name
+		v := a.trimmedEnv(key)
+		if v == "" {
+			continue
+		}
+
+		cur, max, err := getrlimit(limit.name)
+		if err != nil {
+			return fmt.Errorf("getrlimit %s: %v", limit.name, err)
+		}
+
+		if strings.EqualFold(v, "max") {
+			cur = max
+		} else {
+			n, err := limit.parse(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", key, v)
+			}
+			cur = n
+			if max < n {
+				max = n
+			}
+		}
+
+		if err := setrlimit(limit.name, cur, max); err != nil {
+			return fmt.Errorf("setrlimit %s %d/%d: %v", limit.name, cur, max, err)
+		}
+		attrs[strings.ToLower(limit.name)] = fmt.Sprintf("%d/%d", cur, max)
+	}
+
+	if len(attrs) > 0 {
+		_ = a.Logger().Infom(gomol.NewAttrsFromMap(attrs), "set resource limits")
+	}
+	return nil
+}
+
+// parseSize parses a number of bytes with an optional KB, MB, or GB suffix, in powers of 1024.
+func parseSize(v string) (uint64, error) {
+	shift := uint(0)
+	upper := strings.ToUpper(v)
+	for i, suffix := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(upper, suffix) {
+			shift = 10 * uint(i+1)
+			v = strings.TrimSpace(v[:len(v)-len(suffix)])
+			break
+		}
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64>>shift {
+		return 0, strconv.ErrRange
+	}
+	return n << shift, nil
+}
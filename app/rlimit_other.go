@@ -0,0 +1,17 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package app
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func getrlimit(string) (uint64, uint64, error) {
+	return 0, 0, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
+
+func setrlimit(string, uint64, uint64) error {
+	return fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,45 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package app_test
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_SetResourceLimits(t *testing.T) {
+	var orig syscall.Rlimit
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &orig))
+	defer func() { _ = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &orig) }()
+	if orig.Max < 512 {
+		t.Skipf("hard RLIMIT_NOFILE %d is too low", orig.Max)
+	}
+
+	var rlim syscall.Rlimit
+	require.NoError(t, newApp([]string{"APP_RLIMIT_NOFILE=256"}).SetResourceLimits())
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim))
+	assert.Equal(t, syscall.Rlimit{Cur: 256, Max: orig.Max}, rlim)
+
+	require.NoError(t, newApp([]string{"APP_RLIMIT_NOFILE=max"}).SetResourceLimits())
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim))
+	assert.Equal(t, syscall.Rlimit{Cur: orig.Max, Max: orig.Max}, rlim)
+
+	assert.NoError(t, newApp(nil).SetResourceLimits())
+}
+
+func TestApp_SetResourceLimits_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"APP_RLIMIT_NOFILE=lots": `invalid APP_RLIMIT_NOFILE "lots"`,
+		"APP_RLIMIT_AS=-1":       `invalid APP_RLIMIT_AS "-1"`,
+		"APP_RLIMIT_AS=1TB":      `invalid APP_RLIMIT_AS "1TB"`,
+		"APP_RLIMIT_CPU=0s":      `invalid APP_RLIMIT_CPU "0s"`,
+		"APP_RLIMIT_CPU=10":      `invalid APP_RLIMIT_CPU "10"`,
+	}
+	for env, expected := range tests {
+		assert.EqualError(t, newApp([]string{env}).SetResourceLimits(), expected, env)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package app
+
+import "syscall"
+
+var rlimitResources = map[string]int{
+	"NOFILE": syscall.RLIMIT_NOFILE,
+	"AS":     syscall.RLIMIT_AS,
+	"CPU":    syscall.RLIMIT_CPU,
+}
+
+func getrlimit(name string) (uint64, uint64, error) {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(rlimitResources[name], &rlim); err != nil {
+		return 0, 0, err
+	}
+	return rlim.Cur, rlim.Max, nil
+}
+
+func setrlimit(name string, cur, max uint64) error {
+	return syscall.Setrlimit(rlimitResources[name], &syscall.Rlimit{Cur: cur, Max: max})
+}
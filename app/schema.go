@@ -0,0 +1,309 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+)
+
+// ConfigKey describes an environment variable the app reads.
+type ConfigKey = configschema.Key
+
+func init() {
+	configschema.Register("app", builtinConfig...)
+	configschema.Register("tlsconfig", tlsconfig.ConfigKeys("APP_GRPC_TLS_")...)
+}
+
+// builtinConfig are the variables read by the app. The framework packages register theirs with configschema.
+var builtinConfig = []ConfigKey{
+	{Name: "APP_AGE_KEY", Type: "string",
+		Description: "The age secret keys that decrypt encrypted values and files.", Secret: true},
+	{Name: "APP_AGE_KEY_FILE", Type: "path", Description: "The age identity file, used when APP_AGE_KEY is not set."},
+	{Name: "APP_BANNER", Type: "bool", Default: "true",
+		Description: "Print the startup banner on a terminal rather than log it."},
+	{Name: "APP_CACHE_DIR", Type: "path", Description: "The directory the app caches data it can fetch again in."},
+	{Name: "APP_CHILD_STOP_TIMEOUT", Type: "duration", Default: DefaultChildStopTimeout.String(),
+		Description: "How long Exit waits for child processes to stop before killing them."},
+	{Name: "APP_CHROOT", Type: "path", Description: "The directory Prepare changes the root to."},
+	{Name: "APP_CONFIG_DIR_INTERVAL", Type: "duration", Default: DefaultConfigDirInterval.String(),
+		Description: "How often directories loaded with LoadConfigDir are checked for updates."},
+	{Name: "APP_CREDENTIALS_KEY", Type: "string",
+		Description: "The key of the credentials file, required where there is no keychain.", Secret: true},
+	{Name: "APP_DAEMON", Type: "bool", Default: "false",
+		Description: "Detach from the terminal and run in the background."},
+	{Name: "APP_DAEMON_OUTPUT", Type: "path",
+		Description: "The file, or syslog, that receives the output of the daemon."},
+	{Name: DaemonReadyEnv, Type: "int", Description: "Set for the daemon, the descriptor it reports its start on."},
+	{Name: "APP_DATA_DIR", Type: "path", Description: "The directory the app keeps data of the user in."},
+	{Name: "APP_DEPRECATION_ERRORS", Type: "bool", Default: "false",
+		Description: "Fail on the use of deprecated features, rather than warn."},
+	{Name: "APP_DRY_RUN", Type: "bool", Default: "false",
+		Description: "Report the changes commands would make rather than make them."},
+	{Name: "APP_ENV", Type: "string", Description: "The environment the app runs in, such as development."},
+	{Name: "APP_EXIT_REPORT", Type: "path", Description: "The file the exit report is written to."},
+	{Name: "APP_EXPERIMENTAL", Type: "bool", Default: "false",
+		Description: "Allow the commands and flags marked experimental."},
+	{Name: "APP_GROUP", Type: "string", Description: "The group, name or ID, DropPrivileges switches to."},
+	{Name: "APP_GRPC_INSECURE", Type: "bool", Default: "false", Description: "Connect to gRPC servers without TLS."},
+	{Name: "APP_GRPC_KEEPALIVE_TIME", Type: "duration", Default: DefaultGRPCKeepaliveTime.String(),
+		Description: "How often idle gRPC connections are pinged."},
+	{Name: "APP_GRPC_KEEPALIVE_TIMEOUT", Type: "duration", Default: DefaultGRPCKeepaliveTimeout.String(),
+		Description: "How long a gRPC ping may go unanswered."},
+	{Name: "APP_GRPC_REFLECTION", Type: "bool", Default: "false", Description: "Serve the gRPC reflection service."},
+	{Name: "APP_GRPC_RETRIES", Type: "int", Default: strconv.Itoa(DefaultGRPCRetries),
+		Description: "How often failed gRPC calls are retried."},
+	{Name: "APP_GRPC_RETRY_BACKOFF", Type: "duration", Default: DefaultGRPCRetryBackoff.String(),
+		Description: "The wait before the first gRPC retry."},
+	{Name: "APP_HTTP_DEVCERT", Type: "bool", Default: "true",
+		Description: "Serve HTTPS with a development certificate when APP_ENV is development."},
+	{Name: "APP_HTTP_H2C", Type: "bool", Default: "false", Description: "Serve HTTP/2 without TLS."},
+	{Name: "APP_HTTP_HTTP3", Type: "bool", Default: "false", Description: "Serve HTTP/3 alongside HTTP/1 and HTTP/2."},
+	{Name: "APP_KEEP_CAPS", Type: "string", Description: "The capabilities kept by DropPrivileges."},
+	{Name: "APP_LOG_FORMAT", Type: "string", Default: "text",
+		Description: "The format of log messages: text, or json; json in a container."},
+	{Name: "APP_METRICS_MAX_ROUTES", Type: "int", Default: strconv.Itoa(DefaultMetricsMaxRoutes),
+		Description: "How many routes, and hosts, the request metrics tell apart; the others are recorded as other."},
+	{Name: "APP_METRICS_RED", Type: "bool", Default: "true",
+		Description: "Record the rate, errors, and duration of the requests served and sent."},
+	{Name: "APP_OUTPUT_FORMAT", Type: "string", Default: "text",
+		Description: "The format of the summary: text or json."},
+	{Name: "APP_PID_FILE", Type: "path", Description: "The file the daemon writes its process ID to."},
+	{Name: "APP_PREFLIGHT_TIMEOUT", Type: "duration", Default: DefaultPreflightTimeout.String(),
+		Description: "How long the pre-flight checks may take before Run gives up."},
+	{Name: "APP_PRINT_ENV", Type: "bool", Default: "false", Description: "Print the environment and exit."},
+	{Name: "APP_PROJECT_DIR", Type: "path",
+		Description: "The root of the project LoadProject loads, rather than the one it finds."},
+	{Name: "APP_RLIMIT_AS", Type: "size", Description: "The limit on the address space of the process."},
+	{Name: "APP_RLIMIT_CPU", Type: "duration", Description: "The limit on the CPU time of the process."},
+	{Name: "APP_RLIMIT_NOFILE", Type: "int", Description: "The limit on open files, or max for the hard limit."},
+	{Name: "APP_SESSION_LOG", Type: "bool", Default: "false",
+		Description: "Record the output of the session in the state directory."},
+	{Name: "APP_SHUTDOWN_TIMEOUT", Type: "duration", Default: DefaultShutdownTimeout.String(),
+		Description: "How long servers get to shut down gracefully."},
+	{Name: "APP_STATE_DIR", Type: "path", Description: "The directory the app keeps its state in."},
+	{Name: "APP_SUPERVISE", Type: "bool", Default: "false",
+		Description: "Run the app as a child process restarted when it fails."},
+	{Name: SupervisedEnv, Type: "int",
+		Description: "Set for the supervised child, the number of times it was restarted."},
+	{Name: "APP_SUPERVISE_BACKOFF", Type: "duration", Default: DefaultSuperviseBackoff.String(),
+		Description: "The wait before the first restart."},
+	{Name: "APP_SUPERVISE_MAX_BACKOFF", Type: "duration", Default: DefaultSuperviseMaxBackoff.String(),
+		Description: "The longest wait between restarts."},
+	{Name: "APP_SUPERVISE_MAX_RESTARTS", Type: "int", Default: strconv.Itoa(DefaultSuperviseMaxRestarts),
+		Description: "How often the child is restarted; 0 never gives up."},
+	{Name: "APP_TIMEOUT", Type: "duration", Description: "How long a command may run before it is stopped."},
+	{Name: "APP_UMASK", Type: "octal", Description: "The file mode creation mask set by Prepare."},
+	{Name: "APP_UPGRADE_LISTENERS", Type: "string",
+		Description: "Set for an upgraded process, the listeners it inherits."},
+	{Name: "APP_UPGRADE_READY_FD", Type: "int",
+		Description: "Set for an upgraded process, the descriptor it reports on."},
+	{Name: "APP_USER", Type: "string", Description: "The user, name or ID, DropPrivileges switches to."},
+	{Name: "APP_USER_CONFIG", Type: "path",
+		Description: "The user config file LoadProject loads, rather than the one in $XDG_CONFIG_HOME."},
+	{Name: "APP_VERBOSITY", Type: "string", Default: "normal",
+		Description: "How much the app logs and prints: quiet, normal, verbose, or debug."},
+	{Name: "APP_WAIT_FOR", Type: "string",
+		Description: "The targets WaitFor waits for when given none, comma separated."},
+	{Name: "APP_WAIT_TIMEOUT", Type: "duration", Default: DefaultWaitTimeout.String(),
+		Description: "How long WaitFor waits for its targets."},
+	{Name: "APP_WORKDIR", Type: "path", Description: "The working directory set by Prepare."},
+	{Name: "APP_YES", Type: "bool", Default: "false", Description: "Confirm dangerous changes without asking."},
+	{Name: "NODE_NAME", Type: "string",
+		Description: "The Kubernetes node, from the downward API; added to log messages."},
+	{Name: "NO_COLOR", Type: "string",
+		Description: "Set to anything to turn off the colors of log messages and rendered templates."},
+	{Name: "POD_IP", Type: "string",
+		Description: "The Kubernetes pod IP, from the downward API; added to log messages."},
+	{Name: "POD_NAME", Type: "string",
+		Description: "The Kubernetes pod, from the downward API; added to log messages."},
+	{Name: "POD_NAMESPACE", Type: "string",
+		Description: "The Kubernetes namespace, from the downward API; added to log messages."},
+	{Name: "POD_SERVICE_ACCOUNT", Type: "string",
+		Description: "The Kubernetes service account, from the downward API; added to log messages."},
+	{Name: "POD_UID", Type: "string",
+		Description: "The Kubernetes pod UID, from the downward API; added to log messages."},
+	{Name: "PORT", Type: "int", Default: strconv.Itoa(DefaultHTTPPort),
+		Description: "The port an HTTPServer without a Spec listens on in a container."},
+}
+
+// DeclareConfig adds the variables a module reads to the ConfigSchema, replacing those declared before with the same
+// name. Declare them before Run, which warns about APP_ variables nobody declared.
+func (a *App) DeclareConfig(module string, keys ...ConfigKey) {
+	a.schemaMu.Lock()
+	defer a.schemaMu.Unlock()
+
+	if a.schema == nil {
+		a.schema = make(map[string]ConfigKey)
+	}
+	for _, key := range keys {
+		key.Module = module
+		a.schema[key.Name] = key
+	}
+}
+
+// ConfigSchema returns the variables read by the app, the framework packages linked into it, and the modules that
+// declared theirs with DeclareConfig, sorted by module and name.
+func (a *App) ConfigSchema() []ConfigKey {
+	registered := configschema.Keys()
+	schema := make(map[string]ConfigKey, len(registered))
+	for _, key := range registered {
+		schema[key.Name] = key
+	}
+
+	a.schemaMu.Lock()
+	for name, key := range a.schema {
+		schema[name] = key
+	}
+	a.schemaMu.Unlock()
+
+	keys := make([]ConfigKey, 0, len(schema))
+	for _, key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Module != keys[j].Module {
+			return keys[i].Module < keys[j].Module
+		}
+		return keys[i].Name < keys[j].Name
+	})
+	return keys
+}
+
+// ConfigCommand runs the config command with the arguments that follow it:
+//
+//	schema [--json]  print the ConfigSchema as markdown, or JSON
+//	show [--json]    print the EffectiveConfig as YAML, or JSON
+func (a *App) ConfigCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected a config command: schema or show")
+	}
+
+	asJSON := false
+	for _, arg := range args[1:] {
+		if arg != "--json" {
+			return fmt.Errorf("unknown argument %q", arg)
+		}
+		asJSON = true
+	}
+
+	switch args[0] {
+	case "schema":
+		return writeSchema(a.Output(), a.ConfigSchema(), asJSON)
+	case "show":
+		return writeEffectiveConfig(a.Output(), a.EffectiveConfig(), asJSON)
+	default:
+		return fmt.Errorf("unknown config command %q, expected schema or show", args[0])
+	}
+}
+
+func writeSchema(w io.Writer, keys []ConfigKey, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(keys)
+	}
+
+	module := ""
+	for _, key := range keys {
+		if key.Module != module {
+			if module != "" {
+				_, _ = fmt.Fprintln(w)
+			}
+			module = key.Module
+			_, _ = fmt.Fprintf(w, "## %s\n\n", module)
+			_, _ = fmt.Fprintln(w, "| Variable | Type | Default | Description |")
+			_, _ = fmt.Fprintln(w, "| --- | --- | --- | --- |")
+		}
+
+		typ := key.Type
+		if key.Secret {
+			typ = strings.TrimSpace(typ + " (secret)")
+		}
+		_, err := fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", key.Name, typ, markdownCode(key.Default),
+			strings.ReplaceAll(key.Description, "|", `\|`))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+// warnUnknownConfig logs a warning for every APP_ variable in the environment that is not in the ConfigSchema,
+// suggesting the declared variable it is likely a misspelling of.
+func (a *App) warnUnknownConfig() {
+	schema := a.ConfigSchema()
+
+	for _, line := range a.environ().Snapshot() {
+		name, _ := splitEnv(line)
+		if !strings.HasPrefix(name, "APP_") || configKnown(schema, name) {
+			continue
+		}
+
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"variable": name})
+		if guess := closestConfig(schema, name); guess != "" {
+			_ = a.Logger().Warnm(attrs, "unknown variable %s, did you mean %s?", name, guess)
+		} else {
+			_ = a.Logger().Warnm(attrs, "unknown variable %s", name)
+		}
+	}
+}
+
+func configKnown(schema []ConfigKey, name string) bool {
+	for _, key := range schema {
+		if key.Name == name || strings.HasSuffix(key.Name, "*") && strings.HasPrefix(name, key.Name[:len(key.Name)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestConfig returns the declared variable at most two edits away from name, or an empty string.
+func closestConfig(schema []ConfigKey, name string) string {
+	best, bestDistance := "", 3
+	for _, key := range schema {
+		if strings.HasSuffix(key.Name, "*") {
+			continue
+		}
+		if d := editDistance(name, key.Name); d < bestDistance {
+			best, bestDistance = key.Name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
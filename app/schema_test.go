@@ -0,0 +1,81 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_ConfigSchema(t *testing.T) {
+	a := newApp(nil)
+	a.DeclareConfig("db",
+		app.ConfigKey{Name: "APP_DB_URL", Type: "url", Description: "Where | the database is.", Secret: true},
+		app.ConfigKey{Name: "APP_DB_POOL", Type: "int", Default: "4", Description: "The connections kept open."},
+	)
+
+	schema := a.ConfigSchema()
+	var db []app.ConfigKey
+	for _, key := range schema {
+		if key.Module == "db" {
+			db = append(db, key)
+		}
+	}
+	assert.Equal(t, []app.ConfigKey{
+		{Module: "db", Name: "APP_DB_POOL", Type: "int", Default: "4", Description: "The connections kept open."},
+		{Module: "db", Name: "APP_DB_URL", Type: "url", Description: "Where | the database is.", Secret: true},
+	}, db)
+	assert.Equal(t, "app", schema[0].Module)
+
+	require.NoError(t, a.ConfigCommand([]string{"schema"}))
+	out := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, out, "## db\n\n| Variable | Type | Default | Description |\n| --- | --- | --- | --- |\n"+
+		"| `APP_DB_POOL` | int | `4` | The connections kept open. |\n"+
+		"| `APP_DB_URL` | url (secret) |  | Where \\| the database is. |\n")
+	assert.Contains(t, out, "| `APP_SHUTDOWN_TIMEOUT` | duration | `30s` |")
+	assert.Contains(t, out, "## tlsconfig\n")
+	assert.Contains(t, out, "| `APP_GRPC_TLS_CA_FILE` | path |  |")
+	assert.NotContains(t, out, "*`")
+
+	a = newApp(nil)
+	require.NoError(t, a.ConfigCommand([]string{"schema", "--json"}))
+	var decoded []app.ConfigKey
+	require.NoError(t, json.Unmarshal(a.Stdout.(*bytes.Buffer).Bytes(), &decoded))
+	assert.Equal(t, a.ConfigSchema(), decoded)
+
+	assert.EqualError(t, a.ConfigCommand(nil), "expected a config command: schema or show")
+	assert.EqualError(t, a.ConfigCommand([]string{"dump"}), `unknown config command "dump", expected schema or show`)
+	assert.EqualError(t, a.ConfigCommand([]string{"schema", "--yaml"}), `unknown argument "--yaml"`)
+}
+
+func TestApp_Run_UnknownConfig(t *testing.T) {
+	a := newApp([]string{
+		"APP_SHUTDOWN_TIMOUT=1s", "APP_TLS_CA_FILE=ca.pem", "APP_TLS_CA_FIEL=ca.pem", "APP_DB_URL=x", "APP_WHATEVER=1",
+		"OTHER=1",
+	})
+	a.DeclareConfig("db", app.ConfigKey{Name: "APP_DB_URL"})
+	go a.HandleError(nil)
+	assert.NoError(t, a.Run())
+
+	_ = a.Logger().ShutdownLoggers()
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Regexp(t, `WARN.*\] unknown variable APP_SHUTDOWN_TIMOUT, did you mean APP_SHUTDOWN_TIMEOUT\? \{`, logs)
+	assert.Regexp(t, `WARN.*\] unknown variable APP_TLS_CA_FIEL, did you mean APP_TLS_CA_FILE\? \{`, logs)
+	assert.Regexp(t, `WARN.*\] unknown variable APP_WHATEVER \{`, logs)
+	assert.NotContains(t, logs, "variable APP_TLS_CA_FILE")
+	assert.NotContains(t, logs, "APP_DB_URL")
+	assert.NotContains(t, logs, "OTHER")
+}
+
+func TestApp_PrintEnvironment_Secret(t *testing.T) {
+	a := newApp([]string{"APP_DB_URL=postgres://db/app", "APP_PRINT_ENV=1"})
+	a.DeclareConfig("db", app.ConfigKey{Name: "APP_DB_URL", Secret: true})
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.PrintEnvironment()
+	})
+	assert.Equal(t, "APP_DB_URL=[REDACTED]\nAPP_PRINT_ENV=1\n", a.Stdout.(*bytes.Buffer).String())
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/secrets"
+)
+
+// agePrefix marks an environment variable value encrypted with age, followed by the base64 encoded age file, as
+// printed by: age -r RECIPIENT | base64 -w0
+const agePrefix = "age:"
+
+// ageIdentities returns the age identities in APP_AGE_KEY, or the file in APP_AGE_KEY_FILE, falling back to the
+// SOPS_AGE_KEY and SOPS_AGE_KEY_FILE variables sops reads.
+func (a *App) ageIdentities() ([]*secrets.Identity, error) {
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+
+	if a.identities != nil {
+		return a.identities, nil
+	}
+
+	for _, prefix := range []string{"APP_", "SOPS_"} {
+		var err error
+		if v, _ := a.lookupEnv(prefix + "AGE_KEY"); strings.TrimSpace(v) != "" {
+			a.identities, err = secrets.ParseIdentities(strings.NewReader(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %sAGE_KEY: %v", prefix, err)
+			}
+			return a.identities, nil
+		}
+
+		if path, _ := a.lookupEnv(prefix + "AGE_KEY_FILE"); strings.TrimSpace(path) != "" {
+			f, err := os.Open(strings.TrimSpace(path))
+			if err != nil {
+				return nil, fmt.Errorf("invalid %sAGE_KEY_FILE: %v", prefix, err)
+			}
+			defer f.Close()
+
+			a.identities, err = secrets.ParseIdentities(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %sAGE_KEY_FILE %q: %v", prefix, path, err)
+			}
+			return a.identities, nil
+		}
+	}
+	return nil, errors.New("no age key, set APP_AGE_KEY or APP_AGE_KEY_FILE")
+}
+
+// lookupDecrypted returns the value of the variable key, decrypted if it holds an age encrypted value.
+func (a *App) lookupDecrypted(key string) (string, bool, error) {
+	v, _, ok := a.lookupLayered(key)
+	if !ok || !strings.HasPrefix(v, agePrefix) {
+		return v, ok, nil
+	}
+
+	a.secretsMu.Lock()
+	plaintext, cached := a.decrypted[v]
+	a.secretsMu.Unlock()
+	if cached {
+		return plaintext, true, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(v, agePrefix)))
+	if err != nil {
+		return "", false, fmt.Errorf("decrypt %s: invalid base64: %v", key, err)
+	}
+	identities, err := a.ageIdentities()
+	if err != nil {
+		return "", false, fmt.Errorf("decrypt %s: %v", key, err)
+	}
+	b, err := secrets.Decrypt(ciphertext, identities...)
+	if err != nil {
+		return "", false, fmt.Errorf("decrypt %s: %v", key, err)
+	}
+
+	a.secretsMu.Lock()
+	if a.decrypted == nil {
+		a.decrypted = make(map[string]string)
+	}
+	a.decrypted[v] = string(b)
+	a.secretsMu.Unlock()
+	return string(b), true, nil
+}
+
+// recordDecryptError records the error of decrypting the variable key for ConfigErrors.
+func (a *App) recordDecryptError(key string, err error) {
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+
+	if a.undecrypt == nil {
+		a.undecrypt = make(map[string]error)
+	}
+	a.undecrypt[key] = err
+}
+
+// decryptErrors returns the errors recorded by recordDecryptError, sorted by variable.
+func (a *App) decryptErrors() ConfigErrors {
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+
+	keys := make([]string, 0, len(a.undecrypt))
+	for key := range a.undecrypt {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs ConfigErrors
+	for _, key := range keys {
+		errs = append(errs, &FieldError{Module: "app", Err: a.undecrypt[key]})
+	}
+	return errs
+}
+
+// encrypted reports whether the environment variable key holds an age encrypted value or was loaded from a file
+// encrypted by SOPS.
+func (a *App) encrypted(key string) bool {
+	if v, _, _ := a.lookupLayered(key); strings.HasPrefix(v, agePrefix) {
+		return true
+	}
+
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+	return a.sealedEnv[key]
+}
+
+// LoadEnvFile adds the variables in a dotenv file to the app Environment, except those already set, which take
+// precedence. A file encrypted by SOPS with an age key is decrypted with the key in APP_AGE_KEY or APP_AGE_KEY_FILE,
+// so that it can be committed to version control; its values are redacted wherever the environment is shown. Call
+// LoadEnvFile first thing, before the environment is read.
+func (a *App) LoadEnvFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var env []string
+	sealed := secrets.IsSOPS(data)
+	if sealed {
+		identities, err := a.ageIdentities()
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %v", path, err)
+		}
+		if env, err = secrets.DecryptDotenv(data, identities...); err != nil {
+			return fmt.Errorf("decrypt %s: %v", path, err)
+		}
+	} else if env, err = secrets.ParseDotenv(data); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	a.envMu.Lock()
+	set := Environ(a.Environment).values()
+	var loaded []string
+	for _, line := range env {
+		k, _ := splitEnv(line)
+		if _, ok := set[k]; ok {
+			continue
+		}
+		a.Environment = append(a.Environment, line)
+		loaded = append(loaded, k)
+	}
+	a.envMu.Unlock()
+
+	a.secretsMu.Lock()
+	if a.fileEnv == nil {
+		a.fileEnv = make(map[string]string)
+		a.sealedEnv = make(map[string]bool)
+	}
+	for _, k := range loaded {
+		a.fileEnv[k] = path
+		a.sealedEnv[k] = sealed
+	}
+	a.secretsMu.Unlock()
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"path": path, "variables": len(loaded)})
+	_ = a.Logger().Debugm(attrs, "loaded environment file")
+	return nil
+}
+
+// envFile returns the file the environment variable key was loaded from by LoadEnvFile, if any.
+func (a *App) envFile(key string) string {
+	a.secretsMu.Lock()
+	defer a.secretsMu.Unlock()
+	return a.fileEnv[key]
+}
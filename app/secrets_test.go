@@ -0,0 +1,111 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// encryptedPassword returns hunter2 encrypted to the key in testdata/age.key, as an environment variable value.
+func encryptedPassword(t *testing.T) string {
+	b, err := os.ReadFile("testdata/password.age")
+	require.NoError(t, err)
+	return "age:" + base64.StdEncoding.EncodeToString(b)
+}
+
+func TestApp_LookupEnv_Encrypted(t *testing.T) {
+	a := newApp([]string{
+		"APP_AGE_KEY_FILE=testdata/age.key",
+		"DB_PASSWORD=" + encryptedPassword(t),
+		"DB_URL=postgres://app:${DB_PASSWORD}@db/app",
+	})
+	a.ExpandVars("DB_URL")
+
+	v, ok := a.LookupEnv("DB_PASSWORD")
+	assert.True(t, ok)
+	assert.Equal(t, "hunter2", v)
+
+	v, _ = a.LookupEnv("DB_URL")
+	assert.Equal(t, "postgres://app:hunter2@db/app", v)
+
+	a = newApp([]string{"DB_PASSWORD=" + encryptedPassword(t)})
+	_, _, err := a.ExpandEnv("DB_PASSWORD")
+	assert.EqualError(t, err, "decrypt DB_PASSWORD: no age key, set APP_AGE_KEY or APP_AGE_KEY_FILE")
+
+	key, err := os.ReadFile("testdata/age.key")
+	require.NoError(t, err)
+	a = newApp([]string{"SOPS_AGE_KEY=" + string(key), "DB_PASSWORD=" + encryptedPassword(t), "BAD=age:!!"})
+	v, _, err = a.ExpandEnv("DB_PASSWORD")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+	_, _, err = a.ExpandEnv("BAD")
+	assert.ErrorContains(t, err, "decrypt BAD: invalid base64")
+}
+
+func TestApp_LookupEnv_Undecryptable(t *testing.T) {
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	for name, env := range map[string][]string{
+		"no key":    {"DB_PASSWORD=" + encryptedPassword(t)},
+		"wrong key": {"APP_AGE_KEY=" + other.String(), "DB_PASSWORD=" + encryptedPassword(t)},
+	} {
+		a := newApp(env)
+		require.NoError(t, a.ConfigErrors(), name)
+
+		// the ciphertext is never handed out as the value
+		v, ok := a.LookupEnv("DB_PASSWORD")
+		assert.False(t, ok, name)
+		assert.Empty(t, v, name)
+
+		err := a.ConfigErrors()
+		var errs app.ConfigErrors
+		require.ErrorAs(t, err, &errs, name)
+		require.Len(t, errs, 1, name)
+		assert.ErrorContains(t, errs[0], "decrypt DB_PASSWORD: ", name)
+	}
+}
+
+func TestApp_LoadEnvFile(t *testing.T) {
+	a := newApp([]string{"APP_AGE_KEY_FILE=testdata/age.key", "DB_HOST_unencrypted=override"})
+	require.NoError(t, a.LoadEnvFile("testdata/secrets.sops.env"))
+
+	for key, expected := range map[string]string{
+		"DB_PASSWORD":          "s3cret",
+		"DB_HOST_unencrypted":  "override",
+		"APP_SHUTDOWN_TIMEOUT": "7s",
+	} {
+		v, _ := a.LookupEnv(key)
+		assert.Equal(t, expected, v, key)
+	}
+
+	values := a.EffectiveConfig()
+	assert.Contains(t, values, app.ConfigValue{Module: "app", Name: "APP_SHUTDOWN_TIMEOUT", Value: app.Redacted,
+		Source: app.SourceFile})
+
+	a.Arguments = []string{"--print-env"}
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.PrintEnvironment()
+	})
+	out := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, out, "DB_PASSWORD=[REDACTED]\n")
+	assert.NotContains(t, out, "s3cret")
+
+	plain := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(plain, []byte("# plain\nAPP_SHUTDOWN_TIMEOUT=9s\n"), 0o600))
+	a = newApp(nil)
+	require.NoError(t, a.LoadEnvFile(plain))
+	assert.Contains(t, a.EffectiveConfig(), app.ConfigValue{Module: "app", Name: "APP_SHUTDOWN_TIMEOUT", Value: "9s",
+		Source: app.SourceFile})
+
+	err := newApp(nil).LoadEnvFile("testdata/secrets.sops.env")
+	assert.EqualError(t, err, "decrypt testdata/secrets.sops.env: no age key, set APP_AGE_KEY or APP_AGE_KEY_FILE")
+}
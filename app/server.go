@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// DefaultShutdownTimeout is how long Run waits for each server to shut down when APP_SHUTDOWN_TIMEOUT is not set.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Server is a long running service managed by Run.
+type Server interface {
+	// Bind acquires the resources the server needs to start, typically its listeners. Run binds every registered
+	// server before serving any of them.
+	Bind(a *App) error
+
+	// Serve blocks while the server runs. A nil error means the server was shut down.
+	Serve() error
+
+	// Shutdown stops the server, waiting for in-flight work until the context is done.
+	Shutdown(ctx context.Context) error
+}
+
+type namedServer struct {
+	name   string
+	server Server
+}
+
+// Register adds a server to be started by Run. Servers are shut down in the reverse order they were registered.
+func (a *App) Register(name string, s Server) {
+	a.serversMu.Lock()
+	defer a.serversMu.Unlock()
+
+	a.servers = append(a.servers, namedServer{name: name, server: s})
+}
+
+// Run sets the resource limits with SetResourceLimits, binds every registered server, failing fast if any of them
+// cannot bind, sets up the process with Prepare, drops privileges with DropPrivileges, makes the pre-flight checks, see
+// Preflight, prints or logs the startup Banner, and then serves them concurrently. Run returns once a server fails, a
+// value is sent via the Errors channel, Stop is called, the process receives SIGINT or SIGTERM, a write to Output finds
+// the reader of Stdout gone, or the app Context is done, shutting down every server and scheduled task before returning
+// the error that caused it to stop. ShuttingDown is closed before the first server is shut down. The error is recorded
+// for the exit report, see ReportError; a clierror.Error is also rendered on ErrOutput, and its exit status used by
+// Exit.
+func (a *App) Run() error {
+	err := a.run()
+	if err != nil {
+		a.reportFailure(err)
+	}
+	return err
+}
+
+func (a *App) run() error {
+	a.serversMu.Lock()
+	servers := append([]namedServer(nil), a.servers...)
+	a.serversMu.Unlock()
+
+	a.warnUnknownConfig()
+	if err := a.ConfigErrors(); err != nil {
+		return err
+	}
+
+	if err := a.SetResourceLimits(); err != nil {
+		return err
+	}
+
+	for idx, s := range servers {
+		if err := s.server.Bind(a); err != nil {
+			a.shutdownServers(servers[:idx])
+			return fmt.Errorf("server %s: %v", s.name, err)
+		}
+	}
+
+	// every privileged port is bound now, the process may give up what it no longer needs
+	if err := a.confine(); err != nil {
+		a.shutdownServers(servers)
+		return err
+	}
+
+	// the servers are only served, and the readiness reported, once the dependencies are verified
+	if err := a.runPreflight(); err != nil {
+		a.shutdownServers(servers)
+		return err
+	}
+
+	if err := a.NotifyReady(); err != nil {
+		_ = a.Logger().Warnf("unable to notify parent process: %v", err)
+	}
+	a.writeBanner()
+
+	errch := make(chan error, len(servers))
+	for _, s := range servers {
+		s := s
+		go func() {
+			_ = a.Logger().Infom(gomol.NewAttrsFromMap(map[string]interface{}{"server": s.name}), "server started")
+			if err := s.server.Serve(); err != nil {
+				errch <- fmt.Errorf("server %s: %v", s.name, err)
+				return
+			}
+			errch <- nil
+		}()
+	}
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigch)
+
+	var err error
+	select {
+	case err = <-errch:
+	case err = <-a.Errors():
+	case <-a.ensureStopped():
+		_ = a.Logger().Debugf("stopped, shutting down")
+	case sig := <-sigch:
+		_ = a.Logger().Infof("received %s, shutting down", sig)
+	case <-a.StdoutClosed():
+		_ = a.Logger().Debugf("stdout closed, shutting down")
+	case <-a.Context.Done():
+	}
+
+	a.shutdownServers(servers)
+	a.shutdownTasks(a.shutdownTimeout())
+	return err
+}
+
+func (a *App) confine() error {
+	if err := a.Prepare(); err != nil {
+		return err
+	}
+	return a.DropPrivileges()
+}
+
+func (a *App) shutdownServers(servers []namedServer) {
+	a.beginShutdown()
+	timeout := a.shutdownTimeout()
+
+	for idx := len(servers) - 1; idx >= 0; idx-- {
+		s := servers[idx]
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"server": s.name})
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := s.server.Shutdown(ctx)
+		cancel()
+
+		if err != nil {
+			_ = a.Logger().Errorm(attrs, "server shutdown failed: %v", err)
+		} else {
+			_ = a.Logger().Infom(attrs, "server stopped")
+		}
+	}
+}
+
+func (a *App) shutdownTimeout() time.Duration {
+	v, ok := a.LookupEnv("APP_SHUTDOWN_TIMEOUT")
+	if !ok {
+		return DefaultShutdownTimeout
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		_ = a.Logger().Warnf("invalid APP_SHUTDOWN_TIMEOUT %q, using %s", v, DefaultShutdownTimeout)
+		return DefaultShutdownTimeout
+	}
+
+	return d
+}
@@ -0,0 +1,141 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type fakeServer struct {
+	name     string
+	recorder *recorder
+	bindErr  error
+	serveErr error
+	stop     chan struct{}
+}
+
+func newFakeServer(name string, r *recorder) *fakeServer {
+	return &fakeServer{name: name, recorder: r, stop: make(chan struct{})}
+}
+
+func (s *fakeServer) Bind(*app.App) error {
+	s.recorder.record("bind " + s.name)
+	return s.bindErr
+}
+
+func (s *fakeServer) Serve() error {
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.stop
+	return nil
+}
+
+func (s *fakeServer) Shutdown(context.Context) error {
+	s.recorder.record("shutdown " + s.name)
+	close(s.stop)
+	return nil
+}
+
+func TestApp_Run(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+	a.Register("admin", newFakeServer("admin", r))
+	a.Register("metrics", newFakeServer("metrics", r))
+
+	go a.HandleError(nil)
+
+	assert.NoError(t, a.Run())
+	assert.Equal(t, []string{
+		"bind public",
+		"bind admin",
+		"bind metrics",
+		"shutdown metrics",
+		"shutdown admin",
+		"shutdown public",
+	}, r.Events())
+}
+
+func TestApp_Stop(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+
+	a.Stop()
+	a.Stop()
+
+	assert.NoError(t, a.Run())
+	assert.Equal(t, []string{"bind public", "shutdown public"}, r.Events())
+}
+
+func TestApp_Run_BindError(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+
+	admin := newFakeServer("admin", r)
+	admin.bindErr = errors.New("address already in use")
+	a.Register("admin", admin)
+	a.Register("metrics", newFakeServer("metrics", r))
+
+	assert.EqualError(t, a.Run(), "server admin: address already in use")
+	assert.Equal(t, []string{
+		"bind public",
+		"bind admin",
+		"shutdown public",
+	}, r.Events())
+}
+
+func TestApp_Run_ServeError(t *testing.T) {
+	r := new(recorder)
+	a := newApp(nil)
+	a.Register("public", newFakeServer("public", r))
+
+	admin := newFakeServer("admin", r)
+	admin.serveErr = errors.New("accept failed")
+	a.Register("admin", admin)
+
+	assert.EqualError(t, a.Run(), "server admin: accept failed")
+	assert.Equal(t, []string{
+		"bind public",
+		"bind admin",
+		"shutdown admin",
+		"shutdown public",
+	}, r.Events())
+}
+
+func TestApp_Run_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := new(recorder)
+	a := newApp(nil)
+	a.Context = ctx
+	a.Register("public", newFakeServer("public", r))
+
+	cancel()
+
+	assert.NoError(t, a.Run())
+	assert.Equal(t, []string{"bind public", "shutdown public"}, r.Events())
+}
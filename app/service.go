@@ -0,0 +1,47 @@
+package app
+
+import "sync"
+
+// service is a value kept by Service, built once.
+type service struct {
+	mu    sync.Mutex
+	built bool
+	value interface{}
+}
+
+// Service returns the value the app keeps under key, building it with build on first use, so that the packages
+// built on the App share a client, such as a connection pool, for as long as the app runs:
+//
+//	type clientKey struct{}
+//
+//	func For(a *app.App) (*Client, error) {
+//		return app.Service(a, clientKey{}, func() (*Client, error) { return New(a) })
+//	}
+//
+// The key must be comparable; an unexported type of the package keeps its values apart from those of others. The
+// value is built once, even by concurrent calls, and built again on the next call if build fails. A value kept with
+// another type under key is a programming error and panics.
+func Service[T any](a *App, key interface{}, build func() (T, error)) (T, error) {
+	a.servicesMu.Lock()
+	if a.services == nil {
+		a.services = make(map[interface{}]*service)
+	}
+	s, ok := a.services[key]
+	if !ok {
+		s = &service{}
+		a.services[key] = s
+	}
+	a.servicesMu.Unlock()
+
+	// the value is built without holding servicesMu, so that building it can look up the services it depends on
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.built {
+		v, err := build()
+		if err != nil {
+			return v, err
+		}
+		s.value, s.built = v, true
+	}
+	return s.value.(T), nil
+}
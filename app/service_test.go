@@ -0,0 +1,53 @@
+package app_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type poolKey struct{}
+
+type pool struct{ n int }
+
+func TestService(t *testing.T) {
+	a := &app.App{}
+	builds := 0
+	build := func() (*pool, error) {
+		builds++
+		if builds == 1 {
+			return nil, errors.New("unreachable")
+		}
+		return &pool{n: builds}, nil
+	}
+
+	// a failed build is not kept
+	_, err := app.Service(a, poolKey{}, build)
+	assert.EqualError(t, err, "unreachable")
+
+	var wg sync.WaitGroup
+	pools := make([]*pool, 10)
+	for i := range pools {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pools[i], _ = app.Service(a, poolKey{}, build)
+		}()
+	}
+	wg.Wait()
+	for _, p := range pools {
+		require.NotNil(t, p)
+		assert.Same(t, pools[0], p)
+	}
+	assert.Equal(t, 2, builds)
+
+	// each app keeps its own
+	other, err := app.Service(&app.App{}, poolKey{}, func() (*pool, error) { return &pool{}, nil })
+	require.NoError(t, err)
+	assert.NotSame(t, pools[0], other)
+}
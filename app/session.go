@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// openSessionLog opens the session log when APP_SESSION_LOG is true. It is called with outputMu held the first time
+// Output or ErrOutput is used, so problems are reported on Stderr directly rather than through the Logger.
+func (a *App) openSessionLog() {
+	if a.sessionChecked {
+		return
+	}
+	a.sessionChecked = true
+
+	enabled, err := a.lookupBool("APP_SESSION_LOG")
+	if err == nil && enabled {
+		err = a.createSessionLog()
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(a.Stderr, "unable to open session log: %v\n", err)
+	}
+}
+
+func (a *App) createSessionLog() error {
+	dir, err := a.StateDir()
+	if err != nil {
+		return err
+	}
+
+	dir = filepath.Join(dir, "sessions")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s.%d.log", a.clock().Now().UTC().Format("20060102T150405Z"), os.Getpid())
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	a.sessionFile = f
+	a.session = &ansiStripper{w: f}
+	return nil
+}
+
+// SessionLog returns the path of the file receiving a copy of everything written to Output and ErrOutput, or the
+// empty string when APP_SESSION_LOG is not enabled. Session logs are kept in the "sessions" directory of StateDir,
+// named after the UTC time the session started, with ANSI escape sequences removed.
+func (a *App) SessionLog() string {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	a.openSessionLog()
+	if a.sessionFile == nil {
+		return ""
+	}
+	return a.sessionFile.Name()
+}
+
+func (a *App) closeSessionLog() {
+	a.outputMu.Lock()
+	defer a.outputMu.Unlock()
+
+	if a.sessionFile != nil {
+		_ = a.sessionFile.Close()
+		a.sessionFile = nil
+		a.session = nil
+	}
+}
+
+const (
+	ansiText = iota
+	ansiEscape
+	ansiCSI
+	ansiOSC
+	ansiOSCEscape
+)
+
+// ansiStripper removes ANSI escape sequences, which may be split across writes, from the bytes written to w.
+type ansiStripper struct {
+	w     io.Writer
+	state int
+}
+
+func (s *ansiStripper) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p))
+
+	for _, c := range p {
+		switch s.state {
+		case ansiText:
+			if c == 0x1b {
+				s.state = ansiEscape
+			} else {
+				out = append(out, c)
+			}
+		case ansiEscape:
+			switch c {
+			case '[':
+				s.state = ansiCSI
+			case ']':
+				s.state = ansiOSC
+			default:
+				s.state = ansiText
+			}
+		case ansiCSI:
+			// parameter and intermediate bytes continue the sequence, a final byte ends it
+			if c >= 0x40 && c <= 0x7e {
+				s.state = ansiText
+			}
+		case ansiOSC:
+			switch c {
+			case 0x07:
+				s.state = ansiText
+			case 0x1b:
+				s.state = ansiOSCEscape
+			}
+		case ansiOSCEscape:
+			s.state = ansiText
+		}
+	}
+
+	if _, err := s.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
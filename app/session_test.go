@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efritz/glock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_SessionLog(t *testing.T) {
+	dir := t.TempDir()
+	a := newApp([]string{"APP_SESSION_LOG=true", "APP_STATE_DIR=" + dir})
+	a.Clock = glock.NewMockClockAt(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC))
+
+	expected := filepath.Join(dir, "sessions", fmt.Sprintf("20240301T123000Z.%d.log", os.Getpid()))
+	assert.Equal(t, expected, a.SessionLog())
+
+	_, err := fmt.Fprint(a.Output(), "\x1b[1mbold\x1b[0m and \x1b]0;title\x07plain\n")
+	require.NoError(t, err)
+	// sequences split across writes are removed too
+	_, err = fmt.Fprint(a.Output(), "split \x1b[3")
+	require.NoError(t, err)
+	_, err = fmt.Fprint(a.Output(), "1mred\x1b[0m\n")
+	require.NoError(t, err)
+	require.NoError(t, a.Logger().Warn("careful"))
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	b, err := os.ReadFile(expected)
+	require.NoError(t, err)
+	assert.Regexp(t, `^bold and plain\nsplit red\n\[WARN\] careful \{.*\}\n$`, string(b))
+
+	info, err := os.Stat(expected)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestApp_SessionLog_Disabled(t *testing.T) {
+	a := newApp([]string{"APP_STATE_DIR=" + t.TempDir()})
+	assert.Empty(t, a.SessionLog())
+
+	a = newApp([]string{"APP_SESSION_LOG=maybe"})
+	assert.Empty(t, a.SessionLog())
+	assert.Equal(t, "unable to open session log: invalid APP_SESSION_LOG \"maybe\"\n", a.Stderr.(fmt.Stringer).String())
+}
@@ -0,0 +1,291 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/demosdemon/golang-app-framework/clierror"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// ShellHistorySize is how many lines the Shell keeps in its history.
+const ShellHistorySize = 1000
+
+// Shell reads command lines from Stdin, showing prompt on ErrOutput, and runs them as Dispatch runs the Arguments,
+// for tools whose users explore rather than script:
+//
+//	shell := func(a *app.App, _ *struct{}, _ []string) error {
+//		return a.Shell(a.Name + "> ")
+//	}
+//	a.AddCommand(app.NewCommand("shell", "run commands interactively", shell))
+//
+// Lines are split into arguments like a shell does, with quotes and backslashes. The errors of commands are shown
+// and the Shell goes on. Besides the commands, the Shell understands:
+//
+//	help      the usage of the app, or of a command with help <command>
+//	history   the lines run before, kept in the StateDir across sessions
+//	!!, !N    run the last line, or line N of the history, again
+//	...?      a line that ends with ? lists the completions of its last word, such as the commands or the flags
+//	exit      leave the Shell, as does the end of the input
+//
+// Ctrl-C cancels the CommandContext of the command that is running, or discards the line being typed. The flags of
+// a line, such as --dry-run or -v, apply to that line only.
+func (a *App) Shell(prompt string) error {
+	a.commandsMu.Lock()
+	nested := a.inShell
+	a.inShell = true
+	a.commandsMu.Unlock()
+	if nested {
+		return errors.New("already in the shell")
+	}
+	defer func() {
+		a.commandsMu.Lock()
+		a.inShell = false
+		a.commandsMu.Unlock()
+	}()
+
+	history := a.loadShellHistory()
+	in := a.stdinReader()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	ctx := a.CommandContext()
+	w := a.ErrOutput()
+	var pending <-chan readResult
+	for {
+		_, _ = io.WriteString(w, prompt)
+		_ = a.Flush()
+
+		// a line is only read while at the prompt, so that the commands, such as a Confirm, read the input after theirs
+		if pending == nil {
+			pending = readLine(in)
+		}
+
+		var line string
+		select {
+		case <-ctx.Done():
+			_, _ = fmt.Fprintln(w)
+			return nil
+		case <-interrupts:
+			_, _ = fmt.Fprintln(w)
+			continue
+		case res := <-pending:
+			pending = nil
+			if res.err != nil && res.line == "" {
+				_, _ = fmt.Fprintln(w)
+				return nil
+			}
+			line = res.line
+		}
+
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "!") {
+			expanded, err := expandHistory(history, line)
+			if err != nil {
+				_, _ = io.WriteString(w, clierror.Render(err, false))
+				continue
+			}
+			line = expanded
+			_, _ = fmt.Fprintln(w, line)
+		}
+
+		switch {
+		case line == "":
+			continue
+		case line == "exit" || line == "quit":
+			return nil
+		case strings.HasSuffix(line, "?"):
+			a.shellComplete(strings.TrimSuffix(line, "?"))
+			continue
+		}
+
+		history = append(history, line)
+		if len(history) > ShellHistorySize {
+			history = history[len(history)-ShellHistorySize:]
+		}
+		a.saveShellHistory(history)
+
+		if line == "history" {
+			for idx, entry := range history {
+				_, _ = fmt.Fprintf(a.Output(), "%5d  %s\n", idx+1, entry)
+			}
+			continue
+		}
+
+		args, err := splitWords(line)
+		if err == nil {
+			err = a.runShellLine(ctx, args, interrupts)
+		}
+		if err != nil {
+			_, _ = io.WriteString(w, clierror.Render(err, a.Verbosity() >= VerbosityDebug))
+		}
+	}
+}
+
+type readResult struct {
+	line string
+	err  error
+}
+
+// readLine reads the next line of r in the background, so that the Shell can wait for it and for interrupts at once.
+func readLine(r *bufio.Reader) <-chan readResult {
+	ch := make(chan readResult, 1)
+	go func() {
+		line, err := r.ReadString('\n')
+		ch <- readResult{line, err}
+	}()
+	return ch
+}
+
+// runShellLine dispatches args with a CommandContext that an interrupt cancels, then forgets the flags they set.
+func (a *App) runShellLine(ctx context.Context, args []string, interrupts <-chan os.Signal) error {
+	a.layersMu.Lock()
+	flags := make(map[string]string, len(a.flags))
+	for k, v := range a.flags {
+		flags[k] = v
+	}
+	a.layersMu.Unlock()
+	verbosity, verbositySet := a.verbosityState()
+	code := a.failureCode()
+
+	lineCtx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-stopped:
+		}
+	}()
+
+	defer func() {
+		close(stopped)
+		cancel()
+
+		a.layersMu.Lock()
+		a.flags = flags
+		a.layersMu.Unlock()
+		a.restoreVerbosity(verbosity, verbositySet)
+		a.setFailureCode(code)
+	}()
+	return a.withCommandContext(lineCtx, func() error {
+		return a.dispatch(args)
+	})
+}
+
+// shellComplete lists the completions of the last word of line, as the __complete command does.
+func (a *App) shellComplete(line string) {
+	words, err := splitWords(line)
+	if err != nil {
+		return
+	}
+	if line == "" || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+
+	tw := tabwriter.NewWriter(a.Output(), 0, 0, 2, ' ', 0)
+	_ = a.writeCompletions(tw, words)
+	_ = tw.Flush()
+}
+
+// expandHistory returns the line of history that line, !! or !N, refers to.
+func expandHistory(history []string, line string) (string, error) {
+	if line == "!!" {
+		if len(history) == 0 {
+			return "", errors.New("no history")
+		}
+		return history[len(history)-1], nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(history) {
+		return "", fmt.Errorf("no history entry %s", line[1:])
+	}
+	return history[n-1], nil
+}
+
+func (a *App) shellHistoryPath() string {
+	dir, err := a.StateDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "history")
+}
+
+func (a *App) loadShellHistory() []string {
+	path := a.shellHistoryPath()
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func (a *App) saveShellHistory(history []string) {
+	path := a.shellHistoryPath()
+	if path == "" {
+		return
+	}
+	if err := atomicfile.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o600); err != nil {
+		_ = a.Logger().Debugf("unable to save the shell history: %v", err)
+	}
+}
+
+// splitWords splits line into words as a shell does: on spaces, except within single or double quotes, and
+// keeping the character after a backslash, outside single quotes, as it is.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	switch {
+	case quote != 0:
+		return nil, errors.New("unterminated quote")
+	case escaped:
+		return nil, errors.New("trailing backslash")
+	case inWord:
+		words = append(words, word.String())
+	}
+	return words, nil
+}
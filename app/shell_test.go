@@ -0,0 +1,75 @@
+package app_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Shell(t *testing.T) {
+	dir := t.TempDir()
+	a, ran := newCommandApp()
+	a.Environment = []string{"APP_STATE_DIR=" + dir}
+	a.Stdin = strings.NewReader("serve -a :1 x\n!!\n--dry-run check\ncheck\nse?\nserve 'x\nhistory\nexit\nserve\n")
+
+	var dryRuns []bool
+	a.AddCommand(app.NewCommand("check", "check the changes", func(a *app.App, _ *struct{}, _ []string) error {
+		dryRuns = append(dryRuns, a.DryRun())
+		return nil
+	}))
+
+	require.NoError(t, a.Shell("> "))
+	assert.Equal(t, []string{":1", "x", ":1", "x"}, *ran)
+	assert.Equal(t, []bool{true, false}, dryRuns)
+
+	stdout := a.Stdout.(*bytes.Buffer).String()
+	assert.Contains(t, stdout, "serve  run the server\n")
+	assert.NotContains(t, stdout, "migrate")
+	assert.Contains(t, stdout, "    1  serve -a :1 x\n    2  serve -a :1 x\n")
+	assert.Contains(t, stdout, "    6  history\n")
+
+	stderr := a.Stderr.(*bytes.Buffer).String()
+	assert.True(t, strings.HasPrefix(stderr, "> > serve -a :1 x\n> "), stderr)
+	assert.Contains(t, stderr, "error: unterminated quote\n")
+
+	b, err := os.ReadFile(filepath.Join(dir, "history"))
+	require.NoError(t, err)
+	assert.Equal(t, "serve -a :1 x\nserve -a :1 x\n--dry-run check\ncheck\nserve 'x\nhistory\n", string(b))
+
+	a.Stdin = strings.NewReader("!1\n!9\n")
+	require.NoError(t, a.Shell("> "))
+	assert.Equal(t, []string{":1", "x", ":1", "x", ":1", "x"}, *ran)
+	assert.Contains(t, a.Stderr.(*bytes.Buffer).String(), "error: no history entry 9\n")
+}
+
+func TestApp_Shell_Confirm(t *testing.T) {
+	a, _ := newCommandApp()
+	a.Environment = []string{"APP_STATE_DIR=" + t.TempDir()}
+	app.SetInteractive(a, true)
+	a.Stdin = strings.NewReader("drop prod\ny\ndrop test\nno\nexit\n")
+
+	var dropped []string
+	a.AddCommand(app.NewCommand("drop", "drop a database", func(a *app.App, _ *struct{}, args []string) error {
+		if err := a.Confirm("Drop "+args[0]+"?", app.ConfirmOptions{}); err != nil {
+			return err
+		}
+		dropped = append(dropped, args[0])
+		return nil
+	}))
+
+	// the answers are read by Confirm, not run as commands
+	require.NoError(t, a.Shell("> "))
+	assert.Equal(t, []string{"prod"}, dropped)
+
+	stderr := a.Stderr.(*bytes.Buffer).String()
+	assert.Equal(t, 2, strings.Count(stderr, "[y/N] "), stderr)
+	assert.Contains(t, stderr, "error: not confirmed\n")
+	assert.NotContains(t, stderr, "unknown command")
+}
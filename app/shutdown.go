@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShuttingDown is the cause of the contexts ShutdownContext cancels.
+var ErrShuttingDown = errors.New("app: shutting down")
+
+type shutdownKey struct{}
+
+func (a *App) ensureShuttingDown() chan struct{} {
+	a.shutdownMu.Lock()
+	defer a.shutdownMu.Unlock()
+
+	if a.shuttingDown == nil {
+		a.shuttingDown = make(chan struct{})
+	}
+	return a.shuttingDown
+}
+
+// ShuttingDown returns a channel that is closed once Run begins shutting down the servers, before the first is shut
+// down, so that long running work, such as streams and uploads, can end early rather than be cut off once the
+// shutdown timeout passes.
+func (a *App) ShuttingDown() <-chan struct{} {
+	return a.ensureShuttingDown()
+}
+
+// beginShutdown closes the ShuttingDown channel.
+func (a *App) beginShutdown() {
+	ch := a.ensureShuttingDown()
+	a.shutdownOnce.Do(func() { close(ch) })
+}
+
+func (a *App) ensureStopped() chan struct{} {
+	a.shutdownMu.Lock()
+	defer a.shutdownMu.Unlock()
+
+	if a.stopped == nil {
+		a.stopped = make(chan struct{})
+	}
+	return a.stopped
+}
+
+// Stop makes Run shut down the servers and the scheduled tasks and return nil, as it does when the process receives
+// SIGTERM, for an app that has finished its work. Calling Stop again has no effect.
+func (a *App) Stop() {
+	ch := a.ensureStopped()
+	a.stopOnce.Do(func() { close(ch) })
+}
+
+// WithShutdown returns a copy of ctx carrying the ShuttingDown channel of the app. The HTTPServer and GRPCServer give
+// it to the contexts of the requests they serve.
+func (a *App) WithShutdown(ctx context.Context) context.Context {
+	return context.WithValue(ctx, shutdownKey{}, (<-chan struct{})(a.ensureShuttingDown()))
+}
+
+// ShuttingDown returns the ShuttingDown channel of the app ctx carries, see WithShutdown, or nil, a channel that is
+// never closed, if it carries none. A handler streaming its response ends the stream with:
+//
+//	select {
+//	case <-app.ShuttingDown(r.Context()):
+//		return // the client reconnects to another instance
+//	case event := <-events:
+//		...
+//	}
+func ShuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(shutdownKey{}).(<-chan struct{})
+	return ch
+}
+
+// ShutdownContext returns a copy of ctx canceled, with ErrShuttingDown as its cause, grace after the app ctx carries
+// begins shutting down, so that a handler gets to answer, with partial results or 503 Service Unavailable, before the
+// server cuts it off. A grace shorter than the shutdown timeout leaves the handler the rest to answer. Calling the
+// CancelFunc releases the resources of the context.
+func ShutdownContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stopping := ShuttingDown(ctx)
+	if stopping == nil {
+		return ctx, func() { cancel(context.Canceled) }
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopping:
+		}
+		if grace > 0 {
+			t := time.NewTimer(grace)
+			defer t.Stop()
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+		cancel(ErrShuttingDown)
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
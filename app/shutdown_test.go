@@ -0,0 +1,112 @@
+package app_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// hookServer is a Server calling its functions when it is bound and shut down.
+type hookServer struct {
+	bind     func()
+	shutdown func()
+	stop     chan struct{}
+}
+
+func (s *hookServer) Bind(*app.App) error {
+	s.bind()
+	return nil
+}
+
+func (s *hookServer) Serve() error {
+	<-s.stop
+	return nil
+}
+
+func (s *hookServer) Shutdown(context.Context) error {
+	s.shutdown()
+	close(s.stop)
+	return nil
+}
+
+func TestApp_ShuttingDown(t *testing.T) {
+	a := newApp(nil)
+	s := app.NewHTTPServer("tcp://127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("event 1\n"))
+		_ = http.NewResponseController(w).Flush()
+		<-app.ShuttingDown(r.Context())
+		_, _ = w.Write([]byte("shutting down\n"))
+	}))
+
+	addr := make(chan string, 1)
+	var closed bool
+	a.Register("http", s)
+	a.Register("hook", &hookServer{
+		bind: func() { addr <- s.ListenerAddr().String() },
+		shutdown: func() {
+			select {
+			case <-a.ShuttingDown():
+				closed = true
+			default:
+			}
+		},
+		stop: make(chan struct{}),
+	})
+
+	ran := make(chan error, 1)
+	go func() { ran <- a.Run() }()
+
+	res, err := http.Get("http://" + <-addr + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	lines := bufio.NewScanner(res.Body)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "event 1", lines.Text())
+
+	// the stream ends as the app shuts down, rather than when the shutdown timeout passes
+	a.HandleError(nil)
+	require.True(t, lines.Scan())
+	assert.Equal(t, "shutting down", lines.Text())
+	assert.NoError(t, <-ran)
+	assert.True(t, closed, "ShuttingDown is closed before the servers are shut down")
+}
+
+func TestShutdownContext(t *testing.T) {
+	a := newApp(nil)
+	ctx := a.WithShutdown(context.Background())
+	assert.Equal(t, a.ShuttingDown(), app.ShuttingDown(ctx))
+	assert.Nil(t, app.ShuttingDown(context.Background()))
+
+	now, cancelNow := app.ShutdownContext(ctx, 0)
+	defer cancelNow()
+	soon, cancelSoon := app.ShutdownContext(ctx, 20*time.Millisecond)
+	defer cancelSoon()
+	later, cancelLater := app.ShutdownContext(ctx, time.Hour)
+	plain, cancelPlain := app.ShutdownContext(context.Background(), 0)
+	defer cancelPlain()
+
+	go a.HandleError(nil)
+	require.NoError(t, a.Run())
+
+	<-now.Done()
+	assert.ErrorIs(t, now.Err(), context.Canceled)
+	assert.ErrorIs(t, context.Cause(now), app.ErrShuttingDown)
+
+	assert.NoError(t, soon.Err())
+	assert.Eventually(t, func() bool { return soon.Err() != nil }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, context.Cause(soon), app.ErrShuttingDown)
+
+	assert.NoError(t, later.Err())
+	cancelLater()
+	assert.ErrorIs(t, context.Cause(later), context.Canceled)
+
+	// a context without the app is only canceled by its CancelFunc
+	assert.NoError(t, plain.Err())
+}
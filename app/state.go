@@ -0,0 +1,198 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateDir returns the directory where the app keeps data that should survive restarts, creating it if it does not
+// exist. The directory is APP_STATE_DIR when set, otherwise a directory named after the executable in
+// $XDG_STATE_HOME, which defaults to ~/.local/state.
+func (a *App) StateDir() (string, error) {
+	return a.userDir("state", "APP_STATE_DIR", "XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// CacheDir returns the directory where the app keeps data it can fetch or compute again, such as HTTP responses,
+// creating it if it does not exist. The directory is APP_CACHE_DIR when set, otherwise a directory named after the
+// executable in $XDG_CACHE_HOME, which defaults to ~/.cache.
+func (a *App) CacheDir() (string, error) {
+	return a.userDir("cache", "APP_CACHE_DIR", "XDG_CACHE_HOME", ".cache")
+}
+
+// DataDir returns the directory where the app keeps data of the user that is neither state nor cache, such as the
+// development certificates of the HTTPServer, creating it if it does not exist. The directory is APP_DATA_DIR when
+// set, otherwise a directory named after the executable in $XDG_DATA_HOME, which defaults to ~/.local/share.
+func (a *App) DataDir() (string, error) {
+	return a.userDir("data", "APP_DATA_DIR", "XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// userDir returns the directory named by the variable override, or the directory named after the executable in the
+// directory named by the variable xdg, which defaults to home in the home directory, creating it if it does not exist.
+func (a *App) userDir(kind, override, xdg, home string) (string, error) {
+	dir, err := a.userPath(kind, override, xdg, home)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// userPath returns the path named by the variable override, or the directory named after the executable in the
+// directory named by the variable xdg, which defaults to home in the home directory.
+func (a *App) userPath(kind, override, xdg, home string) (string, error) {
+	if path, _ := a.LookupEnv(override); path != "" {
+		return path, nil
+	}
+
+	base, _ := a.LookupEnv(xdg)
+	if base == "" {
+		v, _ := a.LookupEnv("HOME")
+		if v == "" {
+			return "", fmt.Errorf("unable to determine the %s directory, set %s", kind, override)
+		}
+		base = filepath.Join(v, home)
+	}
+	return filepath.Join(base, executableName()), nil
+}
+
+// executableName returns the name of the executable, without the .exe extension of Windows.
+func executableName() string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = os.Args[0]
+	}
+	return strings.TrimSuffix(filepath.Base(exe), ".exe")
+}
+
+// buildVersion returns the version of the main module, as recorded by go install, or the empty string.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
+
+// State is a small JSON document of values the app keeps between runs, such as one-time notices already shown or the
+// version local data was migrated to. It is stored as state.json in StateDir. State is safe for concurrent use, but
+// concurrent processes overwrite each other's changes.
+type State struct {
+	mu          sync.Mutex
+	path        string
+	doc         stateDoc
+	firstRun    bool
+	lastVersion string
+}
+
+type stateDoc struct {
+	FirstRun time.Time                  `json:"first_run"`
+	LastRun  time.Time                  `json:"last_run"`
+	Version  string                     `json:"version,omitempty"`
+	Values   map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+// State loads the app State the first time it is called, recording the current run and the app Version.
+func (a *App) State() (*State, error) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+
+	if a.state != nil {
+		return a.state, nil
+	}
+
+	dir, err := a.StateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{path: filepath.Join(dir, "state.json")}
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		s.firstRun = true
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(b, &s.doc); err != nil {
+			return nil, fmt.Errorf("invalid state file %s: %v", s.path, err)
+		}
+	}
+
+	now := a.clock().Now().UTC()
+	if s.doc.FirstRun.IsZero() {
+		s.doc.FirstRun = now
+	}
+	s.doc.LastRun = now
+	s.lastVersion, s.doc.Version = s.doc.Version, a.Version
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+
+	a.state = s
+	return s, nil
+}
+
+// FirstRun reports whether there was no state before this run.
+func (s *State) FirstRun() bool {
+	return s.firstRun
+}
+
+// LastVersionRun returns the app Version recorded by the previous run, or the empty string on the first run.
+func (s *State) LastVersionRun() string {
+	return s.lastVersion
+}
+
+// Get decodes the value stored under key into v, reporting whether there was one.
+func (s *State) Get(key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.doc.Values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Set stores the JSON encoding of v under key and saves the state.
+func (s *State) Set(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.doc.Values == nil {
+		s.doc.Values = make(map[string]json.RawMessage)
+	}
+	s.doc.Values[key] = raw
+	return s.save()
+}
+
+// Delete removes the value stored under key and saves the state.
+func (s *State) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.doc.Values[key]; !ok {
+		return nil
+	}
+	delete(s.doc.Values, key)
+	return s.save()
+}
+
+func (s *State) save() error {
+	return writeJSONFile(s.path, s.doc, 0o600)
+}
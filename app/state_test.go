@@ -0,0 +1,135 @@
+package app_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_StateDir(t *testing.T) {
+	tmp := t.TempDir()
+
+	dir, err := newApp([]string{"APP_STATE_DIR=" + filepath.Join(tmp, "explicit")}).StateDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, "explicit"), dir)
+	assert.DirExists(t, dir)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+
+	dir, err = newApp([]string{"XDG_STATE_HOME=" + filepath.Join(tmp, "xdg")}).StateDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, "xdg", name), dir)
+
+	dir, err = newApp([]string{"HOME=" + tmp}).StateDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, ".local", "state", name), dir)
+
+	_, err = newApp(nil).StateDir()
+	assert.EqualError(t, err, "unable to determine the state directory, set APP_STATE_DIR")
+}
+
+func TestApp_CacheDir(t *testing.T) {
+	tmp := t.TempDir()
+
+	dir, err := newApp([]string{"APP_CACHE_DIR=" + filepath.Join(tmp, "explicit")}).CacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, "explicit"), dir)
+	assert.DirExists(t, dir)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+
+	dir, err = newApp([]string{"XDG_CACHE_HOME=" + filepath.Join(tmp, "xdg")}).CacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, "xdg", name), dir)
+
+	dir, err = newApp([]string{"HOME=" + tmp}).CacheDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, ".cache", name), dir)
+
+	_, err = newApp(nil).CacheDir()
+	assert.EqualError(t, err, "unable to determine the cache directory, set APP_CACHE_DIR")
+}
+
+func TestApp_State(t *testing.T) {
+	env := []string{"APP_STATE_DIR=" + t.TempDir()}
+
+	a := newApp(env)
+	a.Version = "v1.0.0"
+	s, err := a.State()
+	require.NoError(t, err)
+	assert.True(t, s.FirstRun())
+	assert.Empty(t, s.LastVersionRun())
+
+	same, err := a.State()
+	require.NoError(t, err)
+	assert.Same(t, s, same)
+
+	var shown bool
+	ok, err := s.Get("notice.shown", &shown)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, s.Set("notice.shown", true))
+	require.NoError(t, s.Set("migrated", map[string]int{"schema": 3}))
+
+	a = newApp(env)
+	a.Version = "v1.1.0"
+	s, err = a.State()
+	require.NoError(t, err)
+	assert.False(t, s.FirstRun())
+	assert.Equal(t, "v1.0.0", s.LastVersionRun())
+
+	ok, err = s.Get("notice.shown", &shown)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, shown)
+
+	require.NoError(t, s.Delete("notice.shown"))
+	require.NoError(t, s.Delete("missing"))
+
+	a = newApp(env)
+	s, err = a.State()
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", s.LastVersionRun())
+	ok, err = s.Get("notice.shown", &shown)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	var migrated map[string]int
+	ok, err = s.Get("migrated", &migrated)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]int{"schema": 3}, migrated)
+}
+
+func TestApp_State_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "state.json"), []byte("{"), 0o600))
+
+	_, err := newApp([]string{"APP_STATE_DIR=" + dir}).State()
+	assert.EqualError(t, err, "invalid state file "+filepath.Join(dir, "state.json")+": unexpected end of JSON input")
+}
+
+func TestApp_DataDir(t *testing.T) {
+	tmp := t.TempDir()
+
+	dir, err := newApp([]string{"APP_DATA_DIR=" + filepath.Join(tmp, "explicit")}).DataDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, "explicit"), dir)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+
+	dir, err = newApp([]string{"HOME=" + tmp}).DataDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmp, ".local", "share", name), dir)
+	assert.DirExists(t, dir)
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/i18n"
+)
+
+// Summary accumulates facts about a command run, such as the number of items processed. Exit renders the facts,
+// the warnings, and the duration of the run on ErrOutput: as a table, unless the Verbosity is quiet, or as a JSON
+// object when APP_OUTPUT_FORMAT is json. The facts are also attached to a final "summary" log record.
+type Summary struct {
+	mu       sync.Mutex
+	start    time.Time
+	keys     []string
+	facts    map[string]interface{}
+	warnings []string
+}
+
+// Summary returns the app Summary. The duration of the run is measured from the first call.
+func (a *App) Summary() *Summary {
+	a.summaryMu.Lock()
+	defer a.summaryMu.Unlock()
+
+	if a.summary == nil {
+		a.summary = &Summary{start: a.clock().Now(), facts: make(map[string]interface{})}
+	}
+	return a.summary
+}
+
+func (s *Summary) set(key string, value interface{}) {
+	if _, ok := s.facts[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.facts[key] = value
+}
+
+// Set records a fact, replacing any previous value. Facts are rendered in the order they were first set.
+func (s *Summary) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value)
+}
+
+// Add increments the counter key by n. The counter replaces any value previously set that is not an int64.
+func (s *Summary) Add(key string, n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, _ := s.facts[key].(int64)
+	s.set(key, v+n)
+}
+
+// Warn records a warning to show in the summary.
+func (s *Summary) Warn(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+// snapshot returns copies of the fact keys in order, the facts, and the warnings.
+func (s *Summary) snapshot() ([]string, map[string]interface{}, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	facts := make(map[string]interface{}, len(s.facts))
+	for k, v := range s.facts {
+		facts[k] = v
+	}
+	return append([]string(nil), s.keys...), facts, append([]string{}, s.warnings...)
+}
+
+func (a *App) outputFormat() string {
+	v, _ := a.LookupEnv("APP_OUTPUT_FORMAT")
+	switch v = strings.ToLower(v); v {
+	case "", "text":
+		return "text"
+	case "json":
+		return v
+	default:
+		_ = a.Logger().Warnf("invalid APP_OUTPUT_FORMAT %q, using text", v)
+		return "text"
+	}
+}
+
+// writeSummary logs and renders the Summary, if one was started.
+func (a *App) writeSummary() {
+	a.summaryMu.Lock()
+	s := a.summary
+	a.summaryMu.Unlock()
+	if s == nil {
+		return
+	}
+
+	keys, facts, warnings := s.snapshot()
+	keys = append(keys, "warnings", "duration")
+	facts["warnings"] = len(warnings)
+	facts["duration"] = a.clock().Since(s.start).Round(time.Millisecond).String()
+
+	_ = a.Logger().Infom(gomol.NewAttrsFromMap(facts), "summary")
+
+	if a.outputFormat() == "json" {
+		facts["warnings"] = warnings
+		// a map of plain values always encodes
+		b, _ := json.Marshal(facts)
+		_, _ = fmt.Fprintf(a.ErrOutput(), "%s\n", b)
+		return
+	}
+
+	if a.Verbosity() > VerbosityQuiet {
+		writeSummaryTable(a.ErrOutput(), a.Localizer(), keys, facts, warnings)
+	}
+}
+
+func writeSummaryTable(w io.Writer, l *i18n.Localizer, keys []string, facts map[string]interface{}, warnings []string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, l.T("app.summary"))
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(tw, "  %s\t%v\n", k, facts[k])
+	}
+	_ = tw.Flush()
+
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintf(w, "  %s\n", l.T("app.summary.warning", warning))
+	}
+}
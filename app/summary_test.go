@@ -0,0 +1,61 @@
+package app_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/efritz/glock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApp_Summary(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp(nil)
+	a.Clock = clock
+
+	s := a.Summary()
+	assert.Same(t, s, a.Summary())
+	s.Add("items processed", 40)
+	s.Set("source", "s3://bucket")
+	s.Add("items processed", 2)
+	s.Warn("skipped %d malformed rows", 3)
+	clock.Advance(1500 * time.Millisecond)
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	out := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, out, "] summary {")
+	assert.Contains(t, out, `"items processed":42`)
+	assert.Contains(t, out, "Summary\n"+
+		"  items processed  42\n"+
+		"  source           s3://bucket\n"+
+		"  warnings         1\n"+
+		"  duration         1.5s\n"+
+		"  warning: skipped 3 malformed rows\n")
+}
+
+func TestApp_Summary_JSON(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp([]string{"APP_OUTPUT_FORMAT=json"})
+	a.Clock = clock
+
+	a.Summary().Add("files", 3)
+	clock.Advance(time.Second)
+
+	assert.PanicsWithValue(t, "system exit 1", func() {
+		a.Exit(1)
+	})
+	assert.Contains(t, a.Stderr.(*bytes.Buffer).String(),
+		`{"duration":"1s","files":3,"warnings":[]}`+"\n")
+}
+
+func TestApp_Summary_Unused(t *testing.T) {
+	a := newApp(nil)
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+	assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+const (
+	// SupervisedEnv names the environment variable set for an app started by Supervise, holding the number of times
+	// it was restarted.
+	SupervisedEnv = "APP_SUPERVISED"
+
+	// DefaultSuperviseMaxRestarts is how many times Supervise restarts the app when APP_SUPERVISE_MAX_RESTARTS is not
+	// set.
+	DefaultSuperviseMaxRestarts = 5
+
+	// DefaultSuperviseBackoff is how long Supervise waits before the first restart when APP_SUPERVISE_BACKOFF is not
+	// set.
+	DefaultSuperviseBackoff = time.Second
+
+	// DefaultSuperviseMaxBackoff caps the wait between restarts when APP_SUPERVISE_MAX_BACKOFF is not set.
+	DefaultSuperviseMaxBackoff = time.Minute
+)
+
+// Supervised reports whether this app was started by Supervise.
+func (a *App) Supervised() bool {
+	_, ok := a.LookupEnv(SupervisedEnv)
+	return ok
+}
+
+// Supervise runs the app as a supervised child process when it was started with the --supervise argument or
+// APP_SUPERVISE=true, and should be called first thing, before any output. It is configured by the environment:
+//
+//	APP_SUPERVISE_MAX_RESTARTS  how many times the child is restarted, 5 by default; 0 never gives up
+//	APP_SUPERVISE_BACKOFF       the wait before the first restart, 1s by default, doubled for every restart
+//	APP_SUPERVISE_MAX_BACKOFF   the longest wait between restarts, 1m by default
+//
+// The child is a copy of the running executable without the --supervise argument, started as a Child, so signals
+// reach it and it is stopped when the supervisor exits. Each line it writes to Stdout and Stderr is logged by the
+// supervisor. The child is restarted when it fails or crashes, but not when it succeeds or the supervisor was asked
+// to stop with SIGINT or SIGTERM; the backoff starts over once a child has run for the maximum backoff. The
+// supervisor exits with the exit code of the last child, or 128 plus the signal that killed it. In the child, and
+// when not requested, Supervise returns nil.
+func (a *App) Supervise() error {
+	if a.Supervised() {
+		return nil
+	}
+
+	ok, err := a.modeRequested("--supervise", "APP_SUPERVISE")
+	if err != nil || !ok {
+		return err
+	}
+
+	maxRestarts := DefaultSuperviseMaxRestarts
+	if v := a.trimmedEnv("APP_SUPERVISE_MAX_RESTARTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid APP_SUPERVISE_MAX_RESTARTS %q", v)
+		}
+		maxRestarts = n
+	}
+
+	backoff, err := a.lookupDuration("APP_SUPERVISE_BACKOFF", DefaultSuperviseBackoff)
+	if err != nil {
+		return err
+	}
+	maxBackoff, err := a.lookupDuration("APP_SUPERVISE_MAX_BACKOFF", DefaultSuperviseMaxBackoff)
+	if err != nil {
+		return err
+	}
+
+	a.Exit(a.supervise(maxRestarts, backoff, maxBackoff))
+	return nil
+}
+
+func (a *App) supervise(maxRestarts int, initial, maxBackoff time.Duration) int {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, terminateSignal)
+	defer signal.Stop(stop)
+
+	backoff := initial
+	for restarts := 0; ; restarts++ {
+		started := a.clock().Now()
+		code := a.runSupervised(restarts)
+
+		select {
+		case sig := <-stop:
+			_ = a.Logger().Infof("received %s, not restarting", sig)
+			return code
+		default:
+		}
+
+		if code == 0 {
+			return 0
+		}
+		if maxRestarts > 0 && restarts >= maxRestarts {
+			_ = a.Logger().Errorf("giving up after %d restarts", restarts)
+			return code
+		}
+
+		if a.clock().Since(started) >= maxBackoff {
+			backoff = initial
+		}
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"code": code, "restart": restarts + 1})
+		_ = a.Logger().Warnm(attrs, "child failed, restarting in %s", backoff)
+
+		select {
+		case <-a.clock().After(backoff):
+		case sig := <-stop:
+			_ = a.Logger().Infof("received %s, not restarting", sig)
+			return code
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runSupervised runs the child once and returns its exit code.
+func (a *App) runSupervised(restarts int) int {
+	path, err := os.Executable()
+	if err != nil {
+		_ = a.Logger().Errorf("unable to start child: %v", err)
+		return 1
+	}
+
+	var args []string
+	for _, arg := range a.Arguments {
+		if arg != "--supervise" {
+			args = append(args, arg)
+		}
+	}
+
+	c := a.Command(path, args...)
+	c.Cmd.Env = nil
+	for _, line := range a.environ() {
+		if !strings.HasPrefix(line, "APP_SUPERVISE=") && !strings.HasPrefix(line, SupervisedEnv+"=") {
+			c.Cmd.Env = append(c.Cmd.Env, line)
+		}
+	}
+	c.Cmd.Env = append(c.Cmd.Env, fmt.Sprintf("%s=%d", SupervisedEnv, restarts))
+
+	stdout := &lineLogger{app: a, level: gomol.LevelInfo, stream: "stdout", restart: restarts}
+	stderr := &lineLogger{app: a, level: gomol.LevelWarning, stream: "stderr", restart: restarts}
+	c.Cmd.Stdout, c.Cmd.Stderr = stdout, stderr
+
+	if err := c.Start(); err != nil {
+		_ = a.Logger().Errorf("unable to start child: %v", err)
+		return 1
+	}
+
+	err = c.Wait()
+	stdout.flush()
+	stderr.flush()
+	return exitCode(err)
+}
+
+// exitCode returns the exit code of a process that exited with err, 128 plus the signal if it was killed by one.
+func exitCode(err error) int {
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exit):
+		if status, ok := exit.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exit.ExitCode()
+	default:
+		return 1
+	}
+}
+
+// lineLogger logs every line written to it.
+type lineLogger struct {
+	app     *App
+	level   gomol.LogLevel
+	stream  string
+	restart int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		idx := bytes.IndexByte(l.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		l.log(string(l.buf[:idx]))
+		l.buf = l.buf[idx+1:]
+	}
+	return len(p), nil
+}
+
+// flush logs the last line, if it did not end with a newline.
+func (l *lineLogger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.log(string(l.buf))
+		l.buf = nil
+	}
+}
+
+func (l *lineLogger) log(line string) {
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"stream": l.stream, "restart": l.restart})
+	_ = l.app.Logger().Log(l.level, attrs, "%s", strings.TrimSuffix(line, "\r"))
+}
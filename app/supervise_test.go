@@ -0,0 +1,78 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const superviseChildEnv = "APP_TEST_SUPERVISE_SUCCEED_AT"
+
+// TestApp_Supervise runs as the supervised child too, failing until it has been restarted the number of times in
+// superviseChildEnv.
+func TestApp_Supervise(t *testing.T) {
+	if v, ok := os.LookupEnv(superviseChildEnv); ok {
+		a := app.New()
+		if !a.Supervised() {
+			t.Skip("not supervised")
+		}
+		restarts, _ := strconv.Atoi(os.Getenv(app.SupervisedEnv))
+		succeedAt, _ := strconv.Atoi(v)
+		fmt.Printf("run %d\n", restarts)
+		_, _ = fmt.Fprint(os.Stderr, "no newline")
+		if restarts < succeedAt {
+			os.Exit(3)
+		}
+		os.Exit(0)
+	}
+
+	env := append(os.Environ(), superviseChildEnv+"=2", "APP_SUPERVISE_BACKOFF=10ms")
+	a := newApp(env, "-test.run=^TestApp_Supervise$", "--supervise")
+	assert.False(t, a.Supervised())
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		_ = a.Supervise()
+	})
+
+	logs := a.Stderr.(*bytes.Buffer).String()
+	for idx := 0; idx <= 2; idx++ {
+		assert.Regexp(t, fmt.Sprintf(`INFO.*\] run %d \{.*"restart":%d,.*"stream":"stdout"`, idx, idx), logs)
+		assert.Regexp(t, fmt.Sprintf(`WARN.*\] no newline \{.*"restart":%d,.*"stream":"stderr"`, idx), logs)
+	}
+	assert.Regexp(t, `WARN.*\] child failed, restarting in 10ms \{"code":3,.*"restart":1,`, logs)
+	assert.Regexp(t, `WARN.*\] child failed, restarting in 20ms \{"code":3,.*"restart":2,`, logs)
+}
+
+func TestApp_Supervise_GiveUp(t *testing.T) {
+	env := append(os.Environ(), superviseChildEnv+"=5", "APP_SUPERVISE=true", "APP_SUPERVISE_BACKOFF=1ms",
+		"APP_SUPERVISE_MAX_RESTARTS=1")
+	a := newApp(env, "-test.run=^TestApp_Supervise$")
+	assert.PanicsWithValue(t, "system exit 3", func() {
+		_ = a.Supervise()
+	})
+
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, logs, "run 1")
+	assert.NotContains(t, logs, "run 2")
+	assert.Regexp(t, `ERROR.*\] giving up after 1 restarts`, logs)
+}
+
+func TestApp_Supervise_Invalid(t *testing.T) {
+	assert.NoError(t, newApp(nil).Supervise())
+	assert.NoError(t, newApp([]string{app.SupervisedEnv + "=0"}, "--supervise").Supervise())
+
+	assert.EqualError(t, newApp([]string{"APP_SUPERVISE=often"}).Supervise(), `invalid APP_SUPERVISE "often"`)
+
+	tests := map[string]string{
+		"APP_SUPERVISE_MAX_RESTARTS": `invalid APP_SUPERVISE_MAX_RESTARTS "often"`,
+		"APP_SUPERVISE_BACKOFF":      `invalid APP_SUPERVISE_BACKOFF "often"`,
+	}
+	for key, expected := range tests {
+		assert.EqualError(t, newApp([]string{key + "=often"}, "--supervise").Supervise(), expected)
+	}
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+	"github.com/efritz/glock"
+)
+
+// Task is a function scheduled to run once with After or At.
+type Task struct {
+	at       time.Time
+	canceled chan struct{}
+	cancel   sync.Once
+	started  chan struct{}
+	done     chan struct{}
+}
+
+// When returns the time the task is scheduled to run.
+func (t *Task) When() time.Time {
+	return t.at
+}
+
+// Cancel prevents the task from running. It returns false if the task has already started or was already canceled.
+// Cancel does not interrupt a running task.
+func (t *Task) Cancel() bool {
+	canceled := false
+	t.cancel.Do(func() {
+		close(t.canceled)
+		canceled = true
+	})
+	return canceled
+}
+
+// Done returns a channel that is closed once the task has run or been canceled.
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+type taskSet struct {
+	mu      sync.Mutex
+	pending map[*Task]struct{}
+	running sync.WaitGroup
+	closed  bool
+	ctx     context.Context
+	stop    context.CancelFunc
+}
+
+func (a *App) clock() glock.Clock {
+	if a.Clock == nil {
+		return glock.NewRealClock()
+	}
+	return a.Clock
+}
+
+// After runs fn once the duration elapses on the app Clock. Tasks belong to the App rather than to any server, so
+// they outlive configuration reloads. A panic in fn is recovered and logged. When Run returns, pending tasks are
+// canceled and running tasks are awaited for up to APP_SHUTDOWN_TIMEOUT, after which the context passed to fn is
+// canceled.
+func (a *App) After(d time.Duration, fn func(ctx context.Context)) *Task {
+	clock := a.clock()
+	return a.schedule(clock.Now().Add(d), clock.After(d), fn)
+}
+
+// At runs fn at the time t on the app Clock, as After does. A time in the past runs fn immediately.
+func (a *App) At(t time.Time, fn func(ctx context.Context)) *Task {
+	clock := a.clock()
+	return a.schedule(t, clock.After(clock.Until(t)), fn)
+}
+
+func (a *App) schedule(at time.Time, fire <-chan time.Time, fn func(ctx context.Context)) *Task {
+	t := &Task{
+		at:       at,
+		canceled: make(chan struct{}),
+		started:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	s := &a.tasks
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = a.Logger().Warnf("task scheduled for %s after shutdown, not running", at.Format(time.RFC3339))
+		t.Cancel()
+		close(t.done)
+		return t
+	}
+	if s.pending == nil {
+		s.pending = make(map[*Task]struct{})
+		s.ctx, s.stop = context.WithCancel(context.Background())
+	}
+	s.pending[t] = struct{}{}
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	go func() {
+		defer close(t.done)
+
+		select {
+		case <-fire:
+		case <-t.canceled:
+		}
+
+		s.mu.Lock()
+		delete(s.pending, t)
+		run := false
+		t.cancel.Do(func() {
+			if s.closed {
+				close(t.canceled)
+				return
+			}
+			close(t.started)
+			run = true
+		})
+		if run {
+			s.running.Add(1)
+		}
+		s.mu.Unlock()
+
+		if run {
+			defer s.running.Done()
+			a.runTask(ctx, t, fn)
+		}
+	}()
+
+	return t
+}
+
+func (a *App) runTask(ctx context.Context, t *Task, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"task": t.at.Format(time.RFC3339)})
+			_ = a.Logger().Errorm(attrs, "task panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	fn(ctx)
+}
+
+// shutdownTasks cancels pending tasks and waits for running tasks until the timeout elapses, then cancels their
+// context without waiting further.
+func (a *App) shutdownTasks(timeout time.Duration) {
+	s := &a.tasks
+	s.mu.Lock()
+	s.closed = true
+	pending := make([]*Task, 0, len(s.pending))
+	for t := range s.pending {
+		pending = append(pending, t)
+	}
+	s.mu.Unlock()
+
+	for _, t := range pending {
+		if t.Cancel() {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"task": t.at.Format(time.RFC3339)})
+			_ = a.Logger().Infom(attrs, "task canceled")
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		_ = a.Logger().Warnf("tasks still running after %s, canceling", timeout)
+		s.mu.Lock()
+		if s.stop != nil {
+			s.stop()
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,113 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/efritz/glock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_After(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp(nil)
+	a.Clock = clock
+
+	ran := make(chan struct{})
+	task := a.After(time.Minute, func(ctx context.Context) { close(ran) })
+	assert.Equal(t, clock.Now().Add(time.Minute), task.When())
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-ran:
+		t.Fatal("task ran early")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	<-ran
+	<-task.Done()
+	assert.False(t, task.Cancel())
+}
+
+func TestApp_At(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp(nil)
+	a.Clock = clock
+
+	ran := make(chan struct{})
+	task := a.At(clock.Now().Add(time.Hour), func(ctx context.Context) { close(ran) })
+
+	clock.Advance(time.Hour)
+	<-ran
+	<-task.Done()
+
+	// a time in the past runs immediately
+	past := a.At(clock.Now().Add(-time.Hour), func(ctx context.Context) {})
+	clock.Advance(0)
+	<-past.Done()
+}
+
+func TestTask_Cancel(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp(nil)
+	a.Clock = clock
+
+	task := a.After(time.Minute, func(ctx context.Context) { t.Error("canceled task ran") })
+	assert.True(t, task.Cancel())
+	assert.False(t, task.Cancel())
+	<-task.Done()
+
+	clock.Advance(time.Minute)
+}
+
+func TestApp_After_Panic(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp(nil)
+	a.Clock = clock
+
+	task := a.After(time.Second, func(ctx context.Context) { panic("boom") })
+	clock.Advance(time.Second)
+	<-task.Done()
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Contains(t, a.Stderr.(*bytes.Buffer).String(), "task panicked: boom")
+}
+
+func TestApp_Run_Tasks(t *testing.T) {
+	clock := glock.NewMockClock()
+	a := newApp([]string{"APP_SHUTDOWN_TIMEOUT=50ms"})
+	a.Clock = clock
+
+	pending := a.After(time.Hour, func(ctx context.Context) { t.Error("pending task ran") })
+
+	started := make(chan struct{})
+	var canceled error
+	running := a.After(time.Second, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		canceled = ctx.Err()
+	})
+	clock.Advance(time.Second)
+	<-started
+
+	go a.HandleError(nil)
+	require.NoError(t, a.Run())
+
+	<-pending.Done()
+	<-running.Done()
+	assert.Equal(t, context.Canceled, canceled)
+
+	// tasks scheduled after shutdown never run
+	late := a.After(0, func(ctx context.Context) { t.Error("late task ran") })
+	clock.Advance(0)
+	<-late.Done()
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	out := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, out, "task canceled")
+	assert.Contains(t, out, "tasks still running after 50ms, canceling")
+}
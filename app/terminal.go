@@ -0,0 +1,248 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal is returned by RawMode when Stdin is not a terminal.
+var ErrNotTerminal = errors.New("stdin is not a terminal")
+
+// KeyCode is the key of a Key that is not a character.
+type KeyCode int
+
+// The keys a KeyReader reads. KeyRune is a character, given by the Rune of the Key.
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyUp
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+)
+
+var keyNames = [...]string{"", "enter", "tab", "backspace", "esc", "up", "down", "right", "left", "home", "end",
+	"pgup", "pgdown", "insert", "delete"}
+
+// Key is a key pressed on the terminal, with the modifiers held down.
+type Key struct {
+	Code  KeyCode
+	Rune  rune // the character, for KeyRune
+	Ctrl  bool
+	Alt   bool
+	Shift bool // only reported for the keys that are not characters
+}
+
+// String returns the key as it is commonly written, such as a, ctrl+c, alt+x, or shift+up.
+func (k Key) String() string {
+	var sb strings.Builder
+	if k.Ctrl {
+		sb.WriteString("ctrl+")
+	}
+	if k.Alt {
+		sb.WriteString("alt+")
+	}
+	if k.Shift {
+		sb.WriteString("shift+")
+	}
+	switch {
+	case k.Code != KeyRune && int(k.Code) < len(keyNames):
+		sb.WriteString(keyNames[k.Code])
+	case k.Code != KeyRune:
+		sb.WriteString("key" + strconv.Itoa(int(k.Code)))
+	case k.Rune == ' ':
+		sb.WriteString("space")
+	default:
+		sb.WriteRune(k.Rune)
+	}
+	return sb.String()
+}
+
+// RawMode runs fn with the terminal on Stdin in raw mode: keys are read as they are pressed, without echo, and
+// Ctrl-C is read as a key rather than sent as an interrupt. fn reads the keys from the KeyReader it is given:
+//
+//	err := a.RawMode(func(keys *app.KeyReader) error {
+//		for {
+//			key, err := keys.ReadKey()
+//			if err != nil || key.String() == "ctrl+c" {
+//				return err
+//			}
+//			...
+//		}
+//	})
+//
+// Output is not processed either, so lines written while fn runs must end with "\r\n". The terminal is restored when
+// fn returns or panics, and when the app Exits while it runs. RawMode returns ErrNotTerminal if Stdin is not a
+// terminal.
+func (a *App) RawMode(fn func(keys *KeyReader) error) error {
+	f, ok := a.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return fmt.Errorf("unable to make the terminal raw: %w", err)
+	}
+	var once sync.Once
+	restore := func() {
+		once.Do(func() { _ = term.Restore(int(f.Fd()), state) })
+	}
+	remove := a.onExit(func(int) { restore() })
+	defer func() {
+		remove()
+		restore()
+	}()
+
+	return fn(NewKeyReader(f))
+}
+
+// KeyReader reads the keys pressed on a terminal in raw mode from the bytes the terminal sends, decoding the escape
+// sequences of the special keys and the modifiers.
+type KeyReader struct {
+	r *bufio.Reader
+}
+
+// NewKeyReader returns a KeyReader that reads from r.
+func NewKeyReader(r io.Reader) *KeyReader {
+	return &KeyReader{r: bufio.NewReader(r)}
+}
+
+// ReadKey returns the next key. An escape byte on its own is the Escape key, and one followed by another key is that
+// key with Alt. Escape sequences that ReadKey does not know are skipped.
+func (k *KeyReader) ReadKey() (Key, error) {
+	b, err := k.r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch {
+	case b == 0x1b && k.r.Buffered() == 0:
+		// terminals send a sequence in one write, so nothing following means the key itself
+		return Key{Code: KeyEscape}, nil
+	case b == 0x1b:
+		return k.readEscape()
+	case b < utf8.RuneSelf:
+		return controlKey(b), nil
+	}
+
+	_ = k.r.UnreadByte()
+	r, _, err := k.r.ReadRune()
+	return Key{Code: KeyRune, Rune: r}, err
+}
+
+func controlKey(b byte) Key {
+	switch b {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}
+	case '\t':
+		return Key{Code: KeyTab}
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}
+	case 0:
+		return Key{Code: KeyRune, Rune: ' ', Ctrl: true}
+	}
+	if b < 0x20 {
+		return Key{Code: KeyRune, Rune: unicode.ToLower(rune(b) + '@'), Ctrl: true}
+	}
+	return Key{Code: KeyRune, Rune: rune(b)}
+}
+
+func (k *KeyReader) readEscape() (Key, error) {
+	b, err := k.r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+	if (b == '[' || b == 'O') && k.r.Buffered() > 0 {
+		return k.readSequence()
+	}
+
+	_ = k.r.UnreadByte()
+	key, err := k.ReadKey()
+	key.Alt = true
+	return key, err
+}
+
+// readSequence decodes the rest of a sequence such as ESC [ A for up or ESC [ 1 ; 5 C for ctrl+right.
+func (k *KeyReader) readSequence() (Key, error) {
+	var params []byte
+	var final byte
+	for {
+		b, err := k.r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		if b >= 0x40 && b <= 0x7e {
+			final = b
+			break
+		}
+		params = append(params, b)
+	}
+	fields := strings.Split(string(params), ";")
+
+	var key Key
+	switch final {
+	case 'A':
+		key.Code = KeyUp
+	case 'B':
+		key.Code = KeyDown
+	case 'C':
+		key.Code = KeyRight
+	case 'D':
+		key.Code = KeyLeft
+	case 'H':
+		key.Code = KeyHome
+	case 'F':
+		key.Code = KeyEnd
+	case 'Z':
+		key.Code, key.Shift = KeyTab, true
+	case '~':
+		switch fields[0] {
+		case "1", "7":
+			key.Code = KeyHome
+		case "2":
+			key.Code = KeyInsert
+		case "3":
+			key.Code = KeyDelete
+		case "4", "8":
+			key.Code = KeyEnd
+		case "5":
+			key.Code = KeyPageUp
+		case "6":
+			key.Code = KeyPageDown
+		}
+	}
+	if key.Code == KeyRune {
+		return k.ReadKey()
+	}
+
+	if len(fields) > 1 {
+		// the modifier parameter is one more than a bit mask of shift, alt, and ctrl
+		if mod, err := strconv.Atoi(fields[1]); err == nil && mod > 1 {
+			mod--
+			key.Shift = key.Shift || mod&1 != 0
+			key.Alt = mod&2 != 0
+			key.Ctrl = mod&4 != 0
+		}
+	}
+	return key, nil
+}
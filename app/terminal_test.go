@@ -0,0 +1,42 @@
+package app_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestKeyReader_ReadKey(t *testing.T) {
+	keys := app.NewKeyReader(strings.NewReader(
+		"a\x03 \x00\x1b[A\x1b[1;5C\x1bOH\x1b[3~\x1b[5;3~\x1b[Z\x1b[?1u\x1bx\r\t\x7fé\x1b"))
+
+	var got []string
+	for {
+		key, err := keys.ReadKey()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, key.String())
+	}
+	assert.Equal(t, []string{
+		"a", "ctrl+c", "space", "ctrl+space", "up", "ctrl+right", "home", "delete", "alt+pgup", "shift+tab",
+		"alt+x", "enter", "tab", "backspace", "é", "esc",
+	}, got)
+}
+
+func TestApp_RawMode(t *testing.T) {
+	a := newApp(nil)
+	called := false
+	err := a.RawMode(func(*app.KeyReader) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, app.ErrNotTerminal)
+	assert.False(t, called)
+}
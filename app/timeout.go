@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExitTimeout is the status Exit uses once a command has run out of the time given by --timeout or APP_TIMEOUT. The
+// timeout command of coreutils reports the same status.
+const ExitTimeout = 124
+
+// ErrTimeout is returned by commands that ran out of the time given by --timeout or APP_TIMEOUT.
+var ErrTimeout = errors.New("timed out")
+
+// withTimeout calls fn with the CommandContext bounded by the --timeout flag or APP_TIMEOUT, if set. When fn fails
+// once the time is up, the error wraps ErrTimeout and Exit uses ExitTimeout. A command that ignores the Context is
+// stopped with Exit once it has overrun by the shutdown timeout as well.
+func (a *App) withTimeout(fn func() error) error {
+	d, err := a.lookupDuration("APP_TIMEOUT", 0)
+	if err != nil {
+		return err
+	}
+	if d == 0 {
+		return fn()
+	}
+
+	ctx, cancel := context.WithTimeout(a.CommandContext(), d)
+	defer cancel()
+
+	stuck := time.AfterFunc(d+a.shutdownTimeout(), func() {
+		_ = a.Logger().Errorf("the command did not stop %s after timing out", a.shutdownTimeout())
+		a.setFailureCode(ExitTimeout)
+		a.Exit(ExitTimeout)
+	})
+	defer stuck.Stop()
+
+	err = a.withCommandContext(ctx, fn)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		a.setFailureCode(ExitTimeout)
+		return fmt.Errorf("%w after %s: %w", ErrTimeout, d, err)
+	}
+	return err
+}
+
+// setFailureCode sets the code Exit uses in place of any failure code.
+func (a *App) setFailureCode(code int) {
+	a.exitMu.Lock()
+	defer a.exitMu.Unlock()
+	a.exitCode = code
+}
+
+func (a *App) failureCode() int {
+	a.exitMu.Lock()
+	defer a.exitMu.Unlock()
+	return a.exitCode
+}
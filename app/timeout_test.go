@@ -0,0 +1,51 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func newWaitApp(environ []string, args ...string) *app.App {
+	a := newApp(environ, args...)
+	a.AddCommand(app.NewCommand("wait", "", func(a *app.App, _ *struct{}, _ []string) error {
+		select {
+		case <-a.CommandContext().Done():
+			return a.CommandContext().Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}))
+	return a
+}
+
+func TestApp_Dispatch_timeout(t *testing.T) {
+	for _, a := range []*app.App{
+		newWaitApp(nil, "--timeout", "10ms", "wait"),
+		newWaitApp(nil, "wait", "--timeout=10ms"),
+		newWaitApp([]string{"APP_TIMEOUT=10ms"}, "wait"),
+	} {
+		ctx := a.CommandContext()
+		err := a.Dispatch()
+		assert.ErrorIs(t, err, app.ErrTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualError(t, err, "timed out after 10ms: context deadline exceeded")
+		assert.Equal(t, ctx, a.CommandContext(), "the CommandContext is restored")
+		assert.PanicsWithValue(t, "system exit 124", func() { a.Exit(1) })
+	}
+
+	a := newWaitApp(nil, "--timeout", "1m", "wait")
+	require.NoError(t, a.Dispatch())
+	v, _ := a.LookupEnv("APP_TIMEOUT")
+	assert.Equal(t, "1m", v)
+	assert.PanicsWithValue(t, "system exit 0", func() { a.Exit(0) })
+
+	assert.EqualError(t, newWaitApp(nil, "wait", "--timeout", "soon").Dispatch(), `invalid --timeout "soon"`)
+	assert.EqualError(t, newWaitApp(nil, "wait", "--timeout").Dispatch(), "flag --timeout needs a value")
+	assert.EqualError(t, newWaitApp([]string{"APP_TIMEOUT=soon"}, "wait").Dispatch(), `invalid APP_TIMEOUT "soon"`)
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/aphistic/gomol"
+	"golang.org/x/term"
+)
+
+// leaveScreen leaves the alternate screen and shows the cursor, which a full-screen program that did not return has
+// left behind.
+const leaveScreen = "\x1b[?1049l\x1b[?25h"
+
+// RunTUI runs a full-screen terminal program, such as a bubbletea Program, with the CommandContext, Stdin, and Stdout
+// of the app:
+//
+//	err := a.RunTUI(func(ctx context.Context, in io.Reader, out io.Writer) error {
+//		_, err := tea.NewProgram(model, tea.WithContext(ctx), tea.WithInput(in), tea.WithOutput(out)).Run()
+//		return err
+//	})
+//
+// The program owns the terminal while it runs. What the app Logger and ErrOutput write is held until it returns, so
+// that it does not corrupt the screen, and the standard log package, which bubbletea programs log with, writes to the
+// app Logger instead of Stderr. The terminal is restored as RunTUI found it when the program returns, when it panics,
+// and when the app Exits while it runs.
+func (a *App) RunTUI(run func(ctx context.Context, in io.Reader, out io.Writer) error) error {
+	_ = a.Flush()
+	release := a.holdErrOutput()
+	restoreTerminal := a.saveTerminal()
+
+	logOutput, logFlags, logPrefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(&logWriter{a: a})
+	log.SetFlags(0)
+	log.SetPrefix("")
+
+	var once sync.Once
+	cleanup := func(aborted bool) {
+		once.Do(func() {
+			restoreTerminal(aborted)
+			log.SetOutput(logOutput)
+			log.SetFlags(logFlags)
+			log.SetPrefix(logPrefix)
+			release()
+		})
+	}
+	remove := a.onExit(func(int) { cleanup(true) })
+	defer func() {
+		remove()
+		if r := recover(); r != nil {
+			cleanup(true)
+			panic(r)
+		}
+		cleanup(false)
+	}()
+
+	return run(a.CommandContext(), a.Stdin, a.Stdout)
+}
+
+// saveTerminal saves the modes of Stdin and Stdout, if they are terminals, returning the function that restores them.
+// When the program was aborted, the function also leaves the alternate screen and shows the cursor.
+func (a *App) saveTerminal() func(aborted bool) {
+	type saved struct {
+		fd    int
+		state *term.State
+	}
+	var states []saved
+	for _, v := range [...]interface{}{a.Stdin, a.Stdout} {
+		f, ok := v.(*os.File)
+		if !ok || !term.IsTerminal(int(f.Fd())) {
+			continue
+		}
+		if state, err := term.GetState(int(f.Fd())); err == nil {
+			states = append(states, saved{int(f.Fd()), state})
+		}
+	}
+
+	return func(aborted bool) {
+		for _, s := range states {
+			_ = term.Restore(s.fd, s.state)
+		}
+		if f, ok := a.Stdout.(*os.File); aborted && ok && term.IsTerminal(int(f.Fd())) {
+			_, _ = io.WriteString(f, leaveScreen)
+		}
+	}
+}
+
+// logWriter writes the lines of the standard log package to the app Logger.
+type logWriter struct {
+	a *App
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"source": "log"})
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		_ = w.a.Logger().Infom(attrs, "%s", line)
+	}
+	return len(p), nil
+}
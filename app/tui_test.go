@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_RunTUI(t *testing.T) {
+	a := newApp(nil)
+	stderr := a.Stderr.(*bytes.Buffer)
+	logOutput := log.Writer()
+
+	err := a.RunTUI(func(ctx context.Context, in io.Reader, out io.Writer) error {
+		assert.Equal(t, a.Context, ctx)
+		assert.Same(t, a.Stdin, in)
+		assert.Same(t, a.Stdout, out)
+
+		_, _ = fmt.Fprintln(out, "screen")
+		_, _ = fmt.Fprintln(a.ErrOutput(), "held")
+		log.Printf("from the program")
+		assert.Empty(t, stderr.String())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Zero(t, app.ExitHooks(a))
+	assert.Equal(t, logOutput, log.Writer())
+	assert.Equal(t, "screen\n", a.Stdout.(*bytes.Buffer).String())
+
+	a.Logger().ShutdownLoggers()
+	assert.Contains(t, stderr.String(), "held\n")
+	assert.Regexp(t, `INFO.*\] from the program {.*"source":"log"`, stderr.String())
+
+	a = newApp(nil)
+	assert.PanicsWithValue(t, "system exit 2", func() {
+		_ = a.RunTUI(func(context.Context, io.Reader, io.Writer) error {
+			_, _ = fmt.Fprintln(a.ErrOutput(), "held")
+			a.Exit(2)
+			return nil
+		})
+	})
+	assert.Equal(t, logOutput, log.Writer())
+	assert.Equal(t, "held\n", a.Stderr.(*bytes.Buffer).String())
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"sort"
+	"sync"
+)
+
+// ConfigUpdate reports variables that changed when the configuration was reloaded.
+type ConfigUpdate struct {
+	Source string   // the source passed to Reload, such as a remote store or a configuration directory
+	Keys   []string // the variables that were added, changed, or removed, sorted
+}
+
+// Has reports whether the variable key is among the changed ones.
+func (u ConfigUpdate) Has(key string) bool {
+	idx := sort.SearchStrings(u.Keys, key)
+	return idx < len(u.Keys) && u.Keys[idx] == key
+}
+
+type configSub struct {
+	keys map[string]bool
+	ch   chan ConfigUpdate
+}
+
+// ConfigUpdates subscribes to the changes Reload makes to the given variables, or to every variable if none are
+// given, so that long-lived components can apply new values without restarting the process:
+//
+//	updates, cancel := a.ConfigUpdates("APP_WORKERS")
+//	defer cancel()
+//	for range updates {
+//		v, _ := a.LookupEnv("APP_WORKERS")
+//		n, _ := strconv.Atoi(v)
+//		pool.Resize(n)
+//	}
+//
+// Read the new values with LookupEnv. Updates are never dropped: when the subscriber falls behind, the pending update
+// absorbs the next, and the Source is that of the latest. cancel ends the subscription and closes the channel.
+func (a *App) ConfigUpdates(keys ...string) (<-chan ConfigUpdate, func()) {
+	sub := &configSub{ch: make(chan ConfigUpdate, 1)}
+	if len(keys) > 0 {
+		sub.keys = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			sub.keys[key] = true
+		}
+	}
+
+	a.updatesMu.Lock()
+	if a.updates == nil {
+		a.updates = make(map[*configSub]struct{})
+	}
+	a.updates[sub] = struct{}{}
+	a.updatesMu.Unlock()
+
+	once := sync.Once{}
+	return sub.ch, func() {
+		once.Do(func() {
+			a.updatesMu.Lock()
+			delete(a.updates, sub)
+			a.updatesMu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// publishConfig sends the changed keys to the subscribers of ConfigUpdates interested in them.
+func (a *App) publishConfig(source string, changed []string) {
+	a.updatesMu.Lock()
+	defer a.updatesMu.Unlock()
+
+	for sub := range a.updates {
+		update := ConfigUpdate{Source: source}
+		for _, key := range changed {
+			if sub.keys == nil || sub.keys[key] {
+				update.Keys = append(update.Keys, key)
+			}
+		}
+		if len(update.Keys) == 0 {
+			continue
+		}
+
+		select {
+		case sub.ch <- update:
+			continue
+		default:
+		}
+
+		// the subscriber has yet to receive the last update; merge it into this one
+		select {
+		case pending := <-sub.ch:
+			update.Keys = mergeKeys(pending.Keys, update.Keys)
+		default:
+		}
+		sub.ch <- update
+	}
+}
+
+// mergeKeys returns the union of two sorted lists of keys, sorted.
+func mergeKeys(a, b []string) []string {
+	merged := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, key := range append(append([]string(nil), a...), b...) {
+		if !seen[key] {
+			seen[key] = true
+			merged = append(merged, key)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,42 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_ConfigUpdates(t *testing.T) {
+	a := newApp(nil)
+
+	all, cancelAll := a.ConfigUpdates()
+	workers, cancelWorkers := a.ConfigUpdates("APP_WORKERS")
+
+	a.Reload("etcd", map[string]string{"APP_WORKERS": "4", "APP_NAME": "x"})
+	assert.Equal(t, app.ConfigUpdate{Source: "etcd", Keys: []string{"APP_NAME", "APP_WORKERS"}}, <-all)
+	update := <-workers
+	assert.Equal(t, app.ConfigUpdate{Source: "etcd", Keys: []string{"APP_WORKERS"}}, update)
+	assert.True(t, update.Has("APP_WORKERS"))
+	assert.False(t, update.Has("APP_NAME"))
+
+	// unrelated changes are not sent, and pending updates absorb the next
+	a.Reload("etcd", map[string]string{"APP_WORKERS": "4", "APP_NAME": "y"})
+	a.Reload("etcd", map[string]string{"APP_WORKERS": "4", "APP_NAME": "y", "APP_DEBUG": "1"})
+	a.SetFlag("APP_WORKERS", "8")
+	assert.Equal(t, app.ConfigUpdate{Source: "flag", Keys: []string{"APP_DEBUG", "APP_NAME", "APP_WORKERS"}}, <-all)
+	assert.Equal(t, app.ConfigUpdate{Source: "flag", Keys: []string{"APP_WORKERS"}}, <-workers)
+	v, _ := a.LookupEnv("APP_WORKERS")
+	assert.Equal(t, "8", v)
+
+	a.SetFlag("APP_WORKERS", "8")
+	cancelWorkers()
+	cancelWorkers()
+	_, open := <-workers
+	assert.False(t, open)
+
+	a.Reload("etcd", nil)
+	assert.Equal(t, app.ConfigUpdate{Source: "etcd", Keys: []string{"APP_DEBUG", "APP_NAME", "APP_WORKERS"}}, <-all)
+	cancelAll()
+}
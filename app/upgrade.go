@@ -0,0 +1,80 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// UpgradeListenersEnv names the environment variable used to pass the specs of inherited listeners to a new
+	// process during an upgrade. The listener for the spec at index i is available as file descriptor 3+i.
+	UpgradeListenersEnv = "APP_UPGRADE_LISTENERS"
+
+	// UpgradeReadyEnv names the environment variable holding the file descriptor a new process writes to once it is
+	// ready to accept connections.
+	UpgradeReadyEnv = "APP_UPGRADE_READY_FD"
+
+	// UpgradeTimeout is how long Upgrade waits for the new process to become ready.
+	UpgradeTimeout = 30 * time.Second
+)
+
+// Upgraded reports whether this app was started by Upgrade in another process.
+func (a *App) Upgraded() bool {
+	_, ok := a.LookupEnv(UpgradeReadyEnv)
+	return ok
+}
+
+// NotifyReady tells the process that started this app with Upgrade that all listeners are open and the old process
+// may begin draining. NotifyReady is a no-op when the app was not started by Upgrade.
+func (a *App) NotifyReady() error {
+	v, ok := a.LookupEnv(UpgradeReadyEnv)
+	if !ok {
+		return nil
+	}
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %v", UpgradeReadyEnv, v, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "upgrade-ready")
+	if f == nil {
+		return fmt.Errorf("invalid %s %q", UpgradeReadyEnv, v)
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+func (a *App) inheritedListener(spec string) (net.Listener, error) {
+	a.listenersMu.Lock()
+	defer a.listenersMu.Unlock()
+
+	if a.inherited == nil {
+		a.inherited = make(map[string]int)
+
+		if v, ok := a.LookupEnv(UpgradeListenersEnv); ok {
+			var specs []string
+			if err := json.Unmarshal([]byte(v), &specs); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", UpgradeListenersEnv, err)
+			}
+
+			for idx, s := range specs {
+				a.inherited[s] = listenFDsStart + idx
+			}
+		}
+	}
+
+	fd, ok := a.inherited[spec]
+	if !ok {
+		return nil, nil
+	}
+	delete(a.inherited, spec)
+
+	return fileListener(fd, spec)
+}
@@ -0,0 +1,94 @@
+//go:build !windows
+// +build !windows
+
+package app_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const upgradeChildEnv = "APP_TEST_UPGRADE_CHILD"
+
+func TestApp_Upgrade(t *testing.T) {
+	if spec, ok := os.LookupEnv(upgradeChildEnv); ok {
+		upgradeChild(t, spec)
+		return
+	}
+
+	spec := "tcp://127.0.0.1:0"
+	a := newApp(append(os.Environ(), upgradeChildEnv+"="+spec), "-test.run=^TestApp_Upgrade$")
+
+	l, err := a.Listen(spec)
+	require.NoError(t, err)
+	addr := l.Addr().String()
+
+	require.NoError(t, a.Upgrade())
+
+	assert.PanicsWithValue(t, "system exit 0", func() {
+		a.Exit(0)
+	})
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from "+addr+"\n", line)
+}
+
+func upgradeChild(t *testing.T, spec string) {
+	a := app.New()
+	require.True(t, a.Upgraded())
+
+	l, err := a.Listen(spec)
+	require.NoError(t, err)
+	require.NoError(t, a.NotifyReady())
+
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("hello from " + l.Addr().String() + "\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+}
+
+func TestApp_NotifyReady(t *testing.T) {
+	a := newApp(nil)
+	assert.False(t, a.Upgraded())
+	assert.NoError(t, a.NotifyReady())
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+
+	// NotifyReady closes the descriptor, so hand it a copy
+	fd, err := syscall.Dup(int(w.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	a = newApp([]string{app.UpgradeReadyEnv + "=" + strconv.Itoa(fd)})
+	assert.True(t, a.Upgraded())
+	assert.NoError(t, a.NotifyReady())
+
+	buf := make([]byte, 2)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	a = newApp([]string{app.UpgradeReadyEnv + "=stdout"})
+	assert.Error(t, a.NotifyReady())
+
+	a = newApp([]string{app.UpgradeListenersEnv + "=tcp://:80"})
+	_, err = a.Listen("tcp://127.0.0.1:0")
+	assert.Error(t, err)
+}
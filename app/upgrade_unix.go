@@ -0,0 +1,161 @@
+//go:build !windows
+// +build !windows
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Upgrade starts a new instance of the running executable with the same arguments and environment, hands it every
+// listener opened with Listen, and waits for it to call NotifyReady. When Upgrade returns successfully, both
+// processes are accepting connections on the shared listeners and this process may drain and exit.
+func (a *App) Upgrade() error {
+	a.upgradeMu.Lock()
+	defer a.upgradeMu.Unlock()
+
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	a.listenersMu.Lock()
+	specs := make([]string, 0, len(a.listeners))
+	files := make([]*os.File, 0, len(a.listeners)+1)
+	for _, t := range a.listeners {
+		l, ok := t.listener.(filer)
+		if !ok {
+			continue
+		}
+
+		f, err := l.File()
+		if err != nil {
+			a.listenersMu.Unlock()
+			closeFiles(files)
+			return fmt.Errorf("unable to hand over %s: %v", t.spec, err)
+		}
+
+		specs = append(specs, t.spec)
+		files = append(files, f)
+	}
+	a.listenersMu.Unlock()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		closeFiles(files)
+		return err
+	}
+	defer r.Close()
+
+	// json.Marshal cannot fail for a slice of strings
+	encoded, _ := json.Marshal(specs)
+
+	env := make([]string, 0, len(a.Environment)+2)
+	for _, line := range a.Environment {
+		if !strings.HasPrefix(line, UpgradeListenersEnv+"=") && !strings.HasPrefix(line, UpgradeReadyEnv+"=") {
+			env = append(env, line)
+		}
+	}
+	env = append(
+		env,
+		UpgradeListenersEnv+"="+string(encoded),
+		fmt.Sprintf("%s=%d", UpgradeReadyEnv, listenFDsStart+len(files)),
+	)
+
+	cmd := exec.Command(path, a.Arguments...)
+	cmd.Env = env
+	cmd.Stdin = a.Stdin
+	cmd.Stdout = a.Stdout
+	cmd.Stderr = a.Stderr
+	cmd.ExtraFiles = append(files, w)
+
+	err = cmd.Start()
+	// the child has its own copies now; closing ours lets the read below see EOF if the child dies
+	closeFiles(cmd.ExtraFiles)
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		ready <- err
+	}()
+
+	timer := time.NewTimer(UpgradeTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-ready:
+		if err != nil {
+			_ = cmd.Wait()
+			return errors.New("new process exited before becoming ready")
+		}
+	case <-timer.C:
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.New("timed out waiting for new process to become ready")
+	}
+
+	// reap the child if it exits before we do
+	go func() { _ = cmd.Wait() }()
+
+	a.listenersMu.Lock()
+	for _, t := range a.listeners {
+		if l, ok := t.listener.(*net.UnixListener); ok {
+			// the socket file now belongs to the new process
+			l.SetUnlinkOnClose(false)
+		}
+	}
+	a.listenersMu.Unlock()
+
+	return nil
+}
+
+// HandleUpgrades calls Upgrade whenever the process receives SIGUSR2 until the app Context is done. After a
+// successful upgrade, the listeners opened with Listen are closed so that only the new process accepts connections,
+// and the app is stopped with Stop, so that Run drains in-flight work and returns nil.
+func (a *App) HandleUpgrades() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-a.Context.Done():
+				return
+			case <-ch:
+				if err := a.Upgrade(); err != nil {
+					_ = a.Logger().Errorf("upgrade failed: %v", err)
+					continue
+				}
+
+				_ = a.Logger().Info("upgrade complete, draining")
+				a.closeListeners()
+				a.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
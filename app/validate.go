@@ -0,0 +1,262 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validator is implemented by configuration structs that check themselves, beyond what validate tags express.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a configuration value that failed validation.
+type FieldError struct {
+	Module string // the module the configuration belongs to
+	Field  string // the struct field, with the path of nested structs, or empty for the whole struct
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	switch {
+	case e.Module == "" && e.Field == "":
+		return e.Err.Error()
+	case e.Module == "":
+		return e.Field + ": " + e.Err.Error()
+	case e.Field == "" && strings.HasPrefix(e.Err.Error(), e.Module+": "):
+		// errors of the FromEnv functions already name their package
+		return e.Err.Error()
+	case e.Field == "":
+		return e.Module + ": " + e.Err.Error()
+	default:
+		return e.Module + ": " + e.Field + ": " + e.Err.Error()
+	}
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigErrors are the configuration errors of every module, reported together so that they can be fixed at once.
+type ConfigErrors []*FieldError
+
+func (e ConfigErrors) Error() string {
+	if len(e) == 1 {
+		return "invalid configuration: " + e[0].Error()
+	}
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "invalid configuration, %d errors:", len(e))
+	for _, err := range e {
+		sb.WriteString("\n\t" + err.Error())
+	}
+	return sb.String()
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for idx, err := range e {
+		errs[idx] = err
+	}
+	return errs
+}
+
+// ValidateConfig records the errors in the configuration a module loaded, so that Run can report the errors of
+// every module together and refuse to start. The err is that of loading the configuration, such as the error
+// returned by a FromEnv function; the config is validated only when err is nil:
+//
+//	cacheConfig, err := cache.FromEnv(a.LookupEnv, "")
+//	a.ValidateConfig("cache", cacheConfig, err)
+//	lockConfig, err := lock.FromEnv(a.LookupEnv, "")
+//	a.ValidateConfig("lock", lockConfig, err)
+//	return a.Run()
+//
+// See Validate for how the config is validated. ValidateConfig returns the errors it recorded, if any.
+func (a *App) ValidateConfig(module string, config interface{}, err error) error {
+	if err == nil {
+		err = Validate(config)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		errs = ConfigErrors{{Err: err}}
+	}
+	recorded := make(ConfigErrors, len(errs))
+	for idx, e := range errs {
+		recorded[idx] = &FieldError{Module: module, Field: e.Field, Err: e.Err}
+	}
+
+	a.schemaMu.Lock()
+	a.configErrs = append(a.configErrs, recorded...)
+	a.schemaMu.Unlock()
+	return recorded
+}
+
+// ConfigErrors returns the errors recorded by ValidateConfig, those of expanding the variables passed to ExpandVars,
+// and those of decrypting the variables LookupEnv was asked for, as ConfigErrors, or nil if there are none.
+func (a *App) ConfigErrors() error {
+	expandErrs := a.expandErrors()
+	decryptErrs := a.decryptErrors()
+
+	a.schemaMu.Lock()
+	defer a.schemaMu.Unlock()
+	if len(a.configErrs) == 0 && len(expandErrs) == 0 && len(decryptErrs) == 0 {
+		return nil
+	}
+	return append(append(append(ConfigErrors(nil), a.configErrs...), expandErrs...), decryptErrs...)
+}
+
+// Validate checks the validate tags of the fields of a struct, or pointer to one, then calls its Validate method if
+// it is a Validator. Nested structs are validated in turn. The tags are comma separated rules:
+//
+//	required      the value is not the zero value
+//	min=N, max=N  bounds on numbers and durations, or on the length of strings, slices, and maps
+//	oneof=a b c   the value is one of the space separated values
+//	url           the value is an absolute URL
+//
+// The oneof and url rules are skipped for zero values, whose presence only required checks; min and max apply to every
+// value, so that min=1 refuses 0. The first rule each field fails is returned, and every other failure, as
+// ConfigErrors.
+func Validate(config interface{}) error {
+	var errs ConfigErrors
+	validateValue(reflect.ValueOf(config), "", make(map[uintptr]bool), &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateValue(v reflect.Value, path string, seen map[uintptr]bool, errs *ConfigErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Pointer {
+			// structs may point at each other
+			if seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if path != "" {
+			name = path + "." + name
+		}
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				if err := checkRule(v.Field(idx), strings.TrimSpace(rule)); err != nil {
+					*errs = append(*errs, &FieldError{Field: name, Err: err})
+					break
+				}
+			}
+		}
+
+		validateValue(v.Field(idx), name, seen, errs)
+	}
+
+	validator, ok := v.Interface().(Validator)
+	if !ok && v.CanAddr() {
+		validator, ok = v.Addr().Interface().(Validator)
+	}
+	if ok {
+		if err := validator.Validate(); err != nil {
+			*errs = append(*errs, &FieldError{Field: path, Err: err})
+		}
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func checkRule(v reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "":
+		return nil
+	case "required":
+		if v.IsZero() {
+			return errors.New("is required")
+		}
+		return nil
+	case "min", "max":
+	case "oneof", "url":
+		if v.IsZero() {
+			return nil
+		}
+	default:
+		panic(fmt.Sprintf("app: unknown validate rule %q", rule))
+	}
+
+	switch name {
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(arg) {
+			if s == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(strings.Fields(arg), ", "))
+	case "url":
+		u, err := url.Parse(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("must be an absolute URL")
+		}
+		return nil
+	}
+
+	// min and max
+	var n, bound float64
+	var err error
+	unit := ""
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		n, bound = float64(v.Int()), float64(d)
+	case v.CanInt():
+		n = float64(v.Int())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.CanUint():
+		n = float64(v.Uint())
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.CanFloat():
+		n = v.Float()
+		bound, err = strconv.ParseFloat(arg, 64)
+	case v.Kind() == reflect.String, v.Kind() == reflect.Slice, v.Kind() == reflect.Map, v.Kind() == reflect.Array:
+		n = float64(v.Len())
+		bound, err = strconv.ParseFloat(arg, 64)
+		unit = " in length"
+	default:
+		panic(fmt.Sprintf("app: validate rule %q does not apply to %s", rule, v.Type()))
+	}
+	if err != nil {
+		panic(fmt.Sprintf("app: invalid validate rule %q", rule))
+	}
+
+	switch {
+	case name == "min" && n < bound:
+		return fmt.Errorf("must be at least %s%s", arg, unit)
+	case name == "max" && n > bound:
+		return fmt.Errorf("must be at most %s%s", arg, unit)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package app_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type poolConfig struct {
+	Size    int           `validate:"min=1,max=100"`
+	Timeout time.Duration `validate:"required,min=10ms"`
+}
+
+type dbConfig struct {
+	URL     string   `validate:"required,url"`
+	Mode    string   `validate:"oneof=read write"`
+	Hosts   []string `validate:"max=2"`
+	Pool    poolConfig
+	Replica *dbConfig
+}
+
+func (c *dbConfig) Validate() error {
+	if c.Replica == c {
+		return errors.New("the database cannot be its own replica")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	valid := &dbConfig{URL: "postgres://db/app", Pool: poolConfig{Size: 4, Timeout: time.Second}}
+	assert.NoError(t, app.Validate(valid))
+	assert.NoError(t, app.Validate(*valid))
+
+	invalid := &dbConfig{
+		URL:   "db:5432",
+		Mode:  "delete",
+		Hosts: []string{"a", "b", "c"},
+		Pool:  poolConfig{Size: 200, Timeout: time.Millisecond},
+	}
+	invalid.Replica = invalid
+	err := app.Validate(invalid)
+	var errs app.ConfigErrors
+	require.ErrorAs(t, err, &errs)
+	assert.EqualError(t, err, `invalid configuration, 6 errors:
+	URL: must be an absolute URL
+	Mode: must be one of read, write
+	Hosts: must be at most 2 in length
+	Pool.Size: must be at most 100
+	Pool.Timeout: must be at least 10ms
+	the database cannot be its own replica`)
+
+	// min applies to zero values too, but a field is only reported once
+	assert.EqualError(t, app.Validate(&dbConfig{}), `invalid configuration, 3 errors:
+	URL: is required
+	Pool.Size: must be at least 1
+	Pool.Timeout: is required`)
+
+	assert.PanicsWithValue(t, `app: unknown validate rule "positive"`, func() {
+		_ = app.Validate(&struct {
+			N int `validate:"positive"`
+		}{N: 1})
+	})
+}
+
+func TestApp_ValidateConfig(t *testing.T) {
+	a := newApp(nil)
+	assert.NoError(t, a.ValidateConfig("db", &poolConfig{Size: 1, Timeout: time.Second}, nil))
+	assert.NoError(t, a.ConfigErrors())
+
+	loadErr := errors.New(`cache: invalid APP_CACHE_TTL "soon"`)
+	assert.Equal(t, loadErr, errors.Unwrap(a.ValidateConfig("cache", nil, loadErr).(app.ConfigErrors)[0]))
+	assert.Error(t, a.ValidateConfig("db", &poolConfig{Timeout: time.Second, Size: -1}, nil))
+
+	r := new(recorder)
+	a.Register("public", newFakeServer("public", r))
+	err := a.Run()
+	assert.EqualError(t, err, `invalid configuration, 2 errors:
+	cache: invalid APP_CACHE_TTL "soon"
+	db: Size: must be at least 1`)
+	assert.ErrorIs(t, err, loadErr)
+	assert.Empty(t, r.Events(), "no server is bound")
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aphistic/gomol"
+)
+
+// Verbosity is how much the app logs and prints, chosen with -q, -v, -vv, or --debug, or with APP_VERBOSITY.
+type Verbosity int
+
+const (
+	// VerbosityQuiet logs only warnings and errors, and discards the non-essential output written to InfoOutput.
+	VerbosityQuiet Verbosity = iota - 1
+	// VerbosityNormal logs informational messages; it is the default.
+	VerbosityNormal
+	// VerbosityVerbose logs debug messages too.
+	VerbosityVerbose
+	// VerbosityDebug logs everything, and tells commands to print whatever helps debugging.
+	VerbosityDebug
+)
+
+var verbosityNames = [...]string{"quiet", "normal", "verbose", "debug"}
+
+func (v Verbosity) String() string {
+	if v < VerbosityQuiet || v > VerbosityDebug {
+		return strconv.Itoa(int(v))
+	}
+	return verbosityNames[v-VerbosityQuiet]
+}
+
+func (v Verbosity) logLevel() gomol.LogLevel {
+	switch {
+	case v <= VerbosityQuiet:
+		return gomol.LevelWarning
+	case v == VerbosityNormal:
+		return gomol.LevelInfo
+	default:
+		return gomol.LevelDebug
+	}
+}
+
+func parseVerbosity(s string) (Verbosity, bool) {
+	for idx, name := range verbosityNames {
+		if strings.EqualFold(s, name) {
+			return Verbosity(idx) + VerbosityQuiet, true
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < int(VerbosityQuiet) || n > int(VerbosityDebug) {
+		return VerbosityNormal, false
+	}
+	return Verbosity(n), true
+}
+
+// Verbosity returns the verbosity chosen with SetVerbosity or the -q, -v, -vv, and --debug flags, or else with
+// APP_VERBOSITY, as quiet, normal, verbose, or debug.
+func (a *App) Verbosity() Verbosity {
+	a.verbosityMu.Lock()
+	v, ok := a.verbosity, a.verbositySet
+	a.verbosityMu.Unlock()
+	if ok {
+		return v
+	}
+
+	if s, ok := a.LookupEnv("APP_VERBOSITY"); ok {
+		v, _ = parseVerbosity(s)
+	}
+	return v
+}
+
+// SetVerbosity sets the verbosity of the app, which sets the level of the app Logger.
+func (a *App) SetVerbosity(v Verbosity) {
+	a.restoreVerbosity(v, true)
+}
+
+// verbosityState returns the verbosity set with SetVerbosity, and whether it was set, for restoreVerbosity.
+func (a *App) verbosityState() (Verbosity, bool) {
+	a.verbosityMu.Lock()
+	defer a.verbosityMu.Unlock()
+	return a.verbosity, a.verbositySet
+}
+
+// restoreVerbosity sets the verbosity as verbosityState returned it, and the level of the app Logger to match.
+func (a *App) restoreVerbosity(v Verbosity, set bool) {
+	a.verbosityMu.Lock()
+	a.verbosity, a.verbositySet = v, set
+	a.verbosityMu.Unlock()
+
+	level := a.Verbosity().logLevel()
+	a.loggerMu.Lock()
+	defer a.loggerMu.Unlock()
+	if a.logger != nil {
+		a.logger.SetLogLevel(level)
+	}
+}
+
+// InfoOutput returns the writer for the output of a command that is not essential, such as progress and
+// confirmations: Output, unless the verbosity is quiet, when the output is discarded. The results of a command are
+// written to Output, so that they are printed however quiet the app is.
+func (a *App) InfoOutput() io.Writer {
+	if a.Verbosity() <= VerbosityQuiet {
+		return io.Discard
+	}
+	return a.Output()
+}
+
+// verbosityFlag applies the verbosity flag arg, reporting whether arg is one.
+func (a *App) verbosityFlag(arg string) bool {
+	switch arg {
+	case "-q", "--quiet":
+		a.SetVerbosity(VerbosityQuiet)
+	case "-vv", "--debug":
+		a.SetVerbosity(VerbosityDebug)
+	case "-v", "--verbose":
+		if v, set := a.verbosityState(); set && v >= VerbosityVerbose {
+			a.SetVerbosity(VerbosityDebug)
+		} else {
+			a.SetVerbosity(VerbosityVerbose)
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package app_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+func TestApp_Verbosity(t *testing.T) {
+	assert.Equal(t, app.VerbosityNormal, newApp(nil).Verbosity())
+	assert.Equal(t, app.VerbosityDebug, newApp([]string{"APP_VERBOSITY=debug"}).Verbosity())
+	assert.Equal(t, app.VerbosityQuiet, newApp([]string{"APP_VERBOSITY=-1"}).Verbosity())
+	assert.Equal(t, app.VerbosityNormal, newApp([]string{"APP_VERBOSITY=loud"}).Verbosity())
+	assert.Equal(t, "verbose", app.VerbosityVerbose.String())
+
+	for expected, args := range map[app.Verbosity][]string{
+		app.VerbosityQuiet:   {"-q", "run"},
+		app.VerbosityVerbose: {"run", "--verbose"},
+		app.VerbosityDebug:   {"-v", "run", "-v"},
+	} {
+		a := newApp(nil, args...)
+		var seen app.Verbosity
+		a.AddCommand(app.NewCommand("run", "", func(a *app.App, _ *struct{}, args []string) error {
+			seen = a.Verbosity()
+			assert.Empty(t, args)
+			return nil
+		}))
+		require.NoError(t, a.Dispatch())
+		assert.Equal(t, expected, seen, args)
+	}
+}
+
+func TestApp_InfoOutput(t *testing.T) {
+	for _, verbosity := range []app.Verbosity{app.VerbosityQuiet, app.VerbosityNormal, app.VerbosityVerbose} {
+		a := newApp(nil)
+		a.SetVerbosity(verbosity)
+		l := a.Logger()
+		_, _ = fmt.Fprintln(a.InfoOutput(), "progress")
+		_, _ = fmt.Fprintln(a.Output(), "result")
+		_ = l.Debug("debug")
+		_ = l.Info("info")
+		_ = l.Warn("warn")
+		l.ShutdownLoggers()
+
+		stdout, stderr := a.Stdout.(*bytes.Buffer).String(), a.Stderr.(*bytes.Buffer).String()
+		assert.Contains(t, stdout, "result")
+		assert.Equal(t, verbosity != app.VerbosityQuiet, strings.Contains(stdout, "progress"))
+		assert.Contains(t, stderr, "warn")
+		assert.Equal(t, verbosity != app.VerbosityQuiet, strings.Contains(stderr, "info"))
+		assert.Equal(t, verbosity == app.VerbosityVerbose, strings.Contains(stderr, "debug"))
+	}
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+const (
+	// DefaultWaitTimeout is how long WaitFor waits for its targets when APP_WAIT_TIMEOUT is not set.
+	DefaultWaitTimeout = time.Minute
+
+	// waitBackoff is the wait before the second probe of a target, doubled for every probe up to waitMaxBackoff.
+	waitBackoff    = 100 * time.Millisecond
+	waitMaxBackoff = 5 * time.Second
+)
+
+// waitPorts are the default ports of the database and broker schemes WaitFor accepts.
+var waitPorts = map[string]string{
+	"amqp":       "5672",
+	"amqps":      "5671",
+	"memcached":  "11211",
+	"mongodb":    "27017",
+	"mysql":      "3306",
+	"nats":       "4222",
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"redis":      "6379",
+	"rediss":     "6379",
+}
+
+// FreePort returns a TCP port on the loopback interface that is free to listen on, for tests and for child
+// processes that are told which port to use. Another process may take the port before it is used.
+func (a *App) FreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// WaitFor waits until every target is ready, probing them together with a backoff, as wait-for-it.sh does in
+// container entrypoints. Without targets, it waits for those listed in APP_WAIT_FOR, comma separated. A target is:
+//
+//	host:port, tcp://host:port           ready once a TCP connection is accepted
+//	unix:///path/app.sock                ready once a connection to the socket is accepted
+//	http://host/path, https://host/path  ready once a GET is answered with a status below 400
+//	postgres://host, redis://host, ...   ready once a TCP connection is accepted on the port of the URL, or the
+//	                                     default port of the scheme: amqp, memcached, mongodb, mysql, nats,
+//	                                     postgres, or redis
+//
+// WaitFor gives up when ctx is done or APP_WAIT_TIMEOUT, 1m by default, elapses, returning an error with the last
+// failure of a target that is not ready. The first failure and the readiness of each target are logged.
+func (a *App) WaitFor(ctx context.Context, targets ...string) error {
+	if len(targets) == 0 {
+		for _, t := range strings.Split(a.trimmedEnv("APP_WAIT_FOR"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	timeout, err := a.lookupDuration("APP_WAIT_TIMEOUT", DefaultWaitTimeout)
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	probes := make([]func(context.Context) error, len(targets))
+	for i, t := range targets {
+		if probes[i], err = waitProbe(t); err != nil {
+			return err
+		}
+	}
+
+	errs := make(chan error, len(targets))
+	for i, t := range targets {
+		go func(target string, probe func(context.Context) error) {
+			errs <- a.waitFor(ctx, target, probe)
+		}(t, probes[i])
+	}
+
+	var failed []error
+	for range targets {
+		if err := <-errs; err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// waitFor probes target until it is ready or ctx is done.
+func (a *App) waitFor(ctx context.Context, target string, probe func(context.Context) error) error {
+	started := a.clock().Now()
+	backoff := waitBackoff
+	for attempt := 1; ; attempt++ {
+		err := probe(ctx)
+		if err == nil {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+				"target":   target,
+				"attempts": attempt,
+				"elapsed":  a.clock().Since(started).String(),
+			})
+			_ = a.Logger().Infom(attrs, "%s is ready", target)
+			return nil
+		}
+
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+			"target":  target,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+		if attempt == 1 {
+			_ = a.Logger().Infom(attrs, "waiting for %s", target)
+		} else {
+			_ = a.Logger().Debugm(attrs, "%s is not ready, retrying in %s", target, backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s: %v", target, err)
+		case <-a.clock().After(backoff):
+		}
+		if backoff *= 2; backoff > waitMaxBackoff {
+			backoff = waitMaxBackoff
+		}
+	}
+}
+
+// waitProbe returns the function probing target once.
+func waitProbe(target string) (func(context.Context) error, error) {
+	if !strings.Contains(target, "://") {
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return nil, fmt.Errorf("invalid wait target %q: %v", target, err)
+		}
+		return dialProbe("tcp", target), nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wait target %q: %v", target, err)
+	}
+
+	switch scheme := strings.ToLower(u.Scheme); {
+	case scheme == "tcp", scheme == "tcp4", scheme == "tcp6":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("invalid wait target %q: missing port", target)
+		}
+		return dialProbe(scheme, u.Host), nil
+	case scheme == "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		return dialProbe("unix", path), nil
+	case scheme == "http", scheme == "https":
+		return httpProbe(target), nil
+	case waitPorts[scheme] != "":
+		host := u.Hostname()
+		if host == "" {
+			host = "localhost"
+		}
+		port := u.Port()
+		if port == "" {
+			port = waitPorts[scheme]
+		}
+		return dialProbe("tcp", net.JoinHostPort(host, port)), nil
+	default:
+		return nil, fmt.Errorf("invalid wait target %q: unsupported scheme %q", target, u.Scheme)
+	}
+}
+
+func dialProbe(network, address string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		ctx, cancel := context.WithTimeout(ctx, waitMaxBackoff)
+		defer cancel()
+
+		conn, err := d.DialContext(ctx, network, address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func httpProbe(target string) func(context.Context) error {
+	client := &http.Client{Timeout: waitMaxBackoff}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		if res.StatusCode >= 400 {
+			return fmt.Errorf("%s answered %s", target, res.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package app_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_FreePort(t *testing.T) {
+	a := newApp(nil)
+	port, err := a.FreePort()
+	require.NoError(t, err)
+	assert.Positive(t, port)
+
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	require.NoError(t, err)
+	_ = l.Close()
+}
+
+func TestApp_WaitFor(t *testing.T) {
+	a := newApp(nil)
+	port, err := a.FreePort()
+	require.NoError(t, err)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	// the TCP target starts listening after a while
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err == nil {
+			listening <- l
+		}
+		close(listening)
+	}()
+	defer func() {
+		if l, ok := <-listening; ok {
+			_ = l.Close()
+		}
+	}()
+
+	require.NoError(t, a.WaitFor(context.Background(), addr, srv.URL+"/healthz"))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&calls), int32(3))
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	logs := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, logs, "waiting for "+addr)
+	assert.Contains(t, logs, addr+" is ready")
+	assert.Contains(t, logs, srv.URL+"/healthz is ready")
+}
+
+func TestApp_WaitFor_Env(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	a := newApp([]string{"APP_WAIT_FOR=" + l.Addr().String() + ", postgres://127.0.0.1:" + portOf(l)})
+	assert.NoError(t, a.WaitFor(context.Background()))
+	assert.NoError(t, newApp(nil).WaitFor(context.Background()))
+}
+
+func TestApp_WaitFor_Timeout(t *testing.T) {
+	a := newApp([]string{"APP_WAIT_TIMEOUT=150ms"})
+	port, err := a.FreePort()
+	require.NoError(t, err)
+
+	err = a.WaitFor(context.Background(), fmt.Sprintf("tcp://127.0.0.1:%d", port))
+	assert.ErrorContains(t, err, fmt.Sprintf("timed out waiting for tcp://127.0.0.1:%d", port))
+}
+
+func TestApp_WaitFor_Invalid(t *testing.T) {
+	for msg, target := range map[string]string{
+		`invalid wait target "localhost": address localhost: missing port in address`: "localhost",
+		`invalid wait target "tcp://localhost": missing port`:                         "tcp://localhost",
+		`invalid wait target "ftp://localhost": unsupported scheme "ftp"`:             "ftp://localhost",
+	} {
+		assert.EqualError(t, newApp(nil).WaitFor(context.Background(), target), msg)
+	}
+
+	a := newApp([]string{"APP_WAIT_TIMEOUT=soon"})
+	assert.EqualError(t, a.WaitFor(context.Background(), "localhost:80"), `invalid APP_WAIT_TIMEOUT "soon"`)
+}
+
+func portOf(l net.Listener) string {
+	return fmt.Sprint(l.Addr().(*net.TCPAddr).Port)
+}
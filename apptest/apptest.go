@@ -0,0 +1,81 @@
+// Package apptest helps test the commands of apps: New returns an App writing to buffers, and AssertOutput compares
+// what a command wrote against golden files kept in testdata:
+//
+//	func TestList(t *testing.T) {
+//		a := apptest.New(t, []string{"APP_ENV=test"}, "list", "--all")
+//		require.NoError(t, run(a))
+//		apptest.AssertOutput(t, a)
+//	}
+//
+// The output is compared with testdata/TestList.stdout.golden and testdata/TestList.stderr.golden once normalized:
+// the ANSI escape sequences are removed, and the timestamps replaced with <TIME>. Run the tests with -apptest.update,
+// as in go test ./... -args -apptest.update, to write the golden files from the output instead.
+//
+// Cassette records the HTTP requests of a test, and replays them on the next runs, see the vcr package.
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// New returns an App for a test: running args, with the variables of environ, an empty Stdin, Stdout and Stderr
+// writing to buffers, see Stdout and Stderr, and an ExitHandler panicking with "system exit <code>". Its Context is
+// canceled once the test ends.
+func New(tb testing.TB, environ []string, args ...string) *app.App {
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+
+	return &app.App{
+		Name:        "app",
+		Arguments:   args,
+		Environment: environ,
+		Context:     ctx,
+		Container:   &app.Container{},
+		Stdin:       new(bytes.Buffer),
+		Stdout:      new(bytes.Buffer),
+		Stderr:      new(bytes.Buffer),
+		ExitHandler: func(code int) {
+			panic(fmt.Sprintf("system exit %d", code))
+		},
+	}
+}
+
+// Lookup returns a function looking the variables up in env, for the tests of code reading them with App.LookupEnv,
+// such as the FromEnv functions of the framework packages.
+func Lookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+// Stdout returns what a, made with New, wrote to Stdout so far, flushing its Output first.
+func Stdout(tb testing.TB, a *app.App) string {
+	tb.Helper()
+	return buffered(tb, a, a.Stdout, "Stdout")
+}
+
+// Stderr returns what a, made with New, wrote to Stderr so far, flushing its ErrOutput first. The log messages are
+// only all written once the loggers are shut down.
+func Stderr(tb testing.TB, a *app.App) string {
+	tb.Helper()
+	return buffered(tb, a, a.Stderr, "Stderr")
+}
+
+func buffered(tb testing.TB, a *app.App, w interface{}, name string) string {
+	tb.Helper()
+
+	if err := a.Flush(); err != nil {
+		tb.Fatalf("apptest: %v", err)
+	}
+	buf, ok := w.(*bytes.Buffer)
+	if !ok {
+		tb.Fatalf("apptest: the %s of the app is a %T, not a *bytes.Buffer", name, w)
+	}
+	return buf.String()
+}
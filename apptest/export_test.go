@@ -0,0 +1,15 @@
+package apptest
+
+// SetUpdate sets the -apptest.update flag, as if the tests ran with it, returning a function restoring it.
+func SetUpdate(on bool) func() {
+	old := *update
+	*update = on
+	return func() { *update = old }
+}
+
+// SetVCRMode sets the -apptest.vcr flag, as if the tests ran with it, returning a function restoring it.
+func SetVCRMode(mode string) func() {
+	old := *vcrMode
+	*vcrMode = mode
+	return func() { *vcrMode = old }
+}
@@ -0,0 +1,114 @@
+package apptest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+var update = flag.Bool("apptest.update", false, "write the golden files of apptest from the output of the tests")
+
+// diffContext is how many unchanged lines are shown around the changes of a diff.
+const diffContext = 3
+
+var (
+	// ansiPattern matches the CSI and OSC escape sequences.
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+	// timePattern matches the timestamps of RFC 3339, of the logs, and of time.Time.String.
+	timePattern = regexp.MustCompile(
+		`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2}| [+-]\d{4}(?: [A-Z]{3,5})?)?`)
+)
+
+// Normalize returns s without ANSI escape sequences, with the timestamps replaced with <TIME>, and with Unix line
+// endings.
+func Normalize(s string) string {
+	s = ansiPattern.ReplaceAllString(s, "")
+	s = timePattern.ReplaceAllString(s, "<TIME>")
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+// AssertOutput compares the Stdout and Stderr of a, made with New, with the golden files named after the test,
+// testdata/<test>.stdout.golden and testdata/<test>.stderr.golden, see Golden.
+func AssertOutput(tb testing.TB, a *app.App) {
+	tb.Helper()
+
+	Golden(tb, tb.Name()+".stdout", Stdout(tb, a))
+	Golden(tb, tb.Name()+".stderr", Stderr(tb, a))
+}
+
+// Golden compares got with the golden file testdata/<name>.golden, both normalized, failing the test with a diff if
+// they differ. A missing golden file stands for an empty output. With -apptest.update, the golden file is written
+// instead, or removed if got is empty.
+func Golden(tb testing.TB, name, got string) {
+	tb.Helper()
+
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+	got = Normalize(got)
+	if *update {
+		if err := writeGolden(path, got); err != nil {
+			tb.Fatalf("apptest: %v", err)
+		}
+		return
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		tb.Fatalf("apptest: %v", err)
+	}
+	if want := Normalize(string(b)); want != got {
+		tb.Errorf("apptest: the output differs from %s, run the test with -apptest.update to replace it:\n%s",
+			path, Diff(want, got))
+	}
+}
+
+func writeGolden(path, got string) error {
+	if got == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(got), 0o644)
+}
+
+// Diff returns a unified diff from want to got, with diffContext unchanged lines around the changes, or the empty
+// string if they are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(want),
+		B:        splitLines(got),
+		FromFile: "want",
+		ToFile:   "got",
+		Context:  diffContext,
+	})
+	return diff
+}
+
+// splitLines returns the lines of s with their newlines, marking the last one if it does not end with a newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += " (no newline at end)\n"
+	return lines
+}
@@ -0,0 +1,105 @@
+package apptest_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+)
+
+// recordingTB records the failures of a test rather than failing it.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"\x1b[1;32mok\x1b[0m\r\n":                           "ok\n",
+		"\x1b]8;;https://example.com\x07link\x1b]8;;\x1b\\": "link",
+		"started at 2024-03-01T12:30:00Z":                   "started at <TIME>",
+		"started at 2024-03-01T12:30:00.123456+02:00":       "started at <TIME>",
+		"2024-03-01 12:30:00.000 [INFO] ready":              "<TIME> [INFO] ready",
+		"created 2024-03-01 12:30:00 +0000 UTC by gopher":   "created <TIME> by gopher",
+		"version 2024.03.01, build 42":                      "version 2024.03.01, build 42",
+	} {
+		assert.Equal(t, want, apptest.Normalize(in), in)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	assert.Empty(t, apptest.Diff("a\nb\n", "a\nb\n"))
+	assert.Equal(t, `--- want
++++ got
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -12,3 +12,4 @@
+ 12
+ 13
+ 14
++15
+`, apptest.Diff("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n",
+		"1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"))
+	assert.Equal(t, "--- want\n+++ got\n@@ -1 +1 @@\n-a\n+a (no newline at end)\n", apptest.Diff("a\n", "a"))
+}
+
+func TestAssertOutput(t *testing.T) {
+	a := apptest.New(t, nil, "list")
+	_, _ = fmt.Fprintf(a.Output(), "\x1b[1mListing\x1b[0m %s\n  alpha\n  beta\n", "2024-03-01T12:30:00Z")
+	_, _ = io.WriteString(a.ErrOutput(), "warning: beta is archived\n")
+	apptest.AssertOutput(t, a)
+}
+
+func TestGolden(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tb := &recordingTB{TB: t}
+	apptest.Golden(tb, "empty", "")
+	assert.Empty(t, tb.errors)
+
+	apptest.Golden(tb, "list", "alpha\nbeta\n")
+	require.Len(t, tb.errors, 1)
+	assert.Equal(t, "apptest: the output differs from "+filepath.Join("testdata", "list.golden")+
+		", run the test with -apptest.update to replace it:\n--- want\n+++ got\n@@ -0,0 +1,2 @@\n+alpha\n+beta\n",
+		tb.errors[0])
+
+	restore := apptest.SetUpdate(true)
+	apptest.Golden(tb, "nested/list", "\x1b[1malpha\x1b[0m\nbeta\n")
+	restore()
+	b, err := os.ReadFile(filepath.Join("testdata", "nested", "list.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, "alpha\nbeta\n", string(b))
+
+	tb.errors = nil
+	apptest.Golden(tb, "nested/list", "alpha\nbeta\n")
+	assert.Empty(t, tb.errors)
+
+	// the golden file is normalized too, as when edited on Windows
+	path := filepath.Join("testdata", "nested", "list.golden")
+	require.NoError(t, os.WriteFile(path, []byte("alpha\r\nbeta\r\n"), 0o644))
+	apptest.Golden(tb, "nested/list", "alpha\nbeta\n")
+	assert.Empty(t, tb.errors)
+
+	// an empty output removes the golden file
+	restore = apptest.SetUpdate(true)
+	apptest.Golden(tb, "nested/list", "")
+	restore()
+	assert.NoFileExists(t, filepath.Join("testdata", "nested", "list.golden"))
+}
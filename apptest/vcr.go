@@ -0,0 +1,24 @@
+package apptest
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/vcr"
+)
+
+var vcrMode = flag.String("apptest.vcr", "", "the mode of the cassettes of apptest: off, record, replay, or auto")
+
+// Cassette records the HTTP requests a, made with New, sends to the cassette named after the test, or replays them,
+// as vcr.ForTest does. Run the tests with -apptest.vcr=record, as in go test ./... -args -apptest.vcr=record, to
+// record the cassettes again, or with -apptest.vcr=replay to fail when one is missing, as in CI; the flag takes
+// precedence over APP_VCR_MODE.
+func Cassette(tb testing.TB, a *app.App) *vcr.Recorder {
+	tb.Helper()
+
+	if *vcrMode != "" {
+		a.SetFlag(vcr.DefaultPrefix+"MODE", *vcrMode)
+	}
+	return vcr.ForTest(tb, a)
+}
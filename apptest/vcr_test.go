@@ -0,0 +1,21 @@
+package apptest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/vcr"
+)
+
+func TestCassette(t *testing.T) {
+	dir := t.TempDir()
+
+	a := apptest.New(t, []string{"APP_VCR_DIR=" + dir, "APP_VCR_MODE=off"})
+	assert.Equal(t, vcr.Off, apptest.Cassette(t, a).Mode())
+
+	defer apptest.SetVCRMode("record")()
+	a = apptest.New(t, []string{"APP_VCR_DIR=" + dir, "APP_VCR_MODE=off"})
+	assert.Equal(t, vcr.Record, apptest.Cassette(t, a).Mode())
+}
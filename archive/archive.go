@@ -0,0 +1,137 @@
+// Package archive extracts and creates tar and zip archives for installer and updater commands. Extraction is safe
+// with archives from untrusted sources: entries may not escape the destination, by their names or through symbolic
+// links, the size and number of the entries are limited, and permissions are normalized to 0644 and 0755, with the
+// owner of the archive entries ignored.
+package archive
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+const (
+	// DefaultMaxSize is the number of bytes extracted from an archive at most.
+	DefaultMaxSize = 1 << 30
+
+	// DefaultMaxFiles is the number of entries extracted from an archive at most.
+	DefaultMaxFiles = 100000
+)
+
+var (
+	// ErrUnsafePath is returned when an entry would be extracted outside the destination.
+	ErrUnsafePath = errors.New("archive: unsafe path")
+
+	// ErrTooLarge is returned when an archive has more bytes or entries than the Options allow.
+	ErrTooLarge = errors.New("archive: too large")
+)
+
+// Options describe how an archive is extracted or created. The limits only apply to extraction.
+type Options struct {
+	MaxSize         int64         // bytes extracted at most, DefaultMaxSize if zero
+	MaxFiles        int           // entries extracted at most, DefaultMaxFiles if zero
+	StripComponents int           // leading elements removed from the names of the entries, as by tar
+	Progress        *app.Progress // counts the bytes extracted or archived, if set
+}
+
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxFiles == 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	return opts
+}
+
+// format returns the format of the archive at path, as told by its extension: zip, tar, or tar.gz.
+func format(path string) (string, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(name, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz", nil
+	}
+	return "", fmt.Errorf("archive: unknown format of %s", path)
+}
+
+// Extract extracts the archive at path, a .zip, .tar, .tar.gz, or .tgz file, into the directory dest, creating it if
+// it does not exist.
+func Extract(path, dest string, opts *Options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	kind, err := format(path)
+	if err != nil {
+		return err
+	}
+	if kind != "zip" {
+		return ExtractTar(f, dest, opts)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return ExtractZip(f, info.Size(), dest, opts)
+}
+
+// Create creates the archive at path, a .zip, .tar, .tar.gz, or .tgz file, of src, a directory whose contents are
+// archived or a single file. The archive is written to a temporary file renamed to path once it is complete.
+func Create(path, src string, opts *Options) error {
+	kind, err := format(path)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(path, 0o644, func(w io.Writer) error {
+		switch kind {
+		case "zip":
+			return WriteZip(w, src, opts)
+		case "tar":
+			return WriteTar(w, src, opts)
+		default:
+			zw := gzip.NewWriter(w)
+			err := WriteTar(zw, src, opts)
+			if closeErr := zw.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+	})
+}
+
+// normalMode returns the permissions an entry of mode is extracted or archived with: 0755 for directories and
+// executables, 0644 for other files.
+func normalMode(mode fs.FileMode) fs.FileMode {
+	if mode.IsDir() || mode&0o111 != 0 {
+		return 0o755
+	}
+	return 0o644
+}
+
+// progressWriter returns a writer counting the bytes written to w with progress, if it is set.
+func progressWriter(w io.Writer, progress *app.Progress) io.Writer {
+	if progress == nil {
+		return w
+	}
+	return io.MultiWriter(w, progress)
+}
@@ -0,0 +1,209 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/archive"
+)
+
+type entry struct {
+	name, body, link string
+	typ              byte
+}
+
+func tarball(t *testing.T, entries ...entry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		typ := e.typ
+		if typ == 0 {
+			typ = tar.TypeReg
+		}
+		hdr := &tar.Header{Name: e.name, Typeflag: typ, Linkname: e.link, Mode: 0o4777, Size: int64(len(e.body))}
+		if typ != tar.TypeReg {
+			hdr.Size = 0
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestCreate_Extract(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "tool-1.0")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("#!/bin/sh\n"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README"), []byte("read me"), 0o600))
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink("bin/tool", filepath.Join(src, "tool")))
+	}
+
+	for _, name := range []string{"tool.tar.gz", "tool.tar", "tool.zip"} {
+		t.Run(name, func(t *testing.T) {
+			a := &app.App{Stderr: new(bytes.Buffer)}
+			path := filepath.Join(t.TempDir(), name)
+			progress := a.NewProgress(name, 0)
+			require.NoError(t, archive.Create(path, src, &archive.Options{Progress: progress}))
+			assert.Contains(t, progress.String(), "100% [########################] 17 B / 17 B")
+
+			dest := filepath.Join(t.TempDir(), "out")
+			require.NoError(t, archive.Extract(path, dest, nil))
+
+			b, err := os.ReadFile(filepath.Join(dest, "bin", "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, "#!/bin/sh\n", string(b))
+			b, err = os.ReadFile(filepath.Join(dest, "README"))
+			require.NoError(t, err)
+			assert.Equal(t, "read me", string(b))
+			if runtime.GOOS == "windows" {
+				return
+			}
+
+			info, err := os.Stat(filepath.Join(dest, "bin", "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o755), info.Mode().Perm()&^umask(t))
+			info, err = os.Stat(filepath.Join(dest, "README"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o644), info.Mode().Perm()&^umask(t))
+			target, err := os.Readlink(filepath.Join(dest, "tool"))
+			require.NoError(t, err)
+			assert.Equal(t, "bin/tool", target)
+		})
+	}
+}
+
+// umask returns the bits the umask of the process clears, as seen on a new file.
+func umask(t *testing.T) os.FileMode {
+	path := filepath.Join(t.TempDir(), "probe")
+	require.NoError(t, os.WriteFile(path, nil, 0o777))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return 0o777 &^ info.Mode().Perm()
+}
+
+func TestExtractTar_Unsafe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	tests := map[string][]entry{
+		"parent":        {{name: "../evil", body: "x"}},
+		"nested parent": {{name: "a/../../evil", body: "x"}},
+		"absolute":      {{name: "/tmp/evil", body: "x"}},
+		"link outside":  {{name: "link", link: "../outside", typ: tar.TypeSymlink}},
+		"link absolute": {{name: "link", link: "/etc", typ: tar.TypeSymlink}},
+		"through link":  {{name: "link", link: ".", typ: tar.TypeSymlink}, {name: "link/evil", body: "x"}},
+		"hard link":     {{name: "link", link: "../outside", typ: tar.TypeLink}},
+		"chained links": {
+			{name: "a/up", link: "..", typ: tar.TypeSymlink},
+			{name: "a/b", link: "up/..", typ: tar.TypeSymlink},
+		},
+		"through parent link": {
+			{name: "link", link: "..", typ: tar.TypeSymlink},
+			{name: "link/x", body: "x"},
+		},
+		"backslash name": {{name: `..\evil`, body: "x"}},
+	}
+
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "out")
+			err := archive.ExtractTar(bytes.NewReader(tarball(t, entries...)), dest, nil)
+			assert.True(t, errors.Is(err, archive.ErrUnsafePath), err)
+			assert.NoFileExists(t, filepath.Join(dir, "evil"))
+			assert.NoFileExists(t, filepath.Join(dir, "x"))
+		})
+	}
+
+	// a link is not created through a symbolic link already in the destination
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "out")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "outside"), 0o755))
+	require.NoError(t, os.MkdirAll(dest, 0o755))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dest, "escape")))
+	err := archive.ExtractTar(bytes.NewReader(tarball(t,
+		entry{name: "escape/link", link: "target", typ: tar.TypeSymlink},
+	)), dest, nil)
+	assert.Error(t, err)
+	_, err = os.Lstat(filepath.Join(dir, "outside", "link"))
+	assert.True(t, os.IsNotExist(err), err)
+
+	// links within the destination are fine
+	dest = t.TempDir()
+	err = archive.ExtractTar(bytes.NewReader(tarball(t,
+		entry{name: "lib/libx.so.1", body: "elf"},
+		entry{name: "lib/libx.so", link: "libx.so.1", typ: tar.TypeSymlink},
+		entry{name: "bin/x", link: "../lib/libx.so", typ: tar.TypeSymlink},
+		entry{name: "lib/copy", link: "lib/libx.so.1", typ: tar.TypeLink},
+	)), dest, nil)
+	require.NoError(t, err)
+	b, err := os.ReadFile(filepath.Join(dest, "bin", "x"))
+	require.NoError(t, err)
+	assert.Equal(t, "elf", string(b))
+	b, err = os.ReadFile(filepath.Join(dest, "lib", "copy"))
+	require.NoError(t, err)
+	assert.Equal(t, "elf", string(b))
+}
+
+func TestExtractTar_StripComponents(t *testing.T) {
+	dest := t.TempDir()
+	err := archive.ExtractTar(bytes.NewReader(tarball(t,
+		entry{name: "tool-1.0/", typ: tar.TypeDir},
+		entry{name: "tool-1.0/bin/tool", body: "#!/bin/sh\n"},
+		entry{name: "LICENSE", body: "skipped"},
+	)), dest, &archive.Options{StripComponents: 1})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dest)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "bin", entries[0].Name())
+	assert.FileExists(t, filepath.Join(dest, "bin", "tool"))
+}
+
+func TestExtract_Limits(t *testing.T) {
+	data := tarball(t, entry{name: "a", body: "12345"}, entry{name: "b", body: "67890"})
+
+	err := archive.ExtractTar(bytes.NewReader(data), t.TempDir(), &archive.Options{MaxSize: 8})
+	assert.True(t, errors.Is(err, archive.ErrTooLarge), err)
+	assert.EqualError(t, err, "archive: too large: more than 8 bytes")
+
+	err = archive.ExtractTar(bytes.NewReader(data), t.TempDir(), &archive.Options{MaxFiles: 1})
+	assert.EqualError(t, err, "archive: too large: more than 1 entries")
+
+	assert.NoError(t, archive.ExtractTar(bytes.NewReader(data), t.TempDir(), &archive.Options{MaxSize: 10}))
+}
+
+func TestExtractZip_Unsafe(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("../evil")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("x"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	dir := t.TempDir()
+	err = archive.ExtractZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), filepath.Join(dir, "out"), nil)
+	assert.True(t, errors.Is(err, archive.ErrUnsafePath), err)
+	assert.NoFileExists(t, filepath.Join(dir, "evil"))
+}
+
+func TestCreate_UnknownFormat(t *testing.T) {
+	err := archive.Create(filepath.Join(t.TempDir(), "tool.rar"), t.TempDir(), nil)
+	assert.ErrorContains(t, err, "archive: unknown format of ")
+}
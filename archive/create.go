@@ -0,0 +1,147 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteTar writes a tar archive of src, a directory whose contents are archived or a single file, to w. The entries
+// have normalized permissions and no owner, so the archive does not depend on who created it.
+func WriteTar(w io.Writer, src string, opts *Options) error {
+	tw := tar.NewWriter(w)
+	err := walk(src, opts, func(name string, info fs.FileInfo, target string) (io.Writer, error) {
+		hdr, err := tar.FileInfoHeader(info, target)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Name = name
+		hdr.Mode = int64(normalMode(info.Mode()))
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		hdr.AccessTime, hdr.ChangeTime = time.Time{}, time.Time{}
+		hdr.Format = tar.FormatPAX
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		return tw, nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// WriteZip writes a zip archive of src, a directory whose contents are archived or a single file, to w, with
+// normalized permissions.
+func WriteZip(w io.Writer, src string, opts *Options) error {
+	zw := zip.NewWriter(w)
+	err := walk(src, opts, func(name string, info fs.FileInfo, target string) (io.Writer, error) {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return nil, err
+		}
+		hdr.Name = name
+		hdr.Method = zip.Deflate
+		mode := normalMode(info.Mode())
+		switch {
+		case info.IsDir():
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+			mode |= fs.ModeDir
+		case target != "":
+			hdr.Method = zip.Store
+			mode = fs.ModeSymlink | 0o777
+		}
+		hdr.SetMode(mode)
+
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+		if target != "" {
+			_, err = io.WriteString(fw, target)
+			return nil, err
+		}
+		return fw, nil
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// walk calls add with the name, information, and target, for a symbolic link, of every file in src, in lexical
+// order, and copies the content of the regular files to the writer it returns. The total of the Progress of opts is
+// set to the size of the regular files.
+func walk(src string, opts *Options, add func(name string, info fs.FileInfo, target string) (io.Writer, error)) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	base := src
+	if !info.IsDir() {
+		base = filepath.Dir(src)
+	}
+
+	progress := opts.withDefaults().Progress
+	if progress != nil {
+		var total int64
+		err := filepath.WalkDir(src, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+			info, err := d.Info()
+			if err == nil {
+				total += info.Size()
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		progress.Set(0, total)
+	}
+
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(base, path)
+		if err != nil || name == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var target string
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			if target, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case !d.IsDir() && !d.Type().IsRegular():
+			return nil
+		}
+
+		w, err := add(filepath.ToSlash(name), info, target)
+		if err != nil || w == nil || !d.Type().IsRegular() {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(progressWriter(w, progress), f)
+		return err
+	})
+}
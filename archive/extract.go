@@ -0,0 +1,302 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxLinkTarget bounds the size of the target of a symbolic link in a zip archive.
+const maxLinkTarget = 4096
+
+// ExtractTar extracts the tar archive read from r, compressed with gzip or not, into the directory dest, creating it
+// if it does not exist. Device files and FIFOs are skipped. The archive is read as a stream, so the sizes of its files
+// are only known as they are reached: the Progress counts the bytes extracted without setting a total, unlike that of
+// ExtractZip.
+func ExtractTar(r io.Reader, dest string, opts *Options) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	e, err := newExtractor(dest, opts)
+	if err != nil {
+		return err
+	}
+	defer e.root.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+
+		name, err := e.entry(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdirAll(name)
+		case tar.TypeReg:
+			err = e.writeFile(name, hdr.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = e.symlink(name, hdr.Linkname)
+		case tar.TypeLink:
+			err = e.link(name, hdr.Linkname)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExtractZip extracts the zip archive of size bytes read from r into the directory dest, creating it if it does not
+// exist. The total of the Progress is set to the sum of the sizes of the files, read from the central directory.
+func ExtractZip(r io.ReaderAt, size int64, dest string, opts *Options) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+
+	e, err := newExtractor(dest, opts)
+	if err != nil {
+		return err
+	}
+	defer e.root.Close()
+
+	if e.opts.Progress != nil {
+		var total uint64
+		for _, f := range zr.File {
+			total += f.UncompressedSize64
+		}
+		e.opts.Progress.Set(0, int64(total))
+	}
+
+	for _, f := range zr.File {
+		name, err := e.entry(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if err := e.extractZipFile(name, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZipFile(name string, f *zip.File) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return e.mkdirAll(name)
+	}
+	if !mode.IsRegular() && mode&fs.ModeSymlink == 0 {
+		return nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("archive: %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if mode&fs.ModeSymlink != 0 {
+		target, err := io.ReadAll(io.LimitReader(rc, maxLinkTarget))
+		if err != nil {
+			return fmt.Errorf("archive: %s: %w", f.Name, err)
+		}
+		return e.symlink(name, string(target))
+	}
+	return e.writeFile(name, mode, rc)
+}
+
+// extractor writes the entries of an archive in its root, within its limits.
+type extractor struct {
+	root  *os.Root
+	opts  Options
+	size  int64
+	files int
+	links map[string]bool // the symbolic links extracted, by name
+}
+
+func newExtractor(dest string, opts *Options) (*extractor, error) {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return nil, err
+	}
+	return &extractor{root: root, opts: opts.withDefaults(), links: make(map[string]bool)}, nil
+}
+
+// entry counts an entry named name and returns the path it is extracted to, or the empty string if it is skipped.
+func (e *extractor) entry(name string) (string, error) {
+	if e.files++; e.files > e.opts.MaxFiles {
+		return "", fmt.Errorf("%w: more than %d entries", ErrTooLarge, e.opts.MaxFiles)
+	}
+	return e.local(name)
+}
+
+// local returns the path in the destination of the entry named name, or the empty string if the entry is skipped: it
+// names the destination itself, or has no more than StripComponents elements. Names that are absolute, have parent
+// elements leading out of the destination, or go through a symbolic link are unsafe.
+func (e *extractor) local(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	parts := strings.Split(clean, "/")
+	if clean == "." || len(parts) <= e.opts.StripComponents {
+		return "", nil
+	}
+	local := filepath.FromSlash(path.Join(parts[e.opts.StripComponents:]...))
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	for dir := filepath.Dir(local); dir != "."; dir = filepath.Dir(dir) {
+		if e.links[dir] {
+			return "", fmt.Errorf("%w: %s goes through the symbolic link %s", ErrUnsafePath, name, dir)
+		}
+	}
+	return local, nil
+}
+
+func (e *extractor) mkdirAll(name string) error {
+	var dir string
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		if err := e.root.Mkdir(dir, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("archive: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *extractor) mkdirParent(name string) error {
+	if dir := filepath.Dir(name); dir != "." {
+		return e.mkdirAll(dir)
+	}
+	return nil
+}
+
+// writeFile writes the file name with the content read from r, within MaxSize.
+func (e *extractor) writeFile(name string, mode fs.FileMode, r io.Reader) error {
+	if err := e.mkdirParent(name); err != nil {
+		return err
+	}
+	f, err := e.root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, normalMode(mode))
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+
+	n, err := io.Copy(progressWriter(f, e.opts.Progress), io.LimitReader(r, e.opts.MaxSize-e.size+1))
+	e.size += n
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("archive: %s: %w", name, err)
+	}
+	if e.size > e.opts.MaxSize {
+		return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, e.opts.MaxSize)
+	}
+	return nil
+}
+
+// symlink creates the symbolic link name to target, which must resolve within the destination without going through
+// another symbolic link.
+func (e *extractor) symlink(name, target string) error {
+	unsafe := fmt.Errorf("%w: %s links to %s", ErrUnsafePath, name, target)
+	if target == "" || filepath.IsAbs(target) || path.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return unsafe
+	}
+
+	var resolved []string
+	if dir := filepath.Dir(name); dir != "." {
+		resolved = strings.Split(dir, string(filepath.Separator))
+	}
+	elems := strings.Split(strings.ReplaceAll(target, `\`, "/"), "/")
+	for i, elem := range elems {
+		switch elem {
+		case "", ".":
+		case "..":
+			if len(resolved) == 0 {
+				return unsafe
+			}
+			resolved = resolved[:len(resolved)-1]
+		default:
+			resolved = append(resolved, elem)
+			if i < len(elems)-1 && e.links[filepath.Join(resolved...)] {
+				return unsafe
+			}
+		}
+	}
+
+	if err := e.mkdirParent(name); err != nil {
+		return err
+	}
+	if err := e.root.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if err := e.root.Symlink(target, name); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	e.links[name] = true
+	return nil
+}
+
+// link creates name as a copy of the file target extracted before, for a hard link.
+func (e *extractor) link(name, target string) error {
+	src, err := e.local(target)
+	if err != nil {
+		return err
+	}
+	if src == "" {
+		return fmt.Errorf("%w: %s links to %s", ErrUnsafePath, name, target)
+	}
+
+	f, err := e.root.Open(src)
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s links to %s", ErrUnsafePath, name, target)
+	}
+	return e.writeFile(name, info.Mode(), f)
+}
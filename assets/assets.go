@@ -0,0 +1,211 @@
+// Package assets gives an app the files it embeds, such as templates, SQL migrations, and static files, letting
+// operators override any of them with a file of the same name in a directory on disk:
+//
+//	//go:embed templates
+//	var embedded embed.FS
+//
+//	config, err := assets.FromEnv(a.LookupEnv, "")
+//	sub, err := fs.Sub(embedded, "templates")
+//	files := assets.New(sub, config.Dir)
+//	tmpl, err := template.ParseFS(files, "*.html")
+//
+// The assets command lists the assets, telling which are overridden, and extracts the embedded ones to start an
+// override directory from.
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+// DefaultPrefix prefixes the override directory, APP_ASSETS_DIR.
+const DefaultPrefix = "APP_ASSETS_"
+
+// Config describes where the assets are overridden.
+type Config struct {
+	Dir string // directory whose files override the embedded ones, none if empty
+}
+
+func init() {
+	configschema.Register("assets", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes DIR with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "DIR", Type: "path", Description: "The directory whose files override the embedded ones."},
+	}
+}
+
+// FromEnv reads the override directory from DIR, with the prefix or DefaultPrefix, and checks that it is a directory.
+// Without it, only the embedded assets are served.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	v, _ := lookup(prefix + "DIR")
+	config := &Config{Dir: strings.TrimSpace(v)}
+	if config.Dir != "" {
+		info, err := os.Stat(config.Dir)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("assets: invalid %sDIR %q: not a directory", prefix, config.Dir)
+		}
+	}
+	return config, nil
+}
+
+// FS is a file system of the embedded assets, overridden by the files in a directory. Listing a directory lists the
+// files of both, so fs.Glob, fs.WalkDir, and template.ParseFS see the overrides and the assets that are not
+// overridden alike.
+type FS struct {
+	embedded fs.FS
+	dir      string
+	override fs.FS
+}
+
+// New returns the FS of the assets embedded, overridden by the files in dir, unless it is empty.
+func New(embedded fs.FS, dir string) *FS {
+	f := &FS{embedded: embedded, dir: dir}
+	if dir != "" {
+		f.override = os.DirFS(dir)
+	}
+	return f
+}
+
+// Open opens the asset name, from the override directory if it has it.
+func (f *FS) Open(name string) (fs.File, error) {
+	if f.override != nil {
+		file, err := f.override.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return file, err
+		}
+	}
+	return f.embedded.Open(name)
+}
+
+// ReadFile returns the content of the asset name, from the override directory if it has it.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	if f.override != nil {
+		b, err := fs.ReadFile(f.override, name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return b, err
+		}
+	}
+	return fs.ReadFile(f.embedded, name)
+}
+
+// Stat returns the information of the asset name, from the override directory if it has it.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	if f.override != nil {
+		info, err := fs.Stat(f.override, name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return info, err
+		}
+	}
+	return fs.Stat(f.embedded, name)
+}
+
+// ReadDir lists the directory name of the embedded assets and of the override directory, sorted by name. An entry in
+// both is that of the override directory.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	embedded, err := fs.ReadDir(f.embedded, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var overrides []fs.DirEntry
+	overrideErr := fs.ErrNotExist
+	if f.override != nil {
+		overrides, overrideErr = fs.ReadDir(f.override, name)
+		if overrideErr != nil && !errors.Is(overrideErr, fs.ErrNotExist) {
+			return nil, overrideErr
+		}
+	}
+	if err != nil && overrideErr != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]fs.DirEntry)
+	for _, e := range embedded {
+		entries[e.Name()] = e
+	}
+	for _, e := range overrides {
+		entries[e.Name()] = e
+	}
+
+	list := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+// Asset describes an asset.
+type Asset struct {
+	Name       string `json:"name"`
+	Size       int64  `json:"size"`
+	Overridden bool   `json:"overridden,omitempty"` // the file in the override directory is used
+	Embedded   bool   `json:"embedded"`             // the app embeds the asset, false for files only on disk
+}
+
+// List returns the assets, embedded or in the override directory, sorted by name.
+func (f *FS) List() ([]Asset, error) {
+	var list []Asset
+	err := fs.WalkDir(f, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := f.Stat(name)
+		if err != nil {
+			return err
+		}
+		asset := Asset{Name: name, Size: info.Size()}
+		if _, err := fs.Stat(f.embedded, name); err == nil {
+			asset.Embedded = true
+		}
+		if f.override != nil {
+			if _, err := fs.Stat(f.override, name); err == nil {
+				asset.Overridden = true
+			}
+		}
+		list = append(list, asset)
+		return nil
+	})
+	return list, err
+}
+
+// Extract writes the embedded assets to the directory dir, as a start for an override directory. Files that exist are
+// left as they are unless overwrite is set. It returns the names of the assets written.
+func (f *FS) Extract(dir string, overwrite bool) ([]string, error) {
+	var written []string
+	err := fs.WalkDir(f.embedded, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0o755)
+		}
+		if _, err := os.Lstat(dest); err == nil && !overwrite {
+			return nil
+		}
+		b, err := fs.ReadFile(f.embedded, name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, b, 0o644); err != nil {
+			return err
+		}
+		written = append(written, name)
+		return nil
+	})
+	return written, err
+}
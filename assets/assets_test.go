@@ -0,0 +1,104 @@
+package assets_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/assets"
+)
+
+var embedded = fstest.MapFS{
+	"templates/index.html": {Data: []byte("embedded index")},
+	"templates/page.html":  {Data: []byte("embedded page")},
+	"schema.sql":           {Data: []byte("create table t;")},
+}
+
+func overrides(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "index.html"), []byte("custom index"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "templates", "extra.html"), []byte("extra"), 0o644))
+	return dir
+}
+
+func TestFS(t *testing.T) {
+	files := assets.New(embedded, overrides(t))
+
+	b, err := files.ReadFile("templates/index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "custom index", string(b))
+	b, err = fs.ReadFile(files, "templates/page.html")
+	require.NoError(t, err)
+	assert.Equal(t, "embedded page", string(b))
+	_, err = files.Stat("templates/missing.html")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	matches, err := fs.Glob(files, "templates/*.html")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"templates/extra.html", "templates/index.html", "templates/page.html"}, matches)
+
+	list, err := files.List()
+	require.NoError(t, err)
+	assert.Equal(t, []assets.Asset{
+		{Name: "schema.sql", Size: 15, Embedded: true},
+		{Name: "templates/extra.html", Size: 5, Overridden: true},
+		{Name: "templates/index.html", Size: 12, Overridden: true, Embedded: true},
+		{Name: "templates/page.html", Size: 13, Embedded: true},
+	}, list)
+}
+
+func TestFS_NoOverride(t *testing.T) {
+	files := assets.New(embedded, "")
+	b, err := files.ReadFile("templates/index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "embedded index", string(b))
+	require.NoError(t, fstest.TestFS(files, "schema.sql", "templates/index.html", "templates/page.html"))
+}
+
+func TestFS_Extract(t *testing.T) {
+	dir := overrides(t)
+	written, err := assets.New(embedded, "").Extract(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"schema.sql", "templates/page.html"}, written)
+	b, err := os.ReadFile(filepath.Join(dir, "templates", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "custom index", string(b))
+
+	written, err = assets.New(embedded, "").Extract(dir, true)
+	require.NoError(t, err)
+	assert.Len(t, written, 3)
+	b, err = os.ReadFile(filepath.Join(dir, "templates", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "embedded index", string(b))
+}
+
+func TestCommand(t *testing.T) {
+	files := assets.New(embedded, overrides(t))
+
+	a := apptest.New(t, nil, "assets", "list")
+	a.AddCommand(assets.Command(files))
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, ""+
+		"schema.sql            15  embedded\n"+
+		"templates/extra.html  5   disk\n"+
+		"templates/index.html  12  overridden\n"+
+		"templates/page.html   13  embedded\n", apptest.Stdout(t, a))
+
+	dir := t.TempDir()
+	a = apptest.New(t, nil, "assets", "extract", "-f", dir)
+	a.AddCommand(assets.Command(files))
+	require.NoError(t, a.Dispatch())
+	assert.Equal(t, "schema.sql\ntemplates/index.html\ntemplates/page.html\n", apptest.Stdout(t, a))
+	assert.FileExists(t, filepath.Join(dir, "templates", "page.html"))
+
+	a = apptest.New(t, nil, "assets", "remove")
+	a.AddCommand(assets.Command(files))
+	assert.Error(t, a.Dispatch())
+}
@@ -0,0 +1,55 @@
+package assets
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+type commandOptions struct {
+	Action string `arg:"action,list or extract" validate:"oneof=list extract"`
+	Dir    string `arg:"[dir],the directory extract writes to" default:"."`
+	Force  bool   `flag:"force,f,overwrite the files that exist"`
+}
+
+// Command returns the hidden assets command, a debugging aid for the assets of f:
+//
+//	assets list           the assets, their size, and whether they are embedded or overridden
+//	assets extract [dir]  write the embedded assets to dir, the working directory by default, to override them
+//
+// Register it with App.AddCommand.
+func Command(f *FS) *app.Command {
+	cmd := app.NewCommand("assets", "list or extract the embedded assets",
+		func(a *app.App, opts *commandOptions, _ []string) error {
+			if opts.Action == "extract" {
+				written, err := f.Extract(opts.Dir, opts.Force)
+				if err != nil {
+					return err
+				}
+				for _, name := range written {
+					_, _ = fmt.Fprintln(a.Output(), name)
+				}
+				return nil
+			}
+
+			list, err := f.List()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(a.Output(), 0, 0, 2, ' ', 0)
+			for _, asset := range list {
+				source := "embedded"
+				switch {
+				case asset.Overridden && asset.Embedded:
+					source = "overridden"
+				case asset.Overridden:
+					source = "disk"
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", asset.Name, asset.Size, source)
+			}
+			return tw.Flush()
+		})
+	cmd.Hidden = true
+	return cmd
+}
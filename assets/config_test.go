@@ -0,0 +1,35 @@
+package assets_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/assets"
+)
+
+func TestFromEnv_Dir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.css")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	// without an override directory only the embedded assets are served
+	config, err := assets.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Empty(t, config.Dir)
+
+	config, err = assets.FromEnv(apptest.Lookup(map[string]string{"TOOL_ASSETS_DIR": " " + dir + "\n"}), "TOOL_ASSETS_")
+	require.NoError(t, err)
+	assert.Equal(t, dir, config.Dir)
+
+	// a file, or a directory that does not exist yet, cannot override anything
+	for _, v := range []string{file, filepath.Join(dir, "missing")} {
+		_, err := assets.FromEnv(apptest.Lookup(map[string]string{"APP_ASSETS_DIR": v}), "")
+		assert.EqualError(t, err, fmt.Sprintf("assets: invalid APP_ASSETS_DIR %q: not a directory", v))
+	}
+}
@@ -0,0 +1,306 @@
+// Package auth signs the user of a command line tool in to an OAuth 2.0 or OpenID Connect provider, keeps their
+// token in the App Credentials, and refreshes it as it expires:
+//
+//	config, err := auth.FromEnv(a.LookupEnv, "")
+//	client, err := auth.Open(a, config)
+//
+//	// in the login command
+//	_, err = auth.Login(a, client)
+//
+//	// in the other commands
+//	res, err := client.HTTPClient().Get("https://api.example.com/v1/me")
+//
+// Login uses the authorization code flow with PKCE, receiving the code on a server listening on the loopback
+// interface, or the device flow when the user is in an SSH session or the provider has no authorization endpoint.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/credstore"
+)
+
+// expiryDelta is how long before it expires a token is refreshed, so that it does not expire in flight.
+const expiryDelta = 30 * time.Second
+
+// ErrLoginRequired is returned by Client.Token when there is no token, or it expired and cannot be refreshed.
+var ErrLoginRequired = errors.New("auth: login required")
+
+// Token is the token the provider issued to the user.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"` // the OpenID Connect ID token, if the openid scope was requested
+	Expiry       time.Time `json:"expiry,omitempty"`   // zero if the token does not expire
+}
+
+func (t *Token) valid(now time.Time) bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(expiryDelta).Before(t.Expiry))
+}
+
+// Error is an error response of the provider, such as access_denied or invalid_grant.
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return "auth: " + e.Code
+	}
+	return "auth: " + e.Code + ": " + e.Description
+}
+
+// TokenSource returns a valid token for the requests of an HTTP client. Client is one.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// Client signs the user in with a Config and keeps their token in a credstore.Store. It is safe for concurrent use.
+type Client struct {
+	config Config
+	store  credstore.Store
+	key    string
+	http   *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// New returns a Client signing in with config and keeping the token in store. The HTTP client, used to call the
+// provider, may be nil.
+func New(config *Config, store credstore.Store, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{
+		config: *config,
+		store:  store,
+		key:    "oauth2:" + config.ClientID,
+		http:   client,
+		now:    time.Now,
+	}
+}
+
+// Open returns a Client signing in with config and keeping the token in the Credentials of the app.
+func Open(a *app.App, config *Config) (*Client, error) {
+	if config.ClientID == "" {
+		return nil, errors.New("auth: no client ID")
+	}
+	store, err := a.Credentials()
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	return New(config, store, nil), nil
+}
+
+// Login signs the user in, with the authorization code flow if the Config has an AuthURL and the user is not in an
+// SSH session, where the browser cannot reach the loopback interface, and with the device flow otherwise. The pages
+// the user signs in on are opened with App.OpenURL.
+func Login(a *app.App, c *Client) (*Token, error) {
+	if c.config.AuthURL != "" && (c.config.DeviceAuthURL == "" || !a.SSHSession()) {
+		return c.BrowserLogin(a.CommandContext(), a.OpenURL)
+	}
+
+	return c.DeviceLogin(a.CommandContext(), func(code *DeviceCode) error {
+		_, _ = fmt.Fprintf(a.ErrOutput(), "To sign in, enter the code %s at %s\n", code.UserCode, code.VerificationURI)
+		if code.VerificationURIComplete != "" {
+			return a.OpenURL(code.VerificationURIComplete)
+		}
+		return a.OpenURL(code.VerificationURI)
+	})
+}
+
+// Token returns the token of the user, refreshing it if it expired. It returns an error wrapping ErrLoginRequired
+// if the user has not signed in, or their token cannot be refreshed.
+func (c *Client) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == nil {
+		v, err := c.store.Get(c.key)
+		if errors.Is(err, credstore.ErrNotFound) {
+			return nil, ErrLoginRequired
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth: unable to load the token: %w", err)
+		}
+		var tok Token
+		if err := json.Unmarshal([]byte(v), &tok); err != nil {
+			return nil, fmt.Errorf("auth: unable to load the token: %w", err)
+		}
+		c.token = &tok
+	}
+
+	if c.token.valid(c.now()) {
+		tok := *c.token
+		return &tok, nil
+	}
+	if c.token.RefreshToken == "" {
+		return nil, ErrLoginRequired
+	}
+
+	tok, err := c.exchange(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.token.RefreshToken},
+	})
+	var oauthErr *Error
+	if errors.As(err, &oauthErr) && oauthErr.Code == "invalid_grant" {
+		return nil, fmt.Errorf("%w: %v", ErrLoginRequired, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = c.token.RefreshToken
+	}
+	if err := c.save(tok); err != nil {
+		return nil, err
+	}
+	out := *tok
+	return &out, nil
+}
+
+// Logout forgets the token of the user.
+func (c *Client) Logout() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = nil
+	if err := c.store.Delete(c.key); err != nil && !errors.Is(err, credstore.ErrNotFound) {
+		return fmt.Errorf("auth: unable to delete the token: %w", err)
+	}
+	return nil
+}
+
+// HTTPClient returns an HTTP client that sends the token of the user with every request.
+func (c *Client) HTTPClient() *http.Client {
+	return &http.Client{Transport: NewTransport(c, c.http.Transport)}
+}
+
+// save keeps tok as the token of the user. c.mu must be held.
+func (c *Client) save(tok *Token) error {
+	b, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	if err := c.store.Set(c.key, string(b)); err != nil {
+		return fmt.Errorf("auth: unable to save the token: %w", err)
+	}
+	c.token = tok
+	return nil
+}
+
+// login saves the token of a flow that just completed.
+func (c *Client) login(tok *Token) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.save(tok); err != nil {
+		return nil, err
+	}
+	out := *tok
+	return &out, nil
+}
+
+// exchange requests a token from the token endpoint with form.
+func (c *Client) exchange(ctx context.Context, form url.Values) (*Token, error) {
+	var body struct {
+		Token
+		ExpiresIn int64 `json:"expires_in"`
+	}
+	if err := c.post(ctx, c.config.TokenURL, form, &body); err != nil {
+		return nil, err
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("auth: response has no access token")
+	}
+
+	tok := body.Token
+	if body.ExpiresIn > 0 {
+		tok.Expiry = c.now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return &tok, nil
+}
+
+// post sends form, with the credentials of the client, to endpoint and decodes the JSON response into v.
+func (c *Client) post(ctx context.Context, endpoint string, form url.Values, v interface{}) error {
+	if endpoint == "" {
+		return errors.New("auth: no endpoint configured")
+	}
+	form.Set("client_id", c.config.ClientID)
+	if c.config.ClientSecret != "" {
+		form.Set("client_secret", c.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var errBody struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if res.StatusCode != http.StatusOK {
+		if json.NewDecoder(res.Body).Decode(&errBody) == nil && errBody.Error != "" {
+			return &Error{Code: errBody.Error, Description: errBody.ErrorDescription}
+		}
+		return fmt.Errorf("auth: %s", res.Status)
+	}
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("auth: invalid response: %v", err)
+	}
+	return nil
+}
+
+// NewTransport returns a RoundTripper sending the token of src with every request it makes with base, or with
+// http.DefaultTransport if base is nil.
+func NewTransport(src TokenSource, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{src: src, base: base}
+}
+
+type transport struct {
+	src  TokenSource
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.src.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	typ := tok.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", typ+" "+tok.AccessToken)
+	return t.base.RoundTrip(req)
+}
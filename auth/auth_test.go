@@ -0,0 +1,237 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/auth"
+	"github.com/demosdemon/golang-app-framework/credstore"
+)
+
+// provider is an OAuth 2.0 provider issuing tokens numbered in order.
+type provider struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	issued    int
+	pending   int // authorization_pending responses left for the device code
+	challenge string
+	forms     []url.Values
+}
+
+func (p *provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	require.NoError(p.t, r.ParseForm())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.forms = append(p.forms, r.PostForm)
+
+	reply := func(status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	issue := func() {
+		p.issued++
+		reply(http.StatusOK, map[string]interface{}{
+			"access_token":  "access-" + string(rune('0'+p.issued)),
+			"token_type":    "bearer",
+			"refresh_token": "refresh-" + string(rune('0'+p.issued)),
+			"expires_in":    3600,
+		})
+	}
+
+	switch r.URL.Path {
+	case "/authorize":
+		q := r.URL.Query()
+		p.challenge = q.Get("code_challenge")
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=abc&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	case "/device":
+		reply(http.StatusOK, map[string]interface{}{
+			"device_code":      "dev",
+			"user_code":        "WDJB-MJHT",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       60,
+		})
+	case "/token":
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "abc" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+				reply(http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			issue()
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if p.pending > 0 {
+				p.pending--
+				reply(http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+				return
+			}
+			issue()
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") == "revoked" {
+				reply(http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "revoked"})
+				return
+			}
+			issue()
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newClient(t *testing.T) (*auth.Client, *provider, credstore.Store) {
+	p := &provider{t: t}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+
+	store := new(credstore.MemoryStore)
+	config := &auth.Config{
+		ClientID:      "cli",
+		AuthURL:       srv.URL + "/authorize",
+		TokenURL:      srv.URL + "/token",
+		DeviceAuthURL: srv.URL + "/device",
+		Scopes:        []string{"openid", "offline_access"},
+	}
+	return auth.New(config, store, srv.Client()), p, store
+}
+
+func TestClient_BrowserLogin(t *testing.T) {
+	c, p, _ := newClient(t)
+
+	_, err := c.Token(context.Background())
+	assert.ErrorIs(t, err, auth.ErrLoginRequired)
+
+	tok, err := c.BrowserLogin(context.Background(), func(link string) error {
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		assert.Equal(t, "openid offline_access", u.Query().Get("scope"))
+		assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+		res, err := http.Get(link)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", tok.AccessToken)
+	assert.Equal(t, "cli", p.forms[len(p.forms)-1].Get("client_id"))
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer api.Close()
+	res, err := c.HTTPClient().Get(api.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	var body [64]byte
+	n, _ := res.Body.Read(body[:])
+	assert.Equal(t, "Bearer access-1", string(body[:n]))
+}
+
+func TestClient_DeviceLogin(t *testing.T) {
+	defer auth.SetDeviceIntervals(time.Millisecond, time.Millisecond)()
+	c, p, _ := newClient(t)
+	p.pending = 2
+
+	var shown *auth.DeviceCode
+	tok, err := c.DeviceLogin(context.Background(), func(code *auth.DeviceCode) error {
+		shown = code
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "WDJB-MJHT", shown.UserCode)
+	assert.Equal(t, "access-1", tok.AccessToken)
+	assert.Len(t, p.forms, 4)
+}
+
+func TestClient_Token(t *testing.T) {
+	c, _, store := newClient(t)
+	now := time.Now()
+	c.SetNow(func() time.Time { return now })
+
+	_, err := c.BrowserLogin(context.Background(), func(link string) error {
+		res, err := http.Get(link)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	})
+	require.NoError(t, err)
+
+	tok, err := c.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", tok.AccessToken)
+
+	now = now.Add(time.Hour)
+	tok, err = c.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", tok.AccessToken)
+	assert.Equal(t, "refresh-2", tok.RefreshToken)
+
+	v, err := store.Get("oauth2:cli")
+	require.NoError(t, err)
+	assert.Contains(t, v, `"access_token":"access-2"`)
+
+	require.NoError(t, c.Logout())
+	_, err = store.Get("oauth2:cli")
+	assert.ErrorIs(t, err, credstore.ErrNotFound)
+
+	revoked := `{"access_token":"old","refresh_token":"revoked","expiry":"2000-01-01T00:00:00Z"}`
+	require.NoError(t, store.Set("oauth2:cli", revoked))
+	_, err = c.Token(context.Background())
+	assert.ErrorIs(t, err, auth.ErrLoginRequired)
+	assert.ErrorContains(t, err, "invalid_grant: revoked")
+}
+
+func TestLogin(t *testing.T) {
+	defer auth.SetDeviceIntervals(time.Millisecond, time.Millisecond)()
+
+	for _, environ := range [][]string{nil, {"SSH_CONNECTION=10.0.0.1 22 10.0.0.2 22"}} {
+		c, _, _ := newClient(t)
+		var opened []string
+		a := &app.App{
+			Environment: environ,
+			Context:     context.Background(),
+			Stderr:      new(bytes.Buffer),
+			Browser: func(link string) error {
+				opened = append(opened, link)
+				if !strings.Contains(link, "/authorize") {
+					return nil
+				}
+				res, err := http.Get(link)
+				if err == nil {
+					res.Body.Close()
+				}
+				return err
+			},
+		}
+
+		tok, err := auth.Login(a, c)
+		require.NoError(t, err)
+		assert.Equal(t, "access-1", tok.AccessToken)
+		if environ == nil {
+			assert.Len(t, opened, 1)
+			assert.Empty(t, a.Stderr.(*bytes.Buffer).String())
+			continue
+		}
+		assert.Equal(t, []string{"https://example.com/device"}, opened)
+		assert.Contains(t, a.Stderr.(*bytes.Buffer).String(),
+			"To sign in, enter the code WDJB-MJHT at https://example.com/device\n")
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+// DefaultPrefix prefixes the OAuth 2.0 client settings, as in APP_AUTH_CLIENT_ID.
+const DefaultPrefix = "APP_AUTH_"
+
+// Config describes the OAuth 2.0 client of the app and the endpoints of the provider it signs in with. A public
+// client, as a command line tool is, has no ClientSecret.
+type Config struct {
+	ClientID      string
+	ClientSecret  string
+	AuthURL       string   // authorization endpoint, for the authorization code flow
+	TokenURL      string   // token endpoint
+	DeviceAuthURL string   // device authorization endpoint, for the device flow
+	Scopes        []string // scopes requested when signing in
+}
+
+func init() {
+	configschema.Register("auth", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the variables of the OAuth 2.0 client with the prefix, such as for App.DeclareConfig when the
+// app signs in to more than one provider.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "CLIENT_ID", Type: "string", Description: "The OAuth 2.0 client ID."},
+		{Name: prefix + "CLIENT_SECRET", Type: "string", Description: "The OAuth 2.0 client secret.", Secret: true},
+		{Name: prefix + "AUTH_URL", Type: "url", Description: "The authorization endpoint, unless discovered."},
+		{Name: prefix + "TOKEN_URL", Type: "url", Description: "The token endpoint, unless discovered."},
+		{Name: prefix + "DEVICE_AUTH_URL", Type: "url",
+			Description: "The device authorization endpoint, unless discovered."},
+		{Name: prefix + "SCOPES", Type: "string", Description: "The scopes requested, separated by spaces or commas."},
+	}
+}
+
+// FromEnv reads the OAuth 2.0 client from CLIENT_ID, CLIENT_SECRET, SCOPES, separated by spaces or commas, and the
+// AUTH_URL, TOKEN_URL, and DEVICE_AUTH_URL endpoints, with the prefix or DefaultPrefix. The endpoints left unset are
+// for Discover to find from the issuer.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := &Config{
+		ClientID:     get("CLIENT_ID"),
+		ClientSecret: get("CLIENT_SECRET"),
+		Scopes:       strings.FieldsFunc(get("SCOPES"), func(r rune) bool { return r == ' ' || r == ',' }),
+	}
+	for key, dst := range map[string]*string{
+		"AUTH_URL":        &config.AuthURL,
+		"TOKEN_URL":       &config.TokenURL,
+		"DEVICE_AUTH_URL": &config.DeviceAuthURL,
+	} {
+		if v := get(key); v != "" {
+			if !validURL(v) {
+				return nil, fmt.Errorf("auth: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = v
+		}
+	}
+	return config, nil
+}
+
+func validURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Discover sets the endpoints of the Config that are not set from the OpenID Connect discovery document of issuer,
+// such as https://accounts.example.com. The client may be nil.
+func (c *Config) Discover(ctx context.Context, client *http.Client, issuer string) error {
+	if !validURL(issuer) {
+		return fmt.Errorf("auth: invalid issuer %q", issuer)
+	}
+	endpoint := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("auth: discovery: %s", res.Status)
+	}
+
+	var doc struct {
+		AuthorizationEndpoint       string `json:"authorization_endpoint"`
+		TokenEndpoint               string `json:"token_endpoint"`
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("auth: discovery: invalid response: %v", err)
+	}
+
+	for _, field := range [...]struct{ dst, v *string }{
+		{&c.AuthURL, &doc.AuthorizationEndpoint},
+		{&c.TokenURL, &doc.TokenEndpoint},
+		{&c.DeviceAuthURL, &doc.DeviceAuthorizationEndpoint},
+	} {
+		if *field.dst == "" {
+			*field.dst = *field.v
+		}
+	}
+	if c.TokenURL == "" {
+		return fmt.Errorf("auth: discovery: %s has no token endpoint", issuer)
+	}
+	return nil
+}
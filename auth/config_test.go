@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/auth"
+)
+
+func TestFromEnv_Scopes(t *testing.T) {
+	for v, expected := range map[string][]string{
+		"":                         {},
+		"openid":                   {"openid"},
+		"openid, profile  email":   {"openid", "profile", "email"},
+		" ,openid,,offline_access": {"openid", "offline_access"},
+	} {
+		config, err := auth.FromEnv(apptest.Lookup(map[string]string{"APP_AUTH_SCOPES": v}), "")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.Scopes, v)
+	}
+}
+
+func TestFromEnv_Endpoints(t *testing.T) {
+	config, err := auth.FromEnv(apptest.Lookup(map[string]string{
+		"GITHUB_CLIENT_ID":       " cli ",
+		"GITHUB_CLIENT_SECRET":   "s3cret",
+		"GITHUB_DEVICE_AUTH_URL": "https://github.com/login/device/code",
+	}), "GITHUB_")
+	require.NoError(t, err)
+	assert.Equal(t, &auth.Config{
+		ClientID:      "cli",
+		ClientSecret:  "s3cret",
+		DeviceAuthURL: "https://github.com/login/device/code",
+		Scopes:        []string{},
+	}, config, "the endpoints left unset are for Discover")
+
+	// every endpoint is an absolute http or https URL
+	for _, key := range []string{"AUTH_URL", "TOKEN_URL", "DEVICE_AUTH_URL"} {
+		for _, v := range []string{"example.com/token", "/token", "ftp://example.com/token"} {
+			_, err := auth.FromEnv(apptest.Lookup(map[string]string{"APP_AUTH_" + key: v}), "")
+			assert.EqualError(t, err, fmt.Sprintf("auth: invalid APP_AUTH_%s %q", key, v))
+		}
+	}
+}
+
+func TestConfig_Discover(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"authorization_endpoint":"` + srv.URL + `/authorize","token_endpoint":"` + srv.URL +
+			`/token","device_authorization_endpoint":"` + srv.URL + `/device"}`))
+	}))
+	defer srv.Close()
+
+	config := &auth.Config{ClientID: "cli", TokenURL: "https://example.com/token"}
+	require.NoError(t, config.Discover(context.Background(), srv.Client(), srv.URL+"/"))
+	assert.Equal(t, &auth.Config{
+		ClientID:      "cli",
+		AuthURL:       srv.URL + "/authorize",
+		TokenURL:      "https://example.com/token",
+		DeviceAuthURL: srv.URL + "/device",
+	}, config)
+
+	assert.EqualError(t, config.Discover(context.Background(), srv.Client(), srv.URL+"/missing"),
+		"auth: discovery: 404 Not Found")
+}
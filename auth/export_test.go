@@ -0,0 +1,15 @@
+package auth
+
+import "time"
+
+// SetNow replaces the clock used to expire tokens.
+func (c *Client) SetNow(now func() time.Time) {
+	c.now = now
+}
+
+// SetDeviceIntervals replaces the polling intervals of the device flow, returning a function restoring them.
+func SetDeviceIntervals(interval, slow time.Duration) func() {
+	prevInterval, prevSlow := defaultInterval, slowDown
+	defaultInterval, slowDown = interval, slow
+	return func() { defaultInterval, slowDown = prevInterval, prevSlow }
+}
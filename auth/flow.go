@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The polling intervals of the device flow, as set by RFC 8628: the default, and what slow_down adds.
+var (
+	defaultInterval = 5 * time.Second
+	slowDown        = 5 * time.Second
+)
+
+// DeviceCode is the code the user enters to sign in with the device flow.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"` // the URI with the code filled in
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// DeviceLogin signs the user in with the device flow of RFC 8628: show is given the code the user enters on the page
+// of the provider, and DeviceLogin waits for them to do so, until the code expires or ctx is done.
+func (c *Client) DeviceLogin(ctx context.Context, show func(*DeviceCode) error) (*Token, error) {
+	form := url.Values{}
+	if len(c.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+	var code DeviceCode
+	if err := c.post(ctx, c.config.DeviceAuthURL, form, &code); err != nil {
+		return nil, err
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, errors.New("auth: response has no device code")
+	}
+	if err := show(&code); err != nil {
+		return nil, err
+	}
+
+	interval := defaultInterval
+	if code.Interval > 0 {
+		interval = time.Duration(code.Interval) * time.Second
+	}
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, &Error{Code: "expired_token", Description: "the code expired before it was entered"}
+			}
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		tok, err := c.exchange(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {code.DeviceCode},
+		})
+		var oauthErr *Error
+		switch {
+		case errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending":
+			continue
+		case errors.As(err, &oauthErr) && oauthErr.Code == "slow_down":
+			interval += slowDown
+			continue
+		case err != nil:
+			return nil, err
+		}
+		return c.login(tok)
+	}
+}
+
+// BrowserLogin signs the user in with the authorization code flow and PKCE: open is given the page of the provider
+// the user signs in on, which redirects their browser to a server BrowserLogin runs on the loopback interface with
+// the code. It waits for the redirect until ctx is done.
+func (c *Client) BrowserLogin(ctx context.Context, open func(url string) error) (*Token, error) {
+	if c.config.AuthURL == "" {
+		return nil, errors.New("auth: no authorization endpoint configured")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("auth: unable to listen for the redirect: %w", err)
+	}
+	redirectURI := "http://" + ln.Addr().String() + "/callback"
+
+	verifier, state := randomString(32), randomString(16)
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {redirectURI},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if len(c.config.Scopes) > 0 {
+		params.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
+	authURL := c.config.AuthURL
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + params.Encode()
+	} else {
+		authURL += "?" + params.Encode()
+	}
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+
+			q := r.URL.Query()
+			var res result
+			switch {
+			case q.Get("state") != state:
+				http.Error(w, "Sign in failed: the state does not match.", http.StatusBadRequest)
+				return
+			case q.Get("error") != "":
+				res.err = &Error{Code: q.Get("error"), Description: q.Get("error_description")}
+				http.Error(w, "Sign in failed: "+res.err.Error(), http.StatusForbidden)
+			case q.Get("code") == "":
+				res.err = errors.New("auth: redirect has no code")
+				http.Error(w, "Sign in failed: no code.", http.StatusBadRequest)
+			default:
+				res.code = q.Get("code")
+				_, _ = fmt.Fprintln(w, "Signed in. You can close this window and return to the terminal.")
+			}
+
+			select {
+			case results <- res:
+			default:
+			}
+		}),
+	}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	if err := open(authURL); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-results:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	tok, err := c.exchange(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.login(tok)
+}
+
+// randomString returns n random bytes, encoded for a URL.
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,338 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureVersion is the Blob service REST API version used for requests and shared access signatures.
+const azureVersion = "2021-08-06"
+
+// azureBucket speaks the Azure Blob Storage REST API authorized with a storage account shared key.
+type azureBucket struct {
+	client    *http.Client
+	endpoint  *url.URL
+	account   string
+	key       []byte
+	container string
+	now       func() time.Time
+}
+
+func openAzure(u *url.URL, client *http.Client, lookup func(string) (string, bool)) (Bucket, error) {
+	if u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return nil, errors.New("blob: azblob URLs must name only a container, e.g. azblob://container")
+	}
+
+	q := u.Query()
+	account := q.Get("account")
+	if account == "" {
+		account, _ = lookup("AZURE_STORAGE_ACCOUNT")
+	}
+	secret, _ := lookup("AZURE_STORAGE_KEY")
+	if account == "" || secret == "" {
+		return nil, errors.New("blob: AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY are required for azblob containers")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, errors.New("blob: AZURE_STORAGE_KEY is not valid base64")
+	}
+
+	// a custom endpoint, such as Azurite, includes the account in its path
+	endpoint := "https://" + account + ".blob.core.windows.net"
+	if v := q.Get("endpoint"); v != "" {
+		endpoint = v
+	}
+
+	b := &azureBucket{
+		client:    client,
+		account:   account,
+		key:       key,
+		container: u.Host,
+		now:       time.Now,
+	}
+	b.endpoint, err = url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || b.endpoint.Host == "" {
+		return nil, fmt.Errorf("blob: invalid endpoint %q", endpoint)
+	}
+
+	return b, nil
+}
+
+// url returns the URL of the blob, or of the container if key is empty.
+func (b *azureBucket) url(key string) *url.URL {
+	u := *b.endpoint
+	u.Path += "/" + b.container
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	return &u
+}
+
+func (b *azureBucket) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	res, err := b.do(ctx, http.MethodGet, b.url(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (b *azureBucket) Write(ctx context.Context, key string, r io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	res, err := b.do(ctx, http.MethodPut, b.url(key), http.Header{"X-Ms-Blob-Type": {"BlockBlob"}}, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (b *azureBucket) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	res, err := b.do(ctx, http.MethodDelete, b.url(key), nil, nil)
+	if err == ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+type azureListResult struct {
+	Blobs struct {
+		Blob []struct {
+			Name       string
+			Properties struct {
+				LastModified  string `xml:"Last-Modified"`
+				Etag          string
+				ContentLength int64 `xml:"Content-Length"`
+			}
+		}
+	}
+	NextMarker string
+}
+
+func (b *azureBucket) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	marker := ""
+
+	for {
+		u := b.url("")
+		q := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		u.RawQuery = q.Encode()
+
+		res, err := b.do(ctx, http.MethodGet, u, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result azureListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("blob: decoding list response: %v", err)
+		}
+
+		for _, blob := range result.Blobs.Blob {
+			modTime, _ := time.Parse(http.TimeFormat, blob.Properties.LastModified)
+			objects = append(objects, Object{
+				Key:     blob.Name,
+				Size:    blob.Properties.ContentLength,
+				ModTime: modTime,
+				ETag:    strings.Trim(blob.Properties.Etag, `"`),
+			})
+		}
+
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (b *azureBucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if err := checkMethod(method); err != nil {
+		return "", err
+	}
+	if expiry <= 0 {
+		return "", errors.New("blob: signed URL expiry must be positive")
+	}
+
+	permissions := map[string]string{http.MethodGet: "r", http.MethodPut: "cw", http.MethodDelete: "d"}[method]
+	expires := b.now().UTC().Add(expiry).Format("2006-01-02T15:04:05Z")
+
+	stringToSign := strings.Join([]string{
+		permissions,
+		"", // signed start
+		expires,
+		"/blob/" + b.account + "/" + b.container + "/" + key,
+		"", // signed identifier
+		"", // signed IP
+		"https,http",
+		azureVersion,
+		"b",
+		"",                 // signed snapshot time
+		"",                 // signed encryption scope
+		"", "", "", "", "", // response header overrides
+	}, "\n")
+
+	q := url.Values{
+		"sv":  {azureVersion},
+		"spr": {"https,http"},
+		"sr":  {"b"},
+		"sp":  {permissions},
+		"se":  {expires},
+		"sig": {b.sign(stringToSign)},
+	}
+
+	u := b.url(key)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// do sends the request authorized with the shared key and returns the response if it succeeded.
+func (b *azureBucket) do(
+	ctx context.Context,
+	method string,
+	u *url.URL,
+	header http.Header,
+	body []byte,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("X-Ms-Date", b.now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+	req.Header.Set("Authorization", "SharedKey "+b.account+":"+b.sign(b.stringToSign(req)))
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var e struct {
+		Code    string
+		Message string
+	}
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&e)
+	if e.Code == "" {
+		e.Code = res.Header.Get("X-Ms-Error-Code")
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && e.Code != "ContainerNotFound":
+		return nil, ErrNotExist
+	case e.Code == "":
+		return nil, fmt.Errorf("blob: %s %s: %s", method, u.Path, res.Status)
+	default:
+		return nil, fmt.Errorf("blob: %s %s: %s: %s", method, u.Path, e.Code, strings.SplitN(e.Message, "\n", 2)[0])
+	}
+}
+
+// stringToSign returns the shared key string to sign for the request.
+func (b *azureBucket) stringToSign(req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var names []string
+	for k := range req.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var headers strings.Builder
+	for _, k := range names {
+		headers.WriteString(k + ":" + strings.TrimSpace(req.Header.Get(k)) + "\n")
+	}
+
+	resource := "/" + b.account + req.URL.EscapedPath()
+	q := req.URL.Query()
+	params := make([]string, 0, len(q))
+	for k := range q {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-Md5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		headers.String() + resource,
+	}, "\n")
+}
+
+func (b *azureBucket) sign(stringToSign string) string {
+	h := hmac.New(sha256.New, b.key)
+	_, _ = h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,156 @@
+package blob_test
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/blob"
+)
+
+// fakeAzure is an in-memory Blob service for a single container that returns one blob per list page.
+type fakeAzure struct {
+	mu      sync.Mutex
+	objects map[string]string
+}
+
+func (s *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey acct:") || r.Header.Get("X-Ms-Version") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/acct/container/")
+	switch {
+	case r.URL.Path == "/acct/container" && r.URL.Query().Get("comp") == "list":
+		s.list(w, r)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.objects[key] = string(body)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		if _, ok := s.objects[key]; !ok {
+			w.Header().Set("X-Ms-Error-Code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet:
+		if v, ok := s.objects[key]; ok {
+			_, _ = io.WriteString(w, v)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "<Error><Code>BlobNotFound</Code></Error>")
+	}
+}
+
+func (s *fakeAzure) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, r.URL.Query().Get("prefix")) && k > r.URL.Query().Get("marker") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	type properties struct {
+		LastModified  string `xml:"Last-Modified"`
+		Etag          string
+		ContentLength int `xml:"Content-Length"`
+	}
+	type item struct {
+		Name       string
+		Properties properties
+	}
+	var result struct {
+		XMLName    xml.Name `xml:"EnumerationResults"`
+		Blobs      []item   `xml:"Blobs>Blob"`
+		NextMarker string
+	}
+	if len(keys) > 0 {
+		modTime := time.Unix(0, 0).UTC().Format(http.TimeFormat)
+		result.Blobs = []item{{keys[0], properties{modTime, "0x8D", len(s.objects[keys[0]])}}}
+		if len(keys) > 1 {
+			result.NextMarker = keys[0]
+		}
+	}
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func TestAzureBucket(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&fakeAzure{objects: map[string]string{}})
+	defer server.Close()
+
+	b, err := blob.Open("azblob://container?endpoint="+server.URL+"/acct", apptest.Lookup(map[string]string{
+		"AZURE_STORAGE_ACCOUNT": "acct",
+		"AZURE_STORAGE_KEY":     "c2VjcmV0",
+	}))
+	require.NoError(t, err)
+
+	require.NoError(t, b.Write(ctx, "dir/a.txt", strings.NewReader("hello")))
+	require.NoError(t, b.Write(ctx, "dir/b.txt", strings.NewReader("world!")))
+
+	r, err := b.NewReader(ctx, "dir/a.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "hello", string(data))
+
+	objects, err := b.List(ctx, "dir/")
+	require.NoError(t, err)
+	assert.Equal(t, []blob.Object{
+		{Key: "dir/a.txt", Size: 5, ModTime: time.Unix(0, 0).UTC(), ETag: "0x8D"},
+		{Key: "dir/b.txt", Size: 6, ModTime: time.Unix(0, 0).UTC(), ETag: "0x8D"},
+	}, objects)
+
+	require.NoError(t, b.Delete(ctx, "dir/a.txt"))
+	require.NoError(t, b.Delete(ctx, "dir/a.txt"))
+	_, err = b.NewReader(ctx, "dir/a.txt")
+	assert.Equal(t, blob.ErrNotExist, err)
+}
+
+func TestAzureBucket_SignedURL(t *testing.T) {
+	env := map[string]string{"AZURE_STORAGE_KEY": "c2VjcmV0"}
+	b, err := blob.Open("azblob://container?account=acct", apptest.Lookup(env))
+	require.NoError(t, err)
+	blob.SetNow(b, func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) })
+
+	raw, err := b.SignedURL(context.Background(), "dir/a.txt", "PUT", time.Hour)
+	require.NoError(t, err)
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "acct.blob.core.windows.net", u.Host)
+	assert.Equal(t, "/container/dir/a.txt", u.Path)
+	q := u.Query()
+	assert.Equal(t, "2024-01-02T04:04:05Z", q.Get("se"))
+	assert.Equal(t, "cw", q.Get("sp"))
+	assert.Equal(t, "b", q.Get("sr"))
+	assert.NotEmpty(t, q.Get("sig"))
+
+	_, err = b.SignedURL(context.Background(), "dir/a.txt", "GET", 0)
+	assert.EqualError(t, err, "blob: signed URL expiry must be positive")
+}
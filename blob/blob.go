@@ -0,0 +1,172 @@
+// Package blob provides a Bucket interface over object storage with drivers for Amazon S3, Google Cloud Storage,
+// Azure Blob Storage, and the local file system. A bucket is opened from a URL, typically read from BLOB_URL:
+//
+//	s3://bucket?region=us-east-1           AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+//	s3://bucket?endpoint=http://minio:9000 any S3 compatible service, addressed by path
+//	gs://bucket                            GOOGLE_HMAC_ACCESS_ID, GOOGLE_HMAC_SECRET (interoperability HMAC keys)
+//	azblob://container?account=name        AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_KEY
+//	file:///var/lib/app/blobs              a local directory, for development and tests
+//
+// The credentials are read from the environment variables listed next to each scheme. The region of an s3 bucket
+// without a region parameter is read from AWS_REGION or AWS_DEFAULT_REGION, and is us-east-1 when neither is set.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the location of the store, BLOB_URL.
+	DefaultPrefix = "BLOB_"
+
+	// DefaultTimeout bounds each request to a cloud store, including reading the content of an object.
+	DefaultTimeout = 5 * time.Minute
+)
+
+var (
+	// ErrNotExist is returned when an object does not exist. It matches fs.ErrNotExist.
+	ErrNotExist = fmt.Errorf("blob: object %w", fs.ErrNotExist)
+
+	// ErrNotSupported is returned by drivers that cannot perform an operation.
+	ErrNotSupported = errors.New("blob: operation not supported")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	ETag    string
+}
+
+// Bucket is a collection of objects addressed by key. Keys use "/" as a separator.
+type Bucket interface {
+	// NewReader opens the object for reading. The caller must close it.
+	NewReader(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Write stores the content read from r as the object, replacing any previous content. Cloud drivers buffer the
+	// content in memory.
+	Write(ctx context.Context, key string, r io.Reader) error
+
+	// Delete removes the object. Deleting an object that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns the objects whose key starts with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// SignedURL returns a URL granting the bearer access to the object with the HTTP method (GET, PUT, or DELETE)
+	// until the expiry elapses.
+	SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error)
+}
+
+// Config describes a Bucket and how its store is reached.
+type Config struct {
+	URL     string        // the bucket: a file, s3, gs, or azblob URL
+	Timeout time.Duration // bound on each request to a cloud store; DefaultTimeout when zero
+	Client  *http.Client  // client of the requests to a cloud store; one bounded by Timeout when nil
+}
+
+// Open returns the Bucket described by rawurl with the default Config. The lookup function, typically App.LookupEnv,
+// supplies credentials.
+func Open(rawurl string, lookup func(string) (string, bool)) (Bucket, error) {
+	return New(Config{URL: rawurl}, lookup)
+}
+
+// New returns the Bucket described by config. The lookup function, typically App.LookupEnv, supplies credentials.
+func New(config Config, lookup func(string) (string, bool)) (Bucket, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("blob: invalid URL %q", config.URL)
+	}
+
+	client := config.Client
+	if client == nil {
+		if config.Timeout <= 0 {
+			config.Timeout = DefaultTimeout
+		}
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	switch u.Scheme {
+	case "file":
+		return openFile(u)
+	case "s3":
+		return openS3(u, client, lookup)
+	case "gs":
+		return openGCS(u, client, lookup)
+	case "azblob":
+		return openAzure(u, client, lookup)
+	default:
+		return nil, fmt.Errorf("blob: unsupported scheme %q", u.Scheme)
+	}
+}
+
+func init() {
+	configschema.Register("blob", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes URL and TIMEOUT with the prefix. The credentials are read without the prefix from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION, and AWS_DEFAULT_REGION for s3 buckets,
+// GOOGLE_HMAC_ACCESS_ID and GOOGLE_HMAC_SECRET for gs buckets, and AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY for
+// azblob containers, which are not included.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "URL", Type: "url", Description: "The bucket: a file, s3, gs, or azblob URL."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a request to the store may take, reading the content of an object included."},
+	}
+}
+
+// FromEnv opens the Bucket at the URL of the variable URL, its requests bounded by TIMEOUT, with the prefix or
+// DefaultPrefix. The credentials of the store are looked up as Open does.
+func FromEnv(lookup func(string) (string, bool), prefix string) (Bucket, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{URL: get("URL")}
+	if v := get("TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("blob: invalid %sTIMEOUT %q", prefix, v)
+		}
+		config.Timeout = d
+	}
+
+	if config.URL == "" {
+		return nil, fmt.Errorf("blob: %sURL is required", prefix)
+	}
+
+	return New(config, lookup)
+}
+
+func checkMethod(method string) error {
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete:
+		return nil
+	default:
+		return fmt.Errorf("blob: cannot sign %s requests", method)
+	}
+}
+
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("blob: invalid key %q", key)
+	}
+	return nil
+}
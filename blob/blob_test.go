@@ -0,0 +1,100 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/blob"
+)
+
+func TestFromEnv(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := blob.FromEnv(apptest.Lookup(map[string]string{"BLOB_URL": "file://" + dir}), "")
+	assert.NoError(t, err)
+	assert.NotNil(t, b)
+
+	b, err = blob.FromEnv(apptest.Lookup(map[string]string{"UPLOADS_URL": " file://" + dir + " "}), "UPLOADS_")
+	assert.NoError(t, err)
+	assert.NotNil(t, b)
+
+	b, err = blob.FromEnv(apptest.Lookup(nil), "")
+	assert.Nil(t, b)
+	assert.EqualError(t, err, "blob: BLOB_URL is required")
+
+	b, err = blob.FromEnv(apptest.Lookup(map[string]string{"BLOB_URL": "file://" + dir, "BLOB_TIMEOUT": "soon"}), "")
+	assert.Nil(t, b)
+	assert.EqualError(t, err, `blob: invalid BLOB_TIMEOUT "soon"`)
+}
+
+func TestNew_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	env := apptest.Lookup(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"})
+	b, err := blob.New(blob.Config{URL: "s3://bucket?endpoint=" + server.URL, Timeout: 50 * time.Millisecond}, env)
+	require.NoError(t, err)
+
+	_, err = b.NewReader(context.Background(), "key")
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
+
+func TestNew_Client(t *testing.T) {
+	server := httptest.NewTLSServer(&fakeS3{bucket: "bucket", objects: map[string]string{"key": "value"}})
+	defer server.Close()
+
+	env := apptest.Lookup(map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"})
+	b, err := blob.New(blob.Config{URL: "s3://bucket?endpoint=" + server.URL, Client: server.Client()}, env)
+	require.NoError(t, err)
+
+	r, err := b.NewReader(context.Background(), "key")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "value", string(data))
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	aws := map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"}
+	azure := map[string]string{"AZURE_STORAGE_ACCOUNT": "acct", "AZURE_STORAGE_KEY": "c2VjcmV0"}
+
+	tests := []struct {
+		url string
+		env map[string]string
+		err string
+	}{
+		{"ftp://host/dir", nil, `blob: unsupported scheme "ftp"`},
+		{"file://host/dir", nil, "blob: file URLs must name an absolute directory, e.g. file:///var/lib/blobs"},
+		{"s3://bucket", nil, "blob: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for s3 buckets"},
+		{"s3://bucket/dir", aws, "blob: s3 URLs must name only a bucket, e.g. s3://bucket"},
+		{"s3://bucket?endpoint=minio", aws, `blob: invalid endpoint "minio"`},
+		{"gs://bucket", nil, "blob: GOOGLE_HMAC_ACCESS_ID and GOOGLE_HMAC_SECRET are required for gs buckets"},
+		{"azblob://container", nil,
+			"blob: AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY are required for azblob containers"},
+		{"azblob://container/dir", azure, "blob: azblob URLs must name only a container, e.g. azblob://container"},
+		{"azblob://container", map[string]string{"AZURE_STORAGE_ACCOUNT": "acct", "AZURE_STORAGE_KEY": "!"},
+			"blob: AZURE_STORAGE_KEY is not valid base64"},
+	}
+
+	for _, tt := range tests {
+		b, err := blob.Open(tt.url, apptest.Lookup(tt.env))
+		assert.Nil(t, b, tt.url)
+		assert.EqualError(t, err, tt.err, tt.url)
+	}
+}
@@ -0,0 +1,128 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// fileBucket stores objects as files below a directory.
+type fileBucket struct {
+	dir string
+}
+
+func openFile(u *url.URL) (Bucket, error) {
+	dir := filepath.FromSlash(u.Path)
+	if u.Host != "" || dir == "" {
+		return nil, errors.New("blob: file URLs must name an absolute directory, e.g. file:///var/lib/blobs")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &fileBucket{dir: dir}, nil
+}
+
+func (b *fileBucket) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if !fs.ValidPath(key) {
+		return "", fmt.Errorf("blob: invalid key %q", key)
+	}
+	return filepath.Join(b.dir, filepath.FromSlash(key)), nil
+}
+
+func (b *fileBucket) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+func (b *fileBucket) Write(ctx context.Context, key string, r io.Reader) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	return atomicfile.Write(p, 0o600, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+func (b *fileBucket) Delete(ctx context.Context, key string) error {
+	p, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *fileBucket) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+
+	err := filepath.WalkDir(b.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), atomicfile.TempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// SignedURL is not supported: local files have no server to present the signature to.
+func (b *fileBucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	return "", ErrNotSupported
+}
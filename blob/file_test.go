@@ -0,0 +1,71 @@
+package blob_test
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/blob"
+)
+
+func TestFileBucket(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, err := blob.Open("file://"+filepath.ToSlash(dir), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Write(ctx, "a/one.txt", strings.NewReader("one")))
+	require.NoError(t, b.Write(ctx, "a/two.txt", strings.NewReader("two")))
+	require.NoError(t, b.Write(ctx, "b.txt", strings.NewReader("b")))
+	require.NoError(t, b.Write(ctx, "a/one.txt", strings.NewReader("uno")))
+
+	r, err := b.NewReader(ctx, "a/one.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "uno", string(data))
+
+	objects, err := b.List(ctx, "a/")
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "a/one.txt", objects[0].Key)
+	assert.Equal(t, int64(3), objects[0].Size)
+	assert.Equal(t, "a/two.txt", objects[1].Key)
+
+	objects, err = b.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, objects, 3)
+
+	require.NoError(t, b.Delete(ctx, "a/one.txt"))
+	require.NoError(t, b.Delete(ctx, "a/one.txt"))
+
+	_, err = b.NewReader(ctx, "a/one.txt")
+	assert.Equal(t, blob.ErrNotExist, err)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = b.NewReader(ctx, "../escape")
+	assert.EqualError(t, err, `blob: invalid key "../escape"`)
+
+	_, err = b.SignedURL(ctx, "b.txt", "GET", time.Minute)
+	assert.Equal(t, blob.ErrNotSupported, err)
+
+	// failed writes leave no trace behind
+	err = b.Write(ctx, "c.txt", io.MultiReader(strings.NewReader("partial"), errReader{}))
+	assert.Error(t, err)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
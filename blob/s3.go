@@ -0,0 +1,383 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest validity of a SigV4 presigned URL.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// s3Bucket speaks the S3 REST API signed with AWS Signature Version 4. Google Cloud Storage serves the same API to
+// clients authenticating with interoperability HMAC keys.
+type s3Bucket struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	region    string
+	accessKey string
+	secretKey string
+	token     string
+	now       func() time.Time
+}
+
+func openS3(u *url.URL, client *http.Client, lookup func(string) (string, bool)) (Bucket, error) {
+	q := u.Query()
+
+	region := q.Get("region")
+	for _, key := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region == "" {
+			region, _ = lookup(key)
+		}
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	accessKey, _ := lookup("AWS_ACCESS_KEY_ID")
+	secretKey, _ := lookup("AWS_SECRET_ACCESS_KEY")
+	token, _ := lookup("AWS_SESSION_TOKEN")
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("blob: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for s3 buckets")
+	}
+
+	endpoint := "https://s3." + region + ".amazonaws.com"
+	if region == "us-east-1" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+
+	return newS3Bucket(u, client, endpoint, region, accessKey, secretKey, token)
+}
+
+func openGCS(u *url.URL, client *http.Client, lookup func(string) (string, bool)) (Bucket, error) {
+	accessKey, _ := lookup("GOOGLE_HMAC_ACCESS_ID")
+	secretKey, _ := lookup("GOOGLE_HMAC_SECRET")
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.New("blob: GOOGLE_HMAC_ACCESS_ID and GOOGLE_HMAC_SECRET are required for gs buckets")
+	}
+
+	return newS3Bucket(u, client, "https://storage.googleapis.com", "auto", accessKey, secretKey, "")
+}
+
+func newS3Bucket(u *url.URL, c *http.Client, endpoint, region, accessKey, secretKey, token string) (*s3Bucket, error) {
+	if u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return nil, fmt.Errorf("blob: %s URLs must name only a bucket, e.g. %s://bucket", u.Scheme, u.Scheme)
+	}
+
+	b := &s3Bucket{
+		client:    c,
+		bucket:    u.Host,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		token:     token,
+		now:       time.Now,
+	}
+
+	// a custom endpoint, such as MinIO, is addressed by path rather than by virtual host
+	if v := u.Query().Get("endpoint"); v != "" {
+		endpoint = v
+		b.pathStyle = true
+	}
+
+	var err error
+	b.endpoint, err = url.Parse(endpoint)
+	if err != nil || b.endpoint.Host == "" {
+		return nil, fmt.Errorf("blob: invalid endpoint %q", endpoint)
+	}
+
+	return b, nil
+}
+
+// url returns the URL of the object, or of the bucket if key is empty.
+func (b *s3Bucket) url(key string) *url.URL {
+	u := &url.URL{Scheme: b.endpoint.Scheme, Host: b.endpoint.Host, Path: "/" + key}
+	if b.pathStyle {
+		u.Path = "/" + b.bucket + u.Path
+	} else {
+		u.Host = b.bucket + "." + u.Host
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	return u
+}
+
+func (b *s3Bucket) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	res, err := b.do(ctx, http.MethodGet, b.url(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (b *s3Bucket) Write(ctx context.Context, key string, r io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	res, err := b.do(ctx, http.MethodPut, b.url(key), body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (b *s3Bucket) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+
+	res, err := b.do(ctx, http.MethodDelete, b.url(key), nil)
+	if err == ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+		ETag         string
+		Size         int64
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (b *s3Bucket) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+
+	for {
+		u := b.url("")
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = awsQuery(q)
+
+		res, err := b.do(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("blob: decoding list response: %v", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, Object{
+				Key:     c.Key,
+				Size:    c.Size,
+				ModTime: c.LastModified,
+				ETag:    strings.Trim(c.ETag, `"`),
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (b *s3Bucket) SignedURL(ctx context.Context, key, method string, expiry time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if err := checkMethod(method); err != nil {
+		return "", err
+	}
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return "", fmt.Errorf("blob: signed URL expiry must be between 1s and %s", maxPresignExpiry)
+	}
+
+	return b.presign(method, b.url(key), expiry), nil
+}
+
+func (b *s3Bucket) presign(method string, u *url.URL, expiry time.Duration) string {
+	t := b.now().UTC()
+	scope := t.Format("20060102") + "/" + b.region + "/s3/aws4_request"
+
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", b.accessKey+"/"+scope)
+	q.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.FormatInt(int64(expiry/time.Second), 10))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if b.token != "" {
+		q.Set("X-Amz-Security-Token", b.token)
+	}
+	query := awsQuery(q)
+
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		query,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	u.RawQuery = query + "&X-Amz-Signature=" + b.signature(t, scope, canonical)
+	return u.String()
+}
+
+// do sends the signed request and returns the response if it succeeded.
+func (b *s3Bucket) do(ctx context.Context, method string, u *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+	req.ContentLength = int64(len(body))
+	if body == nil {
+		req.Body = http.NoBody
+	}
+
+	b.sign(req, body)
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var e struct {
+		Code    string
+		Message string
+	}
+	_ = xml.NewDecoder(io.LimitReader(res.Body, 1<<16)).Decode(&e)
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && e.Code != "NoSuchBucket":
+		return nil, ErrNotExist
+	case e.Code == "":
+		return nil, fmt.Errorf("blob: %s %s: %s", method, u.Path, res.Status)
+	default:
+		return nil, fmt.Errorf("blob: %s %s: %s: %s", method, u.Path, e.Code, e.Message)
+	}
+}
+
+func (b *s3Bucket) sign(req *http.Request, body []byte) {
+	t := b.now().UTC()
+	scope := t.Format("20060102") + "/" + b.region + "/s3/aws4_request"
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+
+	req.Header.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.token != "" {
+		req.Header.Set("X-Amz-Security-Token", b.token)
+	}
+
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headers := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + t.Format("20060102T150405Z") + "\n"
+	if b.token != "" {
+		names = append(names, "x-amz-security-token")
+		headers += "x-amz-security-token:" + b.token + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		awsQuery(req.URL.Query()),
+		headers,
+		signed,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signed, b.signature(t, scope, canonical)))
+}
+
+func (b *s3Bucket) signature(t time.Time, scope, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + t.Format("20060102T150405Z") + "\n" + scope + "\n" +
+		hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), t.Format("20060102"))
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsQuery returns the canonical query string: sorted by key and value, with every value escaped.
+func awsQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, awsEscape(k, false)+"="+awsEscape(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent-encodes every byte except the unreserved characters and, if keepSlash, the slash.
+func awsEscape(s string, keepSlash bool) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && keepSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,316 @@
+// Package cache provides a typed in-memory cache with least recently used eviction, expiring entries, and loading
+// that calls the loader once for concurrent misses of the same key:
+//
+//	users := cache.New[int64, *User](&cache.Config{Name: "users", Capacity: 10000, TTL: time.Minute}, a.Metrics())
+//	user, err := users.GetOrLoad(ctx, id, db.LoadUser)
+//
+// A Cache with a Persist file is an app.Server: registered with App.Register, it restores its entries from the App
+// state directory when bound and saves them when shut down.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// Cache is a concurrency safe map of K to V. Expired entries are dropped when they are read or evicted.
+type Cache[K comparable, V any] struct {
+	config   Config
+	registry *metrics.Registry
+	now      func() time.Time
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	lru   *list.List // front is the most recently used
+	calls map[K]*call[V]
+
+	path     string
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+type entry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Expires time.Time
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New returns an empty Cache. The registry may be nil.
+func New[K comparable, V any](config *Config, registry *metrics.Registry) *Cache[K, V] {
+	c := &Cache[K, V]{
+		config:   *config,
+		registry: registry,
+		now:      time.Now,
+		items:    make(map[K]*list.Element),
+		lru:      list.New(),
+		calls:    make(map[K]*call[V]),
+		stopping: make(chan struct{}),
+	}
+	if c.config.Name == "" {
+		c.config.Name = "default"
+	}
+	return c
+}
+
+// Get returns the value of key and whether it was found.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.get(key); ok {
+		c.count("cache_hits_total")
+		return v, true
+	}
+
+	c.count("cache_misses_total")
+	var zero V
+	return zero, false
+}
+
+// Set stores the value of key, expiring after the configured TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetTTL(key, value, c.config.TTL)
+}
+
+// SetTTL stores the value of key, expiring after ttl, or never if ttl is zero.
+func (c *Cache[K, V]) SetTTL(key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(entry[K, V]{Key: key, Value: value, Expires: expires})
+}
+
+// Delete removes key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len returns the number of entries, including expired entries not yet dropped.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge removes every entry.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*list.Element)
+	c.lru.Init()
+	c.gauge()
+}
+
+// GetOrLoad returns the value of key, calling load to produce and store it on a miss. Concurrent misses of the same
+// key wait for a single call, which runs with the context of the first caller; the others stop waiting when their
+// own context is done. Errors are returned to every waiting caller and are not cached.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(context.Context, K) (V, error)) (V, error) {
+	c.mu.Lock()
+	if v, ok := c.get(key); ok {
+		c.count("cache_hits_total")
+		c.mu.Unlock()
+		return v, nil
+	}
+	c.count("cache_misses_total")
+
+	cl, loading := c.calls[key]
+	if !loading {
+		cl = &call[V]{done: make(chan struct{})}
+		c.calls[key] = cl
+	}
+	c.mu.Unlock()
+
+	if loading {
+		select {
+		case <-cl.done:
+			return cl.value, cl.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+
+	defer close(cl.done)
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+	}()
+
+	// a panicking loader must not leave the waiting callers blocked
+	cl.err = errors.New("cache: loader panicked")
+	cl.value, cl.err = load(ctx, key)
+	if cl.err != nil {
+		c.count("cache_load_errors_total")
+		return cl.value, cl.err
+	}
+
+	c.Set(key, cl.value)
+	return cl.value, nil
+}
+
+// Save writes the unexpired entries, most recently used first, to w with encoding/gob. K and V must be encodable.
+func (c *Cache[K, V]) Save(w io.Writer) error {
+	c.mu.Lock()
+	now := c.now()
+	entries := make([]entry[K, V], 0, c.lru.Len())
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		if e := el.Value.(*entry[K, V]); !expired(e, now) {
+			entries = append(entries, *e)
+		}
+	}
+	c.mu.Unlock()
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Load adds the unexpired entries written by Save, keeping their recency and expiry, up to the capacity.
+func (c *Cache[K, V]) Load(r io.Reader) error {
+	var entries []entry[K, V]
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.Capacity > 0 && len(entries) > c.config.Capacity {
+		entries = entries[:c.config.Capacity]
+	}
+
+	now := c.now()
+	for idx := len(entries) - 1; idx >= 0; idx-- {
+		if !expired(&entries[idx], now) {
+			c.set(entries[idx])
+		}
+	}
+	return nil
+}
+
+// Bind restores the entries saved in the Persist file of the App state directory, if any. An unreadable file is
+// logged and ignored.
+func (c *Cache[K, V]) Bind(a *app.App) error {
+	if c.config.Persist == "" {
+		return nil
+	}
+
+	dir, err := a.StateDir()
+	if err != nil {
+		return err
+	}
+	c.path = filepath.Join(dir, c.config.Persist)
+
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := c.Load(f); err != nil {
+		_ = a.Logger().Warnf("cache %s: discarding unreadable %s: %v", c.config.Name, c.path, err)
+	}
+	return nil
+}
+
+// Serve waits until the Cache is shut down.
+func (c *Cache[K, V]) Serve() error {
+	<-c.stopping
+	return nil
+}
+
+// Shutdown saves the entries to the Persist file, if any.
+func (c *Cache[K, V]) Shutdown(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stopping) })
+
+	if c.path == "" {
+		return nil
+	}
+
+	return atomicfile.Write(c.path, 0o600, c.Save)
+}
+
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	el, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	e := el.Value.(*entry[K, V])
+	if expired(e, c.now()) {
+		c.remove(el)
+		var zero V
+		return zero, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e.Value, true
+}
+
+func (c *Cache[K, V]) set(e entry[K, V]) {
+	if el, ok := c.items[e.Key]; ok {
+		*el.Value.(*entry[K, V]) = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.items[e.Key] = c.lru.PushFront(&e)
+
+	for c.config.Capacity > 0 && c.lru.Len() > c.config.Capacity {
+		c.remove(c.lru.Back())
+		c.count("cache_evictions_total")
+	}
+	c.gauge()
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).Key)
+	c.gauge()
+}
+
+func (c *Cache[K, V]) count(name string) {
+	if c.registry != nil {
+		c.registry.Counter(name, metrics.Labels{"cache": c.config.Name}).Inc()
+	}
+}
+
+func (c *Cache[K, V]) gauge() {
+	if c.registry != nil {
+		c.registry.Gauge("cache_entries", metrics.Labels{"cache": c.config.Name}).Set(float64(c.lru.Len()))
+	}
+}
+
+func expired[K comparable, V any](e *entry[K, V], now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
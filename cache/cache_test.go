@@ -0,0 +1,170 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+func TestCache_LRU(t *testing.T) {
+	registry := metrics.NewRegistry()
+	c := cache.New[string, int](&cache.Config{Name: "test", Capacity: 2}, registry)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, _ = c.Get("a") // b is now the least recently used
+	c.Set("c", 3)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.Len())
+
+	c.Delete("a")
+	assert.Equal(t, 1, c.Len())
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+
+	labels := metrics.Labels{"cache": "test"}
+	assert.Equal(t, 2.0, registry.Counter("cache_hits_total", labels).Value())
+	assert.Equal(t, 1.0, registry.Counter("cache_misses_total", labels).Value())
+	assert.Equal(t, 1.0, registry.Counter("cache_evictions_total", labels).Value())
+	assert.Equal(t, 0.0, registry.Gauge("cache_entries", labels).Value())
+}
+
+func TestCache_TTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := cache.New[string, string](&cache.Config{TTL: time.Minute}, nil)
+	c.SetNow(func() time.Time { return now })
+
+	c.Set("short", "lived")
+	c.SetTTL("long", "lived", time.Hour)
+	c.SetTTL("forever", "lived", 0)
+
+	now = now.Add(time.Minute)
+	_, ok := c.Get("short")
+	assert.False(t, ok)
+	_, ok = c.Get("long")
+	assert.True(t, ok)
+
+	now = now.Add(24 * time.Hour)
+	_, ok = c.Get("long")
+	assert.False(t, ok)
+	_, ok = c.Get("forever")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+	c := cache.New[int, int](cache.DefaultConfig(), nil)
+
+	var calls int32
+	release := make(chan struct{})
+	load := func(ctx context.Context, key int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return key * 2, nil
+	}
+
+	const n = 10
+	results := make(chan int, n)
+	wg := new(sync.WaitGroup)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), 21, load)
+			assert.NoError(t, err)
+			results <- v
+		}()
+	}
+
+	// the waiters pile up behind the first call
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		assert.Equal(t, 42, v)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	v, err := c.GetOrLoad(context.Background(), 21, load)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// errors are not cached
+	boom := errors.New("boom")
+	_, err = c.GetOrLoad(context.Background(), 1, func(context.Context, int) (int, error) { return 0, boom })
+	assert.Equal(t, boom, err)
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+}
+
+func TestCache_SaveLoad(t *testing.T) {
+	c := cache.New[string, int](&cache.Config{Capacity: 3}, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.SetTTL("expired", 4, time.Nanosecond)
+
+	buf := new(bytes.Buffer)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, c.Save(buf))
+
+	// the smaller cache keeps the most recently used entries
+	restored := cache.New[string, int](&cache.Config{Capacity: 2}, nil)
+	require.NoError(t, restored.Load(buf))
+	assert.Equal(t, 2, restored.Len())
+	_, ok := restored.Get("a")
+	assert.False(t, ok)
+	v, ok := restored.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, v)
+
+	assert.Error(t, restored.Load(bytes.NewReader([]byte("garbage"))))
+}
+
+func TestCache_Persist(t *testing.T) {
+	dir := t.TempDir()
+	a := &app.App{Environment: []string{"APP_STATE_DIR=" + dir}, Context: context.Background(), Stderr: io.Discard}
+	config := &cache.Config{Capacity: 10, Persist: "test.gob"}
+
+	c := cache.New[string, int](config, nil)
+	require.NoError(t, c.Bind(a))
+	c.Set("a", 1)
+	require.NoError(t, c.Shutdown(context.Background()))
+	assert.FileExists(t, filepath.Join(dir, "test.gob"))
+
+	c = cache.New[string, int](config, nil)
+	require.NoError(t, c.Bind(a))
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// an unreadable file does not prevent the app from starting
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.gob"), []byte("garbage"), 0o600))
+	c = cache.New[string, int](config, nil)
+	require.NoError(t, c.Bind(a))
+	assert.Equal(t, 0, c.Len())
+	require.NoError(t, a.Logger().ShutdownLoggers())
+}
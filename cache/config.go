@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the cache sizing, as in APP_CACHE_CAPACITY; a second cache reads its own.
+	DefaultPrefix = "APP_CACHE_"
+
+	// DefaultCapacity is the number of entries kept before the least recently used are evicted.
+	DefaultCapacity = 1000
+)
+
+// Config describes the size and expiry of a Cache.
+type Config struct {
+	Name     string        // label of the cache metrics; "default" when empty
+	Capacity int           // entries kept before the least recently used are evicted; unbounded when zero
+	TTL      time.Duration // lifetime of entries added with Set; entries never expire when zero
+	Persist  string        // file in the App state directory that keeps the entries across restarts
+}
+
+// DefaultConfig returns a Config with the default capacity, no expiry, and no persistence.
+func DefaultConfig() *Config {
+	return &Config{Capacity: DefaultCapacity}
+}
+
+func init() {
+	configschema.Register("cache", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the settings of a Cache with the prefix, such as for App.DeclareConfig for a second cache.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "CAPACITY", Type: "int", Default: strconv.Itoa(DefaultCapacity),
+			Description: "The entries kept before the least recently used are evicted."},
+		{Name: prefix + "TTL", Type: "duration",
+			Description: "How long entries added with Set are kept; 0 keeps them until evicted."},
+		{Name: prefix + "PERSIST", Type: "string",
+			Description: "The file in the state directory that keeps the entries across restarts."},
+	}
+}
+
+// FromEnv reads the CAPACITY, TTL, and PERSIST of a Cache, with the prefix or DefaultPrefix. PERSIST is a file name
+// without directories, kept in the state directory of the app.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("CAPACITY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("cache: invalid %sCAPACITY %q", prefix, v)
+		}
+		config.Capacity = n
+	}
+
+	if v := get("TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("cache: invalid %sTTL %q", prefix, v)
+		}
+		config.TTL = d
+	}
+
+	if v := get("PERSIST"); v != "" {
+		if filepath.Base(v) != v || v == "." || v == ".." {
+			return nil, fmt.Errorf("cache: invalid %sPERSIST %q", prefix, v)
+		}
+		config.Persist = v
+	}
+
+	return config, nil
+}
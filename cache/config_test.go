@@ -0,0 +1,43 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/cache"
+)
+
+func TestFromEnv_Unbounded(t *testing.T) {
+	// zero turns the bound and the expiry off rather than falling back to the defaults
+	config, err := cache.FromEnv(apptest.Lookup(map[string]string{
+		"SESSIONS_CAPACITY": "0",
+		"SESSIONS_TTL":      "0s",
+	}), "SESSIONS_")
+	require.NoError(t, err)
+	assert.Equal(t, &cache.Config{}, config)
+
+	config, err = cache.FromEnv(apptest.Lookup(map[string]string{"APP_CACHE_TTL": " 90s "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, &cache.Config{Capacity: cache.DefaultCapacity, TTL: 90 * time.Second}, config)
+
+	for key, v := range map[string]string{"CAPACITY": "-1", "TTL": "-1m"} {
+		_, err := cache.FromEnv(apptest.Lookup(map[string]string{"APP_CACHE_" + key: v}), "")
+		assert.EqualError(t, err, "cache: invalid APP_CACHE_"+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Persist(t *testing.T) {
+	config, err := cache.FromEnv(apptest.Lookup(map[string]string{"APP_CACHE_PERSIST": "users.gob"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "users.gob", config.Persist)
+
+	// the file stays in the state directory of the app
+	for _, v := range []string{".", "..", "../users.gob", "cache/users.gob", "/var/lib/users.gob"} {
+		_, err := cache.FromEnv(apptest.Lookup(map[string]string{"APP_CACHE_PERSIST": v}), "")
+		assert.EqualError(t, err, `cache: invalid APP_CACHE_PERSIST "`+v+`"`, v)
+	}
+}
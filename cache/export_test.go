@@ -0,0 +1,8 @@
+package cache
+
+import "time"
+
+// SetNow replaces the clock used to expire entries.
+func (c *Cache[K, V]) SetNow(now func() time.Time) {
+	c.now = now
+}
@@ -0,0 +1,243 @@
+// Package chaos injects faults, delays, errors, and dropped connections, into the HTTP servers and clients of the app
+// and its database connections, to test how it copes with them in staging. Nothing is injected unless
+// APP_CHAOS_ENABLED is true:
+//
+//	config, err := chaos.FromEnv(a.LookupEnv, "")
+//	if err != nil {
+//		return err
+//	}
+//	injector := chaos.Install(a, config)
+//	db := sql.OpenDB(injector.Connector(connector))
+//
+// Install applies the Middleware to every HTTPServer and the Transport to every client made with App.HTTPTransport,
+// such as those of restclient. Each fault is injected at random, with the probability configured for it, and counted
+// in the chaos_faults_total{target, fault} metric. The health checks, DefaultExempt, are spared unless
+// APP_CHAOS_EXEMPT lists other paths.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// ErrInjected is the cause of the errors injected.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// fault is what is injected into a request.
+type fault int
+
+const (
+	noFault fault = iota
+	errorFault
+	dropFault
+)
+
+// Injector injects the faults of a Config.
+type Injector struct {
+	config   Config
+	registry *metrics.Registry
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New returns an Injector of the faults config describes, counting them in registry.
+func New(config *Config, registry *metrics.Registry) *Injector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &Injector{
+		config:   *config,
+		registry: registry,
+		rng:      rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Install returns the Injector of config, adding its Middleware to the HTTP servers of the app and its Transport to
+// the HTTP clients when it is Enabled.
+func Install(a *app.App, config *Config) *Injector {
+	i := New(config, a.Metrics())
+	if !config.Enabled {
+		return i
+	}
+
+	targets := make([]string, len(config.Targets))
+	for idx, t := range config.Targets {
+		targets[idx] = string(t)
+	}
+	_ = a.Logger().Warnm(gomol.NewAttrsFromMap(map[string]interface{}{
+		"targets":      targets,
+		"latency":      config.Latency.String(),
+		"latency_rate": config.LatencyRate,
+		"error_rate":   config.ErrorRate,
+		"drop_rate":    config.DropRate,
+	}), "chaos mode enabled, faults will be injected")
+
+	a.WrapHTTPServers(i.Middleware)
+	a.WrapHTTPClients(i.Transport)
+	return i
+}
+
+// targets reports whether faults are injected into t.
+func (i *Injector) targets(t Target) bool {
+	if !i.config.Enabled {
+		return false
+	}
+	for _, target := range i.config.Targets {
+		if target == t {
+			return true
+		}
+	}
+	return false
+}
+
+// inject delays the work on target t at random, returning the error of ctx if it is done first, and then picks the
+// fault injected into it, if any.
+func (i *Injector) inject(ctx context.Context, t Target) (fault, error) {
+	i.mu.Lock()
+	delay := time.Duration(0)
+	if i.rng.Float64() < i.config.LatencyRate {
+		delay = time.Duration(i.rng.Int64N(int64(i.config.Latency))) + 1
+	}
+	f := noFault
+	switch roll := i.rng.Float64(); {
+	case roll < i.config.DropRate:
+		f = dropFault
+	case roll < i.config.DropRate+i.config.ErrorRate:
+		f = errorFault
+	}
+	i.mu.Unlock()
+
+	if delay > 0 {
+		i.count(t, "latency")
+		if err := sleep(ctx, delay); err != nil {
+			return noFault, err
+		}
+	}
+	switch f {
+	case errorFault:
+		i.count(t, "error")
+	case dropFault:
+		i.count(t, "drop")
+	}
+	return f, nil
+}
+
+func (i *Injector) count(t Target, name string) {
+	i.registry.Counter("chaos_faults_total", metrics.Labels{"target": string(t), "fault": name}).Inc()
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// exempt reports whether path is Exempt.
+func (i *Injector) exempt(path string) bool {
+	for _, p := range i.config.Exempt {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns a handler injecting faults into the requests before they reach next, unless their path is
+// Exempt: the errors are answered with ErrorStatus problem details, and the connections dropped are aborted.
+func (i *Injector) Middleware(next http.Handler) http.Handler {
+	if !i.targets(Server) {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i.exempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := i.inject(r.Context(), Server)
+		switch {
+		case err != nil:
+			// the client is gone
+			return
+		case f == dropFault:
+			panic(http.ErrAbortHandler)
+		case f == errorFault:
+			problem.Write(w, problem.New(i.config.ErrorStatus, "%v", ErrInjected))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Transport returns a RoundTripper injecting faults into the requests before next, or http.DefaultTransport if nil,
+// sends them: the errors are ErrorStatus responses, and the connections dropped are errors wrapping ErrInjected.
+func (i *Injector) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !i.targets(Client) {
+		return next
+	}
+	return &transport{injector: i, next: next}
+}
+
+type transport struct {
+	injector *Injector
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f, err := t.injector.inject(req.Context(), Client)
+	if err == nil && f == noFault {
+		return t.next.RoundTrip(req)
+	}
+
+	// a RoundTripper closes the body of the request, even when it fails
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case f == dropFault:
+		return nil, fmt.Errorf("%w: connection to %s dropped", ErrInjected, req.URL.Host)
+	}
+
+	// a problem of plain values always encodes
+	status := t.injector.config.ErrorStatus
+	body, _ := json.Marshal(problem.New(status, "%v", ErrInjected))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {problem.ContentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
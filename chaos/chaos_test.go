@@ -0,0 +1,158 @@
+package chaos_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/chaos"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "ok")
+})
+
+func enabled() *chaos.Config {
+	config := chaos.DefaultConfig()
+	config.Enabled = true
+	config.Seed = 1
+	return config
+}
+
+func faults(registry *metrics.Registry, target, fault string) float64 {
+	return registry.Counter("chaos_faults_total", metrics.Labels{"target": target, "fault": fault}).Value()
+}
+
+func TestInjector_Middleware(t *testing.T) {
+	config := enabled()
+	config.ErrorRate = 1
+	registry := metrics.NewRegistry()
+	srv := httptest.NewServer(chaos.New(config, registry).Middleware(ok))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/users")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `"detail":"chaos: injected fault"`)
+	assert.Equal(t, 1.0, faults(registry, "server", "error"))
+
+	// the health checks are exempt by default
+	for _, path := range []string{"/healthz", "/readyz"} {
+		res, err = http.Get(srv.URL + path)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode, path)
+	}
+}
+
+func TestInjector_Middleware_Drop(t *testing.T) {
+	config := enabled()
+	config.DropRate = 1
+	srv := httptest.NewServer(chaos.New(config, metrics.NewRegistry()).Middleware(ok))
+	defer srv.Close()
+
+	_, err := http.Get(srv.URL)
+	assert.Error(t, err)
+}
+
+func TestInjector_Middleware_Latency(t *testing.T) {
+	config := enabled()
+	config.Latency = 20 * time.Millisecond
+	config.LatencyRate = 1
+	registry := metrics.NewRegistry()
+	handler := chaos.New(config, registry).Middleware(ok)
+
+	for range 5 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "ok", w.Body.String())
+	}
+	assert.Equal(t, 5.0, faults(registry, "server", "latency"))
+
+	// the request is given up on when the client is gone
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestInjector_Disabled(t *testing.T) {
+	config := enabled()
+	config.Enabled = false
+	config.ErrorRate = 1
+	i := chaos.New(config, metrics.NewRegistry())
+
+	w := httptest.NewRecorder()
+	i.Middleware(ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.DefaultTransport, i.Transport(nil))
+
+	// only the targets get faults
+	config = enabled()
+	config.Targets = []chaos.Target{chaos.Client}
+	config.ErrorRate = 1
+	w = httptest.NewRecorder()
+	chaos.New(config, metrics.NewRegistry()).Middleware(ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestInjector_Transport(t *testing.T) {
+	srv := httptest.NewServer(ok)
+	defer srv.Close()
+
+	config := enabled()
+	config.ErrorRate = 1
+	config.ErrorStatus = http.StatusBadGateway
+	registry := metrics.NewRegistry()
+	client := &http.Client{Transport: chaos.New(config, registry).Transport(nil)}
+
+	res, err := client.Post(srv.URL, "text/plain", bytes.NewBufferString("hello"))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, "502 Bad Gateway", res.Status)
+	assert.Contains(t, string(body), `"status":502`)
+	assert.Equal(t, 1.0, faults(registry, "client", "error"))
+
+	config.ErrorRate, config.DropRate = 0, 1
+	client = &http.Client{Transport: chaos.New(config, registry).Transport(nil)}
+	_, err = client.Get(srv.URL)
+	assert.True(t, errors.Is(err, chaos.ErrInjected))
+	assert.ErrorContains(t, err, "chaos: injected fault: connection to "+srv.Listener.Addr().String()+" dropped")
+}
+
+func TestInstall(t *testing.T) {
+	srv := httptest.NewServer(ok)
+	defer srv.Close()
+
+	config := enabled()
+	config.Targets = []chaos.Target{chaos.Client}
+	config.ErrorRate = 1
+	a := apptest.New(t, nil)
+	chaos.Install(a, config)
+
+	res, err := (&http.Client{Transport: a.HTTPTransport(nil)}).Get(srv.URL)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	// the faults are recorded in the RED metrics too
+	assert.Equal(t, 1.0, a.Metrics().Counter("http_client_requests_total",
+		metrics.Labels{"method": "GET", "host": srv.Listener.Addr().String(), "code": "503"}).Value())
+
+	_ = a.Logger().ShutdownLoggers()
+	assert.Contains(t, apptest.Stderr(t, a), "chaos mode enabled")
+}
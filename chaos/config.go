@@ -0,0 +1,175 @@
+package chaos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the faults injected, as in APP_CHAOS_LATENCY.
+	DefaultPrefix = "APP_CHAOS_"
+
+	// DefaultLatency is the delay injected when LATENCY is not set.
+	DefaultLatency = time.Second
+)
+
+// DefaultExempt are the paths served without faults when EXEMPT is not set: the health and readiness checks
+// Kubernetes and load balancers probe, so that the experiments test how the app copes with the faults, not how its
+// orchestrator restarts it.
+var DefaultExempt = []string{"/healthz", "/readyz", "/livez", "/health", "/ready"}
+
+// Target is where faults are injected.
+type Target string
+
+const (
+	// Server is the handlers of the HTTP servers, see Injector.Middleware.
+	Server Target = "server"
+
+	// Client is the requests of the HTTP clients, see Injector.Transport.
+	Client Target = "client"
+
+	// DB is the database connections, see Injector.Connector.
+	DB Target = "db"
+)
+
+// Config describes the faults injected, and where.
+type Config struct {
+	Enabled     bool          // whether any fault is injected
+	Targets     []Target      // where the faults are injected
+	Latency     time.Duration // the longest delay injected; each delay is random, up to it
+	LatencyRate float64       // the probability, from 0 to 1, a delay is injected
+	ErrorRate   float64       // the probability an error is injected
+	DropRate    float64       // the probability the connection is dropped
+	ErrorStatus int           // the status of the HTTP responses injected
+	Exempt      []string      // the paths served without faults: a path, or the paths under it if it ends with a slash
+	Seed        uint64        // the seed of the random faults, for reproducible runs; random if zero
+}
+
+// DefaultConfig returns a disabled Config, exempting the DefaultExempt paths.
+func DefaultConfig() *Config {
+	return &Config{
+		Targets:     []Target{Server, Client, DB},
+		Latency:     DefaultLatency,
+		ErrorStatus: 503,
+		Exempt:      DefaultExempt,
+	}
+}
+
+func init() {
+	configschema.Register("chaos", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the fault injection variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "ENABLED", Type: "bool", Default: "false", Description: "Inject the faults."},
+		{Name: prefix + "TARGETS", Type: "string", Default: "server client db",
+			Description: "Where the faults are injected: server, client, or db, separated by spaces or commas."},
+		{Name: prefix + "LATENCY", Type: "duration", Default: DefaultLatency.String(),
+			Description: "The longest delay injected."},
+		{Name: prefix + "LATENCY_RATE", Type: "float", Default: "0",
+			Description: "The probability, from 0 to 1, a delay is injected."},
+		{Name: prefix + "ERROR_RATE", Type: "float", Default: "0",
+			Description: "The probability an error is injected."},
+		{Name: prefix + "DROP_RATE", Type: "float", Default: "0",
+			Description: "The probability the connection is dropped."},
+		{Name: prefix + "ERROR_STATUS", Type: "int", Default: "503",
+			Description: "The status of the responses injected."},
+		{Name: prefix + "EXEMPT", Type: "string", Default: strings.Join(DefaultExempt, " "),
+			Description: "The paths served without faults, separated by spaces or commas."},
+		{Name: prefix + "SEED", Type: "int", Description: "The seed of the random faults, for reproducible runs."},
+	}
+}
+
+// FromEnv reads the faults to inject, with the prefix or DefaultPrefix: nothing is injected unless ENABLED is true, so
+// that the variables can be left in place between experiments. TARGETS names where the faults go (server, client, and
+// db); LATENCY_RATE, ERROR_RATE, and DROP_RATE how often each fault happens; LATENCY and ERROR_STATUS what the delays
+// and errors are; and EXEMPT the paths spared, replacing DefaultExempt. SEED makes a run reproducible.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+	list := func(key string) []string {
+		return strings.FieldsFunc(get(key), func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	config := DefaultConfig()
+	if v := get("ENABLED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("chaos: invalid %sENABLED %q", prefix, v)
+		}
+		config.Enabled = b
+	}
+
+	if targets := list("TARGETS"); len(targets) > 0 {
+		config.Targets = nil
+		for _, v := range targets {
+			switch t := Target(strings.ToLower(v)); t {
+			case Server, Client, DB:
+				config.Targets = append(config.Targets, t)
+			default:
+				return nil, fmt.Errorf("chaos: invalid %sTARGETS target %q", prefix, v)
+			}
+		}
+	}
+
+	if v := get("LATENCY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("chaos: invalid %sLATENCY %q", prefix, v)
+		}
+		config.Latency = d
+	}
+
+	for key, dst := range map[string]*float64{
+		"LATENCY_RATE": &config.LatencyRate,
+		"ERROR_RATE":   &config.ErrorRate,
+		"DROP_RATE":    &config.DropRate,
+	} {
+		if v := get(key); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || !(f >= 0 && f <= 1) {
+				return nil, fmt.Errorf("chaos: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = f
+		}
+	}
+
+	if v := get("ERROR_STATUS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 400 || n > 599 {
+			return nil, fmt.Errorf("chaos: invalid %sERROR_STATUS %q", prefix, v)
+		}
+		config.ErrorStatus = n
+	}
+
+	if exempt := list("EXEMPT"); len(exempt) > 0 {
+		config.Exempt = nil
+		for _, p := range exempt {
+			if !strings.HasPrefix(p, "/") {
+				return nil, fmt.Errorf("chaos: invalid %sEXEMPT path %q", prefix, p)
+			}
+			config.Exempt = append(config.Exempt, p)
+		}
+	}
+
+	if v := get("SEED"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("chaos: invalid %sSEED %q", prefix, v)
+		}
+		config.Seed = n
+	}
+
+	return config, nil
+}
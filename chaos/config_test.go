@@ -0,0 +1,76 @@
+package chaos_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/chaos"
+)
+
+func TestFromEnv_Disabled(t *testing.T) {
+	// the faults stay configured between experiments; only ENABLED turns them on
+	env := map[string]string{"STAGING_CHAOS_ERROR_RATE": "1", "STAGING_CHAOS_ENABLED": "false"}
+	config, err := chaos.FromEnv(apptest.Lookup(env), "STAGING_CHAOS_")
+	require.NoError(t, err)
+	assert.False(t, config.Enabled)
+	assert.Equal(t, 1.0, config.ErrorRate)
+
+	config, err = chaos.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, chaos.DefaultConfig(), config)
+}
+
+func TestFromEnv_Lists(t *testing.T) {
+	config, err := chaos.FromEnv(apptest.Lookup(map[string]string{
+		"APP_CHAOS_TARGETS": " Server,DB ",
+		"APP_CHAOS_EXEMPT":  "/healthz, /debug/",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []chaos.Target{chaos.Server, chaos.DB}, config.Targets)
+	// EXEMPT replaces the defaults rather than adding to them
+	assert.Equal(t, []string{"/healthz", "/debug/"}, config.Exempt)
+
+	_, err = chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_TARGETS": "server grpc"}), "")
+	assert.EqualError(t, err, `chaos: invalid APP_CHAOS_TARGETS target "grpc"`)
+	_, err = chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_EXEMPT": "/healthz debug/"}), "")
+	assert.EqualError(t, err, `chaos: invalid APP_CHAOS_EXEMPT path "debug/"`)
+}
+
+func TestFromEnv_Rates(t *testing.T) {
+	// the bounds are inclusive: 0 never injects the fault and 1 always does
+	config, err := chaos.FromEnv(apptest.Lookup(map[string]string{
+		"APP_CHAOS_LATENCY_RATE": "0",
+		"APP_CHAOS_ERROR_RATE":   "1",
+		"APP_CHAOS_DROP_RATE":    ".25",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, [3]float64{0, 1, 0.25}, [3]float64{config.LatencyRate, config.ErrorRate, config.DropRate})
+
+	for _, v := range []string{"50%", "1.01", "-0.1", "NaN"} {
+		_, err := chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_DROP_RATE": v}), "")
+		assert.EqualError(t, err, `chaos: invalid APP_CHAOS_DROP_RATE "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_ErrorStatus(t *testing.T) {
+	for _, v := range []string{"400", "599"} {
+		_, err := chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_ERROR_STATUS": v}), "")
+		assert.NoError(t, err, v)
+	}
+
+	// a fault is an error response; a 2xx or 3xx would look like success to the caller
+	for _, v := range []string{"200", "302", "600", "5xx"} {
+		_, err := chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_ERROR_STATUS": v}), "")
+		assert.EqualError(t, err, `chaos: invalid APP_CHAOS_ERROR_STATUS "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Latency(t *testing.T) {
+	for _, v := range []string{"0s", "-1s", "250"} {
+		_, err := chaos.FromEnv(apptest.Lookup(map[string]string{"APP_CHAOS_LATENCY": v}), "")
+		assert.EqualError(t, err, `chaos: invalid APP_CHAOS_LATENCY "`+v+`"`, v)
+	}
+}
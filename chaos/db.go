@@ -0,0 +1,160 @@
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// Connector returns a Connector, for sql.OpenDB, injecting faults into the connections of c: when they are opened,
+// pinged, and prepare, execute, or query statements, or begin transactions. The errors wrap ErrInjected, and the
+// connections dropped fail with driver.ErrBadConn, so that database/sql discards them and retries on another.
+func (i *Injector) Connector(c driver.Connector) driver.Connector {
+	if !i.targets(DB) {
+		return c
+	}
+	return &connector{injector: i, connector: c}
+}
+
+// fail returns the error injected into the work on a database connection, if any.
+func (i *Injector) fail(ctx context.Context) error {
+	f, err := i.inject(ctx, DB)
+	switch {
+	case err != nil:
+		return err
+	case f == dropFault:
+		return driver.ErrBadConn
+	case f == errorFault:
+		return ErrInjected
+	}
+	return nil
+}
+
+type connector struct {
+	injector  *Injector
+	connector driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.injector.fail(ctx); err != nil {
+		return nil, err
+	}
+	dc, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{injector: c.injector, conn: dc}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// Close closes the wrapped Connector if it is an io.Closer, as sql.DB.Close does.
+func (c *connector) Close() error {
+	if closer, ok := c.connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// conn injects faults into a connection. The optional interfaces of database/sql it implements fall back on what the
+// wrapped connection implements, as database/sql itself would.
+type conn struct {
+	injector *Injector
+	conn     driver.Conn
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.injector.fail(ctx); err != nil {
+		return nil, err
+	}
+	if p, ok := c.conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.conn.Prepare(query)
+}
+
+func (c *conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.injector.fail(ctx); err != nil {
+		return nil, err
+	}
+	if b, ok := c.conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	// the driver only implements the deprecated Begin
+	return c.conn.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql prepares the statement instead
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.fail(ctx); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.injector.fail(ctx); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if err := c.injector.fail(ctx); err != nil {
+		return err
+	}
+	if p, ok := c.conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	if n, ok := c.conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package chaos_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/chaos"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// fakeDriver counts the connections it opens and the statements it executes.
+type fakeDriver struct {
+	conns, execs int
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	d.conns++
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakeConn) Commit() error                       { return nil }
+func (c *fakeConn) Rollback() error                     { return nil }
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.driver.execs++
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ done bool }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(42)
+	return nil
+}
+
+func TestInjector_Connector(t *testing.T) {
+	d := new(fakeDriver)
+	config := enabled()
+	config.LatencyRate = 0.5
+	config.Latency = time.Millisecond
+	db := sql.OpenDB(chaos.New(config, metrics.NewRegistry()).Connector(d))
+	defer db.Close()
+
+	_, err := db.Exec("UPDATE users SET name = $1", "gopher")
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, db.QueryRow("SELECT 42").Scan(&n))
+	assert.Equal(t, 42, n)
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	require.NoError(t, db.Ping())
+
+	config.ErrorRate = 1
+	registry := metrics.NewRegistry()
+	failing := sql.OpenDB(chaos.New(config, registry).Connector(d))
+	defer failing.Close()
+	_, err = failing.Exec("UPDATE users SET name = $1", "gopher")
+	assert.ErrorIs(t, err, chaos.ErrInjected)
+	assert.Equal(t, 1.0, faults(registry, "db", "error"))
+}
+
+func TestInjector_Connector_Drop(t *testing.T) {
+	d := new(fakeDriver)
+	config := enabled()
+	config.DropRate = 1
+	db := sql.OpenDB(chaos.New(config, metrics.NewRegistry()).Connector(d))
+	defer db.Close()
+
+	// the connections are never made
+	assert.ErrorIs(t, db.Ping(), driver.ErrBadConn)
+	assert.Zero(t, d.conns)
+
+	// database/sql discards the connections dropped, and tries others
+	config.DropRate = 0.5
+	db = sql.OpenDB(chaos.New(config, metrics.NewRegistry()).Connector(d))
+	defer db.Close()
+	for range 20 {
+		if _, err := db.Exec("DELETE FROM sessions"); err != nil {
+			assert.ErrorIs(t, err, driver.ErrBadConn)
+		}
+	}
+	assert.Positive(t, d.execs)
+	assert.Greater(t, d.conns, 1)
+}
+
+func TestInjector_Connector_Disabled(t *testing.T) {
+	d := new(fakeDriver)
+	config := enabled()
+	config.Targets = []chaos.Target{chaos.Server}
+	assert.Same(t, d, chaos.New(config, metrics.NewRegistry()).Connector(d))
+}
@@ -0,0 +1,111 @@
+// Package clierror describes the errors a command reports to the person running it: what went wrong, what they can
+// do about it, the underlying cause, and the exit status. App.Run and App.Dispatch render them on Stderr.
+package clierror
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// ExitFailure is the exit status of errors created without one.
+const ExitFailure = 1
+
+// Error is an error meant for the person running a command rather than for a developer.
+type Error struct {
+	Message string // what went wrong, in the words of the user
+	Hint    string // what the user can do about it, if anything
+	Cause   error  // the underlying error, shown as the cause
+	Code    int    // the exit status of the command; ExitFailure if zero
+
+	stack []uintptr
+}
+
+// New returns an Error with the message and exit status code, recording where it was created.
+func New(code int, format string, args ...interface{}) *Error {
+	return newError(code, nil, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns an Error with the message and exit status code, caused by err, recording where it was created.
+func Wrap(err error, code int, format string, args ...interface{}) *Error {
+	return newError(code, err, fmt.Sprintf(format, args...))
+}
+
+func newError(code int, cause error, message string) *Error {
+	stack := make([]uintptr, 32)
+	n := runtime.Callers(3, stack)
+	return &Error{Message: message, Cause: cause, Code: code, stack: stack[:n]}
+}
+
+// WithHint sets the hint of e, such as "check APP_DB_URL", and returns e.
+func (e *Error) WithHint(format string, args ...interface{}) *Error {
+	e.Hint = fmt.Sprintf(format, args...)
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Cause.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// ExitCode returns the exit status of the command that failed with e.
+func (e *Error) ExitCode() int {
+	if e.Code == 0 {
+		return ExitFailure
+	}
+	return e.Code
+}
+
+// StackTrace returns the functions that led to the creation of e, one per line with their file and line, or an
+// empty string if e was not created by New or Wrap.
+func (e *Error) StackTrace() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			_, _ = fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return sb.String()
+		}
+	}
+}
+
+// As returns the first Error in the chain of err, or nil if there is none.
+func As(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+// Render returns err as shown to the user: the message, then the cause and the hint on their own lines. The stack
+// trace follows when stack is set. Errors other than Error are rendered with their message.
+func Render(err error, stack bool) string {
+	e := As(err)
+	if e == nil {
+		return "error: " + err.Error() + "\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("error: " + e.Message + "\n")
+	if e.Cause != nil {
+		sb.WriteString("  cause: " + e.Cause.Error() + "\n")
+	}
+	if e.Hint != "" {
+		sb.WriteString("  hint: " + e.Hint + "\n")
+	}
+	if trace := e.StackTrace(); stack && trace != "" {
+		sb.WriteString("\n" + trace)
+	}
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+package clierror_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/clierror"
+)
+
+func TestError(t *testing.T) {
+	err := clierror.Wrap(fs.ErrNotExist, 66, "unable to read %s", "config.yaml").
+		WithHint("create it with %q", "app init")
+	assert.EqualError(t, err, "unable to read config.yaml: file does not exist")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Equal(t, 66, err.ExitCode())
+	assert.Contains(t, err.StackTrace(), "clierror_test.TestError\n\t")
+
+	wrapped := fmt.Errorf("load: %w", err)
+	assert.Same(t, err, clierror.As(wrapped))
+	assert.Nil(t, clierror.As(errors.New("plain")))
+
+	assert.Equal(t, `error: unable to read config.yaml
+  cause: file does not exist
+  hint: create it with "app init"
+`, clierror.Render(wrapped, false))
+	assert.Contains(t, clierror.Render(wrapped, true),
+		"\n\ngithub.com/demosdemon/golang-app-framework/clierror_test.TestError\n")
+	assert.Equal(t, "error: plain\n", clierror.Render(errors.New("plain"), true))
+
+	e := clierror.New(0, "no input")
+	assert.EqualError(t, e, "no input")
+	assert.Equal(t, clierror.ExitFailure, e.ExitCode())
+	assert.Equal(t, "error: no input\n", clierror.Render(e, false))
+	assert.Equal(t, "", (&clierror.Error{Message: "literal"}).StackTrace())
+}
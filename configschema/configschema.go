@@ -0,0 +1,50 @@
+// Package configschema collects the environment variables read by the framework packages, so that App.ConfigSchema
+// can document them and App.Run can warn about the APP_ variables nobody reads. Each package registers the
+// variables it reads with its default prefix when it is linked into the program:
+//
+//	func init() {
+//		configschema.Register("ratelimit", ConfigKeys(DefaultPrefix)...)
+//	}
+//
+// Variables read with another prefix are declared by the app with App.DeclareConfig.
+//
+// The packages read their configuration the same way: FromEnv(lookup, prefix) returns a Config from the variables
+// ConfigKeys(prefix) describes, looked up with lookup, usually App.LookupEnv, and named with the prefix, or with the
+// DefaultPrefix of the package when it is empty, so that an app can configure two of a kind, such as two caches.
+package configschema
+
+import "sync"
+
+// Key describes an environment variable.
+type Key struct {
+	Module      string `json:"module"`            // the module that reads the variable
+	Name        string `json:"name"`              // the name; a trailing * stands for any variable with the prefix
+	Type        string `json:"type"`              // the kind of value, such as string, bool, int, duration, or size
+	Default     string `json:"default,omitempty"` // the value used when the variable is not set
+	Description string `json:"description"`       // a sentence on what the variable does
+	Secret      bool   `json:"secret,omitempty"`  // the value is redacted wherever it is shown
+}
+
+var (
+	mu   sync.Mutex
+	keys []Key
+)
+
+// Register adds the variables read by a module, setting their Module.
+func Register(module string, ks ...Key) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, key := range ks {
+		key.Module = module
+		keys = append(keys, key)
+	}
+}
+
+// Keys returns the registered variables in the order they were registered.
+func Keys() []Key {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return append([]Key(nil), keys...)
+}
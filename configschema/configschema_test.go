@@ -0,0 +1,25 @@
+package configschema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+func TestRegister(t *testing.T) {
+	before := len(configschema.Keys())
+	configschema.Register("queue",
+		configschema.Key{Name: "APP_QUEUE_SIZE", Type: "int", Default: "10", Description: "The size."},
+		configschema.Key{Module: "other", Name: "APP_QUEUE_TOKEN", Type: "string", Secret: true},
+	)
+	assert.Equal(t, []configschema.Key{
+		{Module: "queue", Name: "APP_QUEUE_SIZE", Type: "int", Default: "10", Description: "The size."},
+		{Module: "queue", Name: "APP_QUEUE_TOKEN", Type: "string", Secret: true},
+	}, configschema.Keys()[before:])
+
+	keys := configschema.Keys()
+	keys[0].Name = "CHANGED"
+	assert.NotEqual(t, "CHANGED", configschema.Keys()[0].Name)
+}
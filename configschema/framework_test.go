@@ -0,0 +1,160 @@
+package configschema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/assets"
+	"github.com/demosdemon/golang-app-framework/auth"
+	"github.com/demosdemon/golang-app-framework/blob"
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/chaos"
+	"github.com/demosdemon/golang-app-framework/configschema"
+	"github.com/demosdemon/golang-app-framework/cors"
+	"github.com/demosdemon/golang-app-framework/csrf"
+	"github.com/demosdemon/golang-app-framework/devrun"
+	"github.com/demosdemon/golang-app-framework/idempotency"
+	"github.com/demosdemon/golang-app-framework/jobs"
+	"github.com/demosdemon/golang-app-framework/jwtauth"
+	"github.com/demosdemon/golang-app-framework/k8s"
+	"github.com/demosdemon/golang-app-framework/keyauth"
+	"github.com/demosdemon/golang-app-framework/leader"
+	"github.com/demosdemon/golang-app-framework/limits"
+	"github.com/demosdemon/golang-app-framework/lock"
+	"github.com/demosdemon/golang-app-framework/mailer"
+	"github.com/demosdemon/golang-app-framework/openapi"
+	"github.com/demosdemon/golang-app-framework/otlp"
+	"github.com/demosdemon/golang-app-framework/pipeline"
+	"github.com/demosdemon/golang-app-framework/proxy"
+	"github.com/demosdemon/golang-app-framework/ratelimit"
+	"github.com/demosdemon/golang-app-framework/remote"
+	"github.com/demosdemon/golang-app-framework/restclient"
+	"github.com/demosdemon/golang-app-framework/service"
+	"github.com/demosdemon/golang-app-framework/sessions"
+	"github.com/demosdemon/golang-app-framework/slo"
+	"github.com/demosdemon/golang-app-framework/sse"
+	"github.com/demosdemon/golang-app-framework/static"
+	"github.com/demosdemon/golang-app-framework/statsd"
+	"github.com/demosdemon/golang-app-framework/telemetry"
+	"github.com/demosdemon/golang-app-framework/tenant"
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+	"github.com/demosdemon/golang-app-framework/update"
+	"github.com/demosdemon/golang-app-framework/vcr"
+	"github.com/demosdemon/golang-app-framework/watch"
+	"github.com/demosdemon/golang-app-framework/webhook"
+	"github.com/demosdemon/golang-app-framework/ws"
+)
+
+type lookup = func(string) (string, bool)
+
+// TestFrameworkKeys checks that every package registers exactly the variables its FromEnv reads.
+func TestFrameworkKeys(t *testing.T) {
+	registered := map[string]string{}
+	for _, key := range configschema.Keys() {
+		registered[key.Name] = key.Module
+	}
+
+	for _, tc := range []struct {
+		module  string
+		keys    []configschema.Key
+		fromEnv func(lookup)
+		set     map[string]string
+	}{
+		{"assets", assets.ConfigKeys(assets.DefaultPrefix),
+			func(l lookup) { _, _ = assets.FromEnv(l, "") }, nil},
+		{"auth", auth.ConfigKeys(auth.DefaultPrefix),
+			func(l lookup) { _, _ = auth.FromEnv(l, "") }, nil},
+		{"blob", blob.ConfigKeys(blob.DefaultPrefix),
+			func(l lookup) { _, _ = blob.FromEnv(l, "") }, nil},
+		{"cache", cache.ConfigKeys(cache.DefaultPrefix),
+			func(l lookup) { _, _ = cache.FromEnv(l, "") }, nil},
+		{"chaos", chaos.ConfigKeys(chaos.DefaultPrefix),
+			func(l lookup) { _, _ = chaos.FromEnv(l, "") }, nil},
+		{"cors", cors.ConfigKeys(cors.DefaultPrefix),
+			func(l lookup) { _, _ = cors.FromEnv(l, "") }, nil},
+		{"csrf", csrf.ConfigKeys(csrf.DefaultPrefix),
+			func(l lookup) { _, _ = csrf.FromEnv(l, "") }, nil},
+		{"devrun", devrun.ConfigKeys(devrun.DefaultPrefix),
+			func(l lookup) { _, _ = devrun.FromEnv(l, "") }, nil},
+		{"idempotency", idempotency.ConfigKeys(idempotency.DefaultPrefix),
+			func(l lookup) { _, _ = idempotency.FromEnv(l, "") }, nil},
+		{"jobs", jobs.ConfigKeys(jobs.DefaultPrefix),
+			func(l lookup) { _, _ = jobs.FromEnv(l, "") }, nil},
+		{"jwtauth", jwtauth.ConfigKeys(jwtauth.DefaultPrefix),
+			func(l lookup) { _, _ = jwtauth.FromEnv(l, "") }, nil},
+		{"k8s", k8s.ConfigKeys(k8s.DefaultPrefix),
+			func(l lookup) { _, _ = k8s.FromEnv(l, "") }, nil},
+		{"keyauth", keyauth.ConfigKeys(keyauth.DefaultPrefix),
+			func(l lookup) { _, _ = keyauth.FromEnv(l, "") }, nil},
+		{"leader", leader.ConfigKeys(leader.DefaultPrefix),
+			func(l lookup) { _, _ = leader.FromEnv(l, "") }, nil},
+		{"limits", limits.ConfigKeys(limits.DefaultPrefix),
+			func(l lookup) { _, _ = limits.FromEnv(l, "") }, nil},
+		{"lock", lock.ConfigKeys(lock.DefaultPrefix),
+			func(l lookup) { _, _ = lock.FromEnv(l, "") }, nil},
+		{"mailer", mailer.ConfigKeys(mailer.DefaultPrefix),
+			func(l lookup) { _, _ = mailer.FromEnv(l, "") }, map[string]string{"HOST": "smtp"}},
+		{"openapi", openapi.ConfigKeys(openapi.DefaultPrefix),
+			func(l lookup) { _, _ = openapi.FromEnv(l, "") }, nil},
+		{"otlp", otlp.ConfigKeys(otlp.DefaultPrefix),
+			func(l lookup) { _, _ = otlp.FromEnv(l, "") }, nil},
+		{"pipeline", pipeline.ConfigKeys(pipeline.DefaultPrefix),
+			func(l lookup) { _, _ = pipeline.FromEnv(l, "") }, nil},
+		{"proxy", proxy.ConfigKeys(proxy.DefaultPrefix),
+			func(l lookup) { _, _ = proxy.FromEnv(l, "") }, map[string]string{"UPSTREAM": "http://upstream"}},
+		{"ratelimit", ratelimit.ConfigKeys(ratelimit.DefaultPrefix),
+			func(l lookup) { _, _ = ratelimit.FromEnv(l, "") }, nil},
+		{"remote", remote.ConfigKeys(remote.DefaultPrefix),
+			func(l lookup) { _, _ = remote.FromEnv(l, "") }, map[string]string{"PROVIDER": "etcd", "ENDPOINT": "http://etcd"}},
+		{"restclient", restclient.ConfigKeys(restclient.DefaultPrefix),
+			func(l lookup) { _, _ = restclient.FromEnv(l, "") }, nil},
+		{"service", service.ConfigKeys(service.DefaultPrefix),
+			func(l lookup) { _, _ = service.FromEnv(l, "") }, nil},
+		{"sessions", sessions.ConfigKeys(sessions.DefaultPrefix),
+			func(l lookup) { _, _ = sessions.FromEnv(l, "") }, nil},
+		{"slo", slo.ConfigKeys(slo.DefaultPrefix),
+			func(l lookup) { _, _ = slo.FromEnv(l, "") }, nil},
+		{"sse", sse.ConfigKeys(sse.DefaultPrefix),
+			func(l lookup) { _, _ = sse.FromEnv(l, "") }, nil},
+		{"static", static.ConfigKeys(static.DefaultPrefix),
+			func(l lookup) { _, _ = static.FromEnv(l, "") }, nil},
+		{"statsd", statsd.ConfigKeys(statsd.DefaultPrefix),
+			func(l lookup) { _, _ = statsd.FromEnv(l, "") }, nil},
+		{"telemetry", telemetry.ConfigKeys(telemetry.DefaultPrefix),
+			func(l lookup) { _, _ = telemetry.FromEnv(l, "") }, nil},
+		{"tenant", tenant.ConfigKeys(tenant.DefaultPrefix),
+			func(l lookup) { _, _ = tenant.FromEnv(l, "") }, nil},
+		{"tlsconfig", tlsconfig.ConfigKeys(tlsconfig.DefaultPrefix),
+			func(l lookup) { _, _ = tlsconfig.FromEnv(l, "") }, nil},
+		{"update", update.ConfigKeys(update.DefaultPrefix),
+			func(l lookup) { _, _ = update.FromEnv(l, "") }, nil},
+		{"vcr", vcr.ConfigKeys(vcr.DefaultPrefix),
+			func(l lookup) { _, _ = vcr.FromEnv(l, "") }, nil},
+		{"watch", watch.ConfigKeys(watch.DefaultPrefix),
+			func(l lookup) { _, _ = watch.FromEnv(l, "") }, nil},
+		{"webhook", webhook.ConfigKeys(webhook.DefaultPrefix),
+			func(l lookup) { _, _ = webhook.FromEnv(l, "") }, nil},
+		{"ws", ws.ConfigKeys(ws.DefaultPrefix),
+			func(l lookup) { _, _ = ws.FromEnv(l, "") }, nil},
+	} {
+		read := map[string]bool{}
+		tc.fromEnv(func(name string) (string, bool) {
+			read[name] = true
+			for suffix, v := range tc.set {
+				if strings.HasSuffix(name, "_"+suffix) {
+					return v, true
+				}
+			}
+			return "", false
+		})
+
+		declared := map[string]bool{}
+		for _, key := range tc.keys {
+			declared[key.Name] = true
+			assert.Equal(t, tc.module, registered[key.Name], key.Name)
+		}
+		assert.Equal(t, read, declared, tc.module)
+	}
+}
@@ -0,0 +1,191 @@
+// Package correlation gives every request an ID, so that the logs of a request can be told apart from those of
+// others and followed across the services it goes through:
+//
+//	handler := correlation.Middleware(mux)
+//
+//	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+//		logger := correlation.Logger(r.Context(), a.Logger()) // logs with the "request_id" attribute
+//		...
+//	})
+//
+// The Middleware keeps the ID a request arrives with in its X-Request-ID header, so that an ID set by a load balancer
+// or the calling service is kept, and otherwise makes one with New. The ID travels in the context of the request, and
+// from there in the header of the HTTP requests sent through Transport, such as those of a restclient.Client, and in
+// the metadata of the gRPC calls made with the client interceptors, such as those of App.GRPCClient. The server
+// interceptors do for gRPC services what the Middleware does for HTTP handlers.
+package correlation
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aphistic/gomol"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Header is the HTTP header carrying the request ID.
+	Header = "X-Request-ID"
+
+	// MetadataKey is the gRPC metadata key carrying the request ID.
+	MetadataKey = "x-request-id"
+
+	// maxLength bounds the length of the request IDs kept from requests.
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID ctx carries and whether it carries one.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Valid reports whether id can be kept as a request ID: up to 128 printable ASCII characters other than spaces, so
+// that it cannot forge log lines or headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Logger returns a logger adding the request ID ctx carries, if any, to the attributes of every message as
+// "request_id".
+func Logger(ctx context.Context, logger gomol.WrappableLogger) *gomol.LogAdapter {
+	return gomol.NewLogAdapterFor(logger, gomol.NewAttrsFromMap(Attrs(ctx)))
+}
+
+// Attrs returns the request ID ctx carries, if any, as attributes for logs.
+func Attrs(ctx context.Context) map[string]interface{} {
+	attrs := make(map[string]interface{}, 1)
+	if id, ok := FromContext(ctx); ok {
+		attrs["request_id"] = id
+	}
+	return attrs
+}
+
+// Middleware returns a handler calling next with the request ID in the context of the request, and in the Header of
+// the response. The ID is the one in the Header of the request if it is Valid, and a new one otherwise.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !Valid(id) {
+			id = New().String()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Transport returns a RoundTripper sending the requests with next, http.DefaultTransport if nil, adding the request
+// ID their context carries as their Header unless they already have one.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		id, ok := FromContext(req.Context())
+		if !ok || req.Header.Get(Header) != "" {
+			return next.RoundTrip(req)
+		}
+
+		// a RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set(Header, id)
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// UnaryClientInterceptor returns a gRPC interceptor adding the request ID the context of a call carries to its
+// metadata, unless the metadata already has one.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a gRPC interceptor adding the request ID the context of a stream carries to its
+// metadata, unless the metadata already has one.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// outgoing returns ctx with the request ID it carries in its outgoing metadata.
+func outgoing(ctx context.Context) context.Context {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get(MetadataKey)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, id)
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor calling the handler with the request ID in its context: the one in
+// the metadata of the call if it is Valid, and a new one otherwise.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(incoming(ctx), req)
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor calling the handler with the request ID in the context of the
+// stream, as UnaryServerInterceptor does.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: incoming(ss.Context())})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// incoming returns ctx carrying the request ID of its incoming metadata, or a new one.
+func incoming(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(MetadataKey); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if !Valid(id) {
+		id = New().String()
+	}
+	return NewContext(ctx, id)
+}
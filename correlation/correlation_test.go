@@ -0,0 +1,136 @@
+package correlation_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/demosdemon/golang-app-framework/correlation"
+)
+
+func TestContext(t *testing.T) {
+	_, ok := correlation.FromContext(context.Background())
+	assert.False(t, ok)
+	assert.Empty(t, correlation.Attrs(context.Background()))
+
+	ctx := correlation.NewContext(context.Background(), "req-1")
+	id, ok := correlation.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "req-1", id)
+	assert.Equal(t, map[string]interface{}{"request_id": "req-1"}, correlation.Attrs(ctx))
+}
+
+func TestValid(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"req-1":                      true,
+		"01ARZ3NDEKTSV4RRFFQ69G5FAV": true,
+		"":                           false,
+		"two words":                  false,
+		"line\nbreak":                false,
+		"café":                       false,
+		strings.Repeat("a", 129):     false,
+	} {
+		assert.Equal(t, valid, correlation.Valid(id), id)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := correlation.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = correlation.FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(correlation.Header, "from-the-load-balancer")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, "from-the-load-balancer", seen)
+	assert.Equal(t, "from-the-load-balancer", w.Header().Get(correlation.Header))
+
+	for _, inbound := range []string{"", "not valid"} {
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(correlation.Header, inbound)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		_, err := correlation.Parse(seen)
+		assert.NoError(t, err)
+		assert.Equal(t, seen, w.Header().Get(correlation.Header))
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(correlation.Header))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: correlation.Transport(nil)}
+
+	send := func(ctx context.Context, header string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(correlation.Header, header)
+		}
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, header, req.Header.Get(correlation.Header), "the request is not modified")
+	}
+
+	ctx := correlation.NewContext(context.Background(), "req-1")
+	send(ctx, "")
+	send(ctx, "explicit")
+	send(context.Background(), "")
+	assert.Equal(t, []string{"req-1", "explicit", ""}, got)
+}
+
+func TestInterceptors(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	seen := make(chan string, 1)
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		correlation.UnaryServerInterceptor(),
+		func(
+			ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (interface{}, error) {
+			id, _ := correlation.FromContext(ctx)
+			seen <- id
+			return handler(ctx, req)
+		},
+	))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go func() { _ = s.Serve(l) }()
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(l.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(correlation.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(correlation.StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := correlation.NewContext(context.Background(), "req-1")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", <-seen)
+
+	// the server makes an ID for calls without one
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = correlation.Parse(<-seen)
+	assert.NoError(t, err)
+}
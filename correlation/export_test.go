@@ -0,0 +1,4 @@
+package correlation
+
+// NewAt is newAt.
+var NewAt = newAt
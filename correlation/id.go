@@ -0,0 +1,119 @@
+package correlation
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encoding is the Crockford base32 alphabet of ULIDs, which sorts like the bytes it encodes.
+const encoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ID is a ULID: a 48-bit timestamp in milliseconds followed by 80 random bits. Its String sorts by the time the ID
+// was made, and the IDs made by New within a millisecond sort in the order they were made.
+type ID [16]byte
+
+var (
+	mu   sync.Mutex
+	last ID
+)
+
+// New returns a new ID for the current time.
+func New() ID {
+	return newAt(time.Now())
+}
+
+// newAt returns a new ID for t, monotonic with the previous one: in the same millisecond, or when the clock went
+// back, it is the previous ID plus one.
+func newAt(t time.Time) ID {
+	ms := uint64(t.UnixMilli())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ms <= last.ms() && increment(&last) {
+		return last
+	}
+
+	var id ID
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("correlation: unable to read random bytes: %v", err))
+	}
+	last = id
+	return id
+}
+
+// increment adds one to the random bits of id and reports whether they did not overflow.
+func increment(id *ID) bool {
+	for i := len(id) - 1; i >= 6; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (id ID) ms() uint64 {
+	return uint64(binary.BigEndian.Uint16(id[0:2]))<<32 | uint64(binary.BigEndian.Uint32(id[2:6]))
+}
+
+// Time returns the time the ID was made, to the millisecond.
+func (id ID) Time() time.Time {
+	return time.UnixMilli(int64(id.ms()))
+}
+
+// String returns the 26 character ULID representation of the ID.
+func (id ID) String() string {
+	var b [26]byte
+	// 128 bits in 26 groups of 5 bits, the first group holding only 3
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	for i := 25; i >= 0; i-- {
+		b[i] = encoding[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(b[:])
+}
+
+// Parse returns the ID s represents, case insensitively.
+func Parse(s string) (ID, error) {
+	var id ID
+	if len(s) != 26 || s[0] > '7' {
+		return id, fmt.Errorf("correlation: invalid ID %q", s)
+	}
+
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		v, ok := decode(s[i])
+		if !ok {
+			return id, fmt.Errorf("correlation: invalid ID %q", s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(id[0:8], hi)
+	binary.BigEndian.PutUint64(id[8:16], lo)
+	return id, nil
+}
+
+// decode returns the value of the character c, reading I and L as 1 and O as 0 as Crockford base32 does.
+func decode(c byte) (byte, bool) {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	switch c {
+	case 'I', 'L':
+		c = '1'
+	case 'O':
+		c = '0'
+	}
+	i := strings.IndexByte(encoding, c)
+	return byte(i), i >= 0
+}
@@ -0,0 +1,61 @@
+package correlation_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/correlation"
+)
+
+func TestNew(t *testing.T) {
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		ids[i] = correlation.New().String()
+		assert.Len(t, ids[i], 26)
+		assert.False(t, seen[ids[i]], ids[i])
+		seen[ids[i]] = true
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+}
+
+func TestNew_Monotonic(t *testing.T) {
+	now := time.Now()
+	first := correlation.NewAt(now)
+	second := correlation.NewAt(now)
+	assert.Less(t, first.String(), second.String())
+	assert.Equal(t, first[:15], second[:15])
+
+	// a clock going back does not break the order
+	third := correlation.NewAt(now.Add(-time.Second))
+	assert.Less(t, second.String(), third.String())
+
+	later := correlation.NewAt(now.Add(time.Second))
+	assert.Equal(t, now.Add(time.Second).Truncate(time.Millisecond).UnixMilli(), later.Time().UnixMilli())
+}
+
+func TestParse(t *testing.T) {
+	id := correlation.New()
+	parsed, err := correlation.Parse(id.String())
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+
+	parsed, err = correlation.Parse("01arz3ndektsv4rrffq69g5fav")
+	require.NoError(t, err)
+	assert.Equal(t, "01ARZ3NDEKTSV4RRFFQ69G5FAV", parsed.String())
+	assert.Equal(t, int64(1469922850259), parsed.Time().UnixMilli())
+
+	for _, s := range []string{
+		"",
+		"01ARZ3NDEKTSV4RRFFQ69G5FA",  // too short
+		"01ARZ3NDEKTSV4RRFFQ69G5FAU", // U is not a digit
+		"81ARZ3NDEKTSV4RRFFQ69G5FAV", // over 128 bits
+	} {
+		_, err := correlation.Parse(s)
+		assert.EqualError(t, err, "correlation: invalid ID \""+s+"\"")
+	}
+}
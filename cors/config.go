@@ -0,0 +1,137 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the cross-origin policy, as in APP_CORS_ORIGINS.
+	DefaultPrefix = "APP_CORS_"
+
+	// DefaultMaxAge is how long browsers may cache a preflight response.
+	DefaultMaxAge = 10 * time.Minute
+)
+
+var (
+	// DefaultMethods are the methods allowed when none are configured.
+	DefaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+	// DefaultHeaders are the request headers allowed when none are configured.
+	DefaultHeaders = []string{"Accept", "Accept-Language", "Content-Language", "Content-Type"}
+
+	// ErrWildcardCredentials is returned when credentials are allowed for any origin.
+	ErrWildcardCredentials = errors.New("cors: credentials cannot be allowed for the * origin")
+)
+
+// Config describes which cross-origin requests are allowed. The zero value allows none.
+//
+// Origins are matched exactly, except for "*", which matches any origin, and a leading "*." in the host, which
+// matches any subdomain, e.g. "https://*.example.com".
+type Config struct {
+	Origins     []string      // origins allowed to make requests
+	Methods     []string      // methods allowed in preflight requests
+	Headers     []string      // request headers allowed in preflight requests
+	Expose      []string      // response headers exposed to scripts
+	Credentials bool          // allow cookies and authorization headers
+	MaxAge      time.Duration // how long browsers may cache a preflight response
+	Dev         bool          // allow any origin, method, and header with credentials, for local development only
+}
+
+func init() {
+	configschema.Register("cors", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the CORS policy variables with the prefix, whose defaults are the strict ones of FromEnv.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "ORIGINS", Type: "string",
+			Description: "The origins allowed to make requests, comma separated."},
+		{Name: prefix + "METHODS", Type: "string", Default: strings.Join(DefaultMethods, ","),
+			Description: "The methods allowed in preflight requests, comma separated."},
+		{Name: prefix + "HEADERS", Type: "string", Default: strings.Join(DefaultHeaders, ","),
+			Description: "The request headers allowed in preflight requests, comma separated."},
+		{Name: prefix + "EXPOSE_HEADERS", Type: "string",
+			Description: "The response headers exposed to scripts, comma separated."},
+		{Name: prefix + "CREDENTIALS", Type: "bool", Default: "false",
+			Description: "Allow cookies and authorization headers."},
+		{Name: prefix + "MAX_AGE", Type: "duration", Default: DefaultMaxAge.String(),
+			Description: "How long browsers may cache a preflight response."},
+		{Name: prefix + "DEV", Type: "bool", Default: "false",
+			Description: "Allow any origin, method, and header, for local development only."},
+	}
+}
+
+// FromEnv reads the CORS policy from ORIGINS, METHODS, HEADERS, EXPOSE_HEADERS, CREDENTIALS, MAX_AGE, and DEV, with the
+// prefix or DefaultPrefix; the lists are comma separated.
+//
+// Unset variables take strict defaults: no origins, DefaultMethods, DefaultHeaders, no credentials, and DefaultMaxAge.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{
+		Origins: split(get("ORIGINS"), strings.TrimSpace),
+		Methods: split(get("METHODS"), strings.ToUpper),
+		Headers: split(get("HEADERS"), http.CanonicalHeaderKey),
+		Expose:  split(get("EXPOSE_HEADERS"), http.CanonicalHeaderKey),
+		MaxAge:  DefaultMaxAge,
+	}
+
+	if config.Methods == nil {
+		config.Methods = DefaultMethods
+	}
+	if config.Headers == nil {
+		config.Headers = DefaultHeaders
+	}
+
+	for key, dst := range map[string]*bool{"CREDENTIALS": &config.Credentials, "DEV": &config.Dev} {
+		if v := get(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("cors: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = b
+		}
+	}
+
+	if v := get("MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("cors: invalid %sMAX_AGE %q", prefix, v)
+		}
+		config.MaxAge = d
+	}
+
+	if config.Credentials {
+		for _, o := range config.Origins {
+			if o == "*" {
+				return nil, ErrWildcardCredentials
+			}
+		}
+	}
+
+	return &config, nil
+}
+
+func split(v string, canonical func(string) string) []string {
+	var res []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, canonical(s))
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+package cors_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/cors"
+)
+
+func TestFromEnv_Lists(t *testing.T) {
+	config, err := cors.FromEnv(apptest.Lookup(map[string]string{
+		"API_CORS_ORIGINS":        "https://app.example.com,, https://*.example.org ",
+		"API_CORS_METHODS":        "get, delete",
+		"API_CORS_HEADERS":        "authorization,x-request-id",
+		"API_CORS_EXPOSE_HEADERS": "x-total-count",
+	}), "API_CORS_")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.example.com", "https://*.example.org"}, config.Origins)
+	assert.Equal(t, []string{"GET", "DELETE"}, config.Methods)
+	assert.Equal(t, []string{"Authorization", "X-Request-Id"}, config.Headers)
+	assert.Equal(t, []string{"X-Total-Count"}, config.Expose)
+
+	// a list of nothing but separators is unset, and keeps the defaults
+	config, err = cors.FromEnv(apptest.Lookup(map[string]string{"APP_CORS_METHODS": " , ,"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, &cors.Config{
+		Methods: cors.DefaultMethods,
+		Headers: cors.DefaultHeaders,
+		MaxAge:  cors.DefaultMaxAge,
+	}, config)
+}
+
+func TestFromEnv_MaxAge(t *testing.T) {
+	// zero makes browsers send a preflight before every request
+	config, err := cors.FromEnv(apptest.Lookup(map[string]string{"APP_CORS_MAX_AGE": "0s"}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.MaxAge)
+
+	for _, v := range []string{"600", "-1m"} {
+		_, err := cors.FromEnv(apptest.Lookup(map[string]string{"APP_CORS_MAX_AGE": v}), "")
+		assert.EqualError(t, err, `cors: invalid APP_CORS_MAX_AGE "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Credentials(t *testing.T) {
+	config, err := cors.FromEnv(apptest.Lookup(map[string]string{"APP_CORS_ORIGINS": "*"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, config.Origins)
+
+	// browsers refuse credentials for a wildcard origin, so the pairing is caught at startup
+	config, err = cors.FromEnv(apptest.Lookup(map[string]string{
+		"APP_CORS_ORIGINS":     "https://app.example.com, *",
+		"APP_CORS_CREDENTIALS": "1",
+	}), "")
+	assert.Nil(t, config)
+	assert.ErrorIs(t, err, cors.ErrWildcardCredentials)
+
+	for _, key := range []string{"CREDENTIALS", "DEV"} {
+		_, err := cors.FromEnv(apptest.Lookup(map[string]string{"APP_CORS_" + key: "sure"}), "")
+		assert.EqualError(t, err, "cors: invalid APP_CORS_"+key+` "sure"`)
+	}
+}
@@ -0,0 +1,159 @@
+// Package cors implements Cross-Origin Resource Sharing as HTTP middleware configured from the environment.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware answers preflight requests and adds CORS headers to the responses of allowed origins.
+type Middleware struct {
+	config Config
+
+	methods map[string]bool
+	headers map[string]bool
+}
+
+// New returns a Middleware enforcing config.
+func New(config *Config) *Middleware {
+	m := &Middleware{
+		config:  *config,
+		methods: make(map[string]bool, len(config.Methods)),
+		headers: make(map[string]bool, len(config.Headers)),
+	}
+
+	for _, v := range config.Methods {
+		m.methods[strings.ToUpper(v)] = true
+	}
+	for _, v := range config.Headers {
+		m.headers[http.CanonicalHeaderKey(v)] = true
+	}
+
+	return m
+}
+
+// Wrap returns a handler applying the CORS policy before calling next. Preflight requests are answered directly
+// with 204 No Content, or 403 Forbidden if the origin, method, or headers are not allowed.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		if !m.allowOrigin(origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if m.config.Credentials || m.config.Dev {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if m.wildcard() {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if !preflight {
+			if len(m.config.Expose) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(m.config.Expose, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+
+		method := r.Header.Get("Access-Control-Request-Method")
+		headers := requestedHeaders(r)
+		if !m.allowMethod(method) || !m.allowHeaders(headers) {
+			header.Del("Access-Control-Allow-Origin")
+			header.Del("Access-Control-Allow-Credentials")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", method)
+		if len(headers) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		if m.config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.FormatInt(int64(m.config.MaxAge/time.Second), 10))
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (m *Middleware) wildcard() bool {
+	if m.config.Dev || m.config.Credentials {
+		return false
+	}
+	for _, o := range m.config.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Middleware) allowOrigin(origin string) bool {
+	if m.config.Dev {
+		return true
+	}
+
+	for _, o := range m.config.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+
+		// https://*.example.com matches https://api.example.com but not https://example.com
+		if scheme, host, ok := strings.Cut(o, "://*."); ok {
+			rest, found := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if found && strings.HasSuffix(rest, "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (m *Middleware) allowMethod(method string) bool {
+	return m.config.Dev || m.methods[strings.ToUpper(method)]
+}
+
+func (m *Middleware) allowHeaders(headers []string) bool {
+	if m.config.Dev {
+		return true
+	}
+	for _, h := range headers {
+		if !m.headers[h] {
+			return false
+		}
+	}
+	return true
+}
+
+func requestedHeaders(r *http.Request) []string {
+	var res []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		res = append(res, split(v, http.CanonicalHeaderKey)...)
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+package cors_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/cors"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+})
+
+func request(h http.Handler, method, origin string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	h := cors.New(&cors.Config{
+		Origins: []string{"https://app.example.com", "https://*.example.org"},
+		Methods: []string{"GET", "PUT"},
+		Headers: []string{"Content-Type", "X-Request-Id"},
+		Expose:  []string{"X-Total-Count"},
+		MaxAge:  time.Hour,
+	}).Wrap(ok)
+
+	w := request(h, "GET", "")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Empty(t, w.Header().Get("Vary"))
+
+	w = request(h, "GET", "https://app.example.com")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total-Count", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+
+	w = request(h, "GET", "https://api.example.org")
+	assert.Equal(t, "https://api.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+
+	for _, origin := range []string{"https://example.org", "http://api.example.org", "https://evil.com"} {
+		w = request(h, "GET", origin)
+		assert.Equal(t, "ok", w.Body.String())
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), origin)
+	}
+}
+
+func TestMiddleware_Preflight(t *testing.T) {
+	h := cors.New(&cors.Config{
+		Origins: []string{"https://app.example.com"},
+		Methods: []string{"GET", "PUT"},
+		Headers: []string{"Content-Type", "X-Request-Id"},
+		MaxAge:  time.Hour,
+	}).Wrap(ok)
+
+	w := request(h, "OPTIONS", "https://app.example.com",
+		"Access-Control-Request-Method", "PUT",
+		"Access-Control-Request-Headers", "content-type,x-request-id")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "PUT", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, X-Request-Id", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = request(h, "OPTIONS", "https://app.example.com", "Access-Control-Request-Method", "DELETE")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = request(h, "OPTIONS", "https://app.example.com",
+		"Access-Control-Request-Method", "GET",
+		"Access-Control-Request-Headers", "Authorization")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = request(h, "OPTIONS", "https://evil.com", "Access-Control-Request-Method", "GET")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// a plain OPTIONS request is not a preflight
+	w = request(h, "OPTIONS", "https://app.example.com")
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestMiddleware_Wildcard(t *testing.T) {
+	h := cors.New(&cors.Config{Origins: []string{"*"}, Methods: []string{"GET"}}).Wrap(ok)
+
+	w := request(h, "GET", "https://anywhere.test")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestMiddleware_Dev(t *testing.T) {
+	h := cors.New(&cors.Config{Dev: true}).Wrap(ok)
+
+	w := request(h, "OPTIONS", "http://localhost:3000",
+		"Access-Control-Request-Method", "PATCH",
+		"Access-Control-Request-Headers", "Authorization")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "PATCH", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+}
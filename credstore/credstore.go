@@ -0,0 +1,181 @@
+// Package credstore keeps the secrets of the user of a command line tool, such as tokens, in the keychain of the
+// platform rather than in plaintext files: the login keychain on macOS, the Credential Manager on Windows, and the
+// Secret Service, such as GNOME Keyring, on Linux. Where there is no keychain, as on servers and in containers, a
+// FileStore encrypts the secrets instead. App.Credentials returns the store an app uses.
+package credstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// ErrNotFound is returned by Store.Get when there is no secret for the key.
+var ErrNotFound = errors.New("credstore: not found")
+
+// Store keeps secrets, such as tokens, by key. Implementations must be safe for concurrent use.
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// Keychain returns the keychain of the platform, keeping the secrets under service, typically the name of the app, or
+// nil if there is none. On Linux, the Secret Service is only used in a desktop session, as told by
+// DBUS_SESSION_BUS_ADDRESS in the environment looked up with lookup.
+func Keychain(service string, lookup func(string) (string, bool)) Store {
+	return keychain(lookup, service)
+}
+
+// MemoryStore is a Store that keeps the secrets in memory, for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// Get returns the secret for key.
+func (s *MemoryStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Set keeps value as the secret for key.
+func (s *MemoryStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		s.secrets = make(map[string]string)
+	}
+	s.secrets[key] = value
+	return nil
+}
+
+// Delete forgets the secret for key.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.secrets, key)
+	return nil
+}
+
+// FileStore is a Store keeping its secrets in a file encrypted with AES-GCM, for hosts without a keychain. The file is
+// only readable by the user.
+type FileStore struct {
+	path string
+	aead cipher.AEAD
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a FileStore keeping its secrets in the file at path, encrypted with a key derived from key.
+func NewFileStore(path string, key []byte) *FileStore {
+	sum := sha256.Sum256(key)
+	// neither fails with a 32 byte key
+	block, _ := aes.NewCipher(sum[:])
+	aead, _ := cipher.NewGCM(block)
+	return &FileStore{path: path, aead: aead}
+}
+
+// Get returns the secret for key.
+func (s *FileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	v, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Set keeps value as the secret for key.
+func (s *FileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	values[key] = value
+	return s.save(values)
+}
+
+// Delete forgets the secret for key.
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return ErrNotFound
+	}
+	delete(values, key)
+	return s.save(values)
+}
+
+func (s *FileStore) load() (map[string]string, error) {
+	values := make(map[string]string)
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	n := s.aead.NonceSize()
+	if len(b) < n {
+		return nil, fmt.Errorf("credstore: %s is corrupt", s.path)
+	}
+	plaintext, err := s.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("credstore: unable to decrypt %s, the key changed or the file is corrupt", s.path)
+	}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("credstore: %s is corrupt: %v", s.path, err)
+	}
+	return values, nil
+}
+
+func (s *FileStore) save(values map[string]string) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.path, s.aead.Seal(nonce, nonce, plaintext, nil), 0o600)
+}
@@ -0,0 +1,56 @@
+package credstore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/credstore"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "credentials")
+	store := credstore.NewFileStore(path, []byte("key"))
+
+	_, err := store.Get("token")
+	assert.ErrorIs(t, err, credstore.ErrNotFound)
+	assert.ErrorIs(t, store.Delete("token"), credstore.ErrNotFound)
+
+	require.NoError(t, store.Set("token", "s3cret"))
+	require.NoError(t, store.Set("other", "value"))
+	v, err := store.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "s3cret")
+	if info, err := os.Stat(path); assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	_, err = credstore.NewFileStore(path, []byte("other key")).Get("token")
+	assert.ErrorContains(t, err, "unable to decrypt")
+
+	require.NoError(t, store.Delete("token"))
+	_, err = store.Get("token")
+	assert.ErrorIs(t, err, credstore.ErrNotFound)
+	v, err = store.Get("other")
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+}
+
+func TestMemoryStore(t *testing.T) {
+	var store credstore.Store = new(credstore.MemoryStore)
+	_, err := store.Get("token")
+	assert.ErrorIs(t, err, credstore.ErrNotFound)
+	require.NoError(t, store.Set("token", "s3cret"))
+	v, err := store.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", v)
+	require.NoError(t, store.Delete("token"))
+	assert.ErrorIs(t, store.Delete("token"), credstore.ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package credstore
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errItemNotFound is the exit status of the security command when there is no such item.
+const errItemNotFound = 44
+
+// keychain returns the login keychain, through the security command.
+func keychain(_ func(string) (string, bool), service string) Store {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil
+	}
+	return &securityKeychain{service: service}
+}
+
+// securityKeychain keeps the secrets as generic passwords of the service. The secrets are passed to the security
+// command on its stdin, rather than as arguments that other processes could see, and base64 encoded, so that it
+// prints them back as they are.
+type securityKeychain struct {
+	service string
+}
+
+func (k *securityKeychain) Get(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", k.service, "-a", key, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return "", fmt.Errorf("credstore: the keychain item %s is not one of ours", key)
+	}
+	return string(b), nil
+}
+
+func (k *securityKeychain) Set(key, value string) error {
+	secret := hex.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte(value))))
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		securityQuote(k.service), securityQuote(key), secret))
+	if out, err := cmd.CombinedOutput(); err != nil || len(strings.TrimSpace(string(out))) > 0 {
+		return fmt.Errorf("credstore: unable to add to the keychain: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (k *securityKeychain) Delete(key string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", k.service, "-a", key).Run()
+	return securityError(err)
+}
+
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errItemNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("credstore: keychain: %w", err)
+	}
+	return nil
+}
+
+// securityQuote quotes s for the interactive mode of the security command.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
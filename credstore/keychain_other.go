@@ -0,0 +1,61 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychain returns the Secret Service of the desktop session, through the secret-tool command, if there is one.
+func keychain(lookup func(string) (string, bool), service string) Store {
+	if v, _ := lookup("DBUS_SESSION_BUS_ADDRESS"); v == "" {
+		return nil
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil
+	}
+	return &secretTool{service: service}
+}
+
+// secretTool keeps the secrets as items with the attributes service and account. The secrets are passed to
+// secret-tool on its stdin, rather than as arguments that other processes could see.
+type secretTool struct {
+	service string
+}
+
+func (s *secretTool) Get(key string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", s.service, "account", key)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(out) == 0 && stderr.Len() == 0 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("credstore: secret-tool: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func (s *secretTool) Set(key, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+s.service+" "+key, "service", s.service, "account", key)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("credstore: secret-tool: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s *secretTool) Delete(key string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", s.service, "account", key)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("credstore: secret-tool: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
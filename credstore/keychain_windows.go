@@ -0,0 +1,120 @@
+package credstore
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+var (
+	advapi32       = windows.NewLazySystemDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredDelete = advapi32.NewProc("CredDeleteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+// credential is the CREDENTIALW structure of the Credential Manager.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// keychain returns the Credential Manager of the user.
+func keychain(_ func(string) (string, bool), service string) Store {
+	if advapi32.Load() != nil {
+		return nil
+	}
+	return &credManager{service: service}
+}
+
+// credManager keeps the secrets as generic credentials named service:key.
+type credManager struct {
+	service string
+}
+
+func (m *credManager) target(key string) (*uint16, error) {
+	return windows.UTF16PtrFromString(m.service + ":" + key)
+}
+
+func (m *credManager) Get(key string) (string, error) {
+	target, err := m.target(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0,
+		uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return "", credError(err)
+	}
+	defer func() { _, _, _ = procCredFree.Call(uintptr(unsafe.Pointer(cred))) }()
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (m *credManager) Set(key, value string) error {
+	target, err := m.target(key)
+	if err != nil {
+		return err
+	}
+	user, err := windows.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); r == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+func (m *credManager) Delete(key string) error {
+	target, err := m.target(key)
+	if err != nil {
+		return err
+	}
+	if r, _, err := procCredDelete.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); r == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+func credError(err error) error {
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("credstore: credential manager: %w", err)
+}
@@ -0,0 +1,149 @@
+package csrf
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the token settings, as in APP_CSRF_MODE.
+	DefaultPrefix = "APP_CSRF_"
+
+	// DefaultCookie is the name of the token cookie, prefixed with __Host- when it is Secure.
+	DefaultCookie = "csrf"
+
+	// DefaultHeader is the header carrying the token, or marking the requests in HeaderMode.
+	DefaultHeader = "X-CSRF-Token"
+
+	// DefaultField is the form field carrying the token.
+	DefaultField = "csrf_token"
+)
+
+// Mode is how the Middleware tells the requests of the pages of the app from those forged by other sites.
+type Mode string
+
+const (
+	// CookieMode requires the unsafe requests to send back, in the Header or the Field, the token of the cookie: the
+	// double-submit cookie pattern, for apps with forms.
+	CookieMode Mode = "cookie"
+
+	// HeaderMode requires the unsafe requests to have the Header, which other sites cannot set on requests to the app
+	// unless CORS allows them, for apps calling their API with JavaScript.
+	HeaderMode Mode = "header"
+)
+
+// Config describes the token and the requests the Middleware checks.
+type Config struct {
+	Mode           Mode
+	Cookie         string   // the name of the token cookie, prefixed with __Host- when it is Secure
+	Header         string   // the header carrying the token
+	Field          string   // the form field carrying the token
+	Secure         bool     // whether the cookie is only sent over HTTPS
+	Exempt         []string // the paths not checked: a path, or the paths under it if it ends with a slash
+	TrustedOrigins []string // the origins, such as https://admin.example.com, allowed to send requests too
+}
+
+// DefaultConfig returns a Config for the CookieMode with a Secure cookie.
+func DefaultConfig() *Config {
+	return &Config{Mode: CookieMode, Cookie: DefaultCookie, Header: DefaultHeader, Field: DefaultField, Secure: true}
+}
+
+func init() {
+	configschema.Register("csrf", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the CSRF protection variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "MODE", Type: "string", Default: string(CookieMode),
+			Description: "How requests are checked: cookie, or header."},
+		{Name: prefix + "COOKIE", Type: "string", Default: DefaultCookie, Description: "The name of the token cookie."},
+		{Name: prefix + "HEADER", Type: "string", Default: DefaultHeader,
+			Description: "The header carrying the token."},
+		{Name: prefix + "FIELD", Type: "string", Default: DefaultField,
+			Description: "The form field carrying the token."},
+		{Name: prefix + "SECURE", Type: "bool", Default: "true", Description: "Only send the cookie over HTTPS."},
+		{Name: prefix + "EXEMPT", Type: "string",
+			Description: "The paths not checked, separated by spaces or commas."},
+		{Name: prefix + "TRUSTED_ORIGINS", Type: "string",
+			Description: "The other origins allowed to send requests, separated by spaces or commas."},
+	}
+}
+
+// FromEnv reads the CSRF protection from MODE, cookie or header, the COOKIE, HEADER, and FIELD names carrying the
+// token, SECURE, and the EXEMPT paths and TRUSTED_ORIGINS, separated by spaces or commas, with the prefix or
+// DefaultPrefix. The names are checked to be valid in cookies and headers.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+	list := func(key string) []string {
+		return strings.FieldsFunc(get(key), func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	config := DefaultConfig()
+	switch v := get("MODE"); Mode(strings.ToLower(v)) {
+	case "", CookieMode:
+	case HeaderMode:
+		config.Mode = HeaderMode
+	default:
+		return nil, fmt.Errorf("csrf: invalid %sMODE %q", prefix, v)
+	}
+
+	for key, dst := range map[string]*string{
+		"COOKIE": &config.Cookie,
+		"HEADER": &config.Header,
+		"FIELD":  &config.Field,
+	} {
+		if v := get(key); v != "" {
+			if strings.ContainsAny(v, " \t;,=\"") {
+				return nil, fmt.Errorf("csrf: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = v
+		}
+	}
+
+	if v := get("SECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("csrf: invalid %sSECURE %q", prefix, v)
+		}
+		config.Secure = b
+	}
+
+	for _, p := range list("EXEMPT") {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("csrf: invalid %sEXEMPT path %q", prefix, p)
+		}
+		config.Exempt = append(config.Exempt, p)
+	}
+
+	for _, origin := range list("TRUSTED_ORIGINS") {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("csrf: invalid %sTRUSTED_ORIGINS origin %q", prefix, origin)
+		}
+		config.TrustedOrigins = append(config.TrustedOrigins, u.Scheme+"://"+u.Host)
+	}
+
+	return config, nil
+}
+
+// cookieName returns the name of the token cookie.
+func (c *Config) cookieName() string {
+	if c.Secure {
+		// a __Host- cookie cannot be set by the other hosts of the domain, or for a single path
+		return "__Host-" + c.Cookie
+	}
+	return c.Cookie
+}
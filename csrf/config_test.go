@@ -0,0 +1,65 @@
+package csrf_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/csrf"
+)
+
+func TestFromEnv_Mode(t *testing.T) {
+	config, err := csrf.FromEnv(apptest.Lookup(map[string]string{"ADMIN_CSRF_MODE": " Header "}), "ADMIN_CSRF_")
+	require.NoError(t, err)
+	assert.Equal(t, csrf.HeaderMode, config.Mode)
+
+	config, err = csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_SECURE": "false"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, csrf.CookieMode, config.Mode)
+	assert.False(t, config.Secure)
+
+	_, err = csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_MODE": "session"}), "")
+	assert.EqualError(t, err, `csrf: invalid APP_CSRF_MODE "session"`)
+}
+
+func TestFromEnv_Names(t *testing.T) {
+	config, err := csrf.FromEnv(apptest.Lookup(map[string]string{
+		"APP_CSRF_COOKIE": "xsrf",
+		"APP_CSRF_HEADER": "X-XSRF-Token",
+		"APP_CSRF_FIELD":  "_xsrf",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, [3]string{"xsrf", "X-XSRF-Token", "_xsrf"}, [3]string{config.Cookie, config.Header, config.Field})
+
+	// each of these would split or end the name in a Cookie or Set-Cookie header
+	for _, v := range []string{"a;b", "a=b", "a,b", "a b", `"a"`} {
+		_, err := csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_COOKIE": v}), "")
+		assert.EqualError(t, err, "csrf: invalid APP_CSRF_COOKIE "+strconv.Quote(v), v)
+	}
+}
+
+func TestFromEnv_Exempt(t *testing.T) {
+	config, err := csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_EXEMPT": "/webhooks/,/login /logout"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/webhooks/", "/login", "/logout"}, config.Exempt)
+
+	_, err = csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_EXEMPT": "/login webhooks/"}), "")
+	assert.EqualError(t, err, `csrf: invalid APP_CSRF_EXEMPT path "webhooks/"`)
+}
+
+func TestFromEnv_TrustedOrigins(t *testing.T) {
+	// an origin is kept as the browser sends it: the scheme and host, without a trailing slash
+	config, err := csrf.FromEnv(apptest.Lookup(map[string]string{
+		"APP_CSRF_TRUSTED_ORIGINS": "https://admin.example.com/, http://localhost:3000",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://admin.example.com", "http://localhost:3000"}, config.TrustedOrigins)
+
+	for _, v := range []string{"admin.example.com", "ftp://admin.example.com", "https://", "https://a.com/b"} {
+		_, err := csrf.FromEnv(apptest.Lookup(map[string]string{"APP_CSRF_TRUSTED_ORIGINS": v}), "")
+		assert.EqualError(t, err, `csrf: invalid APP_CSRF_TRUSTED_ORIGINS origin "`+v+`"`, v)
+	}
+}
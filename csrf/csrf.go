@@ -0,0 +1,216 @@
+// Package csrf protects the users of web apps from cross-site request forgery: other sites making their browser send
+// requests to the app, with their cookies, such as their session cookie, see sessions.
+//
+//	config, err := csrf.FromEnv(a.LookupEnv, "")
+//	handler := csrf.New(config).Wrap(mux)
+//
+//	tpl := template.Must(template.New("").Funcs(csrf.Funcs()).ParseFS(templates, "*.html"))
+//
+//	<form method="post" action="/settings">
+//		{{csrfField .Request}}
+//		...
+//	</form>
+//
+// The unsafe requests, those with other methods than GET, HEAD, OPTIONS, and TRACE, are checked unless their path is
+// Exempt. In the CookieMode, the default, the Middleware gives every browser a random token in a cookie, which the
+// pages of the app send back with their unsafe requests, in a form field or a header; other sites cannot read the
+// cookie to do so. Token returns the token to send, masked differently each time so that it cannot be guessed from
+// compressed responses. In the HeaderMode, there is no token, and the unsafe requests only have to carry the Header.
+//
+// In both modes, unsafe requests whose Origin header, or Sec-Fetch-Site header, shows that they come from a page of
+// another origin are refused, unless the origin is trusted. The requests refused are answered with 403 Forbidden
+// problem details; see the problem package.
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// tokenLength is the length of the tokens, in bytes.
+const tokenLength = 32
+
+// Middleware is HTTP middleware refusing the forged requests.
+type Middleware struct {
+	config Config
+}
+
+// New returns Middleware checking the requests as config describes.
+func New(config *Config) *Middleware {
+	return &Middleware{config: *config}
+}
+
+type contextKey struct{}
+
+// token is what the context of a request carries: the token of the cookie, and the form field it is sent back in.
+type token struct {
+	value []byte
+	field string
+}
+
+// Wrap returns a handler calling next with the requests that are not forged, with the token in their context.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.config.Mode == CookieMode {
+			t := &token{field: m.config.Field}
+			if c, err := r.Cookie(m.config.cookieName()); err == nil {
+				if b := decode(c.Value); len(b) == tokenLength {
+					t.value = b
+				}
+			}
+			if t.value == nil {
+				t.value = make([]byte, tokenLength)
+				_, _ = rand.Read(t.value)
+				http.SetCookie(w, &http.Cookie{
+					Name:     m.config.cookieName(),
+					Value:    base64.RawURLEncoding.EncodeToString(t.value),
+					Path:     "/",
+					Secure:   m.config.Secure,
+					HttpOnly: true,
+					// Lax, so that a page reached from another site has the cookie, and does not replace it
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, t))
+			w.Header().Add("Vary", "Cookie")
+		}
+
+		if safe(r.Method) || m.exempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !m.sameOrigin(r) {
+			forbidden(w, r, "cross-origin request")
+			return
+		}
+		if m.config.Mode == HeaderMode {
+			if r.Header.Get(m.config.Header) == "" {
+				forbidden(w, r, "missing "+m.config.Header+" header")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		t, _ := r.Context().Value(contextKey{}).(*token)
+		sent := r.Header.Get(m.config.Header)
+		if sent == "" {
+			// the body is only parsed for the form field when the header is missing
+			sent = r.PostFormValue(m.config.Field)
+		}
+		if sent == "" {
+			forbidden(w, r, "missing CSRF token")
+			return
+		}
+		if !valid(t.value, sent) {
+			forbidden(w, r, "invalid CSRF token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exempt reports whether path is Exempt.
+func (m *Middleware) exempt(path string) bool {
+	for _, p := range m.config.Exempt {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether r comes from a page of the app, or of a TrustedOrigin, as far as the browser tells.
+func (m *Middleware) sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "null" {
+		// without an Origin, Sec-Fetch-Site tells where the request comes from if the browser sends it, and the token
+		// or the Header decides otherwise
+		site := r.Header.Get("Sec-Fetch-Site")
+		return site != "cross-site" && site != "same-site"
+	}
+	for _, trusted := range m.config.TrustedOrigins {
+		if origin == trusted {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// Token returns the token the page answering r sends back with its unsafe requests, masked differently each time,
+// or an empty string if r has no token, as in the HeaderMode.
+func Token(r *http.Request) string {
+	t, ok := r.Context().Value(contextKey{}).(*token)
+	if !ok {
+		return ""
+	}
+	b := make([]byte, 2*tokenLength)
+	_, _ = rand.Read(b[:tokenLength])
+	subtle.XORBytes(b[tokenLength:], b[:tokenLength], t.value)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// TemplateField returns the hidden form field sending back the Token of r, for HTML templates.
+func TemplateField(r *http.Request) template.HTML {
+	t, ok := r.Context().Value(contextKey{}).(*token)
+	if !ok {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(t.field) + `" value="` +
+		Token(r) + `">`)
+}
+
+// Funcs returns the functions csrfToken and csrfField, calling Token and TemplateField, for HTML templates.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": Token,
+		"csrfField": TemplateField,
+	}
+}
+
+// valid reports whether sent, masked or not, is the token.
+func valid(token []byte, sent string) bool {
+	b := decode(sent)
+	if len(b) == 2*tokenLength {
+		subtle.XORBytes(b[tokenLength:], b[tokenLength:], b[:tokenLength])
+		b = b[tokenLength:]
+	}
+	return len(b) == tokenLength && subtle.ConstantTimeCompare(b, token) == 1
+}
+
+// decode returns the bytes of a token, or nil if s is not one.
+func decode(s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || (len(b) != tokenLength && len(b) != 2*tokenLength) {
+		return nil
+	}
+	return b
+}
+
+// safe reports whether method is safe, and so cannot be forged to change anything.
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// forbidden answers r with 403 Forbidden.
+func forbidden(w http.ResponseWriter, r *http.Request, detail string) {
+	p := problem.New(http.StatusForbidden, "%s", detail)
+	if id, ok := correlation.FromContext(r.Context()); ok {
+		p.RequestID = id
+	}
+	problem.Write(w, p)
+}
@@ -0,0 +1,185 @@
+package csrf_test
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/csrf"
+)
+
+// page renders a form with the token field.
+var page = template.Must(template.New("page").Funcs(csrf.Funcs()).Parse(
+	`<form method="post">{{csrfField .}}</form>{{csrfToken .}}`))
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		_ = page.Execute(w, r)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware_Cookie(t *testing.T) {
+	config := csrf.DefaultConfig()
+	config.Exempt = []string{"/webhooks/"}
+	h := csrf.New(config).Wrap(ok)
+
+	// the page gets a token cookie and the masked token
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/settings", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, "__Host-csrf", cookie.Name)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, "/", cookie.Path)
+	assert.Equal(t, "Cookie", w.Header().Get("Vary"))
+
+	pattern := regexp.MustCompile(`^<form method="post"><input type="hidden" name="csrf_token" value="([\w-]+)"></form>` +
+		`([\w-]+)$`)
+	m := pattern.FindStringSubmatch(w.Body.String())
+	require.NotNil(t, m, w.Body.String())
+	field, token := m[1], m[2]
+	assert.NotEqual(t, field, token, "the token is masked differently each time")
+	assert.NotEqual(t, cookie.Value, field)
+
+	// a page requested with the cookie keeps it
+	r := httptest.NewRequest(http.MethodGet, "/settings", nil)
+	r.AddCookie(cookie)
+	assert.Empty(t, serve(h, r).Result().Cookies())
+
+	post := func(body, header string, cookie *http.Cookie) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/settings", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			r.Header.Set("X-CSRF-Token", header)
+		}
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+
+	for name, r := range map[string]*http.Request{
+		"field":          post("csrf_token="+url.QueryEscape(field), "", cookie),
+		"header":         post("", token, cookie),
+		"unmasked token": post("", cookie.Value, cookie),
+	} {
+		w := serve(h, r)
+		assert.Equal(t, http.StatusOK, w.Code, name)
+		assert.Equal(t, "ok", w.Body.String(), name)
+	}
+
+	other := &http.Cookie{Name: "__Host-csrf", Value: strings.Repeat("A", 43)}
+	for name, tt := range map[string]struct {
+		r      *http.Request
+		detail string
+	}{
+		"no token":     {post("name=x", "", cookie), "missing CSRF token"},
+		"no cookie":    {post("", token, nil), "invalid CSRF token"},
+		"other cookie": {post("", token, other), "invalid CSRF token"},
+		"bad token":    {post("", token[:len(token)-2], cookie), "invalid CSRF token"},
+	} {
+		w := serve(h, tt.r)
+		assert.Equal(t, http.StatusForbidden, w.Code, name)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), name)
+		assert.Contains(t, w.Body.String(), tt.detail, name)
+	}
+
+	// exempt paths need no token
+	w = serve(h, httptest.NewRequest(http.MethodPost, "/webhooks/stripe", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(h, httptest.NewRequest(http.MethodPost, "/webhooks", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMiddleware_Origin(t *testing.T) {
+	config := csrf.DefaultConfig()
+	config.Secure = false
+	config.TrustedOrigins = []string{"https://admin.example.com"}
+	h := csrf.New(config).Wrap(ok)
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	cookie := w.Result().Cookies()[0]
+	assert.Equal(t, "csrf", cookie.Name)
+	assert.False(t, cookie.Secure)
+
+	for origin, allowed := range map[string]bool{
+		"http://example.com":        true,
+		"https://admin.example.com": true,
+		"https://evil.example":      false,
+		"http://example.com.evil":   false,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/settings", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("X-CSRF-Token", cookie.Value)
+		r.AddCookie(cookie)
+		w := serve(h, r)
+		assert.Equal(t, allowed, w.Code == http.StatusOK, origin)
+	}
+
+	for site, allowed := range map[string]bool{"": true, "same-origin": true, "same-site": false, "cross-site": false} {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/settings", nil)
+		if site != "" {
+			r.Header.Set("Sec-Fetch-Site", site)
+		}
+		r.Header.Set("X-CSRF-Token", cookie.Value)
+		r.AddCookie(cookie)
+		w := serve(h, r)
+		assert.Equal(t, allowed, w.Code == http.StatusOK, site)
+		if !allowed {
+			assert.Contains(t, w.Body.String(), "cross-origin request")
+		}
+	}
+}
+
+func TestMiddleware_Header(t *testing.T) {
+	config := csrf.DefaultConfig()
+	config.Mode = csrf.HeaderMode
+	h := csrf.New(config).Wrap(ok)
+
+	// there is no token
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, w.Result().Cookies())
+	assert.Equal(t, `<form method="post"></form>`, w.Body.String())
+
+	r := httptest.NewRequest(http.MethodDelete, "/items/1", nil)
+	r.Header.Set("X-CSRF-Token", "1")
+	assert.Equal(t, http.StatusOK, serve(h, r).Code)
+
+	w = serve(h, httptest.NewRequest(http.MethodDelete, "/items/1", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "missing X-CSRF-Token header")
+
+	r = httptest.NewRequest(http.MethodDelete, "/items/1", nil)
+	r.Header.Set("X-CSRF-Token", "1")
+	r.Header.Set("Origin", "https://evil.example")
+	assert.Equal(t, http.StatusForbidden, serve(h, r).Code)
+}
+
+func TestTemplateField(t *testing.T) {
+	// without the Middleware there is no token
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, csrf.Token(r))
+	assert.Empty(t, csrf.TemplateField(r))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, page.Execute(buf, r))
+	assert.Equal(t, `<form method="post"></form>`, buf.String())
+}
@@ -0,0 +1,295 @@
+// Package devcert issues HTTPS certificates for local development, as mkcert does: a certificate authority private to
+// the user signs a certificate for the names a server is reached at, such as localhost and 127.0.0.1. Once the
+// certificate of the authority is trusted, by adding the CertFile to the trust store of the system or the browser,
+// local HTTPS works without warnings:
+//
+//	ca, err := devcert.Load(dir)
+//	config, err := ca.TLSConfig("localhost", "127.0.0.1", "::1")
+//	server.TLSConfig = config
+//
+// The authority and the certificates are kept in a directory, so they are the same from run to run. The app
+// HTTPServer uses one in the data directory of the app when APP_ENV is development.
+package devcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+const (
+	// CAValidity is how long the certificate of an authority is valid.
+	CAValidity = 10 * 365 * 24 * time.Hour
+
+	// Validity is how long an issued certificate is valid, within the 825 days browsers accept.
+	Validity = 820 * 24 * time.Hour
+
+	// RenewBefore is how long before it expires an issued certificate is replaced.
+	RenewBefore = 30 * 24 * time.Hour
+)
+
+// DefaultHosts are the names of the local machine certificates are issued for when none are given.
+var DefaultHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// Authority is a certificate authority for local development, kept in a directory.
+type Authority struct {
+	dir  string
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// Load returns the Authority kept in dir, creating it, and the directory, if it does not exist.
+func Load(dir string) (*Authority, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	ca := &Authority{dir: dir}
+
+	cert, key, err := readPair(ca.CertFile(), filepath.Join(dir, "rootCA-key.pem"))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ca, ca.create()
+	case err != nil:
+		return nil, err
+	case !cert.IsCA:
+		return nil, fmt.Errorf("devcert: %s is not a certificate authority", ca.CertFile())
+	}
+	ca.cert, ca.key = cert, key
+	return ca, nil
+}
+
+// CertFile returns the path of the PEM encoded certificate of the Authority, to add to a trust store.
+func (ca *Authority) CertFile() string {
+	return filepath.Join(ca.dir, "rootCA.pem")
+}
+
+// Certificate returns the certificate of the Authority.
+func (ca *Authority) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// CertPool returns a pool with the certificate of the Authority, for clients to trust the certificates it issues.
+func (ca *Authority) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *Authority) create() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"devcert development CA"},
+			OrganizationalUnit: []string{owner()},
+			CommonName:         "devcert " + owner(),
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+
+	if err := writePair(ca.CertFile(), filepath.Join(ca.dir, "rootCA-key.pem"), der, key); err != nil {
+		return err
+	}
+	ca.cert, ca.key = cert, key
+	return nil
+}
+
+// Issue returns a certificate for hosts, names and IP addresses, or DefaultHosts if there are none. A certificate
+// issued before for the same hosts is reused until RenewBefore it expires.
+func (ca *Authority) Issue(hosts ...string) (*tls.Certificate, error) {
+	if hosts = normalize(hosts); len(hosts) == 0 {
+		hosts = normalize(DefaultHosts)
+	}
+
+	// the files are named after the first host, made safe for any file system, and a hash of all of them
+	sum := sha256.Sum256([]byte(strings.Join(hosts, ",")))
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, hosts[0])
+	base := filepath.Join(ca.dir, name+"-"+hex.EncodeToString(sum[:4]))
+	certFile, keyFile := base+".pem", base+"-key.pem"
+
+	if cert, key, err := readPair(certFile, keyFile); err == nil && ca.valid(cert) {
+		return &tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization:       []string{"devcert development certificate"},
+			OrganizationalUnit: []string{owner()},
+		},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(Validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePair(certFile, keyFile, der, key); err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, nil
+}
+
+// valid reports whether cert was issued by the Authority and does not expire within RenewBefore.
+func (ca *Authority) valid(cert *x509.Certificate) bool {
+	return cert.CheckSignatureFrom(ca.cert) == nil && time.Until(cert.NotAfter) > RenewBefore
+}
+
+// TLSConfig returns a server *tls.Config presenting the certificate Issue returns for hosts.
+func (ca *Authority) TLSConfig(hosts ...string) (*tls.Config, error) {
+	cert, err := ca.Issue(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{*cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// normalize returns the hosts lowercased, sorted, and without duplicates.
+func normalize(hosts []string) []string {
+	set := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.Trim(strings.TrimSpace(h), "[]")); h != "" {
+			set[h] = true
+		}
+	}
+	list := make([]string, 0, len(set))
+	for h := range set {
+		list = append(list, h)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// owner returns user@host, naming whose certificates they are.
+func owner() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
+
+func serialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// readPair reads a PEM certificate and its PKCS #8 key.
+func readPair(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("devcert: invalid PEM in %s or %s", certFile, keyFile)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("devcert: %s: %w", certFile, err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("devcert: %s: %w", keyFile, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("devcert: %s: unsupported key", keyFile)
+	}
+	if pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(cert.PublicKey) {
+		return nil, nil, fmt.Errorf("devcert: %s does not match %s", keyFile, certFile)
+	}
+	return cert, signer, nil
+}
+
+// writePair writes a certificate and its key as PEM files, the key readable by the user only.
+func writePair(certFile, keyFile string, der []byte, key crypto.Signer) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := atomicfile.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
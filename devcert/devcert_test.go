@@ -0,0 +1,75 @@
+package devcert_test
+
+import (
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/devcert"
+)
+
+func TestAuthority(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "devcert")
+	ca, err := devcert.Load(dir)
+	require.NoError(t, err)
+	assert.True(t, ca.Certificate().IsCA)
+	assert.FileExists(t, ca.CertFile())
+
+	cert, err := ca.Issue("LOCALHOST", "127.0.0.1", "[::1]", "app.test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.test", "localhost"}, cert.Leaf.DNSNames)
+	assert.Len(t, cert.Leaf.IPAddresses, 2)
+	for _, host := range []string{"localhost", "app.test", "127.0.0.1", "::1"} {
+		_, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: ca.CertPool()})
+		assert.NoError(t, err, host)
+	}
+
+	// the authority and the certificate are kept
+	again, err := devcert.Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ca.Certificate().Raw, again.Certificate().Raw)
+	reissued, err := again.Issue("app.test", "localhost", "::1", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, cert.Certificate, reissued.Certificate)
+
+	other, err := again.Issue()
+	require.NoError(t, err)
+	assert.NotEqual(t, cert.Certificate, other.Certificate)
+	assert.True(t, other.Leaf.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) ||
+		other.Leaf.IPAddresses[1].Equal(net.ParseIP("127.0.0.1")))
+
+	// a certificate issued by another authority is replaced
+	foreignDir := t.TempDir()
+	foreign, err := devcert.Load(foreignDir)
+	require.NoError(t, err)
+	_, err = foreign.Issue()
+	require.NoError(t, err)
+	issued, err := filepath.Glob(filepath.Join(foreignDir, "127.0.0.1-*"))
+	require.NoError(t, err)
+	require.Len(t, issued, 2)
+	for _, path := range issued {
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.Base(path)), b, 0o600))
+	}
+	cert, err = ca.Issue()
+	require.NoError(t, err)
+	assert.NoError(t, cert.Leaf.CheckSignatureFrom(ca.Certificate()))
+
+	config, err := ca.TLSConfig()
+	require.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rootCA.pem"), []byte("nope"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rootCA-key.pem"), []byte("nope"), 0o600))
+	_, err := devcert.Load(dir)
+	assert.ErrorContains(t, err, "devcert: invalid PEM in ")
+}
@@ -0,0 +1,50 @@
+package devrun
+
+import (
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+type commandOptions struct {
+	Build   string   `flag:"build,b,the command that builds the program before each run"`
+	Watch   []string `flag:"watch,w,the files and directories watched"`
+	Include []string `flag:"include,i,the patterns of the files whose changes start a new run"`
+	Command []string `arg:"command,the program to run and its arguments"`
+}
+
+// Command returns the dev command, which runs a program as Run, configured by config, or FromEnv if it is nil, and
+// its flags:
+//
+//	dev --build "go build -o bin/server ./cmd/server" --include "*.go" -- bin/server --addr :8080
+//
+// Register it with App.AddCommand.
+func Command(config *Config) *app.Command {
+	return app.NewCommand("dev", "run a program, starting it over when files change",
+		func(a *app.App, opts *commandOptions, _ []string) error {
+			c := config
+			if c == nil {
+				var err error
+				if c, err = FromEnv(a.LookupEnv, ""); err != nil {
+					return err
+				}
+			}
+			copied := *c
+			if opts.Build != "" {
+				copied.Build = strings.Fields(opts.Build)
+			}
+			if len(opts.Watch) > 0 {
+				copied.Paths = opts.Watch
+			}
+			if len(opts.Include) > 0 {
+				w := watch.DefaultConfig()
+				if copied.Watch != nil {
+					*w = *copied.Watch
+				}
+				w.Include = opts.Include
+				copied.Watch = w
+			}
+			return Run(a, &copied, opts.Command[0], opts.Command[1:]...)
+		})
+}
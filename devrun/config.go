@@ -0,0 +1,95 @@
+package devrun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+const (
+	// DefaultPrefix prefixes the settings of the development mode, as in APP_DEV_PATHS.
+	DefaultPrefix = "APP_DEV_"
+
+	// DefaultThrottle is the minimum time between two runs.
+	DefaultThrottle = time.Second
+
+	// DefaultStopTimeout is how long a run may take to stop before it is killed.
+	DefaultStopTimeout = 5 * time.Second
+)
+
+// Config describes what Run watches, builds, and runs.
+type Config struct {
+	Paths       []string      // the files and directories watched, the working directory if empty
+	Build       []string      // the command run to completion before each run, such as go build; none if empty
+	Throttle    time.Duration // the minimum time between two runs
+	StopTimeout time.Duration // how long a run may take to stop before it is killed
+	Label       string        // the prefix of the output lines of the runs, the base name of the command if empty
+	Watch       *watch.Config // which changes start a new run, watch.DefaultConfig if nil
+}
+
+// DefaultConfig returns the Config with DefaultThrottle and DefaultStopTimeout.
+func DefaultConfig() *Config {
+	return &Config{Throttle: DefaultThrottle, StopTimeout: DefaultStopTimeout}
+}
+
+func init() {
+	configschema.Register("devrun", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the variables of the development runner with the prefix, the WATCH_ ones included.
+func ConfigKeys(prefix string) []configschema.Key {
+	keys := []configschema.Key{
+		{Name: prefix + "PATHS", Type: "string",
+			Description: "The files and directories watched, comma separated; the working directory if not set."},
+		{Name: prefix + "BUILD", Type: "string", Description: "The command run to completion before each run."},
+		{Name: prefix + "THROTTLE", Type: "duration", Default: DefaultThrottle.String(),
+			Description: "The minimum time between two runs."},
+		{Name: prefix + "STOP_TIMEOUT", Type: "duration", Default: DefaultStopTimeout.String(),
+			Description: "How long a run may take to stop before it is killed."},
+		{Name: prefix + "LABEL", Type: "string", Description: "The prefix of the output lines of the runs."},
+	}
+	return append(keys, watch.ConfigKeys(prefix+"WATCH_")...)
+}
+
+// FromEnv reads how to run the app in development, with the prefix or DefaultPrefix: the comma separated PATHS watched,
+// the BUILD command, split into words at spaces, THROTTLE, STOP_TIMEOUT, and the LABEL of the output. Which changes
+// start a run is read by watch.FromEnv with the prefix followed by WATCH_, such as APP_DEV_WATCH_INCLUDE.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	for _, p := range strings.Split(get("PATHS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			config.Paths = append(config.Paths, p)
+		}
+	}
+	config.Build = strings.Fields(get("BUILD"))
+	config.Label = get("LABEL")
+
+	for key, dst := range map[string]*time.Duration{"THROTTLE": &config.Throttle, "STOP_TIMEOUT": &config.StopTimeout} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("devrun: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	w, err := watch.FromEnv(lookup, prefix+"WATCH_")
+	if err != nil {
+		return nil, err
+	}
+	config.Watch = w
+	return config, nil
+}
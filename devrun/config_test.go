@@ -0,0 +1,58 @@
+package devrun_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/devrun"
+)
+
+func TestFromEnv_Command(t *testing.T) {
+	config, err := devrun.FromEnv(apptest.Lookup(map[string]string{
+		"APP_DEV_PATHS": "cmd,, internal ,",
+		"APP_DEV_BUILD": "  go build  -o bin/server ./cmd/server ",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cmd", "internal"}, config.Paths)
+	// the command is split at spaces, without a shell, so runs of spaces do not make empty arguments
+	assert.Equal(t, []string{"go", "build", "-o", "bin/server", "./cmd/server"}, config.Build)
+
+	config, err = devrun.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Nil(t, config.Paths)
+	assert.Empty(t, config.Build)
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	// zero restarts on every change and kills the old process at once
+	config, err := devrun.FromEnv(apptest.Lookup(map[string]string{
+		"APP_DEV_THROTTLE":     "0s",
+		"APP_DEV_STOP_TIMEOUT": "0s",
+	}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Throttle)
+	assert.Zero(t, config.StopTimeout)
+
+	for _, v := range []string{"soon", "-1s", "2"} {
+		_, err := devrun.FromEnv(apptest.Lookup(map[string]string{"APP_DEV_STOP_TIMEOUT": v}), "")
+		assert.EqualError(t, err, `devrun: invalid APP_DEV_STOP_TIMEOUT "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Watch(t *testing.T) {
+	// the watch variables nest under the prefix, whatever it is
+	config, err := devrun.FromEnv(apptest.Lookup(map[string]string{
+		"WORKER_LABEL":          "worker",
+		"WORKER_WATCH_INCLUDE":  "*.go",
+		"APP_DEV_WATCH_INCLUDE": "*.tmpl",
+	}), "WORKER_")
+	require.NoError(t, err)
+	assert.Equal(t, "worker", config.Label)
+	assert.Equal(t, []string{"*.go"}, config.Watch.Include)
+
+	_, err = devrun.FromEnv(apptest.Lookup(map[string]string{"WORKER_WATCH_DEBOUNCE": "bad"}), "WORKER_")
+	assert.EqualError(t, err, `watch: invalid WORKER_WATCH_DEBOUNCE "bad"`)
+}
@@ -0,0 +1,243 @@
+// Package devrun runs a program in development, starting it over whenever its sources change, as tools like air do:
+//
+//	config, err := devrun.FromEnv(a.LookupEnv, "")
+//	config.Build = []string{"go", "build", "-o", "bin/server", "./cmd/server"}
+//	return devrun.Run(a, config, "bin/server", "--addr", ":8080")
+//
+// On a change, the run is stopped, gracefully first, then the program is built, if there is a build command, and
+// started again. Runs start at most once per Throttle, however often files change. The output lines of the build and
+// the runs are prefixed with a label to tell them from those of the app. Command returns a dev command doing the same
+// for any program.
+package devrun
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+// Run runs the program name with args, building it first if config has a Build command, and runs it again whenever
+// the watched files change, until the CommandContext is done. A run that exits on its own is not restarted until the
+// next change. config may be nil for the DefaultConfig.
+func Run(a *app.App, config *Config, name string, args ...string) error {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	paths := config.Paths
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	w, err := watch.New(a, config.Watch, paths...)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	label := config.Label
+	if label == "" {
+		label = filepath.Base(name)
+	}
+	r := &runner{a: a, config: config, name: name, args: args, prefix: "[" + label + "] "}
+	r.start()
+	defer r.stop()
+
+	ctx := a.CommandContext()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.Events():
+			if !ok {
+				return nil
+			}
+			if wait := config.Throttle - time.Since(r.started); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			// the changes made while throttled are part of this run
+			for drained := false; !drained; {
+				select {
+				case more := <-w.Events():
+					ev.Paths = append(ev.Paths, more.Paths...)
+				default:
+					drained = true
+				}
+			}
+
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"files": len(ev.Paths)})
+			_ = a.Logger().Infom(attrs, "%s changed, restarting", ev.Paths[0])
+			r.stop()
+			r.start()
+		}
+	}
+}
+
+// runner starts and stops the runs of a program.
+type runner struct {
+	a       *app.App
+	config  *Config
+	name    string
+	args    []string
+	prefix  string
+	started time.Time
+	current *run
+}
+
+// run is a running program.
+type run struct {
+	child   *app.Child
+	stopped bool // set by stop, so that the exit is not reported
+	mu      sync.Mutex
+}
+
+// start builds the program, if there is a Build command, and starts it. Failures are logged, leaving no run until
+// the next change.
+func (r *runner) start() {
+	r.started = time.Now()
+	if len(r.config.Build) > 0 {
+		if err := r.build(); err != nil {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"error": err.Error()})
+			_ = r.a.Logger().Errorm(attrs, "build failed, waiting for changes")
+			return
+		}
+	}
+
+	child := r.a.Command(r.name, r.args...)
+	stdout, stderr := r.output(child)
+	if err := child.Start(); err != nil {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"error": err.Error()})
+		_ = r.a.Logger().Errorm(attrs, "unable to start %s, waiting for changes", r.name)
+		return
+	}
+	current := &run{child: child}
+	r.current = current
+
+	go func() {
+		err := child.Wait()
+		_ = stdout.Flush()
+		_ = stderr.Flush()
+
+		current.mu.Lock()
+		defer current.mu.Unlock()
+		if current.stopped {
+			return
+		}
+		if err != nil {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"error": err.Error()})
+			_ = r.a.Logger().Warnm(attrs, "%s failed, waiting for changes", r.name)
+		} else {
+			_ = r.a.Logger().Infof("%s exited, waiting for changes", r.name)
+		}
+	}()
+}
+
+// build runs the Build command to completion.
+func (r *runner) build() error {
+	child := r.a.Command(r.config.Build[0], r.config.Build[1:]...)
+	stdout, stderr := r.output(child)
+	err := child.Start()
+	if err == nil {
+		err = child.Wait()
+	}
+	_ = stdout.Flush()
+	_ = stderr.Flush()
+	return err
+}
+
+// output sends the output of child to the app Output and ErrOutput, each line prefixed with the label.
+func (r *runner) output(child *app.Child) (stdout, stderr *prefixWriter) {
+	stdout = &prefixWriter{w: r.a.Output(), prefix: r.prefix}
+	stderr = &prefixWriter{w: r.a.ErrOutput(), prefix: r.prefix}
+	child.Cmd.Stdin = nil
+	child.Cmd.Stdout, child.Cmd.Stderr = stdout, stderr
+	return stdout, stderr
+}
+
+// stop stops the current run, with SIGTERM, then by killing it if it is still running after the StopTimeout.
+func (r *runner) stop() {
+	current := r.current
+	if current == nil {
+		return
+	}
+	r.current = nil
+
+	current.mu.Lock()
+	current.stopped = true
+	current.mu.Unlock()
+
+	child := current.child
+	select {
+	case <-child.Done():
+		return
+	default:
+	}
+
+	_ = child.Signal(syscall.SIGTERM)
+	timer := time.NewTimer(r.config.StopTimeout)
+	defer timer.Stop()
+	select {
+	case <-child.Done():
+	case <-timer.C:
+		_ = r.a.Logger().Warnf("%s still running after %s, killing it", r.name, r.config.StopTimeout)
+		_ = child.Signal(os.Kill)
+		<-child.Done()
+	}
+}
+
+// prefixWriter writes the lines written to it to w, each with the prefix. An incomplete last line is held until it
+// is complete or Flush is called.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf = append(p.buf, b...)
+	var out []byte
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		out = append(out, p.prefix...)
+		out = append(out, p.buf[:i+1]...)
+		p.buf = p.buf[i+1:]
+	}
+	if len(out) > 0 {
+		if _, err := p.w.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes the incomplete last line, if any, ending it.
+func (p *prefixWriter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.buf) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+	p.buf = nil
+	return err
+}
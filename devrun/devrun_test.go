@@ -0,0 +1,119 @@
+//go:build !windows
+// +build !windows
+
+package devrun_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/devrun"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while the runs write to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// start runs Run, or the dev command when args are given, in the background, returning the output of the app and
+// the function that stops it.
+func start(t *testing.T, config *devrun.Config, args ...string) (*syncBuffer, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out := new(syncBuffer)
+	a := &app.App{Context: ctx, Arguments: args, Stdin: new(bytes.Buffer), Stdout: out, Stderr: new(syncBuffer)}
+
+	done := make(chan error, 1)
+	go func() {
+		if len(args) > 0 {
+			a.AddCommand(devrun.Command(config))
+			done <- a.Dispatch()
+			return
+		}
+		done <- devrun.Run(a, config, "sh", "-c", `
+			echo "run $(cat version)"
+			printf partial
+			trap 'echo; echo stopping; exit 0' TERM
+			while :; do sleep 0.05; done
+		`)
+	}()
+
+	return out, func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("Run did not return when the context was done")
+		}
+	}
+}
+
+func contains(out *syncBuffer, s string) func() bool {
+	return func() bool { return strings.Contains(out.String(), s) }
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile("version", []byte("1"), 0o644))
+
+	config := devrun.DefaultConfig()
+	config.Throttle = 0
+	config.Label = "web"
+	config.Build = []string{"sh", "-c", "echo building"}
+	config.Watch = &watch.Config{Debounce: 20 * time.Millisecond, Include: []string{"version"}}
+	out, stop := start(t, config)
+
+	require.Eventually(t, contains(out, "[web] run 1\n"), 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, out.String(), "[web] building\n")
+
+	require.NoError(t, os.WriteFile("version", []byte("2"), 0o644))
+	require.Eventually(t, contains(out, "[web] run 2\n"), 5*time.Second, 10*time.Millisecond)
+	stop()
+
+	assert.Contains(t, out.String(), "[web] partial\n[web] stopping\n")
+	assert.Equal(t, 2, strings.Count(out.String(), "[web] building\n"))
+}
+
+func TestRun_Kill(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "version"), []byte("1"), 0o644))
+
+	config := devrun.DefaultConfig()
+	config.Paths = []string{dir}
+	config.Throttle = 0
+	config.StopTimeout = 100 * time.Millisecond
+	config.Watch = &watch.Config{Debounce: 20 * time.Millisecond}
+	out, stop := start(t, config, "dev", "--", "sh", "-c", `trap '' TERM; echo started; while :; do sleep 0.05; done`)
+	defer stop()
+
+	require.Eventually(t, contains(out, "[sh] started\n"), 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "version"), []byte("2"), 0o644))
+	require.Eventually(t, func() bool {
+		return strings.Count(out.String(), "[sh] started\n") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
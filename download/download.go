@@ -0,0 +1,299 @@
+// Package download fetches files over HTTP for installers and updaters. File streams the file to a partial file next
+// to its destination, resuming it with a range request when the transfer is interrupted or the command is run again,
+// verifies its SHA-256 digest and Ed25519 signature, and only then renames it into place, so the destination is never
+// a partial or unverified file:
+//
+//	err := download.File(a.Context, a, release.URL, "/usr/local/bin/tool", &download.Options{
+//		SHA256: release.SHA256,
+//		Mode:   0o755,
+//	})
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// DefaultMaxRetries is the number of times an interrupted transfer is resumed.
+const DefaultMaxRetries = 3
+
+var (
+	// ErrChecksum is returned by File when the file does not have the SHA-256 digest it should.
+	ErrChecksum = errors.New("download: checksum mismatch")
+
+	// ErrSignature is returned by File when the signature of the file does not verify.
+	ErrSignature = errors.New("download: invalid signature")
+)
+
+// The delay before the first retry, doubling with every retry, and its cap.
+var (
+	retryBackoff = time.Second
+	maxBackoff   = 30 * time.Second
+)
+
+// Options describe how a file is verified and kept. The zero value verifies nothing.
+type Options struct {
+	SHA256     string            // hex SHA-256 digest the file must have, if set
+	PublicKey  ed25519.PublicKey // key the Signature must verify with, if set
+	Signature  []byte            // Ed25519ph signature of the file, as in RFC 8032, required with PublicKey
+	Mode       os.FileMode       // permissions of the file, 0644 if zero
+	MaxRetries int               // times an interrupted transfer is resumed; DefaultMaxRetries if zero, none below
+	Client     *http.Client      // the client sending the requests, http.DefaultClient if nil
+}
+
+// File downloads the file at url to dest, showing its progress with App.NewProgress. It is streamed to dest.part,
+// which is kept if the transfer fails or ctx is done so that the next call resumes it, and renamed to dest once it is
+// complete and verified. A file that fails verification is deleted, and File returns an error wrapping ErrChecksum or
+// ErrSignature.
+func File(ctx context.Context, a *app.App, url, dest string, opts *Options) error {
+	if opts == nil {
+		opts = new(Options)
+	}
+	d := &download{url: url, part: dest + ".part", opts: *opts}
+	if d.opts.SHA256 != "" {
+		digest, err := hex.DecodeString(d.opts.SHA256)
+		if err != nil || len(digest) != sha256.Size {
+			return fmt.Errorf("download: invalid SHA-256 digest %q", d.opts.SHA256)
+		}
+		d.digest = digest
+	}
+	if d.opts.PublicKey != nil && len(d.opts.PublicKey) != ed25519.PublicKeySize {
+		return errors.New("download: invalid public key")
+	}
+	if d.opts.Client == nil {
+		d.opts.Client = http.DefaultClient
+	}
+	if d.opts.MaxRetries == 0 {
+		d.opts.MaxRetries = DefaultMaxRetries
+	}
+	if d.opts.Mode == 0 {
+		d.opts.Mode = 0o644
+	}
+
+	progress := a.NewProgress(filepath.Base(dest), 0)
+	err := d.fetch(ctx, progress)
+	backoff := retryBackoff
+	for retries := 1; err != nil && retries <= d.opts.MaxRetries && errors.As(err, new(*retryError)); retries++ {
+		_ = a.Logger().Debugm(gomol.NewAttrsFromMap(map[string]interface{}{
+			"url":     url,
+			"retries": retries,
+		}), "download interrupted, resuming in %s: %v", backoff, err)
+		if err = sleep(ctx, backoff); err != nil {
+			break
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		err = d.fetch(ctx, progress)
+	}
+	progress.Done()
+	if err != nil {
+		return fmt.Errorf("download: %s: %w", url, err)
+	}
+
+	if err := d.verify(); err != nil {
+		_ = os.Remove(d.part)
+		_ = os.Remove(d.part + ".meta")
+		return err
+	}
+	if err := os.Chmod(d.part, d.opts.Mode); err != nil {
+		return err
+	}
+	if err := os.Rename(d.part, dest); err != nil {
+		return err
+	}
+	_ = os.Remove(d.part + ".meta")
+
+	_ = a.Logger().Debugm(gomol.NewAttrsFromMap(map[string]interface{}{"url": url, "path": dest}), "downloaded")
+	return nil
+}
+
+type download struct {
+	url    string
+	part   string
+	opts   Options
+	digest []byte
+}
+
+// retryError wraps the errors of a transfer worth resuming, such as a dropped connection or a 503 response.
+type retryError struct {
+	err error
+}
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// fetch transfers the file to the partial file, asking for the bytes it does not have yet if it has some and knows
+// the version of the file they are from, as recorded in the .meta file next to it.
+func (d *download) fetch(ctx context.Context, progress *app.Progress) error {
+	f, err := os.OpenFile(d.part, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return err
+	}
+	validator, _ := os.ReadFile(d.part + ".meta")
+	if offset > 0 && len(validator) > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		req.Header.Set("If-Range", string(validator))
+	}
+
+	res, err := d.opts.Client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &retryError{err}
+	}
+	defer res.Body.Close()
+
+	total := res.ContentLength
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		start, size := contentRange(res.Header.Get("Content-Range"))
+		if start != offset {
+			// start over rather than guess which bytes these are
+			_ = f.Truncate(0)
+			return &retryError{fmt.Errorf("unexpected Content-Range %q", res.Header.Get("Content-Range"))}
+		}
+		total = size
+
+	case http.StatusOK:
+		// the range was not asked for, or the file changed since the partial file was started
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if offset, err = f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := os.WriteFile(d.part+".meta", []byte(validatorOf(res.Header)), 0o600); err != nil {
+			return err
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		if _, size := contentRange(res.Header.Get("Content-Range")); size == offset {
+			return nil // the partial file is the whole file
+		}
+		_ = f.Truncate(0)
+		return &retryError{errors.New(res.Status)}
+
+	default:
+		err := errors.New(res.Status)
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusRequestTimeout ||
+			res.StatusCode == http.StatusTooManyRequests {
+			return &retryError{err}
+		}
+		return err
+	}
+
+	progress.Set(offset, total)
+
+	n, err := io.Copy(io.MultiWriter(f, progress), res.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &retryError{err}
+	}
+	if total >= 0 && offset+n != total {
+		return &retryError{fmt.Errorf("transfer ended after %d of %d bytes", offset+n, total)}
+	}
+	return f.Sync()
+}
+
+// verify checks the digest and the signature of the partial file.
+func (d *download) verify() error {
+	if d.digest == nil && d.opts.PublicKey == nil {
+		return nil
+	}
+
+	f, err := os.Open(d.part)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h256, h512 := sha256.New(), sha512.New()
+	if _, err := io.Copy(io.MultiWriter(h256, h512), f); err != nil {
+		return err
+	}
+
+	if d.digest != nil {
+		if sum := h256.Sum(nil); !bytes.Equal(sum, d.digest) {
+			return fmt.Errorf("%w: %s has SHA-256 %x, expected %x", ErrChecksum, d.url, sum, d.digest)
+		}
+	}
+	if d.opts.PublicKey != nil {
+		opts := &ed25519.Options{Hash: crypto.SHA512}
+		if ed25519.VerifyWithOptions(d.opts.PublicKey, h512.Sum(nil), d.opts.Signature, opts) != nil {
+			return fmt.Errorf("%w: %s", ErrSignature, d.url)
+		}
+	}
+	return nil
+}
+
+// validatorOf returns the validator of the file a response has, for If-Range: its strong ETag, or its Last-Modified.
+func validatorOf(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// contentRange returns the first byte and the size of the file in a Content-Range header such as "bytes 100-199/200"
+// or "bytes */200", with -1 for what it does not tell.
+func contentRange(v string) (start, size int64) {
+	start, size = -1, -1
+	v, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return start, size
+	}
+	r, s, _ := strings.Cut(v, "/")
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		size = n
+	}
+	if first, _, ok := strings.Cut(r, "-"); ok {
+		if n, err := strconv.ParseInt(first, 10, 64); err == nil {
+			start = n
+		}
+	}
+	return start, size
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package download_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/download"
+)
+
+var content = bytes.Repeat([]byte("0123456789abcdef"), 4096)
+
+func sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// serve serves content with ETag "v1", recording the Range header of every request. The connection of the first
+// request is dropped halfway through the body if drop is set.
+func serve(t *testing.T, drop bool) (*httptest.Server, *[]string) {
+	var ranges []string
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if drop && atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Content-Length", "65536")
+			_, _ = w.Write(content[:len(content)/2])
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &ranges
+}
+
+func TestFile(t *testing.T) {
+	srv, ranges := serve(t, false)
+	dest := filepath.Join(t.TempDir(), "file")
+
+	err := download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{
+		SHA256: sum(content),
+		Mode:   0o755,
+	})
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, b)
+	assert.Equal(t, []string{""}, *ranges)
+	assert.NoFileExists(t, dest+".part")
+	assert.NoFileExists(t, dest+".part.meta")
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(dest)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+	}
+}
+
+func TestFile_Resume(t *testing.T) {
+	defer download.SetRetryBackoff(time.Millisecond)()
+
+	srv, ranges := serve(t, true)
+	dest := filepath.Join(t.TempDir(), "file")
+
+	require.NoError(t, download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, nil))
+	b, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, b)
+	assert.Equal(t, []string{"", "bytes=32768-"}, *ranges)
+
+	// a partial file left by an earlier run is resumed, unless the file changed since
+	srv, ranges = serve(t, false)
+	require.NoError(t, os.WriteFile(dest+".part", content[:1000], 0o600))
+	require.NoError(t, os.WriteFile(dest+".part.meta", []byte(`"v1"`), 0o600))
+	require.NoError(t, download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{
+		SHA256: sum(content),
+	}))
+	assert.Equal(t, []string{"bytes=1000-"}, *ranges)
+
+	require.NoError(t, os.WriteFile(dest+".part", []byte("stale"), 0o600))
+	require.NoError(t, os.WriteFile(dest+".part.meta", []byte(`"v0"`), 0o600))
+	require.NoError(t, download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{
+		SHA256: sum(content),
+	}))
+	b, err = os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, b)
+}
+
+func TestFile_Verify(t *testing.T) {
+	srv, _ := serve(t, false)
+	dest := filepath.Join(t.TempDir(), "file")
+
+	err := download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{SHA256: sum(nil)})
+	assert.True(t, errors.Is(err, download.ErrChecksum), err)
+	assert.NoFileExists(t, dest)
+	assert.NoFileExists(t, dest+".part")
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	digest := sha512.Sum512(content)
+	sig, err := priv.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+	require.NoError(t, err)
+
+	err = download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{
+		PublicKey: pub,
+		Signature: sig[:len(sig)-1],
+	})
+	assert.True(t, errors.Is(err, download.ErrSignature), err)
+	assert.NoFileExists(t, dest)
+
+	err = download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{
+		PublicKey: pub,
+		Signature: sig,
+	})
+	require.NoError(t, err)
+	assert.FileExists(t, dest)
+
+	err = download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{SHA256: "abc"})
+	assert.EqualError(t, err, `download: invalid SHA-256 digest "abc"`)
+}
+
+func TestFile_Interrupted(t *testing.T) {
+	srv, _ := serve(t, true)
+	dest := filepath.Join(t.TempDir(), "file")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := download.File(ctx, apptest.New(t, nil), srv.URL, dest, nil)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.NoFileExists(t, dest)
+
+	// the interrupted transfer is kept for the next call to resume
+	err = download.File(context.Background(), apptest.New(t, nil), srv.URL, dest, &download.Options{MaxRetries: -1})
+	assert.Error(t, err)
+	assert.NoFileExists(t, dest)
+	info, err := os.Stat(dest + ".part")
+	require.NoError(t, err)
+	assert.EqualValues(t, len(content)/2, info.Size())
+}
@@ -0,0 +1,10 @@
+package download
+
+import "time"
+
+// SetRetryBackoff sets the delay before the first retry and returns a function restoring it.
+func SetRetryBackoff(d time.Duration) func() {
+	old := retryBackoff
+	retryBackoff = d
+	return func() { retryBackoff = old }
+}
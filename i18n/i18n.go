@@ -0,0 +1,181 @@
+// Package i18n translates messages shown to users. A Catalog holds the messages of each locale, registered by the
+// framework and by commands, and a Localizer formats them for the locales the user prefers, as detected from
+// LC_ALL, LC_MESSAGES, LANG, and LANGUAGE:
+//
+//	catalog := i18n.NewCatalog()
+//	catalog.Add("de", map[string]i18n.Message{
+//		"files.copied": {One: "%d Datei kopiert", Other: "%d Dateien kopiert"},
+//	})
+//	l := catalog.Localizer(i18n.Detect(a.LookupEnv)...)
+//	fmt.Println(l.N("files.copied", 3))
+//
+// Messages are fmt format strings. Messages missing from every preferred locale fall back to the catalog fallback
+// locale, and then to the unformatted key itself.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the fallback locale of a new Catalog.
+const DefaultLocale = "en"
+
+// Message is a translated message. Other is required; the other forms are used for the counts the plural rule of
+// the locale selects them for, falling back to Other when empty.
+type Message struct {
+	Zero  string
+	One   string
+	Two   string
+	Few   string
+	Many  string
+	Other string
+}
+
+func (m Message) form(f Form) string {
+	var s string
+	switch f {
+	case Zero:
+		s = m.Zero
+	case One:
+		s = m.One
+	case Two:
+		s = m.Two
+	case Few:
+		s = m.Few
+	case Many:
+		s = m.Many
+	}
+	if s == "" {
+		return m.Other
+	}
+	return s
+}
+
+// Catalog holds messages by locale and key. It is safe for concurrent use.
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]Message
+}
+
+// NewCatalog returns an empty Catalog falling back to DefaultLocale.
+func NewCatalog() *Catalog {
+	return &Catalog{fallback: DefaultLocale, messages: make(map[string]map[string]Message)}
+}
+
+// SetFallback sets the locale used for messages missing from every preferred locale.
+func (c *Catalog) SetFallback(locale string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fallback = Normalize(locale)
+}
+
+// Add registers messages for the locale, replacing messages already registered with the same keys.
+func (c *Catalog) Add(locale string, messages map[string]Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locale = Normalize(locale)
+	m := c.messages[locale]
+	if m == nil {
+		m = make(map[string]Message, len(messages))
+		c.messages[locale] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+// AddStrings registers messages without plural forms for the locale.
+func (c *Catalog) AddStrings(locale string, messages map[string]string) {
+	m := make(map[string]Message, len(messages))
+	for k, v := range messages {
+		m[k] = Message{Other: v}
+	}
+	c.Add(locale, m)
+}
+
+// lookup returns the message for key in the first of the locales that has it, and that locale.
+func (c *Catalog) lookup(locales []string, key string) (Message, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, locale := range locales {
+		if msg, ok := c.messages[locale][key]; ok {
+			return msg, locale, true
+		}
+	}
+	msg, ok := c.messages[c.fallback][key]
+	return msg, c.fallback, ok
+}
+
+// Localizer returns a Localizer for the locales in order of preference. Each locale is followed by its language
+// without the region, so a catalog for "pt" serves "pt-BR".
+func (c *Catalog) Localizer(locales ...string) *Localizer {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(locale string) {
+		if locale != "" && !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, locale := range locales {
+		locale = Normalize(locale)
+		add(locale)
+		if idx := strings.IndexByte(locale, '-'); idx > 0 {
+			add(locale[:idx])
+		}
+	}
+
+	return &Localizer{catalog: c, locales: chain}
+}
+
+// Localizer formats messages from a Catalog for a list of preferred locales.
+type Localizer struct {
+	catalog *Catalog
+	locales []string
+}
+
+// Locale returns the most preferred locale, or the fallback locale of the catalog if there is none.
+func (l *Localizer) Locale() string {
+	if len(l.locales) > 0 {
+		return l.locales[0]
+	}
+
+	l.catalog.mu.RLock()
+	defer l.catalog.mu.RUnlock()
+	return l.catalog.fallback
+}
+
+// T formats the message for key with args.
+func (l *Localizer) T(key string, args ...interface{}) string {
+	msg, _, ok := l.catalog.lookup(l.locales, key)
+	if !ok {
+		return key
+	}
+	return sprintf(msg.Other, args)
+}
+
+// N formats the plural form of the message for key selected by the count n. The count is the first argument of the
+// format, followed by args.
+func (l *Localizer) N(key string, n int, args ...interface{}) string {
+	args = append([]interface{}{n}, args...)
+
+	msg, locale, ok := l.catalog.lookup(l.locales, key)
+	if !ok {
+		return key
+	}
+	return sprintf(msg.form(PluralForm(locale, n)), args)
+}
+
+func sprintf(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,65 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/i18n"
+)
+
+func newCatalog() *i18n.Catalog {
+	c := i18n.NewCatalog()
+	c.AddStrings("en", map[string]string{"greeting": "Hello, %s!", "bye": "Goodbye"})
+	c.Add("en", map[string]i18n.Message{"files": {One: "%d file", Other: "%d files"}})
+	c.AddStrings("pt", map[string]string{"greeting": "Olá, %s!"})
+	c.Add("pt_BR", map[string]i18n.Message{"files": {One: "%d arquivo", Other: "%d arquivos"}})
+	c.Add("ru", map[string]i18n.Message{
+		"files": {One: "%d файл", Few: "%d файла", Many: "%d файлов"},
+		"dirs":  {One: "%d каталог", Other: "%d каталогов"},
+	})
+	return c
+}
+
+func TestLocalizer_T(t *testing.T) {
+	l := newCatalog().Localizer("pt-BR")
+	assert.Equal(t, "pt-BR", l.Locale())
+	assert.Equal(t, "Olá, Ana!", l.T("greeting", "Ana"))
+	assert.Equal(t, "Goodbye", l.T("bye"))
+	assert.Equal(t, "missing.key", l.T("missing.key", 1))
+
+	assert.Equal(t, "en", newCatalog().Localizer().Locale())
+}
+
+func TestLocalizer_N(t *testing.T) {
+	c := newCatalog()
+
+	en := c.Localizer("en-GB")
+	assert.Equal(t, "1 file", en.N("files", 1))
+	assert.Equal(t, "0 files", en.N("files", 0))
+
+	// pt-BR treats zero as singular
+	br := c.Localizer("pt-BR")
+	assert.Equal(t, "0 arquivo", br.N("files", 0))
+	assert.Equal(t, "2 arquivos", br.N("files", 2))
+
+	ru := c.Localizer("ru")
+	assert.Equal(t, "1 файл", ru.N("files", 1))
+	assert.Equal(t, "3 файла", ru.N("files", 3))
+	assert.Equal(t, "11 файлов", ru.N("files", 11))
+	assert.Equal(t, "21 файл", ru.N("files", 21))
+	// forms missing from a message fall back to Other
+	assert.Equal(t, "5 каталогов", ru.N("dirs", 5))
+}
+
+func TestPluralForm(t *testing.T) {
+	assert.Equal(t, i18n.Other, i18n.PluralForm("ja", 1))
+	assert.Equal(t, i18n.One, i18n.PluralForm("fr-CA", 0))
+	assert.Equal(t, i18n.Few, i18n.PluralForm("pl", 22))
+	assert.Equal(t, i18n.Many, i18n.PluralForm("pl", 12))
+	assert.Equal(t, i18n.Two, i18n.PluralForm("ar", 2))
+	assert.Equal(t, i18n.One, i18n.PluralForm("xx", -1))
+
+	i18n.SetPluralRule("xx", func(n int) i18n.Form { return i18n.Many })
+	assert.Equal(t, i18n.Many, i18n.PluralForm("xx", 1))
+}
@@ -0,0 +1,49 @@
+package i18n
+
+import "strings"
+
+// Detect returns the locales preferred by the user, most preferred first, from the environment variables read with
+// lookup, typically App.LookupEnv. The locale is taken from LC_ALL, LC_MESSAGES, or LANG, whichever is set first, and
+// is preceded by the colon separated list in LANGUAGE unless it is the "C" or "POSIX" locale, as GNU gettext does.
+// The C and POSIX locales are reported as DefaultLocale.
+func Detect(lookup func(string) (string, bool)) []string {
+	var locale string
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v, _ := lookup(key); v != "" {
+			locale = v
+			break
+		}
+	}
+
+	locale = Normalize(locale)
+	if locale == "" || locale == "c" || locale == "posix" {
+		return []string{DefaultLocale}
+	}
+
+	var locales []string
+	if v, _ := lookup("LANGUAGE"); v != "" {
+		for _, l := range strings.Split(v, ":") {
+			if l = Normalize(l); l != "" {
+				locales = append(locales, l)
+			}
+		}
+	}
+	return append(locales, locale)
+}
+
+// Normalize converts a POSIX locale name such as "pt_BR.UTF-8@euro" to the tag form used by Catalog, "pt-BR".
+func Normalize(locale string) string {
+	if idx := strings.IndexAny(locale, ".@"); idx >= 0 {
+		locale = locale[:idx]
+	}
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+
+	parts := strings.Split(locale, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for idx := 1; idx < len(parts); idx++ {
+		if len(parts[idx]) == 2 {
+			parts[idx] = strings.ToUpper(parts[idx])
+		}
+	}
+	return strings.Join(parts, "-")
+}
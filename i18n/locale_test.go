@@ -0,0 +1,35 @@
+package i18n_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/i18n"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected []string
+	}{
+		{nil, []string{"en"}},
+		{map[string]string{"LANG": "de_DE.UTF-8"}, []string{"de-DE"}},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_MESSAGES": "fr_FR"}, []string{"fr-FR"}},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_MESSAGES": "fr_FR", "LC_ALL": "es_ES@euro"}, []string{"es-ES"}},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LANGUAGE": "pt_BR:pt:"}, []string{"pt-BR", "pt", "de-DE"}},
+		{map[string]string{"LANG": "C.UTF-8", "LANGUAGE": "pt_BR"}, []string{"en"}},
+		{map[string]string{"LC_ALL": "POSIX"}, []string{"en"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, i18n.Detect(apptest.Lookup(test.env)), "%v", test.env)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "zh-Hant-TW", i18n.Normalize("zh_Hant_tw"))
+	assert.Equal(t, "en", i18n.Normalize("EN"))
+	assert.Equal(t, "sr-RS", i18n.Normalize("sr_RS@latin"))
+}
@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"strings"
+	"sync"
+)
+
+// Form is a plural category, as defined by the Unicode CLDR.
+type Form int
+
+// The plural categories.
+const (
+	Other Form = iota
+	Zero
+	One
+	Two
+	Few
+	Many
+)
+
+// PluralRule selects the plural form for the count n.
+type PluralRule func(n int) Form
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]PluralRule{}
+)
+
+func init() {
+	register := func(rule PluralRule, langs ...string) {
+		for _, lang := range langs {
+			rules[lang] = rule
+		}
+	}
+
+	register(func(n int) Form { return Other }, "id", "ja", "km", "ko", "lo", "ms", "my", "th", "vi", "zh")
+	register(func(n int) Form {
+		if n == 0 || n == 1 {
+			return One
+		}
+		return Other
+	}, "fr", "hi", "pt-BR")
+	register(func(n int) Form {
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	}, "be", "ru", "uk")
+	register(func(n int) Form {
+		switch {
+		case n == 1:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	}, "pl")
+	register(func(n int) Form {
+		switch {
+		case n == 1:
+			return One
+		case n >= 2 && n <= 4:
+			return Few
+		default:
+			return Other
+		}
+	}, "cs", "sk")
+	register(func(n int) Form {
+		switch {
+		case n == 0:
+			return Zero
+		case n == 1:
+			return One
+		case n == 2:
+			return Two
+		case n%100 >= 3 && n%100 <= 10:
+			return Few
+		case n%100 >= 11:
+			return Many
+		default:
+			return Other
+		}
+	}, "ar")
+}
+
+// SetPluralRule sets the plural rule of a locale or language, replacing the built in rule.
+func SetPluralRule(locale string, rule PluralRule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+
+	rules[Normalize(locale)] = rule
+}
+
+// PluralForm returns the plural form for the count n in the locale. The rule of the locale is used if there is one,
+// then the rule of its language, and otherwise the English rule, which selects One for 1 and Other for any other
+// count.
+func PluralForm(locale string, n int) Form {
+	if n < 0 {
+		n = -n
+	}
+
+	rulesMu.RLock()
+	rule, ok := rules[locale]
+	if !ok {
+		if idx := strings.IndexByte(locale, '-'); idx > 0 {
+			rule, ok = rules[locale[:idx]]
+		}
+	}
+	rulesMu.RUnlock()
+
+	if ok {
+		return rule(n)
+	}
+	if n == 1 {
+		return One
+	}
+	return Other
+}
@@ -0,0 +1,116 @@
+package idempotency
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the key handling settings, as in APP_IDEMPOTENCY_HEADER.
+	DefaultPrefix = "APP_IDEMPOTENCY_"
+
+	// DefaultHeader is the request header carrying the idempotency key.
+	DefaultHeader = "Idempotency-Key"
+
+	// DefaultTTL is how long the response to a request is kept for its retries.
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultLockTimeout is how long a request holds its key before a retry may run it again, in case the replica
+	// serving it died.
+	DefaultLockTimeout = time.Minute
+
+	// DefaultMaxBodySize is the largest request body read to fingerprint a request, in bytes.
+	DefaultMaxBodySize = 1 << 20
+)
+
+// Config describes how the Middleware reads idempotency keys and how long it keeps responses.
+type Config struct {
+	Header      string        // the request header carrying the idempotency key
+	Required    bool          // whether unsafe requests without a key are refused with 400 Bad Request
+	TTL         time.Duration // how long the response to a request is kept for its retries
+	LockTimeout time.Duration // how long a request holds its key before a retry may run it again
+	MaxBodySize int64         // largest request body accepted with a key, in bytes
+}
+
+// DefaultConfig returns a Config reading the DefaultHeader, with keys optional, that keeps responses for DefaultTTL.
+func DefaultConfig() *Config {
+	return &Config{
+		Header:      DefaultHeader,
+		TTL:         DefaultTTL,
+		LockTimeout: DefaultLockTimeout,
+		MaxBodySize: DefaultMaxBodySize,
+	}
+}
+
+func init() {
+	configschema.Register("idempotency", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the idempotency key variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "HEADER", Type: "string", Default: DefaultHeader,
+			Description: "The request header carrying the idempotency key."},
+		{Name: prefix + "REQUIRED", Type: "bool", Default: "false",
+			Description: "Refuse unsafe requests without a key with 400 Bad Request."},
+		{Name: prefix + "TTL", Type: "duration", Default: DefaultTTL.String(),
+			Description: "How long the response to a request is kept for its retries."},
+		{Name: prefix + "LOCK_TIMEOUT", Type: "duration", Default: DefaultLockTimeout.String(),
+			Description: "How long a request holds its key before a retry may run it again."},
+		{Name: prefix + "MAX_BODY_SIZE", Type: "int", Default: strconv.Itoa(DefaultMaxBodySize),
+			Description: "The largest request body read to fingerprint a request, in bytes."},
+	}
+}
+
+// FromEnv reads the idempotency key HEADER, whether it is REQUIRED, the TTL of the responses kept, the LOCK_TIMEOUT,
+// and the MAX_BODY_SIZE fingerprinted, in bytes, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("HEADER"); v != "" {
+		config.Header = v
+	}
+
+	if v := get("REQUIRED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("idempotency: invalid %sREQUIRED %q", prefix, v)
+		}
+		config.Required = b
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"TTL":          &config.TTL,
+		"LOCK_TIMEOUT": &config.LockTimeout,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("idempotency: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v := get("MAX_BODY_SIZE"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("idempotency: invalid %sMAX_BODY_SIZE %q", prefix, v)
+		}
+		config.MaxBodySize = n
+	}
+
+	return config, nil
+}
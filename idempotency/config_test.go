@@ -0,0 +1,60 @@
+package idempotency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/idempotency"
+)
+
+func TestFromEnv_Header(t *testing.T) {
+	config, err := idempotency.FromEnv(apptest.Lookup(map[string]string{
+		"PAYMENTS_HEADER":   " X-Idempotency-Key ",
+		"PAYMENTS_REQUIRED": "1",
+	}), "PAYMENTS_")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Idempotency-Key", config.Header)
+	assert.True(t, config.Required)
+
+	// keys are optional unless REQUIRED is set
+	config, err = idempotency.FromEnv(apptest.Lookup(map[string]string{"APP_IDEMPOTENCY_HEADER": "  "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, idempotency.DefaultConfig(), config)
+
+	_, err = idempotency.FromEnv(apptest.Lookup(map[string]string{"APP_IDEMPOTENCY_REQUIRED": "maybe"}), "")
+	assert.EqualError(t, err, `idempotency: invalid APP_IDEMPOTENCY_REQUIRED "maybe"`)
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	config, err := idempotency.FromEnv(apptest.Lookup(map[string]string{
+		"APP_IDEMPOTENCY_TTL":          "1h",
+		"APP_IDEMPOTENCY_LOCK_TIMEOUT": "30s",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, config.TTL)
+	assert.Equal(t, 30*time.Second, config.LockTimeout)
+
+	// a zero TTL would forget a response before its retry, and a zero lock would let two replicas run one request
+	for _, key := range []string{"TTL", "LOCK_TIMEOUT"} {
+		for _, v := range []string{"0s", "-1m", "30"} {
+			_, err := idempotency.FromEnv(apptest.Lookup(map[string]string{"APP_IDEMPOTENCY_" + key: v}), "")
+			assert.EqualError(t, err, "idempotency: invalid APP_IDEMPOTENCY_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_MaxBodySize(t *testing.T) {
+	config, err := idempotency.FromEnv(apptest.Lookup(map[string]string{"APP_IDEMPOTENCY_MAX_BODY_SIZE": "1024"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), config.MaxBodySize)
+
+	// the size is a plain count of bytes
+	for _, v := range []string{"0", "-1", "1MiB", "1e6"} {
+		_, err := idempotency.FromEnv(apptest.Lookup(map[string]string{"APP_IDEMPOTENCY_MAX_BODY_SIZE": v}), "")
+		assert.EqualError(t, err, `idempotency: invalid APP_IDEMPOTENCY_MAX_BODY_SIZE "`+v+`"`, v)
+	}
+}
@@ -0,0 +1,196 @@
+// Package idempotency provides HTTP middleware making unsafe requests safe to retry, as payment APIs do: a client
+// sends an Idempotency-Key header with a request, and the retries of the request with the same key get the response
+// to the first one instead of running it again.
+//
+//	config, err := idempotency.FromEnv(a.LookupEnv, "")
+//	mw := idempotency.New(idempotency.NewRedis(client), config)
+//	mux.Handle("POST /payments", mw.Wrap(createPayment))
+//
+// The response to the first request is kept in the Store for the TTL, unless it is a server error, which the retries
+// run again. A retry arriving while the first request runs is answered with 409 Conflict, and a request reusing a key
+// for another method, URL, or body with 422 Unprocessable Entity. Safe methods, such as GET, are passed through. The
+// keys are scoped to the client that sent them, as told by the Principal of the Middleware.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxKeyLength bounds the length of the idempotency keys.
+const maxKeyLength = 255
+
+// Middleware is HTTP middleware enforcing the idempotency of the requests with a key.
+type Middleware struct {
+	// Principal returns who sent the request, so that clients cannot read the responses of others by guessing their
+	// keys, such as the subject of jwtauth.FromContext. The Authorization header is used if Principal is nil.
+	Principal func(r *http.Request) string
+
+	store  Store
+	config Config
+}
+
+// New returns Middleware keeping the responses in store as config describes.
+func New(store Store, config *Config) *Middleware {
+	return &Middleware{store: store, config: *config}
+}
+
+// Wrap returns a handler calling next once for each idempotency key, and replaying the response it gave to the
+// retries. The replayed responses have the Idempotent-Replayed: true header.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(m.config.Header)
+		switch {
+		case safe(r.Method):
+			next.ServeHTTP(w, r)
+			return
+		case key == "" && m.config.Required:
+			http.Error(w, "missing "+m.config.Header+" header", http.StatusBadRequest)
+			return
+		case key == "":
+			next.ServeHTTP(w, r)
+			return
+		case len(key) > maxKeyLength:
+			http.Error(w, m.config.Header+" header too long", http.StatusBadRequest)
+			return
+		}
+
+		if m.config.MaxBodySize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, m.config.MaxBodySize)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "unable to read the request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = m.scope(r, key)
+		fp := fingerprint(r, body)
+		saved, err := m.store.Reserve(r.Context(), key, &Record{Fingerprint: fp}, m.config.LockTimeout)
+		switch {
+		case err != nil:
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		case saved == nil:
+			m.serve(w, r, next, key, fp)
+		case saved.Fingerprint != fp:
+			http.Error(w, m.config.Header+" already used for another request", http.StatusUnprocessableEntity)
+		case !saved.Done:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "a request with this "+m.config.Header+" is in progress", http.StatusConflict)
+		default:
+			replay(w, saved)
+		}
+	})
+}
+
+// serve calls next and saves its response for key. If next panics or fails with a server error, key is released so
+// that the request can be retried.
+func (m *Middleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler, key, fp string) {
+	// the response is saved even if the client went away, since that is when it retries
+	ctx := context.WithoutCancel(r.Context())
+
+	rec := &recorder{ResponseWriter: w}
+	completed := false
+	defer func() {
+		if !completed {
+			_ = m.store.Delete(ctx, key)
+		}
+	}()
+	next.ServeHTTP(rec, r)
+	if rec.code == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.code >= http.StatusInternalServerError {
+		return
+	}
+	completed = true
+
+	// the response is already sent; if it cannot be saved, retries get 409 until the LockTimeout and then run again
+	_ = m.store.Save(ctx, key, &Record{
+		Fingerprint: fp,
+		Done:        true,
+		StatusCode:  rec.code,
+		Header:      rec.header,
+		Body:        rec.body.Bytes(),
+	}, m.config.TTL)
+}
+
+// scope returns key prefixed with a hash of the principal of r, so that the keys of different clients do not meet.
+func (m *Middleware) scope(r *http.Request, key string) string {
+	var principal string
+	if m.Principal != nil {
+		principal = m.Principal(r)
+	} else {
+		principal = r.Header.Get("Authorization")
+	}
+	sum := sha256.Sum256([]byte(principal))
+	return hex.EncodeToString(sum[:16]) + ":" + key
+}
+
+// replay writes the response of rec.
+func replay(w http.ResponseWriter, rec *Record) {
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.StatusCode)
+	_, _ = w.Write(rec.Body)
+}
+
+// safe reports whether method is safe, and so needs no idempotency key.
+func safe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// fingerprint returns a hash of the method, URL, and body of r.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder is a ResponseWriter keeping a copy of the response it writes.
+type recorder struct {
+	http.ResponseWriter
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	// informational responses, such as 103 Early Hints, precede the final one
+	if r.code == 0 && code >= http.StatusOK {
+		r.code = code
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the ResponseWriter, for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,153 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/idempotency"
+)
+
+func newStore() *idempotency.Cache {
+	return idempotency.NewCache(cache.New[string, idempotency.Record](cache.DefaultConfig(), nil))
+}
+
+func send(h http.Handler, method, target, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotency.DefaultHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	h := idempotency.New(newStore(), idempotency.DefaultConfig()).Wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			w.Header().Set("Location", "/payments/"+strconv.Itoa(int(n)))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("payment " + strconv.Itoa(int(n))))
+		}))
+
+	w := send(h, http.MethodPost, "/payments", "key-1", `{"amount":100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "payment 1", w.Body.String())
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+	// the retry gets the same response
+	w = send(h, http.MethodPost, "/payments", "key-1", `{"amount":100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "payment 1", w.Body.String())
+	assert.Equal(t, "/payments/1", w.Header().Get("Location"))
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+
+	// another body with the same key is refused
+	w = send(h, http.MethodPost, "/payments", "key-1", `{"amount":200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// another key runs the handler again, as do requests without a key and safe requests
+	assert.Equal(t, "payment 2", send(h, http.MethodPost, "/payments", "key-2", `{"amount":100}`).Body.String())
+	assert.Equal(t, "payment 3", send(h, http.MethodPost, "/payments", "", `{"amount":100}`).Body.String())
+	assert.Equal(t, "payment 4", send(h, http.MethodGet, "/payments", "key-1", "").Body.String())
+	assert.Equal(t, int32(4), calls.Load())
+
+	w = send(h, http.MethodPost, "/payments", strings.Repeat("k", 256), "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// another client using the same key runs the handler
+	r := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount":100}`))
+	r.Header.Set(idempotency.DefaultHeader, "key-1")
+	r.Header.Set("Authorization", "Bearer other")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "payment 5", w.Body.String())
+}
+
+func TestMiddleware_ServerError(t *testing.T) {
+	var calls atomic.Int32
+	h := idempotency.New(newStore(), idempotency.DefaultConfig()).Wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+
+	send(h, http.MethodPost, "/payments", "key-1", "")
+
+	// the server error was not kept, so the retry runs the handler, and its final response is kept
+	send(h, http.MethodPost, "/payments", "key-1", "")
+	assert.Equal(t, int32(2), calls.Load())
+	w := send(h, http.MethodPost, "/payments", "key-1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMiddleware_MaxBodySize(t *testing.T) {
+	config := idempotency.DefaultConfig()
+	config.MaxBodySize = 4
+	h := idempotency.New(newStore(), config).Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	assert.Equal(t, http.StatusOK, send(h, http.MethodPost, "/payments", "key-1", "1234").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, send(h, http.MethodPost, "/payments", "key-2", "12345").Code)
+}
+
+func TestMiddleware_Required(t *testing.T) {
+	config := idempotency.DefaultConfig()
+	config.Required = true
+	h := idempotency.New(newStore(), config).Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	w := send(h, http.MethodPost, "/payments", "", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "missing Idempotency-Key header\n", w.Body.String())
+	assert.Equal(t, http.StatusOK, send(h, http.MethodGet, "/payments", "", "").Code)
+	assert.Equal(t, http.StatusOK, send(h, http.MethodPost, "/payments", "key-1", "").Code)
+}
+
+func TestMiddleware_Concurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := idempotency.New(newStore(), idempotency.DefaultConfig()).Wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			_, _ = w.Write([]byte("done"))
+		}))
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send(h, http.MethodPost, "/payments", "key-1", "") }()
+	<-started
+
+	w := send(h, http.MethodPost, "/payments", "key-1", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, "done", (<-first).Body.String())
+	assert.Equal(t, "done", send(h, http.MethodPost, "/payments", "key-1", "").Body.String())
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	var calls atomic.Int32
+	h := idempotency.New(newStore(), idempotency.DefaultConfig()).Wrap(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+		}))
+
+	assert.PanicsWithValue(t, "boom", func() { send(h, http.MethodPost, "/payments", "key-1", "") })
+
+	// the key was released, so the retry runs the handler
+	assert.Equal(t, http.StatusOK, send(h, http.MethodPost, "/payments", "key-1", "").Code)
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,210 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+)
+
+// Record is what a Store keeps for an idempotency key: the request that used it and, once the request completed, the
+// response to replay to its retries.
+type Record struct {
+	Fingerprint string      // a hash of the method, URL, and body of the request
+	Done        bool        // whether the request completed and the response below is set
+	StatusCode  int         `json:",omitempty"`
+	Header      http.Header `json:",omitempty"`
+	Body        []byte      `json:",omitempty"`
+}
+
+// Store keeps the Records of the idempotency keys. Reserve must be atomic across the replicas sharing the Store.
+type Store interface {
+	// Reserve saves rec for key, kept for ttl, unless a Record is saved for key already, in which case it returns that
+	// Record. It returns nil when rec was saved.
+	Reserve(ctx context.Context, key string, rec *Record, ttl time.Duration) (*Record, error)
+
+	// Save replaces the Record of key, kept for ttl.
+	Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+
+	// Delete removes the Record of key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Cache is a Store keeping the Records in memory, for an app with a single replica.
+type Cache struct {
+	mu    sync.Mutex
+	cache *cache.Cache[string, Record]
+}
+
+// NewCache returns a Store keeping the Records in c.
+func NewCache(c *cache.Cache[string, Record]) *Cache {
+	return &Cache{cache: c}
+}
+
+// Reserve saves rec unless the cache has a Record for key.
+func (c *Cache) Reserve(_ context.Context, key string, rec *Record, ttl time.Duration) (*Record, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if saved, ok := c.cache.Get(key); ok {
+		return &saved, nil
+	}
+	c.cache.SetTTL(key, *rec, ttl)
+	return nil, nil
+}
+
+// Save replaces the Record of key.
+func (c *Cache) Save(_ context.Context, key string, rec *Record, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.SetTTL(key, *rec, ttl)
+	return nil
+}
+
+// Delete removes the Record of key.
+func (c *Cache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Delete(key)
+	return nil
+}
+
+// Redis is a Store keeping the Records as JSON in Redis keys expiring with them.
+type Redis struct {
+	client redis.UniversalClient
+
+	// Prefix is prepended to the idempotency keys to form the Redis keys.
+	Prefix string
+}
+
+// NewRedis returns a Store using client.
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client, Prefix: "idempotency:"}
+}
+
+// Reserve sets the Redis key of key if it does not exist.
+func (r *Redis) Reserve(ctx context.Context, key string, rec *Record, ttl time.Duration) (*Record, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	// the saved Record may expire between SETNX and GET, in which case the key is free again
+	for {
+		ok, err := r.client.SetNX(ctx, r.Prefix+key, b, ttl).Result()
+		if err != nil || ok {
+			return nil, err
+		}
+
+		saved, err := r.client.Get(ctx, r.Prefix+key).Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decode(saved)
+	}
+}
+
+// Save sets the Redis key of key.
+func (r *Redis) Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.Prefix+key, b, ttl).Err()
+}
+
+// Delete deletes the Redis key of key.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.Prefix+key).Err()
+}
+
+// Postgres is a Store keeping the Records as JSON in a table created with:
+//
+//	CREATE TABLE idempotency_keys (
+//		key        text PRIMARY KEY,
+//		record     bytea NOT NULL,
+//		expires_at timestamptz NOT NULL
+//	);
+//
+// The Records are not deleted when they expire, only replaced when their key is used again; delete the rows whose
+// expires_at has passed from time to time to keep the table small.
+type Postgres struct {
+	db  *sql.DB
+	now func() time.Time
+
+	// Table is the name of the table.
+	Table string
+}
+
+// NewPostgres returns a Store using db, which must be a PostgreSQL database.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db, now: time.Now, Table: "idempotency_keys"}
+}
+
+// Reserve inserts a row for key, or replaces the row of key if it expired.
+func (p *Postgres) Reserve(ctx context.Context, key string, rec *Record, ttl time.Duration) (*Record, error) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %[1]s (key, record, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE SET record = EXCLUDED.record, expires_at = EXCLUDED.expires_at
+WHERE %[1]s.expires_at <= $4`, p.Table)
+	for {
+		now := p.now()
+		res, err := p.db.ExecContext(ctx, insert, key, b, now.Add(ttl), now)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return nil, err
+		}
+
+		var saved []byte
+		err = p.db.QueryRowContext(ctx, fmt.Sprintf("SELECT record FROM %s WHERE key = $1", p.Table), key).Scan(&saved)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decode(saved)
+	}
+}
+
+// Save updates the row of key.
+func (p *Postgres) Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE %s SET record = $2, expires_at = $3 WHERE key = $1", p.Table)
+	_, err = p.db.ExecContext(ctx, query, key, b, p.now().Add(ttl))
+	return err
+}
+
+// Delete deletes the row of key.
+func (p *Postgres) Delete(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE key = $1", p.Table), key)
+	return err
+}
+
+func decode(b []byte) (*Record, error) {
+	rec := &Record{}
+	if err := json.Unmarshal(b, rec); err != nil {
+		return nil, fmt.Errorf("idempotency: invalid record: %w", err)
+	}
+	return rec, nil
+}
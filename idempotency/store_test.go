@@ -0,0 +1,98 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/idempotency"
+)
+
+// testStore runs a Store through a reservation, a retry while in progress, and the completion of a request.
+func testStore(t *testing.T, store idempotency.Store) {
+	ctx := context.Background()
+	pending := &idempotency.Record{Fingerprint: "fp"}
+	done := &idempotency.Record{
+		Fingerprint: "fp",
+		Done:        true,
+		StatusCode:  http.StatusCreated,
+		Header:      http.Header{"Location": {"/payments/1"}},
+		Body:        []byte("created"),
+	}
+
+	saved, err := store.Reserve(ctx, "key-1", pending, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+
+	saved, err = store.Reserve(ctx, "key-1", pending, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, pending, saved)
+
+	require.NoError(t, store.Save(ctx, "key-1", done, time.Hour))
+	saved, err = store.Reserve(ctx, "key-1", pending, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, done, saved)
+
+	require.NoError(t, store.Delete(ctx, "key-1"))
+	saved, err = store.Reserve(ctx, "key-1", pending, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestCache(t *testing.T) {
+	testStore(t, newStore())
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	testStore(t, idempotency.NewRedis(client))
+	assert.True(t, server.Exists("idempotency:key-1"))
+	assert.Equal(t, time.Minute, server.TTL("idempotency:key-1"))
+
+	// an expired record frees the key
+	server.FastForward(time.Minute)
+	saved, err := idempotency.NewRedis(client).Reserve(context.Background(), "key-1", &idempotency.Record{}, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	insert := `INSERT INTO idempotency_keys \(key, record, expires_at\) VALUES \(\$1, \$2, \$3\)`
+	mock.ExpectExec(insert).WithArgs("key-1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT record FROM idempotency_keys WHERE key = \$1`).WithArgs("key-1").
+		WillReturnRows(sqlmock.NewRows([]string{"record"}).AddRow([]byte(`{"Fingerprint":"fp","Done":false}`)))
+	mock.ExpectExec(`UPDATE idempotency_keys SET record = \$2, expires_at = \$3 WHERE key = \$1`).
+		WithArgs("key-1", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE key = \$1`).WithArgs("key-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	store := idempotency.NewPostgres(db)
+	ctx := context.Background()
+	saved, err := store.Reserve(ctx, "key-1", &idempotency.Record{Fingerprint: "fp"}, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+
+	saved, err = store.Reserve(ctx, "key-1", &idempotency.Record{Fingerprint: "fp"}, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, &idempotency.Record{Fingerprint: "fp"}, saved)
+
+	require.NoError(t, store.Save(ctx, "key-1", &idempotency.Record{Fingerprint: "fp", Done: true}, time.Hour))
+	require.NoError(t, store.Delete(ctx, "key-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,47 @@
+// Package atomicfile replaces files so that readers never see them half written, and a crash never leaves them
+// truncated: the content goes to a temporary file in the same directory, renamed over the file once it is complete.
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// TempPrefix starts the names of the temporary files, which directory listings skip.
+const TempPrefix = ".tmp-"
+
+// WriteFile replaces the file at path with b, with the permissions perm.
+func WriteFile(path string, b []byte, perm os.FileMode) error {
+	return Write(path, perm, func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// Write replaces the file at path with what write writes, with the permissions perm. The file is left as it was if
+// write fails.
+func Write(path string, perm os.FileMode, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), TempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
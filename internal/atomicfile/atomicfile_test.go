@@ -0,0 +1,59 @@
+package atomicfile_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	require.NoError(t, atomicfile.WriteFile(path, []byte("one"), 0o600))
+	require.NoError(t, atomicfile.WriteFile(path, []byte("two"), 0o644))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWrite_Error(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, atomicfile.WriteFile(path, []byte("old"), 0o600))
+
+	failed := errors.New("failed")
+	err := atomicfile.Write(path, 0o600, func(w io.Writer) error {
+		_, _ = io.WriteString(w, "half")
+		return failed
+	})
+	assert.ErrorIs(t, err, failed)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(b))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, atomicfile.WriteFile(filepath.Join(dir, "missing", "config.json"), nil, 0o600))
+}
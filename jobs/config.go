@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the queue and worker settings, as in APP_JOBS_CONCURRENCY.
+	DefaultPrefix = "APP_JOBS_"
+
+	// DefaultTable is the table holding pending jobs; dead jobs are moved to the table with the "_dead" suffix.
+	DefaultTable = "jobs"
+
+	// DefaultConcurrency is the number of jobs of each queue handled at once.
+	DefaultConcurrency = 4
+
+	// DefaultPollInterval is how often an idle worker checks for new jobs.
+	DefaultPollInterval = time.Second
+
+	// DefaultDepthInterval is how often the jobs_queue_depth gauges are sampled.
+	DefaultDepthInterval = 15 * time.Second
+
+	// DefaultVisibility is how long a job is hidden from other workers while it is handled.
+	DefaultVisibility = 5 * time.Minute
+
+	// DefaultMaxAttempts is the number of attempts made before a job is dead-lettered.
+	DefaultMaxAttempts = 5
+
+	// DefaultRetryBackoff is the delay before the first retry; it doubles with every attempt.
+	DefaultRetryBackoff = 10 * time.Second
+
+	// DefaultMaxBackoff caps the delay between attempts.
+	DefaultMaxBackoff = time.Hour
+)
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Config describes the storage and worker behavior of a Queue.
+type Config struct {
+	Table         string
+	Concurrency   int
+	PollInterval  time.Duration
+	DepthInterval time.Duration // how often the depth of the queues is sampled, DefaultDepthInterval if zero
+	Visibility    time.Duration // bound on each attempt; the job is retried by another worker once it elapses
+	MaxAttempts   int
+	RetryBackoff  time.Duration
+	MaxBackoff    time.Duration
+}
+
+// DefaultConfig returns a Config for the DefaultTable, making DefaultMaxAttempts at each job with a doubling backoff.
+func DefaultConfig() *Config {
+	return &Config{
+		Table:         DefaultTable,
+		Concurrency:   DefaultConcurrency,
+		PollInterval:  DefaultPollInterval,
+		DepthInterval: DefaultDepthInterval,
+		Visibility:    DefaultVisibility,
+		MaxAttempts:   DefaultMaxAttempts,
+		RetryBackoff:  DefaultRetryBackoff,
+		MaxBackoff:    DefaultMaxBackoff,
+	}
+}
+
+func init() {
+	configschema.Register("jobs", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the job queue variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "TABLE", Type: "string", Default: DefaultTable, Description: "The table holding pending jobs."},
+		{Name: prefix + "CONCURRENCY", Type: "int", Default: strconv.Itoa(DefaultConcurrency),
+			Description: "The jobs of each queue handled at once."},
+		{Name: prefix + "POLL_INTERVAL", Type: "duration", Default: DefaultPollInterval.String(),
+			Description: "How often an idle worker checks for new jobs."},
+		{Name: prefix + "DEPTH_INTERVAL", Type: "duration", Default: DefaultDepthInterval.String(),
+			Description: "How often the depth of the queues is sampled for the jobs_queue_depth metric."},
+		{Name: prefix + "VISIBILITY", Type: "duration", Default: DefaultVisibility.String(),
+			Description: "How long a job is hidden from other workers while it is handled."},
+		{Name: prefix + "MAX_ATTEMPTS", Type: "int", Default: strconv.Itoa(DefaultMaxAttempts),
+			Description: "The attempts made before a job is dead-lettered."},
+		{Name: prefix + "RETRY_BACKOFF", Type: "duration", Default: DefaultRetryBackoff.String(),
+			Description: "The wait before the first retry."},
+		{Name: prefix + "MAX_BACKOFF", Type: "duration", Default: DefaultMaxBackoff.String(),
+			Description: "The longest wait between attempts."},
+	}
+}
+
+// FromEnv reads the TABLE of the queue and how its workers run, with the prefix or DefaultPrefix: CONCURRENCY,
+// POLL_INTERVAL, and VISIBILITY, how failed jobs are retried, MAX_ATTEMPTS, RETRY_BACKOFF, and MAX_BACKOFF, and how
+// often the depth of the queues is sampled, DEPTH_INTERVAL. The table name is checked to be a plain SQL identifier, as
+// it is put in the queries.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("TABLE"); v != "" {
+		if !identifier.MatchString(v) {
+			return nil, fmt.Errorf("jobs: invalid %sTABLE %q", prefix, v)
+		}
+		config.Table = v
+	}
+
+	for key, dst := range map[string]*int{"CONCURRENCY": &config.Concurrency, "MAX_ATTEMPTS": &config.MaxAttempts} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("jobs: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"POLL_INTERVAL":  &config.PollInterval,
+		"DEPTH_INTERVAL": &config.DepthInterval,
+		"VISIBILITY":     &config.Visibility,
+		"RETRY_BACKOFF":  &config.RetryBackoff,
+		"MAX_BACKOFF":    &config.MaxBackoff,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("jobs: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,62 @@
+package jobs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/jobs"
+)
+
+func TestFromEnv_Table(t *testing.T) {
+	config, err := jobs.FromEnv(apptest.Lookup(map[string]string{"WORKER_TABLE": " _work_items2 "}), "WORKER_")
+	require.NoError(t, err)
+	assert.Equal(t, "_work_items2", config.Table)
+
+	// the name is put in the queries as is, so anything but a plain identifier is refused
+	for _, v := range []string{"jobs; DROP TABLE users", "public.jobs", `"jobs"`, "2jobs", "work-items"} {
+		_, err := jobs.FromEnv(apptest.Lookup(map[string]string{"APP_JOBS_TABLE": v}), "")
+		assert.ErrorContains(t, err, "jobs: invalid APP_JOBS_TABLE ", v)
+	}
+}
+
+func TestFromEnv_Counts(t *testing.T) {
+	config, err := jobs.FromEnv(apptest.Lookup(map[string]string{
+		"APP_JOBS_CONCURRENCY":  "1",
+		"APP_JOBS_MAX_ATTEMPTS": "1",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.Concurrency)
+	assert.Equal(t, 1, config.MaxAttempts)
+
+	for _, key := range []string{"CONCURRENCY", "MAX_ATTEMPTS"} {
+		for _, v := range []string{"0", "-2", "four"} {
+			_, err := jobs.FromEnv(apptest.Lookup(map[string]string{"APP_JOBS_" + key: v}), "")
+			assert.EqualError(t, err, "jobs: invalid APP_JOBS_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	config, err := jobs.FromEnv(apptest.Lookup(map[string]string{
+		"APP_JOBS_POLL_INTERVAL": "250ms",
+		"APP_JOBS_RETRY_BACKOFF": "1s",
+		"APP_JOBS_MAX_BACKOFF":   "10m",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, config.PollInterval)
+	assert.Equal(t, time.Second, config.RetryBackoff)
+	assert.Equal(t, 10*time.Minute, config.MaxBackoff)
+	assert.Equal(t, jobs.DefaultVisibility, config.Visibility)
+
+	// a zero interval would spin the workers, and a zero visibility would hand every job to every worker
+	for _, key := range []string{"POLL_INTERVAL", "DEPTH_INTERVAL", "VISIBILITY", "RETRY_BACKOFF", "MAX_BACKOFF"} {
+		for _, v := range []string{"0s", "-1s", "5"} {
+			_, err := jobs.FromEnv(apptest.Lookup(map[string]string{"APP_JOBS_" + key: v}), "")
+			assert.EqualError(t, err, "jobs: invalid APP_JOBS_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Package jobs is a persistent job queue stored in a PostgreSQL database. Jobs are delivered at least once: a job is
+// hidden from other workers while it is handled and reappears if its worker does not finish it within the visibility
+// timeout. Failed jobs are retried with exponential backoff and moved to a dead-letter table after the last attempt.
+//
+//	q := jobs.New(db, config, nil, a.Metrics())
+//	q.Handle("email", sendEmail)
+//	a.Register("jobs", q)
+//
+//	id, err := q.Enqueue(ctx, "email", payload, 0)
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// bookkeepingTimeout bounds the statements recording the outcome of a job, which run even during shutdown.
+const bookkeepingTimeout = 30 * time.Second
+
+// Job is a unit of work taken from a queue.
+type Job struct {
+	ID        int64
+	Queue     string
+	Payload   []byte
+	Attempts  int // including the current attempt
+	CreatedAt time.Time
+}
+
+// Handler processes a job. A returned error, or a panic, schedules a retry. The context is canceled when the
+// visibility timeout elapses or the Queue is forcibly shut down.
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue stores jobs in a database and runs workers for the queues with a registered Handler. It is an app.Server:
+// registered with App.Register, its workers stop taking jobs at shutdown and the jobs in progress are awaited. With a
+// registry, Serve samples the depth of the queues in the jobs_queue_depth gauges every DepthInterval.
+type Queue struct {
+	db       *sql.DB
+	config   Config
+	logger   gomol.WrappableLogger
+	registry *metrics.Registry
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	stopping chan struct{}
+	stopOnce sync.Once
+	workers  sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// New returns a Queue storing jobs in db, which must be a PostgreSQL database. The logger and registry may be nil.
+func New(db *sql.DB, config *Config, logger gomol.WrappableLogger, registry *metrics.Registry) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		db:       db,
+		config:   *config,
+		logger:   logger,
+		registry: registry,
+		handlers: make(map[string]Handler),
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Migrate creates the job and dead-letter tables if they do not exist.
+func (q *Queue) Migrate(ctx context.Context) error {
+	for _, stmt := range []string{
+		`CREATE TABLE IF NOT EXISTS %[1]s (
+			id BIGSERIAL PRIMARY KEY,
+			queue TEXT NOT NULL,
+			payload BYTEA NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			last_error TEXT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+		`CREATE INDEX IF NOT EXISTS %[1]s_queue_run_at ON %[1]s (queue, run_at)`,
+		`CREATE TABLE IF NOT EXISTS %[1]s_dead (
+			id BIGINT PRIMARY KEY,
+			queue TEXT NOT NULL,
+			payload BYTEA NOT NULL,
+			attempts INTEGER NOT NULL,
+			last_error TEXT,
+			created_at TIMESTAMPTZ NOT NULL,
+			failed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`,
+	} {
+		if _, err := q.db.ExecContext(ctx, q.sql(stmt)); err != nil {
+			return fmt.Errorf("jobs: migrating: %v", err)
+		}
+	}
+	return nil
+}
+
+// Enqueue adds a job to the named queue, runnable after delay, and returns its ID.
+func (q *Queue) Enqueue(ctx context.Context, queue string, payload []byte, delay time.Duration) (int64, error) {
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	var id int64
+	err := q.db.QueryRowContext(ctx, q.sql(`
+		INSERT INTO %[1]s (queue, payload, run_at) VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		RETURNING id`,
+	), queue, payload, delay.Milliseconds()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("jobs: enqueueing: %v", err)
+	}
+	return id, nil
+}
+
+// Depth returns the number of jobs in the named queue, including those being handled or waiting to be retried.
+func (q *Queue) Depth(ctx context.Context, queue string) (int, error) {
+	var n int
+	err := q.db.QueryRowContext(ctx, q.sql(`SELECT count(*) FROM %[1]s WHERE queue = $1`), queue).Scan(&n)
+	return n, err
+}
+
+// Handle registers the handler of the named queue. Handlers must be registered before the Queue is bound.
+func (q *Queue) Handle(queue string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[queue] = h
+}
+
+// Bind uses the App logger if the Queue has none and starts the workers of every queue with a handler.
+func (q *Queue) Bind(a *app.App) error {
+	if q.logger == nil {
+		q.logger = a.Logger()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for queue, h := range q.handlers {
+		for i := 0; i < q.config.Concurrency; i++ {
+			q.workers.Add(1)
+			go q.work(queue, h)
+		}
+	}
+	return nil
+}
+
+// Serve samples the depth of the queues with a handler every DepthInterval until the Queue is shut down.
+func (q *Queue) Serve() error {
+	if q.registry == nil {
+		<-q.stopping
+		return nil
+	}
+
+	interval := q.config.DepthInterval
+	if interval <= 0 {
+		interval = DefaultDepthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stopping:
+			return nil
+		default:
+		}
+		q.sampleDepth()
+
+		select {
+		case <-q.stopping:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleDepth sets the jobs_queue_depth gauges of the queues with a handler.
+func (q *Queue) sampleDepth() {
+	q.mu.Lock()
+	queues := make([]string, 0, len(q.handlers))
+	for queue := range q.handlers {
+		queues = append(queues, queue)
+	}
+	q.mu.Unlock()
+
+	for _, queue := range queues {
+		n, err := q.Depth(q.ctx, queue)
+		if err != nil {
+			q.log(gomol.LevelWarning, queue, nil, "unable to sample the depth of the queue: %v", err)
+			continue
+		}
+		q.registry.Gauge("jobs_queue_depth", metrics.Labels{"queue": queue}).Set(float64(n))
+	}
+}
+
+// Shutdown stops the workers from taking jobs and waits for the jobs in progress until the context is done, then
+// cancels their contexts. Canceled jobs are retried like failed ones.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work(queue string, h Handler) {
+	defer q.workers.Done()
+
+	for {
+		select {
+		case <-q.stopping:
+			return
+		default:
+		}
+
+		job, err := q.dequeue(queue)
+		if err != nil {
+			q.log(gomol.LevelError, queue, nil, "unable to take a job: %v", err)
+		}
+
+		if job != nil {
+			q.handle(job, h)
+			continue
+		}
+
+		timer := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-timer.C:
+		case <-q.stopping:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// dequeue takes the next runnable job of the queue, hiding it for the visibility timeout, or returns nil if there is
+// none.
+func (q *Queue) dequeue(queue string) (*Job, error) {
+	job := &Job{Queue: queue}
+	err := q.db.QueryRowContext(q.ctx, q.sql(`
+		UPDATE %[1]s SET attempts = attempts + 1, run_at = now() + $2 * interval '1 millisecond'
+		WHERE id = (
+			SELECT id FROM %[1]s WHERE queue = $1 AND run_at <= now()
+			ORDER BY run_at, id LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, payload, attempts, created_at`,
+	), queue, q.config.Visibility.Milliseconds()).Scan(&job.ID, &job.Payload, &job.Attempts, &job.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (q *Queue) handle(job *Job, h Handler) {
+	start := time.Now()
+	err := q.call(job, h)
+	if q.registry != nil {
+		q.registry.Histogram("jobs_duration_seconds", nil, metrics.Labels{"queue": job.Queue}).
+			Observe(time.Since(start).Seconds())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bookkeepingTimeout)
+	defer cancel()
+
+	var result string
+	var berr error
+	switch {
+	case err == nil:
+		result = "success"
+		_, berr = q.db.ExecContext(ctx, q.sql(`DELETE FROM %[1]s WHERE id = $1 AND attempts = $2`),
+			job.ID, job.Attempts)
+	case job.Attempts >= q.config.MaxAttempts:
+		result = "dead"
+		q.log(gomol.LevelError, job.Queue, job, "job failed after %d attempts: %v", job.Attempts, err)
+		_, berr = q.db.ExecContext(ctx, q.sql(`
+			WITH dead AS (
+				DELETE FROM %[1]s WHERE id = $1 AND attempts = $2
+				RETURNING id, queue, payload, attempts, created_at
+			)
+			INSERT INTO %[1]s_dead (id, queue, payload, attempts, last_error, created_at)
+			SELECT id, queue, payload, attempts, $3, created_at FROM dead`,
+		), job.ID, job.Attempts, err.Error())
+	default:
+		result = "retry"
+		backoff := q.backoff(job.Attempts)
+		q.log(gomol.LevelWarning, job.Queue, job, "job failed, retrying in %s: %v", backoff, err)
+		_, berr = q.db.ExecContext(ctx, q.sql(`
+			UPDATE %[1]s SET run_at = now() + $3 * interval '1 millisecond', last_error = $4
+			WHERE id = $1 AND attempts = $2`,
+		), job.ID, job.Attempts, backoff.Milliseconds(), err.Error())
+	}
+
+	// the job reappears once the visibility timeout elapses if its outcome could not be recorded
+	if berr != nil {
+		q.log(gomol.LevelError, job.Queue, job, "unable to record the job outcome: %v", berr)
+	}
+	if q.registry != nil {
+		q.registry.Counter("jobs_processed_total", metrics.Labels{"queue": job.Queue, "result": result}).Inc()
+	}
+}
+
+// call runs the handler, converting a panic to an error.
+func (q *Queue) call(job *Job, h Handler) (err error) {
+	ctx, cancel := context.WithTimeout(q.ctx, q.config.Visibility)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h(ctx, job)
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	backoff := q.config.RetryBackoff
+	for i := 1; i < attempts && backoff < q.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > q.config.MaxBackoff {
+		backoff = q.config.MaxBackoff
+	}
+	return backoff
+}
+
+func (q *Queue) sql(stmt string) string {
+	return fmt.Sprintf(stmt, q.config.Table)
+}
+
+func (q *Queue) log(level gomol.LogLevel, queue string, job *Job, format string, args ...interface{}) {
+	if q.logger == nil {
+		return
+	}
+
+	attrs := map[string]interface{}{"queue": queue}
+	if job != nil {
+		attrs["job"] = job.ID
+		attrs["attempts"] = job.Attempts
+	}
+	_ = q.logger.Log(level, gomol.NewAttrsFromMap(attrs), format, args...)
+}
@@ -0,0 +1,188 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/jobs"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+const (
+	dequeue  = `UPDATE jobs SET attempts = attempts \+ 1`
+	complete = `DELETE FROM jobs WHERE id = \$1 AND attempts = \$2`
+	retry    = `UPDATE jobs SET run_at = now\(\) \+ \$3 \* interval '1 millisecond', last_error = \$4`
+	dead     = `INSERT INTO jobs_dead`
+	depth    = `SELECT count\(\*\) FROM jobs WHERE queue = \$1`
+)
+
+func newQueue(t *testing.T, registry *metrics.Registry) (*jobs.Queue, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	config := jobs.DefaultConfig()
+	config.Concurrency = 1
+	config.PollInterval = time.Hour
+	config.MaxAttempts = 3
+	config.RetryBackoff = time.Second
+	return jobs.New(db, config, nil, registry), mock
+}
+
+func jobRow(id int64, attempts int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "payload", "attempts", "created_at"}).
+		AddRow(id, []byte("payload"), attempts, time.Unix(0, 0))
+}
+
+func run(t *testing.T, q *jobs.Queue, mock sqlmock.Sqlmock) {
+	require.NoError(t, q.Bind(&app.App{Stderr: io.Discard}))
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, q.Shutdown(ctx))
+	require.NoError(t, q.Serve())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueue_Enqueue(t *testing.T) {
+	q, mock := newQueue(t, nil)
+
+	mock.ExpectQuery(`INSERT INTO jobs \(queue, payload, run_at\)`).
+		WithArgs("email", []byte("hi"), int64(1500)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	id, err := q.Enqueue(context.Background(), "email", []byte("hi"), 1500*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), id)
+
+	mock.ExpectQuery(`INSERT INTO jobs`).WillReturnError(errors.New("connection refused"))
+	_, err = q.Enqueue(context.Background(), "email", nil, 0)
+	assert.EqualError(t, err, "jobs: enqueueing: connection refused")
+}
+
+func TestQueue_Migrate(t *testing.T) {
+	q, mock := newQueue(t, nil)
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS jobs \(`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS jobs_queue_run_at ON jobs`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS jobs_dead`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, q.Migrate(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueue_Work(t *testing.T) {
+	registry := metrics.NewRegistry()
+	q, mock := newQueue(t, registry)
+
+	var handled []*jobs.Job
+	q.Handle("email", func(ctx context.Context, job *jobs.Job) error {
+		handled = append(handled, job)
+		return nil
+	})
+
+	mock.ExpectQuery(dequeue).WithArgs("email", int64(jobs.DefaultVisibility/time.Millisecond)).
+		WillReturnRows(jobRow(1, 1))
+	mock.ExpectExec(complete).WithArgs(int64(1), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(dequeue).WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts", "created_at"}))
+
+	run(t, q, mock)
+
+	require.Len(t, handled, 1)
+	assert.Equal(t, &jobs.Job{
+		ID:        1,
+		Queue:     "email",
+		Payload:   []byte("payload"),
+		Attempts:  1,
+		CreatedAt: time.Unix(0, 0),
+	}, handled[0])
+
+	labels := metrics.Labels{"queue": "email", "result": "success"}
+	assert.Equal(t, 1.0, registry.Counter("jobs_processed_total", labels).Value())
+}
+
+func TestQueue_Depth(t *testing.T) {
+	registry := metrics.NewRegistry()
+	q, mock := newQueue(t, registry)
+	q.Handle("email", func(ctx context.Context, job *jobs.Job) error { return nil })
+
+	// the idle worker polls the queue, and Serve samples its depth
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(dequeue).WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts", "created_at"}))
+	mock.ExpectQuery(depth).WithArgs("email").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	require.NoError(t, q.Bind(&app.App{Stderr: io.Discard}))
+	served := make(chan error, 1)
+	go func() { served <- q.Serve() }()
+	gauge := registry.Gauge("jobs_queue_depth", metrics.Labels{"queue": "email"})
+	assert.Eventually(t, func() bool { return gauge.Value() == 3 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, time.Millisecond)
+
+	require.NoError(t, q.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+}
+
+func TestQueue_Retry(t *testing.T) {
+	registry := metrics.NewRegistry()
+	q, mock := newQueue(t, registry)
+
+	q.Handle("email", func(ctx context.Context, job *jobs.Job) error {
+		if job.ID == 2 {
+			panic("boom")
+		}
+		return errors.New("smtp unavailable")
+	})
+
+	// the second attempt waits twice the backoff
+	mock.ExpectQuery(dequeue).WillReturnRows(jobRow(1, 2))
+	mock.ExpectExec(retry).WithArgs(int64(1), 2, int64(2000), "smtp unavailable").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(dequeue).WillReturnRows(jobRow(2, 1))
+	mock.ExpectExec(retry).WithArgs(int64(2), 1, int64(1000), "panic: boom").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(dequeue).WillReturnRows(jobRow(1, 3))
+	mock.ExpectExec(dead).WithArgs(int64(1), 3, "smtp unavailable").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(dequeue).WillReturnError(errors.New("connection refused"))
+
+	run(t, q, mock)
+
+	assert.Equal(t, 2.0, registry.Counter("jobs_processed_total", metrics.Labels{
+		"queue":  "email",
+		"result": "retry",
+	}).Value())
+	assert.Equal(t, 1.0, registry.Counter("jobs_processed_total", metrics.Labels{
+		"queue":  "email",
+		"result": "dead",
+	}).Value())
+}
+
+func TestQueue_Shutdown(t *testing.T) {
+	q, mock := newQueue(t, nil)
+
+	started := make(chan struct{})
+	q.Handle("slow", func(ctx context.Context, job *jobs.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	mock.ExpectQuery(dequeue).WillReturnRows(jobRow(1, 1))
+	mock.ExpectExec(retry).WithArgs(int64(1), 1, int64(1000), "context canceled").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, q.Bind(&app.App{Stderr: io.Discard}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, q.Shutdown(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
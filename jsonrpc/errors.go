@@ -0,0 +1,29 @@
+package jsonrpc
+
+import "fmt"
+
+// Error codes defined by the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error object. Handlers return an *Error to control the error sent to the client; any other
+// error is sent as an internal error.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// InvalidParams returns the error for parameters the handler cannot decode or accept.
+func InvalidParams(err error) *Error {
+	return &Error{Code: CodeInvalidParams, Message: "invalid params", Data: err.Error()}
+}
@@ -0,0 +1,16 @@
+package jsonrpc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/jsonrpc"
+)
+
+func TestError(t *testing.T) {
+	err := jsonrpc.InvalidParams(errors.New("missing name"))
+	assert.Equal(t, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "invalid params", Data: "missing name"}, err)
+	assert.EqualError(t, err, "jsonrpc: invalid params (-32602)")
+}
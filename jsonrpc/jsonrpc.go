@@ -0,0 +1,412 @@
+// Package jsonrpc serves JSON-RPC 2.0 over the App standard streams, for editor plugins and automation agents
+// driving the program as a subprocess.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+// FramingEnv selects the message framing when the Server does not set one: "header" or "line".
+const FramingEnv = "APP_JSONRPC_FRAMING"
+
+func init() {
+	configschema.Register("jsonrpc", configschema.Key{
+		Name: FramingEnv, Type: "string", Description: "The framing of JSON-RPC messages: header or line.",
+	})
+}
+
+// Framing is the way messages are delimited on the streams.
+type Framing int
+
+const (
+	// FramingAuto detects the framing from the first message received.
+	FramingAuto Framing = iota
+
+	// FramingHeader prefixes each message with a Content-Length header, as the Language Server Protocol does.
+	FramingHeader
+
+	// FramingLine writes each message on its own line.
+	FramingLine
+)
+
+// Handler serves a method. The context is canceled when the App shuts down. The result is encoded as JSON.
+type Handler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// Server is an app.Server answering JSON-RPC 2.0 requests read from App.Stdin on App.Stdout. Requests are served
+// concurrently and batches are supported. Serve returns when the input is exhausted, which shuts the App down.
+type Server struct {
+	Framing Framing
+
+	methodsMu sync.RWMutex
+	methods   map[string]Handler
+
+	in       *bufio.Reader
+	out      io.Writer
+	logger   gomol.WrappableLogger
+	writeMu  sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inflight sync.WaitGroup
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer returns a Server with no methods.
+func NewServer() *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		methods:  make(map[string]Handler),
+		ctx:      ctx,
+		cancel:   cancel,
+		stopping: make(chan struct{}),
+	}
+}
+
+// Register serves method with h, replacing any previous handler.
+func (s *Server) Register(method string, h Handler) {
+	s.methodsMu.Lock()
+	defer s.methodsMu.Unlock()
+	s.methods[method] = h
+}
+
+// Bind attaches the Server to the App standard streams and logger.
+func (s *Server) Bind(a *app.App) error {
+	if s.Framing == FramingAuto {
+		if v, _ := a.LookupEnv(FramingEnv); v != "" {
+			switch strings.ToLower(v) {
+			case "header":
+				s.Framing = FramingHeader
+			case "line":
+				s.Framing = FramingLine
+			default:
+				return fmt.Errorf("invalid %s %q", FramingEnv, v)
+			}
+		}
+	}
+
+	s.in = bufio.NewReader(a.Stdin)
+	s.out = a.Output()
+	s.logger = a.Logger()
+	return nil
+}
+
+// Serve answers requests until the input is exhausted or the Server is shut down.
+func (s *Server) Serve() error {
+	msgs := make(chan []byte)
+	errch := make(chan error, 1)
+
+	go func() {
+		for {
+			msg, err := s.read()
+			if err != nil {
+				errch <- err
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-s.stopping:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-msgs:
+			s.inflight.Add(1)
+			go func() {
+				defer s.inflight.Done()
+				if res := s.handle(msg); res != nil {
+					s.write(res)
+				}
+			}()
+		case err := <-errch:
+			s.inflight.Wait()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case <-s.stopping:
+			return nil
+		}
+	}
+}
+
+// Shutdown stops reading requests and cancels the contexts of those in flight, waiting for their responses until
+// the context is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"` // empty if absent, null if null
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+var null = json.RawMessage("null")
+
+// handle returns the encoded response to msg, or nil if nothing is to be sent.
+func (s *Server) handle(msg []byte) []byte {
+	msg = bytes.TrimSpace(msg)
+
+	if len(msg) > 0 && msg[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return encode(errorResponse(null, &Error{Code: CodeParseError, Message: "parse error"}))
+		}
+		if len(batch) == 0 {
+			return encode(errorResponse(null, &Error{Code: CodeInvalidRequest, Message: "invalid request"}))
+		}
+
+		responses := make([]*response, len(batch))
+		wg := new(sync.WaitGroup)
+		wg.Add(len(batch))
+		for idx, raw := range batch {
+			go func(idx int, raw json.RawMessage) {
+				defer wg.Done()
+				responses[idx] = s.call(raw)
+			}(idx, raw)
+		}
+		wg.Wait()
+
+		var res []*response
+		for _, r := range responses {
+			if r != nil {
+				res = append(res, r)
+			}
+		}
+		if len(res) == 0 {
+			return nil
+		}
+		return encode(res)
+	}
+
+	if res := s.call(msg); res != nil {
+		return encode(res)
+	}
+	return nil
+}
+
+// call serves a single request, returning nil for notifications.
+func (s *Server) call(msg json.RawMessage) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return errorResponse(null, &Error{Code: CodeParseError, Message: "parse error"})
+		}
+		return errorResponse(null, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	// a request with a null id is answered; only one without an id is a notification
+	notification := len(req.ID) == 0
+	id := null
+	if !notification {
+		id = req.ID
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(id, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	s.methodsMu.RLock()
+	h, ok := s.methods[req.Method]
+	s.methodsMu.RUnlock()
+
+	var res *response
+	if !ok {
+		res = errorResponse(id, &Error{Code: CodeMethodNotFound, Message: "method not found", Data: req.Method})
+	} else {
+		res = s.invoke(h, id, &req)
+	}
+
+	if notification {
+		return nil
+	}
+	return res
+}
+
+func (s *Server) invoke(h Handler, id json.RawMessage, req *request) (res *response) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.log(gomol.LevelError, req.Method, "panic serving %s: %v", req.Method, p)
+			res = errorResponse(id, &Error{Code: CodeInternalError, Message: "internal error"})
+		}
+	}()
+
+	result, err := h(s.ctx, req.Params)
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			s.log(gomol.LevelError, req.Method, "error serving %s: %v", req.Method, err)
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return errorResponse(id, rpcErr)
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(id, &Error{Code: CodeInternalError, Message: err.Error()})
+	}
+
+	raw := json.RawMessage(b)
+	return &response{JSONRPC: "2.0", ID: id, Result: &raw}
+}
+
+// errorResponse returns the response with err, or with an internal error if the Data of err cannot be encoded.
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if err.Data != nil {
+		if _, marshalErr := json.Marshal(err.Data); marshalErr != nil {
+			err = &Error{Code: CodeInternalError, Message: "internal error"}
+		}
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// encode returns v encoded, or an internal error response if it cannot be encoded.
+func encode(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(errorResponse(null, &Error{Code: CodeInternalError, Message: "internal error"}))
+	}
+	return b
+}
+
+// read returns the next message, detecting the framing if needed.
+func (s *Server) read() ([]byte, error) {
+	if s.Framing == FramingAuto {
+		for {
+			b, err := s.in.Peek(1)
+			if err != nil {
+				return nil, err
+			}
+			if b[0] != ' ' && b[0] != '\t' && b[0] != '\r' && b[0] != '\n' {
+				break
+			}
+			_, _ = s.in.ReadByte()
+		}
+
+		b, _ := s.in.Peek(1)
+		if b[0] == '{' || b[0] == '[' {
+			s.Framing = FramingLine
+		} else {
+			s.Framing = FramingHeader
+		}
+	}
+
+	if s.Framing == FramingLine {
+		for {
+			line, err := s.in.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				return line, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line != "" {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length < 0 {
+				// tolerate blank lines between messages
+				continue
+			}
+			break
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("jsonrpc: invalid Content-Length %q", value)
+			}
+			length = n
+		}
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(s.in, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *Server) write(msg []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var err error
+	if s.Framing == FramingHeader {
+		_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	} else {
+		_, err = fmt.Fprintf(s.out, "%s\n", msg)
+	}
+
+	// a closed stdout shuts the App down; there is no one left to answer
+	if err != nil && !app.IsBrokenPipe(err) {
+		s.log(gomol.LevelError, "", "unable to write response: %v", err)
+	}
+}
+
+func (s *Server) log(level gomol.LogLevel, method string, msg string, a ...interface{}) {
+	if s.logger == nil {
+		return
+	}
+
+	var attrs *gomol.Attrs
+	if method != "" {
+		attrs = gomol.NewAttrsFromMap(map[string]interface{}{"method": method})
+	}
+	_ = s.logger.Log(level, attrs, msg, a...)
+}
@@ -0,0 +1,151 @@
+package jsonrpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/jsonrpc"
+)
+
+func newServer() *jsonrpc.Server {
+	s := jsonrpc.NewServer()
+	s.Register("add", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		var args []int
+		if err := json.Unmarshal(params, &args); err != nil {
+			return nil, jsonrpc.InvalidParams(err)
+		}
+		sum := 0
+		for _, v := range args {
+			sum += v
+		}
+		return sum, nil
+	})
+	s.Register("fail", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	s.Register("panic", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		panic("oops")
+	})
+	s.Register("unencodable", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, &jsonrpc.Error{Code: 1, Message: "unencodable", Data: func() {}}
+	})
+	s.Register("nothing", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	return s
+}
+
+func serve(t *testing.T, s *jsonrpc.Server, env []string, input string) string {
+	out := new(bytes.Buffer)
+	a := &app.App{
+		Environment: env,
+		Context:     context.Background(),
+		Stdin:       strings.NewReader(input),
+		Stdout:      out,
+		Stderr:      io.Discard,
+	}
+
+	require.NoError(t, s.Bind(a))
+	require.NoError(t, s.Serve())
+	return out.String()
+}
+
+func TestServer_Line(t *testing.T) {
+	out := serve(t, newServer(), nil, `
+{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2,3]}
+{"jsonrpc":"2.0","method":"add","params":[1]}
+`)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":6}`+"\n", out)
+}
+
+func TestServer_Header(t *testing.T) {
+	msg := `{"jsonrpc":"2.0","id":"a","method":"nothing"}`
+	out := serve(t, newServer(), nil, "Content-Length: 45\r\n\r\n"+msg)
+
+	expected := `{"jsonrpc":"2.0","id":"a","result":null}`
+	assert.Equal(t, "Content-Length: 40\r\n\r\n"+expected, out)
+
+	// the environment selects the framing when it cannot be detected
+	out = serve(t, newServer(), []string{"APP_JSONRPC_FRAMING=header"}, "Content-Length: 45\r\n\r\n"+msg)
+	assert.Equal(t, "Content-Length: 40\r\n\r\n"+expected, out)
+
+	assert.EqualError(t, newServer().Bind(&app.App{
+		Environment: []string{"APP_JSONRPC_FRAMING=xml"},
+		Context:     context.Background(),
+	}), `invalid APP_JSONRPC_FRAMING "xml"`)
+}
+
+func TestServer_Errors(t *testing.T) {
+	tests := map[string]string{
+		`{"jsonrpc":"2.0","id":1,"method":"missing"}`: `"id":1,"error":{"code":-32601,"message":"method not found",` +
+			`"data":"missing"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"add","params":{}}`: `"id":2,"error":{"code":-32602,"message":"invalid params",` +
+			`"data":"json: cannot unmarshal object into Go value of type []int"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"fail"}`:        `"id":3,"error":{"code":-32603,"message":"boom"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"panic"}`:       `"id":4,"error":{"code":-32603,"message":"internal error"}`,
+		`{"jsonrpc":"1.0","id":5,"method":"add"}`:         `"id":5,"error":{"code":-32600,"message":"invalid request"}`,
+		`{"jsonrpc":"2.0","id":6,"method":`:               `"id":null,"error":{"code":-32700,"message":"parse error"}`,
+		`{"jsonrpc":"2.0","id":7,"method":"unencodable"}`: `"id":7,"error":{"code":-32603,"message":"internal error"}`,
+		`{"jsonrpc":"2.0","id":null,"method":"missing"}`: `"id":null,"error":{"code":-32601,` +
+			`"message":"method not found","data":"missing"}`,
+		`[]`: `"id":null,"error":{"code":-32600,"message":"invalid request"}`,
+	}
+
+	for input, expected := range tests {
+		assert.Equal(t, `{"jsonrpc":"2.0",`+expected+"}\n", serve(t, newServer(), nil, input+"\n"), input)
+	}
+}
+
+func TestServer_Batch(t *testing.T) {
+	out := serve(t, newServer(), nil, `[`+
+		`{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2]},`+
+		`{"jsonrpc":"2.0","method":"add","params":[3]},`+
+		`{"jsonrpc":"2.0","id":2,"method":"missing"}`+
+		`]`+"\n")
+
+	var res []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &res))
+	require.Len(t, res, 2)
+	assert.Equal(t, 3.0, res[0]["result"])
+	assert.Equal(t, 2.0, res[1]["id"])
+
+	assert.Empty(t, serve(t, newServer(), nil, `[{"jsonrpc":"2.0","method":"add","params":[3]}]`+"\n"))
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	s := newServer()
+	started := make(chan struct{})
+	s.Register("wait", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, &jsonrpc.Error{Code: -32800, Message: "request canceled"}
+	})
+
+	in, w := io.Pipe()
+	defer w.Close()
+	out := new(bytes.Buffer)
+	require.NoError(t, s.Bind(&app.App{Stdin: in, Stdout: out, Stderr: io.Discard}))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve() }()
+
+	_, err := io.WriteString(w, `{"jsonrpc":"2.0","id":7,"method":"wait"}`+"\n")
+	require.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.NoError(t, <-done)
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"error":{"code":-32800,"message":"request canceled"}}`+"\n", out.String())
+}
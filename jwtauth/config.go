@@ -0,0 +1,142 @@
+package jwtauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the tokens accepted and where their keys are, as in APP_JWT_ISSUER.
+	DefaultPrefix = "APP_JWT_"
+
+	// DefaultLeeway is the clock skew allowed when checking the times of a token.
+	DefaultLeeway = 30 * time.Second
+
+	// DefaultRefreshInterval is how long the keys of the JWKS are used before they are fetched again.
+	DefaultRefreshInterval = time.Hour
+)
+
+// Config describes the tokens the Authenticator accepts and where it finds the keys they are signed with.
+//
+// The provider signs the tokens of all its clients with the same keys, so the Audience is required to refuse the
+// tokens it issued to other services, unless AnyAudience explicitly accepts them.
+type Config struct {
+	JWKSURL         string        // the JWKS of the issuer; found by OpenID Connect discovery from the Issuer if empty
+	Issuer          string        // the iss claim the tokens must have; required to discover the JWKS
+	Audience        []string      // the aud claim of the tokens must hold one of these; required unless AnyAudience
+	AnyAudience     bool          // accept the tokens of any audience, such as when a gateway checks it already
+	Leeway          time.Duration // the clock skew allowed when checking the times of a token
+	RefreshInterval time.Duration // how long the keys are used before they are fetched again
+	Optional        bool          // whether requests without a token are served, without claims
+}
+
+// DefaultConfig returns a Config allowing DefaultLeeway of clock skew and fetching the keys every
+// DefaultRefreshInterval; it accepts no issuer or audience until they are set.
+func DefaultConfig() *Config {
+	return &Config{Leeway: DefaultLeeway, RefreshInterval: DefaultRefreshInterval}
+}
+
+func init() {
+	configschema.Register("jwtauth", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the JWT variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "JWKS_URL", Type: "url",
+			Description: "The keys of the issuer; discovered from ISSUER if not set."},
+		{Name: prefix + "ISSUER", Type: "url", Description: "The iss claim the tokens must have."},
+		{Name: prefix + "AUDIENCE", Type: "string",
+			Description: "The aud claims accepted, separated by spaces or commas; required unless ANY_AUDIENCE."},
+		{Name: prefix + "ANY_AUDIENCE", Type: "bool", Default: "false",
+			Description: "Accept the tokens of any audience, such as when a gateway checks it already."},
+		{Name: prefix + "LEEWAY", Type: "duration", Default: DefaultLeeway.String(),
+			Description: "The clock skew allowed when checking the times of a token."},
+		{Name: prefix + "REFRESH_INTERVAL", Type: "duration", Default: DefaultRefreshInterval.String(),
+			Description: "How long the keys are used before they are fetched again."},
+		{Name: prefix + "OPTIONAL", Type: "bool", Default: "false", Description: "Serve requests without a token."},
+	}
+}
+
+// FromEnv reads which tokens are accepted, with the prefix or DefaultPrefix: those signed by the keys at JWKS_URL, or
+// of the ISSUER when it is not set, for one of the AUDIENCE, separated by spaces or commas. LEEWAY, REFRESH_INTERVAL,
+// and OPTIONAL tune the checks. It fails without either JWKS_URL or ISSUER, or without AUDIENCE unless ANY_AUDIENCE
+// is set.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("AUDIENCE"); v != "" {
+		config.Audience = strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+	for key, dst := range map[string]*string{
+		"JWKS_URL": &config.JWKSURL,
+		"ISSUER":   &config.Issuer,
+	} {
+		if v := get(key); v != "" {
+			if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("jwtauth: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = v
+		}
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"LEEWAY":           &config.Leeway,
+		"REFRESH_INTERVAL": &config.RefreshInterval,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 || (d == 0 && key == "REFRESH_INTERVAL") {
+				return nil, fmt.Errorf("jwtauth: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	for key, dst := range map[string]*bool{
+		"ANY_AUDIENCE": &config.AnyAudience,
+		"OPTIONAL":     &config.Optional,
+	} {
+		if v := get(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("jwtauth: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = b
+		}
+	}
+
+	switch {
+	case config.JWKSURL == "" && config.Issuer == "":
+		return nil, fmt.Errorf("jwtauth: %sJWKS_URL or %sISSUER is required", prefix, prefix)
+	case len(config.Audience) == 0 && !config.AnyAudience:
+		return nil, fmt.Errorf("jwtauth: %sAUDIENCE is required unless %sANY_AUDIENCE is set", prefix, prefix)
+	}
+	return config, nil
+}
+
+// Validate returns an error if c names neither the JWKS URL nor the Issuer to discover it from, or has no Audience
+// and does not accept AnyAudience.
+func (c *Config) Validate() error {
+	switch {
+	case c.JWKSURL == "" && c.Issuer == "":
+		return errors.New("jwtauth: a JWKS URL or an issuer is required")
+	case len(c.Audience) == 0 && !c.AnyAudience:
+		return errors.New("jwtauth: an audience is required")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package jwtauth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/jwtauth"
+)
+
+func TestFromEnv_Keys(t *testing.T) {
+	// either one is enough: the JWKS is discovered from the issuer when it is not set
+	for key, v := range map[string]string{
+		"JWKS_URL": "https://auth.example.com/.well-known/jwks.json",
+		"ISSUER":   "https://auth.example.com/",
+	} {
+		_, err := jwtauth.FromEnv(apptest.Lookup(map[string]string{"AUTH_" + key: v, "AUTH_AUDIENCE": "orders"}), "AUTH_")
+		assert.NoError(t, err, key)
+	}
+
+	_, err := jwtauth.FromEnv(apptest.Lookup(map[string]string{"APP_JWT_AUDIENCE": "orders"}), "")
+	assert.EqualError(t, err, "jwtauth: APP_JWT_JWKS_URL or APP_JWT_ISSUER is required")
+
+	for _, v := range []string{"auth.example.com", "/.well-known/jwks.json", "ftp://auth.example.com", "https://"} {
+		_, err := jwtauth.FromEnv(apptest.Lookup(map[string]string{"APP_JWT_JWKS_URL": v}), "")
+		assert.EqualError(t, err, `jwtauth: invalid APP_JWT_JWKS_URL "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Audience(t *testing.T) {
+	env := map[string]string{"APP_JWT_ISSUER": "https://auth.example.com/"}
+
+	env["APP_JWT_AUDIENCE"] = "orders, billing,,admin"
+	config, err := jwtauth.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders", "billing", "admin"}, config.Audience)
+
+	// separators alone are no audience, which must be waived explicitly
+	env["APP_JWT_AUDIENCE"] = " , "
+	_, err = jwtauth.FromEnv(apptest.Lookup(env), "")
+	assert.EqualError(t, err, "jwtauth: APP_JWT_AUDIENCE is required unless APP_JWT_ANY_AUDIENCE is set")
+
+	env["APP_JWT_ANY_AUDIENCE"] = "true"
+	config, err = jwtauth.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.True(t, config.AnyAudience)
+	assert.Empty(t, config.Audience)
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	env := map[string]string{
+		"APP_JWT_ISSUER":       "https://auth.example.com/",
+		"APP_JWT_ANY_AUDIENCE": "true",
+		"APP_JWT_LEEWAY":       "0s",
+	}
+
+	// no leeway demands exact clocks, which is strict but valid; the keys still must be fetched again
+	config, err := jwtauth.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Leeway)
+	assert.Equal(t, jwtauth.DefaultRefreshInterval, config.RefreshInterval)
+
+	for key, v := range map[string]string{"LEEWAY": "-1s", "REFRESH_INTERVAL": "0s"} {
+		_, err := jwtauth.FromEnv(apptest.Lookup(map[string]string{"APP_JWT_" + key: v}), "")
+		assert.EqualError(t, err, "jwtauth: invalid APP_JWT_"+key+` "`+v+`"`)
+	}
+}
@@ -0,0 +1,8 @@
+package jwtauth
+
+import "time"
+
+// SetNow replaces the clock the Authenticator fetches its keys by.
+func SetNow(a *Authenticator, now func() time.Time) {
+	a.keys.now = now
+}
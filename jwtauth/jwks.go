@@ -0,0 +1,253 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minRefetch bounds how often the keys are fetched again for a token signed with an unknown key, so that tokens with
+// made up key IDs cannot make the Authenticator flood the issuer with requests.
+const minRefetch = time.Minute
+
+// fetchTimeout bounds how long fetching the keys takes, including the discovery of the JWKS URL.
+const fetchTimeout = 10 * time.Second
+
+// maxJWKS bounds the size of the JWKS and discovery documents.
+const maxJWKS = 1 << 20
+
+// keySet caches the keys of a JWKS, fetching them again once they are older than the refresh interval, or when a
+// token is signed with a key they do not have. The keys are fetched without holding the mutex, so that the tokens
+// signed with the cached keys are verified while they are, and only once for the tokens waiting for them.
+type keySet struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	url       string // the JWKS URL, once discovered
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time     // when the keys were fetched
+	attempted time.Time     // when the keys were last fetched or failed to be
+	err       error         // why they failed to be
+	fetching  chan struct{} // closed once the keys being fetched are, if they are
+}
+
+// key returns the key with the ID kid, or the only key if kid is empty.
+func (s *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// stale keys are used while the JWKS cannot be fetched
+	if s.keys == nil || s.now().Sub(s.fetched) >= s.config.RefreshInterval {
+		if err := s.refetch(ctx); err != nil && s.keys == nil {
+			return nil, err
+		}
+	}
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+
+	// the issuer may have rotated its keys
+	if err := s.refetch(ctx); err != nil {
+		return nil, err
+	}
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("no key %q in the JWKS", kid)
+}
+
+// refetch fetches the keys unless they were fetched, or failed to be, less than minRefetch ago, or waits for those
+// being fetched. s.mu must be held; it is released while the keys are fetched.
+func (s *keySet) refetch(ctx context.Context) error {
+	if done := s.fetching; done != nil {
+		s.mu.Unlock()
+		select {
+		case <-done:
+			s.mu.Lock()
+			return s.err
+		case <-ctx.Done():
+			s.mu.Lock()
+			return ctx.Err()
+		}
+	}
+
+	now := s.now()
+	if !s.attempted.IsZero() && now.Sub(s.attempted) < minRefetch {
+		return s.err
+	}
+	s.attempted = now
+	done := make(chan struct{})
+	s.fetching = done
+	url := s.url
+	s.mu.Unlock()
+
+	// the keys are shared by all the requests waiting for them, so they are not fetched with the context of the first
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	url, keys, err := s.fetch(ctx, url)
+	cancel()
+
+	s.mu.Lock()
+	s.url = url
+	if err == nil {
+		s.keys, s.fetched = keys, s.now()
+	}
+	s.err = err
+	s.fetching = nil
+	close(done)
+	return err
+}
+
+func (s *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// fetch returns the keys of the JWKS at url, discovering it first if it is empty, and the URL they were fetched from.
+func (s *keySet) fetch(ctx context.Context, url string) (string, map[string]crypto.PublicKey, error) {
+	if url == "" {
+		if s.config.JWKSURL != "" {
+			url = s.config.JWKSURL
+		} else {
+			var err error
+			if url, err = s.discover(ctx); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := s.get(ctx, url, &jwks); err != nil {
+		return url, nil, fmt.Errorf("jwtauth: unable to fetch the JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		// keys of unknown types or for encryption are skipped, as RFC 7517 asks
+		if kid, k, err := parseJWK(raw); err == nil {
+			keys[kid] = k
+		}
+	}
+	return url, keys, nil
+}
+
+// discover returns the JWKS URL of the OpenID Connect discovery document of the Issuer, which must name the Issuer as
+// its own, as the iss claim of the tokens is checked against it.
+func (s *keySet) discover(ctx context.Context) (string, error) {
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	endpoint := strings.TrimSuffix(s.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := s.get(ctx, endpoint, &doc); err != nil {
+		return "", fmt.Errorf("jwtauth: discovery: %w", err)
+	}
+	if doc.Issuer != s.config.Issuer {
+		return "", fmt.Errorf("jwtauth: discovery: issuer %q is not %q", doc.Issuer, s.config.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", errors.New("jwtauth: discovery: no jwks_uri")
+	}
+	return doc.JWKSURI, nil
+}
+
+func (s *keySet) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New(res.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxJWKS)).Decode(v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// parseJWK returns the ID and the public key of the signing JWK raw.
+func parseJWK(raw json.RawMessage) (string, crypto.PublicKey, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return "", nil, err
+	}
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return "", nil, fmt.Errorf("key for %q", jwk.Use)
+	}
+
+	field := func(v string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, e := field(jwk.N), field(jwk.E)
+		if n == nil || e == nil || !e.IsInt64() {
+			return "", nil, errors.New("invalid RSA key")
+		}
+		return jwk.Kid, &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		curves := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}
+		curve, ok := curves[jwk.Crv]
+		x, y := field(jwk.X), field(jwk.Y)
+		if !ok || x == nil || y == nil || !curve.IsOnCurve(x, y) {
+			return "", nil, errors.New("invalid EC key")
+		}
+		return jwk.Kid, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return "", nil, errors.New("invalid OKP key")
+		}
+		return jwk.Kid, ed25519.PublicKey(x), nil
+	}
+	return "", nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
@@ -0,0 +1,218 @@
+// Package jwtauth authenticates the requests of an API with the bearer JSON Web Tokens an OAuth 2.0 or OpenID Connect
+// provider issues, checking their signature with the keys the provider publishes as a JWKS:
+//
+//	config, err := jwtauth.FromEnv(a.LookupEnv, "")
+//	...
+//	authn, err := jwtauth.New(config, nil)
+//	mux.Handle("GET /me", authn.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		claims, _ := jwtauth.FromContext(r.Context())
+//		if !claims.HasScope("profile") {
+//			...
+//		}
+//		fmt.Fprintln(w, claims.Subject())
+//	})))
+//
+// The keys are fetched on first use, from the JWKS URL or the one OpenID Connect discovery finds for the issuer, and
+// fetched again once they are older than the RefreshInterval or when a token is signed with a key they lack, so that
+// the provider can rotate its keys. Requests without a valid token are answered with 401 Unauthorized problem
+// details; see the problem package.
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// ErrNoToken is returned by Token when a request has no bearer token.
+var ErrNoToken = errors.New("jwtauth: no bearer token")
+
+// algorithms are the signature algorithms of the tokens accepted, those of public keys.
+var algorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Claims are the claims of a verified token.
+type Claims map[string]interface{}
+
+// Subject returns the sub claim, the user or client the token was issued to.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer returns the iss claim.
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// String returns the claim name if it is a string, and an empty string otherwise.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns the claim name as a list of strings: the strings of a list, the words of a string, or nil.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var ss []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	}
+	return nil
+}
+
+// Audience returns the aud claim.
+func (c Claims) Audience() []string {
+	return c.Strings("aud")
+}
+
+// Scopes returns the scopes granted to the token, from the scope claim or, as some providers name it, the scp claim.
+func (c Claims) Scopes() []string {
+	if _, ok := c["scope"]; ok {
+		return c.Strings("scope")
+	}
+	return c.Strings("scp")
+}
+
+// HasScope reports whether the token was granted scope.
+func (c Claims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpiresAt returns the exp claim, or the zero time if the token does not expire.
+func (c Claims) ExpiresAt() time.Time {
+	if exp, ok := c["exp"].(float64); ok {
+		return time.Unix(int64(exp), 0)
+	}
+	return time.Time{}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying claims.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the claims of the token the request of ctx was authenticated with, and whether it was.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
+
+// Authenticator verifies bearer tokens.
+type Authenticator struct {
+	config Config
+	keys   *keySet
+	parser *jwt.Parser
+}
+
+// New returns an Authenticator accepting the tokens config describes, fetching the keys with client, or a client
+// giving up after 10 seconds if nil. It fails if the config does not Validate.
+func New(config *Config, client *http.Client) (*Authenticator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: fetchTimeout}
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(config.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	// the Issuer is required to discover the JWKS, so the tokens are always checked against a discovered issuer
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if len(config.Audience) > 0 {
+		opts = append(opts, jwt.WithAudience(config.Audience...))
+	}
+
+	return &Authenticator{
+		config: *config,
+		keys:   &keySet{config: *config, client: client, now: time.Now},
+		parser: jwt.NewParser(opts...),
+	}, nil
+}
+
+// Verify returns the claims of token if it is signed with a key of the JWKS, has not expired, and has the issuer and
+// one of the audiences of the Config.
+func (a *Authenticator) Verify(ctx context.Context, token string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jwtauth: %w", err)
+	}
+	return Claims(claims), nil
+}
+
+// Token returns the bearer token of the Authorization header of r, or ErrNoToken.
+func Token(r *http.Request) (string, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrNoToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// Wrap returns a handler calling next with the claims of the bearer token of the request in its context. Requests
+// without a token, unless the Config makes it Optional, and requests with an invalid token are answered with 401
+// Unauthorized.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := Token(r)
+		if err == ErrNoToken && a.config.Optional {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			unauthorized(w, r, `Bearer`, "a bearer token is required")
+			return
+		}
+
+		claims, err := a.Verify(r.Context(), token)
+		if err != nil {
+			unauthorized(w, r, `Bearer error="invalid_token"`, strings.TrimPrefix(err.Error(), "jwtauth: "))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+// unauthorized answers r with 401 Unauthorized, challenging the client as RFC 6750 describes.
+func unauthorized(w http.ResponseWriter, r *http.Request, challenge, detail string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	p := problem.New(http.StatusUnauthorized, "%s", detail)
+	if id, ok := correlation.FromContext(r.Context()); ok {
+		p.RequestID = id
+	}
+	problem.Write(w, p)
+}
@@ -0,0 +1,324 @@
+package jwtauth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/jwtauth"
+)
+
+// issuer is an OpenID Connect provider publishing its keys as a JWKS.
+type issuer struct {
+	*httptest.Server
+	keys    map[string]interface{} // the private keys by ID
+	fetches atomic.Int32
+}
+
+func newIssuer(t *testing.T) *issuer {
+	iss := &issuer{keys: make(map[string]interface{})}
+	iss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/jwks"})
+		case "/jwks":
+			iss.fetches.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": iss.jwks()})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(iss.Close)
+	iss.rotate(t, "rsa-1")
+	return iss
+}
+
+func b64(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// rotate adds a key, RSA if its ID starts with rsa and P-256 otherwise.
+func (iss *issuer) rotate(t *testing.T, kid string) {
+	var err error
+	if kid[:3] == "rsa" {
+		iss.keys[kid], err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		iss.keys[kid], err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	require.NoError(t, err)
+}
+
+func (iss *issuer) jwks() []map[string]string {
+	jwks := []map[string]string{{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"}}
+	for kid, key := range iss.keys {
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "n": b64(k.N), "e": b64(big.NewInt(int64(k.E))),
+			})
+		case *ecdsa.PrivateKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(k.X), "y": b64(k.Y),
+			})
+		}
+	}
+	return jwks
+}
+
+// sign returns a token signed with the key kid, issued by iss to the orders API, expiring in an hour. The claims
+// override those, or remove them when nil.
+func (iss *issuer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	all := jwt.MapClaims{
+		"iss": iss.URL,
+		"aud": "orders",
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(all, k)
+		} else {
+			all[k] = v
+		}
+	}
+
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if _, ok := iss.keys[kid].(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+	token := jwt.NewWithClaims(method, all)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(iss.keys[kid])
+	require.NoError(t, err)
+	return s
+}
+
+func TestAuthenticator_Verify(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.Issuer = iss.URL
+	config.Audience = []string{"orders"}
+	authn, err := jwtauth.New(config, nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	claims, err := authn.Verify(ctx, iss.sign(t, "rsa-1", jwt.MapClaims{"scope": "read write"}))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject())
+	assert.Equal(t, iss.URL, claims.Issuer())
+	assert.Equal(t, []string{"orders"}, claims.Audience())
+	assert.True(t, claims.HasScope("write"))
+	assert.False(t, claims.HasScope("admin"))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt(), time.Minute)
+
+	for name, tt := range map[string]jwt.MapClaims{
+		"expired":       {"exp": time.Now().Add(-time.Hour).Unix()},
+		"never expires": {"exp": nil},
+		"other issuer":  {"iss": "https://evil.example.com"},
+		"other API":     {"aud": []string{"billing"}},
+	} {
+		_, err := authn.Verify(ctx, iss.sign(t, "rsa-1", tt))
+		assert.Error(t, err, name)
+	}
+
+	// a token signed by a key the issuer does not publish
+	other := newIssuer(t)
+	_, err = authn.Verify(ctx, other.sign(t, "rsa-1", jwt.MapClaims{"iss": iss.URL}))
+	assert.ErrorContains(t, err, "signature is invalid")
+
+	// an unsigned token
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"iss": iss.URL, "aud": "orders"}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	_, err = authn.Verify(ctx, unsigned)
+	assert.Error(t, err)
+
+	// the keys were discovered and fetched once
+	assert.Equal(t, int32(1), iss.fetches.Load())
+}
+
+func TestAuthenticator_Audience(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.Issuer = iss.URL
+	_, err := jwtauth.New(config, nil)
+	assert.EqualError(t, err, "jwtauth: an audience is required")
+
+	// unless the tokens of any audience are explicitly accepted
+	config.AnyAudience = true
+	authn, err := jwtauth.New(config, nil)
+	require.NoError(t, err)
+	_, err = authn.Verify(context.Background(), iss.sign(t, "rsa-1", jwt.MapClaims{"aud": "billing"}))
+	assert.NoError(t, err)
+
+	_, err = jwtauth.New(&jwtauth.Config{AnyAudience: true}, nil)
+	assert.EqualError(t, err, "jwtauth: a JWKS URL or an issuer is required")
+}
+
+func TestAuthenticator_DiscoveredIssuer(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.Issuer = iss.URL + "/"
+	config.Audience = []string{"orders"}
+	authn, err := jwtauth.New(config, nil)
+	require.NoError(t, err)
+
+	// the discovery document names another issuer, so the keys it points at are not trusted
+	_, err = authn.Verify(context.Background(), iss.sign(t, "rsa-1", jwt.MapClaims{"iss": iss.URL + "/"}))
+	assert.ErrorContains(t, err, "discovery: issuer")
+	assert.Equal(t, int32(0), iss.fetches.Load())
+}
+
+func TestAuthenticator_Rotation(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.JWKSURL = iss.URL + "/jwks"
+	config.Audience = []string{"orders"}
+	authn, err := jwtauth.New(config, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	jwtauth.SetNow(authn, func() time.Time { return now })
+	ctx := context.Background()
+
+	_, err = authn.Verify(ctx, iss.sign(t, "rsa-1", nil))
+	require.NoError(t, err)
+
+	// a token signed with a new key makes the keys be fetched again, but not more than once a minute
+	iss.rotate(t, "ec-1")
+	_, err = authn.Verify(ctx, iss.sign(t, "ec-1", nil))
+	assert.ErrorContains(t, err, `no key "ec-1"`)
+	assert.Equal(t, int32(1), iss.fetches.Load())
+
+	now = now.Add(time.Minute)
+	claims, err := authn.Verify(ctx, iss.sign(t, "ec-1", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject())
+	assert.Equal(t, int32(2), iss.fetches.Load())
+
+	// the keys are fetched again once they are older than the RefreshInterval
+	now = now.Add(jwtauth.DefaultRefreshInterval)
+	_, err = authn.Verify(ctx, iss.sign(t, "rsa-1", nil))
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), iss.fetches.Load())
+
+	// stale keys are used while the JWKS cannot be fetched
+	now = now.Add(jwtauth.DefaultRefreshInterval)
+	iss.Config.Handler = http.NotFoundHandler()
+	_, err = authn.Verify(ctx, iss.sign(t, "rsa-1", nil))
+	require.NoError(t, err)
+}
+
+func TestAuthenticator_Fetching(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.JWKSURL = iss.URL + "/jwks"
+	config.Audience = []string{"orders"}
+	authn, err := jwtauth.New(config, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	jwtauth.SetNow(authn, func() time.Time { return now })
+	ctx := context.Background()
+
+	_, err = authn.Verify(ctx, iss.sign(t, "rsa-1", nil))
+	require.NoError(t, err)
+
+	// the tokens signed with a new key wait for the keys to be fetched once
+	iss.rotate(t, "ec-1")
+	token := iss.sign(t, "ec-1", nil)
+	handler, fetching, release := iss.Config.Handler, make(chan struct{}), make(chan struct{})
+	iss.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+		handler.ServeHTTP(w, r)
+	})
+	now = now.Add(time.Minute)
+	errs := make(chan error)
+	for range 3 {
+		go func() {
+			_, err := authn.Verify(ctx, token)
+			errs <- err
+		}()
+	}
+	<-fetching
+
+	// while the tokens signed with the cached keys are verified
+	_, err = authn.Verify(ctx, iss.sign(t, "rsa-1", nil))
+	require.NoError(t, err)
+
+	// and the requests giving up stop waiting
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = authn.Verify(canceled, token)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	for range 3 {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(2), iss.fetches.Load())
+}
+
+func TestAuthenticator_Wrap(t *testing.T) {
+	iss := newIssuer(t)
+	config := jwtauth.DefaultConfig()
+	config.Issuer = iss.URL
+	config.Audience = []string{"orders"}
+	handler := func(config *jwtauth.Config) http.Handler {
+		authn, err := jwtauth.New(config, nil)
+		require.NoError(t, err)
+		return authn.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := jwtauth.FromContext(r.Context())
+			if !ok {
+				_, _ = w.Write([]byte("anonymous"))
+				return
+			}
+			_, _ = w.Write([]byte(claims.Subject()))
+		}))
+	}
+	serve := func(h http.Handler, authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	h := handler(config)
+
+	w := serve(h, "Bearer "+iss.sign(t, "rsa-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user-1", w.Body.String())
+
+	w = serve(h, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	w = serve(h, "Basic dXNlcjpwYXNz")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = serve(h, "Bearer "+iss.sign(t, "rsa-1", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, w.Body.String(), "token is expired")
+
+	config.Optional = true
+	h = handler(config)
+	assert.Equal(t, "anonymous", serve(h, "").Body.String())
+	assert.Equal(t, http.StatusUnauthorized, serve(h, "Bearer garbage").Code)
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the client settings, as in APP_K8S_QPS; KUBECONFIG itself is read too.
+	DefaultPrefix = "APP_K8S_"
+
+	// DefaultQPS is the rate of requests to the API server a Client sustains.
+	DefaultQPS = 20
+
+	// DefaultBurst is the number of requests to the API server a Client may make at once, above the QPS.
+	DefaultBurst = 40
+
+	// DefaultTimeout is how long a request to the API server may take.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Config describes how a Client reaches the Kubernetes API server.
+type Config struct {
+	Kubeconfig string        // the kubeconfig files, separated as in KUBECONFIG; in-cluster or ~/.kube/config if empty
+	Context    string        // the kubeconfig context used, the current context if empty
+	QPS        float32       // the rate of requests sustained
+	Burst      int           // the requests made at once, above the QPS
+	Timeout    time.Duration // how long a request may take
+}
+
+// DefaultConfig returns a Config for the in-cluster or default kubeconfig, throttled to DefaultQPS and DefaultBurst.
+func DefaultConfig() *Config {
+	return &Config{QPS: DefaultQPS, Burst: DefaultBurst, Timeout: DefaultTimeout}
+}
+
+func init() {
+	configschema.Register("k8s", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the Kubernetes client variables with the prefix, and the plain KUBECONFIG FromEnv falls back to.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "KUBECONFIG", Type: "path",
+			Description: "The kubeconfig files; in-cluster, KUBECONFIG, or ~/.kube/config if not set."},
+		{Name: prefix + "CONTEXT", Type: "string", Description: "The kubeconfig context, the current one if not set."},
+		{Name: prefix + "QPS", Type: "float", Default: strconv.Itoa(DefaultQPS),
+			Description: "The rate of requests sustained."},
+		{Name: prefix + "BURST", Type: "int", Default: strconv.Itoa(DefaultBurst),
+			Description: "The requests made at once, above the QPS."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a request may take."},
+		{Name: "KUBECONFIG", Type: "path", Description: "The kubeconfig files kubectl reads, unless " + prefix +
+			"KUBECONFIG is set."},
+	}
+}
+
+// FromEnv reads how to reach the cluster from KUBECONFIG, CONTEXT, QPS, BURST, and TIMEOUT, with the prefix or
+// DefaultPrefix. Without a prefixed KUBECONFIG, the KUBECONFIG variable kubectl reads is used, and in a pod neither is
+// needed.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	config.Kubeconfig = get("KUBECONFIG")
+	if config.Kubeconfig == "" {
+		v, _ := lookup("KUBECONFIG")
+		config.Kubeconfig = strings.TrimSpace(v)
+	}
+	config.Context = get("CONTEXT")
+
+	if v := get("QPS"); v != "" {
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil || f <= 0 {
+			return nil, fmt.Errorf("k8s: invalid %sQPS %q", prefix, v)
+		}
+		config.QPS = float32(f)
+	}
+
+	if v := get("BURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("k8s: invalid %sBURST %q", prefix, v)
+		}
+		config.Burst = n
+	}
+
+	if v := get("TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("k8s: invalid %sTIMEOUT %q", prefix, v)
+		}
+		config.Timeout = d
+	}
+
+	return config, nil
+}
@@ -0,0 +1,61 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/k8s"
+)
+
+func TestFromEnv_Kubeconfig(t *testing.T) {
+	// the prefixed variable wins over the one kubectl reads
+	config, err := k8s.FromEnv(apptest.Lookup(map[string]string{
+		"APP_K8S_KUBECONFIG": " /etc/kube/config ",
+		"KUBECONFIG":         "/home/user/.kube/config",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/kube/config", config.Kubeconfig)
+
+	// the list is kept whole for the loading rules to split
+	config, err = k8s.FromEnv(apptest.Lookup(map[string]string{
+		"CLUSTER_CONTEXT": "staging",
+		"KUBECONFIG":      "/a:/b",
+	}), "CLUSTER_")
+	require.NoError(t, err)
+	assert.Equal(t, "/a:/b", config.Kubeconfig)
+	assert.Equal(t, "staging", config.Context)
+
+	// in a pod neither is set, and the in-cluster config is used
+	config, err = k8s.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, k8s.DefaultConfig(), config)
+}
+
+func TestFromEnv_Throttle(t *testing.T) {
+	config, err := k8s.FromEnv(apptest.Lookup(map[string]string{"APP_K8S_QPS": "0.5", "APP_K8S_BURST": "1"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, float32(0.5), config.QPS)
+	assert.Equal(t, 1, config.Burst)
+
+	for key, values := range map[string][]string{"QPS": {"0", "-1", "fast"}, "BURST": {"0", "2.5"}} {
+		for _, v := range values {
+			_, err := k8s.FromEnv(apptest.Lookup(map[string]string{"APP_K8S_" + key: v}), "")
+			assert.EqualError(t, err, "k8s: invalid APP_K8S_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_Timeout(t *testing.T) {
+	// zero leaves the requests without a timeout, as client-go does
+	config, err := k8s.FromEnv(apptest.Lookup(map[string]string{"APP_K8S_TIMEOUT": "0s"}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Timeout)
+
+	for _, v := range []string{"30", "-5s"} {
+		_, err := k8s.FromEnv(apptest.Lookup(map[string]string{"APP_K8S_TIMEOUT": v}), "")
+		assert.EqualError(t, err, `k8s: invalid APP_K8S_TIMEOUT "`+v+`"`, v)
+	}
+}
@@ -0,0 +1,8 @@
+package k8s
+
+// SetServiceAccountDir replaces the directory the service account of the pod is read from.
+func SetServiceAccountDir(dir string) func() {
+	prev := serviceAccountDir
+	serviceAccountDir = dir
+	return func() { serviceAccountDir = prev }
+}
@@ -0,0 +1,152 @@
+// Package k8s builds Kubernetes clients, from the service account of the pod in a cluster or from a kubeconfig file
+// outside of one, as kubectl does:
+//
+//	client, err := k8s.For(a)
+//	if err != nil {
+//		return err
+//	}
+//	pods, err := client.CoreV1().Pods(client.Namespace).List(ctx, metav1.ListOptions{})
+//
+// For returns the same Client for every call with an App, configured by FromEnv and kept as an app.Service; New
+// builds others, such as for another cluster. A Client is safe for concurrent use. Check and Handler report whether
+// the API server is reachable, for readiness probes, and LeaderElection runs a function on one replica at a time; see
+// the leader package for elections that do not depend on Kubernetes.
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// serviceAccountDir is where Kubernetes mounts the token, the CA certificate, and the namespace of the service
+// account of a pod.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client is a Kubernetes clientset with the configuration it was built from.
+type Client struct {
+	kubernetes.Interface
+
+	REST      *rest.Config // the configuration of the clientset, to build other clients such as dynamic ones
+	Namespace string       // the namespace of the pod, or of the kubeconfig context; default if neither sets one
+	InCluster bool         // whether the Client uses the service account of the pod
+}
+
+// clientKey is the key of the Client of an App among its services.
+type clientKey struct{}
+
+// For returns the Client of the app, built by New with the Config from FromEnv on first use and kept until the app
+// exits.
+func For(a *app.App) (*Client, error) {
+	return app.Service(a, clientKey{}, func() (*Client, error) {
+		config, err := FromEnv(a.LookupEnv, "")
+		if err != nil {
+			return nil, err
+		}
+		return New(a, config)
+	})
+}
+
+// New returns a Client configured by config, or the DefaultConfig if it is nil. Without a Kubeconfig, the Client
+// uses the service account of the pod when the app Environment has KUBERNETES_SERVICE_HOST, as it does in a cluster,
+// and ~/.kube/config otherwise.
+func New(a *app.App, config *Config) (*Client, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	c := &Client{}
+	var err error
+	if _, ok := a.LookupEnv("KUBERNETES_SERVICE_HOST"); ok && config.Kubeconfig == "" {
+		c.REST, err = inClusterConfig(a)
+		c.Namespace, c.InCluster = a.Pod().Namespace, true
+	} else {
+		c.REST, c.Namespace, err = kubeconfig(config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Namespace == "" {
+		c.Namespace = "default"
+	}
+
+	c.REST.QPS, c.REST.Burst, c.REST.Timeout = config.QPS, config.Burst, config.Timeout
+	if c.REST.UserAgent == "" {
+		c.REST.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	if c.Interface, err = kubernetes.NewForConfig(c.REST); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// inClusterConfig returns the configuration of the service account of the pod, as rest.InClusterConfig does but
+// with the address of the API server from the app Environment.
+func inClusterConfig(a *app.App) (*rest.Config, error) {
+	host, _ := a.LookupEnv("KUBERNETES_SERVICE_HOST")
+	port, _ := a.LookupEnv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("k8s: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set in a cluster")
+	}
+
+	token := filepath.Join(serviceAccountDir, "token")
+	if _, err := os.Stat(token); err != nil {
+		return nil, fmt.Errorf("k8s: no service account token: %w", err)
+	}
+	return &rest.Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		BearerTokenFile: token,
+		TLSClientConfig: rest.TLSClientConfig{CAFile: filepath.Join(serviceAccountDir, "ca.crt")},
+	}, nil
+}
+
+// kubeconfig returns the configuration of the Context in the Kubeconfig files, and its namespace.
+func kubeconfig(config *Config) (*rest.Config, string, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(config.Kubeconfig)}
+	if len(rules.Precedence) == 0 {
+		rules.Precedence = []string{clientcmd.RecommendedHomeFile}
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: config.Context}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("k8s: %w", err)
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("k8s: %w", err)
+	}
+	return restConfig, namespace, nil
+}
+
+// Check returns an error unless the API server answers a request for its version.
+func (c *Client) Check(ctx context.Context) error {
+	if err := c.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("k8s: API server unreachable: %w", err)
+	}
+	return nil
+}
+
+// Handler returns a health check handler answering 200 when Check succeeds and 503 with the error otherwise.
+func (c *Client) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := c.Check(r.Context()); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, err)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+}
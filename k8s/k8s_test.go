@@ -0,0 +1,157 @@
+package k8s_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/k8s"
+)
+
+// apiServer answers requests for the version like an API server, checking the bearer token if it is not empty.
+func apiServer(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/version" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"major":"1","minor":"34","gitVersion":"v1.34.1"}`)
+	})
+}
+
+// writeKubeconfig writes a kubeconfig file for srv, trusting its certificate if it serves TLS.
+func writeKubeconfig(t *testing.T, srv *httptest.Server) string {
+	var ca string
+	if srv.TLS != nil {
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		ca = base64.StdEncoding.EncodeToString(cert)
+	}
+
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+  - name: test
+    cluster: {server: %q, certificate-authority-data: %q}
+users:
+  - name: test
+    user:
+      token: secret
+contexts:
+  - name: test
+    context: {cluster: test, user: test, namespace: apps}
+  - name: other
+    context: {cluster: test, user: test}
+current-context: test
+`, srv.URL, ca)), 0o600))
+	return path
+}
+
+func TestNew_Kubeconfig(t *testing.T) {
+	srv := httptest.NewTLSServer(apiServer("secret"))
+	defer srv.Close()
+
+	config := k8s.DefaultConfig()
+	config.Kubeconfig = writeKubeconfig(t, srv)
+	client, err := k8s.New(apptest.New(t, nil), config)
+	require.NoError(t, err)
+	assert.False(t, client.InCluster)
+	assert.Equal(t, "apps", client.Namespace)
+	assert.Equal(t, srv.URL, client.REST.Host)
+	assert.Equal(t, float32(k8s.DefaultQPS), client.REST.QPS)
+	assert.NoError(t, client.Check(context.Background()))
+
+	version, err := client.Discovery().ServerVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "v1.34.1", version.GitVersion)
+
+	config.Context = "other"
+	client, err = k8s.New(apptest.New(t, nil), config)
+	require.NoError(t, err)
+	assert.Equal(t, "default", client.Namespace)
+
+	config.Context = "missing"
+	_, err = k8s.New(apptest.New(t, nil), config)
+	assert.ErrorContains(t, err, `k8s: context "missing" does not exist`)
+}
+
+func TestNew_InCluster(t *testing.T) {
+	srv := httptest.NewTLSServer(apiServer("pod-token"))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	defer k8s.SetServiceAccountDir(dir)()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("pod-token"), 0o600))
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0o600))
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	a := apptest.New(t, []string{
+		"KUBERNETES_SERVICE_HOST=" + host,
+		"KUBERNETES_SERVICE_PORT=" + port,
+		"POD_NAMESPACE=jobs",
+	})
+
+	client, err := k8s.New(a, nil)
+	require.NoError(t, err)
+	assert.True(t, client.InCluster)
+	assert.Equal(t, "jobs", client.Namespace)
+	assert.NoError(t, client.Check(context.Background()))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "token")))
+	_, err = k8s.New(a, nil)
+	assert.ErrorContains(t, err, "k8s: no service account token")
+}
+
+func TestClient_Handler(t *testing.T) {
+	srv := httptest.NewServer(apiServer(""))
+	client, err := k8s.New(apptest.New(t, nil), &k8s.Config{Kubeconfig: writeKubeconfig(t, srv)})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	client.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok\n", rec.Body.String())
+
+	srv.Close()
+	rec = httptest.NewRecorder()
+	client.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "k8s: API server unreachable")
+}
+
+func TestFor(t *testing.T) {
+	srv := httptest.NewServer(apiServer(""))
+	defer srv.Close()
+
+	a := apptest.New(t, []string{"KUBECONFIG=" + writeKubeconfig(t, srv)})
+	client, err := k8s.For(a)
+	require.NoError(t, err)
+	same, err := k8s.For(a)
+	require.NoError(t, err)
+	assert.Same(t, client, same)
+
+	other, err := k8s.For(apptest.New(t, []string{"KUBECONFIG=" + writeKubeconfig(t, srv)}))
+	require.NoError(t, err)
+	assert.NotSame(t, client, other)
+
+	_, err = k8s.For(apptest.New(t, []string{"APP_K8S_QPS=fast"}))
+	assert.EqualError(t, err, `k8s: invalid APP_K8S_QPS "fast"`)
+}
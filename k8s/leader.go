@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const (
+	// DefaultLeaseDuration is how long the other replicas wait for a leader that stopped renewing its lease.
+	DefaultLeaseDuration = 15 * time.Second
+
+	// DefaultRenewDeadline is how long the leader keeps trying to renew its lease before it gives up leading.
+	DefaultRenewDeadline = 10 * time.Second
+
+	// DefaultRetryPeriod is how often the replicas try to acquire, and the leader to renew, the lease.
+	DefaultRetryPeriod = 2 * time.Second
+)
+
+// Election describes a leader election held with a Lease, in which one replica at a time leads.
+type Election struct {
+	Name      string // the name of the Lease
+	Namespace string // the namespace of the Lease, the namespace of the Client if empty
+	Identity  string // the identity of the replica in the Lease, its host name, the pod name in a cluster, if empty
+
+	LeaseDuration time.Duration // DefaultLeaseDuration if zero
+	RenewDeadline time.Duration // DefaultRenewDeadline if zero
+	RetryPeriod   time.Duration // DefaultRetryPeriod if zero
+
+	// OnStartedLeading is called when the replica becomes the leader, with a context canceled when it stops leading.
+	OnStartedLeading func(ctx context.Context)
+
+	// OnStoppedLeading, if set, is called when the replica stops leading.
+	OnStoppedLeading func()
+
+	// OnNewLeader, if set, is called with the identity of each new leader, this replica included.
+	OnNewLeader func(identity string)
+}
+
+// LeaderElection takes part in election until ctx is done. A replica that loses the lead, because it could not renew
+// the Lease in time, stops leading and takes part again once OnStartedLeading has returned. The lease is released
+// when ctx is done, so that another replica leads without waiting for the LeaseDuration. The changes of leader are
+// logged with the app Logger.
+func (c *Client) LeaderElection(ctx context.Context, a *app.App, election *Election) error {
+	if election.Name == "" || election.OnStartedLeading == nil {
+		return errors.New("k8s: an Election needs a Name and OnStartedLeading")
+	}
+
+	e := *election
+	if e.Namespace == "" {
+		e.Namespace = c.Namespace
+	}
+	if e.Identity == "" {
+		host, err := a.Hostname()
+		if err != nil {
+			return err
+		}
+		e.Identity = host
+	}
+	for dst, def := range map[*time.Duration]time.Duration{
+		&e.LeaseDuration: DefaultLeaseDuration,
+		&e.RenewDeadline: DefaultRenewDeadline,
+		&e.RetryPeriod:   DefaultRetryPeriod,
+	} {
+		if *dst == 0 {
+			*dst = def
+		}
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+		"lease":    e.Namespace + "/" + e.Name,
+		"identity": e.Identity,
+	})
+	// client-go calls OnStartedLeading in its own goroutine and does not wait for it, so each Run waits for the
+	// OnStartedLeading it started to return before the next, which could lead again
+	var (
+		mu      sync.Mutex
+		leading sync.WaitGroup
+	)
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: e.Name, Namespace: e.Namespace},
+			Client:     c.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: e.Identity},
+		},
+		LeaseDuration:   e.LeaseDuration,
+		RenewDeadline:   e.RenewDeadline,
+		RetryPeriod:     e.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            e.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				if ctx.Err() != nil {
+					// the Run that started leading is already over
+					mu.Unlock()
+					return
+				}
+				leading.Add(1)
+				mu.Unlock()
+				defer leading.Done()
+
+				_ = a.Logger().Infom(attrs, "started leading")
+				e.OnStartedLeading(ctx)
+			},
+			OnStoppedLeading: func() {
+				_ = a.Logger().Infom(attrs, "stopped leading")
+				if e.OnStoppedLeading != nil {
+					e.OnStoppedLeading()
+				}
+			},
+			OnNewLeader: func(identity string) {
+				if identity != e.Identity {
+					_ = a.Logger().Infom(attrs, "%s is leading", identity)
+				}
+				if e.OnNewLeader != nil {
+					e.OnNewLeader(identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+		// an OnStartedLeading of the Run either registered before this, or sees its context canceled
+		mu.Lock()
+		leading.Wait()
+		mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/k8s"
+)
+
+func TestClient_LeaderElection(t *testing.T) {
+	client := &k8s.Client{Interface: fake.NewClientset(), Namespace: "apps"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := apptest.New(t, nil)
+	a.Identity = &app.Identity{Hostname: "replica-1"}
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	leaders := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.LeaderElection(ctx, a, &k8s.Election{
+			Name:          "scheduler",
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+			OnStartedLeading: func(ctx context.Context) {
+				close(started)
+				<-ctx.Done()
+			},
+			OnStoppedLeading: func() { close(stopped) },
+			OnNewLeader:      func(identity string) { leaders <- identity },
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not leading")
+	}
+	lease, err := client.CoordinationV1().Leases("apps").Get(context.Background(), "scheduler", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1", *lease.Spec.HolderIdentity)
+
+	assert.Equal(t, "replica-1", <-leaders)
+
+	cancel()
+	assert.NoError(t, <-done)
+	<-stopped
+
+	// the lease was released
+	lease, err = client.CoordinationV1().Leases("apps").Get(context.Background(), "scheduler", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, lease.Spec.HolderIdentity)
+
+	err = client.LeaderElection(context.Background(), a, &k8s.Election{Name: "scheduler"})
+	assert.EqualError(t, err, "k8s: an Election needs a Name and OnStartedLeading")
+}
+
+func TestClient_LeaderElection_lostLead(t *testing.T) {
+	clientset := fake.NewClientset()
+	// renewing the Lease fails while failing is set
+	var failing atomic.Bool
+	clientset.PrependReactor("update", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failing.Load() {
+			return true, nil, errors.New("unavailable")
+		}
+		return false, nil, nil
+	})
+	client := &k8s.Client{Interface: clientset, Namespace: "apps"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := apptest.New(t, nil)
+	a.Identity = &app.Identity{Hostname: "replica-1"}
+
+	var active, overlaps atomic.Int32
+	started := make(chan struct{}, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.LeaderElection(ctx, a, &k8s.Election{
+			Name:          "scheduler",
+			LeaseDuration: time.Second,
+			RenewDeadline: 500 * time.Millisecond,
+			RetryPeriod:   100 * time.Millisecond,
+			OnStartedLeading: func(ctx context.Context) {
+				if active.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				defer active.Add(-1)
+				started <- struct{}{}
+				<-ctx.Done()
+				failing.Store(false)
+				// outlives the lead, so that another campaign would overlap it
+				time.Sleep(time.Second)
+			},
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("not leading")
+	}
+
+	failing.Store(true)
+	select {
+	case <-started:
+	case <-time.After(10 * time.Second):
+		t.Fatal("not leading again")
+	}
+	assert.Zero(t, overlaps.Load())
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Zero(t, active.Load())
+}
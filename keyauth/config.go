@@ -0,0 +1,109 @@
+package keyauth
+
+import (
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the keys of the clients, APP_KEYAUTH_KEYS, and how they are sent.
+	DefaultPrefix = "APP_KEYAUTH_"
+
+	// DefaultHeader is the header carrying the API key of a request.
+	DefaultHeader = "X-API-Key"
+
+	// DefaultTolerance is how far the timestamp of a signed request may be from the current time.
+	DefaultTolerance = 5 * time.Minute
+
+	// DefaultMaxBodySize is the largest body of a signed request read, in bytes.
+	DefaultMaxBodySize = 1 << 20
+)
+
+// Config describes the keys of the clients an Authenticator accepts.
+type Config struct {
+	Keys        map[string]string // the secret keys by client name, such as the name of the calling service
+	Header      string            // the header carrying the API key of a request
+	Tolerance   time.Duration     // how far the timestamp of a signed request may be from the current time
+	MaxBodySize int64             // largest body of a signed request read, in bytes
+}
+
+// DefaultConfig returns a Config with no keys and the default header, tolerance, and body size.
+func DefaultConfig() *Config {
+	return &Config{
+		Keys:        map[string]string{},
+		Header:      DefaultHeader,
+		Tolerance:   DefaultTolerance,
+		MaxBodySize: DefaultMaxBodySize,
+	}
+}
+
+func init() {
+	configschema.Register("keyauth", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the API key variables with the prefix, such as for App.DeclareConfig for the keys of a route
+// group.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "KEYS", Type: "string", Secret: true,
+			Description: "The name:key pairs of the clients, separated by spaces or commas."},
+		{Name: prefix + "HEADER", Type: "string", Default: DefaultHeader,
+			Description: "The header carrying the API key of a request."},
+		{Name: prefix + "TOLERANCE", Type: "duration", Default: DefaultTolerance.String(),
+			Description: "How far the timestamp of a signed request may be from the current time."},
+		{Name: prefix + "MAX_BODY_SIZE", Type: "int", Default: strconv.Itoa(DefaultMaxBodySize),
+			Description: "The largest body of a signed request read, in bytes."},
+	}
+}
+
+// FromEnv reads the client KEYS, name:key pairs separated by spaces or commas, the HEADER carrying them, and the
+// TOLERANCE and MAX_BODY_SIZE, in bytes, of signed requests, with the prefix or DefaultPrefix. Use App.LookupEnv as
+// lookup to decrypt the keys encrypted with age. Route groups accepting other clients read their keys with other
+// prefixes.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	for i, pair := range strings.FieldsFunc(get("KEYS"), func(r rune) bool { return r == ' ' || r == ',' }) {
+		name, key, ok := strings.Cut(pair, ":")
+		if _, dup := config.Keys[name]; !ok || name == "" || key == "" || dup {
+			// the keys are secret, so the entry is not shown
+			return nil, fmt.Errorf("keyauth: invalid %sKEYS entry %d", prefix, i+1)
+		}
+		config.Keys[name] = key
+	}
+
+	if v := get("HEADER"); v != "" {
+		config.Header = textproto.CanonicalMIMEHeaderKey(v)
+	}
+
+	if v := get("TOLERANCE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("keyauth: invalid %sTOLERANCE %q", prefix, v)
+		}
+		config.Tolerance = d
+	}
+
+	if v := get("MAX_BODY_SIZE"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("keyauth: invalid %sMAX_BODY_SIZE %q", prefix, v)
+		}
+		config.MaxBodySize = n
+	}
+
+	return config, nil
+}
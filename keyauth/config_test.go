@@ -0,0 +1,62 @@
+package keyauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/keyauth"
+)
+
+func TestFromEnv_Keys(t *testing.T) {
+	// a key is everything after the first colon, so it may hold colons itself
+	config, err := keyauth.FromEnv(apptest.Lookup(map[string]string{
+		"APP_INTERNAL_KEYS": "billing:k3y, reports:s3:cr3t,,",
+	}), "APP_INTERNAL_")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"billing": "k3y", "reports": "s3:cr3t"}, config.Keys)
+
+	// the error names the entry by position, never by its contents
+	for v, n := range map[string]string{
+		"billing:a,billing:b": "2",
+		"reports:":            "1",
+		":s3cr3t":             "1",
+		"a:1 b:2 s3cr3t":      "3",
+	} {
+		_, err := keyauth.FromEnv(apptest.Lookup(map[string]string{"APP_KEYAUTH_KEYS": v}), "")
+		assert.EqualError(t, err, "keyauth: invalid APP_KEYAUTH_KEYS entry "+n, v)
+	}
+}
+
+func TestFromEnv_Header(t *testing.T) {
+	config, err := keyauth.FromEnv(apptest.Lookup(map[string]string{"APP_KEYAUTH_HEADER": " x-service-key "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Service-Key", config.Header)
+
+	config, err = keyauth.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, keyauth.DefaultConfig(), config)
+}
+
+func TestFromEnv_Signing(t *testing.T) {
+	config, err := keyauth.FromEnv(apptest.Lookup(map[string]string{
+		"APP_KEYAUTH_TOLERANCE":     "1m",
+		"APP_KEYAUTH_MAX_BODY_SIZE": "4096",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, config.Tolerance)
+	assert.Equal(t, int64(4096), config.MaxBodySize)
+
+	for key, values := range map[string][]string{
+		"TOLERANCE":     {"0s", "-1m", "60"},
+		"MAX_BODY_SIZE": {"0", "1MB"},
+	} {
+		for _, v := range values {
+			_, err := keyauth.FromEnv(apptest.Lookup(map[string]string{"APP_KEYAUTH_" + key: v}), "")
+			assert.EqualError(t, err, "keyauth: invalid APP_KEYAUTH_"+key+` "`+v+`"`)
+		}
+	}
+}
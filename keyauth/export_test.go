@@ -0,0 +1,8 @@
+package keyauth
+
+import "time"
+
+// SetNow replaces the clock of a.
+func SetNow(a *Authenticator, now func() time.Time) {
+	a.now = now
+}
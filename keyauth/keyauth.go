@@ -0,0 +1,312 @@
+// Package keyauth authenticates the calls services make to each other with keys shared between them, either sent as
+// they are, as API keys, or used to sign the requests with HMAC-SHA256:
+//
+//	config, err := keyauth.FromEnv(a.LookupEnv, "") // APP_KEYAUTH_KEYS=billing:k3y,reports:s3cr3t
+//	authn := keyauth.New(config, nil)
+//	mux.Handle("/internal/", authn.HMAC(internal))
+//	mux.Handle("/metrics/", authn.APIKey(metrics))
+//
+// and on the calling side:
+//
+//	client := &http.Client{Transport: keyauth.Transport(nil, "billing", []byte(key))}
+//
+// An API key is sent in the Header of the Config. A signed request has the SignatureHeader, holding the name of the
+// key, a timestamp, a nonce, and the signature of those, the method, the URL, and the body; see Sign. Signatures are
+// accepted while their timestamp is within the Tolerance of the current time, and once only, the NonceStore
+// remembering the nonces of those accepted. Requests that do not authenticate are answered with 401 Unauthorized
+// problem details; see the problem package. The handlers find the name of the calling client with FromContext.
+package keyauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// SignatureHeader carries the key name, timestamp, nonce, and HMAC-SHA256 signature of a signed request, e.g.
+// "key=billing,t=1700000000,nonce=8f3a...,v1=5257a8...".
+const SignatureHeader = "X-Signature"
+
+// defaultNonces is the capacity of the in-memory NonceStore New uses when given none.
+const defaultNonces = 100000
+
+// maxNonceLength bounds the length of the nonces.
+const maxNonceLength = 128
+
+var (
+	// ErrInvalidSignature is returned by Verify when a request is not signed with one of the keys.
+	ErrInvalidSignature = errors.New("keyauth: invalid signature")
+
+	// ErrSignatureExpired is returned by Verify when the timestamp of a request is not within the Tolerance.
+	ErrSignatureExpired = errors.New("keyauth: signature expired")
+
+	// ErrReplayed is returned by Verify when the nonce of a request was used already.
+	ErrReplayed = errors.New("keyauth: request replayed")
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the name of the client.
+func NewContext(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, contextKey{}, client)
+}
+
+// FromContext returns the name of the client the request of ctx was authenticated as, and whether it was.
+func FromContext(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(contextKey{}).(string)
+	return client, ok
+}
+
+// Authenticator authenticates requests with the keys of a Config.
+type Authenticator struct {
+	config Config
+	nonces NonceStore
+	now    func() time.Time
+}
+
+// New returns an Authenticator accepting the keys of config, and remembering the nonces of signed requests in nonces,
+// or in memory if nil.
+func New(config *Config, nonces NonceStore) *Authenticator {
+	if nonces == nil {
+		nonces = NewCache(cache.New[string, struct{}](&cache.Config{Name: "keyauth", Capacity: defaultNonces}, nil))
+	}
+	return &Authenticator{config: *config, nonces: nonces, now: time.Now}
+}
+
+// APIKey returns a handler calling next with the name of the client whose key the request has in the Header of the
+// Config, and answering the requests without one with 401 Unauthorized.
+func (a *Authenticator) APIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(a.config.Header)
+		if key == "" {
+			unauthorized(w, r, "missing "+a.config.Header+" header")
+			return
+		}
+
+		// every key is compared, in constant time, so that the time taken tells nothing of the keys
+		sum := sha256.Sum256([]byte(key))
+		client := ""
+		for name, k := range a.config.Keys {
+			ksum := sha256.Sum256([]byte(k))
+			if subtle.ConstantTimeCompare(sum[:], ksum[:]) == 1 {
+				client = name
+			}
+		}
+		if client == "" {
+			unauthorized(w, r, "invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), client)))
+	})
+}
+
+// HMAC returns a handler calling next with the name of the client whose key the request is signed with, and answering
+// the requests without a valid signature with 401 Unauthorized. The body is only read, up to the MaxBodySize of the
+// Config, once the request is signed at a valid time with one of the keys.
+func (a *Authenticator) HMAC(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sig, err := a.parse(r)
+		if err != nil {
+			unauthorized(w, r, strings.TrimPrefix(err.Error(), "keyauth: "))
+			return
+		}
+
+		var body []byte
+		if r.Body != nil {
+			if a.config.MaxBodySize > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, a.config.MaxBodySize)
+			}
+			body, err = io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "unable to read the request body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		client, err := a.verify(r, sig, body)
+		switch {
+		case errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrReplayed):
+			unauthorized(w, r, strings.TrimPrefix(err.Error(), "keyauth: "))
+		case err != nil:
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		default:
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), client)))
+		}
+	})
+}
+
+// Verify returns the name of the client whose key r, with body, is signed with. It returns ErrInvalidSignature,
+// ErrSignatureExpired, or ErrReplayed if the signature is not accepted, or the error of the NonceStore.
+func (a *Authenticator) Verify(r *http.Request, body []byte) (string, error) {
+	sig, err := a.parse(r)
+	if err != nil {
+		return "", err
+	}
+	return a.verify(r, sig, body)
+}
+
+// signature is the SignatureHeader of a request.
+type signature struct {
+	client, ts, nonce, secret string
+	signatures                [][]byte
+}
+
+// parse reads the SignatureHeader of r, checking it names one of the keys and its timestamp is within the Tolerance
+// before the body is read.
+func (a *Authenticator) parse(r *http.Request) (*signature, error) {
+	var sig signature
+	for _, field := range strings.Split(r.Header.Get(SignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "key":
+			sig.client = value
+		case "t":
+			sig.ts = value
+		case "nonce":
+			sig.nonce = value
+		case "v1":
+			if b, err := hex.DecodeString(value); err == nil {
+				sig.signatures = append(sig.signatures, b)
+			}
+		}
+	}
+
+	secret, ok := a.config.Keys[sig.client]
+	unix, err := strconv.ParseInt(sig.ts, 10, 64)
+	if !ok || err != nil || sig.nonce == "" || len(sig.nonce) > maxNonceLength || len(sig.signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	sig.secret = secret
+
+	skew := a.now().Sub(time.Unix(unix, 0))
+	if skew > a.config.Tolerance || skew < -a.config.Tolerance {
+		return nil, ErrSignatureExpired
+	}
+	return &sig, nil
+}
+
+// verify checks the signatures of sig against r and body, and remembers its nonce.
+func (a *Authenticator) verify(r *http.Request, sig *signature, body []byte) (string, error) {
+	expected := mac([]byte(sig.secret), sig.ts, sig.nonce, r.Method, r.URL.RequestURI(), body)
+	valid := false
+	for _, b := range sig.signatures {
+		valid = valid || hmac.Equal(b, expected)
+	}
+	if !valid {
+		return "", ErrInvalidSignature
+	}
+
+	// the nonce is only remembered once the signature is checked, so that others cannot use up the nonces of a client;
+	// it is kept until its timestamp is out of the Tolerance either way
+	fresh, err := a.nonces.Use(r.Context(), sig.client+":"+sig.nonce, 2*a.config.Tolerance)
+	if err != nil {
+		return "", err
+	}
+	if !fresh {
+		return "", ErrReplayed
+	}
+	return sig.client, nil
+}
+
+// Sign sets the SignatureHeader of r, signing it at t with the key named client. The body of r is read, and replaced
+// with a copy unless r has a GetBody function.
+func Sign(r *http.Request, client string, key []byte, t time.Time) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+
+	ts := strconv.FormatInt(t.Unix(), 10)
+	sig := mac(key, ts, nonce, r.Method, r.URL.RequestURI(), body)
+	r.Header.Set(SignatureHeader, "key="+client+",t="+ts+",nonce="+nonce+",v1="+hex.EncodeToString(sig))
+	return nil
+}
+
+// Transport returns a RoundTripper sending the requests with next, http.DefaultTransport if nil, signed with the key
+// named client.
+func Transport(next http.RoundTripper, client string, key []byte) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// a RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		if err := Sign(req, client, key, time.Now()); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// readBody returns the body of r, leaving r with an unread copy.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// mac returns the signature of a request: the HMAC-SHA256 of the timestamp, the nonce, the method, the URL, and the
+// hash of the body, each on a line.
+func mac(key []byte, ts, nonce, method, uri string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	h := hmac.New(sha256.New, key)
+	_, _ = io.WriteString(h, ts+"\n"+nonce+"\n"+method+"\n"+uri+"\n"+hex.EncodeToString(sum[:]))
+	return h.Sum(nil)
+}
+
+// unauthorized answers r with 401 Unauthorized.
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	p := problem.New(http.StatusUnauthorized, "%s", detail)
+	if id, ok := correlation.FromContext(r.Context()); ok {
+		p.RequestID = id
+	}
+	problem.Write(w, p)
+}
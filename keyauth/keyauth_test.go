@@ -0,0 +1,191 @@
+package keyauth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/keyauth"
+)
+
+func newAuthenticator(nonces keyauth.NonceStore) *keyauth.Authenticator {
+	config := keyauth.DefaultConfig()
+	config.Keys = map[string]string{"billing": "k3y", "reports": "s3cr3t"}
+	return keyauth.New(config, nonces)
+}
+
+// echo answers with the client name and the request body.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	client, _ := keyauth.FromContext(r.Context())
+	body, _ := io.ReadAll(r.Body)
+	_, _ = io.WriteString(w, client+" "+string(body))
+})
+
+func TestAuthenticator_APIKey(t *testing.T) {
+	h := newAuthenticator(nil).APIKey(echo)
+	serve := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("s3cr3t")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "reports ", w.Body.String())
+
+	for _, key := range []string{"", "k3", "k3yk3y", "billing"} {
+		w := serve(key)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, key)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestAuthenticator_HMAC(t *testing.T) {
+	authn := newAuthenticator(nil)
+	now := time.Now()
+	keyauth.SetNow(authn, func() time.Time { return now })
+	h := authn.HMAC(echo)
+
+	signed := func(client, key string, at time.Time) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/internal/invoices?month=2024-01", strings.NewReader(`{"total":10}`))
+		require.NoError(t, keyauth.Sign(r, client, []byte(key), at))
+		return r
+	}
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// the body of the signed request is kept for the handler
+	r := signed("billing", "k3y", now)
+	w := serve(r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `billing {"total":10}`, w.Body.String())
+
+	// a request is accepted once
+	replayed := r.Clone(context.Background())
+	replayed.Body = io.NopCloser(strings.NewReader(`{"total":10}`))
+	w = serve(replayed)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "request replayed")
+
+	// within the Tolerance either way
+	assert.Equal(t, http.StatusOK, serve(signed("billing", "k3y", now.Add(-4*time.Minute))).Code)
+	assert.Equal(t, http.StatusOK, serve(signed("billing", "k3y", now.Add(4*time.Minute))).Code)
+	w = serve(signed("billing", "k3y", now.Add(-6*time.Minute)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "signature expired")
+
+	for name, r := range map[string]*http.Request{
+		"unsigned":      httptest.NewRequest(http.MethodPost, "/internal/invoices", nil),
+		"unknown key":   signed("payroll", "k3y", now),
+		"another key":   signed("reports", "k3y", now),
+		"other method":  func() *http.Request { r := signed("billing", "k3y", now); r.Method = http.MethodPut; return r }(),
+		"other query":   func() *http.Request { r := signed("billing", "k3y", now); r.URL.RawQuery = ""; return r }(),
+		"other body":    func() *http.Request { r := signed("billing", "k3y", now); r.Body = http.NoBody; return r }(),
+		"other nonce":   func() *http.Request { r := signed("billing", "k3y", now); return renonce(r) }(),
+		"no signatures": func() *http.Request { r := signed("billing", "k3y", now); return strip(r) }(),
+	} {
+		w := serve(r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Contains(t, w.Body.String(), "invalid signature", name)
+	}
+}
+
+// endless is a request body that never ends, counting the bytes read of it.
+type endless struct{ read int64 }
+
+func (e *endless) Read(p []byte) (int, error) {
+	e.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestAuthenticator_HMAC_MaxBodySize(t *testing.T) {
+	config := keyauth.DefaultConfig()
+	config.Keys = map[string]string{"billing": "k3y"}
+	config.MaxBodySize = 1024
+	h := keyauth.New(config, nil).HMAC(echo)
+
+	// the body of an unsigned request is not read at all
+	body := &endless{}
+	r := httptest.NewRequest(http.MethodPost, "/internal/invoices", body)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Zero(t, body.read)
+
+	// and that of a signed one only up to the MaxBodySize
+	r = httptest.NewRequest(http.MethodPost, "/internal/invoices", nil)
+	require.NoError(t, keyauth.Sign(r, "billing", []byte("k3y"), time.Now()))
+	body = &endless{}
+	r.Body = io.NopCloser(body)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Less(t, body.read, int64(64*1024))
+}
+
+// renonce replaces the nonce of r.
+func renonce(r *http.Request) *http.Request {
+	h := r.Header.Get(keyauth.SignatureHeader)
+	i := strings.Index(h, "nonce=")
+	r.Header.Set(keyauth.SignatureHeader, h[:i+6]+"x"+h[i+7:])
+	return r
+}
+
+// strip removes the signature of r.
+func strip(r *http.Request) *http.Request {
+	h := r.Header.Get(keyauth.SignatureHeader)
+	r.Header.Set(keyauth.SignatureHeader, h[:strings.Index(h, ",v1=")])
+	return r
+}
+
+type failingStore struct{}
+
+func (failingStore) Use(context.Context, string, time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestAuthenticator_HMAC_StoreError(t *testing.T) {
+	h := newAuthenticator(failingStore{}).HMAC(echo)
+	r := httptest.NewRequest(http.MethodPost, "/internal/invoices", strings.NewReader("{}"))
+	require.NoError(t, keyauth.Sign(r, "billing", []byte("k3y"), time.Now()))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(newAuthenticator(nil).HMAC(echo))
+	defer server.Close()
+	client := &http.Client{Transport: keyauth.Transport(nil, "reports", []byte("s3cr3t"))}
+
+	for _, body := range []string{"", "first", "second"} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/internal/reports", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if body == "second" {
+			req.GetBody = nil // the body is read and replaced
+		}
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		b, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "reports "+body, string(b))
+		assert.Empty(t, req.Header.Get(keyauth.SignatureHeader))
+	}
+}
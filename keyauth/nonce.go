@@ -0,0 +1,60 @@
+package keyauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+)
+
+// NonceStore remembers the nonces of the signed requests, so that a captured request cannot be replayed while its
+// timestamp is within the Tolerance. Use must be atomic across the replicas sharing the NonceStore.
+type NonceStore interface {
+	// Use records nonce, kept for ttl, and reports whether it was not recorded already.
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// Cache is a NonceStore keeping the nonces in memory, for an app with a single replica. The cache must hold the
+// nonces of the requests of twice the Tolerance; those it evicts earlier can be replayed.
+type Cache struct {
+	mu    sync.Mutex
+	cache *cache.Cache[string, struct{}]
+}
+
+// NewCache returns a NonceStore keeping the nonces in c.
+func NewCache(c *cache.Cache[string, struct{}]) *Cache {
+	return &Cache{cache: c}
+}
+
+// Use adds nonce to the cache unless it has it.
+func (c *Cache) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.cache.Get(nonce); ok {
+		return false, nil
+	}
+	c.cache.SetTTL(nonce, struct{}{}, ttl)
+	return true, nil
+}
+
+// Redis is a NonceStore keeping the nonces as Redis keys expiring with them.
+type Redis struct {
+	client redis.UniversalClient
+
+	// Prefix is prepended to the nonces to form the Redis keys.
+	Prefix string
+}
+
+// NewRedis returns a NonceStore using client.
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client, Prefix: "keyauth:nonce:"}
+}
+
+// Use sets the Redis key of nonce if it does not exist.
+func (r *Redis) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, r.Prefix+nonce, 1, ttl).Result()
+}
@@ -0,0 +1,57 @@
+package keyauth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/keyauth"
+)
+
+func TestCache(t *testing.T) {
+	store := keyauth.NewCache(cache.New[string, struct{}](cache.DefaultConfig(), nil))
+	ctx := context.Background()
+
+	ok, err := store.Use(ctx, "billing:1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = store.Use(ctx, "billing:1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = store.Use(ctx, "reports:1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := keyauth.NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	ctx := context.Background()
+
+	ok, err := store.Use(ctx, "billing:1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, server.Exists("keyauth:nonce:billing:1"))
+
+	ok, err = store.Use(ctx, "billing:1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// the nonce is forgotten once it expires
+	server.FastForward(time.Minute)
+	ok, err = store.Use(ctx, "billing:1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	server.Close()
+	_, err = store.Use(ctx, "billing:2", time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/k8s"
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+// Backend holds elections.
+type Backend interface {
+	// Campaign runs for the lead of the election named name. When the replica becomes the leader, Campaign calls
+	// lead with a context canceled once it is no longer, and lead blocks until then. Campaign returns when the
+	// replica did not become the leader, when it stopped leading, or when ctx is done; the Elector campaigns again
+	// after its RetryPeriod.
+	Campaign(ctx context.Context, name string, lead func(ctx context.Context)) error
+}
+
+// Locker returns a Backend in which the leader is the replica holding the lock named after the election.
+func Locker(locker lock.Locker) Backend {
+	return lockBackend{locker: locker}
+}
+
+// Redis returns a Backend holding the elections as Redis locks, see lock.Redis.
+func Redis(client redis.UniversalClient, config *lock.Config) Backend {
+	r := lock.NewRedis(client, config)
+	r.Prefix = "leader:"
+	return Locker(r)
+}
+
+// Postgres returns a Backend holding the elections as PostgreSQL advisory locks, see lock.Postgres.
+func Postgres(db *sql.DB, config *lock.Config) Backend {
+	return Locker(lock.NewPostgres(db, config))
+}
+
+type lockBackend struct {
+	locker lock.Locker
+}
+
+func (b lockBackend) Campaign(ctx context.Context, name string, lead func(ctx context.Context)) error {
+	l, err := b.locker.TryLock(ctx, name)
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel()
+		case <-leadCtx.Done():
+		}
+	}()
+	lead(leadCtx)
+	cancel()
+
+	// release the lock even though ctx may be done, so that another replica leads right away
+	return l.Unlock(context.WithoutCancel(ctx))
+}
+
+// Kubernetes returns a Backend holding the elections as Leases in the namespace of client, see
+// k8s.Client.LeaderElection.
+func Kubernetes(a *app.App, client *k8s.Client) Backend {
+	return kubernetesBackend{a: a, client: client}
+}
+
+type kubernetesBackend struct {
+	a      *app.App
+	client *k8s.Client
+}
+
+func (b kubernetesBackend) Campaign(ctx context.Context, name string, lead func(ctx context.Context)) error {
+	// LeaderElection waits for lead to return before it campaigns again, and before it returns
+	return b.client.LeaderElection(ctx, b.a, &k8s.Election{Name: name, OnStartedLeading: lead})
+}
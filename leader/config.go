@@ -0,0 +1,73 @@
+package leader
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the election settings, as in APP_LEADER_NAME.
+	DefaultPrefix = "APP_LEADER_"
+
+	// DefaultName is the name of the election when the Config has none.
+	DefaultName = "leader"
+
+	// DefaultRetryPeriod is how long a replica that is not the leader waits before it campaigns again.
+	DefaultRetryPeriod = 2 * time.Second
+)
+
+// Config describes an election.
+type Config struct {
+	Name        string        // the name of the election, shared by the replicas: the lock or Lease name
+	RetryPeriod time.Duration // how long a replica that is not the leader waits before it campaigns again
+}
+
+// DefaultConfig returns a Config for the DefaultName election, campaigning again every DefaultRetryPeriod.
+func DefaultConfig() *Config {
+	return &Config{Name: DefaultName, RetryPeriod: DefaultRetryPeriod}
+}
+
+func init() {
+	configschema.Register("leader", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the leader election variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "NAME", Type: "string", Default: DefaultName,
+			Description: "The name of the election, shared by the replicas."},
+		{Name: prefix + "RETRY_PERIOD", Type: "duration", Default: DefaultRetryPeriod.String(),
+			Description: "How long a replica that is not the leader waits before it campaigns again."},
+	}
+}
+
+// FromEnv reads the NAME of the election and the RETRY_PERIOD of the replicas waiting for their turn, with the prefix
+// or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("NAME"); v != "" {
+		config.Name = v
+	}
+
+	if v := get("RETRY_PERIOD"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("leader: invalid %sRETRY_PERIOD %q", prefix, v)
+		}
+		config.RetryPeriod = d
+	}
+
+	return config, nil
+}
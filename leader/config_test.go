@@ -0,0 +1,35 @@
+package leader_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/leader"
+)
+
+func TestFromEnv_Name(t *testing.T) {
+	config, err := leader.FromEnv(apptest.Lookup(map[string]string{"JOBS_NAME": " scheduler "}), "JOBS_")
+	require.NoError(t, err)
+	assert.Equal(t, "scheduler", config.Name)
+
+	// a blank name is unset, so the replicas still share the default election
+	config, err = leader.FromEnv(apptest.Lookup(map[string]string{"APP_LEADER_NAME": "  "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, leader.DefaultConfig(), config)
+}
+
+func TestFromEnv_RetryPeriod(t *testing.T) {
+	config, err := leader.FromEnv(apptest.Lookup(map[string]string{"APP_LEADER_RETRY_PERIOD": "500ms"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, config.RetryPeriod)
+
+	// a zero period would campaign in a busy loop
+	for _, v := range []string{"0s", "-1s", "soon", "2"} {
+		_, err := leader.FromEnv(apptest.Lookup(map[string]string{"APP_LEADER_RETRY_PERIOD": v}), "")
+		assert.EqualError(t, err, `leader: invalid APP_LEADER_RETRY_PERIOD "`+v+`"`, v)
+	}
+}
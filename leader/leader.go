@@ -0,0 +1,198 @@
+// Package leader elects one of the replicas of an app as the leader, so that schedulers and singleton workers run on
+// that replica only. The elections are held in Redis, as PostgreSQL advisory locks, or as Kubernetes Leases:
+//
+//	config, err := leader.FromEnv(a.LookupEnv, "")
+//	elector := leader.New(leader.Redis(client, lock.DefaultConfig()), config)
+//	elector.OnStart(func(ctx context.Context) {
+//		scheduler.Run(ctx) // until ctx is canceled, when the replica stops leading
+//	})
+//	a.Register("leader", elector)
+//
+// The Elector is a Server: it campaigns while the app runs, and a leader resigns when the app shuts down, so that
+// another replica takes over at once. The changes of leadership are logged and sent to the subscribers of Changes.
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Change reports that the replica started or stopped leading.
+type Change struct {
+	Name    string // the name of the election
+	Leading bool   // whether the replica leads
+}
+
+// Elector takes part in an election for the replica it runs in.
+type Elector struct {
+	backend Backend
+	config  Config
+	leading atomic.Bool
+
+	mu      sync.Mutex
+	a       *app.App
+	onStart []func(ctx context.Context)
+	onStop  []func()
+	subs    map[chan Change]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns an Elector campaigning with backend in the election config describes, or the DefaultConfig if it is
+// nil.
+func New(backend Backend, config *Config) *Elector {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Elector{
+		backend: backend,
+		config:  *config,
+		subs:    make(map[chan Change]struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+}
+
+// IsLeader reports whether the replica leads.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// OnStart adds a function called, in its own goroutine, whenever the replica starts leading, with a context canceled
+// when it stops. The replica stops leading once every function returned, so they should return soon after the
+// context is canceled.
+func (e *Elector) OnStart(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStart = append(e.onStart, fn)
+}
+
+// OnStop adds a function called whenever the replica stops leading.
+func (e *Elector) OnStop(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStop = append(e.onStop, fn)
+}
+
+// Changes subscribes to the changes of leadership of the replica. Changes are never lost: when the subscriber falls
+// behind, the latest replaces the pending one. cancel ends the subscription and closes the channel.
+func (e *Elector) Changes() (<-chan Change, func()) {
+	ch := make(chan Change, 1)
+	e.mu.Lock()
+	e.subs[ch] = struct{}{}
+	e.mu.Unlock()
+
+	once := sync.Once{}
+	return ch, func() {
+		once.Do(func() {
+			e.mu.Lock()
+			delete(e.subs, ch)
+			e.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Bind uses the app for logging, and ends the campaign when the app Context is done.
+func (e *Elector) Bind(a *app.App) error {
+	e.mu.Lock()
+	e.a = a
+	e.mu.Unlock()
+
+	go func() {
+		select {
+		case <-a.Context.Done():
+			e.cancel()
+		case <-e.ctx.Done():
+		}
+	}()
+	return nil
+}
+
+// Serve campaigns until the Elector is shut down.
+func (e *Elector) Serve() error {
+	defer close(e.done)
+
+	for {
+		if err := e.backend.Campaign(e.ctx, e.config.Name, e.lead); err != nil && e.ctx.Err() == nil {
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"election": e.config.Name, "error": err.Error()})
+			_ = e.a.Logger().Warnm(attrs, "unable to campaign for the lead")
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return nil
+		case <-time.After(e.config.RetryPeriod):
+		}
+	}
+}
+
+// Shutdown ends the campaign, resigning if the replica leads, and waits for Serve to return until ctx is done.
+func (e *Elector) Shutdown(ctx context.Context) error {
+	e.cancel()
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lead runs the OnStart functions until ctx is canceled and they return.
+func (e *Elector) lead(ctx context.Context) {
+	e.mu.Lock()
+	onStart := append([]func(context.Context){}, e.onStart...)
+	onStop := append([]func(){}, e.onStop...)
+	e.mu.Unlock()
+
+	e.change(true)
+
+	var wg sync.WaitGroup
+	for _, fn := range onStart {
+		wg.Add(1)
+		go func(fn func(context.Context)) {
+			defer wg.Done()
+			fn(ctx)
+		}(fn)
+	}
+	<-ctx.Done()
+	wg.Wait()
+
+	e.change(false)
+	for _, fn := range onStop {
+		fn()
+	}
+}
+
+// change records, logs, and publishes a change of leadership.
+func (e *Elector) change(leading bool) {
+	e.leading.Store(leading)
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"election": e.config.Name})
+	if leading {
+		_ = e.a.Logger().Infom(attrs, "became the leader")
+	} else {
+		_ = e.a.Logger().Infom(attrs, "no longer the leader")
+	}
+
+	c := Change{Name: e.config.Name, Leading: leading}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c
+	}
+}
@@ -0,0 +1,126 @@
+package leader_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/k8s"
+	"github.com/demosdemon/golang-app-framework/leader"
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+// newApp returns an App for a test on the host hostname.
+func newApp(t *testing.T, hostname string) *app.App {
+	a := apptest.New(t, nil)
+	a.Identity = &app.Identity{Hostname: hostname}
+	return a
+}
+
+// start binds and serves the elector, returning a function shutting it down.
+func start(t *testing.T, a *app.App, e *leader.Elector) func() {
+	require.NoError(t, e.Bind(a))
+	done := make(chan error, 1)
+	go func() { done <- e.Serve() }()
+	return func() {
+		require.NoError(t, e.Shutdown(context.Background()))
+		require.NoError(t, <-done)
+	}
+}
+
+func receive(t *testing.T, changes <-chan leader.Change) leader.Change {
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change of leadership")
+		return leader.Change{}
+	}
+}
+
+func TestElector(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	config := &leader.Config{Name: "scheduler", RetryPeriod: 20 * time.Millisecond}
+
+	first := leader.New(leader.Redis(client, lock.DefaultConfig()), config)
+	leading := make(chan context.Context, 1)
+	first.OnStart(func(ctx context.Context) {
+		leading <- ctx
+		<-ctx.Done()
+	})
+	stopped := make(chan struct{})
+	first.OnStop(func() { close(stopped) })
+	firstChanges, cancel := first.Changes()
+	defer cancel()
+
+	a := newApp(t, "replica-1")
+	stopFirst := start(t, a, first)
+	assert.Equal(t, leader.Change{Name: "scheduler", Leading: true}, receive(t, firstChanges))
+	assert.True(t, first.IsLeader())
+	<-leading
+	assert.True(t, server.Exists("leader:scheduler"))
+
+	second := leader.New(leader.Redis(client, lock.DefaultConfig()), config)
+	secondChanges, cancel := second.Changes()
+	defer cancel()
+	stopSecond := start(t, newApp(t, "replica-2"), second)
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, second.IsLeader())
+
+	// the leader resigns when it shuts down, and the other replica takes over
+	stopFirst()
+	<-stopped
+	assert.False(t, first.IsLeader())
+	assert.Equal(t, leader.Change{Name: "scheduler", Leading: false}, receive(t, firstChanges))
+	assert.Equal(t, leader.Change{Name: "scheduler", Leading: true}, receive(t, secondChanges))
+	assert.True(t, second.IsLeader())
+	stopSecond()
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	logs := apptest.Stderr(t, a)
+	assert.Contains(t, logs, `became the leader {"election":"scheduler"`)
+	assert.Contains(t, logs, `no longer the leader {"election":"scheduler"`)
+}
+
+func TestElector_LostLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	e := leader.New(leader.Redis(client, &lock.Config{TTL: 300 * time.Millisecond}),
+		&leader.Config{Name: "worker", RetryPeriod: 20 * time.Millisecond})
+	changes, cancel := e.Changes()
+	defer cancel()
+	stop := start(t, newApp(t, "replica-1"), e)
+	defer stop()
+	assert.True(t, receive(t, changes).Leading)
+
+	// another holder takes the lock, so the renewal fails
+	server.Set("leader:worker", "someone else")
+	assert.False(t, receive(t, changes).Leading)
+	assert.False(t, e.IsLeader())
+}
+
+func TestElector_Kubernetes(t *testing.T) {
+	a := newApp(t, "pod-1")
+	client := &k8s.Client{Interface: fake.NewClientset(), Namespace: "apps"}
+	e := leader.New(leader.Kubernetes(a, client), &leader.Config{Name: "scheduler", RetryPeriod: time.Second})
+	changes, cancel := e.Changes()
+	defer cancel()
+
+	stop := start(t, a, e)
+	assert.Equal(t, leader.Change{Name: "scheduler", Leading: true}, receive(t, changes))
+	assert.True(t, e.IsLeader())
+	stop()
+	assert.False(t, e.IsLeader())
+}
@@ -0,0 +1,99 @@
+package limits
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the request limits, APP_LIMITS_TIMEOUT and APP_LIMITS_MAX_BODY_SIZE.
+	DefaultPrefix = "APP_LIMITS_"
+
+	// DefaultTimeout is how long a request may be served before it is canceled.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxBodySize is the largest request body accepted, in bytes.
+	DefaultMaxBodySize = 1 << 20
+)
+
+// Config describes the limits applied to each request. Zero values disable the corresponding limit.
+type Config struct {
+	Timeout     time.Duration // how long a request may be served before its context is canceled
+	MaxBodySize int64         // largest request body accepted, in bytes
+}
+
+func init() {
+	configschema.Register("limits", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the request limit variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a request may be served before its context is canceled."},
+		{Name: prefix + "MAX_BODY_SIZE", Type: "size", Default: "1MB",
+			Description: "The largest request body accepted; 0 disables the limit."},
+	}
+}
+
+// FromEnv reads the TIMEOUT of a request and its MAX_BODY_SIZE, with the prefix or DefaultPrefix. MAX_BODY_SIZE is a
+// number of bytes with an optional KB, MB, or GB suffix (powers of 1024); 0 disables the limit.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{
+		Timeout:     DefaultTimeout,
+		MaxBodySize: DefaultMaxBodySize,
+	}
+
+	if v := get("TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("limits: invalid %sTIMEOUT %q", prefix, v)
+		}
+		config.Timeout = d
+	}
+
+	if v := get("MAX_BODY_SIZE"); v != "" {
+		n, err := parseSize(v)
+		if err != nil {
+			return nil, fmt.Errorf("limits: invalid %sMAX_BODY_SIZE %q", prefix, v)
+		}
+		config.MaxBodySize = n
+	}
+
+	return &config, nil
+}
+
+func parseSize(v string) (int64, error) {
+	shift := uint(0)
+	upper := strings.ToUpper(v)
+	for i, suffix := range []string{"KB", "MB", "GB"} {
+		if strings.HasSuffix(upper, suffix) {
+			shift = 10 * uint(i+1)
+			v = strings.TrimSpace(v[:len(v)-len(suffix)])
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > (1<<63-1)>>shift {
+		return 0, strconv.ErrRange
+	}
+
+	return n << shift, nil
+}
@@ -0,0 +1,48 @@
+package limits_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/limits"
+)
+
+func TestFromEnv_MaxBodySize(t *testing.T) {
+	// the units are powers of 1024, in any case, with or without a space before them
+	tests := map[string]int64{"0": 0, "512": 512, "64KB": 64 << 10, "10 mb": 10 << 20, "2Gb": 2 << 30}
+	for v, expected := range tests {
+		config, err := limits.FromEnv(apptest.Lookup(map[string]string{"UPLOAD_MAX_BODY_SIZE": v}), "UPLOAD_")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.MaxBodySize, v)
+	}
+
+	// a size that overflows once scaled is refused rather than wrapped around
+	for _, v := range []string{"big", "1.5MB", "-1KB", "KB", "9999999999GB", "1TB"} {
+		_, err := limits.FromEnv(apptest.Lookup(map[string]string{"APP_LIMITS_MAX_BODY_SIZE": v}), "")
+		assert.EqualError(t, err, `limits: invalid APP_LIMITS_MAX_BODY_SIZE "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Timeout(t *testing.T) {
+	config, err := limits.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, &limits.Config{Timeout: limits.DefaultTimeout, MaxBodySize: limits.DefaultMaxBodySize}, config)
+
+	// zero lets a request run for as long as its client waits
+	config, err = limits.FromEnv(apptest.Lookup(map[string]string{"APP_LIMITS_TIMEOUT": "0s"}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Timeout)
+
+	config, err = limits.FromEnv(apptest.Lookup(map[string]string{"APP_LIMITS_TIMEOUT": "5m"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, config.Timeout)
+
+	for _, v := range []string{"-1s", "30"} {
+		_, err := limits.FromEnv(apptest.Lookup(map[string]string{"APP_LIMITS_TIMEOUT": v}), "")
+		assert.EqualError(t, err, `limits: invalid APP_LIMITS_TIMEOUT "`+v+`"`, v)
+	}
+}
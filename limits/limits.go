@@ -0,0 +1,163 @@
+// Package limits provides HTTP middleware bounding how long a request may take and how large its body may be.
+package limits
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits is HTTP middleware enforcing a Config.
+//
+// A request whose handler has not responded before the timeout is answered with 408 Request Timeout and its context
+// is canceled; anything the handler writes afterwards is discarded. If the handler has already started responding,
+// the context is canceled and the response is left to the handler.
+//
+// A request declaring a Content-Length over the maximum body size is answered with 413 Request Entity Too Large
+// without calling the handler. Other bodies are read through http.MaxBytesReader, so reads past the limit fail with
+// an *http.MaxBytesError the handler should answer with 413.
+type Limits struct {
+	config Config
+}
+
+// New returns Limits enforcing config.
+func New(config *Config) *Limits {
+	return &Limits{config: *config}
+}
+
+// With returns Limits for a single route: the non-zero fields of override replace those of l.
+func (l *Limits) With(override Config) *Limits {
+	config := l.config
+	if override.Timeout != 0 {
+		config.Timeout = override.Timeout
+	}
+	if override.MaxBodySize != 0 {
+		config.MaxBodySize = override.MaxBodySize
+	}
+	return &Limits{config: config}
+}
+
+// Wrap returns a handler enforcing the limits before calling next.
+func (l *Limits) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := l.config.MaxBodySize; n > 0 {
+			if r.ContentLength > n {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+		}
+
+		if l.config.Timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		serveWithTimeout(w, r, next, l.config.Timeout)
+	})
+}
+
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	tw := &timeoutWriter{w: w, header: make(http.Header)}
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+			close(done)
+		}()
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		tw.mu.Lock()
+		if !tw.wroteHeader {
+			tw.timedOut = true
+			http.Error(w, http.StatusText(http.StatusRequestTimeout), http.StatusRequestTimeout)
+		}
+		tw.mu.Unlock()
+
+		if tw.timedOut {
+			return
+		}
+
+		// the handler owns the response; wait for it to notice the cancellation
+		<-done
+	}
+
+	select {
+	case p := <-panicked:
+		panic(p)
+	default:
+	}
+}
+
+// timeoutWriter serializes the handler writes with the timeout response. The handler gets its own header map, copied
+// to the response when it writes the header, so the two never share it.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(status)
+}
+
+func (tw *timeoutWriter) writeHeader(status int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(b)
+}
+
+// Flush sends any buffered data to the client, unless the request has timed out.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return
+	}
+
+	tw.writeHeader(http.StatusOK)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
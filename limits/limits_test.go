@@ -0,0 +1,100 @@
+package limits_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/limits"
+)
+
+func TestLimits_Timeout(t *testing.T) {
+	canceled := make(chan error, 1)
+	proceed := make(chan struct{})
+	h := limits.New(&limits.Config{Timeout: 10 * time.Millisecond}).
+		Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			<-proceed
+			w.Header().Set("X-Late", "true")
+			_, err := w.Write([]byte("late"))
+			canceled <- err
+		}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	assert.Equal(t, "Request Timeout\n", w.Body.String())
+
+	close(proceed)
+	assert.Equal(t, http.ErrHandlerTimeout, <-canceled)
+	assert.Empty(t, w.Header().Get("X-Late"))
+}
+
+func TestLimits_TimeoutAfterResponse(t *testing.T) {
+	h := limits.New(&limits.Config{Timeout: 10 * time.Millisecond}).
+		Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			_, _ = w.Write([]byte("bye"))
+		}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "bye", w.Body.String())
+}
+
+func TestLimits_Panic(t *testing.T) {
+	h := limits.New(&limits.Config{Timeout: time.Second}).
+		Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+}
+
+func TestLimits_MaxBodySize(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		_, _ = w.Write(b)
+	})
+
+	l := limits.New(&limits.Config{MaxBodySize: 4})
+	h := l.Wrap(echo)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("body")))
+	assert.Equal(t, "body", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("bodies")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "Request Entity Too Large\n", w.Body.String())
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader("bodies"))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "too large\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	l.With(limits.Config{MaxBodySize: 1 << 10}).Wrap(echo).
+		ServeHTTP(w, httptest.NewRequest("POST", "/upload", strings.NewReader("bodies")))
+	assert.Equal(t, "bodies", w.Body.String())
+}
@@ -0,0 +1,60 @@
+package lock
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the lock settings, APP_LOCK_TTL.
+	DefaultPrefix = "APP_LOCK_"
+
+	// DefaultTTL is how long a lock outlives a replica that stopped renewing it.
+	DefaultTTL = 30 * time.Second
+)
+
+// Config describes how locks are held.
+type Config struct {
+	// TTL is the lease of Redis locks, renewed every third of the TTL, and the interval at which Postgres sessions
+	// holding a lock are checked.
+	TTL time.Duration
+}
+
+// DefaultConfig returns a Config whose locks outlive a replica that stopped renewing them by DefaultTTL.
+func DefaultConfig() *Config {
+	return &Config{TTL: DefaultTTL}
+}
+
+func init() {
+	configschema.Register("lock", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes TTL with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "TTL", Type: "duration", Default: DefaultTTL.String(),
+			Description: "The lease of Redis locks, and how often Postgres sessions holding a lock are checked."},
+	}
+}
+
+// FromEnv reads the TTL of the locks, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	config := DefaultConfig()
+
+	if v, _ := lookup(prefix + "TTL"); strings.TrimSpace(v) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("lock: invalid %sTTL %q", prefix, strings.TrimSpace(v))
+		}
+		config.TTL = d
+	}
+
+	return config, nil
+}
@@ -0,0 +1,29 @@
+package lock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+func TestFromEnv_TTL(t *testing.T) {
+	config, err := lock.FromEnv(apptest.Lookup(map[string]string{"JOBS_TTL": " 10s "}), "JOBS_")
+	require.NoError(t, err)
+	assert.Equal(t, &lock.Config{TTL: 10 * time.Second}, config)
+
+	config, err = lock.FromEnv(apptest.Lookup(map[string]string{"APP_LOCK_TTL": " "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, lock.DefaultConfig(), config)
+
+	// a zero lease would expire each lock the moment it is taken
+	for _, v := range []string{"0s", "-10s", "30"} {
+		config, err := lock.FromEnv(apptest.Lookup(map[string]string{"APP_LOCK_TTL": " " + v}), "")
+		assert.Nil(t, config)
+		assert.EqualError(t, err, `lock: invalid APP_LOCK_TTL "`+v+`"`, v)
+	}
+}
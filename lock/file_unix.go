@@ -0,0 +1,59 @@
+//go:build !windows
+// +build !windows
+
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// File holds locks with flock(2) on files in a directory, for replicas sharing a host. The lock is released by the
+// kernel if the process exits, so it is never lost while held.
+type File struct {
+	dir string
+}
+
+// NewFile returns a Locker keeping its lock files in dir, creating it if needed.
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{dir: dir}, nil
+}
+
+// TryLock takes an exclusive lock on the file named name.lock.
+func (f *File) TryLock(ctx context.Context, name string) (Lock, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("lock: invalid file lock name %q", name)
+	}
+
+	file, err := os.OpenFile(filepath.Join(f.dir, name+".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrNotAcquired
+		}
+		return nil, err
+	}
+
+	return &fileLock{held: held{lost: make(chan struct{})}, file: file}, nil
+}
+
+type fileLock struct {
+	held
+	file *os.File
+}
+
+func (l *fileLock) Unlock(ctx context.Context) error {
+	// closing the file releases the lock; the file is kept so that waiting lockers keep locking the same inode
+	return l.unlockOnce(l.file.Close)
+}
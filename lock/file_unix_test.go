@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package lock_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	locker, err := lock.NewFile(t.TempDir())
+	require.NoError(t, err)
+
+	l, err := locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+
+	_, err = locker.TryLock(ctx, "job")
+	assert.Equal(t, lock.ErrNotAcquired, err)
+
+	other, err := locker.TryLock(ctx, "other")
+	require.NoError(t, err)
+	require.NoError(t, other.Unlock(ctx))
+
+	require.NoError(t, l.Unlock(ctx))
+	require.NoError(t, l.Unlock(ctx))
+	<-l.Lost()
+
+	l, err = locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	require.NoError(t, l.Unlock(ctx))
+
+	_, err = locker.TryLock(ctx, "../job")
+	assert.EqualError(t, err, `lock: invalid file lock name "../job"`)
+}
@@ -0,0 +1,104 @@
+// Package lock provides named locks shared by the replicas of an app, so that scheduled jobs run on only one of
+// them. Locks are held in Redis, as Postgres advisory locks, or as files for replicas sharing a host:
+//
+//	locker := lock.NewRedis(client, config)
+//	ran, err := lock.Run(ctx, locker, "nightly-report", func(ctx context.Context) error {
+//		// ctx is canceled if the lock is lost
+//		return report(ctx)
+//	})
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNotAcquired is returned by TryLock when another holder has the lock.
+	ErrNotAcquired = errors.New("lock: held by another holder")
+
+	// ErrLost is returned by Run when the lock was lost while the function ran.
+	ErrLost = errors.New("lock: lost while held")
+)
+
+// Locker acquires named locks.
+type Locker interface {
+	// TryLock acquires the lock without waiting, returning ErrNotAcquired if it is held elsewhere.
+	TryLock(ctx context.Context, name string) (Lock, error)
+}
+
+// Lock is an acquired lock.
+type Lock interface {
+	// Lost is closed once the lock can no longer be guaranteed, for example because its lease could not be renewed,
+	// and when it is unlocked.
+	Lost() <-chan struct{}
+
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+}
+
+// Run calls fn while holding the named lock and reports whether it did. Run returns false without calling fn if the
+// lock is held elsewhere. The context passed to fn is canceled if the lock is lost, in which case Run returns ErrLost
+// unless fn returned an error.
+func Run(ctx context.Context, locker Locker, name string, fn func(ctx context.Context) error) (bool, error) {
+	l, err := locker.TryLock(ctx, name)
+	if err == ErrNotAcquired {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go func() {
+		select {
+		case <-l.Lost():
+			cancel(ErrLost)
+		case <-ctx.Done():
+		}
+	}()
+
+	err = fn(ctx)
+	lost := context.Cause(ctx) == ErrLost
+
+	// release the lock even though the job context may be done
+	uerr := l.Unlock(context.WithoutCancel(ctx))
+
+	switch {
+	case err != nil:
+		return true, err
+	case lost:
+		return true, ErrLost
+	default:
+		return true, uerr
+	}
+}
+
+// held implements Lost for the Lock implementations, and unlocks them once.
+type held struct {
+	lost     chan struct{}
+	once     sync.Once
+	unlocked sync.Once
+}
+
+func (h *held) Lost() <-chan struct{} {
+	return h.lost
+}
+
+func (h *held) release() {
+	h.once.Do(func() { close(h.lost) })
+}
+
+// unlockOnce calls unlock, and then release, the first time it is called. The calls made while unlock runs wait for
+// it to return; those calls, and the later ones, return nil.
+func (h *held) unlockOnce(unlock func() error) error {
+	var err error
+	h.unlocked.Do(func() {
+		defer h.release()
+		err = unlock()
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+// fakeLock is a Lock whose loss is triggered by the test.
+type fakeLock struct {
+	lost     chan struct{}
+	unlocked bool
+}
+
+func (l *fakeLock) Lost() <-chan struct{} { return l.lost }
+
+func (l *fakeLock) Unlock(ctx context.Context) error {
+	l.unlocked = true
+	return ctx.Err()
+}
+
+type fakeLocker struct {
+	lock *fakeLock
+	err  error
+}
+
+func (f *fakeLocker) TryLock(ctx context.Context, name string) (lock.Lock, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.lock, nil
+}
+
+func TestRun(t *testing.T) {
+	locker := &fakeLocker{lock: &fakeLock{lost: make(chan struct{})}}
+
+	ran, err := lock.Run(context.Background(), locker, "job", func(ctx context.Context) error { return nil })
+	assert.True(t, ran)
+	assert.NoError(t, err)
+	assert.True(t, locker.lock.unlocked)
+
+	boom := errors.New("boom")
+	ran, err = lock.Run(context.Background(), locker, "job", func(ctx context.Context) error { return boom })
+	assert.True(t, ran)
+	assert.Equal(t, boom, err)
+
+	locker.err = lock.ErrNotAcquired
+	ran, err = lock.Run(context.Background(), locker, "job", func(ctx context.Context) error {
+		panic("not called")
+	})
+	assert.False(t, ran)
+	assert.NoError(t, err)
+
+	locker.err = boom
+	ran, err = lock.Run(context.Background(), locker, "job", nil)
+	assert.False(t, ran)
+	assert.Equal(t, boom, err)
+}
+
+func TestRun_Lost(t *testing.T) {
+	locker := &fakeLocker{lock: &fakeLock{lost: make(chan struct{})}}
+
+	ran, err := lock.Run(context.Background(), locker, "job", func(ctx context.Context) error {
+		close(locker.lock.lost)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Second):
+			return errors.New("context not canceled")
+		}
+	})
+	assert.True(t, ran)
+	assert.Equal(t, lock.ErrLost, err)
+	require.True(t, locker.lock.unlocked, "the lock is released with a live context")
+}
@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"time"
+)
+
+// Postgres holds locks as session level advisory locks. Each held lock pins a connection of the pool; the lock is
+// lost if that connection breaks, since the server then releases it.
+type Postgres struct {
+	db     *sql.DB
+	config Config
+}
+
+// NewPostgres returns a Locker using db, which must be a PostgreSQL database.
+func NewPostgres(db *sql.DB, config *Config) *Postgres {
+	return &Postgres{db: db, config: *config}
+}
+
+// TryLock calls pg_try_advisory_lock with a 64-bit hash of the name.
+func (p *Postgres) TryLock(ctx context.Context, name string) (Lock, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := advisoryKey(name)
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	l := &postgresLock{
+		held:     held{lost: make(chan struct{})},
+		conn:     conn,
+		key:      key,
+		interval: p.config.TTL / 3,
+		done:     make(chan struct{}),
+		checked:  make(chan struct{}),
+	}
+	go l.check()
+	return l, nil
+}
+
+type postgresLock struct {
+	held
+	conn     *sql.Conn
+	key      int64
+	interval time.Duration
+	done     chan struct{}
+	checked  chan struct{}
+}
+
+// check pings the session holding the lock until it is unlocked.
+func (l *postgresLock) check() {
+	defer close(l.checked)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.interval)
+		err := l.conn.PingContext(ctx)
+		cancel()
+
+		if err != nil {
+			l.release()
+			_ = l.conn.Close()
+			return
+		}
+	}
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	return l.unlockOnce(func() error {
+		close(l.done)
+		<-l.checked
+
+		select {
+		case <-l.Lost():
+			// the session, and with it the lock, is already gone
+			return nil
+		default:
+		}
+
+		_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+		if cerr := l.conn.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
+
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,62 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+func TestPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	locker := lock.NewPostgres(db, lock.DefaultConfig())
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(
+		sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	l, err := locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	require.NoError(t, l.Unlock(ctx))
+	<-l.Lost()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(
+		sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+	_, err = locker.TryLock(ctx, "job")
+	assert.Equal(t, lock.ErrNotAcquired, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_Lost(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WillReturnRows(
+		sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(errors.New("connection reset"))
+
+	l, err := lock.NewPostgres(db, &lock.Config{TTL: 30 * time.Millisecond}).TryLock(ctx, "job")
+	require.NoError(t, err)
+
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock not lost")
+	}
+	require.NoError(t, l.Unlock(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,113 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// release and renew only act on the lock if it still holds our token, so a lock that expired and was acquired by
+// another holder is left alone.
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Redis holds locks as Redis keys with a lease that is renewed while the lock is held.
+type Redis struct {
+	client redis.UniversalClient
+	config Config
+
+	// Prefix is prepended to the lock names to form the keys.
+	Prefix string
+}
+
+// NewRedis returns a Locker using client.
+func NewRedis(client redis.UniversalClient, config *Config) *Redis {
+	return &Redis{client: client, config: *config, Prefix: "lock:"}
+}
+
+// TryLock sets the key if it does not exist.
+func (r *Redis) TryLock(ctx context.Context, name string) (Lock, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	l := &redisLock{
+		held:   held{lost: make(chan struct{})},
+		client: r.client,
+		key:    r.Prefix + name,
+		token:  hex.EncodeToString(b),
+		ttl:    r.config.TTL,
+		done:   make(chan struct{}),
+	}
+
+	ok, err := r.client.SetNX(ctx, l.key, l.token, l.ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	go l.renew()
+	return l, nil
+}
+
+type redisLock struct {
+	held
+	client redis.UniversalClient
+	key    string
+	token  string
+	ttl    time.Duration
+	done   chan struct{}
+}
+
+// renew extends the lease every third of the TTL. The lock is lost once the lease has been found missing or could not
+// be renewed before it expired.
+func (l *redisLock) renew() {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	expires := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), expires)
+		start := time.Now()
+		n, err := renewScript.Run(ctx, l.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+		cancel()
+
+		switch {
+		case err == nil && n == 1:
+			expires = start.Add(l.ttl)
+		case err == nil, time.Now().After(expires):
+			l.release()
+			return
+		}
+	}
+}
+
+func (l *redisLock) Unlock(ctx context.Context) error {
+	return l.unlockOnce(func() error {
+		close(l.done)
+		return releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Err()
+	})
+}
@@ -0,0 +1,85 @@
+package lock_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/lock"
+)
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	locker := lock.NewRedis(client, &lock.Config{TTL: time.Minute})
+
+	l, err := locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	assert.True(t, server.Exists("lock:job"))
+	assert.Equal(t, time.Minute, server.TTL("lock:job"))
+
+	_, err = locker.TryLock(ctx, "job")
+	assert.Equal(t, lock.ErrNotAcquired, err)
+
+	// Unlock may be called concurrently, such as by a deferred call racing a shutdown hook
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			assert.NoError(t, l.Unlock(ctx))
+		}()
+	}
+	close(start)
+	wg.Wait()
+	require.NoError(t, l.Unlock(ctx))
+	assert.False(t, server.Exists("lock:job"))
+	<-l.Lost()
+
+	// an expired lock taken by another holder is not released
+	l, err = locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	server.FastForward(time.Minute)
+	other, err := locker.TryLock(ctx, "job")
+	require.NoError(t, err)
+	require.NoError(t, l.Unlock(ctx))
+	assert.True(t, server.Exists("lock:job"))
+	require.NoError(t, other.Unlock(ctx))
+}
+
+func TestRedis_Lost(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	l, err := lock.NewRedis(client, &lock.Config{TTL: 30 * time.Millisecond}).TryLock(ctx, "job")
+	require.NoError(t, err)
+
+	// the lease is renewed
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-l.Lost():
+		t.Fatal("lock lost while renewed")
+	default:
+	}
+
+	server.Del("lock:job")
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lock not lost")
+	}
+	require.NoError(t, l.Unlock(ctx))
+}
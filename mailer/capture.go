@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Capture is a Driver that records messages instead of delivering them, for tests and local development.
+type Capture struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Send records a copy of the message.
+func (c *Capture) Send(ctx context.Context, msg *Message) error {
+	if _, err := msg.Recipients(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, *msg)
+	return nil
+}
+
+// Messages returns the messages recorded so far.
+func (c *Capture) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Reset discards the recorded messages.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
@@ -0,0 +1,162 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the server settings, SMTP_HOST and so on, as mail tools commonly name them.
+	DefaultPrefix = "SMTP_"
+
+	// DefaultPort is the SMTP submission port.
+	DefaultPort = 587
+
+	// DefaultTimeout bounds each attempt to deliver a message.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultQueueSize is the number of messages Enqueue buffers before refusing more.
+	DefaultQueueSize = 100
+
+	// DefaultRetries is the number of times a failed delivery is retried.
+	DefaultRetries = 3
+
+	// DefaultRetryBackoff is the delay before the first retry; it doubles with every attempt.
+	DefaultRetryBackoff = time.Second
+)
+
+// TLS modes of the SMTP connection.
+const (
+	TLSStartTLS = "starttls" // upgrade a plain connection with STARTTLS, failing if the server does not offer it
+	TLSImplicit = "tls"      // connect with TLS, typically on port 465
+	TLSNone     = "none"     // never encrypt; only suitable for local relays
+)
+
+// Config describes the SMTP server and delivery behavior of a Mailer.
+type Config struct {
+	Host         string
+	Port         int
+	Username     string // authenticates with PLAIN when set
+	Password     string
+	From         string // sender of messages without a From address
+	TLS          string // one of TLSStartTLS, TLSImplicit, or TLSNone
+	Timeout      time.Duration
+	QueueSize    int
+	Retries      int
+	RetryBackoff time.Duration
+}
+
+// DefaultConfig returns a Config using STARTTLS on DefaultPort and retrying DefaultRetries times; it names no server.
+func DefaultConfig() *Config {
+	return &Config{
+		Port:         DefaultPort,
+		TLS:          TLSStartTLS,
+		Timeout:      DefaultTimeout,
+		QueueSize:    DefaultQueueSize,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+	}
+}
+
+func init() {
+	configschema.Register("mailer", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the SMTP variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "HOST", Type: "string", Description: "The SMTP server; required."},
+		{Name: prefix + "PORT", Type: "int", Default: strconv.Itoa(DefaultPort),
+			Description: "The port of the server."},
+		{Name: prefix + "USERNAME", Type: "string", Description: "The user authenticated with PLAIN, if set."},
+		{Name: prefix + "PASSWORD", Type: "string", Description: "The password of the user.", Secret: true},
+		{Name: prefix + "FROM", Type: "string", Description: "The sender of messages without a From address."},
+		{Name: prefix + "TLS", Type: "string", Default: TLSStartTLS,
+			Description: "How the connection is encrypted: starttls, tls, or none."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long talking to the server may take."},
+		{Name: prefix + "QUEUE_SIZE", Type: "int", Default: strconv.Itoa(DefaultQueueSize),
+			Description: "The messages queued by Enqueue before it fails."},
+		{Name: prefix + "RETRIES", Type: "int", Default: strconv.Itoa(DefaultRetries),
+			Description: "How often a message that failed to send is retried."},
+		{Name: prefix + "RETRY_BACKOFF", Type: "duration", Default: DefaultRetryBackoff.String(),
+			Description: "The wait before the first retry."},
+	}
+}
+
+// FromEnv reads the SMTP server, HOST and PORT, the USERNAME and PASSWORD it is signed in with, how the connection is
+// secured, TLS, the default FROM address, and how sending is retried and queued, with the prefix or DefaultPrefix. HOST
+// is required.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	config.Host = get("HOST")
+	if config.Host == "" {
+		return nil, fmt.Errorf("mailer: %sHOST is required", prefix)
+	}
+
+	config.Username = get("USERNAME")
+	// passwords may legitimately begin or end with spaces
+	config.Password, _ = lookup(prefix + "PASSWORD")
+
+	if v := get("FROM"); v != "" {
+		if _, err := mail.ParseAddress(v); err != nil {
+			return nil, fmt.Errorf("mailer: invalid %sFROM %q", prefix, v)
+		}
+		config.From = v
+	}
+
+	if v := strings.ToLower(get("TLS")); v != "" {
+		switch v {
+		case TLSStartTLS, TLSImplicit, TLSNone:
+			config.TLS = v
+		default:
+			return nil, fmt.Errorf("mailer: invalid %sTLS %q", prefix, v)
+		}
+	}
+
+	// implicit TLS is conventionally offered on port 465
+	if config.TLS == TLSImplicit {
+		config.Port = 465
+	}
+
+	for key, dst := range map[string]*int{
+		"PORT":       &config.Port,
+		"QUEUE_SIZE": &config.QueueSize,
+		"RETRIES":    &config.Retries,
+	} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || (n == 0 && key != "RETRIES") || (key == "PORT" && n > 65535) {
+				return nil, fmt.Errorf("mailer: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	for key, dst := range map[string]*time.Duration{"TIMEOUT": &config.Timeout, "RETRY_BACKOFF": &config.RetryBackoff} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("mailer: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	return config, nil
+}
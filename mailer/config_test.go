@@ -0,0 +1,84 @@
+package mailer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/mailer"
+)
+
+func TestFromEnv_Server(t *testing.T) {
+	_, err := mailer.FromEnv(apptest.Lookup(map[string]string{"MAIL_HOST": " "}), "MAIL_")
+	assert.EqualError(t, err, "mailer: MAIL_HOST is required")
+
+	// the password is kept as is, since it may begin or end with spaces
+	config, err := mailer.FromEnv(apptest.Lookup(map[string]string{
+		"MAIL_HOST":     " smtp.example.com ",
+		"MAIL_USERNAME": " app ",
+		"MAIL_PASSWORD": " s3cr3t ",
+	}), "MAIL_")
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com", config.Host)
+	assert.Equal(t, "app", config.Username)
+	assert.Equal(t, " s3cr3t ", config.Password)
+}
+
+func TestFromEnv_TLS(t *testing.T) {
+	// implicit TLS moves to its conventional port unless PORT says otherwise
+	config, err := mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_TLS": "TLS"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, mailer.TLSImplicit, config.TLS)
+	assert.Equal(t, 465, config.Port)
+
+	config, err = mailer.FromEnv(apptest.Lookup(map[string]string{
+		"SMTP_HOST": "localhost",
+		"SMTP_TLS":  "tls",
+		"SMTP_PORT": "2465",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 2465, config.Port)
+
+	config, err = mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_TLS": "none"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, mailer.DefaultPort, config.Port)
+
+	_, err = mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_TLS": "SSL"}), "")
+	assert.EqualError(t, err, `mailer: invalid SMTP_TLS "ssl"`)
+}
+
+func TestFromEnv_From(t *testing.T) {
+	config, err := mailer.FromEnv(apptest.Lookup(map[string]string{
+		"SMTP_HOST": "localhost",
+		"SMTP_FROM": `"App" <noreply@example.com>`,
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, `"App" <noreply@example.com>`, config.From)
+
+	for _, v := range []string{"nobody", "App <noreply>", "noreply@example.com, ops@example.com"} {
+		_, err := mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_FROM": v}), "")
+		assert.EqualError(t, err, `mailer: invalid SMTP_FROM "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Delivery(t *testing.T) {
+	// zero retries sends each message once, but an empty queue or a zero backoff is refused
+	config, err := mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_RETRIES": "0"}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Retries)
+
+	for key, values := range map[string][]string{
+		"PORT":          {"0", "65536"},
+		"QUEUE_SIZE":    {"0", "-1"},
+		"RETRIES":       {"-1", "three"},
+		"TIMEOUT":       {"0s", "30"},
+		"RETRY_BACKOFF": {"0s", "now"},
+	} {
+		for _, v := range values {
+			_, err := mailer.FromEnv(apptest.Lookup(map[string]string{"SMTP_HOST": "localhost", "SMTP_" + key: v}), "")
+			assert.EqualError(t, err, "mailer: invalid SMTP_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package mailer sends email through SMTP or any other Driver, such as an HTTP API provider. Messages can be rendered
+// from templates embedded in the binary and delivered in the background with retries:
+//
+//	//go:embed templates
+//	var templateFS embed.FS
+//
+//	config, err := mailer.FromEnv(a.LookupEnv, "")
+//	templates, err := mailer.ParseFS(templateFS)
+//	m := mailer.New(config, nil, nil)
+//	a.Register("mailer", m)
+//
+//	msg, err := templates.Render("templates/welcome", user)
+//	msg.To = []string{user.Email}
+//	err = m.Enqueue(msg)
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue when the queue cannot accept another message.
+	ErrQueueFull = errors.New("mailer: queue full")
+
+	// ErrClosed is returned by Enqueue once the Mailer is shutting down.
+	ErrClosed = errors.New("mailer: closed")
+)
+
+// Driver delivers a message. Implementations must be safe for concurrent use.
+type Driver interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mailer sends messages with a Driver. It is also an app.Server that delivers queued messages in the background and
+// drains the queue when the App shuts down.
+type Mailer struct {
+	config Config
+	driver Driver
+	logger gomol.WrappableLogger
+
+	mu       sync.Mutex
+	queue    chan *Message
+	closed   bool
+	stopping chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// New returns a Mailer delivering with driver, or with the SMTP server described by config if driver is nil. The
+// logger may be nil.
+func New(config *Config, driver Driver, logger gomol.WrappableLogger) *Mailer {
+	if driver == nil {
+		driver = NewSMTP(config)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Mailer{
+		config:   *config,
+		driver:   driver,
+		logger:   logger,
+		queue:    make(chan *Message, config.QueueSize),
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Send delivers the message, retrying temporary failures, and returns the last error if every attempt failed.
+func (m *Mailer) Send(ctx context.Context, msg *Message) error {
+	msg = m.prepare(msg)
+
+	backoff := m.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := m.driver.Send(ctx, msg)
+		if err == nil || attempt == m.config.Retries || permanent(err) {
+			return err
+		}
+
+		m.log(gomol.LevelWarning, msg, "delivery failed, retrying in %s: %v", backoff, err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// Enqueue queues the message to be delivered in the background once the Mailer is bound. Failed deliveries are
+// logged.
+func (m *Mailer) Enqueue(msg *Message) error {
+	if _, err := msg.Recipients(); err != nil {
+		return err
+	}
+	msg = m.prepare(msg)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Bind uses the App logger if the Mailer has none and starts delivering queued messages.
+func (m *Mailer) Bind(a *app.App) error {
+	if m.logger == nil {
+		m.logger = a.Logger()
+	}
+
+	go m.deliver()
+	return nil
+}
+
+// Serve waits until the Mailer is shut down.
+func (m *Mailer) Serve() error {
+	<-m.stopping
+	return nil
+}
+
+// Shutdown refuses new messages and delivers the queued ones until the context is done, abandoning any left.
+func (m *Mailer) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.stopping)
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-m.done
+		return ctx.Err()
+	}
+}
+
+func (m *Mailer) deliver() {
+	defer close(m.done)
+
+	for msg := range m.queue {
+		if m.ctx.Err() != nil {
+			m.log(gomol.LevelError, msg, "message abandoned at shutdown")
+			continue
+		}
+
+		if err := m.Send(m.ctx, msg); err != nil {
+			m.log(gomol.LevelError, msg, "delivery failed: %v", err)
+		} else {
+			m.log(gomol.LevelDebug, msg, "message delivered")
+		}
+	}
+}
+
+// prepare returns a copy of the message sent from the configured address if it has none.
+func (m *Mailer) prepare(msg *Message) *Message {
+	c := *msg
+	if c.From == "" {
+		c.From = m.config.From
+	}
+	return &c
+}
+
+func (m *Mailer) log(level gomol.LogLevel, msg *Message, format string, args ...interface{}) {
+	if m.logger != nil {
+		_ = m.logger.Log(level, gomol.NewAttrsFromMap(map[string]interface{}{
+			"to":      msg.To,
+			"subject": msg.Subject,
+		}), format, args...)
+	}
+}
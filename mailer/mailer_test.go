@@ -0,0 +1,120 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/mailer"
+)
+
+// flaky fails the first failures sends with err before delivering to the embedded Capture.
+type flaky struct {
+	mailer.Capture
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flaky) Send(ctx context.Context, msg *mailer.Message) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		return f.err
+	}
+	return f.Capture.Send(ctx, msg)
+}
+
+func testConfig() *mailer.Config {
+	config := mailer.DefaultConfig()
+	config.From = "noreply@example.com"
+	config.QueueSize = 2
+	config.Retries = 2
+	config.RetryBackoff = time.Millisecond
+	return config
+}
+
+func TestMailer_Send(t *testing.T) {
+	driver := &flaky{failures: 2, err: errors.New("connection refused")}
+	m := mailer.New(testConfig(), driver, nil)
+
+	msg := &mailer.Message{To: []string{"a@example.com"}, Text: "hi"}
+	require.NoError(t, m.Send(context.Background(), msg))
+	assert.Equal(t, 3, driver.calls)
+	assert.Empty(t, msg.From, "the caller's message is not modified")
+	require.Len(t, driver.Messages(), 1)
+	assert.Equal(t, "noreply@example.com", driver.Messages()[0].From)
+
+	// retries are exhausted
+	driver = &flaky{failures: 3, err: errors.New("connection refused")}
+	assert.EqualError(t, mailer.New(testConfig(), driver, nil).Send(context.Background(), msg), "connection refused")
+	assert.Equal(t, 3, driver.calls)
+
+	// permanent rejections are not retried
+	driver = &flaky{failures: 3, err: &textproto.Error{Code: 550, Msg: "no such user"}}
+	err := mailer.New(testConfig(), driver, nil).Send(context.Background(), msg)
+	assert.Equal(t, driver.err, err)
+	assert.Equal(t, 1, driver.calls)
+}
+
+func TestMailer_Enqueue(t *testing.T) {
+	driver := new(mailer.Capture)
+	m := mailer.New(testConfig(), driver, nil)
+
+	assert.EqualError(t, m.Enqueue(&mailer.Message{}), "mailer: message has no recipients")
+
+	// messages are held until the Mailer is bound
+	require.NoError(t, m.Enqueue(&mailer.Message{To: []string{"a@example.com"}, Text: "1"}))
+	require.NoError(t, m.Enqueue(&mailer.Message{To: []string{"b@example.com"}, Text: "2"}))
+	assert.Equal(t, mailer.ErrQueueFull, m.Enqueue(&mailer.Message{To: []string{"c@example.com"}, Text: "3"}))
+
+	require.NoError(t, m.Bind(&app.App{Stderr: io.Discard}))
+	served := make(chan error, 1)
+	go func() { served <- m.Serve() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, m.Shutdown(ctx))
+	require.NoError(t, <-served)
+
+	messages := driver.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "1", messages[0].Text)
+	assert.Equal(t, "2", messages[1].Text)
+
+	assert.Equal(t, mailer.ErrClosed, m.Enqueue(&mailer.Message{To: []string{"a@example.com"}, Text: "late"}))
+
+	driver.Reset()
+	assert.Empty(t, driver.Messages())
+}
+
+// blocking never delivers a message until its context is done.
+type blocking struct{}
+
+func (blocking) Send(ctx context.Context, msg *mailer.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestMailer_Shutdown_Timeout(t *testing.T) {
+	m := mailer.New(testConfig(), blocking{}, nil)
+	require.NoError(t, m.Enqueue(&mailer.Message{To: []string{"a@example.com"}, Text: "1"}))
+	require.NoError(t, m.Enqueue(&mailer.Message{To: []string{"b@example.com"}, Text: "2"}))
+	require.NoError(t, m.Bind(&app.App{Stderr: io.Discard}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, m.Shutdown(ctx))
+}
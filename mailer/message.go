@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is an email with a plain text body, an HTML body, or both.
+type Message struct {
+	From    string // the Mailer From address when empty
+	To      []string
+	Cc      []string
+	Bcc     []string // recipients omitted from the headers
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // additional headers, e.g. List-Unsubscribe
+}
+
+// Recipients returns the addresses of every recipient, including Bcc.
+func (m *Message) Recipients() ([]string, error) {
+	var addrs []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, v := range list {
+			addr, err := mail.ParseAddress(v)
+			if err != nil {
+				return nil, fmt.Errorf("mailer: invalid recipient %q", v)
+			}
+			addrs = append(addrs, addr.Address)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, errors.New("mailer: message has no recipients")
+	}
+	return addrs, nil
+}
+
+// Bytes encodes the message in the Internet Message Format with MIME bodies.
+func (m *Message) Bytes() ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: invalid sender %q", m.From)
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, errors.New("mailer: message has no body")
+	}
+
+	buf := new(bytes.Buffer)
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(value) + "\r\n")
+	}
+
+	header("From", from.String())
+	if len(m.To) > 0 {
+		header("To", strings.Join(m.To, ", "))
+	}
+	if len(m.Cc) > 0 {
+		header("Cc", strings.Join(m.Cc, ", "))
+	}
+	if m.ReplyTo != "" {
+		header("Reply-To", m.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(m.Subject), " ")))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(k), m.Headers[k])
+	}
+
+	if m.Text == "" || m.HTML == "" {
+		contentType, body := "text/plain", m.Text
+		if m.HTML != "" {
+			contentType, body = "text/html", m.HTML
+		}
+		header("Content-Type", contentType+"; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	// clients display the last part they support, so the HTML part comes last
+	for _, part := range []struct{ contentType, body string }{{"text/plain", m.Text}, {"text/html", m.HTML}} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, body); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if idx := strings.LastIndexByte(from, '@'); idx >= 0 {
+		domain = from[idx+1:]
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
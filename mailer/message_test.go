@@ -0,0 +1,86 @@
+package mailer_test
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/mailer"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := &mailer.Message{
+		From:    "App <noreply@example.com>",
+		To:      []string{"a@example.com", "B <b@example.com>"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Héllo\r\nBcc: injected@example.com",
+		Text:    "plain text",
+		HTML:    "<p>html</p>",
+		Headers: map[string]string{"list-unsubscribe": "<mailto:unsubscribe@example.com>"},
+	}
+
+	data, err := msg.Bytes()
+	require.NoError(t, err)
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, `"App" <noreply@example.com>`, m.Header.Get("From"))
+	assert.Equal(t, "a@example.com, B <b@example.com>", m.Header.Get("To"))
+	assert.Empty(t, m.Header.Get("Bcc"))
+	assert.Equal(t, "<mailto:unsubscribe@example.com>", m.Header.Get("List-Unsubscribe"))
+	assert.Regexp(t, `^<[0-9a-f]{32}@example\.com>$`, m.Header.Get("Message-ID"))
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Héllo Bcc: injected@example.com", subject)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for _, expected := range []string{"plain text", "<p>html</p>"} {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	}
+
+	addrs, err := msg.Recipients()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "audit@example.com"}, addrs)
+}
+
+func TestMessage_Bytes_SinglePart(t *testing.T) {
+	data, err := (&mailer.Message{From: "noreply@example.com", HTML: "<p>only html</p>"}).Bytes()
+	require.NoError(t, err)
+
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", m.Header.Get("Content-Type"))
+	body, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "<p>only html</p>", string(body))
+}
+
+func TestMessage_Invalid(t *testing.T) {
+	_, err := (&mailer.Message{From: "nobody", Text: "x"}).Bytes()
+	assert.EqualError(t, err, `mailer: invalid sender "nobody"`)
+
+	_, err = (&mailer.Message{From: "noreply@example.com"}).Bytes()
+	assert.EqualError(t, err, "mailer: message has no body")
+
+	_, err = (&mailer.Message{}).Recipients()
+	assert.EqualError(t, err, "mailer: message has no recipients")
+
+	_, err = (&mailer.Message{To: []string{"bogus"}}).Recipients()
+	assert.EqualError(t, err, `mailer: invalid recipient "bogus"`)
+}
@@ -0,0 +1,145 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTP delivers messages to an SMTP server, opening a connection for every message.
+type SMTP struct {
+	config Config
+
+	// TLSConfig is used for STARTTLS and implicit TLS connections. The server name defaults to the configured host.
+	TLSConfig *tls.Config
+}
+
+// NewSMTP returns a driver delivering to the server described by config.
+func NewSMTP(config *Config) *SMTP {
+	return &SMTP{config: *config}
+}
+
+// Send delivers the message, failing if the context is done or the configured timeout elapses first.
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid sender %q", msg.From)
+	}
+	recipients, err := msg.Recipients()
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	if s.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// the smtp package has no context support, so the deadline covers the whole conversation
+	_ = conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if s.config.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("mailer: %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(s.tlsConfig()); err != nil {
+			return err
+		}
+	}
+
+	if s.config.Username != "" {
+		if err := c.Auth(plainAuth{s.config.Username, s.config.Password}); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (s *SMTP) tlsConfig() *tls.Config {
+	config := &tls.Config{}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = s.config.Host
+	}
+	return config
+}
+
+// plainAuth implements the PLAIN mechanism. Unlike smtp.PlainAuth it trusts the configured TLS mode, so TLSNone
+// relays on private networks may require authentication.
+type plainAuth struct {
+	username, password string
+}
+
+func (a plainAuth) Start(*smtp.ServerInfo) (string, []byte, error) {
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+func (a plainAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, fmt.Errorf("mailer: unexpected server challenge %q", fromServer)
+	}
+	return nil, nil
+}
+
+// permanent reports whether the error is an SMTP 5xx reply that will not succeed if retried.
+func permanent(err error) bool {
+	tpErr, ok := err.(*textproto.Error)
+	return ok && tpErr.Code >= 500
+}
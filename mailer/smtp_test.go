@@ -0,0 +1,136 @@
+package mailer_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/mailer"
+)
+
+type envelope struct {
+	auth string
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP accepts sessions on a local port, rejecting recipients at reject.example.com, and reports every message.
+func fakeSMTP(t *testing.T) (*mailer.Config, <-chan envelope) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	messages := make(chan envelope, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go session(textproto.NewConn(conn), messages)
+		}
+	}()
+
+	config := mailer.DefaultConfig()
+	config.Host = "127.0.0.1"
+	config.Port = l.Addr().(*net.TCPAddr).Port
+	config.TLS = mailer.TLSNone
+	config.Username = "user"
+	config.Password = "pass"
+	config.Timeout = 5 * time.Second
+	return config, messages
+}
+
+func session(c *textproto.Conn, messages chan<- envelope) {
+	defer c.Close()
+
+	var e envelope
+	_ = c.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			_ = c.PrintfLine("250-fake\r\n250 AUTH PLAIN")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			e.auth = string(decoded)
+			_ = c.PrintfLine("235 ok")
+		case "MAIL":
+			e.from = arg
+			_ = c.PrintfLine("250 ok")
+		case "RCPT":
+			if strings.Contains(arg, "reject.example.com") {
+				_ = c.PrintfLine("550 no such user")
+				continue
+			}
+			e.rcpt = append(e.rcpt, arg)
+			_ = c.PrintfLine("250 ok")
+		case "DATA":
+			_ = c.PrintfLine("354 go ahead")
+			data, _ := c.ReadDotBytes()
+			e.data = string(data)
+			_ = c.PrintfLine("250 queued")
+			messages <- e
+		case "QUIT":
+			_ = c.PrintfLine("221 bye")
+			return
+		default:
+			_ = c.PrintfLine("250 ok")
+		}
+	}
+}
+
+func TestSMTP_Send(t *testing.T) {
+	config, messages := fakeSMTP(t)
+
+	err := mailer.NewSMTP(config).Send(context.Background(), &mailer.Message{
+		From:    "App <noreply@example.com>",
+		To:      []string{"a@example.com"},
+		Bcc:     []string{"b@example.com"},
+		Subject: "hello",
+		Text:    "body",
+	})
+	require.NoError(t, err)
+
+	e := <-messages
+	assert.Equal(t, "\x00user\x00pass", e.auth)
+	assert.Equal(t, "FROM:<noreply@example.com>", e.from)
+	assert.Equal(t, []string{"TO:<a@example.com>", "TO:<b@example.com>"}, e.rcpt)
+	assert.Contains(t, e.data, "Subject: hello\n")
+	assert.True(t, strings.HasSuffix(e.data, "\nbody\n"), e.data)
+}
+
+func TestSMTP_Send_Errors(t *testing.T) {
+	config, _ := fakeSMTP(t)
+
+	err := mailer.NewSMTP(config).Send(context.Background(), &mailer.Message{
+		From: "noreply@example.com",
+		To:   []string{"nobody@reject.example.com"},
+		Text: "body",
+	})
+	var reply *textproto.Error
+	require.ErrorAs(t, err, &reply)
+	assert.Equal(t, 550, reply.Code)
+
+	config.TLS = mailer.TLSStartTLS
+	err = mailer.NewSMTP(config).Send(context.Background(), &mailer.Message{
+		From: "noreply@example.com",
+		To:   []string{"a@example.com"},
+		Text: "body",
+	})
+	assert.EqualError(t, err, fmt.Sprintf("mailer: 127.0.0.1:%d does not support STARTTLS", config.Port))
+}
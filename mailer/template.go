@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates renders messages from a set of named templates. Each template consists of up to three files sharing a
+// name: name.subject.tmpl, name.txt.tmpl, and name.html.tmpl. The subject and at least one body are required. The
+// HTML body is escaped with html/template.
+type Templates struct {
+	templates map[string]*messageTemplate
+}
+
+type messageTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// ParseFS parses every *.tmpl file in the file system, typically an embed.FS.
+func ParseFS(fsys fs.FS) (*Templates, error) {
+	t := &Templates{templates: make(map[string]*messageTemplate)}
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		base := strings.TrimSuffix(p, ".tmpl")
+		kind := path.Ext(base)
+		name := strings.TrimSuffix(base, kind)
+
+		mt := t.templates[name]
+		if mt == nil {
+			mt = &messageTemplate{}
+			t.templates[name] = mt
+		}
+
+		switch kind {
+		case ".subject":
+			mt.subject, err = texttemplate.New(p).Parse(string(data))
+		case ".txt":
+			mt.text, err = texttemplate.New(p).Parse(string(data))
+		case ".html":
+			mt.html, err = htmltemplate.New(p).Parse(string(data))
+		default:
+			return fmt.Errorf("mailer: template %s is not a .subject, .txt, or .html template", p)
+		}
+		if err != nil {
+			return fmt.Errorf("mailer: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, mt := range t.templates {
+		if mt.subject == nil {
+			return nil, fmt.Errorf("mailer: template %s has no subject", name)
+		}
+		if mt.text == nil && mt.html == nil {
+			return nil, fmt.Errorf("mailer: template %s has no body", name)
+		}
+	}
+
+	return t, nil
+}
+
+// Render executes the named template with data and returns a message with its subject and bodies. The caller sets
+// the recipients.
+func (t *Templates) Render(name string, data interface{}) (*Message, error) {
+	mt := t.templates[name]
+	if mt == nil {
+		return nil, fmt.Errorf("mailer: no template %s", name)
+	}
+
+	var msg Message
+	buf := new(bytes.Buffer)
+
+	if err := mt.subject.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("mailer: %v", err)
+	}
+	msg.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	if mt.text != nil {
+		buf.Reset()
+		if err := mt.text.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("mailer: %v", err)
+		}
+		msg.Text = buf.String()
+	}
+
+	if mt.html != nil {
+		buf.Reset()
+		if err := mt.html.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("mailer: %v", err)
+		}
+		msg.HTML = buf.String()
+	}
+
+	return &msg, nil
+}
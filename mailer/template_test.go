@@ -0,0 +1,59 @@
+package mailer_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/mailer"
+)
+
+func TestTemplates(t *testing.T) {
+	templates, err := mailer.ParseFS(fstest.MapFS{
+		"emails/welcome.subject.tmpl": {Data: []byte("Welcome,\n  {{.Name}}!\n")},
+		"emails/welcome.txt.tmpl":     {Data: []byte("Hi {{.Name}}")},
+		"emails/welcome.html.tmpl":    {Data: []byte("<p>Hi {{.Name}}</p>")},
+		"emails/reset.subject.tmpl":   {Data: []byte("Reset your password")},
+		"emails/reset.txt.tmpl":       {Data: []byte("{{.Link}}")},
+		"README.md":                   {Data: []byte("ignored")},
+	})
+	require.NoError(t, err)
+
+	msg, err := templates.Render("emails/welcome", map[string]string{"Name": "<Ann>"})
+	require.NoError(t, err)
+	assert.Equal(t, &mailer.Message{
+		Subject: "Welcome, <Ann>!",
+		Text:    "Hi <Ann>",
+		HTML:    "<p>Hi &lt;Ann&gt;</p>",
+	}, msg)
+
+	msg, err = templates.Render("emails/reset", map[string]string{"Link": "https://example.com/reset"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/reset", msg.Text)
+	assert.Empty(t, msg.HTML)
+
+	_, err = templates.Render("emails/missing", nil)
+	assert.EqualError(t, err, "mailer: no template emails/missing")
+}
+
+func TestParseFS_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"mailer: template a has no subject": {"a.txt.tmpl": {Data: []byte("body")}},
+		"mailer: template a has no body":    {"a.subject.tmpl": {Data: []byte("subject")}},
+		"mailer: template a.md.tmpl is not a .subject, .txt, or .html template": {
+			"a.md.tmpl": {Data: []byte("# body")},
+		},
+		"mailer: template: a.txt.tmpl:1: unclosed action": {
+			"a.subject.tmpl": {Data: []byte("subject")},
+			"a.txt.tmpl":     {Data: []byte("{{.Name")},
+		},
+	}
+
+	for msg, fsys := range tests {
+		templates, err := mailer.ParseFS(fsys)
+		assert.Nil(t, templates)
+		assert.EqualError(t, err, msg)
+	}
+}
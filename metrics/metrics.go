@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram upper bounds used when none are given, suitable for request durations in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Kind identifies the type of a metric.
+type Kind int
+
+// The kinds of metrics held by a Registry.
+const (
+	KindCounter Kind = iota
+	KindGauge
+	KindHistogram
+)
+
+// String returns the name of the kind as used in the Prometheus exposition format.
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindHistogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// Labels are the dimensions of a single time series.
+type Labels map[string]string
+
+// String formats the labels in the Prometheus exposition format, e.g. {code="200",method="GET"}. The result is
+// also the canonical key identifying a series within a Registry.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for idx, k := range keys {
+		if idx > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[k]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// with returns a copy of the labels with an additional label.
+func (l Labels) with(key, value string) Labels {
+	res := make(Labels, len(l)+1)
+	for k, v := range l {
+		res[k] = v
+	}
+	res[key] = value
+	return res
+}
+
+type atomicFloat struct {
+	bits uint64
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := atomic.LoadUint64(&f.bits)
+		if atomic.CompareAndSwapUint64(&f.bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(v float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(v))
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+// Counter is a value that only ever increases.
+type Counter struct {
+	value atomicFloat
+}
+
+// Add increases the counter by v. Add panics if v is negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.value.add(v)
+}
+
+// Inc increases the counter by one.
+func (c *Counter) Inc() {
+	c.value.add(1)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() float64 {
+	return c.value.load()
+}
+
+// Gauge is a value that may go up and down.
+type Gauge struct {
+	value atomicFloat
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.value.set(v)
+}
+
+// Add adds v, which may be negative, to the gauge.
+func (g *Gauge) Add(v float64) {
+	g.value.add(v)
+}
+
+// Inc increases the gauge by one.
+func (g *Gauge) Inc() {
+	g.value.add(1)
+}
+
+// Dec decreases the gauge by one.
+func (g *Gauge) Dec() {
+	g.value.add(-1)
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return g.value.load()
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+	bounds = slices.Compact(bounds)
+
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe records a single observation.
+func (h *Histogram) Observe(v float64) {
+	idx := sort.SearchFloat64s(h.bounds, v)
+	if idx < len(h.counts) {
+		atomic.AddUint64(&h.counts[idx], 1)
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.add(v)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// Sum returns the sum of all observations.
+func (h *Histogram) Sum() float64 {
+	return h.sum.load()
+}
+
+// Bucket is a cumulative histogram bucket.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Buckets returns the cumulative count of observations less than or equal to each upper bound.
+func (h *Histogram) Buckets() []Bucket {
+	res := make([]Bucket, len(h.bounds))
+	var total uint64
+	for idx, bound := range h.bounds {
+		total += atomic.LoadUint64(&h.counts[idx])
+		res[idx] = Bucket{UpperBound: bound, Count: total}
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package metrics_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+func TestLabels_String(t *testing.T) {
+	assert.Equal(t, "", metrics.Labels(nil).String())
+	assert.Equal(t, `{code="200",method="GET"}`, metrics.Labels{"method": "GET", "code": "200"}.String())
+	assert.Equal(t, `{path="/a\"b"}`, metrics.Labels{"path": `/a"b`}.String())
+}
+
+func TestCounter(t *testing.T) {
+	c := new(metrics.Counter)
+
+	wg := new(sync.WaitGroup)
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			c.Inc()
+			c.Add(0.5)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 15.0, c.Value())
+	assert.PanicsWithValue(t, "metrics: counter cannot decrease", func() {
+		c.Add(-1)
+	})
+}
+
+func TestGauge(t *testing.T) {
+	g := new(metrics.Gauge)
+	g.Set(10)
+	g.Inc()
+	g.Dec()
+	g.Dec()
+	g.Add(-2.5)
+	assert.Equal(t, 6.5, g.Value())
+}
+
+func TestHistogram(t *testing.T) {
+	r := metrics.NewRegistry()
+	h := r.Histogram("latency", []float64{1, 0.1, 10}, nil)
+
+	for _, v := range []float64{0.05, 0.1, 0.5, 5, 50} {
+		h.Observe(v)
+	}
+
+	assert.Equal(t, uint64(5), h.Count())
+	assert.Equal(t, 55.65, h.Sum())
+	assert.Equal(t, []metrics.Bucket{
+		{UpperBound: 0.1, Count: 2},
+		{UpperBound: 1, Count: 3},
+		{UpperBound: 10, Count: 4},
+	}, h.Buckets())
+}
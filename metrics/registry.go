@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Registry holds named metrics. Each name has a single Kind and any number of series distinguished by their labels.
+// A Registry is an http.Handler serving its metrics in the Prometheus text exposition format.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+	buckets  map[string][]float64 // the bounds added to the histograms by name, see AddBuckets
+}
+
+type family struct {
+	kind   Kind
+	series map[string]*series
+}
+
+type series struct {
+	labels Labels
+	metric interface{}
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family), buckets: make(map[string][]float64)}
+}
+
+// Counter returns the counter with the given name and labels, creating it if necessary.
+func (r *Registry) Counter(name string, labels Labels) *Counter {
+	return r.get(name, KindCounter, labels, func() interface{} { return new(Counter) }).(*Counter)
+}
+
+// Gauge returns the gauge with the given name and labels, creating it if necessary.
+func (r *Registry) Gauge(name string, labels Labels) *Gauge {
+	return r.get(name, KindGauge, labels, func() interface{} { return new(Gauge) }).(*Gauge)
+}
+
+// Histogram returns the histogram with the given name and labels, creating it with the given bucket upper bounds,
+// and those added with AddBuckets, if necessary. DefaultBuckets are used if buckets is empty.
+func (r *Registry) Histogram(name string, buckets []float64, labels Labels) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return r.get(name, KindHistogram, labels, func() interface{} {
+		return newHistogram(append(append([]float64(nil), buckets...), r.buckets[name]...))
+	}).(*Histogram)
+}
+
+// AddBuckets adds upper bounds to the buckets of the histograms named name created from now on, for the code reading
+// them to count the observations under a bound of its own, such as the threshold of an objective.
+func (r *Registry) AddBuckets(name string, bounds ...float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[name] = append(r.buckets[name], bounds...)
+}
+
+func (r *Registry) get(name string, kind Kind, labels Labels, create func() interface{}) interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{kind: kind, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+
+	if f.kind != kind {
+		panic(fmt.Sprintf("metrics: %s is a %s, not a %s", name, f.kind, kind))
+	}
+
+	key := labels.String()
+	s, ok := f.series[key]
+	if !ok {
+		copied := make(Labels, len(labels))
+		for k, v := range labels {
+			copied[k] = v
+		}
+
+		s = &series{labels: copied, metric: create()}
+		f.series[key] = s
+	}
+
+	return s.metric
+}
+
+// Sample is a point-in-time copy of a single series.
+type Sample struct {
+	Name    string
+	Labels  Labels
+	Kind    Kind
+	Value   float64  // counters and gauges
+	Count   uint64   // histograms
+	Sum     float64  // histograms
+	Buckets []Bucket // histograms
+}
+
+// Gather returns a snapshot of every series, sorted by name and labels.
+func (r *Registry) Gather() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var samples []Sample
+	for name, f := range r.families {
+		for _, s := range f.series {
+			sample := Sample{Name: name, Labels: s.labels, Kind: f.kind}
+
+			switch m := s.metric.(type) {
+			case *Counter:
+				sample.Value = m.Value()
+			case *Gauge:
+				sample.Value = m.Value()
+			case *Histogram:
+				sample.Count = m.Count()
+				sample.Sum = m.Sum()
+				sample.Buckets = m.Buckets()
+			}
+
+			samples = append(samples, sample)
+		}
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Name != samples[j].Name {
+			return samples[i].Name < samples[j].Name
+		}
+		return samples[i].Labels.String() < samples[j].Labels.String()
+	})
+
+	return samples
+}
+
+// ServeHTTP writes every metric in the Prometheus text exposition format.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	last := ""
+	for _, s := range r.Gather() {
+		if s.Name != last {
+			fmt.Fprintf(bw, "# TYPE %s %s\n", s.Name, s.Kind)
+			last = s.Name
+		}
+
+		if s.Kind != KindHistogram {
+			fmt.Fprintf(bw, "%s%s %s\n", s.Name, s.Labels, formatFloat(s.Value))
+			continue
+		}
+
+		for _, b := range s.Buckets {
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", s.Name, s.Labels.with("le", formatFloat(b.UpperBound)), b.Count)
+		}
+		fmt.Fprintf(bw, "%s_bucket%s %d\n", s.Name, s.Labels.with("le", "+Inf"), s.Count)
+		fmt.Fprintf(bw, "%s_sum%s %s\n", s.Name, s.Labels, formatFloat(s.Sum))
+		fmt.Fprintf(bw, "%s_count%s %d\n", s.Name, s.Labels, s.Count)
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,87 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	labels := metrics.Labels{"code": "200"}
+	c := r.Counter("requests_total", labels)
+	labels["code"] = "500"
+
+	assert.Same(t, c, r.Counter("requests_total", metrics.Labels{"code": "200"}))
+	assert.NotSame(t, c, r.Counter("requests_total", metrics.Labels{"code": "500"}))
+	assert.Same(t, r.Gauge("in_flight", nil), r.Gauge("in_flight", metrics.Labels{}))
+
+	assert.PanicsWithValue(t, "metrics: requests_total is a counter, not a gauge", func() {
+		r.Gauge("requests_total", nil)
+	})
+}
+
+func TestRegistry_AddBuckets(t *testing.T) {
+	r := metrics.NewRegistry()
+	before := r.Histogram("duration_seconds", []float64{0.1, 1}, metrics.Labels{"route": "/a"})
+	r.AddBuckets("duration_seconds", 0.3, 1)
+	after := r.Histogram("duration_seconds", []float64{0.1, 1}, metrics.Labels{"route": "/b"})
+	before.Observe(0.2)
+	after.Observe(0.2)
+
+	assert.Equal(t, []metrics.Bucket{{UpperBound: 0.1}, {UpperBound: 1, Count: 1}}, before.Buckets())
+	assert.Equal(t, []metrics.Bucket{{UpperBound: 0.1}, {UpperBound: 0.3, Count: 1}, {UpperBound: 1, Count: 1}},
+		after.Buckets())
+	assert.Len(t, r.Histogram("other_seconds", nil, nil).Buckets(), len(metrics.DefaultBuckets))
+}
+
+func TestRegistry_Gather(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Gauge("b", nil).Set(2)
+	r.Counter("a", metrics.Labels{"x": "2"}).Add(3)
+	r.Counter("a", metrics.Labels{"x": "1"}).Inc()
+	r.Histogram("c", []float64{1}, nil).Observe(0.5)
+
+	assert.Equal(t, []metrics.Sample{
+		{Name: "a", Labels: metrics.Labels{"x": "1"}, Kind: metrics.KindCounter, Value: 1},
+		{Name: "a", Labels: metrics.Labels{"x": "2"}, Kind: metrics.KindCounter, Value: 3},
+		{Name: "b", Labels: metrics.Labels{}, Kind: metrics.KindGauge, Value: 2},
+		{
+			Name:    "c",
+			Labels:  metrics.Labels{},
+			Kind:    metrics.KindHistogram,
+			Count:   1,
+			Sum:     0.5,
+			Buckets: []metrics.Bucket{{UpperBound: 1, Count: 1}},
+		},
+	}, r.Gather())
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("http_requests_total", metrics.Labels{"code": "200", "method": "GET"}).Add(4)
+	r.Gauge("in_flight", nil).Set(1.5)
+	h := r.Histogram("duration_seconds", []float64{0.1, 1}, metrics.Labels{"method": "GET"})
+	h.Observe(0.05)
+	h.Observe(2)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `# TYPE duration_seconds histogram
+duration_seconds_bucket{le="0.1",method="GET"} 1
+duration_seconds_bucket{le="1",method="GET"} 1
+duration_seconds_bucket{le="+Inf",method="GET"} 2
+duration_seconds_sum{method="GET"} 2.05
+duration_seconds_count{method="GET"} 2
+# TYPE http_requests_total counter
+http_requests_total{code="200",method="GET"} 4
+# TYPE in_flight gauge
+in_flight 1.5
+`, w.Body.String())
+}
@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes where the spec is served and what is validated, as in APP_OPENAPI_PATH.
+	DefaultPrefix = "APP_OPENAPI_"
+
+	// DefaultPath is where the document is served.
+	DefaultPath = "/openapi.json"
+
+	// DefaultUIPath is where Swagger UI is served, when enabled.
+	DefaultUIPath = "/docs"
+)
+
+// Config describes where the document is served and how requests and responses are checked against it.
+type Config struct {
+	Path              string // where the document is served as JSON
+	UI                bool   // whether Swagger UI is served
+	UIPath            string // where Swagger UI is served
+	ValidateRequests  bool   // whether requests are validated against the document, logging violations
+	ValidateResponses bool   // whether responses are validated against the document, logging violations
+	Reject            bool   // whether invalid requests are answered with 400 Bad Request rather than served
+}
+
+// DefaultConfig returns a Config serving the document at DefaultPath, without Swagger UI or validation.
+func DefaultConfig() *Config {
+	return &Config{Path: DefaultPath, UIPath: DefaultUIPath}
+}
+
+func init() {
+	configschema.Register("openapi", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the OpenAPI variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "PATH", Type: "string", Default: DefaultPath, Description: "Where the document is served."},
+		{Name: prefix + "UI", Type: "bool", Default: "false", Description: "Serve Swagger UI."},
+		{Name: prefix + "UI_PATH", Type: "string", Default: DefaultUIPath, Description: "Where Swagger UI is served."},
+		{Name: prefix + "VALIDATE_REQUESTS", Type: "bool", Default: "false",
+			Description: "Log the requests that do not match the document."},
+		{Name: prefix + "VALIDATE_RESPONSES", Type: "bool", Default: "false",
+			Description: "Log the responses that do not match the document."},
+		{Name: prefix + "REJECT", Type: "bool", Default: "false",
+			Description: "Answer invalid requests with 400 Bad Request rather than serve them."},
+	}
+}
+
+// FromEnv reads where the document and Swagger UI are served, PATH, UI, and UI_PATH, and how requests and responses are
+// checked against it, VALIDATE_REQUESTS, VALIDATE_RESPONSES, and REJECT, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	for key, dst := range map[string]*string{
+		"PATH":    &config.Path,
+		"UI_PATH": &config.UIPath,
+	} {
+		if v := get(key); v != "" {
+			if !strings.HasPrefix(v, "/") {
+				return nil, fmt.Errorf("openapi: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = v
+		}
+	}
+
+	for key, dst := range map[string]*bool{
+		"UI":                 &config.UI,
+		"VALIDATE_REQUESTS":  &config.ValidateRequests,
+		"VALIDATE_RESPONSES": &config.ValidateResponses,
+		"REJECT":             &config.Reject,
+	} {
+		if v := get(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("openapi: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = b
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,43 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/openapi"
+)
+
+func TestFromEnv_Paths(t *testing.T) {
+	config, err := openapi.FromEnv(apptest.Lookup(map[string]string{
+		"API_PATH":    " /api/openapi.json ",
+		"API_UI":      "true",
+		"API_UI_PATH": "/api/docs/",
+	}), "API_")
+	require.NoError(t, err)
+	assert.Equal(t, "/api/openapi.json", config.Path)
+	assert.True(t, config.UI)
+	assert.Equal(t, "/api/docs/", config.UIPath)
+
+	// the routes are mounted on the mux as they are, so they must be absolute
+	for key, v := range map[string]string{"PATH": "openapi.json", "UI_PATH": "docs"} {
+		_, err := openapi.FromEnv(apptest.Lookup(map[string]string{"APP_OPENAPI_" + key: v}), "")
+		assert.EqualError(t, err, "openapi: invalid APP_OPENAPI_"+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Validation(t *testing.T) {
+	// validating responses alone logs the drift of the handlers from the document without changing what is served
+	config, err := openapi.FromEnv(apptest.Lookup(map[string]string{"APP_OPENAPI_VALIDATE_RESPONSES": "1"}), "")
+	require.NoError(t, err)
+	expected := openapi.DefaultConfig()
+	expected.ValidateResponses = true
+	assert.Equal(t, expected, config)
+
+	for _, key := range []string{"UI", "VALIDATE_REQUESTS", "VALIDATE_RESPONSES", "REJECT"} {
+		_, err := openapi.FromEnv(apptest.Lookup(map[string]string{"APP_OPENAPI_" + key: "no way"}), "")
+		assert.EqualError(t, err, "openapi: invalid APP_OPENAPI_"+key+` "no way"`)
+	}
+}
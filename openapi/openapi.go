@@ -0,0 +1,239 @@
+// Package openapi serves the OpenAPI document of a service and checks the requests and responses of the service
+// against it, for services that treat the document as their contract:
+//
+//	//go:embed openapi.yaml
+//	var document []byte
+//
+//	config, err := openapi.FromEnv(a.LookupEnv, "")
+//	spec, err := openapi.New(a, document, config)
+//	a.Register("http", app.NewHTTPServer("tcp://:8080", spec.Wrap(mux)))
+//
+// The document, in YAML or JSON, is served as JSON at the Path, and Swagger UI at the UIPath when enabled. Swagger UI
+// loads its scripts and styles from the unpkg.com CDN, so it needs the browser to reach it.
+//
+// The requests and responses of the operations of the document are validated when enabled; those of paths the
+// document does not describe are left alone. Violations are logged as warnings with the request ID, see correlation.
+// Invalid requests are answered with 400 Bad Request problem details when Reject is set, and served otherwise.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aphistic/gomol"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// Spec is an OpenAPI document served and enforced by Wrap.
+type Spec struct {
+	a      *app.App
+	config Config
+	doc    *openapi3.T
+	json   []byte
+	router routers.Router
+}
+
+// New returns the Spec of the OpenAPI 3 document data, in YAML or JSON, or an error if the document is invalid.
+func New(a *app.App, data []byte, config *Config) (*Spec, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: invalid document: %w", err)
+	}
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, fmt.Errorf("openapi: invalid document: %w", err)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	router, err := gorillamux.NewRouter(routingDoc(doc))
+	if err != nil {
+		return nil, fmt.Errorf("openapi: invalid document: %w", err)
+	}
+
+	return &Spec{a: a, config: *config, doc: doc, json: b, router: router}, nil
+}
+
+// Document returns the OpenAPI document.
+func (s *Spec) Document() *openapi3.T {
+	return s.doc
+}
+
+// Wrap returns a handler serving the document and Swagger UI, and calling next for the other requests, validating
+// them and their responses against the document as configured.
+func (s *Spec) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead
+		switch {
+		case r.URL.Path == s.config.Path && read:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(s.json)
+			return
+		case s.config.UI && r.URL.Path == s.config.UIPath && read:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_ = swaggerUI.Execute(w, s.config.Path)
+			return
+		case !s.config.ValidateRequests && !s.config.ValidateResponses:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route, params, err := s.router.FindRoute(r)
+		if err != nil {
+			// the document does not describe the request
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				MultiError:         true,
+			},
+		}
+		if s.config.ValidateRequests {
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				s.violation(r, route, "request", err)
+				if s.config.Reject {
+					p := problem.New(http.StatusBadRequest, "%s", err)
+					if id, ok := correlation.FromContext(r.Context()); ok {
+						p.RequestID = id
+					}
+					problem.Write(w, p)
+					return
+				}
+			}
+		}
+		if !s.config.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.code == 0 {
+			rec.WriteHeader(http.StatusOK)
+		}
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.code,
+			Header:                 rec.header,
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+		})
+		if err != nil {
+			s.violation(r, route, "response", err)
+		}
+	})
+}
+
+// violation logs that the request or response of r does not conform to the operation of route.
+func (s *Spec) violation(r *http.Request, route *routers.Route, what string, err error) {
+	attrs := correlation.Attrs(r.Context())
+	attrs["method"] = r.Method
+	attrs["path"] = r.URL.Path
+	attrs["operation"] = route.Method + " " + route.Path
+	if route.Operation != nil && route.Operation.OperationID != "" {
+		attrs["operation"] = route.Operation.OperationID
+	}
+	_ = s.a.Logger().Warnm(gomol.NewAttrsFromMap(attrs), "%s violates the OpenAPI document: %s", what,
+		strings.ReplaceAll(err.Error(), "\n", " "))
+}
+
+// variablePattern matches the variables of a server URL.
+var variablePattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// routingDoc returns doc with its servers reduced to their paths, so that requests are matched whichever host and
+// scheme they reached the service with, as they do behind a proxy.
+func routingDoc(doc *openapi3.T) *openapi3.T {
+	routed := *doc
+	routed.Servers = nil
+	seen := make(map[string]bool)
+	for _, server := range doc.Servers {
+		raw := variablePattern.ReplaceAllStringFunc(server.URL, func(v string) string {
+			if variable := server.Variables[v[1:len(v)-1]]; variable != nil {
+				return variable.Default
+			}
+			return v
+		})
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		base := strings.TrimSuffix(u.Path, "/")
+		if !seen[base] {
+			seen[base] = true
+			routed.Servers = append(routed.Servers, &openapi3.Server{URL: base + "/"})
+		}
+	}
+	return &routed
+}
+
+// recorder is a ResponseWriter keeping a copy of the response it writes.
+type recorder struct {
+	http.ResponseWriter
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+		r.header = r.ResponseWriter.Header().Clone()
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.code == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the ResponseWriter, for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// swaggerUI is the page of Swagger UI, executed with the path of the document.
+var swaggerUI = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>API documentation</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+	window.ui = SwaggerUIBundle({url: {{.}}, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,146 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/openapi"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+const document = `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /orders:
+    post:
+      operationId: createOrder
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [item]
+              properties:
+                item:
+                  type: string
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id:
+                    type: integer
+`
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// orders answers every request with the JSON response.
+func orders(response string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(response))
+	})
+}
+
+func TestNew_Invalid(t *testing.T) {
+	a := apptest.New(t, nil)
+	_, err := openapi.New(a, []byte("openapi: 3.0.3\ninfo: {}\npaths: {}"), openapi.DefaultConfig())
+	assert.ErrorContains(t, err, "openapi: invalid document")
+}
+
+func TestSpec_Serve(t *testing.T) {
+	a := apptest.New(t, nil)
+	spec, err := openapi.New(a, []byte(document), openapi.DefaultConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "Orders", spec.Document().Info.Title)
+	h := spec.Wrap(http.NotFoundHandler())
+
+	w := serve(h, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	assert.Equal(t, http.StatusNotFound, serve(h, http.MethodGet, "/docs", "").Code)
+
+	config := openapi.DefaultConfig()
+	config.UI = true
+	spec, err = openapi.New(a, []byte(document), config)
+	require.NoError(t, err)
+	w = serve(spec.Wrap(http.NotFoundHandler()), http.MethodGet, "/docs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "SwaggerUIBundle")
+	assert.Contains(t, w.Body.String(), `"/openapi.json"`)
+}
+
+func TestSpec_ValidateRequests(t *testing.T) {
+	a := apptest.New(t, nil)
+	config := openapi.DefaultConfig()
+	config.ValidateRequests = true
+	spec, err := openapi.New(a, []byte(document), config)
+	require.NoError(t, err)
+	h := spec.Wrap(orders(`{"id":1}`))
+
+	// the handler reads the body the validation read
+	assert.Equal(t, http.StatusCreated, serve(h, http.MethodPost, "/v1/orders", `{"item":"book"}`).Code)
+	// violations are only logged
+	assert.Equal(t, http.StatusCreated, serve(h, http.MethodPost, "/v1/orders", `{"name":"book"}`).Code)
+	// paths the document does not describe are served
+	assert.Equal(t, http.StatusCreated, serve(h, http.MethodPost, "/healthz", ``).Code)
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	stderr := apptest.Stderr(t, a)
+	assert.Equal(t, 1, strings.Count(stderr, "request violates the OpenAPI document"))
+	assert.Contains(t, stderr, "createOrder")
+
+	config.Reject = true
+	a = apptest.New(t, nil)
+	spec, err = openapi.New(a, []byte(document), config)
+	require.NoError(t, err)
+	w := serve(spec.Wrap(orders(`{"id":1}`)), http.MethodPost, "/v1/orders", `{"name":"book"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "item")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+}
+
+func TestSpec_ValidateResponses(t *testing.T) {
+	a := apptest.New(t, nil)
+	config := openapi.DefaultConfig()
+	config.ValidateResponses = true
+	spec, err := openapi.New(a, []byte(document), config)
+	require.NoError(t, err)
+
+	w := serve(spec.Wrap(orders(`{"id":"one"}`)), http.MethodPost, "/v1/orders", `{"item":"book"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"id":"one"}`, w.Body.String())
+	serve(spec.Wrap(orders(`{"id":1}`)), http.MethodPost, "/v1/orders", `{"item":"book"}`)
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Equal(t, 1, strings.Count(apptest.Stderr(t, a), "response violates the OpenAPI document"))
+}
@@ -0,0 +1,251 @@
+package otlp
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix is the prefix the OpenTelemetry SDKs read their variables with, as in OTEL_EXPORTER_OTLP_ENDPOINT,
+	// so that the exporter is configured as the others of the process are.
+	DefaultPrefix = "OTEL_"
+
+	// DefaultHTTPEndpoint is where the metrics are sent with the HTTP protocols, a collector on the host.
+	DefaultHTTPEndpoint = "http://localhost:4318/v1/metrics"
+
+	// DefaultGRPCEndpoint is where the metrics are sent with gRPC, a collector on the host.
+	DefaultGRPCEndpoint = "http://localhost:4317"
+
+	// DefaultTimeout bounds the time an export takes.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultInterval is how often the metrics are exported.
+	DefaultInterval = time.Minute
+)
+
+// Protocol is the transport and encoding of the metrics sent to the collector.
+type Protocol string
+
+// The protocols of OTLP.
+const (
+	ProtocolHTTPProtobuf Protocol = "http/protobuf"
+	ProtocolHTTPJSON     Protocol = "http/json"
+	ProtocolGRPC         Protocol = "grpc"
+)
+
+// Config describes the collector the Exporter sends the metrics to, and the resource they describe.
+type Config struct {
+	Enabled     bool              // false if the metrics are not to be exported, as OTEL_METRICS_EXPORTER=none says
+	Protocol    Protocol          // the transport and encoding of the metrics
+	Endpoint    string            // the URL of the metrics, with /v1/metrics, over HTTP, or of the collector over gRPC
+	Headers     map[string]string // the headers, or gRPC metadata, sent with the metrics, such as an API key
+	Compression string            // gzip, or empty for none
+	Insecure    bool              // whether gRPC connects without TLS to an https endpoint
+	Timeout     time.Duration     // the bound on the time an export takes
+	Interval    time.Duration     // how often the metrics are exported
+
+	ServiceName string            // the service.name of the resource
+	Resource    map[string]string // the other attributes of the resource, such as deployment.environment
+}
+
+// DefaultConfig returns a Config exporting the metrics with HTTP and protobuf to a collector on the host.
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:  true,
+		Protocol: ProtocolHTTPProtobuf,
+		Endpoint: DefaultHTTPEndpoint,
+		Timeout:  DefaultTimeout,
+		Interval: DefaultInterval,
+	}
+}
+
+func init() {
+	configschema.Register("otlp", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the OpenTelemetry variables with the prefix, each EXPORTER_OTLP_ one followed by its
+// EXPORTER_OTLP_METRICS_ override.
+func ConfigKeys(prefix string) []configschema.Key {
+	keys := []configschema.Key{
+		{Name: prefix + "SDK_DISABLED", Type: "bool", Default: "false", Description: "Export nothing."},
+		{Name: prefix + "METRICS_EXPORTER", Type: "string", Default: "otlp",
+			Description: "The exporters of the metrics: otlp, or none to export nothing."},
+		{Name: prefix + "METRIC_EXPORT_INTERVAL", Type: "int",
+			Default:     strconv.Itoa(int(DefaultInterval.Milliseconds())),
+			Description: "How often the metrics are exported, in milliseconds."},
+		{Name: prefix + "SERVICE_NAME", Type: "string", Description: "The service.name of the resource."},
+		{Name: prefix + "RESOURCE_ATTRIBUTES", Type: "string",
+			Description: "The other attributes of the resource, as key=value pairs separated by commas."},
+	}
+	for _, key := range []configschema.Key{
+		{Name: "PROTOCOL", Type: "string", Default: string(ProtocolHTTPProtobuf),
+			Description: "The protocol: http/protobuf, http/json, or grpc."},
+		{Name: "ENDPOINT", Type: "url", Description: "The URL of the collector."},
+		{Name: "HEADERS", Type: "string", Secret: true,
+			Description: "The headers sent with the metrics, as key=value pairs separated by commas."},
+		{Name: "COMPRESSION", Type: "string", Default: "none", Description: "The compression: gzip or none."},
+		{Name: "INSECURE", Type: "bool", Default: "false",
+			Description: "Connect to the collector without TLS over gRPC."},
+		{Name: "TIMEOUT", Type: "int", Default: strconv.Itoa(int(DefaultTimeout.Milliseconds())),
+			Description: "How long an export may take, in milliseconds."},
+	} {
+		name := key.Name
+		key.Name = prefix + "EXPORTER_OTLP_" + name
+		keys = append(keys, key)
+		key.Name = prefix + "EXPORTER_OTLP_METRICS_" + name
+		key.Default = ""
+		key.Description = "Overrides " + prefix + "EXPORTER_OTLP_" + name + " for the metrics."
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// FromEnv reads the variables of the OpenTelemetry SDKs, with the prefix or DefaultPrefix, so that the metrics, traces,
+// and logs of the app flow to the same collector:
+//
+//	EXPORTER_OTLP_PROTOCOL       http/protobuf, http/json, or grpc
+//	EXPORTER_OTLP_ENDPOINT       the URL of the collector, to which /v1/metrics is added over HTTP
+//	EXPORTER_OTLP_HEADERS        key=value pairs, separated by commas, with URL encoded values
+//	EXPORTER_OTLP_COMPRESSION    gzip or none
+//	EXPORTER_OTLP_INSECURE       true to connect to the collector without TLS over gRPC
+//	EXPORTER_OTLP_TIMEOUT        in milliseconds
+//	METRIC_EXPORT_INTERVAL       in milliseconds
+//	METRICS_EXPORTER             otlp, or none to export nothing
+//	SDK_DISABLED                 true to export nothing
+//	SERVICE_NAME                 the service.name of the resource
+//	RESOURCE_ATTRIBUTES          key=value pairs, separated by commas, with URL encoded values
+//
+// Each EXPORTER_OTLP_ variable is overridden by its EXPORTER_OTLP_METRICS_ one, whose endpoint is used as is.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+	// exporter returns the name and value of the EXPORTER_OTLP_METRICS_ variable if set, or the EXPORTER_OTLP_ one
+	exporter := func(key string) (string, string) {
+		if v := get("EXPORTER_OTLP_METRICS_" + key); v != "" {
+			return prefix + "EXPORTER_OTLP_METRICS_" + key, v
+		}
+		return prefix + "EXPORTER_OTLP_" + key, get("EXPORTER_OTLP_" + key)
+	}
+
+	config := DefaultConfig()
+	if v := get("SDK_DISABLED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: invalid %sSDK_DISABLED %q", prefix, v)
+		}
+		config.Enabled = !b
+	}
+	if v := get("METRICS_EXPORTER"); v != "" {
+		otlp := false
+		for _, e := range strings.Split(v, ",") {
+			otlp = otlp || strings.TrimSpace(e) == "otlp"
+		}
+		config.Enabled = config.Enabled && otlp
+	}
+
+	if key, v := exporter("PROTOCOL"); v != "" {
+		switch p := Protocol(strings.ToLower(v)); p {
+		case ProtocolHTTPProtobuf, ProtocolHTTPJSON, ProtocolGRPC:
+			config.Protocol = p
+		default:
+			return nil, fmt.Errorf("otlp: invalid %s %q", key, v)
+		}
+	}
+
+	if config.Protocol == ProtocolGRPC {
+		config.Endpoint = DefaultGRPCEndpoint
+	}
+	if key, v := exporter("ENDPOINT"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("otlp: invalid %s %q", key, v)
+		}
+		if config.Protocol != ProtocolGRPC && key == prefix+"EXPORTER_OTLP_ENDPOINT" {
+			u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/metrics"
+		}
+		config.Endpoint = u.String()
+	}
+
+	if key, v := exporter("HEADERS"); v != "" {
+		headers, err := pairs(v)
+		if err != nil {
+			// the headers often carry credentials, so only the position of the invalid one is shown
+			return nil, fmt.Errorf("otlp: invalid %s %v", key, err)
+		}
+		config.Headers = headers
+	}
+
+	if key, v := exporter("COMPRESSION"); v != "" {
+		switch strings.ToLower(v) {
+		case "gzip":
+			config.Compression = "gzip"
+		case "none":
+		default:
+			return nil, fmt.Errorf("otlp: invalid %s %q", key, v)
+		}
+	}
+
+	if key, v := exporter("INSECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: invalid %s %q", key, v)
+		}
+		config.Insecure = b
+	}
+
+	millis := func(key, v string, dst *time.Duration) error {
+		if v == "" {
+			return nil
+		}
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return fmt.Errorf("otlp: invalid %s %q", key, v)
+		}
+		*dst = time.Duration(ms) * time.Millisecond
+		return nil
+	}
+	key, v := exporter("TIMEOUT")
+	if err := millis(key, v, &config.Timeout); err != nil {
+		return nil, err
+	}
+	if err := millis(prefix+"METRIC_EXPORT_INTERVAL", get("METRIC_EXPORT_INTERVAL"), &config.Interval); err != nil {
+		return nil, err
+	}
+
+	if v := get("RESOURCE_ATTRIBUTES"); v != "" {
+		attrs, err := pairs(v)
+		if err != nil {
+			return nil, fmt.Errorf("otlp: invalid %sRESOURCE_ATTRIBUTES %v", prefix, err)
+		}
+		config.Resource = attrs
+	}
+	config.ServiceName = get("SERVICE_NAME")
+
+	return config, nil
+}
+
+// pairs parses key=value pairs separated by commas, with URL encoded values.
+func pairs(v string) (map[string]string, error) {
+	res := make(map[string]string)
+	for i, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if value, err := url.PathUnescape(strings.TrimSpace(value)); ok && key != "" && err == nil {
+			res[key] = value
+			continue
+		}
+		return nil, fmt.Errorf("entry %d", i+1)
+	}
+	return res, nil
+}
@@ -0,0 +1,131 @@
+package otlp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/otlp"
+)
+
+func TestFromEnv_Pairs(t *testing.T) {
+	// the values are URL encoded, and a value may hold an equals sign
+	config, err := otlp.FromEnv(apptest.Lookup(map[string]string{
+		"APP_OTEL_EXPORTER_OTLP_HEADERS": "api-key=s3cr3t==, x-tenant = a%20b",
+		"APP_OTEL_RESOURCE_ATTRIBUTES":   "deployment.environment=prod,team=",
+		"APP_OTEL_SERVICE_NAME":          " api ",
+	}), "APP_OTEL_")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api-key": "s3cr3t==", "x-tenant": "a b"}, config.Headers)
+	assert.Equal(t, map[string]string{"deployment.environment": "prod", "team": ""}, config.Resource)
+	assert.Equal(t, "api", config.ServiceName)
+
+	// the headers often carry credentials, so the error names the entry by position only
+	for v, n := range map[string]string{"a=1,secret": "2", "=prod": "1", "a=1,,b=2": "2", "a=%zz": "1"} {
+		_, err := otlp.FromEnv(apptest.Lookup(map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": v}), "")
+		assert.EqualError(t, err, "otlp: invalid OTEL_EXPORTER_OTLP_HEADERS entry "+n, v)
+	}
+}
+
+func TestFromEnv_Millis(t *testing.T) {
+	// the durations are bare milliseconds, as the OpenTelemetry SDKs read them
+	config, err := otlp.FromEnv(apptest.Lookup(map[string]string{
+		"OTEL_EXPORTER_OTLP_TIMEOUT":         "5000",
+		"OTEL_EXPORTER_OTLP_METRICS_TIMEOUT": "2500",
+		"OTEL_METRIC_EXPORT_INTERVAL":        "15000",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 2500*time.Millisecond, config.Timeout)
+	assert.Equal(t, 15*time.Second, config.Interval)
+
+	for key, v := range map[string]string{
+		"OTEL_EXPORTER_OTLP_TIMEOUT":         "10s",
+		"OTEL_EXPORTER_OTLP_METRICS_TIMEOUT": "0",
+		"OTEL_METRIC_EXPORT_INTERVAL":        "-1",
+	} {
+		_, err := otlp.FromEnv(apptest.Lookup(map[string]string{key: v}), "")
+		assert.EqualError(t, err, "otlp: invalid "+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Endpoint(t *testing.T) {
+	for name, tt := range map[string]struct {
+		env      map[string]string
+		protocol otlp.Protocol
+		endpoint string
+	}{
+		"http default": {nil, otlp.ProtocolHTTPProtobuf, otlp.DefaultHTTPEndpoint},
+		"grpc default": {
+			map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc"},
+			otlp.ProtocolGRPC, otlp.DefaultGRPCEndpoint,
+		},
+		"grpc": {
+			map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc", "OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4317"},
+			otlp.ProtocolGRPC, "http://collector:4317",
+		},
+		"metrics endpoint used as is": {
+			map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":         "http://collector:4318",
+				"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT": "http://metrics:9090/api/v1/otlp",
+			},
+			otlp.ProtocolHTTPProtobuf, "http://metrics:9090/api/v1/otlp",
+		},
+		"metrics protocol": {
+			map[string]string{
+				"OTEL_EXPORTER_OTLP_PROTOCOL":         "grpc",
+				"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL": "http/protobuf",
+				"OTEL_EXPORTER_OTLP_ENDPOINT":         "http://collector:4318",
+			},
+			otlp.ProtocolHTTPProtobuf, "http://collector:4318/v1/metrics",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config, err := otlp.FromEnv(apptest.Lookup(tt.env), "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.protocol, config.Protocol)
+			assert.Equal(t, tt.endpoint, config.Endpoint)
+		})
+	}
+}
+
+func TestFromEnv_Disabled(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"OTEL_SDK_DISABLED": "true"},
+		{"OTEL_METRICS_EXPORTER": "none"},
+		{"OTEL_METRICS_EXPORTER": "prometheus"},
+	} {
+		config, err := otlp.FromEnv(apptest.Lookup(env), "")
+		assert.NoError(t, err)
+		assert.False(t, config.Enabled, env)
+	}
+}
+
+func TestFromEnv_Transport(t *testing.T) {
+	config, err := otlp.FromEnv(apptest.Lookup(map[string]string{
+		"OTEL_EXPORTER_OTLP_COMPRESSION":      "GZIP",
+		"OTEL_EXPORTER_OTLP_INSECURE":         "true",
+		"OTEL_EXPORTER_OTLP_METRICS_INSECURE": "false",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", config.Compression)
+	assert.False(t, config.Insecure)
+
+	config, err = otlp.FromEnv(apptest.Lookup(map[string]string{"OTEL_EXPORTER_OTLP_COMPRESSION": "none"}), "")
+	require.NoError(t, err)
+	assert.Empty(t, config.Compression)
+
+	for key, v := range map[string]string{
+		"OTEL_SDK_DISABLED":                   "yes please",
+		"OTEL_EXPORTER_OTLP_PROTOCOL":         "thrift",
+		"OTEL_EXPORTER_OTLP_METRICS_PROTOCOL": "http",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":         "collector:4318",
+		"OTEL_EXPORTER_OTLP_COMPRESSION":      "zstd",
+		"OTEL_EXPORTER_OTLP_INSECURE":         "maybe",
+	} {
+		_, err := otlp.FromEnv(apptest.Lookup(map[string]string{key: v}), "")
+		assert.EqualError(t, err, "otlp: invalid "+key+` "`+v+`"`)
+	}
+}
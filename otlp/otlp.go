@@ -0,0 +1,303 @@
+// Package otlp exports the metrics of a metrics.Registry to an OpenTelemetry collector with OTLP, configured by the
+// OTEL_ environment variables of the OpenTelemetry SDKs, so that the metrics, the traces, and the logs of the app
+// flow to the same collector, describing the same resource:
+//
+//	config, err := otlp.FromEnv(a.LookupEnv, "")
+//	if err != nil {
+//		return err
+//	}
+//	a.Register("otlp", otlp.New(config, a.Metrics()))
+//
+// Every Interval, and when the app shuts down, the Exporter sends the value of every series, cumulative since it was
+// bound: counters as monotonic sums, gauges, and histograms with their buckets. Resource returns the attributes of
+// the resource, for the traces and the logs.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// scope is the instrumentation scope of the metrics.
+var scope = &commonpb.InstrumentationScope{Name: "github.com/demosdemon/golang-app-framework/metrics"}
+
+// Exporter is a Server exporting the metrics of a Registry to a collector.
+type Exporter struct {
+	Client *http.Client // client used over HTTP, http.DefaultClient if nil
+
+	config   Config
+	registry *metrics.Registry
+	now      func() time.Time
+
+	mu       sync.Mutex
+	logger   *gomol.Base
+	resource *resourcepb.Resource
+	start    time.Time
+	grpc     colmetricspb.MetricsServiceClient
+	failing  bool
+
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns an Exporter sending the metrics of registry as config describes.
+func New(config *Config, registry *metrics.Registry) *Exporter {
+	return &Exporter{config: *config, registry: registry, now: time.Now, stopping: make(chan struct{})}
+}
+
+// Bind describes the resource of the app, see Resource, and connects to the collector over gRPC, with GRPCClient.
+func (e *Exporter) Bind(a *app.App) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.logger, e.start = a.Logger(), e.now()
+	attrs := Resource(a, &e.config)
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	e.resource = &resourcepb.Resource{}
+	for _, k := range keys {
+		e.resource.Attributes = append(e.resource.Attributes, attribute(k, attrs[k]))
+	}
+
+	if !e.config.Enabled || e.config.Protocol != ProtocolGRPC {
+		return nil
+	}
+	u, err := url.Parse(e.config.Endpoint)
+	if err != nil {
+		return err
+	}
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if u.Scheme == "http" || e.config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if e.config.Compression == "gzip" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(grpcgzip.Name)))
+	}
+	conn, err := a.GRPCClient(u.Host, opts...)
+	if err != nil {
+		return err
+	}
+	e.grpc = colmetricspb.NewMetricsServiceClient(conn)
+	return nil
+}
+
+// Serve exports the metrics every Interval until the Exporter is shut down. An export failing is logged once, until
+// one succeeds again.
+func (e *Exporter) Serve() error {
+	if !e.config.Enabled {
+		<-e.stopping
+		return nil
+	}
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopping:
+			return nil
+		case <-ticker.C:
+			err := e.Flush(context.Background())
+			e.mu.Lock()
+			if err != nil && !e.failing {
+				_ = e.logger.Warnf("unable to export the metrics to %s: %v", e.config.Endpoint, err)
+			}
+			e.failing = err != nil
+			e.mu.Unlock()
+		}
+	}
+}
+
+// Shutdown exports the metrics a last time.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stopping) })
+	if !e.config.Enabled {
+		return nil
+	}
+	return e.Flush(ctx)
+}
+
+// Flush exports the metrics now, waiting for the collector for up to Timeout.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.resource == nil {
+		return errors.New("otlp: the Exporter is not bound")
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	req := &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource:     e.resource,
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: scope, Metrics: e.collect()}},
+	}}}
+	if e.grpc != nil {
+		if len(e.config.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.config.Headers))
+		}
+		_, err := e.grpc.Export(ctx, req)
+		return err
+	}
+	return e.post(ctx, req)
+}
+
+// post sends req to the Endpoint over HTTP.
+func (e *Exporter) post(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) error {
+	contentType := "application/x-protobuf"
+	marshal := proto.Marshal
+	if e.config.Protocol == ProtocolHTTPJSON {
+		contentType, marshal = "application/json", protojson.Marshal
+	}
+	body, err := marshal(req)
+	if err != nil {
+		return err
+	}
+	if e.config.Compression == "gzip" {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body = buf.Bytes()
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.config.Headers {
+		r.Header.Set(k, v)
+	}
+	r.Header.Set("Content-Type", contentType)
+	if e.config.Compression == "gzip" {
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp: %s answered %s", e.config.Endpoint, res.Status)
+	}
+	return nil
+}
+
+// collect returns the metrics of the registry, one per name.
+func (e *Exporter) collect() []*metricspb.Metric {
+	start, now := uint64(e.start.UnixNano()), uint64(e.now().UnixNano())
+
+	var res []*metricspb.Metric
+	var m *metricspb.Metric
+	for _, s := range e.registry.Gather() {
+		if m == nil || m.Name != s.Name {
+			m = &metricspb.Metric{Name: s.Name}
+			switch s.Kind {
+			case metrics.KindCounter:
+				m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+				}}
+			case metrics.KindGauge:
+				m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+			case metrics.KindHistogram:
+				m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				}}
+			}
+			res = append(res, m)
+		}
+
+		attrs := attributes(s.Labels)
+		switch data := m.Data.(type) {
+		case *metricspb.Metric_Sum:
+			data.Sum.DataPoints = append(data.Sum.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      now,
+				Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: s.Value},
+			})
+		case *metricspb.Metric_Gauge:
+			data.Gauge.DataPoints = append(data.Gauge.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:   attrs,
+				TimeUnixNano: now,
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: s.Value},
+			})
+		case *metricspb.Metric_Histogram:
+			// OTLP counts the observations of each bucket, and of the one above the last bound, not cumulatively
+			p := &metricspb.HistogramDataPoint{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      now,
+				Count:             s.Count,
+				Sum:               proto.Float64(s.Sum),
+			}
+			var below uint64
+			for _, b := range s.Buckets {
+				p.ExplicitBounds = append(p.ExplicitBounds, b.UpperBound)
+				p.BucketCounts = append(p.BucketCounts, b.Count-below)
+				below = b.Count
+			}
+			p.BucketCounts = append(p.BucketCounts, s.Count-below)
+			data.Histogram.DataPoints = append(data.Histogram.DataPoints, p)
+		}
+	}
+	return res
+}
+
+// attributes returns the labels as attributes, sorted by key.
+func attributes(labels metrics.Labels) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, attribute(k, labels[k]))
+	}
+	return res
+}
+
+func attribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
@@ -0,0 +1,239 @@
+package otlp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/otlp"
+)
+
+// newApp returns an App for a test, named api, at version 1.2.3, on the host web-1.
+func newApp(t *testing.T, environ ...string) *app.App {
+	a := apptest.New(t, environ)
+	a.Name, a.Version = "api", "1.2.3"
+	a.Identity = &app.Identity{Hostname: "web-1"}
+	return a
+}
+
+func newRegistry() *metrics.Registry {
+	registry := metrics.NewRegistry()
+	registry.Counter("requests_total", metrics.Labels{"method": "GET", "code": "200"}).Add(2)
+	registry.Gauge("in_flight", nil).Set(3)
+	latency := registry.Histogram("latency_seconds", []float64{1, 2}, nil)
+	latency.Observe(0.5)
+	latency.Observe(1.5)
+	latency.Observe(5)
+	return registry
+}
+
+func attrs(kvs []*commonpb.KeyValue) map[string]string {
+	res := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		res[kv.Key] = kv.Value.GetStringValue()
+	}
+	return res
+}
+
+// collector records the requests of an Exporter over HTTP.
+type collector struct {
+	*httptest.Server
+	requests []*http.Request
+	bodies   [][]byte
+	status   int
+}
+
+func newCollector(t *testing.T) *collector {
+	c := &collector{status: http.StatusOK}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		c.requests, c.bodies = append(c.requests, r), append(c.bodies, body)
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestExporter_Flush(t *testing.T) {
+	c := newCollector(t)
+	config := otlp.DefaultConfig()
+	config.Endpoint = c.URL + "/v1/metrics"
+	config.Headers = map[string]string{"Api-Key": "secret"}
+	config.Resource = map[string]string{"deployment.environment": "prod"}
+
+	e := otlp.New(config, newRegistry())
+	require.NoError(t, e.Bind(newApp(t)))
+	require.NoError(t, e.Flush(context.Background()))
+
+	require.Len(t, c.requests, 1)
+	assert.Equal(t, "/v1/metrics", c.requests[0].URL.Path)
+	assert.Equal(t, "application/x-protobuf", c.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "secret", c.requests[0].Header.Get("Api-Key"))
+
+	var req colmetricspb.ExportMetricsServiceRequest
+	require.NoError(t, proto.Unmarshal(c.bodies[0], &req))
+	require.Len(t, req.ResourceMetrics, 1)
+	resource := attrs(req.ResourceMetrics[0].Resource.Attributes)
+	assert.Equal(t, "api", resource["service.name"])
+	assert.Equal(t, "1.2.3", resource["service.version"])
+	assert.Equal(t, "web-1", resource["host.name"])
+	assert.Equal(t, "prod", resource["deployment.environment"])
+
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1)
+	ms := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, ms, 3)
+
+	assert.Equal(t, "in_flight", ms[0].Name)
+	assert.Equal(t, 3.0, ms[0].GetGauge().DataPoints[0].GetAsDouble())
+
+	assert.Equal(t, "latency_seconds", ms[1].Name)
+	histogram := ms[1].GetHistogram()
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, histogram.AggregationTemporality)
+	p := histogram.DataPoints[0]
+	assert.Equal(t, uint64(3), p.Count)
+	assert.Equal(t, 7.0, p.GetSum())
+	assert.Equal(t, []float64{1, 2}, p.ExplicitBounds)
+	assert.Equal(t, []uint64{1, 1, 1}, p.BucketCounts)
+
+	assert.Equal(t, "requests_total", ms[2].Name)
+	sum := ms[2].GetSum()
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, 2.0, sum.DataPoints[0].GetAsDouble())
+	assert.Equal(t, map[string]string{"code": "200", "method": "GET"}, attrs(sum.DataPoints[0].Attributes))
+	assert.NotZero(t, sum.DataPoints[0].StartTimeUnixNano)
+	assert.LessOrEqual(t, sum.DataPoints[0].StartTimeUnixNano, sum.DataPoints[0].TimeUnixNano)
+}
+
+func TestExporter_Flush_JSON(t *testing.T) {
+	c := newCollector(t)
+	config := otlp.DefaultConfig()
+	config.Protocol = otlp.ProtocolHTTPJSON
+	config.Compression = "gzip"
+	config.Endpoint = c.URL + "/v1/metrics"
+
+	e := otlp.New(config, newRegistry())
+	require.NoError(t, e.Bind(newApp(t)))
+	require.NoError(t, e.Flush(context.Background()))
+
+	require.Len(t, c.requests, 1)
+	assert.Equal(t, "application/json", c.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "gzip", c.requests[0].Header.Get("Content-Encoding"))
+	zr, err := gzip.NewReader(bytes.NewReader(c.bodies[0]))
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var req colmetricspb.ExportMetricsServiceRequest
+	require.NoError(t, protojson.Unmarshal(body, &req))
+	assert.Len(t, req.ResourceMetrics[0].ScopeMetrics[0].Metrics, 3)
+
+	c.status = http.StatusServiceUnavailable
+	assert.EqualError(t, e.Flush(context.Background()),
+		"otlp: "+config.Endpoint+" answered 503 Service Unavailable")
+}
+
+// metricsService is a collector over gRPC.
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+	apiKeys  chan []string
+}
+
+func (s *metricsService) Export(
+	ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest,
+) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.apiKeys <- md.Get("api-key")
+	s.requests <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func TestExporter_GRPC(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	service := &metricsService{
+		requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 1),
+		apiKeys:  make(chan []string, 1),
+	}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, service)
+	go func() { _ = server.Serve(l) }()
+	defer server.Stop()
+
+	config := otlp.DefaultConfig()
+	config.Protocol = otlp.ProtocolGRPC
+	config.Endpoint = "http://" + l.Addr().String()
+	config.Headers = map[string]string{"api-key": "secret"}
+	config.Compression = "gzip"
+
+	a := newApp(t)
+	// Exit closes the connection to the collector
+	defer assert.PanicsWithValue(t, "system exit 0", func() { a.Exit(0) })
+	e := otlp.New(config, newRegistry())
+	require.NoError(t, e.Bind(a))
+	require.NoError(t, e.Shutdown(context.Background()))
+
+	assert.Equal(t, []string{"secret"}, <-service.apiKeys)
+	req := <-service.requests
+	assert.Equal(t, "api", attrs(req.ResourceMetrics[0].Resource.Attributes)["service.name"])
+	assert.Len(t, req.ResourceMetrics[0].ScopeMetrics[0].Metrics, 3)
+}
+
+func TestExporter_Disabled(t *testing.T) {
+	c := newCollector(t)
+	config := otlp.DefaultConfig()
+	config.Endpoint = c.URL + "/v1/metrics"
+	config.Enabled = false
+
+	e := otlp.New(config, newRegistry())
+	assert.EqualError(t, e.Flush(context.Background()), "otlp: the Exporter is not bound")
+	require.NoError(t, e.Bind(newApp(t)))
+
+	served := make(chan error, 1)
+	go func() { served <- e.Serve() }()
+	require.NoError(t, e.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+	assert.Empty(t, c.requests)
+}
+
+func TestResource(t *testing.T) {
+	a := newApp(t,
+		"KUBERNETES_SERVICE_HOST=10.0.0.1",
+		"POD_NAME=api-7d9f",
+		"POD_NAMESPACE=shop",
+		"NODE_NAME=node-1",
+	)
+	config := &otlp.Config{ServiceName: "checkout", Resource: map[string]string{"host.name": "override"}}
+
+	attrs := otlp.Resource(a, config)
+	assert.Equal(t, "checkout", attrs["service.name"])
+	assert.Equal(t, "1.2.3", attrs["service.version"])
+	assert.Equal(t, "override", attrs["host.name"])
+	assert.Equal(t, "api-7d9f", attrs["k8s.pod.name"])
+	assert.Equal(t, "shop", attrs["k8s.namespace.name"])
+	assert.Equal(t, "node-1", attrs["k8s.node.name"])
+	assert.NotContains(t, attrs, "k8s.pod.uid")
+	assert.NotEmpty(t, attrs["process.pid"])
+
+	a.Name = ""
+	assert.Contains(t, otlp.Resource(a, &otlp.Config{})["service.name"], "unknown_service:")
+}
@@ -0,0 +1,51 @@
+package otlp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Resource returns the attributes of the resource the app is, described with the semantic conventions of
+// OpenTelemetry: the service.name, the Name of the app unless config has a ServiceName, its service.version, its
+// host.name, its process.pid, and, in Kubernetes, the k8s.pod.name, k8s.pod.uid, k8s.namespace.name, and
+// k8s.node.name of its pod; followed by the Resource of config, which overrides them. Giving the same attributes to
+// the traces and the logs of the app, as the Exporter gives to its metrics, lets the collector correlate them.
+func Resource(a *app.App, config *Config) map[string]string {
+	attrs := map[string]string{
+		"service.name":           a.Name,
+		"process.pid":            strconv.Itoa(os.Getpid()),
+		"telemetry.sdk.language": "go",
+	}
+	if a.Name == "" {
+		attrs["service.name"] = "unknown_service:" + filepath.Base(os.Args[0])
+	}
+	if a.Version != "" {
+		attrs["service.version"] = a.Version
+	}
+	if host, err := a.Hostname(); err == nil && host != "" {
+		attrs["host.name"] = host
+	}
+	if pod := a.Pod(); pod != nil {
+		for key, v := range map[string]string{
+			"k8s.pod.name":       pod.Name,
+			"k8s.pod.uid":        pod.UID,
+			"k8s.namespace.name": pod.Namespace,
+			"k8s.node.name":      pod.NodeName,
+		} {
+			if v != "" {
+				attrs[key] = v
+			}
+		}
+	}
+
+	for key, v := range config.Resource {
+		attrs[key] = v
+	}
+	if config.ServiceName != "" {
+		attrs["service.name"] = config.ServiceName
+	}
+	return attrs
+}
@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the stage settings, as in APP_PIPELINE_BUFFER.
+	DefaultPrefix = "APP_PIPELINE_"
+
+	// DefaultBuffer is the number of items buffered between two stages.
+	DefaultBuffer = 64
+
+	// DefaultProgressInterval is how often Wait reports progress to the OnProgress function.
+	DefaultProgressInterval = time.Second
+)
+
+// Policy decides what happens to the pipeline when a stage fails to process an item.
+type Policy int
+
+const (
+	// Abort cancels the pipeline on the first error, which Wait returns.
+	Abort Policy = iota
+
+	// Skip drops the failed item and carries on. The failure is only counted in the stage Stats.
+	Skip
+
+	// Collect drops the failed item and carries on, and Wait returns every error once the pipeline completes.
+	Collect
+)
+
+// ParsePolicy returns the Policy named by s: abort, skip, or collect.
+func ParsePolicy(s string) (Policy, error) {
+	switch strings.ToLower(s) {
+	case "abort":
+		return Abort, nil
+	case "skip":
+		return Skip, nil
+	case "collect":
+		return Collect, nil
+	default:
+		return 0, fmt.Errorf("pipeline: unknown policy %q", s)
+	}
+}
+
+func (p Policy) String() string {
+	switch p {
+	case Abort:
+		return "abort"
+	case Skip:
+		return "skip"
+	case Collect:
+		return "collect"
+	default:
+		return "Policy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// Config describes how a Pipeline moves items between stages.
+type Config struct {
+	Buffer           int
+	Policy           Policy
+	ProgressInterval time.Duration
+}
+
+// DefaultConfig returns a Config with DefaultBuffer items between the stages, aborting on the first failed item.
+func DefaultConfig() *Config {
+	return &Config{
+		Buffer:           DefaultBuffer,
+		Policy:           Abort,
+		ProgressInterval: DefaultProgressInterval,
+	}
+}
+
+func init() {
+	configschema.Register("pipeline", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the pipeline variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "BUFFER", Type: "int", Default: strconv.Itoa(DefaultBuffer),
+			Description: "The items buffered between two stages."},
+		{Name: prefix + "POLICY", Type: "string", Default: Abort.String(),
+			Description: "What a failed item does: abort, skip, or collect."},
+		{Name: prefix + "PROGRESS_INTERVAL", Type: "duration", Default: DefaultProgressInterval.String(),
+			Description: "How often progress is reported."},
+	}
+}
+
+// FromEnv reads the BUFFER between the stages, the POLICY for failed items, abort, skip, or collect, and the
+// PROGRESS_INTERVAL, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("BUFFER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("pipeline: invalid %sBUFFER %q", prefix, v)
+		}
+		config.Buffer = n
+	}
+
+	if v := get("POLICY"); v != "" {
+		p, err := ParsePolicy(v)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: invalid %sPOLICY %q", prefix, v)
+		}
+		config.Policy = p
+	}
+
+	if v := get("PROGRESS_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("pipeline: invalid %sPROGRESS_INTERVAL %q", prefix, v)
+		}
+		config.ProgressInterval = d
+	}
+
+	return config, nil
+}
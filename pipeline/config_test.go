@@ -0,0 +1,60 @@
+package pipeline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/pipeline"
+)
+
+func TestFromEnv_Buffer(t *testing.T) {
+	// zero leaves the stages unbuffered, so each item is handed over in lockstep
+	config, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"ETL_BUFFER": "0"}), "ETL_")
+	require.NoError(t, err)
+	assert.Zero(t, config.Buffer)
+
+	for _, v := range []string{"-1", "1k"} {
+		_, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"APP_PIPELINE_BUFFER": v}), "")
+		assert.EqualError(t, err, `pipeline: invalid APP_PIPELINE_BUFFER "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Policy(t *testing.T) {
+	for v, expected := range map[string]pipeline.Policy{
+		"abort":   pipeline.Abort,
+		" Skip ":  pipeline.Skip,
+		"COLLECT": pipeline.Collect,
+	} {
+		config, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"APP_PIPELINE_POLICY": v}), "")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.Policy, v)
+	}
+
+	// the error names the variable, not just the policy
+	_, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"APP_PIPELINE_POLICY": "retry"}), "")
+	assert.EqualError(t, err, `pipeline: invalid APP_PIPELINE_POLICY "retry"`)
+}
+
+func TestFromEnv_ProgressInterval(t *testing.T) {
+	config, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"APP_PIPELINE_PROGRESS_INTERVAL": "5s"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.ProgressInterval)
+
+	for _, v := range []string{"0s", "-5s", "5"} {
+		_, err := pipeline.FromEnv(apptest.Lookup(map[string]string{"APP_PIPELINE_PROGRESS_INTERVAL": v}), "")
+		assert.EqualError(t, err, `pipeline: invalid APP_PIPELINE_PROGRESS_INTERVAL "`+v+`"`, v)
+	}
+}
+
+func TestPolicy_String(t *testing.T) {
+	for _, p := range []pipeline.Policy{pipeline.Abort, pipeline.Skip, pipeline.Collect} {
+		parsed, err := pipeline.ParsePolicy(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, p, parsed)
+	}
+	assert.Equal(t, "Policy(7)", pipeline.Policy(7).String())
+}
@@ -0,0 +1,342 @@
+// Package pipeline runs batch work as a chain of stages connected by bounded channels: a source emits items, any
+// number of Map stages transform them with their own concurrency, and a sink consumes them.
+//
+//	p := pipeline.New(ctx, config)
+//	lines := pipeline.From(p, "read", readLines)
+//	records := pipeline.Map(lines, "parse", 4, parse)
+//	pipeline.To(records, "store", 2, store)
+//	err := p.Wait()
+//
+// Every stage must be consumed by exactly one Map or To, otherwise the pipeline stalls once the buffer fills. The
+// Config Policy decides whether a failed item aborts the pipeline, is skipped, or is collected into the error Wait
+// returns.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDrop may be returned by a Map function to filter the item out without counting a failure.
+var ErrDrop = errors.New("pipeline: drop item")
+
+// Stats counts the items a stage has received, passed on, and failed to process.
+type Stats struct {
+	Stage  string
+	In     int64
+	Out    int64
+	Failed int64
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%s: %d in, %d out, %d failed", s.Stage, s.In, s.Out, s.Failed)
+}
+
+type stage struct {
+	name   string
+	in     atomic.Int64
+	out    atomic.Int64
+	failed atomic.Int64
+}
+
+// Pipeline owns the stages of a batch job. Create one with New, add stages with From, Map, and To, then call Wait.
+type Pipeline struct {
+	config *Config
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	stages   []*stage
+	err      error
+	errs     []error
+	progress func([]Stats)
+}
+
+// New returns an empty Pipeline. Canceling ctx stops every stage.
+func New(ctx context.Context, config *Config) *Pipeline {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	p := &Pipeline{config: config, parent: ctx}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	return p
+}
+
+// OnProgress sets a function that Wait calls every ProgressInterval, and once more when the pipeline completes.
+func (p *Pipeline) OnProgress(fn func([]Stats)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.progress = fn
+}
+
+// Stats returns the counters of every stage, in the order the stages were added.
+func (p *Pipeline) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]Stats, len(p.stages))
+	for idx, s := range p.stages {
+		stats[idx] = Stats{Stage: s.name, In: s.in.Load(), Out: s.out.Load(), Failed: s.failed.Load()}
+	}
+	return stats
+}
+
+// Wait blocks until every stage has finished. It returns the error that aborted the pipeline, the errors collected
+// under the Collect policy joined together, or the context error if the pipeline was canceled.
+func (p *Pipeline) Wait() error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	p.mu.Lock()
+	progress := p.progress
+	p.mu.Unlock()
+
+	if progress != nil && p.config.ProgressInterval > 0 {
+		ticker := time.NewTicker(p.config.ProgressInterval)
+		defer ticker.Stop()
+
+	loop:
+		for {
+			select {
+			case <-done:
+				break loop
+			case <-ticker.C:
+				progress(p.Stats())
+			}
+		}
+	}
+
+	<-done
+	p.cancel()
+	if progress != nil {
+		progress(p.Stats())
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case p.err != nil:
+		return p.err
+	case len(p.errs) > 0:
+		return errors.Join(p.errs...)
+	default:
+		return p.parent.Err()
+	}
+}
+
+// Progress returns an OnProgress function that writes a line with the Stats of every stage to w.
+func Progress(w io.Writer) func([]Stats) {
+	return func(stats []Stats) {
+		parts := make([]string, len(stats))
+		for idx, s := range stats {
+			parts[idx] = s.String()
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(parts, "; "))
+	}
+}
+
+func (p *Pipeline) add(name string) *stage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &stage{name: name}
+	p.stages = append(p.stages, s)
+	return s
+}
+
+// abort cancels the pipeline, keeping the first error.
+func (p *Pipeline) abort(err error) {
+	p.mu.Lock()
+	if p.err == nil {
+		p.err = err
+	}
+	p.mu.Unlock()
+
+	p.cancel()
+}
+
+// fail applies the Policy to an item that failed, returning whether the stage should carry on.
+func (p *Pipeline) fail(s *stage, err error) bool {
+	s.failed.Add(1)
+	err = fmt.Errorf("pipeline: %s: %w", s.name, err)
+
+	switch p.config.Policy {
+	case Skip:
+		return true
+	case Collect:
+		p.mu.Lock()
+		p.errs = append(p.errs, err)
+		p.mu.Unlock()
+		return true
+	default:
+		p.abort(err)
+		return false
+	}
+}
+
+// Stage is the output of a source or a Map stage.
+type Stage[T any] struct {
+	p   *Pipeline
+	out chan T
+}
+
+// From adds a source stage. The function calls emit for every item it produces; emit returns the context error once
+// the pipeline is canceled, which the function should return. An error returned by the source always aborts the
+// pipeline, whatever the Policy.
+func From[T any](p *Pipeline, name string, fn func(ctx context.Context, emit func(T) error) error) *Stage[T] {
+	s := p.add(name)
+	out := make(chan T, p.config.Buffer)
+
+	emit := func(v T) error {
+		s.in.Add(1)
+		select {
+		case out <- v:
+			s.out.Add(1)
+			return nil
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+
+		if err := call(func() error { return fn(p.ctx, emit) }); err != nil && p.ctx.Err() == nil {
+			s.failed.Add(1)
+			p.abort(fmt.Errorf("pipeline: %s: %w", name, err))
+		}
+	}()
+
+	return &Stage[T]{p: p, out: out}
+}
+
+// FromSlice adds a source stage that emits the items in order.
+func FromSlice[T any](p *Pipeline, name string, items []T) *Stage[T] {
+	return From(p, name, func(ctx context.Context, emit func(T) error) error {
+		for _, v := range items {
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Map adds a stage that transforms each item with fn using the given number of workers, so items may be reordered
+// when workers is greater than one.
+func Map[In, Out any](
+	in *Stage[In],
+	name string,
+	workers int,
+	fn func(ctx context.Context, v In) (Out, error),
+) *Stage[Out] {
+	p := in.p
+	s := p.add(name)
+	out := make(chan Out, p.config.Buffer)
+
+	var wg sync.WaitGroup
+	run(p, s, in.out, workers, &wg, func(v In) bool {
+		var res Out
+		err := call(func() (err error) {
+			res, err = fn(p.ctx, v)
+			return err
+		})
+		switch {
+		case errors.Is(err, ErrDrop):
+			return true
+		case err != nil:
+			return p.fail(s, err)
+		}
+
+		select {
+		case out <- res:
+			s.out.Add(1)
+			return true
+		case <-p.ctx.Done():
+			return false
+		}
+	})
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		close(out)
+	}()
+
+	return &Stage[Out]{p: p, out: out}
+}
+
+// To adds the sink stage that consumes each item with fn using the given number of workers.
+func To[T any](in *Stage[T], name string, workers int, fn func(ctx context.Context, v T) error) {
+	p := in.p
+	s := p.add(name)
+
+	var wg sync.WaitGroup
+	run(p, s, in.out, workers, &wg, func(v T) bool {
+		if err := call(func() error { return fn(p.ctx, v) }); err != nil {
+			return p.fail(s, err)
+		}
+		s.out.Add(1)
+		return true
+	})
+}
+
+// run starts the workers of a stage, each handling items from in until it is closed, the pipeline is canceled, or
+// handle returns false.
+func run[T any](p *Pipeline, s *stage, in <-chan T, workers int, wg *sync.WaitGroup, handle func(T) bool) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	wg.Add(workers)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			defer wg.Done()
+
+			for {
+				select {
+				case <-p.ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					s.in.Add(1)
+					if !handle(v) {
+						return
+					}
+				}
+			}
+		}()
+	}
+}
+
+// call runs fn, turning a panic into an error.
+func call(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn()
+}
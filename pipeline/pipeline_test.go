@@ -0,0 +1,158 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/pipeline"
+)
+
+type sink struct {
+	mu    sync.Mutex
+	items []int
+}
+
+func (s *sink) store(ctx context.Context, v int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, v)
+	return nil
+}
+
+func (s *sink) sorted() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Ints(s.items)
+	return s.items
+}
+
+func parse(ctx context.Context, v string) (int, error) {
+	if v == "even" {
+		return 0, pipeline.ErrDrop
+	}
+	if v == "panic" {
+		panic("bad input")
+	}
+	return strconv.Atoi(v)
+}
+
+func newPipeline(policy pipeline.Policy) *pipeline.Pipeline {
+	config := pipeline.DefaultConfig()
+	config.Buffer = 1
+	config.Policy = policy
+	return pipeline.New(context.Background(), config)
+}
+
+func TestPipeline(t *testing.T) {
+	p := newPipeline(pipeline.Abort)
+	out := new(bytes.Buffer)
+	p.OnProgress(pipeline.Progress(out))
+
+	var s sink
+	lines := pipeline.FromSlice(p, "read", []string{"1", "even", "3", "5"})
+	numbers := pipeline.Map(lines, "parse", 3, parse)
+	squares := pipeline.Map(numbers, "square", 2, func(ctx context.Context, v int) (int, error) { return v * v, nil })
+	pipeline.To(squares, "store", 1, s.store)
+
+	assert.NoError(t, p.Wait())
+	assert.Equal(t, []int{1, 9, 25}, s.sorted())
+	assert.Equal(t, []pipeline.Stats{
+		{Stage: "read", In: 4, Out: 4},
+		{Stage: "parse", In: 4, Out: 3},
+		{Stage: "square", In: 3, Out: 3},
+		{Stage: "store", In: 3, Out: 3},
+	}, p.Stats())
+	assert.Equal(t, "read: 4 in, 4 out, 0 failed; parse: 4 in, 3 out, 0 failed; "+
+		"square: 3 in, 3 out, 0 failed; store: 3 in, 3 out, 0 failed\n", out.String())
+}
+
+func TestPipeline_Abort(t *testing.T) {
+	p := newPipeline(pipeline.Abort)
+
+	var s sink
+	lines := pipeline.From(p, "read", func(ctx context.Context, emit func(string) error) error {
+		for i := 0; ; i++ {
+			v := strconv.Itoa(i)
+			if i == 10 {
+				v = "ten"
+			}
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+	})
+	pipeline.To(pipeline.Map(lines, "parse", 1, parse), "store", 1, s.store)
+
+	err := p.Wait()
+	assert.EqualError(t, err, `pipeline: parse: strconv.Atoi: parsing "ten": invalid syntax`)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+	assert.Equal(t, int64(1), p.Stats()[1].Failed)
+}
+
+func TestPipeline_Skip(t *testing.T) {
+	p := newPipeline(pipeline.Skip)
+
+	var s sink
+	lines := pipeline.FromSlice(p, "read", []string{"1", "x", "panic", "2"})
+	pipeline.To(pipeline.Map(lines, "parse", 2, parse), "store", 1, s.store)
+
+	assert.NoError(t, p.Wait())
+	assert.Equal(t, []int{1, 2}, s.sorted())
+	assert.Equal(t, pipeline.Stats{Stage: "parse", In: 4, Out: 2, Failed: 2}, p.Stats()[1])
+}
+
+func TestPipeline_Collect(t *testing.T) {
+	p := newPipeline(pipeline.Collect)
+
+	lines := pipeline.FromSlice(p, "read", []string{"1", "panic", "2"})
+	pipeline.To(pipeline.Map(lines, "parse", 1, parse), "store", 1, func(ctx context.Context, v int) error {
+		if v == 2 {
+			return fmt.Errorf("duplicate %d", v)
+		}
+		return nil
+	})
+
+	assert.EqualError(t, p.Wait(), "pipeline: parse: panic: bad input\npipeline: store: duplicate 2")
+}
+
+func TestPipeline_SourceError(t *testing.T) {
+	p := newPipeline(pipeline.Skip)
+
+	boom := errors.New("connection reset")
+	lines := pipeline.From(p, "read", func(ctx context.Context, emit func(int) error) error {
+		_ = emit(1)
+		return boom
+	})
+	pipeline.To(lines, "store", 1, func(ctx context.Context, v int) error { return nil })
+
+	err := p.Wait()
+	assert.ErrorIs(t, err, boom)
+	assert.EqualError(t, err, "pipeline: read: connection reset")
+}
+
+func TestPipeline_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := pipeline.New(ctx, nil)
+
+	lines := pipeline.From(p, "read", func(ctx context.Context, emit func(int) error) error {
+		for {
+			if err := emit(1); err != nil {
+				return err
+			}
+		}
+	})
+	pipeline.To(lines, "store", 4, func(ctx context.Context, v int) error {
+		cancel()
+		return nil
+	})
+
+	assert.Equal(t, context.Canceled, p.Wait())
+}
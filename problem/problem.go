@@ -0,0 +1,205 @@
+// Package problem renders the errors of HTTP handlers, and the panics they recover from, as RFC 7807 problem details
+// with the application/problem+json media type:
+//
+//	p := problem.NewMiddleware(a)
+//	mux.Handle("GET /orders/{id}", p.Handler(func(w http.ResponseWriter, r *http.Request) error {
+//		order, err := store.Order(r.Context(), r.PathValue("id"))
+//		if errors.Is(err, store.ErrNotFound) {
+//			return problem.New(http.StatusNotFound, "no order %s", r.PathValue("id"))
+//		}
+//		if err != nil {
+//			return err // 500 Internal Server Error
+//		}
+//		return json.NewEncoder(w).Encode(order)
+//	}))
+//	handler := correlation.Middleware(p.Wrap(mux))
+//
+// A Problem is rendered as it is. Other errors and panics are answered with 500 Internal Server Error and logged with
+// the request ID, which the response carries so that the log can be found from a report. Their message is only shown
+// when the app runs for development, with the stack trace of the panic or of the error, if it has one.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/correlation"
+)
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Problem is an error with the problem details of RFC 7807.
+type Problem struct {
+	Type     string `json:"type,omitempty"`     // a URI identifying the kind of problem; about:blank if empty
+	Title    string `json:"title,omitempty"`    // a summary of the kind of problem, the status text if empty
+	Status   int    `json:"status,omitempty"`   // the HTTP status code
+	Detail   string `json:"detail,omitempty"`   // an explanation of this occurrence of the problem
+	Instance string `json:"instance,omitempty"` // a URI identifying this occurrence of the problem
+
+	RequestID string `json:"request_id,omitempty"` // the request ID, see correlation
+	Stack     string `json:"stack,omitempty"`      // the stack trace, in development only
+
+	// Extensions are additional members of the problem details, such as the invalid fields of a request.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// New returns a Problem with the status code and the detail.
+func New(status int, format string, args ...interface{}) *Problem {
+	return &Problem{Title: http.StatusText(status), Status: status, Detail: fmt.Sprintf(format, args...)}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// MarshalJSON encodes the problem details with their Extensions.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	b, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions)+7)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// Write writes p as the response.
+func Write(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// HandlerFunc is an HTTP handler returning an error for the Middleware to render.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Middleware renders the errors of handlers and the panics they recover from as problem details.
+type Middleware struct {
+	a           *app.App
+	development bool
+}
+
+// NewMiddleware returns Middleware logging with the app Logger, and showing the internals of errors when the app runs
+// for Development.
+func NewMiddleware(a *app.App) *Middleware {
+	return &Middleware{a: a, development: a.Development()}
+}
+
+// Wrap returns a handler calling next and answering the panics it recovers from with 500 Internal Server Error.
+// http.ErrAbortHandler is left to the server.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &writer{ResponseWriter: w}
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				m.error(pw, r, fmt.Errorf("panic: %v", v), string(debug.Stack()))
+			}
+		}()
+		next.ServeHTTP(pw, r)
+	})
+}
+
+// Handler returns a handler calling fn, and rendering the error it returns or the panic it recovers from.
+func (m *Middleware) Handler(fn HandlerFunc) http.Handler {
+	return m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			m.Error(w, r, err)
+		}
+	}))
+}
+
+// Error answers r with err: as it is if err is a Problem, and with 500 Internal Server Error otherwise.
+func (m *Middleware) Error(w http.ResponseWriter, r *http.Request, err error) {
+	var stack string
+	var st interface{ StackTrace() string }
+	if errors.As(err, &st) {
+		stack = st.StackTrace()
+	}
+	m.error(w, r, err, stack)
+}
+
+func (m *Middleware) error(w http.ResponseWriter, r *http.Request, err error, stack string) {
+	id, ok := correlation.FromContext(r.Context())
+	if !ok {
+		id = correlation.New().String()
+	}
+
+	var p *Problem
+	if errors.As(err, &p) {
+		copied := *p
+		p = &copied
+	} else {
+		p = New(http.StatusInternalServerError, "")
+		if m.development {
+			p.Detail, p.Stack = err.Error(), stack
+		}
+	}
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.RequestID = id
+
+	if p.Status >= http.StatusInternalServerError {
+		attrs := map[string]interface{}{
+			"request_id": id,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"error":      err.Error(),
+		}
+		if stack != "" {
+			attrs["stack"] = stack
+		}
+		_ = m.a.Logger().Errorm(gomol.NewAttrsFromMap(attrs), "error serving %s %s", r.Method, r.URL.Path)
+	}
+
+	// a response already started cannot be replaced; the error is only logged
+	if pw, ok := w.(*writer); ok && pw.wroteHeader {
+		return
+	}
+	Write(w, p)
+}
+
+// writer is a ResponseWriter recording whether the response started.
+type writer struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the ResponseWriter, for http.ResponseController.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
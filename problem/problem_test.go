@@ -0,0 +1,133 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/clierror"
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/problem"
+)
+
+// serve serves a request with the request ID req-1 and decodes the problem details of the response.
+func serve(t *testing.T, h http.Handler) (*httptest.ResponseRecorder, map[string]interface{}) {
+	r := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	r.Header.Set(correlation.Header, "req-1")
+	w := httptest.NewRecorder()
+	correlation.Middleware(h).ServeHTTP(w, r)
+
+	var body map[string]interface{}
+	if w.Header().Get("Content-Type") == problem.ContentType {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	}
+	return w, body
+}
+
+func TestMiddleware_Problem(t *testing.T) {
+	a := apptest.New(t, nil)
+	m := problem.NewMiddleware(a)
+	h := m.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		p := problem.New(http.StatusNotFound, "no order %s", "1")
+		p.Type = "https://example.com/problems/not-found"
+		p.Extensions = map[string]interface{}{"order": "1"}
+		return p
+	})
+
+	w, body := serve(t, h)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"type":       "https://example.com/problems/not-found",
+		"title":      "Not Found",
+		"status":     404.0,
+		"detail":     "no order 1",
+		"request_id": "req-1",
+		"order":      "1",
+	}, body)
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Empty(t, apptest.Stderr(t, a), "client errors are not logged")
+}
+
+func TestMiddleware_Error(t *testing.T) {
+	fail := func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("connection refused")
+	}
+
+	a := apptest.New(t, []string{"APP_ENV=production"})
+	w, body := serve(t, problem.NewMiddleware(a).Handler(fail))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"title":      "Internal Server Error",
+		"status":     500.0,
+		"request_id": "req-1",
+	}, body)
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Contains(t, apptest.Stderr(t, a), "error serving GET /orders/1")
+	assert.Contains(t, apptest.Stderr(t, a), "req-1")
+
+	a = apptest.New(t, []string{"APP_ENV=development"})
+	_, body = serve(t, problem.NewMiddleware(a).Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return clierror.Wrap(errors.New("connection refused"), 1, "unable to load the order")
+	}))
+	assert.Equal(t, "unable to load the order: connection refused", body["detail"])
+	assert.Contains(t, body["stack"], "problem_test.TestMiddleware_Error")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+}
+
+func TestMiddleware_Panic(t *testing.T) {
+	boom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	a := apptest.New(t, nil)
+	w, body := serve(t, problem.NewMiddleware(a).Wrap(boom))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, body, "detail")
+	assert.NotContains(t, body, "stack")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Contains(t, apptest.Stderr(t, a), "panic: boom")
+
+	a = apptest.New(t, []string{"APP_ENV=development"})
+	_, body = serve(t, problem.NewMiddleware(a).Wrap(boom))
+	assert.Equal(t, "panic: boom", body["detail"])
+	assert.Contains(t, body["stack"], "problem_test.TestMiddleware_Panic")
+	require.NoError(t, a.Logger().ShutdownLoggers())
+
+	// a response already started is left as it is
+	a = apptest.New(t, nil)
+	w, _ = serve(t, problem.NewMiddleware(a).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	})))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+	require.NoError(t, a.Logger().ShutdownLoggers())
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(t, problem.NewMiddleware(a).Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic(http.ErrAbortHandler)
+		})))
+	})
+}
+
+func TestMiddleware_NoRequestID(t *testing.T) {
+	a := apptest.New(t, nil)
+	h := problem.NewMiddleware(a).Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("boom")
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	_, err := correlation.Parse(p.RequestID)
+	assert.NoError(t, err)
+	require.NoError(t, a.Logger().ShutdownLoggers())
+}
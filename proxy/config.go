@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the upstream and retry settings, as in APP_PROXY_UPSTREAM.
+	DefaultPrefix = "APP_PROXY_"
+
+	// DefaultTimeout is how long each attempt waits for the upstream response headers.
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRetryBackoff is the delay before the first retry.
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Config describes the upstream a Proxy forwards to and how requests are rewritten and retried.
+type Config struct {
+	Upstream     *url.URL      // base URL requests are forwarded to
+	Timeout      time.Duration // per attempt limit on waiting for the upstream response headers
+	Retries      int           // additional attempts for idempotent requests that fail or return 502, 503, or 504
+	RetryBackoff time.Duration // delay before the first retry, doubled for every retry after it
+	PassHeaders  []string      // when not empty, only these request headers are forwarded
+	StripHeaders []string      // request headers removed before forwarding
+}
+
+func init() {
+	configschema.Register("proxy", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the reverse proxy variables with the prefix, such as for App.DeclareConfig for a second
+// upstream.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "UPSTREAM", Type: "url", Description: "The URL requests are forwarded to; required."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long each attempt waits for the response headers."},
+		{Name: prefix + "RETRIES", Type: "int", Default: "0",
+			Description: "The attempts made again for idempotent requests that failed."},
+		{Name: prefix + "RETRY_BACKOFF", Type: "duration", Default: DefaultRetryBackoff.String(),
+			Description: "The wait before the first retry."},
+		{Name: prefix + "PASS_HEADERS", Type: "string",
+			Description: "The only request headers forwarded, comma separated; all if not set."},
+		{Name: prefix + "STRIP_HEADERS", Type: "string",
+			Description: "The request headers removed before forwarding, comma separated."},
+	}
+}
+
+// FromEnv reads the UPSTREAM the requests go to, how long and how often they are tried, TIMEOUT, RETRIES, and
+// RETRY_BACKOFF, and the comma separated PASS_HEADERS and STRIP_HEADERS, with the prefix or DefaultPrefix. UPSTREAM is
+// required.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{
+		Timeout:      DefaultTimeout,
+		RetryBackoff: DefaultRetryBackoff,
+		PassHeaders:  splitHeaders(get("PASS_HEADERS")),
+		StripHeaders: splitHeaders(get("STRIP_HEADERS")),
+	}
+
+	upstream := get("UPSTREAM")
+	if upstream == "" {
+		return nil, fmt.Errorf("proxy: %sUPSTREAM is required", prefix)
+	}
+
+	u, err := url.Parse(upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("proxy: invalid %sUPSTREAM %q", prefix, upstream)
+	}
+	config.Upstream = u
+
+	for key, dst := range map[string]*time.Duration{
+		"TIMEOUT":       &config.Timeout,
+		"RETRY_BACKOFF": &config.RetryBackoff,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("proxy: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v := get("RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("proxy: invalid %sRETRIES %q", prefix, v)
+		}
+		config.Retries = n
+	}
+
+	return &config, nil
+}
+
+func splitHeaders(v string) []string {
+	var res []string
+	for _, h := range strings.Split(v, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			res = append(res, http.CanonicalHeaderKey(h))
+		}
+	}
+	return res
+}
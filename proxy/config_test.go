@@ -0,0 +1,65 @@
+package proxy_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/proxy"
+)
+
+func TestFromEnv_Upstream(t *testing.T) {
+	config, err := proxy.FromEnv(apptest.Lookup(map[string]string{"SIDECAR_UPSTREAM": " http://backend:8080/api "}), "SIDECAR_")
+	require.NoError(t, err)
+	assert.Equal(t, &proxy.Config{
+		Upstream:     &url.URL{Scheme: "http", Host: "backend:8080", Path: "/api"},
+		Timeout:      proxy.DefaultTimeout,
+		RetryBackoff: proxy.DefaultRetryBackoff,
+	}, config)
+
+	_, err = proxy.FromEnv(apptest.Lookup(nil), "")
+	assert.EqualError(t, err, "proxy: APP_PROXY_UPSTREAM is required")
+
+	// a bare host or path would be taken as a relative URL and forward nowhere
+	for _, v := range []string{"backend", "backend:8080", "/api", "http://"} {
+		_, err := proxy.FromEnv(apptest.Lookup(map[string]string{"APP_PROXY_UPSTREAM": v}), "")
+		assert.EqualError(t, err, `proxy: invalid APP_PROXY_UPSTREAM "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Headers(t *testing.T) {
+	config, err := proxy.FromEnv(apptest.Lookup(map[string]string{
+		"APP_PROXY_UPSTREAM":      "http://backend",
+		"APP_PROXY_PASS_HEADERS":  "accept, authorization,",
+		"APP_PROXY_STRIP_HEADERS": " , cookie ,x-forwarded-user",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Accept", "Authorization"}, config.PassHeaders)
+	assert.Equal(t, []string{"Cookie", "X-Forwarded-User"}, config.StripHeaders)
+}
+
+func TestFromEnv_Retries(t *testing.T) {
+	// zero retries and a zero backoff are allowed: one attempt, or retries in quick succession
+	config, err := proxy.FromEnv(apptest.Lookup(map[string]string{
+		"APP_PROXY_UPSTREAM":      "http://backend",
+		"APP_PROXY_TIMEOUT":       "5s",
+		"APP_PROXY_RETRIES":       "0",
+		"APP_PROXY_RETRY_BACKOFF": "0s",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.Timeout)
+	assert.Zero(t, config.Retries)
+	assert.Zero(t, config.RetryBackoff)
+
+	for key, v := range map[string]string{"TIMEOUT": "soon", "RETRIES": "-1", "RETRY_BACKOFF": "-1s"} {
+		_, err := proxy.FromEnv(apptest.Lookup(map[string]string{
+			"APP_PROXY_UPSTREAM": "http://backend",
+			"APP_PROXY_" + key:   v,
+		}), "")
+		assert.EqualError(t, err, "proxy: invalid APP_PROXY_"+key+` "`+v+`"`)
+	}
+}
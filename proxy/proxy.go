@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// Proxy is an http.Handler forwarding requests to the upstream described by its Config. Every request is logged and
+// recorded in the proxy_requests_total, proxy_request_duration_seconds, and proxy_retries_total metrics.
+type Proxy struct {
+	config   Config
+	logger   gomol.WrappableLogger
+	registry *metrics.Registry
+	proxy    *httputil.ReverseProxy
+}
+
+// New returns a Proxy for config. The logger and registry may be nil.
+func New(config *Config, logger gomol.WrappableLogger, registry *metrics.Registry) *Proxy {
+	p := &Proxy{
+		config:   *config,
+		logger:   logger,
+		registry: registry,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = p.config.Timeout
+
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: p.rewrite,
+		Transport: &retryTransport{
+			base:    transport,
+			retries: p.config.Retries,
+			backoff: p.config.RetryBackoff,
+			retried: p.retried,
+		},
+		ErrorHandler: p.handleError,
+	}
+
+	return p
+}
+
+// ServeHTTP forwards the request to the upstream.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+
+	p.proxy.ServeHTTP(rec, r)
+
+	elapsed := time.Since(start)
+
+	if p.registry != nil {
+		p.registry.Counter("proxy_requests_total", metrics.Labels{
+			"method": r.Method,
+			"code":   strconv.Itoa(rec.status),
+		}).Inc()
+		p.registry.Histogram("proxy_request_duration_seconds", nil, metrics.Labels{
+			"method": r.Method,
+		}).Observe(elapsed.Seconds())
+	}
+
+	p.log(gomol.LevelInfo, gomol.NewAttrsFromMap(map[string]interface{}{
+		"method":   r.Method,
+		"path":     r.URL.RequestURI(),
+		"status":   rec.status,
+		"bytes":    rec.bytes,
+		"duration": elapsed.String(),
+		"remote":   r.RemoteAddr,
+		"upstream": p.config.Upstream.String(),
+	}), "%s %s %d", r.Method, r.URL.Path, rec.status)
+}
+
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	if len(p.config.PassHeaders) > 0 {
+		header := make(http.Header, len(p.config.PassHeaders))
+		for _, k := range p.config.PassHeaders {
+			if v, ok := pr.Out.Header[k]; ok {
+				header[k] = v
+			}
+		}
+		pr.Out.Header = header
+	}
+
+	for _, k := range p.config.StripHeaders {
+		pr.Out.Header.Del(k)
+	}
+
+	pr.SetURL(p.config.Upstream)
+	pr.SetXForwarded()
+}
+
+func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		status = http.StatusGatewayTimeout
+	}
+
+	p.log(gomol.LevelError, nil, "proxy %s %s: %v", r.Method, r.URL.Path, err)
+	w.WriteHeader(status)
+}
+
+func (p *Proxy) retried(r *http.Request) {
+	if p.registry != nil {
+		p.registry.Counter("proxy_retries_total", metrics.Labels{"method": r.Method}).Inc()
+	}
+}
+
+func (p *Proxy) log(level gomol.LogLevel, attrs *gomol.Attrs, msg string, a ...interface{}) {
+	if p.logger != nil {
+		_ = p.logger.Log(level, attrs, msg, a...)
+	}
+}
+
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+	retried func(*http.Request)
+}
+
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := t.base.RoundTrip(r)
+		if attempt >= t.retries || !retryable(r, res, err) {
+			return res, err
+		}
+
+		if res != nil {
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = r.Clone(r.Context())
+			r.Body = body
+		}
+
+		timer := time.NewTimer(t.backoff << uint(attempt))
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+
+		t.retried(r)
+	}
+}
+
+func retryable(r *http.Request, res *http.Response, err error) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+	default:
+		return false
+	}
+
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		// the body has been consumed and cannot be sent again
+		return false
+	}
+
+	if err != nil {
+		return r.Context().Err() == nil
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer, e.g. to flush streamed responses.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,144 @@
+package proxy_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/proxy"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		w.Header().Set("X-Secret", r.Header.Get("X-Secret"))
+		w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-For"))
+		_, _ = w.Write([]byte("upstream"))
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL + "/api")
+	require.NoError(t, err)
+
+	a := app.App{Stderr: new(bytes.Buffer)}
+	registry := metrics.NewRegistry()
+	p := proxy.New(&proxy.Config{
+		Upstream:     u,
+		Timeout:      time.Second,
+		PassHeaders:  []string{"Accept", "Cookie"},
+		StripHeaders: []string{"Cookie"},
+	}, a.Logger(), registry)
+
+	r := httptest.NewRequest("GET", "/users", nil)
+	r.Header.Set("Accept", "application/json")
+	r.Header.Set("Cookie", "session=1")
+	r.Header.Set("X-Secret", "hunter2")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "upstream", w.Body.String())
+	assert.Equal(t, "/api/users", w.Header().Get("X-Path"))
+	assert.Equal(t, "application/json", w.Header().Get("X-Accept"))
+	assert.Empty(t, w.Header().Get("X-Cookie"))
+	assert.Empty(t, w.Header().Get("X-Secret"))
+	assert.Equal(t, "192.0.2.1", w.Header().Get("X-Forwarded"))
+
+	assert.Equal(t, 1.0, registry.Counter("proxy_requests_total", metrics.Labels{"method": "GET", "code": "200"}).Value())
+	assert.Equal(t, uint64(1), registry.Histogram("proxy_request_duration_seconds", nil, metrics.Labels{
+		"method": "GET",
+	}).Count())
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	assert.Contains(t, a.Stderr.(*bytes.Buffer).String(), "GET /users 200")
+}
+
+func TestProxy_Retries(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(append([]byte("ok "), body...))
+	}))
+	defer upstream.Close()
+
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	registry := metrics.NewRegistry()
+	p := proxy.New(&proxy.Config{
+		Upstream:     u,
+		Timeout:      time.Second,
+		Retries:      2,
+		RetryBackoff: time.Millisecond,
+	}, nil, registry)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok ", w.Body.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, 2.0, registry.Counter("proxy_retries_total", metrics.Labels{"method": "GET"}).Value())
+
+	// POST is not idempotent and is never retried
+	atomic.StoreInt32(&calls, 0)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("payload")))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// PUT bodies are replayed on every attempt
+	atomic.StoreInt32(&calls, 0)
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("PUT", "/", nil)
+	r.Body = io.NopCloser(strings.NewReader("payload"))
+	r.ContentLength = int64(len("payload"))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("payload")), nil }
+	p.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok payload", w.Body.String())
+}
+
+func TestProxy_Unavailable(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	upstream.Close()
+
+	p := proxy.New(&proxy.Config{Upstream: u, Retries: 1, RetryBackoff: time.Millisecond}, nil, nil)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestProxy_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer upstream.Close()
+	defer close(release)
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	p := proxy.New(&proxy.Config{Upstream: u, Timeout: 10 * time.Millisecond}, nil, nil)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
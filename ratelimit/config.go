@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+// DefaultPrefix prefixes the limits and how clients are told apart, as in APP_RATELIMIT_RATE.
+const DefaultPrefix = "APP_RATELIMIT_"
+
+// Config describes the limits enforced by a Limiter. Zero values disable the corresponding limit.
+//
+// The KeyHeader is only read from the TrustedProxies, as it must be set by a trusted proxy, such as an API gateway
+// that authenticated the client; otherwise each client could pick a fresh key for every request. With TrustProxy,
+// clients are identified by the X-Forwarded-For header: the right-most address in it that is not one of the
+// TrustedProxies, which the proxy in front of the app appended. If TrustedProxies is set, the header is only read
+// from peers among them.
+type Config struct {
+	Rate           float64        // requests per second allowed for each client
+	Burst          int            // requests a client may make at once before being limited to Rate
+	KeyHeader      string         // header a trusted proxy identifies the client with; the client IP when absent
+	TrustProxy     bool           // identify clients by X-Forwarded-For instead of the peer address
+	TrustedProxies []netip.Prefix // the proxies whose X-Forwarded-For addresses are skipped
+	MaxInFlight    int            // requests served concurrently across all clients
+}
+
+func init() {
+	configschema.Register("ratelimit", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the rate limit variables with the prefix, such as for App.DeclareConfig for the limits of a
+// route group.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "RATE", Type: "float", Default: "0",
+			Description: "The requests per second allowed for each client; 0 for no limit."},
+		{Name: prefix + "BURST", Type: "int",
+			Description: "The requests a client may make at once; RATE rounded up if not set."},
+		{Name: prefix + "KEY_HEADER", Type: "string",
+			Description: "The request header a trusted proxy identifies the client with; requires TRUSTED_PROXIES."},
+		{Name: prefix + "TRUST_PROXY", Type: "bool", Default: "false",
+			Description: "Identify clients by X-Forwarded-For instead of the peer address."},
+		{Name: prefix + "TRUSTED_PROXIES", Type: "string",
+			Description: "The addresses and CIDR blocks of the proxies in front of the app, comma separated."},
+		{Name: prefix + "MAX_IN_FLIGHT", Type: "int", Default: "0",
+			Description: "The requests served at once across all clients; 0 for no limit."},
+	}
+}
+
+// FromEnv reads the limits, RATE, BURST, and MAX_IN_FLIGHT, and how clients are told apart, KEY_HEADER, TRUST_PROXY,
+// and TRUSTED_PROXIES, a comma separated list of addresses and CIDR blocks, with the prefix or DefaultPrefix. BURST
+// defaults to RATE rounded up. KEY_HEADER is refused without TRUSTED_PROXIES to send it.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	var config Config
+
+	if v := get("KEY_HEADER"); v != "" {
+		config.KeyHeader = http.CanonicalHeaderKey(v)
+	}
+
+	if v := get("RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("ratelimit: invalid %sRATE %q", prefix, v)
+		}
+		config.Rate = rate
+	}
+
+	for key, dst := range map[string]*int{"BURST": &config.Burst, "MAX_IN_FLIGHT": &config.MaxInFlight} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("ratelimit: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	if v := get("TRUST_PROXY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: invalid %sTRUST_PROXY %q", prefix, v)
+		}
+		config.TrustProxy = b
+	}
+
+	for _, v := range strings.Split(get("TRUSTED_PROXIES"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		block, err := parsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: invalid %sTRUSTED_PROXIES address %q", prefix, v)
+		}
+		config.TrustedProxies = append(config.TrustedProxies, block)
+	}
+
+	if config.KeyHeader != "" && len(config.TrustedProxies) == 0 {
+		return nil, fmt.Errorf("ratelimit: %sKEY_HEADER requires %sTRUSTED_PROXIES", prefix, prefix)
+	}
+
+	if config.Burst == 0 && config.Rate > 0 {
+		config.Burst = int(config.Rate)
+		if float64(config.Burst) < config.Rate {
+			config.Burst++
+		}
+	}
+
+	return &config, nil
+}
+
+// parsePrefix parses a CIDR block, or a single address as the block of only itself.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
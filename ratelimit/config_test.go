@@ -0,0 +1,72 @@
+package ratelimit_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/ratelimit"
+)
+
+func TestFromEnv_Burst(t *testing.T) {
+	// without BURST a client may spend one second of its rate at once, rounded up
+	for rate, burst := range map[string]int{"0": 0, "0.1": 1, "2.5": 3, "10": 10} {
+		config, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{"API_RATE": rate}), "API_")
+		require.NoError(t, err, rate)
+		assert.Equal(t, burst, config.Burst, rate)
+	}
+
+	config, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{"API_RATE": "10", "API_BURST": "50"}), "API_")
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimit.Config{Rate: 10, Burst: 50}, config)
+
+	// nothing set limits nothing
+	config, err = ratelimit.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, &ratelimit.Config{}, config)
+
+	for key, v := range map[string]string{"RATE": "-1", "BURST": "lots", "MAX_IN_FLIGHT": "1.5"} {
+		_, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{"APP_RATELIMIT_" + key: v}), "")
+		assert.EqualError(t, err, "ratelimit: invalid APP_RATELIMIT_"+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_TrustedProxies(t *testing.T) {
+	// a bare address is a block of one, and the host bits of a block are dropped
+	config, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{
+		"APP_RATELIMIT_TRUST_PROXY":     "true",
+		"APP_RATELIMIT_TRUSTED_PROXIES": "10.1.2.3/8, ::1,,192.168.0.7",
+	}), "")
+	require.NoError(t, err)
+	assert.True(t, config.TrustProxy)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("192.168.0.7/32"),
+	}, config.TrustedProxies)
+
+	for _, v := range []string{"proxy", "10.0.0.0/33", "10.0.0"} {
+		env := map[string]string{"APP_RATELIMIT_TRUSTED_PROXIES": "10.0.0.0/8," + v}
+		_, err := ratelimit.FromEnv(apptest.Lookup(env), "")
+		assert.EqualError(t, err, `ratelimit: invalid APP_RATELIMIT_TRUSTED_PROXIES address "`+v+`"`, v)
+	}
+
+	_, err = ratelimit.FromEnv(apptest.Lookup(map[string]string{"APP_RATELIMIT_TRUST_PROXY": "yes"}), "")
+	assert.EqualError(t, err, `ratelimit: invalid APP_RATELIMIT_TRUST_PROXY "yes"`)
+}
+
+func TestFromEnv_KeyHeader(t *testing.T) {
+	// any client could send the header itself, so it is only read from requests through a trusted proxy
+	_, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{"APP_RATELIMIT_KEY_HEADER": "X-Client"}), "")
+	assert.EqualError(t, err, "ratelimit: APP_RATELIMIT_KEY_HEADER requires APP_RATELIMIT_TRUSTED_PROXIES")
+
+	config, err := ratelimit.FromEnv(apptest.Lookup(map[string]string{
+		"APP_RATELIMIT_KEY_HEADER":      "x-api-key",
+		"APP_RATELIMIT_TRUSTED_PROXIES": "10.0.0.0/8",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "X-Api-Key", config.KeyHeader)
+}
@@ -0,0 +1,11 @@
+package ratelimit
+
+// MaxBuckets bounds the clients a Limiter tracks at once.
+const MaxBuckets = maxBuckets
+
+// Buckets returns the number of clients l tracks.
+func Buckets(l *Limiter) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
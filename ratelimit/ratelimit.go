@@ -0,0 +1,220 @@
+// Package ratelimit provides HTTP middleware limiting the request rate of each client and the number of requests
+// served concurrently.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/jwtauth"
+	"github.com/demosdemon/golang-app-framework/keyauth"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+const (
+	// sweepInterval is how often buckets of idle clients are discarded.
+	sweepInterval = time.Minute
+
+	// maxBuckets bounds the clients tracked at once; past it, the buckets of idle clients are discarded at once, and
+	// then arbitrary ones.
+	maxBuckets = 100000
+)
+
+// Limiter is HTTP middleware enforcing a token bucket per client and a global in-flight request cap. Requests over
+// the client rate are rejected with 429 Too Many Requests and requests over the in-flight cap with 503 Service
+// Unavailable; both carry a Retry-After header.
+//
+// Clients are identified by the name keyauth authenticated or the subject of the token jwtauth verified, so the
+// Limiter goes inside those handlers, and otherwise by their address.
+//
+// Rejections are counted in ratelimit_rejected_total{reason="rate"|"concurrency"} and the number of requests being
+// served is reported by the ratelimit_in_flight gauge.
+type Limiter struct {
+	config   Config
+	registry *metrics.Registry
+	inflight chan struct{}
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a Limiter enforcing config. The registry may be nil.
+func New(config *Config, registry *metrics.Registry) *Limiter {
+	l := &Limiter{
+		config:    *config,
+		registry:  registry,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+
+	if l.config.MaxInFlight > 0 {
+		l.inflight = make(chan struct{}, l.config.MaxInFlight)
+	}
+
+	return l
+}
+
+// Wrap returns a handler enforcing the limits before calling next.
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := l.Allow(l.key(r)); !ok {
+			l.reject(w, "rate", http.StatusTooManyRequests, wait)
+			return
+		}
+
+		if l.inflight != nil {
+			select {
+			case l.inflight <- struct{}{}:
+				defer func() { <-l.inflight }()
+			default:
+				l.reject(w, "concurrency", http.StatusServiceUnavailable, time.Second)
+				return
+			}
+		}
+
+		if l.registry != nil {
+			g := l.registry.Gauge("ratelimit_in_flight", nil)
+			g.Inc()
+			defer g.Dec()
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Allow takes a token from the bucket of the client identified by key. If the bucket is empty, it returns false and
+// how long until a token is available.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	if l.config.Rate <= 0 {
+		return 0, true
+	}
+
+	burst := float64(l.config.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now, burst, false)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.sweep(now, burst, true)
+			for k := range l.buckets {
+				if len(l.buckets) < maxBuckets {
+					break
+				}
+				delete(l.buckets, k)
+			}
+		}
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.config.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.config.Rate * float64(time.Second)), false
+	}
+
+	b.tokens--
+	return 0, true
+}
+
+// sweep discards the buckets that have refilled completely, as they are indistinguishable from new ones, if the
+// sweepInterval has passed since the last sweep or force is set.
+func (l *Limiter) sweep(now time.Time, burst float64, force bool) {
+	if !force && now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.config.Rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// key identifies the client of r: by the identity keyauth or jwtauth authenticated, then by the KeyHeader if a trusted
+// proxy sent it, and otherwise by its address.
+func (l *Limiter) key(r *http.Request) string {
+	if client, ok := keyauth.FromContext(r.Context()); ok {
+		return "client:" + client
+	}
+	if claims, ok := jwtauth.FromContext(r.Context()); ok && claims.Subject() != "" {
+		return "sub:" + claims.Issuer() + " " + claims.Subject()
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if l.config.KeyHeader != "" && len(l.config.TrustedProxies) > 0 && l.trusted(host) {
+		if v := r.Header.Get(l.config.KeyHeader); v != "" {
+			return "key:" + v
+		}
+	}
+
+	if l.config.TrustProxy && (len(l.config.TrustedProxies) == 0 || l.trusted(host)) {
+		// the addresses are appended by each proxy, so the right-most one not a trusted proxy is the client
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hop := strings.TrimSpace(hops[i]); hop != "" {
+				host = hop
+				if !l.trusted(hop) {
+					break
+				}
+			}
+		}
+	}
+	return "ip:" + host
+}
+
+// trusted reports whether host is the address of one of the TrustedProxies.
+func (l *Limiter) trusted(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range l.config.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Limiter) reject(w http.ResponseWriter, reason string, status int, wait time.Duration) {
+	if l.registry != nil {
+		l.registry.Counter("ratelimit_rejected_total", metrics.Labels{"reason": reason}).Inc()
+	}
+
+	seconds := int64(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	http.Error(w, http.StatusText(status), status)
+}
@@ -0,0 +1,143 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/jwtauth"
+	"github.com/demosdemon/golang-app-framework/keyauth"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/ratelimit"
+)
+
+func request(h http.Handler, remote string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = remote
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	registry := metrics.NewRegistry()
+	h := ratelimit.New(&ratelimit.Config{Rate: 0.5, Burst: 2}, registry).Wrap(http.NotFoundHandler())
+
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1001").Code)
+
+	w := request(h, "10.0.0.1:1002")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// other clients have their own buckets
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.2:1000").Code)
+
+	assert.Equal(t, 1.0, registry.Counter("ratelimit_rejected_total", metrics.Labels{"reason": "rate"}).Value())
+}
+
+func TestLimiter_KeyHeader(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h := ratelimit.New(&ratelimit.Config{Rate: 1, Burst: 1, KeyHeader: "X-Client", TrustedProxies: trusted}, nil).
+		Wrap(http.NotFoundHandler())
+
+	// a trusted proxy names the client
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000", "X-Client", "a").Code)
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000", "X-Client", "b").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.2:1000", "X-Client", "a").Code)
+
+	// but a client cannot pick its own bucket
+	assert.Equal(t, http.StatusNotFound, request(h, "192.0.2.1:1000", "X-Client", "c").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "192.0.2.1:1000", "X-Client", "d").Code)
+}
+
+func TestLimiter_Authenticated(t *testing.T) {
+	limited := ratelimit.New(&ratelimit.Config{Rate: 1, Burst: 1}, nil).Wrap(http.NotFoundHandler())
+	as := func(ctx func(r *http.Request) *http.Request) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { limited.ServeHTTP(w, ctx(r)) })
+	}
+
+	billing := as(func(r *http.Request) *http.Request {
+		return r.WithContext(keyauth.NewContext(r.Context(), "billing"))
+	})
+	alice := as(func(r *http.Request) *http.Request {
+		return r.WithContext(jwtauth.NewContext(r.Context(), jwtauth.Claims{"iss": "idp", "sub": "alice"}))
+	})
+
+	// the authenticated clients have their own buckets, wherever they call from
+	assert.Equal(t, http.StatusNotFound, request(billing, "10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusNotFound, request(alice, "10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusNotFound, request(limited, "10.0.0.1:1000").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(billing, "10.0.0.2:1000").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(alice, "10.0.0.3:1000").Code)
+}
+
+func TestLimiter_TrustProxy(t *testing.T) {
+	h := ratelimit.New(&ratelimit.Config{Rate: 1, Burst: 1, TrustProxy: true}, nil).Wrap(http.NotFoundHandler())
+
+	// the right-most address, appended by the proxy, identifies the client; the others may be forged
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000", "X-Forwarded-For", "192.0.2.9, 192.0.2.1").Code)
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000", "X-Forwarded-For", "192.0.2.1, 192.0.2.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.9:1000", "X-Forwarded-For", "192.0.2.1").Code)
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h = ratelimit.New(&ratelimit.Config{Rate: 1, Burst: 1, TrustProxy: true, TrustedProxies: trusted}, nil).
+		Wrap(http.NotFoundHandler())
+
+	// the trusted proxies are skipped
+	assert.Equal(t, http.StatusNotFound, request(h, "10.0.0.1:1000", "X-Forwarded-For", "192.0.2.1, 10.0.0.2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.1:1000", "X-Forwarded-For", "192.0.2.1").Code)
+
+	// and only they are believed
+	assert.Equal(t, http.StatusNotFound, request(h, "192.0.2.7:1000", "X-Forwarded-For", "192.0.2.3").Code)
+	assert.Equal(t, http.StatusNotFound, request(h, "192.0.2.8:1000", "X-Forwarded-For", "192.0.2.3").Code)
+}
+
+func TestLimiter_MaxBuckets(t *testing.T) {
+	l := ratelimit.New(&ratelimit.Config{Rate: 1, Burst: 1}, nil)
+	for i := 0; i <= ratelimit.MaxBuckets; i++ {
+		_, ok := l.Allow(strconv.Itoa(i))
+		require.True(t, ok)
+	}
+	assert.Equal(t, ratelimit.MaxBuckets, ratelimit.Buckets(l))
+}
+
+func TestLimiter_MaxInFlight(t *testing.T) {
+	registry := metrics.NewRegistry()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	h := ratelimit.New(&ratelimit.Config{MaxInFlight: 1}, registry).
+		Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			<-release
+		}))
+
+	done := make(chan int)
+	go func() { done <- request(h, "10.0.0.1:1000").Code }()
+	<-entered
+
+	assert.Equal(t, 1.0, registry.Gauge("ratelimit_in_flight", nil).Value())
+
+	w := request(h, "10.0.0.2:1000")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+	assert.Equal(t, 0.0, registry.Gauge("ratelimit_in_flight", nil).Value())
+	assert.Equal(t, 1.0, registry.Counter("ratelimit_rejected_total", metrics.Labels{"reason": "concurrency"}).Value())
+
+	// the slot is released once the request completes
+	go func() { done <- request(h, "10.0.0.2:1000").Code }()
+	<-entered
+	assert.Equal(t, http.StatusOK, <-done)
+}
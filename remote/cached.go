@@ -0,0 +1,70 @@
+package remote
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// Cached is a Provider serving the variables it last read from another Provider for the TTL, so that frequent reads
+// do not reach the store. Values delivered by Watch refresh the cache.
+type Cached struct {
+	provider Provider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	values  map[string]string
+	fetched time.Time
+}
+
+// NewCached returns a Cached wrapping provider.
+func NewCached(provider Provider, ttl time.Duration) *Cached {
+	return &Cached{provider: provider, ttl: ttl, now: time.Now}
+}
+
+// Get returns the cached variables if they were read within the TTL, and reads them from the store otherwise. The
+// caller must not modify the map.
+func (c *Cached) Get(ctx context.Context) (map[string]string, error) {
+	c.mu.Lock()
+	values, fetched := c.values, c.fetched
+	c.mu.Unlock()
+	if values != nil && c.now().Sub(fetched) < c.ttl {
+		return values, nil
+	}
+
+	values, err := c.provider.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.store(values)
+	return values, nil
+}
+
+// Watch watches the store, refreshing the cache with every change.
+func (c *Cached) Watch(ctx context.Context, changed func(map[string]string)) error {
+	return c.provider.Watch(ctx, func(values map[string]string) {
+		c.store(values)
+		changed(values)
+	})
+}
+
+// Check checks the store; it is never cached.
+func (c *Cached) Check(ctx context.Context) error {
+	return c.provider.Check(ctx)
+}
+
+// Invalidate drops the cached variables, so that the next Get reads the store.
+func (c *Cached) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = nil
+}
+
+func (c *Cached) store(values map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = maps.Clone(values)
+	c.fetched = c.now()
+}
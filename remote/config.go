@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the configuration store settings, as in APP_REMOTE_PROVIDER.
+	DefaultPrefix = "APP_REMOTE_"
+
+	// DefaultKeyPrefix is the key prefix under which the configuration is stored.
+	DefaultKeyPrefix = "config/"
+
+	// DefaultTTL is how long Cached serves values before fetching them again.
+	DefaultTTL = 30 * time.Second
+
+	// DefaultTimeout bounds each request to the store, except the long polls watching it.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultHealthInterval is how often a Watcher checks the connection to the store.
+	DefaultHealthInterval = 30 * time.Second
+)
+
+// Providers of remote configuration.
+const (
+	ProviderEtcd   = "etcd"
+	ProviderConsul = "consul"
+)
+
+// Config describes the remote configuration store.
+type Config struct {
+	Provider       string // one of ProviderEtcd or ProviderConsul
+	Endpoint       string // base URL of the etcd gRPC gateway or the Consul HTTP API
+	KeyPrefix      string // keys below the prefix are variables, see the package documentation
+	Username       string // etcd user, authenticates when set
+	Password       string
+	Token          string        // Consul ACL token
+	TTL            time.Duration // how long Open caches the values read; zero disables the cache
+	Timeout        time.Duration
+	HealthInterval time.Duration
+}
+
+// DefaultConfig returns a Config reading the variables under DefaultKeyPrefix and caching them for DefaultTTL; it names
+// no store.
+func DefaultConfig() *Config {
+	return &Config{
+		KeyPrefix:      DefaultKeyPrefix,
+		TTL:            DefaultTTL,
+		Timeout:        DefaultTimeout,
+		HealthInterval: DefaultHealthInterval,
+	}
+}
+
+func init() {
+	configschema.Register("remote", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the remote store variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "PROVIDER", Type: "string", Description: "The store: etcd or consul; required."},
+		{Name: prefix + "ENDPOINT", Type: "url", Description: "The URL of the store; required."},
+		{Name: prefix + "KEY_PREFIX", Type: "string", Default: DefaultKeyPrefix,
+			Description: "The prefix of the keys read as variables."},
+		{Name: prefix + "USERNAME", Type: "string", Description: "The etcd user, if it authenticates."},
+		{Name: prefix + "PASSWORD", Type: "string", Description: "The password of the etcd user.", Secret: true},
+		{Name: prefix + "TOKEN", Type: "string", Description: "The Consul ACL token.", Secret: true},
+		{Name: prefix + "TTL", Type: "duration", Default: DefaultTTL.String(),
+			Description: "How long the values read are cached; 0 disables the cache."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a request to the store may take."},
+		{Name: prefix + "HEALTH_INTERVAL", Type: "duration", Default: DefaultHealthInterval.String(),
+			Description: "How often the store is checked."},
+	}
+}
+
+// FromEnv reads which store the configuration is kept in, PROVIDER and ENDPOINT, both required, the KEY_PREFIX of the
+// variables, the USERNAME and PASSWORD of etcd or the TOKEN of Consul, and the TTL, TIMEOUT, and HEALTH_INTERVAL of the
+// requests, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	switch v := strings.ToLower(get("PROVIDER")); v {
+	case ProviderEtcd, ProviderConsul:
+		config.Provider = v
+	case "":
+		return nil, fmt.Errorf("remote: %sPROVIDER is required", prefix)
+	default:
+		return nil, fmt.Errorf("remote: invalid %sPROVIDER %q", prefix, v)
+	}
+
+	config.Endpoint = get("ENDPOINT")
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("remote: %sENDPOINT is required", prefix)
+	}
+	if u, err := url.Parse(config.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("remote: invalid %sENDPOINT %q", prefix, config.Endpoint)
+	}
+
+	if v, ok := lookup(prefix + "KEY_PREFIX"); ok {
+		config.KeyPrefix = strings.TrimSpace(v)
+	}
+	config.Username = get("USERNAME")
+	// passwords may legitimately begin or end with spaces
+	config.Password, _ = lookup(prefix + "PASSWORD")
+	config.Token = get("TOKEN")
+
+	for key, dst := range map[string]*time.Duration{
+		"TTL":             &config.TTL,
+		"TIMEOUT":         &config.Timeout,
+		"HEALTH_INTERVAL": &config.HealthInterval,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 || (d == 0 && key != "TTL") {
+				return nil, fmt.Errorf("remote: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	return config, nil
+}
+
+// Open returns the Provider described by the config, cached for the config TTL. The client may be nil to use
+// http.DefaultClient.
+func Open(config *Config, client *http.Client) (Provider, error) {
+	var p Provider
+	switch config.Provider {
+	case ProviderEtcd:
+		p = NewEtcd(config, client)
+	case ProviderConsul:
+		p = NewConsul(config, client)
+	default:
+		return nil, fmt.Errorf("remote: unknown provider %q", config.Provider)
+	}
+	if config.TTL > 0 {
+		p = NewCached(p, config.TTL)
+	}
+	return p, nil
+}
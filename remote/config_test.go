@@ -0,0 +1,92 @@
+package remote_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/remote"
+)
+
+func TestFromEnv_Store(t *testing.T) {
+	config, err := remote.FromEnv(apptest.Lookup(map[string]string{
+		"X_PROVIDER": " Consul ",
+		"X_ENDPOINT": "http://127.0.0.1:8500",
+		"X_TOKEN":    " s3cr3t ",
+		"X_USERNAME": " app ",
+		"X_PASSWORD": " s3cr3t ",
+	}), "X_")
+	require.NoError(t, err)
+	assert.Equal(t, remote.ProviderConsul, config.Provider)
+	assert.Equal(t, "s3cr3t", config.Token)
+	assert.Equal(t, "app", config.Username)
+	// the password is kept as is, since it may begin or end with spaces
+	assert.Equal(t, " s3cr3t ", config.Password)
+
+	for expected, env := range map[string]map[string]string{
+		"remote: X_PROVIDER is required":                 {},
+		`remote: invalid X_PROVIDER "redis"`:             {"X_PROVIDER": "redis"},
+		"remote: X_ENDPOINT is required":                 {"X_PROVIDER": "etcd"},
+		`remote: invalid X_ENDPOINT "etcd:2379"`:         {"X_PROVIDER": "etcd", "X_ENDPOINT": "etcd:2379"},
+		`remote: invalid X_ENDPOINT "unix:///etcd.sock"`: {"X_PROVIDER": "etcd", "X_ENDPOINT": "unix:///etcd.sock"},
+	} {
+		_, err := remote.FromEnv(apptest.Lookup(env), "X_")
+		assert.EqualError(t, err, expected)
+	}
+}
+
+func TestFromEnv_KeyPrefix(t *testing.T) {
+	env := map[string]string{"APP_REMOTE_PROVIDER": "etcd", "APP_REMOTE_ENDPOINT": "http://etcd:2379"}
+
+	config, err := remote.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Equal(t, remote.DefaultKeyPrefix, config.KeyPrefix)
+
+	// an empty prefix is kept, rather than defaulted, so that every key of the store is a variable
+	env["APP_REMOTE_KEY_PREFIX"] = ""
+	config, err = remote.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Empty(t, config.KeyPrefix)
+
+	env["APP_REMOTE_KEY_PREFIX"] = " apps/billing/ "
+	config, err = remote.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Equal(t, "apps/billing/", config.KeyPrefix)
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	env := map[string]string{
+		"APP_REMOTE_PROVIDER":        "etcd",
+		"APP_REMOTE_ENDPOINT":        "http://etcd:2379",
+		"APP_REMOTE_TTL":             "0s",
+		"APP_REMOTE_HEALTH_INTERVAL": "1m",
+	}
+
+	// zero turns the cache off, but a request or health check must still be bounded
+	config, err := remote.FromEnv(apptest.Lookup(env), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.TTL)
+	assert.Equal(t, remote.DefaultTimeout, config.Timeout)
+	assert.Equal(t, time.Minute, config.HealthInterval)
+
+	for key, v := range map[string]string{"TTL": "-1s", "TIMEOUT": "0s", "HEALTH_INTERVAL": "30"} {
+		env := map[string]string{
+			"APP_REMOTE_PROVIDER": "etcd",
+			"APP_REMOTE_ENDPOINT": "http://etcd:2379",
+			"APP_REMOTE_" + key:   v,
+		}
+		_, err := remote.FromEnv(apptest.Lookup(env), "")
+		assert.EqualError(t, err, "remote: invalid APP_REMOTE_"+key+` "`+v+`"`)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "DB_HOST", remote.EnvName("config/", "config/DB_HOST"))
+	assert.Equal(t, "DB_HOST", remote.EnvName("config/", "config/db/host"))
+	assert.Equal(t, "FEATURE_NEW_UI", remote.EnvName("", "feature.new-ui"))
+	assert.Equal(t, "", remote.EnvName("config/", "other/DB_HOST"))
+	assert.Equal(t, "", remote.EnvName("config/", "config/"))
+}
@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// consulWait is how long a blocking query waits for the keys to change before Consul responds anyway.
+const consulWait = 5 * time.Minute
+
+// Consul is a Provider reading the keys below the KeyPrefix from the Consul KV store, watching them with blocking
+// queries.
+type Consul struct {
+	endpoint string
+	prefix   string
+	token    string
+	client   *http.Client
+}
+
+// NewConsul returns a Consul provider of the store described by config. The client may be nil to use
+// http.DefaultClient.
+func NewConsul(config *Config, client *http.Client) *Consul {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Consul{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		prefix:   strings.TrimPrefix(config.KeyPrefix, "/"),
+		token:    config.Token,
+		client:   client,
+	}
+}
+
+// Get returns the variables in the store.
+func (c *Consul) Get(ctx context.Context) (map[string]string, error) {
+	values, _, err := c.get(ctx, 0)
+	return values, err
+}
+
+// Watch blocks on the keys, calling changed with their values, then whenever they change.
+func (c *Consul) Watch(ctx context.Context, changed func(map[string]string)) error {
+	last, index, err := c.get(ctx, 0)
+	if err != nil {
+		return err
+	}
+	if index == 0 {
+		return errors.New("consul: response without an X-Consul-Index")
+	}
+	changed(last)
+
+	for {
+		values, next, err := c.get(ctx, index)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			return err
+		}
+		// the index may go backwards, after a snapshot restore for one, and must then be reset
+		if next < index {
+			next = 0
+		}
+		index = next
+
+		if !maps.Equal(values, last) {
+			last = values
+			changed(values)
+		}
+	}
+}
+
+// Check asks Consul for the cluster leader, which it only knows while the cluster can serve requests.
+func (c *Consul) Check(ctx context.Context) error {
+	req, err := c.request(ctx, "/v1/status/leader", nil)
+	if err != nil {
+		return err
+	}
+
+	var leader string
+	if _, _, err := do(c.client, req, &leader, false); err != nil {
+		return fmt.Errorf("consul: %v", err)
+	}
+	if leader == "" {
+		return errors.New("consul: no cluster leader")
+	}
+	return nil
+}
+
+// get reads the keys, waiting for the store to move past index if it is not zero, and returns the variables and the
+// index of the response.
+func (c *Consul) get(ctx context.Context, index uint64) (map[string]string, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWait.String())
+	}
+	req, err := c.request(ctx, "/v1/kv/"+c.prefix, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pairs []struct {
+		Key   string
+		Value []byte
+	}
+	// a prefix without keys is not found
+	header, _, err := do(c.client, req, &pairs, true)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: %v", err)
+	}
+
+	values := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		if name := envName(c.prefix, pair.Key); name != "" && pair.Value != nil {
+			values[name] = string(pair.Value)
+		}
+	}
+
+	next, _ := strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+	return values, next, nil
+}
+
+func (c *Consul) request(ctx context.Context, path string, query url.Values) (*http.Request, error) {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: %v", err)
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	return req, nil
+}
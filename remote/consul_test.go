@@ -0,0 +1,172 @@
+package remote_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/remote"
+)
+
+// fakeConsul serves the parts of the Consul HTTP API the Consul provider uses, answering blocking queries when a
+// key changes.
+type fakeConsul struct {
+	mu      sync.Mutex
+	kvs     map[string]string
+	index   uint64
+	changed chan struct{}
+	leader  string
+	waiting int
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{kvs: map[string]string{}, index: 1, changed: make(chan struct{}), leader: "10.0.0.1:8300"}
+}
+
+func (f *fakeConsul) put(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if value == "" {
+		delete(f.kvs, key)
+	} else {
+		f.kvs[key] = value
+	}
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// blocked reports whether a blocking query is waiting for a change.
+func (f *fakeConsul) blocked() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.waiting > 0
+}
+
+func (f *fakeConsul) setLeader(leader string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leader = leader
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Path == "/v1/status/leader" {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(f.leader)
+		return
+	}
+
+	prefix, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if !ok || r.URL.Query().Get("recurse") != "true" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	if index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); index >= f.index {
+		changed := f.changed
+		f.waiting++
+		f.mu.Unlock()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		f.mu.Lock()
+		f.waiting--
+	}
+	defer f.mu.Unlock()
+
+	type pair struct {
+		Key   string
+		Value []byte
+	}
+	var pairs []pair
+	for k, v := range f.kvs {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, pair{Key: k, Value: []byte(v)})
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	if len(pairs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	// a folder has no value
+	pairs = append(pairs, pair{Key: prefix + "folder/"})
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestConsul(t *testing.T) {
+	f := newFakeConsul()
+	f.kvs["config/DB_HOST"] = "db"
+	f.kvs["other/DB_HOST"] = "other"
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	config := remote.DefaultConfig()
+	config.Endpoint, config.Token = srv.URL, "secret"
+	c := remote.NewConsul(config, srv.Client())
+
+	values, err := c.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_HOST": "db"}, values)
+	assert.NoError(t, c.Check(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan map[string]string)
+	done := make(chan error)
+	defer cancel()
+	go func() {
+		done <- c.Watch(ctx, func(values map[string]string) { changes <- values })
+	}()
+	select {
+	case values := <-changes:
+		assert.Equal(t, map[string]string{"DB_HOST": "db"}, values, "the watch starts with the keys")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keys were not delivered")
+	}
+	assert.Eventually(t, f.blocked, 5*time.Second, time.Millisecond)
+
+	// a change to another prefix wakes the blocking query without changing the values
+	f.put("other/DB_HOST", "elsewhere")
+	f.put("config/db/port", "5432")
+	select {
+	case values := <-changes:
+		assert.Equal(t, map[string]string{"DB_HOST": "db", "DB_PORT": "5432"}, values)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change was watched")
+	}
+
+	f.put("config/DB_HOST", "")
+	select {
+	case values := <-changes:
+		assert.Equal(t, map[string]string{"DB_PORT": "5432"}, values)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change was watched")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	f.setLeader("")
+	assert.EqualError(t, c.Check(context.Background()), "consul: no cluster leader")
+
+	config.Token = "wrong"
+	_, err = remote.NewConsul(config, srv.Client()).Get(context.Background())
+	assert.EqualError(t, err, "consul: GET /v1/kv/config/: 403 Forbidden: ACL not found")
+}
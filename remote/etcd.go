@@ -0,0 +1,265 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Etcd is a Provider reading the keys below the KeyPrefix from etcd v3 through its JSON gateway, watching them with
+// a streaming watch.
+type Etcd struct {
+	endpoint string
+	prefix   string
+	username string
+	password string
+	client   *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewEtcd returns an Etcd provider of the cluster described by config, authenticating with the Username and Password
+// when set. The client may be nil to use http.DefaultClient.
+func NewEtcd(config *Config, client *http.Client) *Etcd {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Etcd{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		prefix:   config.KeyPrefix,
+		username: config.Username,
+		password: config.Password,
+		client:   client,
+	}
+}
+
+type etcdHeader struct {
+	Revision int64 `json:"revision,string"`
+}
+
+type etcdKV struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Get returns the variables in the store.
+func (e *Etcd) Get(ctx context.Context) (map[string]string, error) {
+	values, _, err := e.get(ctx)
+	return values, err
+}
+
+// Watch watches the keys from the revision it reads them at, calling changed with all of them at that revision,
+// then after every change.
+func (e *Etcd) Watch(ctx context.Context, changed func(map[string]string)) error {
+	last, revision, err := e.get(ctx)
+	if err != nil {
+		return err
+	}
+	changed(last)
+
+	key, end := e.keyRange()
+	req, err := e.request(ctx, "/v3/watch", map[string]interface{}{
+		"create_request": map[string]interface{}{
+			"key":            key,
+			"range_end":      end,
+			"start_revision": strconv.FormatInt(revision+1, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	res, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		e.unauthorized(res.StatusCode)
+		return fmt.Errorf("etcd: POST /v3/watch: %s", res.Status)
+	}
+
+	// the gateway streams one JSON object per watch response
+	dec := json.NewDecoder(res.Body)
+	for {
+		var msg struct {
+			Result *struct {
+				Canceled     bool              `json:"canceled"`
+				CancelReason string            `json:"cancel_reason"`
+				Events       []json.RawMessage `json:"events"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("etcd: watch: %v", err)
+		}
+
+		switch {
+		case msg.Error != nil:
+			return fmt.Errorf("etcd: watch: %s", msg.Error.Message)
+		case msg.Result == nil:
+			continue
+		case msg.Result.Canceled:
+			return fmt.Errorf("etcd: watch canceled: %s", msg.Result.CancelReason)
+		case len(msg.Result.Events) > 0:
+			values, _, err := e.get(ctx)
+			if err != nil {
+				return err
+			}
+			if !maps.Equal(values, last) {
+				last = values
+				changed(values)
+			}
+		}
+	}
+}
+
+// Check asks the cluster for its health, which is only reported when it has a leader and can commit writes.
+func (e *Etcd) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+
+	var health struct {
+		Health string `json:"health"`
+		Reason string `json:"reason"`
+	}
+	if _, _, err := do(e.client, req, &health, false); err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+	if health.Health != "true" {
+		if health.Reason != "" {
+			return fmt.Errorf("etcd: unhealthy: %s", health.Reason)
+		}
+		return errors.New("etcd: unhealthy")
+	}
+	return nil
+}
+
+// get reads the keys, returning the variables and the revision of the store they were read at.
+func (e *Etcd) get(ctx context.Context) (map[string]string, int64, error) {
+	key, end := e.keyRange()
+	req, err := e.request(ctx, "/v3/kv/range", map[string]interface{}{"key": key, "range_end": end})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var body struct {
+		Header etcdHeader `json:"header"`
+		KVs    []etcdKV   `json:"kvs"`
+	}
+	if err := e.do(req, &body); err != nil {
+		return nil, 0, err
+	}
+
+	values := make(map[string]string, len(body.KVs))
+	for _, kv := range body.KVs {
+		if name := envName(e.prefix, string(kv.Key)); name != "" {
+			values[name] = string(kv.Value)
+		}
+	}
+	return values, body.Header.Revision, nil
+}
+
+// keyRange returns the range of keys with the prefix, every key if the prefix is empty.
+func (e *Etcd) keyRange() ([]byte, []byte) {
+	if e.prefix == "" {
+		return []byte{0}, []byte{0}
+	}
+
+	key := []byte(e.prefix)
+	end := append([]byte(nil), key...)
+	for idx := len(end) - 1; idx >= 0; idx-- {
+		if end[idx] < 0xff {
+			end[idx]++
+			return key, end[:idx+1]
+		}
+	}
+	// a prefix of 0xff bytes ranges to the last key
+	return key, []byte{0}
+}
+
+// request returns a request posting body, authenticated with a token if the Etcd has a username.
+func (e *Etcd) request(ctx context.Context, path string, body interface{}) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("etcd: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if e.username != "" {
+		token, err := e.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", token)
+	}
+	return req, nil
+}
+
+func (e *Etcd) do(req *http.Request, out interface{}) error {
+	_, _, err := do(e.client, req, out, false)
+	var status *statusError
+	if errors.As(err, &status) {
+		e.unauthorized(status.code)
+	}
+	if err != nil {
+		return fmt.Errorf("etcd: %v", err)
+	}
+	return nil
+}
+
+// authenticate returns the token authenticating the Etcd user, requesting one if it has none.
+func (e *Etcd) authenticate(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.token != "" {
+		return e.token, nil
+	}
+
+	b, _ := json.Marshal(map[string]string{"name": e.username, "password": e.password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/v3/auth/authenticate", bytes.NewReader(b))
+	if err != nil {
+		return "", fmt.Errorf("etcd: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if _, _, err := do(e.client, req, &body, false); err != nil {
+		return "", fmt.Errorf("etcd: authenticate: %v", err)
+	}
+	if body.Token == "" {
+		return "", errors.New("etcd: authenticate: no token issued")
+	}
+	e.token = body.Token
+	return e.token, nil
+}
+
+// unauthorized drops the token after a request was refused, so that the next request authenticates again.
+func (e *Etcd) unauthorized(status int) {
+	if status != http.StatusUnauthorized {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.token = ""
+}
@@ -0,0 +1,185 @@
+package remote_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/remote"
+)
+
+// fakeEtcd serves the parts of the etcd v3 JSON gateway the Etcd provider uses.
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	notify   chan struct{}
+	token    string
+	healthy  bool
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{kvs: map[string]string{}, notify: make(chan struct{}, 1), healthy: true}
+}
+
+func (f *fakeEtcd) put(key, value string) {
+	f.mu.Lock()
+	f.kvs[key] = value
+	f.revision++
+	f.mu.Unlock()
+	f.notify <- struct{}{}
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	token, healthy := f.token, f.healthy
+	f.mu.Unlock()
+
+	if r.URL.Path == "/health" {
+		_, _ = fmt.Fprintf(w, `{"health":%q}`, strconv.FormatBool(healthy))
+		return
+	}
+	if r.URL.Path == "/v3/auth/authenticate" {
+		var body struct{ Name, Password string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Name != "root" || body.Password != "hunter2" {
+			http.Error(w, `{"message":"authentication failed"}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"token":%q}`, token)
+		return
+	}
+	if token != "" && r.Header.Get("Authorization") != token {
+		http.Error(w, `{"message":"invalid auth token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Key      []byte `json:"key"`
+		RangeEnd []byte `json:"range_end"`
+	}
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		var kvs []map[string][]byte
+		for k, v := range f.kvs {
+			// a range end of \x00 is every key from the start
+			if k >= string(req.Key) && (k < string(req.RangeEnd) || string(req.RangeEnd) == "\x00") {
+				kvs = append(kvs, map[string][]byte{"key": []byte(k), "value": []byte(v)})
+			}
+		}
+		body := map[string]interface{}{"header": map[string]string{"revision": strconv.FormatInt(f.revision, 10)}}
+		if len(kvs) > 0 {
+			body["kvs"] = kvs
+		}
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(body)
+	case "/v3/watch":
+		_, _ = fmt.Fprint(w, `{"result":{"created":true}}`)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-f.notify:
+				_, _ = fmt.Fprint(w, "\n"+`{"result":{"events":[{"kv":{}}]}}`)
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestEtcd(t *testing.T) {
+	f := newFakeEtcd()
+	f.kvs["config/DB_HOST"] = "db"
+	f.kvs["config/db/port"] = "5432"
+	f.kvs["other/SECRET"] = "x"
+	f.token = "abc"
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	config := remote.DefaultConfig()
+	config.Endpoint = srv.URL
+	config.Username, config.Password = "root", "hunter2"
+	e := remote.NewEtcd(config, srv.Client())
+
+	values, err := e.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_HOST": "db", "DB_PORT": "5432"}, values)
+	require.NoError(t, e.Check(context.Background()))
+
+	// an expired token is replaced
+	f.mu.Lock()
+	f.token = "def"
+	f.mu.Unlock()
+	_, err = e.Get(context.Background())
+	assert.EqualError(t, err, `etcd: POST /v3/kv/range: 401 Unauthorized: {"message":"invalid auth token"}`)
+	_, err = e.Get(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan map[string]string)
+	done := make(chan error)
+	go func() {
+		done <- e.Watch(ctx, func(values map[string]string) { changes <- values })
+	}()
+	select {
+	case values := <-changes:
+		assert.Equal(t, map[string]string{"DB_HOST": "db", "DB_PORT": "5432"}, values, "the watch starts with the keys")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keys were not delivered")
+	}
+
+	f.put("config/DB_HOST", "db2")
+	select {
+	case values := <-changes:
+		assert.Equal(t, map[string]string{"DB_HOST": "db2", "DB_PORT": "5432"}, values)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change was watched")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	f.mu.Lock()
+	f.healthy = false
+	f.mu.Unlock()
+	assert.EqualError(t, e.Check(context.Background()), "etcd: unhealthy")
+
+	config.Password = "wrong"
+	_, err = remote.NewEtcd(config, srv.Client()).Get(context.Background())
+	assert.EqualError(t, err, "etcd: authenticate: POST /v3/auth/authenticate: 400 Bad Request: "+
+		`{"message":"authentication failed"}`)
+}
+
+func TestEtcd_Prefix(t *testing.T) {
+	f := newFakeEtcd()
+	f.kvs["configs/B"] = "2"
+	f.kvs["config/A"] = "1"
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	for prefix, expected := range map[string]map[string]string{
+		"config/": {"A": "1"},
+		"config":  {"A": "1", "S_B": "2"},
+		"":        {"CONFIG_A": "1", "CONFIGS_B": "2"},
+	} {
+		config := remote.DefaultConfig()
+		config.Endpoint, config.KeyPrefix = srv.URL, prefix
+		values, err := remote.NewEtcd(config, nil).Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, expected, values, prefix)
+	}
+}
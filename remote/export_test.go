@@ -0,0 +1,11 @@
+package remote
+
+import "time"
+
+// SetNow replaces the clock of the Cached.
+func (c *Cached) SetNow(now func() time.Time) {
+	c.now = now
+}
+
+// EnvName exposes envName.
+var EnvName = envName
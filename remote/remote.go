@@ -0,0 +1,273 @@
+// Package remote reads configuration from a key/value store, etcd or Consul, into the App environment and keeps it
+// up to date as the keys change:
+//
+//	config, err := remote.FromEnv(a.LookupEnv, "")
+//	provider, err := remote.Open(config, nil)
+//	a.Register("remote-config", remote.NewWatcher(provider, config, nil))
+//
+// The keys below the config KeyPrefix are variables: config/DB_HOST, or config/db/host, is read with
+// a.LookupEnv("DB_HOST"). The Watcher fetches the keys when the App binds its servers, failing startup if the store
+// is unreachable, then applies every change with App.Reload while the App runs. Variables set in the process
+// environment take precedence over the store.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// maxBackoff bounds the delay between attempts to watch the store.
+const maxBackoff = 30 * time.Second
+
+// Provider is a remote configuration store. Implementations must be safe for concurrent use.
+type Provider interface {
+	// Get returns the variables in the store.
+	Get(ctx context.Context) (map[string]string, error)
+
+	// Watch calls changed with the variables in the store once it starts watching, so that the changes made since
+	// they were last read are not missed, then whenever they change. It blocks until the context is done,
+	// returning its error, or until the connection to the store fails.
+	Watch(ctx context.Context, changed func(map[string]string)) error
+
+	// Check returns an error if the store cannot serve requests.
+	Check(ctx context.Context) error
+}
+
+// envName returns the variable stored at key, or an empty string if key is not below the prefix.
+func envName(prefix, key string) string {
+	name, ok := strings.CutPrefix(key, prefix)
+	if !ok {
+		return ""
+	}
+	name = strings.Trim(name, "/")
+	return strings.ToUpper(strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(name))
+}
+
+// Watcher is an app.Server loading the variables in a Provider into the App environment when the App starts and
+// reloading them whenever they change. It checks the health of the store every HealthInterval, reporting it in the
+// remote_config_healthy gauge.
+type Watcher struct {
+	provider Provider
+	config   Config
+	logger   gomol.WrappableLogger
+
+	app    *app.App
+	source string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	loaded  map[string]string
+	checked bool
+	healthy bool
+	gauge   *metrics.Gauge
+}
+
+// NewWatcher returns a Watcher of the provider described by config. The logger may be nil to use the App logger.
+func NewWatcher(provider Provider, config *Config, logger gomol.WrappableLogger) *Watcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	source := "remote"
+	if config.Provider != "" {
+		source = config.Provider
+	}
+	return &Watcher{
+		provider: provider,
+		config:   *config,
+		logger:   logger,
+		source:   source,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Bind fetches the variables in the store into the App environment.
+func (w *Watcher) Bind(a *app.App) error {
+	w.app = a
+	if w.logger == nil {
+		w.logger = a.Logger()
+	}
+	w.gauge = a.Metrics().Gauge("remote_config_healthy", metrics.Labels{"provider": w.source})
+
+	ctx, cancel := w.timeout()
+	defer cancel()
+	values, err := w.provider.Get(ctx)
+	w.setHealthy(err)
+	if err != nil {
+		return fmt.Errorf("remote: load configuration: %v", err)
+	}
+	w.reload(values)
+	return nil
+}
+
+// reload loads the variables into the App environment, unless they are those it loaded last.
+func (w *Watcher) reload(values map[string]string) {
+	w.mu.Lock()
+	same := w.loaded != nil && maps.Equal(values, w.loaded)
+	w.loaded = maps.Clone(values)
+	w.mu.Unlock()
+	if !same {
+		w.app.Reload(w.source, values)
+	}
+}
+
+// Serve watches the store until the Watcher is shut down, reconnecting with a backoff when the connection fails.
+func (w *Watcher) Serve() error {
+	defer close(w.done)
+	go w.check()
+
+	backoff := time.Second
+	for {
+		err := w.provider.Watch(w.ctx, func(values map[string]string) {
+			backoff = time.Second
+			w.reload(values)
+		})
+		if w.ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = errors.New("watch ended")
+		}
+		_ = w.logger.Log(gomol.LevelWarning, gomol.NewAttrsFromMap(map[string]interface{}{
+			"provider": w.source,
+			"error":    err.Error(),
+			"retry":    backoff.String(),
+		}), "remote configuration watch failed")
+
+		select {
+		case <-w.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// Shutdown stops watching the store, waiting until the context is done for Serve to return.
+func (w *Watcher) Shutdown(ctx context.Context) error {
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Healthy reports whether the last health check of the store succeeded.
+func (w *Watcher) Healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.healthy
+}
+
+func (w *Watcher) check() {
+	if w.config.HealthInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := w.timeout()
+		err := w.provider.Check(ctx)
+		cancel()
+		if w.ctx.Err() == nil {
+			w.setHealthy(err)
+		}
+	}
+}
+
+func (w *Watcher) setHealthy(err error) {
+	w.mu.Lock()
+	checked, was := w.checked, w.healthy
+	w.checked, w.healthy = true, err == nil
+	w.mu.Unlock()
+
+	if err == nil {
+		w.gauge.Set(1)
+	} else {
+		w.gauge.Set(0)
+	}
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{"provider": w.source})
+	switch {
+	case err != nil && checked && was:
+		attrs.SetAttr("error", err.Error())
+		_ = w.logger.Log(gomol.LevelWarning, attrs, "remote configuration store is unhealthy")
+	case err == nil && checked && !was:
+		_ = w.logger.Log(gomol.LevelInfo, attrs, "remote configuration store recovered")
+	}
+}
+
+func (w *Watcher) timeout() (context.Context, context.CancelFunc) {
+	if w.config.Timeout <= 0 {
+		return context.WithCancel(w.ctx)
+	}
+	return context.WithTimeout(w.ctx, w.config.Timeout)
+}
+
+// do sends a request to the store, decoding a JSON response body into out unless it is nil. Responses with a status
+// other than 200 OK are errors, except 404 Not Found when notFound is set, reported by a false result.
+func do(client *http.Client, req *http.Request, out interface{}, notFound bool) (http.Header, bool, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && notFound:
+		return res.Header, false, nil
+	case res.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, false, &statusError{
+			request: req.Method + " " + req.URL.Path,
+			status:  res.Status,
+			code:    res.StatusCode,
+			message: strings.TrimSpace(string(body)),
+		}
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return nil, false, fmt.Errorf("%s %s: invalid response: %v", req.Method, req.URL.Path, err)
+		}
+	}
+	return res.Header, true, nil
+}
+
+// statusError is a response from the store with an unexpected status.
+type statusError struct {
+	request string
+	status  string
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	if e.message != "" {
+		return fmt.Sprintf("%s: %s: %s", e.request, e.status, e.message)
+	}
+	return fmt.Sprintf("%s: %s", e.request, e.status)
+}
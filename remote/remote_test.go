@@ -0,0 +1,139 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/remote"
+)
+
+func TestWatcher(t *testing.T) {
+	f := newFakeConsul()
+	f.kvs["config/DB_HOST"] = "db"
+	f.kvs["config/DB_PORT"] = "5432"
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	config, err := remote.FromEnv(apptest.Lookup(map[string]string{
+		"APP_REMOTE_PROVIDER":        "consul",
+		"APP_REMOTE_ENDPOINT":        srv.URL,
+		"APP_REMOTE_TOKEN":           "secret",
+		"APP_REMOTE_HEALTH_INTERVAL": "10ms",
+	}), "")
+	require.NoError(t, err)
+	provider, err := remote.Open(config, srv.Client())
+	require.NoError(t, err)
+	assert.IsType(t, &remote.Cached{}, provider)
+
+	a := &app.App{Context: context.Background(), Environment: []string{"DB_PORT=6432"}, Stderr: io.Discard}
+	w := remote.NewWatcher(provider, config, nil)
+	require.NoError(t, w.Bind(a))
+	env := func(key string) string {
+		v, _ := a.LookupEnv(key)
+		return v
+	}
+	assert.Equal(t, "db", env("DB_HOST"))
+	assert.Equal(t, "6432", env("DB_PORT"), "the process environment takes precedence")
+
+	// a change made before the watch starts is not missed
+	f.put("config/DB_HOST", "db1")
+	done := make(chan error)
+	go func() { done <- w.Serve() }()
+	assert.Eventually(t, func() bool { return env("DB_HOST") == "db1" }, 5*time.Second, 5*time.Millisecond)
+	assert.Eventually(t, f.blocked, 5*time.Second, time.Millisecond)
+
+	f.put("config/DB_HOST", "db2")
+	assert.Eventually(t, func() bool { return env("DB_HOST") == "db2" }, 5*time.Second, 5*time.Millisecond)
+
+	healthy := a.Metrics().Gauge("remote_config_healthy", metrics.Labels{"provider": "consul"})
+	assert.True(t, w.Healthy())
+	assert.Equal(t, 1.0, healthy.Value())
+	f.setLeader("")
+	assert.Eventually(t, func() bool { return !w.Healthy() }, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 0.0, healthy.Value())
+	f.setLeader("10.0.0.1:8300")
+	assert.Eventually(t, w.Healthy, 5*time.Second, 5*time.Millisecond)
+
+	require.NoError(t, w.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+}
+
+func TestWatcher_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(newFakeConsul())
+	config := remote.DefaultConfig()
+	config.Provider, config.Endpoint = remote.ProviderConsul, srv.URL
+	srv.Close()
+
+	w := remote.NewWatcher(remote.NewConsul(config, nil), config, nil)
+	err := w.Bind(&app.App{Context: context.Background(), Stderr: io.Discard})
+	assert.ErrorContains(t, err, "remote: load configuration: consul: Get ")
+	assert.False(t, w.Healthy())
+}
+
+// counting is a Provider counting the reads of its values.
+type counting struct {
+	mu     sync.Mutex
+	values map[string]string
+	err    error
+	reads  int
+}
+
+func (c *counting) Get(context.Context) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reads++
+	return c.values, c.err
+}
+
+func (c *counting) Watch(_ context.Context, changed func(map[string]string)) error {
+	changed(map[string]string{"A": "watched"})
+	return errors.New("disconnected")
+}
+
+func (c *counting) Check(context.Context) error {
+	return c.err
+}
+
+func TestCached(t *testing.T) {
+	p := &counting{values: map[string]string{"A": "1"}}
+	c := remote.NewCached(p, time.Minute)
+	now := time.Now()
+	c.SetNow(func() time.Time { return now })
+
+	for idx := 0; idx < 3; idx++ {
+		values, err := c.Get(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"A": "1"}, values)
+	}
+	assert.Equal(t, 1, p.reads)
+
+	now = now.Add(time.Minute)
+	_, _ = c.Get(context.Background())
+	assert.Equal(t, 2, p.reads)
+
+	c.Invalidate()
+	p.err = errors.New("connection refused")
+	_, err := c.Get(context.Background())
+	assert.EqualError(t, err, "connection refused")
+	assert.EqualError(t, c.Check(context.Background()), "connection refused")
+
+	var watched map[string]string
+	assert.EqualError(t, c.Watch(context.Background(), func(values map[string]string) { watched = values }),
+		"disconnected")
+	assert.Equal(t, map[string]string{"A": "watched"}, watched)
+	values, err := c.Get(context.Background())
+	assert.NoError(t, err, "values delivered by Watch are cached")
+	assert.Equal(t, map[string]string{"A": "watched"}, values)
+	assert.Equal(t, 3, p.reads)
+}
@@ -0,0 +1,236 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Funcs returns the functions templates rendered by a Renderer can call. env reads variables with lookup, typically
+// App.LookupEnv, and the color functions color the text only if color is set. As with sprig, the value a function
+// works on comes last, so that it can be piped: {{.Name | trimPrefix "v" | upper}}.
+//
+//	strings       upper lower title trim trimPrefix trimSuffix replace contains hasPrefix hasSuffix repeat
+//	              split join indent nindent quote squote wrap
+//	values        default empty coalesce ternary required
+//	collections   list dict keys hasKey
+//	encoding      toJson toPrettyJson toYaml
+//	time          now date
+//	app           env
+//	colors        red green yellow blue magenta cyan gray bold dim underline
+func Funcs(lookup func(string) (string, bool), color bool) template.FuncMap {
+	funcs := template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+		"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"squote":     func(v interface{}) string { return "'" + fmt.Sprint(v) + "'" },
+		"wrap":       wrap,
+
+		"default":  func(def, v interface{}) interface{} { return ternary(def, v, empty(v)) },
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		"required": required,
+
+		"list":   func(v ...interface{}) []interface{} { return v },
+		"dict":   dict,
+		"keys":   keys,
+		"hasKey": func(m map[string]interface{}, key string) bool { _, ok := m[key]; return ok },
+
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"toYaml":       toYAML,
+
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+
+		"env": func(key string) string { v, _ := lookup(key); return v },
+	}
+	for name, fn := range colorFuncs(color) {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// colors are the SGR codes of the color functions.
+var colors = map[string]string{
+	"red":       "31",
+	"green":     "32",
+	"yellow":    "33",
+	"blue":      "34",
+	"magenta":   "35",
+	"cyan":      "36",
+	"gray":      "90",
+	"bold":      "1",
+	"dim":       "2",
+	"underline": "4",
+}
+
+// colorFuncs returns the color functions, which leave the text as it is unless on is set.
+func colorFuncs(on bool) template.FuncMap {
+	funcs := template.FuncMap{}
+	for name, code := range colors {
+		code := code
+		funcs[name] = func(v interface{}) string {
+			s := fmt.Sprint(v)
+			if !on || s == "" {
+				return s
+			}
+			return "\x1b[" + code + "m" + s + "\x1b[0m"
+		}
+	}
+	return funcs
+}
+
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+func join(sep string, v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %T is not a list", v)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// indent indents every line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// wrap breaks s into lines of at most width characters, at spaces, except for words that are longer.
+func wrap(width int, s string) string {
+	var sb strings.Builder
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		n := 0
+		for j, word := range strings.Fields(line) {
+			w := utf8.RuneCountInString(word)
+			switch {
+			case j == 0:
+			case n+1+w > width:
+				sb.WriteByte('\n')
+				n = 0
+			default:
+				sb.WriteByte(' ')
+				n++
+			}
+			sb.WriteString(word)
+			n += w
+		}
+	}
+	return sb.String()
+}
+
+// empty reports whether v is nil or the zero value of its type, or an empty slice or map.
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, v := range v {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(yes, no interface{}, cond bool) interface{} {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+func required(msg string, v interface{}) (interface{}, error) {
+	if empty(v) {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+// dict returns the map of its arguments, keys and values in turn.
+func dict(v ...interface{}) (map[string]interface{}, error) {
+	if len(v)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(v)/2)
+	for i := 0; i < len(v); i += 2 {
+		key, ok := v[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", v[i])
+		}
+		m[key] = v[i+1]
+	}
+	return m, nil
+}
+
+func keys(m map[string]interface{}) []string {
+	list := make([]string, 0, len(m))
+	for key := range m {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	return string(b), err
+}
+
+func toYAML(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	return strings.TrimSuffix(string(b), "\n"), err
+}
@@ -0,0 +1,167 @@
+// Package render renders text/template templates with a library of helper functions, for commands that generate code
+// or configuration files and for reports whose format users can customize:
+//
+//	r := render.New(a)
+//	tmpl, err := r.ParseFS(files, "templates/*.tmpl")
+//	err = r.WriteFile("config/app.yaml", tmpl.Lookup("app.yaml.tmpl"), data, 0o644)
+//
+//	// a --format flag such as '{{.Name | bold}}\t{{.Size}}'
+//	err = r.Execute(a.Output(), opts.Format, item)
+//
+// Besides the functions text/template defines, templates can call those of Funcs: string, list, and dictionary
+// helpers in the style of sprig, env to read the app environment, and color functions such as red and bold, which
+// leave the text as it is when Stdout is not a terminal or NO_COLOR is set.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// Renderer parses templates with the functions of Funcs and renders them.
+type Renderer struct {
+	a     *app.App
+	color bool
+	funcs template.FuncMap
+}
+
+// New returns a Renderer for the app a. Its color functions color the text when Stdout is a terminal, NO_COLOR is not
+// set, and TERM is not dumb.
+func New(a *app.App) *Renderer {
+	r := &Renderer{a: a, funcs: template.FuncMap{}}
+	if f, ok := a.Stdout.(*os.File); ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())) {
+		noColor, _ := a.LookupEnv("NO_COLOR")
+		term, _ := a.LookupEnv("TERM")
+		r.color = noColor == "" && term != "dumb"
+	}
+	return r
+}
+
+// SetColor turns the color functions on or off, whatever the terminal, as for a --color flag.
+func (r *Renderer) SetColor(on bool) {
+	r.color = on
+}
+
+// Color reports whether the color functions color the text.
+func (r *Renderer) Color() bool {
+	return r.color
+}
+
+// Funcs adds the functions of funcs to those templates can call, replacing those of the same name. It returns r.
+func (r *Renderer) Funcs(funcs template.FuncMap) *Renderer {
+	for name, fn := range funcs {
+		r.funcs[name] = fn
+	}
+	return r
+}
+
+// FuncMap returns the functions templates can call: those of Funcs for the app, with the color functions on or off,
+// and those added with Funcs.
+func (r *Renderer) FuncMap() template.FuncMap {
+	funcs := Funcs(r.a.LookupEnv, r.color)
+	for name, fn := range r.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// New returns an empty template named name that can call the functions of FuncMap.
+func (r *Renderer) New(name string) *template.Template {
+	return template.New(name).Funcs(r.FuncMap()).Option("missingkey=error")
+}
+
+// Parse parses text as the template named name. Executing the template fails on keys missing from a map.
+func (r *Renderer) Parse(name, text string) (*template.Template, error) {
+	t, err := r.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("render: %w", err)
+	}
+	return t, nil
+}
+
+// ParseFS parses the templates of fsys matching patterns, as template.ParseFS, each named by its base name. fsys may
+// be an assets.FS, so that operators can override the templates an app embeds.
+func (r *Renderer) ParseFS(fsys fs.FS, patterns ...string) (*template.Template, error) {
+	var t *template.Template
+	for _, pattern := range patterns {
+		names, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("render: %w", err)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("render: pattern matches no files: %#q", pattern)
+		}
+		for _, name := range names {
+			b, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, fmt.Errorf("render: %w", err)
+			}
+			base := filepath.Base(name)
+			if t == nil {
+				t = r.New(base)
+			}
+			if _, err := t.New(base).Parse(string(b)); err != nil {
+				return nil, fmt.Errorf("render: %w", err)
+			}
+		}
+	}
+	if t == nil {
+		return nil, fmt.Errorf("render: no patterns")
+	}
+	return t, nil
+}
+
+// Execute parses text, such as the value of a --format flag, and writes it to w rendered with data.
+func (r *Renderer) Execute(w io.Writer, text string, data interface{}) error {
+	t, err := r.Parse("format", text)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
+
+// Render returns t rendered with data.
+func Render(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile renders t with data into the file at path, creating its directory, unless the file already has that
+// content. The file is replaced whole, so readers never see a partial file, and only with App.Mutate, so that a dry
+// run reports the files it would write. The color functions are off, whatever the terminal.
+func (r *Renderer) WriteFile(path string, t *template.Template, data interface{}, perm os.FileMode) error {
+	if r.color {
+		var err error
+		if t, err = t.Clone(); err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+		t.Funcs(colorFuncs(false))
+	}
+	b, err := Render(t, data)
+	if err != nil {
+		return err
+	}
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, b) {
+		return nil
+	}
+
+	return r.a.Mutate("write "+path, func() error {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return atomicfile.WriteFile(path, b, perm)
+	})
+}
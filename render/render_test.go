@@ -0,0 +1,100 @@
+package render_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/render"
+)
+
+func TestFuncs(t *testing.T) {
+	r := render.New(apptest.New(t, []string{"REGION=eu-west-1"}))
+	assert.False(t, r.Color())
+
+	tests := map[string]string{
+		`{{"v1.2.0" | trimPrefix "v" | upper}}`:                   "1.2.0",
+		`{{title "hello big world"}}`:                             "Hello Big World",
+		`{{replace "-" "_" "a-b-c"}}`:                             "a_b_c",
+		`{{split "," "a,b" | join "+"}}`:                          "a+b",
+		`{{list 1 2 3 | join ", "}}`:                              "1, 2, 3",
+		`{{"a\nb" | indent 2}}`:                                   "  a\n  b",
+		`x:{{"a" | nindent 2}}`:                                   "x:\n  a",
+		`{{quote "it"}} {{squote 3}}`:                             `"it" '3'`,
+		`{{wrap 10 "the quick brown fox jumps"}}`:                 "the quick\nbrown fox\njumps",
+		`{{.Missing | default "none"}}`:                           "none",
+		`{{.Name | default "none"}}`:                              "app",
+		`{{coalesce "" .Name}}`:                                   "app",
+		`{{ternary "on" "off" (empty .Tags)}}`:                    "on",
+		`{{$d := dict "b" 2 "a" 1}}{{keys $d}} {{hasKey $d "a"}}`: "[a b] true",
+		`{{dict "a" (list 1 2) | toJson}}`:                        `{"a":[1,2]}`,
+		`{{dict "a" 1 | toYaml}}`:                                 "a: 1",
+		`{{env "REGION"}}{{env "MISSING"}}`:                       "eu-west-1",
+		`{{red "error" | bold}}`:                                  "error",
+		`{{now | date "2006" | len}}`:                             "4",
+	}
+	for text, expected := range tests {
+		var buf bytes.Buffer
+		data := map[string]interface{}{"Name": "app", "Missing": "", "Tags": []string{}}
+		if assert.NoError(t, r.Execute(&buf, text, data), text) {
+			assert.Equal(t, expected, buf.String(), text)
+		}
+	}
+
+	var buf bytes.Buffer
+	assert.EqualError(t, r.Execute(&buf, `{{required "name is required" .Name}}`, map[string]interface{}{"Name": ""}),
+		`template: format:1:2: executing "format" at <required "name is required" .Name>: error calling required: `+
+			`name is required`)
+	assert.Error(t, r.Execute(&buf, `{{.Nope}}`, map[string]interface{}{}))
+	assert.EqualError(t, r.Execute(&buf, `{{`, nil), "render: template: format:1: unclosed action")
+}
+
+func TestRenderer_Color(t *testing.T) {
+	r := render.New(apptest.New(t, nil))
+	r.SetColor(true)
+	r.Funcs(map[string]interface{}{"shout": func(s string) string { return s + "!" }})
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Execute(&buf, `{{"ok" | shout | green}} {{"" | red}}`, nil))
+	assert.Equal(t, "\x1b[32mok!\x1b[0m ", buf.String())
+}
+
+func TestRenderer_WriteFile(t *testing.T) {
+	files := fstest.MapFS{
+		"templates/config.yaml.tmpl": {Data: []byte(`name: {{.Name | red}}{{template "footer.tmpl"}}`)},
+		"templates/footer.tmpl":      {Data: []byte("\n# generated\n")},
+	}
+	a := apptest.New(t, nil)
+	r := render.New(a)
+	r.SetColor(true)
+	tmpl, err := r.ParseFS(files, "templates/*.tmpl")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "config", "app.yaml")
+	data := map[string]string{"Name": "tool"}
+	require.NoError(t, r.WriteFile(path, tmpl.Lookup("config.yaml.tmpl"), data, 0o600))
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "name: tool\n# generated\n", string(b))
+
+	_, err = r.ParseFS(files, "*.html")
+	assert.EqualError(t, err, "render: pattern matches no files: `*.html`")
+}
+
+func TestRenderer_WriteFile_DryRun(t *testing.T) {
+	a := apptest.New(t, []string{"APP_DRY_RUN=true"})
+	r := render.New(a)
+	tmpl, err := r.Parse("config", "name: {{.}}\n")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, r.WriteFile(path, tmpl, "tool", 0o644))
+	assert.NoFileExists(t, path)
+	assert.Contains(t, apptest.Stdout(t, a), "would write "+path)
+}
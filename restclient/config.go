@@ -0,0 +1,141 @@
+package restclient
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the settings of the API a Client calls, as in APP_API_URL.
+	DefaultPrefix = "APP_API_"
+
+	// DefaultTimeout bounds each request, including its retries.
+	DefaultTimeout = time.Minute
+
+	// DefaultMaxRetries is the number of times a failed request is retried.
+	DefaultMaxRetries = 3
+
+	// DefaultRetryBackoff is the delay before the first retry; it doubles with every retry.
+	DefaultRetryBackoff = 500 * time.Millisecond
+
+	// DefaultMaxBackoff caps the delay between retries, including one asked for with Retry-After.
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Config describes the API a Client talks to and how it behaves when the API fails or limits it.
+type Config struct {
+	BaseURL      string        // the URL request paths are resolved against, such as https://api.example.com/v1/
+	Token        string        // a bearer token sent with every request; taken from the credentials when empty
+	UserAgent    string        // the User-Agent header of every request, if set
+	Timeout      time.Duration // bound on each request, including its retries
+	MaxRetries   int           // times a failed request is retried, 0 to never retry
+	RetryBackoff time.Duration // delay before the first retry, doubling with every retry
+	MaxBackoff   time.Duration // cap on the delay between retries
+	Rate         float64       // requests per second sent at most, 0 for no limit
+	Burst        int           // requests sent at once before being limited to Rate
+	CacheDir     string        // directory caching responses for conditional requests; the app cache when empty
+}
+
+// DefaultConfig returns a Config retrying failed requests DefaultMaxRetries times, without a base URL or rate limit.
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:      DefaultTimeout,
+		MaxRetries:   DefaultMaxRetries,
+		RetryBackoff: DefaultRetryBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+	}
+}
+
+func init() {
+	configschema.Register("restclient", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the API client variables with the prefix, such as for App.DeclareConfig when the app calls more
+// than one API.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "URL", Type: "url", Description: "The URL request paths are resolved against."},
+		{Name: prefix + "TOKEN", Type: "string",
+			Description: "The bearer token sent with every request.", Secret: true},
+		{Name: prefix + "USER_AGENT", Type: "string", Description: "The User-Agent header of every request."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a request may take, its retries included."},
+		{Name: prefix + "MAX_RETRIES", Type: "int", Default: strconv.Itoa(DefaultMaxRetries),
+			Description: "How often a failed request is retried."},
+		{Name: prefix + "RETRY_BACKOFF", Type: "duration", Default: DefaultRetryBackoff.String(),
+			Description: "The wait before the first retry."},
+		{Name: prefix + "MAX_BACKOFF", Type: "duration", Default: DefaultMaxBackoff.String(),
+			Description: "The longest wait between retries."},
+		{Name: prefix + "RATE", Type: "float", Default: "0",
+			Description: "The requests sent per second; 0 for no limit."},
+		{Name: prefix + "BURST", Type: "int", Description: "The requests sent at once before being limited to RATE."},
+		{Name: prefix + "CACHE_DIR", Type: "path",
+			Description: "The directory caching responses; the cache directory of the app if not set."},
+	}
+}
+
+// FromEnv reads the API the Client calls, URL, TOKEN, and USER_AGENT, how its requests are retried and paced, TIMEOUT,
+// MAX_RETRIES, RETRY_BACKOFF, MAX_BACKOFF, RATE, and BURST, and where responses are cached, CACHE_DIR, with the prefix
+// or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	config.Token = get("TOKEN")
+	config.UserAgent = get("USER_AGENT")
+	config.CacheDir = get("CACHE_DIR")
+
+	if v := get("URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("restclient: invalid %sURL %q", prefix, v)
+		}
+		config.BaseURL = v
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"TIMEOUT":       &config.Timeout,
+		"RETRY_BACKOFF": &config.RetryBackoff,
+		"MAX_BACKOFF":   &config.MaxBackoff,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("restclient: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	for key, dst := range map[string]*int{"MAX_RETRIES": &config.MaxRetries, "BURST": &config.Burst} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("restclient: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	if v := get("RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("restclient: invalid %sRATE %q", prefix, v)
+		}
+		config.Rate = rate
+	}
+
+	return config, nil
+}
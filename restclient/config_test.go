@@ -0,0 +1,70 @@
+package restclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/restclient"
+)
+
+func TestFromEnv_URL(t *testing.T) {
+	config, err := restclient.FromEnv(apptest.Lookup(map[string]string{
+		"GITHUB_URL":        " https://api.github.com/ ",
+		"GITHUB_TOKEN":      " s3cr3t ",
+		"GITHUB_USER_AGENT": "tool/1.0 (+https://example.com)",
+	}), "GITHUB_")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/", config.BaseURL)
+	assert.Equal(t, "s3cr3t", config.Token)
+	assert.Equal(t, "tool/1.0 (+https://example.com)", config.UserAgent)
+
+	// request paths are resolved against the URL, so it must be absolute
+	for _, v := range []string{"api.example.com", "/v1", "ftp://api.example.com", "https://"} {
+		_, err := restclient.FromEnv(apptest.Lookup(map[string]string{"APP_API_URL": v}), "")
+		assert.EqualError(t, err, `restclient: invalid APP_API_URL "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Retries(t *testing.T) {
+	config, err := restclient.FromEnv(apptest.Lookup(map[string]string{
+		"APP_API_MAX_RETRIES":   "0",
+		"APP_API_RETRY_BACKOFF": "250ms",
+		"APP_API_MAX_BACKOFF":   "1m",
+	}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.MaxRetries)
+	assert.Equal(t, 250*time.Millisecond, config.RetryBackoff)
+	assert.Equal(t, time.Minute, config.MaxBackoff)
+
+	// a request is always bounded, and a retry always waits
+	for key, v := range map[string]string{
+		"TIMEOUT":       "0s",
+		"RETRY_BACKOFF": "-1s",
+		"MAX_BACKOFF":   "soon",
+		"MAX_RETRIES":   "-1",
+	} {
+		_, err := restclient.FromEnv(apptest.Lookup(map[string]string{"APP_API_" + key: v}), "")
+		assert.EqualError(t, err, "restclient: invalid APP_API_"+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Rate(t *testing.T) {
+	config, err := restclient.FromEnv(apptest.Lookup(map[string]string{"APP_API_RATE": "0.5", "APP_API_BURST": "5"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, config.Rate)
+	assert.Equal(t, 5, config.Burst)
+
+	// zero sends requests as fast as they come
+	config, err = restclient.FromEnv(apptest.Lookup(map[string]string{"APP_API_RATE": "0"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, restclient.DefaultConfig(), config)
+
+	for key, v := range map[string]string{"RATE": "fast", "BURST": "-5"} {
+		_, err := restclient.FromEnv(apptest.Lookup(map[string]string{"APP_API_" + key: v}), "")
+		assert.EqualError(t, err, "restclient: invalid APP_API_"+key+` "`+v+`"`)
+	}
+}
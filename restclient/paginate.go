@@ -0,0 +1,170 @@
+package restclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Items returns an iterator over the items of the list endpoint at path, which responds with a JSON array of items
+// and links to the next page with a Link header of relation next, as GitHub does. Pages are requested as the
+// iteration reaches them. An error ends the iteration, yielded with the zero T.
+func Items[T any](ctx context.Context, c *Client, path string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		for path != "" {
+			req, err := c.NewRequest(ctx, http.MethodGet, path, nil)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			var page []T
+			res, err := c.Do(req, &page)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			path = nextLink(req.URL, res.Header)
+		}
+	}
+}
+
+// Cursor describes a list endpoint paginated with cursors. It responds with a JSON object with the items of the page in
+// the field Items and the cursor of the next page in the field Next, which is empty or null on the last page. A field
+// may be nested in objects, such as response_metadata.next_cursor. The next page is requested with the cursor in the
+// query parameter Param.
+type Cursor struct {
+	Items string // the field of the items, items if empty
+	Next  string // the field of the cursor of the next page, next_cursor if empty
+	Param string // the query parameter of the cursor, cursor if empty
+}
+
+// CursorItems returns an iterator over the items of the list endpoint at path described by cursor. Pages are
+// requested as the iteration reaches them. An error ends the iteration, yielded with the zero T.
+func CursorItems[T any](ctx context.Context, c *Client, path string, cursor Cursor) iter.Seq2[T, error] {
+	if cursor.Items == "" {
+		cursor.Items = "items"
+	}
+	if cursor.Next == "" {
+		cursor.Next = "next_cursor"
+	}
+	if cursor.Param == "" {
+		cursor.Param = "cursor"
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		u, err := c.URL(path)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for {
+			var doc json.RawMessage
+			if err := c.Get(ctx, u.String(), &doc); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var page []T
+			if err := field(doc, cursor.Items, &page); err != nil {
+				yield(zero, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			var next interface{}
+			if err := field(doc, cursor.Next, &next); err != nil {
+				yield(zero, err)
+				return
+			}
+			var s string
+			switch v := next.(type) {
+			case nil:
+			case string:
+				s = v
+			case float64:
+				s = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				yield(zero, fmt.Errorf("restclient: the %s of %s is not a cursor", cursor.Next, u.Redacted()))
+				return
+			}
+
+			q := u.Query()
+			if s == "" {
+				return
+			}
+			if s == q.Get(cursor.Param) {
+				yield(zero, fmt.Errorf("restclient: the cursor of %s did not advance", u.Redacted()))
+				return
+			}
+			q.Set(cursor.Param, s)
+			u.RawQuery = q.Encode()
+		}
+	}
+}
+
+// field decodes the field at the dotted path name of the JSON object doc into v, leaving v as it is if the field is
+// missing.
+func field(doc json.RawMessage, name string, v interface{}) error {
+	for _, key := range strings.Split(name, ".") {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(doc, &obj); err != nil {
+			return errors.New("restclient: response is not a JSON object")
+		}
+		var ok bool
+		if doc, ok = obj[key]; !ok {
+			return nil
+		}
+	}
+	if err := json.Unmarshal(doc, v); err != nil {
+		return fmt.Errorf("restclient: invalid %s in response: %v", name, err)
+	}
+	return nil
+}
+
+// nextLink returns the URL of the next page in the Link headers of the response to a request for base, or the empty
+// string if there is none.
+func nextLink(base *url.URL, header http.Header) string {
+	for _, v := range header.Values("Link") {
+		for _, link := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						u, err := base.Parse(target[1 : len(target)-1])
+						if err != nil {
+							return ""
+						}
+						return u.String()
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,296 @@
+// Package restclient is the standard way command line tools talk to HTTP APIs. A Client resolves request paths
+// against the base URL of the API, sends its token, encodes and decodes JSON, turns error responses into an Error,
+// retries failed requests with exponential backoff, keeps under a rate limit, and revalidates the responses it cached
+// with conditional requests:
+//
+//	config, err := restclient.FromEnv(a.LookupEnv, "")
+//	client, err := restclient.Open(a, config)
+//
+//	var user User
+//	err = client.Get(a.Context, "user", &user)
+//
+//	for repo, err := range restclient.Items[Repo](a.Context, client, "user/repos") {
+//		...
+//	}
+//
+// Open sends the token of the Config, or the one saved in the App Credentials with SaveToken. To sign in with OAuth
+// instead, give an auth.Client to New.
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/auth"
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/credstore"
+	"github.com/demosdemon/golang-app-framework/ratelimit"
+)
+
+// maxErrorBody bounds how much of an error response is read for its message.
+const maxErrorBody = 64 << 10
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Status     string
+	Message    string // the message in the body of the response, if any
+	Header     http.Header
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "restclient: " + e.Status
+	}
+	return "restclient: " + e.Status + ": " + e.Message
+}
+
+// StaticToken is a TokenSource of a bearer token that does not expire, such as a personal access token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{AccessToken: string(t)}, nil
+}
+
+// Client sends requests to an API. It is safe for concurrent use.
+type Client struct {
+	base      *url.URL
+	userAgent string
+	http      *http.Client
+}
+
+// New returns a Client for the API described by config, sending the token of src with every request, and the request
+// ID the context of a request carries, see correlation. The token source and the logger, which is told about retries,
+// may be nil. Responses are only cached if config has a CacheDir.
+func New(config *Config, src auth.TokenSource, logger gomol.WrappableLogger) (*Client, error) {
+	base, err := url.Parse(config.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("restclient: invalid base URL %q", config.BaseURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	// the outermost transport adds the token, so that responses are cached for the credentials they were sent for
+	transport := correlation.Transport(http.DefaultTransport)
+	if config.Rate > 0 {
+		limiter := ratelimit.New(&ratelimit.Config{Rate: config.Rate, Burst: config.Burst}, nil)
+		transport = &limitTransport{limiter: limiter, next: transport}
+	}
+	if config.MaxRetries > 0 {
+		transport = &retryTransport{config: *config, logger: logger, next: transport}
+	}
+	if config.CacheDir != "" {
+		transport = &cacheTransport{dir: config.CacheDir, next: transport}
+	}
+	if src != nil {
+		transport = auth.NewTransport(src, transport)
+	}
+
+	return &Client{
+		base:      base,
+		userAgent: config.UserAgent,
+		http:      &http.Client{Transport: transport, Timeout: config.Timeout},
+	}, nil
+}
+
+// Open returns a Client for the API described by config, logging to the App logger, caching responses in the App
+// CacheDir unless config has its own, and recording the requests in the metrics of the App, see App.HTTPTransport.
+// The token is that of config or, if it has none, the one saved with SaveToken for the host of the API; without
+// either, requests are sent without a token.
+func Open(a *app.App, config *Config) (*Client, error) {
+	if config.BaseURL == "" {
+		return nil, errors.New("restclient: no base URL")
+	}
+
+	var src auth.TokenSource
+	if config.Token != "" {
+		src = StaticToken(config.Token)
+	} else {
+		store, err := a.Credentials()
+		if err != nil {
+			return nil, fmt.Errorf("restclient: %w", err)
+		}
+		tok, err := store.Get(tokenKey(config.BaseURL))
+		switch {
+		case err == nil:
+			src = StaticToken(tok)
+		case !errors.Is(err, credstore.ErrNotFound):
+			return nil, fmt.Errorf("restclient: unable to load the token: %w", err)
+		}
+	}
+
+	cfg := *config
+	if cfg.CacheDir == "" {
+		dir, err := a.CacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("restclient: %w", err)
+		}
+		cfg.CacheDir = filepath.Join(dir, "http")
+	}
+	c, err := New(&cfg, src, a.Logger())
+	if err != nil {
+		return nil, err
+	}
+	c.http.Transport = a.HTTPTransport(c.http.Transport)
+	return c, nil
+}
+
+// SaveToken saves token in the App Credentials as the token Open sends to the API at baseURL, such as one the user
+// entered in a login command. An empty token deletes the saved one.
+func SaveToken(a *app.App, baseURL, token string) error {
+	store, err := a.Credentials()
+	if err != nil {
+		return fmt.Errorf("restclient: %w", err)
+	}
+	if token == "" {
+		err = store.Delete(tokenKey(baseURL))
+		if errors.Is(err, credstore.ErrNotFound) {
+			err = nil
+		}
+	} else {
+		err = store.Set(tokenKey(baseURL), token)
+	}
+	if err != nil {
+		return fmt.Errorf("restclient: unable to save the token: %w", err)
+	}
+	return nil
+}
+
+// tokenKey is the key of the token of the API at baseURL in the credentials: the tokens of an host are shared by the
+// versions of its API.
+func tokenKey(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		return "api:" + u.Host
+	}
+	return "api:" + baseURL
+}
+
+// HTTPClient returns the HTTP client of the Client, for requests that are not JSON, such as downloads.
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// URL resolves path against the base URL of the API. A leading slash does not escape the path of the base URL,
+// and absolute URLs, such as the links to next pages, are returned as they are.
+func (c *Client) URL(path string) (*url.URL, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("restclient: invalid path %q: %v", path, err)
+	}
+	if u.IsAbs() {
+		return u, nil
+	}
+	u.Path = strings.TrimPrefix(u.Path, "/")
+	return c.base.ResolveReference(u), nil
+}
+
+// NewRequest returns a request for path, resolved with URL, with the JSON encoding of body, unless it is nil.
+func (c *Client) NewRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	u, err := c.URL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("restclient: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+// Do sends req and decodes the JSON body of the response into v, unless v is nil or the response has no content.
+// A response with a status other than 2xx is returned as an *Error. The body of the returned response is closed.
+func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		return res, &Error{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Message:    errorMessage(res.Header.Get("Content-Type"), body),
+			Header:     res.Header,
+		}
+	}
+
+	if v != nil && res.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil && err != io.EOF {
+			return res, fmt.Errorf("restclient: invalid response from %s: %v", req.URL.Redacted(), err)
+		}
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return res, nil
+}
+
+// Get requests path and decodes the JSON response into v.
+func (c *Client) Get(ctx context.Context, path string, v interface{}) error {
+	return c.Send(ctx, http.MethodGet, path, nil, v)
+}
+
+// Send sends a request for path with the JSON encoding of in, unless it is nil, and decodes the JSON response into
+// out, unless it is nil.
+func (c *Client) Send(ctx context.Context, method, path string, in, out interface{}) error {
+	req, err := c.NewRequest(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	_, err = c.Do(req, out)
+	return err
+}
+
+// errorMessage returns the message of an error response: the first of the usual message fields of a JSON body, or
+// the first line of a text body.
+func errorMessage(contentType string, body []byte) string {
+	if strings.Contains(contentType, "json") {
+		var doc map[string]interface{}
+		if json.Unmarshal(body, &doc) == nil {
+			for _, key := range [...]string{"message", "detail", "error_description", "error", "title"} {
+				if s, ok := doc[key].(string); ok && s != "" {
+					return s
+				}
+			}
+		}
+		return ""
+	}
+	if strings.HasPrefix(contentType, "text/plain") {
+		line, _, _ := strings.Cut(strings.TrimSpace(string(body)), "\n")
+		if len(line) > 200 {
+			line = line[:200] + "..."
+		}
+		return line
+	}
+	return ""
+}
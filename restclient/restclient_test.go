@@ -0,0 +1,237 @@
+package restclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/correlation"
+	"github.com/demosdemon/golang-app-framework/credstore"
+	"github.com/demosdemon/golang-app-framework/restclient"
+)
+
+func newClient(t *testing.T, handler http.HandlerFunc, config *restclient.Config) *restclient.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	if config == nil {
+		config = restclient.DefaultConfig()
+	}
+	config.BaseURL = srv.URL + "/v1"
+	config.RetryBackoff = time.Millisecond
+	c, err := restclient.New(config, restclient.StaticToken("secret"), nil)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_Send(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/users", r.URL.Path)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "tool/1.0", r.Header.Get("User-Agent"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "req-1", r.Header.Get(correlation.Header))
+
+		var in map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": "1", "name": in["name"]})
+	}, &restclient.Config{UserAgent: "tool/1.0"})
+
+	var out struct{ ID, Name string }
+	ctx := correlation.NewContext(context.Background(), "req-1")
+	require.NoError(t, c.Send(ctx, http.MethodPost, "/users", map[string]string{"name": "ada"}, &out))
+	assert.Equal(t, "1", out.ID)
+	assert.Equal(t, "ada", out.Name)
+}
+
+func TestClient_Error(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/json" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"message":"Not Found","documentation_url":"https://example.com"}`)
+			return
+		}
+		http.Error(w, "no such thing\nat all", http.StatusGone)
+	}, nil)
+
+	err := c.Get(context.Background(), "json", nil)
+	var apiErr *restclient.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.EqualError(t, err, "restclient: 404 Not Found: Not Found")
+
+	err = c.Get(context.Background(), "text", nil)
+	assert.EqualError(t, err, "restclient: 410 Gone: no such thing")
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls int32
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch n := atomic.AddInt32(&calls, 1); {
+		case r.URL.Path == "/v1/limited" && n == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.URL.Path == "/v1/limited":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}, &restclient.Config{MaxRetries: 2})
+
+	err := c.Get(context.Background(), "down", nil)
+	assert.EqualError(t, err, "restclient: 503 Service Unavailable")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	// a POST may not be idempotent, so it is only retried if it was refused
+	atomic.StoreInt32(&calls, 0)
+	err = c.Send(context.Background(), http.MethodPost, "down", map[string]int{"n": 1}, nil)
+	assert.EqualError(t, err, "restclient: 503 Service Unavailable")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	assert.NoError(t, c.Send(context.Background(), http.MethodPost, "limited", map[string]int{"n": 1}, nil))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestClient_Rate(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, &restclient.Config{Rate: 20, Burst: 1})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, c.Get(context.Background(), "ping", nil))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestClient_Cache(t *testing.T) {
+	var full, revalidated int32
+	dir := t.TempDir()
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"name":"ada"}`)
+	}, &restclient.Config{CacheDir: dir})
+
+	for i := 0; i < 3; i++ {
+		var user struct{ Name string }
+		require.NoError(t, c.Get(context.Background(), "user", &user))
+		assert.Equal(t, "ada", user.Name)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&full))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&revalidated))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestItems(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</v1/repos?page=2>; rel="next", </v1/repos?page=3>; rel="last"`)
+			_, _ = fmt.Fprint(w, `[1, 2]`)
+		case "2":
+			w.Header().Set("Link", `<`+"http://"+r.Host+`/v1/repos?page=3>; rel="next"`)
+			_, _ = fmt.Fprint(w, `[3]`)
+		default:
+			_, _ = fmt.Fprint(w, `[4, 5]`)
+		}
+	}, nil)
+
+	var items []int
+	for item, err := range restclient.Items[int](context.Background(), c, "repos") {
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, items)
+
+	items = nil
+	for item, err := range restclient.Items[int](context.Background(), c, "repos") {
+		require.NoError(t, err)
+		if items = append(items, item); len(items) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3}, items)
+}
+
+func TestCursorItems(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("after") {
+		case "":
+			_, _ = fmt.Fprint(w, `{"members":["a","b"],"meta":{"next":"c2"}}`)
+		case "c2":
+			_, _ = fmt.Fprint(w, `{"members":["c"],"meta":{"next":null}}`)
+		}
+	}, nil)
+
+	var items []string
+	cursor := restclient.Cursor{Items: "members", Next: "meta.next", Param: "after"}
+	for item, err := range restclient.CursorItems[string](context.Background(), c, "members?limit=2", cursor) {
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+}
+
+func TestOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		_, _ = fmt.Fprintf(w, `{"token":%q}`, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	cache := t.TempDir()
+	a := &app.App{
+		Environment:     []string{"APP_CACHE_DIR=" + cache},
+		Context:         context.Background(),
+		CredentialStore: new(credstore.MemoryStore),
+	}
+	config := restclient.DefaultConfig()
+	config.BaseURL = srv.URL + "/v1"
+
+	get := func() string {
+		c, err := restclient.Open(a, config)
+		require.NoError(t, err)
+		var out struct{ Token string }
+		require.NoError(t, c.Get(context.Background(), "me", &out))
+		return out.Token
+	}
+
+	assert.Empty(t, get())
+	require.NoError(t, restclient.SaveToken(a, srv.URL+"/v2", "saved"))
+	assert.Equal(t, "Bearer saved", get())
+	require.NoError(t, restclient.SaveToken(a, srv.URL, ""))
+	assert.Empty(t, get())
+
+	config.Token = "explicit"
+	assert.Equal(t, "Bearer explicit", get())
+	assert.DirExists(t, filepath.Join(cache, "http"))
+
+	_, err := restclient.Open(a, &restclient.Config{})
+	assert.EqualError(t, err, "restclient: no base URL")
+}
@@ -0,0 +1,275 @@
+package restclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+	"github.com/demosdemon/golang-app-framework/ratelimit"
+)
+
+// maxCacheBody bounds the size of the responses that are cached.
+const maxCacheBody = 8 << 20
+
+// retryTransport retries requests that failed with a network error, 429 Too Many Requests, or, for idempotent
+// requests, 502, 503, and 504, waiting RetryBackoff, doubled with every retry, or what Retry-After asks for.
+type retryTransport struct {
+	config Config
+	logger gomol.WrappableLogger
+	next   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for retries := 0; ; retries++ {
+		attempt := req
+		if retries > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+
+		res, err := t.next.RoundTrip(attempt)
+		if retries >= t.config.MaxRetries || !retryable(req, res, err) {
+			return res, err
+		}
+
+		wait := t.backoff(retries+1, res)
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = res.Status
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxErrorBody))
+			_ = res.Body.Close()
+		}
+		if t.logger != nil {
+			_ = t.logger.Log(gomol.LevelDebug, gomol.NewAttrsFromMap(map[string]interface{}{
+				"method":  req.Method,
+				"url":     req.URL.Redacted(),
+				"retries": retries + 1,
+			}), "request failed, retrying in %s: %s", wait, reason)
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether the attempt of req that ended with res or err is worth retrying.
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil && idempotent(req.Method)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(req.Method)
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the retry, honoring a Retry-After response header within MaxBackoff.
+func (t *retryTransport) backoff(retry int, res *http.Response) time.Duration {
+	backoff := t.config.RetryBackoff
+	for i := 1; i < retry && backoff < t.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if res != nil {
+		if v := res.Header.Get("Retry-After"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil {
+				backoff = time.Duration(secs) * time.Second
+			} else if at, err := http.ParseTime(v); err == nil {
+				backoff = time.Until(at)
+			}
+		}
+	}
+
+	if t.config.MaxBackoff > 0 && backoff > t.config.MaxBackoff {
+		backoff = t.config.MaxBackoff
+	}
+	return backoff
+}
+
+// limitTransport delays requests to keep under the rate of its limiter.
+type limitTransport struct {
+	limiter *ratelimit.Limiter
+	next    http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		wait, ok := t.limiter.Allow("")
+		if ok {
+			return t.next.RoundTrip(req)
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cacheTransport keeps the responses to GET requests that have an ETag or Last-Modified header in files in dir, and
+// revalidates them with a conditional request: if the API answers 304 Not Modified, the kept response is returned,
+// saving the transfer of the body and, with most APIs, the rate limit.
+type cacheTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.dir, cacheKey(req)+".json")
+	cached := loadCached(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if v := cached.Header.Get("ETag"); v != "" {
+			req.Header.Set("If-None-Match", v)
+		}
+		if v := cached.Header.Get("Last-Modified"); v != "" {
+			req.Header.Set("If-Modified-Since", v)
+		}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && cached != nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		return cached.response(req), nil
+	}
+
+	if res.StatusCode != http.StatusOK || (res.Header.Get("ETag") == "" && res.Header.Get("Last-Modified") == "") ||
+		strings.Contains(res.Header.Get("Cache-Control"), "no-store") || res.ContentLength > maxCacheBody {
+		return res, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxCacheBody+1))
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCacheBody {
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), res.Body), res.Body}
+		return res, nil
+	}
+	_ = res.Body.Close()
+
+	// a response that cannot be cached is still a response
+	_ = saveCached(path, &cachedResponse{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Header:     res.Header,
+		Body:       body,
+	})
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// cacheKey identifies the responses to req: those to the same URL, accepting the same formats, for the same
+// credentials, which are hashed so that they are not kept in the clear.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	for _, v := range [...]string{req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization")} {
+		_, _ = io.WriteString(h, v)
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadCached(path string) *cachedResponse {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if json.Unmarshal(b, &cached) != nil {
+		return nil
+	}
+	return &cached
+}
+
+func saveCached(path string, cached *cachedResponse) error {
+	b, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(path, b, 0o600)
+}
+
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        c.Status,
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        c.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
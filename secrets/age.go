@@ -0,0 +1,88 @@
+// Package secrets decrypts configuration that is committed to version control encrypted: values and files encrypted
+// with age (https://age-encryption.org) to X25519 recipients, and dotenv files encrypted by SOPS with age keys.
+//
+//	identities, err := secrets.ParseIdentities(strings.NewReader(os.Getenv("APP_AGE_KEY")))
+//	plaintext, err := secrets.Decrypt(ciphertext, identities...)
+//
+// The age files are decrypted by filippo.io/age. Only decryption is supported; encrypt with the age and sops command
+// line tools.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ErrNoIdentity is returned by Decrypt when none of the identities can decrypt the file.
+var ErrNoIdentity = errors.New("secrets: no identity matched any of the recipients")
+
+// Identity is an age X25519 secret key.
+type Identity = age.X25519Identity
+
+// ParseIdentity parses an age secret key, such as AGE-SECRET-KEY-1QQPQ....
+func ParseIdentity(s string) (*Identity, error) {
+	id, err := age.ParseX25519Identity(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("secrets: invalid identity: %v", err)
+	}
+	return id, nil
+}
+
+// ParseIdentities parses the secret keys in an age identity file, one per line. Empty lines and lines starting with #
+// are skipped.
+func ParseIdentities(r io.Reader) ([]*Identity, error) {
+	var identities []*Identity
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("%v on line %d", err, n)
+		}
+		identities = append(identities, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("secrets: read identities: %v", err)
+	}
+	if len(identities) == 0 {
+		return nil, errors.New("secrets: no identities found")
+	}
+	return identities, nil
+}
+
+// Decrypt decrypts an age file, binary or armored, with the first of the identities it was encrypted to.
+func Decrypt(data []byte, identities ...*Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(data)
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(trimmed))
+	}
+
+	ids := make([]age.Identity, len(identities))
+	for idx, id := range identities {
+		ids[idx] = id
+	}
+	r, err := age.Decrypt(src, ids...)
+	var noMatch *age.NoIdentityMatchError
+	switch {
+	case errors.As(err, &noMatch):
+		return nil, ErrNoIdentity
+	case err != nil:
+		return nil, fmt.Errorf("secrets: %v", err)
+	}
+
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: %v", err)
+	}
+	return plaintext, nil
+}
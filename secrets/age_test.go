@@ -0,0 +1,68 @@
+package secrets_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/secrets"
+)
+
+func TestParseIdentities(t *testing.T) {
+	// the example key from the age README
+	ids, err := secrets.ParseIdentities(strings.NewReader(`# created: 2019-12-20T19:28:10Z
+# public key: age1lvyvwawkr0mcnnnncaghunadrqkmuf9e6507x9y920xxpp866cnql7dp2z
+
+AGE-SECRET-KEY-1N9JEPW6DWJ0ZQUDX63F5A03GX8QUW7PXDE39N8UYF82VZ9PC8UFS3M7XA9
+`))
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	assert.Equal(t, "age1lvyvwawkr0mcnnnncaghunadrqkmuf9e6507x9y920xxpp866cnql7dp2z", ids[0].Recipient().String())
+
+	for line, input := range map[int]string{
+		1: "AGE-SECRET-KEY-1N9JEPW6DWJ",
+		2: "\nage1lvyvwawkr0mcnnnncaghunadrqkmuf9e6507x9y920xxpp866cnql7dp2z",
+	} {
+		_, err := secrets.ParseIdentities(strings.NewReader(input))
+		assert.ErrorContains(t, err, "secrets: invalid identity: ", input)
+		assert.ErrorContains(t, err, fmt.Sprintf(" on line %d", line), input)
+	}
+	for _, input := range []string{"", "# only a comment"} {
+		_, err := secrets.ParseIdentities(strings.NewReader(input))
+		assert.EqualError(t, err, "secrets: no identities found", input)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	alice, _ := secrets.GenerateIdentity()
+	bob, _ := secrets.GenerateIdentity()
+	eve, _ := secrets.GenerateIdentity()
+
+	for _, size := range []int{0, 1, 100, 64 * 1024, 200 * 1024} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+		ciphertext := secrets.Encrypt(plaintext, alice, bob)
+
+		for _, data := range [][]byte{ciphertext, secrets.Armor(ciphertext)} {
+			actual, err := secrets.Decrypt(data, eve, bob)
+			assert.NoError(t, err, size)
+			assert.Equal(t, plaintext, actual, size)
+		}
+
+		_, err := secrets.Decrypt(ciphertext, eve)
+		assert.ErrorIs(t, err, secrets.ErrNoIdentity)
+
+		if size > 0 {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[len(tampered)-1] ^= 1
+			_, err = secrets.Decrypt(tampered, alice)
+			assert.ErrorContains(t, err, "secrets: ")
+		}
+	}
+
+	_, err := secrets.Decrypt([]byte("hello"), alice)
+	assert.ErrorContains(t, err, "secrets: ")
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// dotenvEntry is a line of a dotenv file: a variable or a comment.
+type dotenvEntry struct {
+	key     string
+	value   string
+	comment bool
+}
+
+// ParseDotenv returns the variables in a dotenv file in the form KEY=value, skipping comments and empty lines. A \n
+// in a value stands for a newline.
+func ParseDotenv(data []byte) ([]string, error) {
+	entries, err := parseDotenv(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for _, e := range entries {
+		if !e.comment {
+			env = append(env, e.key+"="+e.value)
+		}
+	}
+	return env, nil
+}
+
+func parseDotenv(data []byte) ([]dotenvEntry, error) {
+	var entries []dotenvEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			entries = append(entries, dotenvEntry{value: line[1:], comment: true})
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("secrets: invalid dotenv line %d: expected KEY=value", n)
+		}
+		entries = append(entries, dotenvEntry{key: key, value: strings.ReplaceAll(value, `\n`, "\n")})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("secrets: read dotenv: %v", err)
+	}
+	return entries, nil
+}
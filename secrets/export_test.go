@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// GenerateIdentity returns a new identity and its secret key.
+func GenerateIdentity() (*Identity, string) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		panic(err)
+	}
+	return id, id.String()
+}
+
+// Encrypt encrypts plaintext to the recipients with age.
+func Encrypt(plaintext []byte, recipients ...*Identity) []byte {
+	to := make([]age.Recipient, len(recipients))
+	for idx, id := range recipients {
+		to[idx] = id.Recipient()
+	}
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, to...)
+	if err != nil {
+		panic(err)
+	}
+	_, _ = w.Write(plaintext)
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return out.Bytes()
+}
+
+// Armor returns the age file in the armored format.
+func Armor(data []byte) []byte {
+	var out bytes.Buffer
+	w := armor.NewWriter(&out)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return out.Bytes()
+}
+
+// EncryptDotenv encrypts the dotenv lines as SOPS does, leaving the values of variables with the suffix
+// _unencrypted in the clear.
+func EncryptDotenv(lines []string, lastModified string, recipients ...*Identity) []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+
+	hash := sha512.New()
+	var out strings.Builder
+	for _, line := range lines {
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			hash.Write([]byte(comment))
+			out.WriteString("#" + sopsEncrypt(key, comment, ":", "comment") + "\n")
+			continue
+		}
+		k, v, _ := strings.Cut(line, "=")
+		hash.Write([]byte(v))
+		if !strings.HasSuffix(k, "_unencrypted") {
+			v = sopsEncrypt(key, v, k+":", "str")
+		}
+		out.WriteString(k + "=" + v + "\n")
+	}
+
+	for idx, r := range recipients {
+		enc := strings.ReplaceAll(string(Armor(Encrypt(key, r))), "\n", `\n`)
+		_, _ = fmt.Fprintf(&out, "sops_age__list_%d__map_enc=%s\n", idx, enc)
+		_, _ = fmt.Fprintf(&out, "sops_age__list_%d__map_recipient=%s\n", idx, r.Recipient().String())
+	}
+	_, _ = fmt.Fprintf(&out, "sops_lastmodified=%s\n", lastModified)
+	mac := sopsEncrypt(key, fmt.Sprintf("%X", hash.Sum(nil)), lastModified, "str")
+	_, _ = fmt.Fprintf(&out, "sops_mac=%s\n", mac)
+	out.WriteString("sops_unencrypted_suffix=_unencrypted\nsops_version=3.8.1\n")
+	return []byte(out.String())
+}
+
+func sopsEncrypt(key []byte, plaintext, additionalData, typ string) string {
+	if plaintext == "" {
+		return ""
+	}
+	iv := make([]byte, 32)
+	_, _ = rand.Read(iv)
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCMWithNonceSize(block, len(iv))
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv), base64.StdEncoding.EncodeToString(tag), typ)
+}
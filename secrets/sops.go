@@ -0,0 +1,212 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const sopsPrefix = "sops_"
+
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// IsSOPS reports whether the dotenv file was encrypted by SOPS.
+func IsSOPS(data []byte) bool {
+	return bytes.Contains(data, []byte("\n"+sopsPrefix+"mac=")) || bytes.HasPrefix(data, []byte(sopsPrefix+"mac="))
+}
+
+type sopsMetadata struct {
+	values map[string]string
+
+	unencryptedSuffix string
+	encryptedSuffix   string
+	unencryptedRegex  *regexp.Regexp
+	encryptedRegex    *regexp.Regexp
+	macOnlyEncrypted  bool
+}
+
+// DecryptDotenv decrypts a dotenv file encrypted by SOPS with an age key, returning its variables in the form
+// KEY=value. The message authentication code of the file is verified, so that values cannot be removed, added, or
+// swapped without the key. The data key is decrypted with age; the file is read here rather than with the sops
+// decrypt package, which links the clients of every key service sops supports.
+func DecryptDotenv(data []byte, identities ...*Identity) ([]string, error) {
+	entries, err := parseDotenv(data)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &sopsMetadata{values: make(map[string]string)}
+	var tree []dotenvEntry
+	for _, e := range entries {
+		if !e.comment && strings.HasPrefix(e.key, sopsPrefix) {
+			meta.values[strings.TrimPrefix(e.key, sopsPrefix)] = e.value
+		} else {
+			tree = append(tree, e)
+		}
+	}
+	if err := meta.parse(); err != nil {
+		return nil, err
+	}
+
+	key, err := meta.dataKey(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha512.New()
+	var env []string
+	for _, e := range tree {
+		path := ""
+		if !e.comment {
+			path = e.key
+		}
+		encrypted := meta.encrypted(path)
+
+		v := e.value
+		if encrypted {
+			if v, err = sopsDecrypt(key, e.value, path+":"); err != nil {
+				if e.comment {
+					return nil, fmt.Errorf("secrets: decrypt comment: %v", err)
+				}
+				return nil, fmt.Errorf("secrets: decrypt %s: %v", e.key, err)
+			}
+		}
+		if encrypted || !meta.macOnlyEncrypted {
+			hash.Write([]byte(v))
+		}
+		if !e.comment {
+			env = append(env, e.key+"="+v)
+		}
+	}
+
+	mac, err := sopsDecrypt(key, meta.values["mac"], meta.values["lastmodified"])
+	if err != nil {
+		return nil, fmt.Errorf("secrets: decrypt MAC: %v", err)
+	}
+	if !strings.EqualFold(mac, fmt.Sprintf("%X", hash.Sum(nil))) {
+		return nil, errors.New("secrets: SOPS MAC mismatch, the file was modified")
+	}
+	return env, nil
+}
+
+func (m *sopsMetadata) parse() error {
+	if m.values["mac"] == "" {
+		return errors.New("secrets: not a SOPS file, sops_mac is missing")
+	}
+
+	m.unencryptedSuffix = m.values["unencrypted_suffix"]
+	m.encryptedSuffix = m.values["encrypted_suffix"]
+	m.macOnlyEncrypted = strings.EqualFold(m.values["mac_only_encrypted"], "true")
+
+	for key, dst := range map[string]**regexp.Regexp{
+		"unencrypted_regex": &m.unencryptedRegex,
+		"encrypted_regex":   &m.encryptedRegex,
+	} {
+		if v := m.values[key]; v != "" {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("secrets: invalid sops_%s %q", key, v)
+			}
+			*dst = re
+		}
+	}
+	return nil
+}
+
+// encrypted reports whether the value at the path was encrypted, following the rules SOPS encrypted it with.
+func (m *sopsMetadata) encrypted(path string) bool {
+	encrypted := true
+	if m.unencryptedSuffix != "" && strings.HasSuffix(path, m.unencryptedSuffix) {
+		encrypted = false
+	}
+	if m.encryptedSuffix != "" {
+		encrypted = strings.HasSuffix(path, m.encryptedSuffix)
+	}
+	if m.unencryptedRegex != nil && m.unencryptedRegex.MatchString(path) {
+		encrypted = false
+	}
+	if m.encryptedRegex != nil {
+		encrypted = m.encryptedRegex.MatchString(path)
+	}
+	return encrypted
+}
+
+// dataKey decrypts the key the values were encrypted with, stored encrypted to each of the age recipients.
+func (m *sopsMetadata) dataKey(identities []*Identity) ([]byte, error) {
+	found := false
+	for idx := 0; ; idx++ {
+		enc, ok := m.values[fmt.Sprintf("age__list_%d__map_enc", idx)]
+		if !ok {
+			break
+		}
+		found = true
+
+		key, err := Decrypt([]byte(enc), identities...)
+		switch {
+		case errors.Is(err, ErrNoIdentity):
+			continue
+		case err != nil:
+			return nil, err
+		case len(key) != 32:
+			return nil, errors.New("secrets: invalid SOPS data key")
+		}
+		return key, nil
+	}
+
+	if !found {
+		return nil, errors.New("secrets: the SOPS file has no age recipients")
+	}
+	return nil, ErrNoIdentity
+}
+
+// sopsDecrypt decrypts a value SOPS encrypted with AES-GCM, authenticating the additional data with it.
+func sopsDecrypt(key []byte, value, additionalData string) (string, error) {
+	if value == "" {
+		// SOPS leaves empty values as they are
+		return "", nil
+	}
+
+	m := sopsValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", errors.New("not a SOPS encrypted value")
+	}
+
+	var parts [3][]byte
+	for idx := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[idx+1])
+		if err != nil {
+			return "", errors.New("invalid SOPS encrypted value")
+		}
+		parts[idx] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) == 0 {
+		return "", errors.New("invalid SOPS encrypted value")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", errors.New("authentication failed")
+	}
+
+	switch m[4] {
+	case "str", "comment", "bytes":
+		return string(plaintext), nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", m[4])
+	}
+}
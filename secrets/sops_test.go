@@ -0,0 +1,60 @@
+package secrets_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/secrets"
+)
+
+func TestDecryptDotenv(t *testing.T) {
+	alice, _ := secrets.GenerateIdentity()
+	bob, _ := secrets.GenerateIdentity()
+	eve, _ := secrets.GenerateIdentity()
+
+	lines := []string{
+		"# the database",
+		"DB_PASSWORD=hunter2",
+		"DB_HOST_unencrypted=db",
+		"EMPTY=",
+		"CERT=line one\nline two",
+	}
+	data := secrets.EncryptDotenv(lines, "2024-01-02T03:04:05Z", alice, bob)
+	assert.True(t, secrets.IsSOPS(data))
+	assert.NotContains(t, string(data), "hunter2")
+	assert.Contains(t, string(data), "DB_HOST_unencrypted=db\n")
+
+	env, err := secrets.DecryptDotenv(data, bob)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD=hunter2", "DB_HOST_unencrypted=db", "EMPTY=", "CERT=line one\nline two"}, env)
+
+	_, err = secrets.DecryptDotenv(data, eve)
+	assert.ErrorIs(t, err, secrets.ErrNoIdentity)
+
+	// values in the clear are covered by the MAC
+	tampered := bytes.Replace(data, []byte("DB_HOST_unencrypted=db"), []byte("DB_HOST_unencrypted=evil"), 1)
+	_, err = secrets.DecryptDotenv(tampered, alice)
+	assert.EqualError(t, err, "secrets: SOPS MAC mismatch, the file was modified")
+
+	// a value moved to another key fails to authenticate
+	swapped := bytes.Replace(data, []byte("DB_PASSWORD="), []byte("DB_PASSWORT="), 1)
+	_, err = secrets.DecryptDotenv(swapped, alice)
+	assert.EqualError(t, err, "secrets: decrypt DB_PASSWORT: authentication failed")
+
+	plain := []byte("A=1\n")
+	assert.False(t, secrets.IsSOPS(plain))
+	_, err = secrets.DecryptDotenv(plain, alice)
+	assert.EqualError(t, err, "secrets: not a SOPS file, sops_mac is missing")
+}
+
+func TestParseDotenv(t *testing.T) {
+	env, err := secrets.ParseDotenv([]byte("# comment\n\nA=1\nB=x=y\nC=two\\nlines\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A=1", "B=x=y", "C=two\nlines"}, env)
+
+	_, err = secrets.ParseDotenv([]byte("A=1\nnonsense\n"))
+	assert.EqualError(t, err, "secrets: invalid dotenv line 2: expected KEY=value")
+}
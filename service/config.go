@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+// DefaultPrefix prefixes the defaults of the generated service files, as in APP_SERVICE_FORMAT.
+const DefaultPrefix = "APP_SERVICE_"
+
+// Format is the kind of service definition rendered.
+type Format string
+
+// The supported formats.
+const (
+	Systemd Format = "systemd" // a systemd unit
+	Launchd Format = "launchd" // a launchd property list
+	OpenRC  Format = "openrc"  // an OpenRC init script
+)
+
+// ParseFormat returns the Format named s.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case Systemd, Launchd, OpenRC:
+		return f, nil
+	default:
+		return "", fmt.Errorf("service: unknown format %q", s)
+	}
+}
+
+// Dir returns the system directory the definitions of the format are installed in.
+func (f Format) Dir() string {
+	switch f {
+	case Launchd:
+		return "/Library/LaunchDaemons"
+	case OpenRC:
+		return "/etc/init.d"
+	default:
+		return "/etc/systemd/system"
+	}
+}
+
+// Config describes how the service definition is rendered and installed.
+type Config struct {
+	Format Format // the format of the definition
+	Dir    string // the directory the definition is installed in, Format.Dir() if empty
+	User   string // the user the service runs as, root if empty
+}
+
+// DefaultConfig returns a Config for the service manager of the running system: launchd on macOS, OpenRC when it
+// is installed and systemd is not running, and systemd otherwise.
+func DefaultConfig() *Config {
+	return &Config{Format: detectFormat()}
+}
+
+func detectFormat() Format {
+	if runtime.GOOS == "darwin" {
+		return Launchd
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		if _, err := os.Stat("/sbin/openrc-run"); err == nil {
+			return OpenRC
+		}
+	}
+	return Systemd
+}
+
+func init() {
+	configschema.Register("service", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the service definition variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "FORMAT", Type: "string",
+			Description: "The format of the definition: systemd, launchd, or openrc; detected if not set."},
+		{Name: prefix + "DIR", Type: "path", Description: "The directory the definition is installed in."},
+		{Name: prefix + "USER", Type: "string", Default: "root", Description: "The user the service runs as."},
+	}
+}
+
+// FromEnv reads the FORMAT of the definition, the DIR it is installed in, and the USER the service runs as, with the
+// prefix or DefaultPrefix. The format is detected from the system when FORMAT is not set.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("FORMAT"); v != "" {
+		f, err := ParseFormat(v)
+		if err != nil {
+			return nil, fmt.Errorf("service: invalid %sFORMAT %q", prefix, v)
+		}
+		config.Format = f
+	}
+
+	if v := get("DIR"); v != "" {
+		if !filepath.IsAbs(v) {
+			return nil, fmt.Errorf("service: invalid %sDIR %q", prefix, v)
+		}
+		config.Dir = v
+	}
+
+	config.User = get("USER")
+
+	return config, nil
+}
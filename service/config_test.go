@@ -0,0 +1,53 @@
+package service_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/service"
+)
+
+func TestFromEnv_Format(t *testing.T) {
+	for v, expected := range map[string]service.Format{
+		"systemd":  service.Systemd,
+		"LaunchD":  service.Launchd,
+		" OpenRC ": service.OpenRC,
+	} {
+		config, err := service.FromEnv(apptest.Lookup(map[string]string{"TOOL_SERVICE_FORMAT": v}), "TOOL_SERVICE_")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.Format, v)
+	}
+
+	// without FORMAT the running system decides
+	config, err := service.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, service.DefaultConfig(), config)
+
+	_, err = service.FromEnv(apptest.Lookup(map[string]string{"APP_SERVICE_FORMAT": "upstart"}), "")
+	assert.EqualError(t, err, `service: invalid APP_SERVICE_FORMAT "upstart"`)
+}
+
+func TestFromEnv_Dir(t *testing.T) {
+	config, err := service.FromEnv(apptest.Lookup(map[string]string{
+		"APP_SERVICE_DIR":  "/usr/local/etc/init.d",
+		"APP_SERVICE_USER": " app ",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/local/etc/init.d", config.Dir)
+	assert.Equal(t, "app", config.User)
+
+	// the definition is installed by a privileged command, whose working directory is not the app's
+	for _, v := range []string{"services", "./init.d", "~/Library/LaunchAgents"} {
+		_, err := service.FromEnv(apptest.Lookup(map[string]string{"APP_SERVICE_DIR": v}), "")
+		assert.EqualError(t, err, `service: invalid APP_SERVICE_DIR "`+v+`"`, v)
+	}
+}
+
+func TestFormat_Dir(t *testing.T) {
+	assert.Equal(t, "/etc/systemd/system", service.Systemd.Dir())
+	assert.Equal(t, "/Library/LaunchDaemons", service.Launchd.Dir())
+	assert.Equal(t, "/etc/init.d", service.OpenRC.Dir())
+}
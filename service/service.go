@@ -0,0 +1,355 @@
+// Package service installs the app as a system service. It renders a systemd unit, a launchd property list, or an
+// OpenRC init script from the Service metadata and installs it in the system directory of the service manager. Run
+// implements a "service" command family for the main function to dispatch to:
+//
+//	if len(a.Arguments) > 0 && a.Arguments[0] == "service" {
+//		config, err := service.FromEnv(a.LookupEnv, "")
+//		if err == nil {
+//			err = service.Run(a, svc, config, a.Arguments[1:])
+//		}
+//		...
+//	}
+package service
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// Service describes the app as a service.
+type Service struct {
+	Name        string   // the name of the service and its definition file, e.g. a reverse DNS name for launchd
+	Description string   // a one line description
+	Executable  string   // the absolute path of the program, the running executable if empty
+	Arguments   []string // the arguments the program is started with
+	Env         []string // the environment variables the service requires, installed with their current values
+}
+
+// Path returns the path the definition of the service is installed at.
+func (s *Service) Path(config *Config) string {
+	dir := config.Dir
+	if dir == "" {
+		dir = config.Format.Dir()
+	}
+
+	switch config.Format {
+	case Systemd:
+		return filepath.Join(dir, s.Name+".service")
+	case Launchd:
+		return filepath.Join(dir, s.Name+".plist")
+	default:
+		return filepath.Join(dir, s.Name)
+	}
+}
+
+// EnvPath returns the path the values of the required environment variables are installed at, readable only by
+// their owner: the file name.env next to a systemd unit, or the conf.d file of an OpenRC init script. A launchd
+// property list holds the values itself.
+func (s *Service) EnvPath(config *Config) string {
+	switch {
+	case config.Format == OpenRC && config.Dir == "":
+		return filepath.Join("/etc/conf.d", s.Name)
+	case config.Dir == "":
+		return filepath.Join(config.Format.Dir(), s.Name+".env")
+	default:
+		return filepath.Join(config.Dir, s.Name+".env")
+	}
+}
+
+// Render returns the definition of the service in the configured format. The values of the required environment
+// variables are read with lookup, typically App.LookupEnv; a systemd unit and an OpenRC init script read them from
+// the file at EnvPath, rendered by RenderEnv, so that they are not readable by everyone. Values with line breaks are
+// rejected, since they would end the line they are written on.
+func (s *Service) Render(config *Config, lookup func(string) (string, bool)) ([]byte, error) {
+	if s.Name == "" || strings.ContainsAny(s.Name, "/ \t\n\r") {
+		return nil, fmt.Errorf("service: invalid name %q", s.Name)
+	}
+	for _, v := range append([]string{s.Description, s.Executable, config.User}, s.Arguments...) {
+		if strings.ContainsAny(v, "\n\r") {
+			return nil, fmt.Errorf("service: invalid value %q: contains a line break", v)
+		}
+	}
+
+	exe := s.Executable
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return nil, fmt.Errorf("service: %v", err)
+		}
+	}
+	command := append([]string{exe}, s.Arguments...)
+
+	env, err := s.environment(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	switch config.Format {
+	case Systemd:
+		renderSystemd(&b, s, config.User, command, len(env) > 0, s.EnvPath(config))
+	case Launchd:
+		renderLaunchd(&b, s, config.User, command, env)
+	case OpenRC:
+		renderOpenRC(&b, s, config.User, command, len(env) > 0, s.EnvPath(config))
+	default:
+		return nil, fmt.Errorf("service: unknown format %q", config.Format)
+	}
+	return []byte(b.String()), nil
+}
+
+// RenderEnv returns the file at EnvPath holding the values of the required environment variables, or nil if there
+// are none or the format keeps them in the definition.
+func (s *Service) RenderEnv(config *Config, lookup func(string) (string, bool)) ([]byte, error) {
+	env, err := s.environment(lookup)
+	if err != nil || len(env) == 0 {
+		return nil, err
+	}
+
+	var b strings.Builder
+	switch config.Format {
+	case Systemd:
+		// systemd expands $ and unescapes \ within double quotes
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+		for _, kv := range env {
+			fmt.Fprintf(&b, "%s=\"%s\"\n", kv[0], quote.Replace(kv[1]))
+		}
+	case OpenRC:
+		for _, kv := range env {
+			fmt.Fprintf(&b, "export %s=%s\n", kv[0], shellQuote(kv[1]))
+		}
+	case Launchd:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("service: unknown format %q", config.Format)
+	}
+	return []byte(b.String()), nil
+}
+
+// environment returns the names and values of the required environment variables.
+func (s *Service) environment(lookup func(string) (string, bool)) ([][2]string, error) {
+	env := make([][2]string, 0, len(s.Env))
+	for _, key := range s.Env {
+		if !validEnvName(key) {
+			return nil, fmt.Errorf("service: invalid variable name %q", key)
+		}
+		v, ok := lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("service: %s is required", key)
+		}
+		if strings.ContainsAny(v, "\n\r") {
+			return nil, fmt.Errorf("service: %s contains a line break", key)
+		}
+		env = append(env, [2]string{key, v})
+	}
+	return env, nil
+}
+
+// validEnvName reports whether name is a valid environment variable name in every format.
+func validEnvName(name string) bool {
+	for idx, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (idx == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// shellQuote quotes v for sh.
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func renderSystemd(b *strings.Builder, s *Service, user string, command []string, hasEnv bool, envPath string) {
+	// % starts a specifier everywhere, $ a variable in the command line
+	quote := func(v string) string {
+		v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(v)
+		return `"` + v + `"`
+	}
+
+	args := make([]string, len(command))
+	for idx, arg := range command {
+		args[idx] = quote(strings.ReplaceAll(arg, "$", "$$"))
+	}
+
+	fmt.Fprintf(b, "[Unit]\nDescription=%s\n", s.Description)
+	b.WriteString("Wants=network-online.target\nAfter=network-online.target\n\n")
+	fmt.Fprintf(b, "[Service]\nType=simple\nExecStart=%s\n", strings.Join(args, " "))
+	if user != "" {
+		fmt.Fprintf(b, "User=%s\n", user)
+	}
+	if hasEnv {
+		fmt.Fprintf(b, "EnvironmentFile=%s\n", quote(envPath))
+	}
+	b.WriteString("Restart=on-failure\n\n[Install]\nWantedBy=multi-user.target\n")
+}
+
+func renderLaunchd(b *strings.Builder, s *Service, user string, command []string, env [][2]string) {
+	str := func(v string) string {
+		return "<string>" + escapeXML(v) + "</string>"
+	}
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" ` +
+		`"http://www.apple.com/DTDs/PropertyList-1.0.dtd">`)
+	b.WriteString("\n<plist version=\"1.0\">\n<dict>\n")
+	fmt.Fprintf(b, "\t<key>Label</key>\n\t%s\n", str(s.Name))
+	if s.Description != "" {
+		// launchd ignores keys it does not know, this one is for people reading the file
+		fmt.Fprintf(b, "\t<key>ServiceDescription</key>\n\t%s\n", str(s.Description))
+	}
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range command {
+		fmt.Fprintf(b, "\t\t%s\n", str(arg))
+	}
+	b.WriteString("\t</array>\n")
+	if user != "" {
+		fmt.Fprintf(b, "\t<key>UserName</key>\n\t%s\n", str(user))
+	}
+	if len(env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, kv := range env {
+			fmt.Fprintf(b, "\t\t<key>%s</key>\n\t\t%s\n", escapeXML(kv[0]), str(kv[1]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n\t<key>KeepAlive</key>\n\t<true/>\n</dict>\n</plist>\n")
+}
+
+func renderOpenRC(b *strings.Builder, s *Service, user string, command []string, hasEnv bool, envPath string) {
+	quote := shellQuote
+
+	// openrc-run evaluates command_args, so each argument is quoted again within it
+	args := make([]string, len(command)-1)
+	for idx, arg := range command[1:] {
+		args[idx] = quote(arg)
+	}
+
+	b.WriteString("#!/sbin/openrc-run\n\n")
+	fmt.Fprintf(b, "description=%s\n", quote(s.Description))
+	fmt.Fprintf(b, "command=%s\n", quote(command[0]))
+	fmt.Fprintf(b, "command_args=%s\n", quote(strings.Join(args, " ")))
+	b.WriteString("command_background=true\npidfile=\"/run/${RC_SVCNAME}.pid\"\n")
+	if user != "" {
+		fmt.Fprintf(b, "command_user=%s\n", quote(user))
+	}
+	if hasEnv {
+		fmt.Fprintf(b, ". %s\n", quote(envPath))
+	}
+	b.WriteString("\ndepend() {\n\tneed net\n}\n")
+}
+
+// escapeXML returns v escaped for XML text.
+func escapeXML(v string) string {
+	var e strings.Builder
+	// strings.Builder never fails to write
+	_ = xml.EscapeText(&e, []byte(v))
+	return e.String()
+}
+
+// Install writes the values of the required environment variables to EnvPath, readable only by its owner, then the
+// definition of the service to its Path, readable by everyone and executable for an init script, and returns the
+// path. A launchd property list that holds values is readable only by its owner.
+func (s *Service) Install(config *Config, lookup func(string) (string, bool)) (string, error) {
+	b, err := s.Render(config, lookup)
+	if err != nil {
+		return "", err
+	}
+	env, err := s.RenderEnv(config, lookup)
+	if err != nil {
+		return "", err
+	}
+
+	perm := os.FileMode(0o644)
+	switch {
+	case config.Format == OpenRC:
+		perm = 0o755
+	case config.Format == Launchd && len(s.Env) > 0:
+		perm = 0o600
+	}
+
+	if env != nil {
+		if err := atomicfile.WriteFile(s.EnvPath(config), env, 0o600); err != nil {
+			return "", fmt.Errorf("service: %v", err)
+		}
+	}
+	path := s.Path(config)
+	if err := atomicfile.WriteFile(path, b, perm); err != nil {
+		return "", fmt.Errorf("service: %v", err)
+	}
+	return path, nil
+}
+
+// Uninstall removes the definition of the service, and its environment file, and returns its path.
+func (s *Service) Uninstall(config *Config) (string, error) {
+	path := s.Path(config)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("service: %s is not installed", s.Name)
+		}
+		return "", fmt.Errorf("service: %v", err)
+	}
+	if err := os.Remove(s.EnvPath(config)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("service: %v", err)
+	}
+	return path, nil
+}
+
+// Run runs the service command named by the first argument:
+//
+//	install    install the definition of the service and print how to start it
+//	uninstall  remove the definition of the service
+//	render     print the definition of the service
+func Run(a *app.App, s *Service, config *Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("service: expected a command: install, uninstall, or render")
+	}
+
+	w := a.Output()
+	switch args[0] {
+	case "render":
+		b, err := s.Render(config, a.LookupEnv)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "install":
+		path, err := s.Install(config, a.LookupEnv)
+		if err != nil {
+			return err
+		}
+		printInstalled(w, s, config, path)
+		return nil
+	case "uninstall":
+		path, err := s.Uninstall(config)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "Removed %s\n", path)
+		return nil
+	default:
+		return fmt.Errorf("service: unknown command %q, expected install, uninstall, or render", args[0])
+	}
+}
+
+func printInstalled(w io.Writer, s *Service, config *Config, path string) {
+	_, _ = fmt.Fprintf(w, "Installed %s\n", path)
+
+	switch config.Format {
+	case Systemd:
+		_, _ = fmt.Fprintf(w, "Start it with: systemctl daemon-reload && systemctl enable --now %s\n", s.Name)
+	case Launchd:
+		_, _ = fmt.Fprintf(w, "Start it with: launchctl bootstrap system %s\n", path)
+	case OpenRC:
+		_, _ = fmt.Fprintf(w, "Start it with: rc-update add %s default && rc-service %s start\n", s.Name, s.Name)
+	}
+}
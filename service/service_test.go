@@ -0,0 +1,209 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/service"
+)
+
+var svc = &service.Service{
+	Name:        "tool",
+	Description: "Tool 100% daemon",
+	Executable:  "/usr/local/bin/tool",
+	Arguments:   []string{"serve", "--greeting=it's $HOME"},
+	Env:         []string{"APP_LISTEN"},
+}
+
+var env = map[string]string{"APP_LISTEN": `tcp://:80 "public"`}
+
+func TestService_Render(t *testing.T) {
+	tests := map[service.Format]string{
+		service.Systemd: `[Unit]
+Description=Tool 100% daemon
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+ExecStart="/usr/local/bin/tool" "serve" "--greeting=it's $$HOME"
+User=tool
+EnvironmentFile="/etc/systemd/system/tool.env"
+Restart=on-failure
+
+[Install]
+WantedBy=multi-user.target
+`,
+		service.Launchd: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>tool</string>
+	<key>ServiceDescription</key>
+	<string>Tool 100% daemon</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/local/bin/tool</string>
+		<string>serve</string>
+		<string>--greeting=it&#39;s $HOME</string>
+	</array>
+	<key>UserName</key>
+	<string>tool</string>
+	<key>EnvironmentVariables</key>
+	<dict>
+		<key>APP_LISTEN</key>
+		<string>tcp://:80 &#34;public&#34;</string>
+	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+</dict>
+</plist>
+`,
+		service.OpenRC: `#!/sbin/openrc-run
+
+description='Tool 100% daemon'
+command='/usr/local/bin/tool'
+command_args=''\''serve'\'' '\''--greeting=it'\''\'\'''\''s $HOME'\'''
+command_background=true
+pidfile="/run/${RC_SVCNAME}.pid"
+command_user='tool'
+. '/etc/conf.d/tool'
+
+depend() {
+	need net
+}
+`,
+	}
+
+	for format, expected := range tests {
+		b, err := svc.Render(&service.Config{Format: format, User: "tool"}, apptest.Lookup(env))
+		require.NoError(t, err, format)
+		assert.Equal(t, expected, string(b), format)
+	}
+}
+
+func TestService_RenderEnv(t *testing.T) {
+	env := map[string]string{"APP_LISTEN": `tcp://:80 "$public" \ it's`}
+	for format, expected := range map[service.Format]string{
+		service.Systemd: `APP_LISTEN="tcp://:80 \"\$public\" \\ it's"` + "\n",
+		service.OpenRC:  `export APP_LISTEN='tcp://:80 "$public" \ it'\''s'` + "\n",
+		service.Launchd: "",
+	} {
+		b, err := svc.RenderEnv(&service.Config{Format: format}, apptest.Lookup(env))
+		require.NoError(t, err, format)
+		assert.Equal(t, expected, string(b), format)
+	}
+
+	b, err := (&service.Service{Name: "tool"}).RenderEnv(&service.Config{Format: service.Systemd}, apptest.Lookup(nil))
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+}
+
+func TestService_Render_Invalid(t *testing.T) {
+	config := &service.Config{Format: service.Systemd}
+
+	_, err := svc.Render(config, apptest.Lookup(nil))
+	assert.EqualError(t, err, "service: APP_LISTEN is required")
+
+	_, err = (&service.Service{Name: "my tool"}).Render(config, apptest.Lookup(nil))
+	assert.EqualError(t, err, `service: invalid name "my tool"`)
+
+	_, err = svc.Render(&service.Config{Format: "upstart"}, apptest.Lookup(env))
+	assert.EqualError(t, err, `service: unknown format "upstart"`)
+
+	// a line break would start another directive
+	_, err = svc.Render(config, apptest.Lookup(map[string]string{"APP_LISTEN": ":80\nExecStartPre=/bin/sh"}))
+	assert.EqualError(t, err, "service: APP_LISTEN contains a line break")
+	_, err = svc.RenderEnv(config, apptest.Lookup(map[string]string{"APP_LISTEN": ":80\r"}))
+	assert.EqualError(t, err, "service: APP_LISTEN contains a line break")
+	_, err = (&service.Service{Name: "tool", Description: "a\nb"}).Render(config, apptest.Lookup(nil))
+	assert.EqualError(t, err, `service: invalid value "a\nb": contains a line break`)
+	_, err = (&service.Service{Name: "tool", Env: []string{"A=B"}}).Render(config, apptest.Lookup(nil))
+	assert.EqualError(t, err, `service: invalid variable name "A=B"`)
+}
+
+func TestService_Install(t *testing.T) {
+	dir := t.TempDir()
+
+	for format, name := range map[service.Format]string{
+		service.Systemd: "tool.service",
+		service.Launchd: "tool.plist",
+		service.OpenRC:  "tool",
+	} {
+		config := &service.Config{Format: format, Dir: dir}
+		path, err := svc.Install(config, apptest.Lookup(env))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, name), path)
+
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		switch format {
+		case service.OpenRC:
+			assert.Equal(t, os.FileMode(0o755), fi.Mode().Perm())
+		case service.Launchd:
+			assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm(), "the property list holds the values")
+		default:
+			assert.Equal(t, os.FileMode(0o644), fi.Mode().Perm())
+		}
+
+		envPath := filepath.Join(dir, "tool.env")
+		if format == service.Launchd {
+			assert.NoFileExists(t, envPath)
+		} else {
+			fi, err = os.Stat(envPath)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm(), "the values are readable only by root")
+		}
+
+		path, err = svc.Uninstall(config)
+		require.NoError(t, err)
+		assert.NoFileExists(t, path)
+		assert.NoFileExists(t, envPath)
+
+		_, err = svc.Uninstall(config)
+		assert.EqualError(t, err, "service: tool is not installed")
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	config := &service.Config{Format: service.Systemd, Dir: dir}
+
+	stdout := new(bytes.Buffer)
+	a := &app.App{
+		Environment: []string{"APP_LISTEN=tcp://:80"},
+		Context:     context.Background(),
+		Stdout:      stdout,
+		Stderr:      new(bytes.Buffer),
+	}
+
+	require.NoError(t, service.Run(a, svc, config, []string{"render"}))
+	assert.Contains(t, stdout.String(), `EnvironmentFile="`+filepath.Join(dir, "tool.env")+`"`)
+	assert.NotContains(t, stdout.String(), "tcp://:80")
+	stdout.Reset()
+
+	require.NoError(t, service.Run(a, svc, config, []string{"install"}))
+	path := filepath.Join(dir, "tool.service")
+	assert.Equal(t, "Installed "+path+"\n"+
+		"Start it with: systemctl daemon-reload && systemctl enable --now tool\n", stdout.String())
+	assert.FileExists(t, path)
+	stdout.Reset()
+
+	require.NoError(t, service.Run(a, svc, config, []string{"uninstall"}))
+	assert.Equal(t, "Removed "+path+"\n", stdout.String())
+
+	assert.EqualError(t, service.Run(a, svc, config, nil), "service: expected a command: install, uninstall, or render")
+	assert.EqualError(t, service.Run(a, svc, config, []string{"start"}),
+		`service: unknown command "start", expected install, uninstall, or render`)
+}
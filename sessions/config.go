@@ -0,0 +1,167 @@
+package sessions
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the session cookie settings, as in APP_SESSIONS_COOKIE.
+	DefaultPrefix = "APP_SESSIONS_"
+
+	// DefaultCookie is the name of the session cookie.
+	DefaultCookie = "session"
+
+	// DefaultIdleTimeout is how long a session is kept without being used.
+	DefaultIdleTimeout = 24 * time.Hour
+
+	// DefaultLifetime is how long a session is kept at most.
+	DefaultLifetime = 30 * 24 * time.Hour
+
+	// DefaultCleanupInterval is how often the expired sessions are deleted from the stores that keep them.
+	DefaultCleanupInterval = 10 * time.Minute
+)
+
+// Config describes the session cookie and how long the sessions are kept.
+type Config struct {
+	Cookie          string        // the name of the cookie
+	Domain          string        // the domain of the cookie; the host of the request if empty
+	Path            string        // the path of the cookie
+	Secure          bool          // whether the cookie is only sent over HTTPS
+	SameSite        http.SameSite // whether the cookie is sent with cross-site requests
+	IdleTimeout     time.Duration // how long a session is kept without being used
+	Lifetime        time.Duration // how long a session is kept at most, however often it is used
+	Keys            []string      // the keys signing the cookie: the first signs, all verify; unsigned if empty
+	CleanupInterval time.Duration // how often the expired sessions are deleted, for the stores that need it
+}
+
+// DefaultConfig returns a Config for secure, unsigned cookies, kept for the default durations.
+func DefaultConfig() *Config {
+	return &Config{
+		Cookie:          DefaultCookie,
+		Path:            "/",
+		Secure:          true,
+		SameSite:        http.SameSiteLaxMode,
+		IdleTimeout:     DefaultIdleTimeout,
+		Lifetime:        DefaultLifetime,
+		CleanupInterval: DefaultCleanupInterval,
+	}
+}
+
+func init() {
+	configschema.Register("sessions", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the session variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "COOKIE", Type: "string", Default: DefaultCookie, Description: "The name of the cookie."},
+		{Name: prefix + "DOMAIN", Type: "string",
+			Description: "The domain of the cookie; the host of the request if not set."},
+		{Name: prefix + "PATH", Type: "string", Default: "/", Description: "The path of the cookie."},
+		{Name: prefix + "SECURE", Type: "bool", Default: "true", Description: "Only send the cookie over HTTPS."},
+		{Name: prefix + "SAME_SITE", Type: "string", Default: "lax",
+			Description: "When the cookie is sent with cross-site requests: lax, strict, or none."},
+		{Name: prefix + "IDLE_TIMEOUT", Type: "duration", Default: DefaultIdleTimeout.String(),
+			Description: "How long a session is kept without being used."},
+		{Name: prefix + "LIFETIME", Type: "duration", Default: DefaultLifetime.String(),
+			Description: "How long a session is kept at most."},
+		{Name: prefix + "KEYS", Type: "string", Secret: true,
+			Description: "The keys signing the cookie, the newest first, separated by spaces or commas."},
+		{Name: prefix + "CLEANUP_INTERVAL", Type: "duration", Default: DefaultCleanupInterval.String(),
+			Description: "How often the expired sessions are deleted."},
+	}
+}
+
+// FromEnv reads the session cookie, COOKIE, DOMAIN, PATH, SECURE, and SAME_SITE (lax, strict, or none), how long
+// sessions last, IDLE_TIMEOUT and LIFETIME, the signing KEYS, separated by spaces or commas, and the CLEANUP_INTERVAL,
+// with the prefix or DefaultPrefix. Use App.LookupEnv as lookup to decrypt the keys encrypted with age. Keys are
+// rotated by adding the new key first, and removing the old one once the sessions it signed expired.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	for key, dst := range map[string]*string{
+		"COOKIE": &config.Cookie,
+		"DOMAIN": &config.Domain,
+		"PATH":   &config.Path,
+	} {
+		if v := get(key); v != "" {
+			*dst = v
+		}
+	}
+	if !validCookieName(config.Cookie) {
+		return nil, fmt.Errorf("sessions: invalid %sCOOKIE %q", prefix, config.Cookie)
+	}
+	if !strings.HasPrefix(config.Path, "/") {
+		return nil, fmt.Errorf("sessions: invalid %sPATH %q", prefix, config.Path)
+	}
+
+	if v := get("SECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("sessions: invalid %sSECURE %q", prefix, v)
+		}
+		config.Secure = b
+	}
+
+	switch v := get("SAME_SITE"); strings.ToLower(v) {
+	case "", "lax":
+	case "strict":
+		config.SameSite = http.SameSiteStrictMode
+	case "none":
+		config.SameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("sessions: invalid %sSAME_SITE %q", prefix, v)
+	}
+
+	if config.SameSite == http.SameSiteNoneMode && !config.Secure {
+		// browsers drop such cookies
+		return nil, fmt.Errorf("sessions: %sSAME_SITE none requires %sSECURE", prefix, prefix)
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"IDLE_TIMEOUT":     &config.IdleTimeout,
+		"LIFETIME":         &config.Lifetime,
+		"CLEANUP_INTERVAL": &config.CleanupInterval,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("sessions: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v := get("KEYS"); v != "" {
+		config.Keys = strings.FieldsFunc(v, func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	return config, nil
+}
+
+// validCookieName reports whether name is a cookie name, an RFC 2616 token.
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
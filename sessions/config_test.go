@@ -0,0 +1,89 @@
+package sessions_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/sessions"
+)
+
+func TestFromEnv_Cookie(t *testing.T) {
+	config, err := sessions.FromEnv(apptest.Lookup(map[string]string{
+		"ADMIN_SESSIONS_COOKIE": " __Host-sid ",
+		"ADMIN_SESSIONS_DOMAIN": "example.com",
+		"ADMIN_SESSIONS_PATH":   "/admin",
+	}), "ADMIN_SESSIONS_")
+	require.NoError(t, err)
+	assert.Equal(t, "__Host-sid", config.Cookie)
+	assert.Equal(t, "example.com", config.Domain)
+	assert.Equal(t, "/admin", config.Path)
+
+	// the name is a token of RFC 6265: no spaces, controls, or separators
+	for _, v := range []string{"my session", "sid;", "a=b", "s/id", `"sid"`, "séance"} {
+		_, err := sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_COOKIE": v}), "")
+		assert.EqualError(t, err, "sessions: invalid APP_SESSIONS_COOKIE "+strconv.Quote(v), v)
+	}
+
+	_, err = sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_PATH": "app"}), "")
+	assert.EqualError(t, err, `sessions: invalid APP_SESSIONS_PATH "app"`)
+}
+
+func TestFromEnv_SameSite(t *testing.T) {
+	for v, expected := range map[string]http.SameSite{
+		"":       http.SameSiteLaxMode,
+		"Lax":    http.SameSiteLaxMode,
+		"STRICT": http.SameSiteStrictMode,
+		"none":   http.SameSiteNoneMode,
+	} {
+		config, err := sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_SAME_SITE": v}), "")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.SameSite, v)
+	}
+
+	// browsers drop a cross-site cookie that is not secure, so the pairing is caught at startup
+	_, err := sessions.FromEnv(apptest.Lookup(map[string]string{
+		"APP_SESSIONS_SAME_SITE": "none",
+		"APP_SESSIONS_SECURE":    "false",
+	}), "")
+	assert.EqualError(t, err, "sessions: APP_SESSIONS_SAME_SITE none requires APP_SESSIONS_SECURE")
+
+	for key, v := range map[string]string{"SAME_SITE": "loose", "SECURE": "maybe"} {
+		_, err := sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_" + key: v}), "")
+		assert.EqualError(t, err, "sessions: invalid APP_SESSIONS_"+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Keys(t *testing.T) {
+	// the new key comes first to sign, and the old one stays to verify the sessions it signed
+	config, err := sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_KEYS": "new-key, old-key"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new-key", "old-key"}, config.Keys)
+
+	config, err = sessions.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, sessions.DefaultConfig(), config)
+	assert.Empty(t, config.Keys)
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	config, err := sessions.FromEnv(apptest.Lookup(map[string]string{
+		"APP_SESSIONS_IDLE_TIMEOUT": "30m",
+		"APP_SESSIONS_LIFETIME":     "12h",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, config.IdleTimeout)
+	assert.Equal(t, 12*time.Hour, config.Lifetime)
+
+	for _, key := range []string{"IDLE_TIMEOUT", "LIFETIME", "CLEANUP_INTERVAL"} {
+		for _, v := range []string{"0s", "-1h", "1"} {
+			_, err := sessions.FromEnv(apptest.Lookup(map[string]string{"APP_SESSIONS_" + key: v}), "")
+			assert.EqualError(t, err, "sessions: invalid APP_SESSIONS_"+key+` "`+v+`"`)
+		}
+	}
+}
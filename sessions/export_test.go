@@ -0,0 +1,8 @@
+package sessions
+
+import "time"
+
+// SetNow replaces the clock of m.
+func SetNow(m *Manager, now func() time.Time) {
+	m.now = now
+}
@@ -0,0 +1,399 @@
+// Package sessions keeps the state of the users of server-rendered web apps across their requests, in a Store the
+// session cookie refers to:
+//
+//	config, err := sessions.FromEnv(a.LookupEnv, "")
+//	m := sessions.New(sessions.NewRedis(client), config)
+//	a.Register("http", app.NewHTTPServer("tcp://:8080", m.Wrap(mux)))
+//	a.Register("sessions", m)
+//
+//	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+//		s, _ := sessions.FromContext(r.Context())
+//		...
+//		s.Renew() // a new ID for the signed in user
+//		_ = s.Set("user", user.ID)
+//	})
+//
+// The cookie only holds the random ID of the session, signed with the first of the Keys when there are some, and is
+// HttpOnly, Secure, and SameSite=Lax unless configured otherwise. A session is loaded the first time a handler uses
+// it, and saved, with the cookie set, before the response starts if it changed or has not been saved for a tenth of
+// the IdleTimeout. New sessions are only saved once a value is set in them, so that anonymous visitors are not given
+// one. A session expires after the IdleTimeout without use, or the Lifetime, whichever comes first.
+//
+// The Manager is an app.Server deleting the expired sessions from the Stores that do not expire them, the Cleaners.
+// Register it after the HTTP server, so that the sessions of the requests served during shutdown are saved first.
+package sessions
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/correlation"
+)
+
+// Manager loads and saves the sessions of the requests it Wraps.
+type Manager struct {
+	store  Store
+	config Config
+	logger gomol.WrappableLogger
+	now    func() time.Time
+
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a Manager keeping the sessions in store as config describes.
+func New(store Store, config *Config) *Manager {
+	return &Manager{store: store, config: *config, now: time.Now, stopping: make(chan struct{})}
+}
+
+// Wrap returns a handler calling next with the session of the request in its context, and saving the session before
+// the response starts.
+func (m *Manager) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := &Session{m: m, ctx: r.Context()}
+		if c, err := r.Cookie(m.config.Cookie); err == nil {
+			s.cookieID, s.resign = m.verify(c.Value)
+		}
+
+		sw := &writer{ResponseWriter: w, s: s}
+		next.ServeHTTP(sw, r.WithContext(NewContext(r.Context(), s)))
+		sw.commit()
+	})
+}
+
+// Bind sets the logger to the app Logger.
+func (m *Manager) Bind(a *app.App) error {
+	m.logger = a.Logger()
+	return nil
+}
+
+// Serve deletes the expired sessions every CleanupInterval if the Store is a Cleaner, until the Manager is shut down.
+func (m *Manager) Serve() error {
+	cleaner, ok := m.store.(Cleaner)
+	if !ok {
+		<-m.stopping
+		return nil
+	}
+
+	ticker := time.NewTicker(m.config.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopping:
+			return nil
+		case <-ticker.C:
+			if err := cleaner.DeleteExpired(context.Background()); err != nil {
+				m.log(gomol.LevelWarning, nil, "unable to delete the expired sessions: %v", err)
+			}
+		}
+	}
+}
+
+// Shutdown stops the cleanup, deleting the expired sessions a last time if the Store is a Cleaner.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stopping) })
+	if cleaner, ok := m.store.(Cleaner); ok {
+		return cleaner.DeleteExpired(ctx)
+	}
+	return nil
+}
+
+// sign returns the cookie value of id.
+func (m *Manager) sign(id string) string {
+	if len(m.config.Keys) == 0 {
+		return id
+	}
+	return id + "." + mac(m.config.Keys[0], id)
+}
+
+// verify returns the session ID of the cookie value, or an empty string if it is not signed with one of the Keys,
+// and whether it is signed with another key than the first.
+func (m *Manager) verify(value string) (string, bool) {
+	id, sig, signed := strings.Cut(value, ".")
+	if len(m.config.Keys) == 0 {
+		if signed {
+			return "", false
+		}
+		return id, false
+	}
+	for i, key := range m.config.Keys {
+		if hmac.Equal([]byte(sig), []byte(mac(key, id))) {
+			return id, i > 0
+		}
+	}
+	return "", false
+}
+
+func (m *Manager) log(level gomol.LogLevel, s *Session, format string, args ...interface{}) {
+	if m.logger == nil {
+		return
+	}
+	attrs := map[string]interface{}{}
+	if s != nil {
+		attrs = correlation.Attrs(s.ctx)
+	}
+	_ = m.logger.Log(level, gomol.NewAttrsFromMap(attrs), format, args...)
+}
+
+func mac(key, id string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	_, _ = h.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// newID returns a random session ID.
+func newID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying s.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the session of the request of ctx, and whether it has one.
+func FromContext(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Session)
+	return s, ok
+}
+
+// record is what the Store keeps for a session.
+type record struct {
+	Created time.Time                  `json:"created"`
+	Saved   time.Time                  `json:"saved"`
+	Values  map[string]json.RawMessage `json:"values"`
+}
+
+// Session is the session of a request. Its values are kept as JSON, so they are read back as they decode from it.
+// It is safe for concurrent use, but changes made once the response started are lost.
+type Session struct {
+	m   *Manager
+	ctx context.Context
+
+	mu        sync.Mutex
+	cookieID  string // the ID of the cookie of the request, if signed as it should be
+	resign    bool   // whether the cookie is signed with an old key
+	loaded    bool
+	err       error
+	id        string // the ID of the session, once loaded; empty for a new session
+	rec       record
+	modified  bool
+	committed bool
+}
+
+// load loads the session of the cookie the first time it is used. s.mu must be held.
+func (s *Session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.rec = record{Values: map[string]json.RawMessage{}}
+	if s.cookieID == "" {
+		return
+	}
+
+	data, err := s.m.store.Load(s.ctx, s.cookieID)
+	if err != nil {
+		s.err = err
+		s.m.log(gomol.LevelError, s, "unable to load the session: %v", err)
+		return
+	}
+	// a session the Store does not have, whatever its ID, is replaced by a new one with a new ID; its times are checked
+	// too, as not every Store expires the sessions on time
+	var rec record
+	now := s.m.now()
+	if data == nil || json.Unmarshal(data, &rec) != nil ||
+		now.Sub(rec.Saved) >= s.m.config.IdleTimeout || now.Sub(rec.Created) >= s.m.config.Lifetime {
+		return
+	}
+	if rec.Values == nil {
+		rec.Values = map[string]json.RawMessage{}
+	}
+	s.id, s.rec = s.cookieID, rec
+}
+
+// Err returns the error the Store failed to load the session with, if any, in which case the session is empty and
+// is not saved.
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.err
+}
+
+// ID returns the ID of the session, or an empty string if it is new and not saved yet.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.id
+}
+
+// Get decodes the value of key into v, and reports whether the session has a value for key that decodes into v.
+func (s *Session) Get(key string, v interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	raw, ok := s.rec.Values[key]
+	return ok && json.Unmarshal(raw, v) == nil
+}
+
+// String returns the value of key if it is a string, and an empty string otherwise.
+func (s *Session) String(key string) string {
+	var v string
+	s.Get(key, &v)
+	return v
+}
+
+// Set sets the value of key, or returns an error if v cannot be encoded as JSON.
+func (s *Session) Set(key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.rec.Values[key] = raw
+	s.modified = true
+	return nil
+}
+
+// Delete removes the value of key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if _, ok := s.rec.Values[key]; ok {
+		delete(s.rec.Values, key)
+		s.modified = true
+	}
+}
+
+// Renew gives the session a new ID, keeping its values, so that an ID known before a user signs in, or is granted
+// more privileges, is of no use afterwards. Renew is called on sign in.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.id = ""
+	s.modified = true
+}
+
+// Destroy removes the session and its cookie, as on sign out. Values set afterwards are saved in a new session.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	s.id = ""
+	s.rec = record{Values: map[string]json.RawMessage{}}
+	s.modified = false
+}
+
+// commit saves the session and sets its cookie in h, if it changed. It is called once, before the response starts.
+func (s *Session) commit(h http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded || s.committed || s.err != nil {
+		return
+	}
+	s.committed = true
+
+	m, now := s.m, s.m.now()
+	replaced := s.cookieID != "" && s.id != s.cookieID
+	if replaced {
+		// the session was renewed or destroyed, or the cookie was of an expired session
+		if err := m.store.Delete(s.ctx, s.cookieID); err != nil {
+			m.log(gomol.LevelWarning, s, "unable to delete the session: %v", err)
+		}
+	}
+
+	stale := now.Sub(s.rec.Saved) >= m.config.IdleTimeout/10
+	if !s.modified && !(s.id != "" && (stale || s.resign)) {
+		if replaced {
+			m.setCookie(h, "", -1)
+		}
+		return
+	}
+
+	if s.id == "" {
+		s.id = newID()
+		s.rec.Created = now
+	}
+	s.rec.Saved = now
+	ttl := m.config.IdleTimeout
+	if left := s.rec.Created.Add(m.config.Lifetime).Sub(now); left < ttl {
+		ttl = left
+	}
+
+	data, err := json.Marshal(&s.rec)
+	if err == nil {
+		err = m.store.Save(s.ctx, s.id, data, ttl)
+	}
+	if err != nil {
+		m.log(gomol.LevelError, s, "unable to save the session: %v", err)
+		return
+	}
+	m.setCookie(h, m.sign(s.id), int(ttl/time.Second))
+}
+
+// setCookie sets the session cookie with value, kept for maxAge seconds, or removed if maxAge is negative.
+func (m *Manager) setCookie(h http.Header, value string, maxAge int) {
+	c := &http.Cookie{
+		Name:     m.config.Cookie,
+		Value:    value,
+		Path:     m.config.Path,
+		Domain:   m.config.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.config.Secure,
+		HttpOnly: true,
+		SameSite: m.config.SameSite,
+	}
+	h.Add("Set-Cookie", c.String())
+	// a response setting a session cookie is only for the user it is sent to
+	if h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", "no-store")
+	}
+}
+
+// writer is a ResponseWriter committing the session before the response starts.
+type writer struct {
+	http.ResponseWriter
+	s    *Session
+	once sync.Once
+}
+
+func (w *writer) commit() {
+	w.once.Do(func() { w.s.commit(w.Header()) })
+}
+
+func (w *writer) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the ResponseWriter, for http.ResponseController.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,237 @@
+package sessions_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/sessions"
+)
+
+// handler serves the pages of a small app signing users in and out.
+func handler(m *sessions.Manager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessions.FromContext(r.Context())
+		_, _ = w.Write([]byte(s.String("user")))
+	})
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessions.FromContext(r.Context())
+		s.Renew()
+		_ = s.Set("user", r.FormValue("user"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessions.FromContext(r.Context())
+		s.Destroy()
+	})
+	mux.HandleFunc("GET /static", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("no session"))
+	})
+	return m.Wrap(mux)
+}
+
+// do sends a request to h with the cookie value, and returns the response and the session cookie it sets, if any.
+func do(h http.Handler, method, target, cookie string) (*httptest.ResponseRecorder, *http.Cookie) {
+	r := httptest.NewRequest(method, target, nil)
+	if cookie != "" {
+		r.AddCookie(&http.Cookie{Name: "session", Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session" {
+			return w, c
+		}
+	}
+	return w, nil
+}
+
+func newStore() *sessions.Cache {
+	return sessions.NewCache(cache.New[string, []byte](cache.DefaultConfig(), nil))
+}
+
+func TestManager(t *testing.T) {
+	m := sessions.New(newStore(), sessions.DefaultConfig())
+	now := time.Now()
+	sessions.SetNow(m, func() time.Time { return now })
+	h := handler(m)
+
+	// anonymous visitors are not given a session
+	w, c := do(h, http.MethodGet, "/", "")
+	assert.Equal(t, "", w.Body.String())
+	assert.Nil(t, c)
+
+	w, c = do(h, http.MethodPost, "/login?user=alice", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	require.NotNil(t, c)
+	assert.True(t, c.HttpOnly)
+	assert.True(t, c.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, c.SameSite)
+	assert.Equal(t, "/", c.Path)
+	assert.Equal(t, int(sessions.DefaultIdleTimeout/time.Second), c.MaxAge)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	sid := c.Value
+
+	w, c = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "alice", w.Body.String())
+	assert.Nil(t, c, "a session just saved is not saved again")
+
+	// pages not using the session do not touch it
+	_, c = do(h, http.MethodGet, "/static", sid)
+	assert.Nil(t, c)
+
+	// a session used a while after it was saved is saved again, to keep it for the IdleTimeout
+	now = now.Add(sessions.DefaultIdleTimeout / 5)
+	w, c = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "alice", w.Body.String())
+	require.NotNil(t, c)
+	assert.Equal(t, sid, c.Value)
+
+	// signing in again gives a new ID, and the old one is of no use
+	_, c = do(h, http.MethodPost, "/login?user=bob", sid)
+	require.NotNil(t, c)
+	assert.NotEqual(t, sid, c.Value)
+	w, _ = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "", w.Body.String())
+	sid = c.Value
+	w, _ = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "bob", w.Body.String())
+
+	// signing out removes the session and its cookie
+	_, c = do(h, http.MethodPost, "/logout", sid)
+	require.NotNil(t, c)
+	assert.Equal(t, -1, c.MaxAge)
+	w, _ = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "", w.Body.String())
+
+	// a session unused for the IdleTimeout expires
+	_, c = do(h, http.MethodPost, "/login?user=carol", "")
+	now = now.Add(sessions.DefaultIdleTimeout)
+	w, _ = do(h, http.MethodGet, "/", c.Value)
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestManager_Lifetime(t *testing.T) {
+	config := sessions.DefaultConfig()
+	config.IdleTimeout = time.Hour
+	config.Lifetime = 90 * time.Minute
+	m := sessions.New(newStore(), config)
+	now := time.Now()
+	sessions.SetNow(m, func() time.Time { return now })
+	h := handler(m)
+
+	_, c := do(h, http.MethodPost, "/login?user=alice", "")
+	sid := c.Value
+
+	now = now.Add(time.Hour - time.Minute)
+	w, c := do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "alice", w.Body.String())
+	require.NotNil(t, c)
+	assert.Equal(t, 31*60, c.MaxAge, "the session is kept until the end of its Lifetime")
+
+	now = now.Add(31 * time.Minute)
+	w, _ = do(h, http.MethodGet, "/", sid)
+	assert.Equal(t, "", w.Body.String())
+}
+
+func TestManager_Keys(t *testing.T) {
+	store := newStore()
+	config := sessions.DefaultConfig()
+	config.Keys = []string{"old-key"}
+	_, c := do(handler(sessions.New(store, config)), http.MethodPost, "/login?user=alice", "")
+	require.NotNil(t, c)
+	sid, sig, ok := strings.Cut(c.Value, ".")
+	require.True(t, ok)
+
+	// the cookie must be signed, and with one of the keys
+	for _, value := range []string{sid, sid + ".", sid + "." + sig + "x", "other." + sig} {
+		w, _ := do(handler(sessions.New(store, config)), http.MethodGet, "/", value)
+		assert.Equal(t, "", w.Body.String(), value)
+	}
+
+	// a cookie signed with an old key is signed again with the new one
+	config.Keys = []string{"new-key", "old-key"}
+	w, resigned := do(handler(sessions.New(store, config)), http.MethodGet, "/", c.Value)
+	assert.Equal(t, "alice", w.Body.String())
+	require.NotNil(t, resigned)
+	assert.True(t, strings.HasPrefix(resigned.Value, sid+"."))
+	assert.NotEqual(t, c.Value, resigned.Value)
+
+	config.Keys = []string{"new-key"}
+	w, _ = do(handler(sessions.New(store, config)), http.MethodGet, "/", resigned.Value)
+	assert.Equal(t, "alice", w.Body.String())
+	w, _ = do(handler(sessions.New(store, config)), http.MethodGet, "/", c.Value)
+	assert.Equal(t, "", w.Body.String())
+}
+
+type failingStore struct {
+	sessions.Store
+}
+
+func (failingStore) Load(context.Context, string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestManager_StoreError(t *testing.T) {
+	a := apptest.New(t, nil)
+	m := sessions.New(failingStore{newStore()}, sessions.DefaultConfig())
+	require.NoError(t, m.Bind(a))
+
+	var err error
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, _ := sessions.FromContext(r.Context())
+		err = s.Err()
+		_ = s.Set("user", "alice")
+	}))
+
+	_, c := do(h, http.MethodGet, "/", "sid")
+	assert.EqualError(t, err, "connection refused")
+	assert.Nil(t, c, "a session that failed to load is not replaced")
+
+	a.Logger().ShutdownLoggers()
+	assert.Contains(t, apptest.Stderr(t, a), "unable to load the session: connection refused")
+}
+
+type cleaningStore struct {
+	sessions.Store
+	cleanups atomic.Int32
+}
+
+func (s *cleaningStore) DeleteExpired(context.Context) error {
+	s.cleanups.Add(1)
+	return nil
+}
+
+func TestManager_Serve(t *testing.T) {
+	store := &cleaningStore{Store: newStore()}
+	config := sessions.DefaultConfig()
+	config.CleanupInterval = 10 * time.Millisecond
+	m := sessions.New(store, config)
+
+	served := make(chan error)
+	go func() { served <- m.Serve() }()
+	assert.Eventually(t, func() bool { return store.cleanups.Load() >= 2 }, time.Second, time.Millisecond)
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+	n := store.cleanups.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, n, store.cleanups.Load(), "the cleanup stopped")
+
+	// the Stores expiring the sessions themselves are left alone
+	m = sessions.New(newStore(), config)
+	go func() { served <- m.Serve() }()
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+}
@@ -0,0 +1,146 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+)
+
+// Store keeps the data of the sessions, encoded by the Manager.
+type Store interface {
+	// Load returns the data of the session id, or nil if there is no such session or it expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+
+	// Save replaces the data of the session id, kept for ttl.
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+
+	// Delete removes the session id.
+	Delete(ctx context.Context, id string) error
+}
+
+// Cleaner is implemented by the Stores keeping the expired sessions until they are deleted. The Manager calls
+// DeleteExpired every CleanupInterval, and when it shuts down.
+type Cleaner interface {
+	DeleteExpired(ctx context.Context) error
+}
+
+// Cache is a Store keeping the sessions in memory, for an app with a single replica. The sessions are lost when the
+// app stops, unless the cache Persists them, and the least recently used are dropped when it is full.
+type Cache struct {
+	cache *cache.Cache[string, []byte]
+}
+
+// NewCache returns a Store keeping the sessions in c.
+func NewCache(c *cache.Cache[string, []byte]) *Cache {
+	return &Cache{cache: c}
+}
+
+// Load returns the data of id in the cache.
+func (c *Cache) Load(_ context.Context, id string) ([]byte, error) {
+	data, _ := c.cache.Get(id)
+	return data, nil
+}
+
+// Save replaces the data of id in the cache.
+func (c *Cache) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	c.cache.SetTTL(id, data, ttl)
+	return nil
+}
+
+// Delete removes id from the cache.
+func (c *Cache) Delete(_ context.Context, id string) error {
+	c.cache.Delete(id)
+	return nil
+}
+
+// Redis is a Store keeping the sessions in Redis keys expiring with them.
+type Redis struct {
+	client redis.UniversalClient
+
+	// Prefix is prepended to the session IDs to form the Redis keys.
+	Prefix string
+}
+
+// NewRedis returns a Store using client.
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client, Prefix: "session:"}
+}
+
+// Load gets the Redis key of id.
+func (r *Redis) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := r.client.Get(ctx, r.Prefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save sets the Redis key of id.
+func (r *Redis) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.Prefix+id, data, ttl).Err()
+}
+
+// Delete deletes the Redis key of id.
+func (r *Redis) Delete(ctx context.Context, id string) error {
+	return r.client.Del(ctx, r.Prefix+id).Err()
+}
+
+// Postgres is a Store keeping the sessions in a table created with:
+//
+//	CREATE TABLE sessions (
+//		id         text PRIMARY KEY,
+//		data       bytea NOT NULL,
+//		expires_at timestamptz NOT NULL
+//	);
+//	CREATE INDEX sessions_expires_at ON sessions (expires_at);
+//
+// It is a Cleaner, deleting the rows of the expired sessions.
+type Postgres struct {
+	db  *sql.DB
+	now func() time.Time
+
+	// Table is the name of the table.
+	Table string
+}
+
+// NewPostgres returns a Store using db, which must be a PostgreSQL database.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db, now: time.Now, Table: "sessions"}
+}
+
+// Load selects the row of id unless it expired.
+func (p *Postgres) Load(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	query := fmt.Sprintf("SELECT data FROM %s WHERE id = $1 AND expires_at > $2", p.Table)
+	err := p.db.QueryRowContext(ctx, query, id, p.now()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save inserts or updates the row of id.
+func (p *Postgres) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	query := fmt.Sprintf(`INSERT INTO %s (id, data, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (id) DO UPDATE SET data = EXCLUDED.data, expires_at = EXCLUDED.expires_at`, p.Table)
+	_, err := p.db.ExecContext(ctx, query, id, data, p.now().Add(ttl))
+	return err
+}
+
+// Delete deletes the row of id.
+func (p *Postgres) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", p.Table), id)
+	return err
+}
+
+// DeleteExpired deletes the rows of the expired sessions.
+func (p *Postgres) DeleteExpired(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE expires_at <= $1", p.Table), p.now())
+	return err
+}
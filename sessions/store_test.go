@@ -0,0 +1,86 @@
+package sessions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/cache"
+	"github.com/demosdemon/golang-app-framework/sessions"
+)
+
+// testStore runs a Store through saving, loading, replacing, and deleting a session.
+func testStore(t *testing.T, store sessions.Store) {
+	ctx := context.Background()
+
+	data, err := store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, store.Save(ctx, "sid-1", []byte("first"), time.Minute))
+	data, err = store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), data)
+
+	require.NoError(t, store.Save(ctx, "sid-1", []byte("second"), time.Minute))
+	data, err = store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	require.NoError(t, store.Delete(ctx, "sid-1"))
+	data, err = store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestCache(t *testing.T) {
+	testStore(t, sessions.NewCache(cache.New[string, []byte](cache.DefaultConfig(), nil)))
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	testStore(t, sessions.NewRedis(client))
+
+	store := sessions.NewRedis(client)
+	require.NoError(t, store.Save(context.Background(), "sid-2", []byte("data"), time.Minute))
+	assert.Equal(t, time.Minute, server.TTL("session:sid-2"))
+}
+
+func TestPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT data FROM sessions WHERE id = \$1 AND expires_at > \$2`).
+		WithArgs("sid-1", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"data"}))
+	mock.ExpectExec(`INSERT INTO sessions \(id, data, expires_at\) VALUES \(\$1, \$2, \$3\)\s+ON CONFLICT \(id\)`).
+		WithArgs("sid-1", []byte("first"), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT data FROM sessions`).
+		WithArgs("sid-1", sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("first")))
+	mock.ExpectExec(`DELETE FROM sessions WHERE id = \$1`).WithArgs("sid-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM sessions WHERE expires_at <= \$1`).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	store := sessions.NewPostgres(db)
+	ctx := context.Background()
+	data, err := store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	require.NoError(t, store.Save(ctx, "sid-1", []byte("first"), time.Minute))
+	data, err = store.Load(ctx, "sid-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), data)
+
+	require.NoError(t, store.Delete(ctx, "sid-1"))
+	require.NoError(t, store.DeleteExpired(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,178 @@
+package slo
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the objectives and their alerts, as in APP_SLO_OBJECTIVES.
+	DefaultPrefix = "APP_SLO_"
+
+	// DefaultInterval is how often the burn rates are computed.
+	DefaultInterval = 30 * time.Second
+)
+
+// DefaultAlerts are the alerting rules of the Site Reliability Workbook: a burn rate of 14.4 over an hour spends 2%
+// of a 30 day error budget, and one of 6 over 6 hours spends 5% of it.
+var DefaultAlerts = []Rule{{Window: time.Hour, BurnRate: 14.4}, {Window: 6 * time.Hour, BurnRate: 6}}
+
+// Kind is what an Objective tells good requests from bad ones by.
+type Kind string
+
+const (
+	// Availability counts the requests answered with a server error, a 5xx status or a gRPC code such as Internal or
+	// Unavailable, as bad.
+	Availability Kind = "availability"
+
+	// Latency counts the requests taking longer than the Threshold as bad. The durations are those of the histograms of
+	// the servers, to whose buckets Monitor.Bind adds the Threshold.
+	Latency Kind = "latency"
+)
+
+// Objective is a service level objective over the requests served by the HTTPServers and GRPCServers of the app.
+type Objective struct {
+	Name      string        // availability, or latency_ followed by the Threshold, such as latency_300ms
+	Kind      Kind          // what tells good requests from bad ones
+	Target    float64       // the ratio of good requests, such as 0.999
+	Threshold time.Duration // the duration under which a request is good, for Latency
+}
+
+// Rule is an alerting rule: an Objective alerts when its burn rate, how many times faster than allowed by its Target
+// it spends its error budget, exceeds the BurnRate over both the Window and a twelfth of it, so that the alert fires
+// fast, and resolves fast, when the requests are bad for a while.
+type Rule struct {
+	Window   time.Duration
+	BurnRate float64
+}
+
+// Config describes the objectives of the app and when they alert.
+type Config struct {
+	Objectives []Objective
+	Alerts     []Rule
+	Interval   time.Duration // how often the burn rates are computed
+	Webhook    string        // the URL the alerts are posted to, if any
+}
+
+// DefaultConfig returns a Config without objectives, with the DefaultAlerts.
+func DefaultConfig() *Config {
+	return &Config{Alerts: append([]Rule(nil), DefaultAlerts...), Interval: DefaultInterval}
+}
+
+func init() {
+	configschema.Register("slo", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the objective variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "OBJECTIVES", Type: "string",
+			Description: "The objectives, such as availability:99.9 latency:99:300ms, separated by spaces or commas."},
+		{Name: prefix + "ALERTS", Type: "string", Default: "1h:14.4 6h:6",
+			Description: "The alert rules as <window>:<burn rate>, separated by spaces or commas, or none."},
+		{Name: prefix + "INTERVAL", Type: "duration", Default: DefaultInterval.String(),
+			Description: "How often the burn rates are computed."},
+		{Name: prefix + "WEBHOOK", Type: "url", Description: "The URL the alerts are posted to."},
+	}
+}
+
+// FromEnv reads the objectives and their alerts, with the prefix or DefaultPrefix:
+//
+//	OBJECTIVES  the objectives, separated by spaces or commas: availability:<target>, or
+//	            latency:<target>:<threshold>, with a target in percent, such as availability:99.9 latency:99:300ms
+//	ALERTS      the rules, separated by spaces or commas, as <window>:<burn rate>, such as 1h:14.4, or none
+//	INTERVAL    how often the burn rates are computed
+//	WEBHOOK     the URL the alerts are posted to
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+	list := func(key string) []string {
+		return strings.FieldsFunc(get(key), func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	config := DefaultConfig()
+	for _, v := range list("OBJECTIVES") {
+		o, ok := parseObjective(v)
+		if !ok {
+			return nil, fmt.Errorf("slo: invalid %sOBJECTIVES objective %q", prefix, v)
+		}
+		for _, other := range config.Objectives {
+			if other.Name == o.Name {
+				return nil, fmt.Errorf("slo: invalid %sOBJECTIVES objective %q", prefix, v)
+			}
+		}
+		config.Objectives = append(config.Objectives, o)
+	}
+
+	if alerts := list("ALERTS"); len(alerts) > 0 {
+		config.Alerts = nil
+		for _, v := range alerts {
+			if v == "none" && len(alerts) == 1 {
+				break
+			}
+			window, rate, _ := strings.Cut(v, ":")
+			d, err := time.ParseDuration(window)
+			f, ferr := strconv.ParseFloat(rate, 64)
+			if err != nil || d <= 0 || ferr != nil || !(f > 0) {
+				return nil, fmt.Errorf("slo: invalid %sALERTS rule %q", prefix, v)
+			}
+			config.Alerts = append(config.Alerts, Rule{Window: d, BurnRate: f})
+		}
+	}
+
+	if v := get("INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("slo: invalid %sINTERVAL %q", prefix, v)
+		}
+		config.Interval = d
+	}
+
+	if v := get("WEBHOOK"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("slo: invalid %sWEBHOOK %q", prefix, v)
+		}
+		config.Webhook = v
+	}
+
+	return config, nil
+}
+
+// parseObjective parses availability:<target> or latency:<target>:<threshold>.
+func parseObjective(v string) (Objective, bool) {
+	parts := strings.Split(v, ":")
+	if len(parts) < 2 {
+		return Objective{}, false
+	}
+	target, err := strconv.ParseFloat(strings.TrimSuffix(parts[1], "%"), 64)
+	if err != nil || !(target > 0 && target < 100) {
+		return Objective{}, false
+	}
+
+	o := Objective{Kind: Kind(strings.ToLower(parts[0])), Target: target / 100}
+	switch {
+	case o.Kind == Availability && len(parts) == 2:
+		o.Name = string(Availability)
+	case o.Kind == Latency && len(parts) == 3:
+		d, err := time.ParseDuration(parts[2])
+		if err != nil || d <= 0 {
+			return Objective{}, false
+		}
+		o.Name, o.Threshold = "latency_"+d.String(), d
+	default:
+		return Objective{}, false
+	}
+	return o, true
+}
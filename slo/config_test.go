@@ -0,0 +1,84 @@
+package slo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/slo"
+)
+
+func TestFromEnv_Objectives(t *testing.T) {
+	// the target is in percent, with or without the sign, and each latency objective is named for its threshold
+	config, err := slo.FromEnv(apptest.Lookup(map[string]string{
+		"WORKER_SLO_OBJECTIVES": "Availability:99.9, latency:99%:250ms latency:95:1s",
+	}), "WORKER_SLO_")
+	require.NoError(t, err)
+	require.Len(t, config.Objectives, 3)
+	for i, expected := range []slo.Objective{
+		{Name: "availability", Kind: slo.Availability, Target: 0.999},
+		{Name: "latency_250ms", Kind: slo.Latency, Target: 0.99, Threshold: 250 * time.Millisecond},
+		{Name: "latency_1s", Kind: slo.Latency, Target: 0.95, Threshold: time.Second},
+	} {
+		assert.InDelta(t, expected.Target, config.Objectives[i].Target, 1e-9)
+		config.Objectives[i].Target = expected.Target
+		assert.Equal(t, expected, config.Objectives[i])
+	}
+
+	for _, v := range []string{
+		"uptime:99",
+		"availability",
+		"availability:0",
+		"availability:100",
+		"availability:NaN",
+		"availability:99:1s",
+		"latency:99",
+		"latency:99:fast",
+		"latency:99:0s",
+	} {
+		_, err := slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_OBJECTIVES": v}), "")
+		assert.EqualError(t, err, `slo: invalid APP_SLO_OBJECTIVES objective "`+v+`"`, v)
+	}
+
+	// two objectives of one name would report to the same series
+	_, err = slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_OBJECTIVES": "latency:99:1s latency:95:1000ms"}), "")
+	assert.EqualError(t, err, `slo: invalid APP_SLO_OBJECTIVES objective "latency:95:1000ms"`)
+}
+
+func TestFromEnv_Alerts(t *testing.T) {
+	config, err := slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_ALERTS": "1h:14.4, 72h:1"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []slo.Rule{{Window: time.Hour, BurnRate: 14.4}, {Window: 72 * time.Hour, BurnRate: 1}}, config.Alerts)
+
+	config, err = slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_ALERTS": " none "}), "")
+	require.NoError(t, err)
+	assert.Empty(t, config.Alerts)
+
+	config, err = slo.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, slo.DefaultConfig(), config)
+
+	// none turns the alerts off only on its own
+	for _, v := range []string{"1h", "0s:2", "1h:0", "1h:NaN", "none"} {
+		_, err := slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_ALERTS": "6h:6 " + v}), "")
+		assert.EqualError(t, err, `slo: invalid APP_SLO_ALERTS rule "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Webhook(t *testing.T) {
+	config, err := slo.FromEnv(apptest.Lookup(map[string]string{
+		"APP_SLO_INTERVAL": "1m",
+		"APP_SLO_WEBHOOK":  "https://alerts.example.com/hooks/slo",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, config.Interval)
+	assert.Equal(t, "https://alerts.example.com/hooks/slo", config.Webhook)
+
+	for key, v := range map[string]string{"INTERVAL": "0s", "WEBHOOK": "alerts.example.com"} {
+		_, err := slo.FromEnv(apptest.Lookup(map[string]string{"APP_SLO_" + key: v}), "")
+		assert.EqualError(t, err, "slo: invalid APP_SLO_"+key+` "`+v+`"`)
+	}
+}
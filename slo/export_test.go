@@ -0,0 +1,8 @@
+package slo
+
+import "time"
+
+// Evaluate computes the burn rates as if it were at.
+func Evaluate(m *Monitor, at time.Time) {
+	m.evaluate(at)
+}
@@ -0,0 +1,388 @@
+// Package slo computes how fast the app spends the error budgets of its service level objectives, from the RED
+// metrics of its HTTPServers and GRPCServers, and alerts when it spends them too fast:
+//
+//	config, err := slo.FromEnv(a.LookupEnv, "")
+//	if err != nil {
+//		return err
+//	}
+//	a.Register("slo", slo.New(config, a.Metrics()))
+//
+// Every Interval, the Monitor sets the slo_burn_rate{slo, window} gauges, for the windows of the alerting rules and
+// their twelfths, and the slo_objective{slo} gauges to the targets. A burn rate of 1 spends the error budget exactly
+// over the period of the objective; one of 14.4 spends the budget of 30 days in about 2 days. When a Rule fires, or
+// resolves, the Monitor logs it and calls OnAlert, and posts it to the Webhook in the background;
+// slo_alert_firing{slo, window} is 1 while it fires.
+package slo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// webhookTimeout bounds the time posting an alert takes.
+const webhookTimeout = 10 * time.Second
+
+// webhookQueue bounds the alerts waiting to be posted; those alerting once it is full are not posted.
+const webhookQueue = 64
+
+// serverErrors are the gRPC codes of the calls counted as bad by the Availability objectives.
+var serverErrors = map[string]bool{
+	"Unknown":          true,
+	"DeadlineExceeded": true,
+	"Unimplemented":    true,
+	"Internal":         true,
+	"Unavailable":      true,
+	"DataLoss":         true,
+}
+
+// Alert is a Rule of an Objective firing, or resolving.
+type Alert struct {
+	SLO       string        // the name of the Objective
+	Window    time.Duration // the Window of the Rule
+	BurnRate  float64       // the burn rate over the Window
+	Threshold float64       // the BurnRate of the Rule
+	Firing    bool          // false once the alert resolves
+	Time      time.Time
+}
+
+// alert is the wire form of an Alert, posted to the Webhook.
+type alert struct {
+	SLO       string    `json:"slo"`
+	Window    string    `json:"window"`
+	BurnRate  float64   `json:"burn_rate"`
+	Threshold float64   `json:"threshold"`
+	Status    string    `json:"status"` // firing or resolved
+	Time      time.Time `json:"time"`
+}
+
+// counts are the numbers of requests, and bad ones, served by the app at some time, by Objective.
+type counts struct {
+	at         time.Time
+	total, bad map[string]float64
+}
+
+// Monitor is a Server computing the burn rates of the objectives.
+type Monitor struct {
+	// OnAlert, if set, is called with every alert, after it is logged.
+	OnAlert func(Alert)
+
+	// Client is used to post the alerts to the Webhook, http.DefaultClient if nil.
+	Client *http.Client
+
+	config   Config
+	registry *metrics.Registry
+	now      func() time.Time
+	logger   *gomol.Base
+
+	mu         sync.Mutex
+	history    []counts
+	firing     map[string]bool // by Objective and Rule
+	posting    chan Alert      // the alerts to post to the Webhook
+	delivering bool            // whether Bind started posting them
+	closed     bool            // whether Shutdown closed posting
+
+	posted   chan struct{} // closed once the alerts are posted, after Shutdown
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a Monitor of the objectives of config, over the metrics of registry.
+func New(config *Config, registry *metrics.Registry) *Monitor {
+	return &Monitor{
+		config:   *config,
+		registry: registry,
+		now:      time.Now,
+		firing:   make(map[string]bool),
+		posting:  make(chan Alert, webhookQueue),
+		posted:   make(chan struct{}),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Bind sets the slo_objective gauges, adds the thresholds of the Latency objectives to the buckets of the duration
+// histograms of the servers, and starts posting the alerts to the Webhook.
+func (m *Monitor) Bind(a *app.App) error {
+	m.logger = a.Logger()
+	for _, o := range m.config.Objectives {
+		m.registry.Gauge("slo_objective", metrics.Labels{"slo": o.Name}).Set(o.Target)
+		if o.Kind == Latency {
+			m.registry.AddBuckets("http_server_request_duration_seconds", o.Threshold.Seconds())
+			m.registry.AddBuckets("grpc_server_request_duration_seconds", o.Threshold.Seconds())
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.config.Webhook != "" && !m.delivering {
+		m.delivering = true
+		go m.deliver()
+	}
+	return nil
+}
+
+// Serve computes the burn rates every Interval until the Monitor is shut down.
+func (m *Monitor) Serve() error {
+	if len(m.config.Objectives) == 0 {
+		<-m.stopping
+		return nil
+	}
+
+	m.evaluate(m.now())
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopping:
+			return nil
+		case <-ticker.C:
+			m.evaluate(m.now())
+		}
+	}
+}
+
+// Shutdown stops computing the burn rates, and waits for the alerts to be posted until ctx is done.
+func (m *Monitor) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() {
+		close(m.stopping)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		close(m.posting)
+		m.closed = true
+		if !m.delivering {
+			close(m.posted)
+		}
+	})
+
+	select {
+	case <-m.posted:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// windows returns the windows the burn rates are computed over: those of the rules, and their twelfths.
+func (m *Monitor) windows() []time.Duration {
+	var res []time.Duration
+	seen := make(map[time.Duration]bool)
+	for _, r := range m.config.Alerts {
+		for _, w := range []time.Duration{r.Window / 12, r.Window} {
+			if !seen[w] {
+				seen[w] = true
+				res = append(res, w)
+			}
+		}
+	}
+	return res
+}
+
+// evaluate records the counts of the requests at now, sets the burn rates, and alerts.
+func (m *Monitor) evaluate(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.count(now)
+	m.history = append(m.history, current)
+
+	// the counts older than the longest window are not needed, but for the last one before it
+	longest := time.Duration(0)
+	for _, w := range m.windows() {
+		longest = max(longest, w)
+	}
+	for len(m.history) > 1 && !m.history[1].at.After(now.Add(-longest)) {
+		m.history = m.history[1:]
+	}
+
+	for _, o := range m.config.Objectives {
+		for _, w := range m.windows() {
+			m.registry.Gauge("slo_burn_rate", metrics.Labels{"slo": o.Name, "window": w.String()}).
+				Set(m.burnRate(o, w, current))
+		}
+
+		for _, r := range m.config.Alerts {
+			rate := m.burnRate(o, r.Window, current)
+			firing := rate > r.BurnRate && m.burnRate(o, r.Window/12, current) > r.BurnRate
+			key := o.Name + " " + r.Window.String()
+			if firing == m.firing[key] {
+				continue
+			}
+			m.firing[key] = firing
+
+			gauge := m.registry.Gauge("slo_alert_firing", metrics.Labels{"slo": o.Name, "window": r.Window.String()})
+			gauge.Set(0)
+			if firing {
+				gauge.Set(1)
+			}
+			m.alert(Alert{
+				SLO:       o.Name,
+				Window:    r.Window,
+				BurnRate:  rate,
+				Threshold: r.BurnRate,
+				Firing:    firing,
+				Time:      now,
+			})
+		}
+	}
+}
+
+// count returns the numbers of requests, and bad ones, served so far.
+func (m *Monitor) count(now time.Time) counts {
+	c := counts{at: now, total: make(map[string]float64), bad: make(map[string]float64)}
+	samples := m.registry.Gather()
+	for _, o := range m.config.Objectives {
+		for _, s := range samples {
+			switch {
+			case o.Kind == Availability && s.Name == "http_server_requests_total":
+				c.total[o.Name] += s.Value
+				if strings.HasPrefix(s.Labels["code"], "5") {
+					c.bad[o.Name] += s.Value
+				}
+			case o.Kind == Availability && s.Name == "grpc_server_requests_total":
+				c.total[o.Name] += s.Value
+				if serverErrors[s.Labels["code"]] {
+					c.bad[o.Name] += s.Value
+				}
+			case o.Kind == Latency && (s.Name == "http_server_request_duration_seconds" ||
+				s.Name == "grpc_server_request_duration_seconds"):
+				// the requests are good up to the bucket of the threshold, or the largest bound under it in the
+				// histograms created before Bind added it
+				var good uint64
+				for _, b := range s.Buckets {
+					if b.UpperBound <= o.Threshold.Seconds() {
+						good = b.Count
+					}
+				}
+				c.total[o.Name] += float64(s.Count)
+				c.bad[o.Name] += float64(s.Count - good)
+			}
+		}
+	}
+	return c
+}
+
+// burnRate returns the ratio of bad requests over the window before current, divided by the ratio the Target of o
+// allows. The counts of the start of the window are the last ones recorded before it, or the first ones recorded if
+// the Monitor has not run for as long.
+func (m *Monitor) burnRate(o Objective, window time.Duration, current counts) float64 {
+	start := m.history[0]
+	for _, c := range m.history {
+		if c.at.After(current.at.Add(-window)) {
+			break
+		}
+		start = c
+	}
+
+	total := current.total[o.Name] - start.total[o.Name]
+	if total <= 0 {
+		return 0
+	}
+	return (current.bad[o.Name] - start.bad[o.Name]) / total / (1 - o.Target)
+}
+
+// attrs returns the attributes of the log messages about a.
+func (a Alert) attrs() *gomol.Attrs {
+	return gomol.NewAttrsFromMap(map[string]interface{}{
+		"slo":       a.SLO,
+		"window":    a.Window.String(),
+		"burn_rate": a.BurnRate,
+		"threshold": a.Threshold,
+	})
+}
+
+// alert logs a, queues it to be posted to the Webhook, and calls OnAlert. m.mu must be held.
+func (m *Monitor) alert(a Alert) {
+	attrs := a.attrs()
+	if m.logger != nil {
+		if a.Firing {
+			_ = m.logger.Errorm(attrs, "SLO %s is spending its error budget %.1f times too fast over %s",
+				a.SLO, a.BurnRate, a.Window)
+		} else {
+			_ = m.logger.Infom(attrs, "SLO %s is no longer spending its error budget too fast over %s", a.SLO, a.Window)
+		}
+	}
+
+	// the alerts of an evaluation running as the Monitor shuts down are not posted
+	if m.config.Webhook != "" && !m.closed {
+		select {
+		case m.posting <- a:
+		default:
+			if m.logger != nil {
+				_ = m.logger.Warnm(attrs, "unable to post the alert of SLO %s: %d alerts are waiting to be posted",
+					a.SLO, webhookQueue)
+			}
+		}
+	}
+
+	if m.OnAlert != nil {
+		m.OnAlert(a)
+	}
+}
+
+// deliver posts the alerts to the Webhook, in the order they were queued, until Shutdown.
+func (m *Monitor) deliver() {
+	defer close(m.posted)
+	for a := range m.posting {
+		if err := m.post(a); err != nil {
+			_ = m.logger.Warnm(a.attrs(), "unable to post the alert of SLO %s: %v", a.SLO, err)
+		}
+	}
+}
+
+// post sends a to the Webhook.
+func (m *Monitor) post(a Alert) error {
+	status := "resolved"
+	if a.Firing {
+		status = "firing"
+	}
+	body, err := json.Marshal(alert{
+		SLO:       a.SLO,
+		Window:    a.Window.String(),
+		BurnRate:  a.BurnRate,
+		Threshold: a.Threshold,
+		Status:    status,
+		Time:      a.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("slo: %s answered %s", m.config.Webhook, res.Status)
+	}
+	return nil
+}
+
+// BurnRate returns the burn rate of the objective named slo over window, as last computed, or 0.
+func (m *Monitor) BurnRate(slo string, window time.Duration) float64 {
+	return m.registry.Gauge("slo_burn_rate", metrics.Labels{"slo": slo, "window": window.String()}).Value()
+}
@@ -0,0 +1,141 @@
+package slo_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/slo"
+)
+
+func newMonitor(t *testing.T, config *slo.Config, registry *metrics.Registry) *slo.Monitor {
+	m := slo.New(config, registry)
+	require.NoError(t, m.Bind(apptest.New(t, nil)))
+	t.Cleanup(func() { _ = m.Shutdown(context.Background()) })
+	return m
+}
+
+func TestMonitor_Availability(t *testing.T) {
+	posted := make(chan map[string]interface{}, 2)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		posted <- body
+	}))
+	defer webhook.Close()
+
+	config := slo.DefaultConfig()
+	config.Objectives = []slo.Objective{{Name: "availability", Kind: slo.Availability, Target: 0.99}}
+	config.Alerts = []slo.Rule{{Window: time.Hour, BurnRate: 10}}
+	config.Webhook = webhook.URL
+
+	registry := metrics.NewRegistry()
+	ok := registry.Counter("http_server_requests_total", metrics.Labels{"code": "200"})
+	failed := registry.Counter("http_server_requests_total", metrics.Labels{"code": "503"})
+	grpcFailed := registry.Counter("grpc_server_requests_total", metrics.Labels{"code": "Unavailable"})
+	registry.Counter("grpc_server_requests_total", metrics.Labels{"code": "NotFound"}).Add(0)
+
+	m := newMonitor(t, config, registry)
+	var alerts []slo.Alert
+	m.OnAlert = func(a slo.Alert) { alerts = append(alerts, a) }
+	assert.Equal(t, 0.99, registry.Gauge("slo_objective", metrics.Labels{"slo": "availability"}).Value())
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	slo.Evaluate(m, start)
+	assert.Empty(t, alerts)
+
+	// 20% of the requests fail: 20 times the 1% allowed
+	ok.Add(800)
+	failed.Add(150)
+	grpcFailed.Add(50)
+	slo.Evaluate(m, start.Add(10*time.Minute))
+	assert.InDelta(t, 20, m.BurnRate("availability", time.Hour), 1e-9)
+	assert.InDelta(t, 20, m.BurnRate("availability", 5*time.Minute), 1e-9)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, slo.Alert{SLO: "availability", Window: time.Hour, BurnRate: alerts[0].BurnRate, Threshold: 10,
+		Firing: true, Time: start.Add(10 * time.Minute)}, alerts[0])
+	assert.Equal(t, 1.0, registry.Gauge("slo_alert_firing", metrics.Labels{"slo": "availability", "window": "1h0m0s"}).
+		Value())
+
+	// the requests succeed again: the burn rate over the hour stays high, but not over the last 5 minutes
+	ok.Add(1000)
+	slo.Evaluate(m, start.Add(20*time.Minute))
+	assert.InDelta(t, 10, m.BurnRate("availability", time.Hour), 1e-9)
+	assert.Zero(t, m.BurnRate("availability", 5*time.Minute))
+	require.Len(t, alerts, 2)
+	assert.False(t, alerts[1].Firing)
+	assert.Zero(t, registry.Gauge("slo_alert_firing", metrics.Labels{"slo": "availability", "window": "1h0m0s"}).Value())
+
+	// the failures leave the hour window
+	slo.Evaluate(m, start.Add(90*time.Minute))
+	assert.Zero(t, m.BurnRate("availability", time.Hour))
+	assert.Len(t, alerts, 2)
+
+	// the alerts are posted in the background, by the time Shutdown returns
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.Len(t, posted, 2)
+	firing, resolved := <-posted, <-posted
+	assert.Equal(t, "availability", firing["slo"])
+	assert.Equal(t, "1h0m0s", firing["window"])
+	assert.Equal(t, "firing", firing["status"])
+	assert.Equal(t, "resolved", resolved["status"])
+}
+
+func TestMonitor_Latency(t *testing.T) {
+	config := slo.DefaultConfig()
+	config.Objectives = []slo.Objective{
+		{Name: "latency_300ms", Kind: slo.Latency, Target: 0.9, Threshold: 300 * time.Millisecond},
+	}
+
+	registry := metrics.NewRegistry()
+	m := newMonitor(t, config, registry)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	slo.Evaluate(m, start)
+
+	durations := registry.Histogram("http_server_request_duration_seconds", nil, metrics.Labels{"route": "/"})
+	for i := 0; i < 6; i++ {
+		durations.Observe(0.1)
+	}
+	durations.Observe(0.28) // under the threshold, between the 250ms and 500ms bounds of the DefaultBuckets
+	durations.Observe(0.32)
+	registry.Histogram("grpc_server_request_duration_seconds", nil, nil).Observe(2)
+	registry.Histogram("grpc_server_request_duration_seconds", nil, nil).Observe(0.3)
+
+	// 2 slow requests out of 10, twice the 10% allowed
+	slo.Evaluate(m, start.Add(time.Minute))
+	assert.InDelta(t, 2, m.BurnRate("latency_300ms", time.Hour), 1e-9)
+	assert.InDelta(t, 2, m.BurnRate("latency_300ms", 30*time.Minute), 1e-9)
+	assert.InDelta(t, 2, m.BurnRate("latency_300ms", 6*time.Hour), 1e-9)
+}
+
+func TestMonitor_Serve(t *testing.T) {
+	config := slo.DefaultConfig()
+	config.Objectives = []slo.Objective{{Name: "availability", Kind: slo.Availability, Target: 0.999}}
+	config.Interval = time.Millisecond
+
+	registry := metrics.NewRegistry()
+	registry.Counter("http_server_requests_total", metrics.Labels{"code": "500"}).Add(1)
+	m := newMonitor(t, config, registry)
+
+	done := make(chan error)
+	go func() { done <- m.Serve() }()
+	require.Eventually(t, func() bool {
+		for _, s := range registry.Gather() {
+			if s.Name == "slo_burn_rate" {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,115 @@
+package sse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the stream settings of a Broker, as in APP_SSE_KEEP_ALIVE.
+	DefaultPrefix = "APP_SSE_"
+
+	// DefaultHistory is the number of recent events kept for clients reconnecting with Last-Event-ID.
+	DefaultHistory = 100
+
+	// DefaultKeepAlive is how often idle streams receive a comment to keep intermediaries from closing them.
+	DefaultKeepAlive = 15 * time.Second
+
+	// DefaultRetry is the reconnection delay suggested to clients.
+	DefaultRetry = 3 * time.Second
+
+	// DefaultSendQueue is the number of events buffered for each client.
+	DefaultSendQueue = 64
+)
+
+// Config describes the buffering and keep-alive behavior of a Hub. New uses the default of each field left zero, but
+// History, which keeps no events when zero.
+type Config struct {
+	History   int           // recent events kept for reconnecting clients
+	KeepAlive time.Duration // interval of keep-alive comments on idle streams
+	Retry     time.Duration // reconnection delay suggested to clients
+	SendQueue int           // events buffered for each client; clients falling further behind are disconnected
+}
+
+// DefaultConfig returns a Config replaying the last DefaultHistory events to reconnecting clients.
+func DefaultConfig() *Config {
+	return &Config{
+		History:   DefaultHistory,
+		KeepAlive: DefaultKeepAlive,
+		Retry:     DefaultRetry,
+		SendQueue: DefaultSendQueue,
+	}
+}
+
+// withDefaults returns a copy of c with the defaults in place of the fields left zero.
+func (c Config) withDefaults() Config {
+	if c.KeepAlive <= 0 {
+		c.KeepAlive = DefaultKeepAlive
+	}
+	if c.Retry <= 0 {
+		c.Retry = DefaultRetry
+	}
+	if c.SendQueue <= 0 {
+		c.SendQueue = DefaultSendQueue
+	}
+	return c
+}
+
+func init() {
+	configschema.Register("sse", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the server-sent events variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "HISTORY", Type: "int", Default: strconv.Itoa(DefaultHistory),
+			Description: "The recent events kept for reconnecting clients."},
+		{Name: prefix + "KEEP_ALIVE", Type: "duration", Default: DefaultKeepAlive.String(),
+			Description: "How often idle streams get a keep-alive comment."},
+		{Name: prefix + "RETRY", Type: "duration", Default: DefaultRetry.String(),
+			Description: "The reconnection delay suggested to clients."},
+		{Name: prefix + "SEND_QUEUE", Type: "int", Default: strconv.Itoa(DefaultSendQueue),
+			Description: "The events buffered for each client before it is disconnected."},
+	}
+}
+
+// FromEnv reads the HISTORY kept for reconnecting clients, the KEEP_ALIVE interval, the RETRY suggested to clients, and
+// the SEND_QUEUE of each, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	for key, dst := range map[string]*time.Duration{"KEEP_ALIVE": &config.KeepAlive, "RETRY": &config.Retry} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("sse: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	for key, dst := range map[string]*int{"HISTORY": &config.History, "SEND_QUEUE": &config.SendQueue} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 || (n == 0 && key == "SEND_QUEUE") {
+				return nil, fmt.Errorf("sse: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	return config, nil
+}
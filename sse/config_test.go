@@ -0,0 +1,52 @@
+package sse_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/sse"
+)
+
+func TestFromEnv_Queues(t *testing.T) {
+	// no history turns replay off, but every client needs room for at least one event
+	config, err := sse.FromEnv(apptest.Lookup(map[string]string{
+		"EVENTS_HISTORY":    "0",
+		"EVENTS_SEND_QUEUE": "1",
+	}), "EVENTS_")
+	require.NoError(t, err)
+	assert.Zero(t, config.History)
+	assert.Equal(t, 1, config.SendQueue)
+
+	for key, values := range map[string][]string{"HISTORY": {"-1", "all"}, "SEND_QUEUE": {"0", "-4"}} {
+		for _, v := range values {
+			_, err := sse.FromEnv(apptest.Lookup(map[string]string{"APP_SSE_" + key: v}), "")
+			assert.EqualError(t, err, "sse: invalid APP_SSE_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	config, err := sse.FromEnv(apptest.Lookup(map[string]string{
+		"APP_SSE_KEEP_ALIVE": "1m",
+		"APP_SSE_RETRY":      "500ms",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, config.KeepAlive)
+	assert.Equal(t, 500*time.Millisecond, config.Retry)
+
+	config, err = sse.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, sse.DefaultConfig(), config)
+
+	// the retry field is whole milliseconds, so a bare number would be ambiguous
+	for key, values := range map[string][]string{"KEEP_ALIVE": {"0s", "-1m"}, "RETRY": {"soon", "500"}} {
+		for _, v := range values {
+			_, err := sse.FromEnv(apptest.Lookup(map[string]string{"APP_SSE_" + key: v}), "")
+			assert.EqualError(t, err, "sse: invalid APP_SSE_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package sse broadcasts Server-Sent Events to HTTP clients. A Hub replays missed events to clients reconnecting with
+// Last-Event-ID and ends every stream when the App shuts down.
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Event is a message published to the clients of a Hub.
+type Event struct {
+	ID   string      // assigned by Publish
+	Type string      // event name dispatched by the client; "message" when empty
+	Data interface{} // string or []byte sent as is; other values are encoded as JSON
+}
+
+// Hub is an http.Handler streaming published events to its clients. It is also an app.Server, so registering it with
+// App.Register ends every stream when the App shuts down; register it after the HTTP server serving it so it is
+// shut down first.
+type Hub struct {
+	config Config
+	logger gomol.WrappableLogger
+
+	mu       sync.Mutex
+	nextID   uint64
+	history  []frame
+	clients  map[*client]struct{}
+	closed   bool
+	stopping chan struct{}
+	streams  sync.WaitGroup
+}
+
+type frame struct {
+	id   uint64
+	data []byte
+}
+
+type client struct {
+	events  chan []byte
+	dropped chan struct{}
+}
+
+// New returns a Hub. The logger may be nil.
+func New(config *Config, logger gomol.WrappableLogger) *Hub {
+	return &Hub{
+		config:   config.withDefaults(),
+		logger:   logger,
+		clients:  make(map[*client]struct{}),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Publish assigns the event the next ID and sends it to every client. Clients whose queue is full are disconnected;
+// they can catch up from the history when they reconnect.
+func (h *Hub) Publish(e Event) error {
+	data, err := encodeData(e.Data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	e.ID = strconv.FormatUint(h.nextID, 10)
+	f := frame{id: h.nextID, data: encode(e, data)}
+
+	if h.config.History > 0 {
+		if len(h.history) == h.config.History {
+			h.history = append(h.history[:0], h.history[1:]...)
+		}
+		h.history = append(h.history, f)
+	}
+
+	for c := range h.clients {
+		select {
+		case c.events <- f.data:
+		default:
+			delete(h.clients, c)
+			close(c.dropped)
+		}
+	}
+
+	return nil
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// ServeHTTP subscribes the client and streams events until the client disconnects or the Hub shuts down.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	c := &client{
+		events:  make(chan []byte, h.config.SendQueue),
+		dropped: make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	replay := h.since(lastEventID(r))
+	h.clients[c] = struct{}{}
+	h.streams.Add(1)
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+		h.streams.Done()
+		h.log(gomol.LevelDebug, r, "event stream closed")
+	}()
+
+	h.log(gomol.LevelDebug, r, "event stream opened")
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("retry: " + strconv.FormatInt(int64(h.config.Retry/time.Millisecond), 10) + "\n\n")
+	for _, f := range replay {
+		buf.Write(f.data)
+	}
+	if !write(w, rc, buf.Bytes()) {
+		return
+	}
+
+	keepAlive := time.NewTicker(h.config.KeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case data := <-c.events:
+			if !write(w, rc, data) {
+				return
+			}
+		case <-keepAlive.C:
+			if !write(w, rc, []byte(": keep-alive\n\n")) {
+				return
+			}
+		case <-c.dropped:
+			h.log(gomol.LevelWarning, r, "dropped slow event stream")
+			return
+		case <-r.Context().Done():
+			return
+		case <-h.stopping:
+			return
+		}
+	}
+}
+
+// Bind uses the App logger if the Hub has none.
+func (h *Hub) Bind(a *app.App) error {
+	if h.logger == nil {
+		h.logger = a.Logger()
+	}
+	return nil
+}
+
+// Serve waits until the Hub is shut down.
+func (h *Hub) Serve() error {
+	<-h.stopping
+	return nil
+}
+
+// Shutdown refuses new clients and ends every stream, waiting for the handlers to return until the context is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.stopping)
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// since returns the events after id, or none if id is unknown or has left the history.
+func (h *Hub) since(id string) []frame {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	for idx, f := range h.history {
+		if f.id > n {
+			if idx == 0 && f.id != n+1 {
+				// events were lost between id and the oldest one kept
+				return append([]frame(nil), h.history...)
+			}
+			return append([]frame(nil), h.history[idx:]...)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) log(level gomol.LogLevel, r *http.Request, msg string) {
+	if h.logger != nil {
+		_ = h.logger.Log(level, gomol.NewAttrsFromMap(map[string]interface{}{
+			"remote": r.RemoteAddr,
+			"path":   r.URL.Path,
+		}), msg)
+	}
+}
+
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	// EventSource polyfills that cannot set headers pass the ID in the query
+	return r.URL.Query().Get("lastEventId")
+}
+
+func encodeData(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+func encode(e Event, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("id: " + e.ID + "\n")
+	if e.Type != "" {
+		buf.WriteString("event: " + strings.NewReplacer("\r", "", "\n", "").Replace(e.Type) + "\n")
+	}
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.ReplaceAll(line, []byte("\r"), nil))
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func write(w http.ResponseWriter, rc *http.ResponseController, data []byte) bool {
+	if _, err := w.Write(data); err != nil {
+		return false
+	}
+	if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/sse"
+)
+
+type stream struct {
+	res *http.Response
+	r   *bufio.Reader
+}
+
+func subscribe(t *testing.T, url, lastEventID string) *stream {
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	return &stream{res: res, r: bufio.NewReader(res.Body)}
+}
+
+// next returns the next block of the stream, without its terminating blank line.
+func (s *stream) next(t *testing.T) string {
+	var lines []string
+	for {
+		line, err := s.r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestHub(t *testing.T) {
+	hub := sse.New(sse.DefaultConfig(), nil)
+	require.NoError(t, hub.Bind(&app.App{Stderr: io.Discard}))
+
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	served := make(chan error, 1)
+	go func() { served <- hub.Serve() }()
+
+	s := subscribe(t, srv.URL, "")
+	defer s.res.Body.Close()
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	assert.Eventually(t, func() bool { return hub.Clients() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, hub.Publish(sse.Event{Data: "hello\nworld"}))
+	require.NoError(t, hub.Publish(sse.Event{Type: "user", Data: map[string]int{"id": 42}}))
+	assert.Error(t, hub.Publish(sse.Event{Data: func() {}}))
+
+	assert.Equal(t, "id: 1\ndata: hello\ndata: world\n", s.next(t))
+	assert.Equal(t, "id: 2\nevent: user\ndata: {\"id\":42}\n", s.next(t))
+
+	require.NoError(t, hub.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+
+	_, err := s.r.ReadString('\n')
+	assert.Equal(t, io.EOF, err)
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
+
+func TestHub_LastEventID(t *testing.T) {
+	config := sse.DefaultConfig()
+	config.History = 2
+	hub := sse.New(config, nil)
+
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer func() { _ = hub.Shutdown(context.Background()) }()
+
+	for _, data := range []string{"a", "b", "c"} {
+		require.NoError(t, hub.Publish(sse.Event{Data: data}))
+	}
+
+	s := subscribe(t, srv.URL, "2")
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	assert.Equal(t, "id: 3\ndata: c\n", s.next(t))
+	assert.NoError(t, s.res.Body.Close())
+
+	// event 1 has left the history, so everything still kept is replayed
+	s = subscribe(t, srv.URL+"?lastEventId=0", "")
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	assert.Equal(t, "id: 2\ndata: b\n", s.next(t))
+	assert.Equal(t, "id: 3\ndata: c\n", s.next(t))
+	assert.NoError(t, s.res.Body.Close())
+
+	s = subscribe(t, srv.URL, "3")
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	require.NoError(t, hub.Publish(sse.Event{Data: "d"}))
+	assert.Equal(t, "id: 4\ndata: d\n", s.next(t))
+	assert.NoError(t, s.res.Body.Close())
+}
+
+func TestHub_KeepAlive(t *testing.T) {
+	config := sse.DefaultConfig()
+	config.KeepAlive = 10 * time.Millisecond
+	hub := sse.New(config, nil)
+
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer func() { _ = hub.Shutdown(context.Background()) }()
+
+	s := subscribe(t, srv.URL, "")
+	defer s.res.Body.Close()
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	assert.Equal(t, ": keep-alive\n", s.next(t))
+}
+
+func TestHub_zeroConfig(t *testing.T) {
+	// the zero Config stands for the defaults, without history
+	hub := sse.New(&sse.Config{}, nil)
+
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	defer func() { _ = hub.Shutdown(context.Background()) }()
+
+	s := subscribe(t, srv.URL, "")
+	defer s.res.Body.Close()
+	assert.Equal(t, "retry: 3000\n", s.next(t))
+	assert.Eventually(t, func() bool { return hub.Clients() == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, hub.Publish(sse.Event{Data: "hello"}))
+	assert.Equal(t, "id: 1\ndata: hello\n", s.next(t))
+}
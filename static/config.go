@@ -0,0 +1,87 @@
+package static
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the file serving settings, as in APP_STATIC_DIR.
+	DefaultPrefix = "APP_STATIC_"
+
+	// DefaultIndex is the file served for directory requests and, in SPA mode, for unknown routes.
+	DefaultIndex = "index.html"
+
+	// DefaultMaxAge is how long clients may cache files that are neither HTML nor fingerprinted.
+	DefaultMaxAge = time.Hour
+)
+
+// Config describes how a Handler serves files.
+type Config struct {
+	Dir    string        // directory served when New is not given a file system
+	Index  string        // file served for directories and SPA routes
+	SPA    bool          // serve the root Index for unknown paths without a file extension
+	MaxAge time.Duration // Cache-Control max-age for files that are neither HTML nor fingerprinted
+}
+
+func init() {
+	configschema.Register("static", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the static file variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "DIR", Type: "path", Description: "The directory served, unless New is given a file system."},
+		{Name: prefix + "INDEX", Type: "string", Default: DefaultIndex,
+			Description: "The file served for directories and SPA routes."},
+		{Name: prefix + "SPA", Type: "bool", Default: "false",
+			Description: "Serve the root index for unknown paths without a file extension."},
+		{Name: prefix + "MAX_AGE", Type: "duration", Default: DefaultMaxAge.String(),
+			Description: "How long clients may cache files that are neither HTML nor fingerprinted."},
+	}
+}
+
+// FromEnv reads the DIR served, its INDEX file, whether it is a single-page app, SPA, and the MAX_AGE clients cache
+// files for, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{
+		Dir:    get("DIR"),
+		Index:  DefaultIndex,
+		MaxAge: DefaultMaxAge,
+	}
+
+	if v := get("INDEX"); v != "" {
+		config.Index = v
+	}
+
+	if v := get("SPA"); v != "" {
+		spa, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("static: invalid %sSPA %q", prefix, v)
+		}
+		config.SPA = spa
+	}
+
+	if v := get("MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("static: invalid %sMAX_AGE %q", prefix, v)
+		}
+		config.MaxAge = d
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,44 @@
+package static_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/static"
+)
+
+func TestFromEnv_SPA(t *testing.T) {
+	config, err := static.FromEnv(apptest.Lookup(map[string]string{
+		"WEB_DIR":   " /srv/www ",
+		"WEB_INDEX": "app.html",
+		"WEB_SPA":   "1",
+	}), "WEB_")
+	require.NoError(t, err)
+	assert.Equal(t, &static.Config{Dir: "/srv/www", Index: "app.html", SPA: true, MaxAge: static.DefaultMaxAge}, config)
+
+	// a blank index keeps the default rather than serving no file for directories
+	config, err = static.FromEnv(apptest.Lookup(map[string]string{"APP_STATIC_INDEX": "  "}), "")
+	require.NoError(t, err)
+	assert.Equal(t, &static.Config{Index: static.DefaultIndex, MaxAge: static.DefaultMaxAge}, config)
+
+	_, err = static.FromEnv(apptest.Lookup(map[string]string{"APP_STATIC_SPA": "maybe"}), "")
+	assert.EqualError(t, err, `static: invalid APP_STATIC_SPA "maybe"`)
+}
+
+func TestFromEnv_MaxAge(t *testing.T) {
+	// zero makes clients revalidate every file
+	for v, expected := range map[string]time.Duration{"0s": 0, "10m": 10 * time.Minute} {
+		config, err := static.FromEnv(apptest.Lookup(map[string]string{"APP_STATIC_MAX_AGE": v}), "")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.MaxAge, v)
+	}
+
+	for _, v := range []string{"-1s", "3600"} {
+		_, err := static.FromEnv(apptest.Lookup(map[string]string{"APP_STATIC_MAX_AGE": v}), "")
+		assert.EqualError(t, err, `static: invalid APP_STATIC_MAX_AGE "`+v+`"`, v)
+	}
+}
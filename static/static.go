@@ -0,0 +1,226 @@
+// Package static serves files from an embed.FS or a directory with cache headers, ETags, pre-compressed variants,
+// and an optional single-page application fallback. A Handler is typically mounted on an app.HTTPServer:
+//
+//	config, err := static.FromEnv(a.LookupEnv, "")
+//	...
+//	a.Register("http", app.NewHTTPServer("tcp://:8080", static.New(assets, config)))
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// immutable is the Cache-Control value for fingerprinted files, whose content never changes for a given name.
+const immutable = "public, max-age=31536000, immutable"
+
+// encodings are the pre-compressed variants looked up next to each file, in order of preference.
+var encodings = []struct{ name, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// fingerprint matches file names carrying a content hash, e.g. app.3f2a9c1b.js or chunk-8d41e0f7.css.
+var fingerprint = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^./]+$`)
+
+// Handler is an http.Handler serving the files of a file system.
+//
+// HTML files are served with Cache-Control: no-cache, fingerprinted files are cached for a year, and all other files
+// for Config.MaxAge. When the client accepts it, a pre-compressed variant (name.br or name.gz) is served in place of
+// the file. Every response carries an ETag derived from its content, so conditional and range requests are honored
+// even for an embed.FS, which has no modification times.
+type Handler struct {
+	fsys   fs.FS
+	config Config
+
+	etags sync.Map // etagKey -> string
+}
+
+type etagKey struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// New returns a Handler serving fsys. If fsys is nil, the files are served from config.Dir.
+func New(fsys fs.FS, config *Config) *Handler {
+	h := &Handler{fsys: fsys, config: *config}
+	if h.fsys == nil {
+		h.fsys = os.DirFS(h.config.Dir)
+	}
+	if h.config.Index == "" {
+		h.config.Index = DefaultIndex
+	}
+	return h
+}
+
+// ServeHTTP serves the file named by the request path.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+
+	name, info, err := h.resolve(name)
+	if errors.Is(err, fs.ErrNotExist) && h.config.SPA && path.Ext(name) == "" {
+		name, info, err = h.resolve(".")
+	}
+
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+		} else {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.serve(w, r, name, info); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// resolve returns the regular file for name, substituting the index file for directories.
+func (h *Handler) resolve(name string) (string, fs.FileInfo, error) {
+	info, err := fs.Stat(h.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, h.config.Index)
+		info, err = fs.Stat(h.fsys, name)
+	}
+
+	if err != nil {
+		return name, nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return name, nil, fs.ErrNotExist
+	}
+
+	return name, info, nil
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) error {
+	header := w.Header()
+	header.Set("Cache-Control", h.cacheControl(name))
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	served, servedInfo := name, info
+
+	for _, enc := range encodings {
+		variant, err := fs.Stat(h.fsys, name+enc.ext)
+		if err != nil || !variant.Mode().IsRegular() {
+			continue
+		}
+
+		header.Add("Vary", "Accept-Encoding")
+		if served == name && acceptsEncoding(r, enc.name) {
+			header.Set("Content-Encoding", enc.name)
+			served, servedInfo = name+enc.ext, variant
+		}
+	}
+
+	if ctype == "" && served != name {
+		// content sniffing would see the compressed bytes
+		ctype = "application/octet-stream"
+	}
+	if ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+
+	f, err := h.fsys.Open(served)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+
+	etag, err := h.etag(served, servedInfo, content)
+	if err != nil {
+		return err
+	}
+	header.Set("ETag", etag)
+
+	http.ServeContent(w, r, name, servedInfo.ModTime(), content)
+	return nil
+}
+
+func (h *Handler) cacheControl(name string) string {
+	switch {
+	case path.Ext(name) == ".html" || path.Base(name) == h.config.Index:
+		return "no-cache"
+	case fingerprint.MatchString(name):
+		return immutable
+	case h.config.MaxAge <= 0:
+		return "no-cache"
+	default:
+		return fmt.Sprintf("public, max-age=%d", int64(h.config.MaxAge/time.Second))
+	}
+}
+
+// etag returns the strong ETag for the content, computing it only the first time a file version is seen.
+func (h *Handler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := etagKey{name: name, modTime: info.ModTime(), size: info.Size()}
+	if v, ok := h.etags.Load(key); ok {
+		return v.(string), nil
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+// acceptsEncoding reports whether the Accept-Encoding request header allows the coding.
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			token, params, _ := strings.Cut(part, ";")
+			if !strings.EqualFold(strings.TrimSpace(token), coding) {
+				continue
+			}
+
+			q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !ok {
+				return true
+			}
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+	}
+	return false
+}
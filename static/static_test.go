@@ -0,0 +1,143 @@
+package static_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/static"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func testFS(t *testing.T) fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":            {Data: []byte("<html>index</html>")},
+		"app.0123abcd.js":       {Data: []byte("console.log('app')")},
+		"app.0123abcd.js.gz":    {Data: gzipped(t, "console.log('app')")},
+		"style.css":             {Data: []byte("body{}")},
+		"docs/index.html":       {Data: []byte("<html>docs</html>")},
+		"docs/guide/intro.html": {Data: []byte("<html>intro</html>")},
+	}
+}
+
+func get(h http.Handler, target string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h := static.New(testFS(t), &static.Config{MaxAge: time.Minute})
+
+	w := get(h, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<html>index</html>", w.Body.String())
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get(h, "/docs")
+	assert.Equal(t, "<html>docs</html>", w.Body.String())
+
+	w = get(h, "/style.css")
+	assert.Equal(t, "body{}", w.Body.String())
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+
+	w = get(h, "/app.0123abcd.js")
+	assert.Equal(t, "console.log('app')", w.Body.String())
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+	assert.Equal(t, http.StatusNotFound, get(h, "/missing").Code)
+	assert.Equal(t, http.StatusNotFound, get(h, "/docs/guide").Code)
+	assert.Equal(t, http.StatusOK, get(h, "/../style.css").Code)
+
+	r := httptest.NewRequest("POST", "/", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+}
+
+func TestHandler_Precompressed(t *testing.T) {
+	h := static.New(testFS(t), &static.Config{})
+
+	w := get(h, "/app.0123abcd.js", "Accept-Encoding", "br;q=1.0, gzip;q=0.8")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/javascript; charset=utf-8", w.Header().Get("Content-Type"))
+
+	zr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	b, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "console.log('app')", string(b))
+
+	w = get(h, "/app.0123abcd.js", "Accept-Encoding", "gzip;q=0")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "console.log('app')", w.Body.String())
+}
+
+func TestHandler_ETag(t *testing.T) {
+	h := static.New(testFS(t), &static.Config{})
+
+	w := get(h, "/style.css")
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	w = get(h, "/style.css", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = get(h, "/style.css", "Range", "bytes=0-3")
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "body", w.Body.String())
+
+	gz := get(h, "/app.0123abcd.js", "Accept-Encoding", "gzip")
+	plain := get(h, "/app.0123abcd.js")
+	assert.NotEqual(t, gz.Header().Get("ETag"), plain.Header().Get("ETag"))
+}
+
+func TestHandler_SPA(t *testing.T) {
+	h := static.New(testFS(t), &static.Config{SPA: true})
+
+	w := get(h, "/users/42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<html>index</html>", w.Body.String())
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusNotFound, get(h, "/missing.js").Code)
+}
+
+func TestHandler_Dir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "home.html"), []byte("home"), 0o644))
+
+	h := static.New(nil, &static.Config{Dir: dir, Index: "home.html"})
+
+	w := get(h, "/")
+	assert.Equal(t, "home", w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+}
@@ -0,0 +1,123 @@
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the StatsD agent settings, as in APP_STATSD_ADDRESS.
+	DefaultPrefix = "APP_STATSD_"
+
+	// DefaultAddress is the address of the StatsD agent, listening on the host of the app.
+	DefaultAddress = "127.0.0.1:8125"
+
+	// DefaultFlushInterval is how often the metrics are sent.
+	DefaultFlushInterval = 10 * time.Second
+
+	// DefaultMaxPacketSize is the size of the largest datagram sent, fitting in an Ethernet frame with the IP and UDP
+	// headers.
+	DefaultMaxPacketSize = 1432
+)
+
+// Config describes where and how the Exporter sends the metrics.
+type Config struct {
+	Address       string        // the host:port of the StatsD agent, over UDP
+	Namespace     string        // prepended to the names of the metrics, such as "myapp."
+	DogStatsD     bool          // whether the labels are sent as DogStatsD tags, rather than in the names
+	Tags          []string      // the DogStatsD tags, such as env:prod, added to every metric
+	FlushInterval time.Duration // how often the metrics are sent
+	MaxPacketSize int           // the size of the largest datagram, in bytes
+}
+
+// DefaultConfig returns a Config sending the metrics to a plain StatsD agent on the host.
+func DefaultConfig() *Config {
+	return &Config{Address: DefaultAddress, FlushInterval: DefaultFlushInterval, MaxPacketSize: DefaultMaxPacketSize}
+}
+
+func init() {
+	configschema.Register("statsd", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the StatsD variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "ADDRESS", Type: "string", Default: DefaultAddress,
+			Description: "The host:port of the StatsD agent, over UDP."},
+		{Name: prefix + "NAMESPACE", Type: "string", Description: "The prefix of the names of the metrics."},
+		{Name: prefix + "DOGSTATSD", Type: "bool", Default: "false", Description: "Send the labels as DogStatsD tags."},
+		{Name: prefix + "TAGS", Type: "string",
+			Description: "The DogStatsD tags added to every metric, separated by spaces or commas."},
+		{Name: prefix + "FLUSH_INTERVAL", Type: "duration", Default: DefaultFlushInterval.String(),
+			Description: "How often the metrics are sent."},
+		{Name: prefix + "MAX_PACKET_SIZE", Type: "int", Default: strconv.Itoa(DefaultMaxPacketSize),
+			Description: "The size of the largest datagram, in bytes."},
+	}
+}
+
+// FromEnv reads the ADDRESS of the agent, the NAMESPACE of the metrics, whether labels are sent as DOGSTATSD tags, the
+// TAGS added to every metric, separated by spaces or commas, the FLUSH_INTERVAL, and the MAX_PACKET_SIZE, with the
+// prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("ADDRESS"); v != "" {
+		if _, port, err := net.SplitHostPort(v); err != nil || port == "" {
+			return nil, fmt.Errorf("statsd: invalid %sADDRESS %q", prefix, v)
+		}
+		config.Address = v
+	}
+
+	if v := get("NAMESPACE"); v != "" {
+		if strings.ContainsAny(v, ":|@# ") {
+			return nil, fmt.Errorf("statsd: invalid %sNAMESPACE %q", prefix, v)
+		}
+		config.Namespace = v
+	}
+
+	if v := get("DOGSTATSD"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("statsd: invalid %sDOGSTATSD %q", prefix, v)
+		}
+		config.DogStatsD = b
+	}
+
+	for _, tag := range strings.FieldsFunc(get("TAGS"), func(r rune) bool { return r == ' ' || r == ',' }) {
+		if strings.ContainsAny(tag, "|#@") {
+			return nil, fmt.Errorf("statsd: invalid %sTAGS tag %q", prefix, tag)
+		}
+		config.Tags = append(config.Tags, tag)
+	}
+
+	if v := get("FLUSH_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("statsd: invalid %sFLUSH_INTERVAL %q", prefix, v)
+		}
+		config.FlushInterval = d
+	}
+
+	if v := get("MAX_PACKET_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 512 || n > 65507 {
+			return nil, fmt.Errorf("statsd: invalid %sMAX_PACKET_SIZE %q", prefix, v)
+		}
+		config.MaxPacketSize = n
+	}
+
+	return config, nil
+}
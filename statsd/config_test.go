@@ -0,0 +1,64 @@
+package statsd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/statsd"
+)
+
+func TestFromEnv_Address(t *testing.T) {
+	for _, v := range []string{"datadog-agent:8125", ":8125", "[::1]:8125"} {
+		config, err := statsd.FromEnv(apptest.Lookup(map[string]string{"WORKER_STATSD_ADDRESS": v}), "WORKER_STATSD_")
+		require.NoError(t, err, v)
+		assert.Equal(t, v, config.Address)
+	}
+
+	// the port is required; there is no default to fall back to over UDP
+	for _, v := range []string{"localhost", "localhost:", "::1"} {
+		_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_ADDRESS": v}), "")
+		assert.EqualError(t, err, `statsd: invalid APP_STATSD_ADDRESS "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Names(t *testing.T) {
+	config, err := statsd.FromEnv(apptest.Lookup(map[string]string{
+		"APP_STATSD_NAMESPACE": "myapp.",
+		"APP_STATSD_DOGSTATSD": "true",
+		"APP_STATSD_TAGS":      "env:prod, service:api,,",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "myapp.", config.Namespace)
+	assert.True(t, config.DogStatsD)
+	// a tag may hold a colon, between its key and value
+	assert.Equal(t, []string{"env:prod", "service:api"}, config.Tags)
+
+	// each of these delimits a field of the line protocol
+	for _, v := range []string{"my app.", "my:app", "app|", "app@", "app#"} {
+		_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_NAMESPACE": v}), "")
+		assert.EqualError(t, err, `statsd: invalid APP_STATSD_NAMESPACE "`+v+`"`, v)
+	}
+	for _, v := range []string{"env|prod", "#env", "env@prod"} {
+		_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_TAGS": "service:api " + v}), "")
+		assert.EqualError(t, err, `statsd: invalid APP_STATSD_TAGS tag "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_Flush(t *testing.T) {
+	// from the smallest datagram every network carries to the largest UDP payload over IPv4
+	for _, v := range []string{"512", "65507"} {
+		_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_MAX_PACKET_SIZE": v}), "")
+		assert.NoError(t, err, v)
+	}
+
+	for _, v := range []string{"511", "65508", "1 KiB"} {
+		_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_MAX_PACKET_SIZE": v}), "")
+		assert.EqualError(t, err, `statsd: invalid APP_STATSD_MAX_PACKET_SIZE "`+v+`"`, v)
+	}
+
+	_, err := statsd.FromEnv(apptest.Lookup(map[string]string{"APP_STATSD_FLUSH_INTERVAL": "0s"}), "")
+	assert.EqualError(t, err, `statsd: invalid APP_STATSD_FLUSH_INTERVAL "0s"`)
+}
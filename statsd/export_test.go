@@ -0,0 +1,10 @@
+package statsd
+
+import "net"
+
+// WrapConn replaces the socket the Exporter sends the metrics on with the one wrap returns.
+func WrapConn(e *Exporter, wrap func(net.Conn) net.Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.conn = wrap(e.conn)
+}
@@ -0,0 +1,225 @@
+// Package statsd sends the metrics of a metrics.Registry to a StatsD agent, or a DogStatsD one such as the Datadog
+// agent, for teams not scraping them with Prometheus:
+//
+//	config, err := statsd.FromEnv(a.LookupEnv, "")
+//	if err != nil {
+//		return err
+//	}
+//	a.Register("statsd", statsd.New(config, a.Metrics()))
+//
+// Every FlushInterval, and when the app shuts down, the Exporter sends the increase of every counter since it was last
+// sent, the value of every gauge, and the increase of the count and the sum of every histogram, as the counters
+// <name>.count and <name>.sum, batched in UDP datagrams of up to MaxPacketSize bytes. The labels of the metrics are
+// sent as DogStatsD tags, or appended to their names, as requests_total.code.200, for plain StatsD.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// replacer replaces the characters StatsD gives a meaning to in the names, tags, and label values.
+var replacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_")
+
+// Exporter is a Server sending the metrics of a Registry to a StatsD agent.
+type Exporter struct {
+	config   Config
+	registry *metrics.Registry
+	logger   *gomol.Base
+
+	mu      sync.Mutex
+	conn    net.Conn
+	last    map[string]float64 // the counters, and the histogram counts and sums, as they were last sent
+	failing bool
+
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns an Exporter sending the metrics of registry as config describes.
+func New(config *Config, registry *metrics.Registry) *Exporter {
+	return &Exporter{
+		config:   *config,
+		registry: registry,
+		last:     make(map[string]float64),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Bind opens the UDP socket to the agent.
+func (e *Exporter) Bind(a *app.App) error {
+	conn, err := net.Dial("udp", e.config.Address)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.conn, e.logger = conn, a.Logger()
+	return nil
+}
+
+// Serve flushes the metrics every FlushInterval until the Exporter is shut down. A flush failing is logged once,
+// until one succeeds again.
+func (e *Exporter) Serve() error {
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopping:
+			return nil
+		case <-ticker.C:
+			err := e.Flush()
+			e.mu.Lock()
+			if err != nil && !e.failing {
+				_ = e.logger.Warnf("unable to send the metrics to %s: %v", e.config.Address, err)
+			}
+			e.failing = err != nil
+			e.mu.Unlock()
+		}
+	}
+}
+
+// Shutdown flushes the metrics a last time and closes the socket.
+func (e *Exporter) Shutdown(context.Context) error {
+	e.stopOnce.Do(func() { close(e.stopping) })
+	err := e.Flush()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conn != nil {
+		_ = e.conn.Close()
+		e.conn = nil
+	}
+	return err
+}
+
+// Flush sends the metrics now, returning the first error writing a datagram.
+func (e *Exporter) Flush() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return errors.New("statsd: the Exporter is not bound")
+	}
+
+	var (
+		packet  []byte
+		pending []entry // the entries of the packet
+		first   error
+	)
+	send := func() {
+		if len(packet) == 0 {
+			return
+		}
+		// the counters sent are recorded only once they are, so that a failed flush sends their increase again
+		if _, err := e.conn.Write(packet); err != nil {
+			if first == nil {
+				first = err
+			}
+		} else {
+			for _, en := range pending {
+				if en.key != "" {
+					e.last[en.key] = en.value
+				}
+			}
+		}
+		packet, pending = packet[:0], pending[:0]
+	}
+	for _, s := range e.registry.Gather() {
+		for _, en := range e.entries(s) {
+			if len(packet) > 0 && len(packet)+1+len(en.line) > e.config.MaxPacketSize {
+				send()
+			}
+			if len(packet) > 0 {
+				packet = append(packet, '\n')
+			}
+			packet, pending = append(packet, en.line...), append(pending, en)
+		}
+	}
+	send()
+	return first
+}
+
+// entry is a StatsD line, with the value of the counter it sends the increase of.
+type entry struct {
+	line  string
+	key   string  // the key of the counter in last, empty for a gauge
+	value float64 // the value of the counter
+}
+
+// entries returns the StatsD lines of a sample.
+func (e *Exporter) entries(s metrics.Sample) []entry {
+	key := s.Name + s.Labels.String()
+	switch s.Kind {
+	case metrics.KindCounter:
+		if delta := s.Value - e.last[key]; delta > 0 {
+			return []entry{{e.line(s.Name, s.Labels, delta, "c"), key, s.Value}}
+		}
+	case metrics.KindGauge:
+		if s.Value < 0 && !e.config.DogStatsD {
+			// StatsD reads a signed value as a change of the gauge, so a negative one is set from zero
+			return []entry{{line: e.line(s.Name, s.Labels, 0, "g")}, {line: e.line(s.Name, s.Labels, s.Value, "g")}}
+		}
+		return []entry{{line: e.line(s.Name, s.Labels, s.Value, "g")}}
+	case metrics.KindHistogram:
+		count, sum := float64(s.Count), s.Sum
+		if delta := count - e.last[key+".count"]; delta > 0 {
+			return []entry{
+				{e.line(s.Name+".count", s.Labels, delta, "c"), key + ".count", count},
+				{e.line(s.Name+".sum", s.Labels, sum-e.last[key+".sum"], "c"), key + ".sum", sum},
+			}
+		}
+	}
+	return nil
+}
+
+// line formats a metric in the StatsD line protocol: <name>:<value>|<type>, followed by |#<tags> for DogStatsD.
+func (e *Exporter) line(name string, labels metrics.Labels, value float64, typ string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(e.config.Namespace)
+	sb.WriteString(replacer.Replace(name))
+	if !e.config.DogStatsD {
+		for _, k := range keys {
+			sb.WriteByte('.')
+			sb.WriteString(strings.ReplaceAll(replacer.Replace(k), ".", "_"))
+			sb.WriteByte('.')
+			sb.WriteString(strings.ReplaceAll(replacer.Replace(labels[k]), ".", "_"))
+		}
+	}
+	sb.WriteByte(':')
+	sb.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	sb.WriteByte('|')
+	sb.WriteString(typ)
+
+	if e.config.DogStatsD && len(keys)+len(e.config.Tags) > 0 {
+		sb.WriteString("|#")
+		sb.WriteString(strings.Join(e.config.Tags, ","))
+		for idx, k := range keys {
+			if idx > 0 || len(e.config.Tags) > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(replacer.Replace(k))
+			sb.WriteByte(':')
+			sb.WriteString(replacer.Replace(labels[k]))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,190 @@
+package statsd_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/statsd"
+)
+
+// agent listens for the datagrams of an Exporter.
+type agent struct {
+	t    *testing.T
+	conn net.PacketConn
+}
+
+func newAgent(t *testing.T) *agent {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &agent{t: t, conn: conn}
+}
+
+// read returns the lines of the next datagram.
+func (a *agent) read() []string {
+	buf := make([]byte, 65536)
+	require.NoError(a.t, a.conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := a.conn.ReadFrom(buf)
+	require.NoError(a.t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func newExporter(t *testing.T, config *statsd.Config, registry *metrics.Registry) *statsd.Exporter {
+	e := statsd.New(config, registry)
+	require.NoError(t, e.Bind(apptest.New(t, nil)))
+	t.Cleanup(func() { _ = e.Shutdown(context.Background()) })
+	return e
+}
+
+func TestExporter_Flush(t *testing.T) {
+	agent := newAgent(t)
+	config := statsd.DefaultConfig()
+	config.Address = agent.conn.LocalAddr().String()
+	config.Namespace = "myapp."
+
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("requests_total", metrics.Labels{"code": "200", "path": "/v1.2/users"})
+	requests.Add(3)
+	registry.Gauge("temperature", nil).Set(-2.5)
+	latency := registry.Histogram("latency_seconds", nil, nil)
+	latency.Observe(0.5)
+	latency.Observe(1.5)
+
+	e := newExporter(t, config, registry)
+	require.NoError(t, e.Flush())
+	assert.Equal(t, []string{
+		"myapp.latency_seconds.count:2|c",
+		"myapp.latency_seconds.sum:2|c",
+		"myapp.requests_total.code.200.path./v1_2/users:3|c",
+		"myapp.temperature:0|g",
+		"myapp.temperature:-2.5|g",
+	}, agent.read())
+
+	// only the increase since the last flush is sent, nothing for the counters that did not change
+	requests.Inc()
+	require.NoError(t, e.Flush())
+	assert.Equal(t, []string{
+		"myapp.requests_total.code.200.path./v1_2/users:1|c",
+		"myapp.temperature:0|g",
+		"myapp.temperature:-2.5|g",
+	}, agent.read())
+}
+
+// flakyConn fails to write while fail is set.
+type flakyConn struct {
+	net.Conn
+	fail bool
+}
+
+func (c *flakyConn) Write(b []byte) (int, error) {
+	if c.fail {
+		return 0, errors.New("network is unreachable")
+	}
+	return c.Conn.Write(b)
+}
+
+func TestExporter_FlushFailed(t *testing.T) {
+	agent := newAgent(t)
+	config := statsd.DefaultConfig()
+	config.Address = agent.conn.LocalAddr().String()
+
+	registry := metrics.NewRegistry()
+	requests := registry.Counter("requests_total", nil)
+	requests.Add(2)
+	latency := registry.Histogram("latency_seconds", nil, nil)
+	latency.Observe(0.5)
+
+	e := newExporter(t, config, registry)
+	conn := &flakyConn{fail: true}
+	statsd.WrapConn(e, func(c net.Conn) net.Conn {
+		conn.Conn = c
+		return conn
+	})
+	assert.EqualError(t, e.Flush(), "network is unreachable")
+
+	// the increase the failed flush did not send is sent by the next one
+	conn.fail = false
+	requests.Inc()
+	latency.Observe(1)
+	require.NoError(t, e.Flush())
+	assert.Equal(t, []string{
+		"latency_seconds.count:2|c",
+		"latency_seconds.sum:1.5|c",
+		"requests_total:3|c",
+	}, agent.read())
+}
+
+func TestExporter_DogStatsD(t *testing.T) {
+	agent := newAgent(t)
+	config := statsd.DefaultConfig()
+	config.Address = agent.conn.LocalAddr().String()
+	config.DogStatsD = true
+	config.Tags = []string{"env:prod"}
+
+	registry := metrics.NewRegistry()
+	registry.Counter("requests_total", metrics.Labels{"code": "200", "method": "GET"}).Inc()
+	registry.Gauge("in_flight", nil).Set(-1)
+
+	e := newExporter(t, config, registry)
+	require.NoError(t, e.Flush())
+	assert.Equal(t, []string{
+		"in_flight:-1|g|#env:prod",
+		"requests_total:1|c|#env:prod,code:200,method:GET",
+	}, agent.read())
+}
+
+func TestExporter_MaxPacketSize(t *testing.T) {
+	agent := newAgent(t)
+	config := statsd.DefaultConfig()
+	config.Address = agent.conn.LocalAddr().String()
+	config.MaxPacketSize = 512
+
+	registry := metrics.NewRegistry()
+	for _, name := range strings.Fields("a b c d e f g h i j k l m n o p q r s t u v w x y z") {
+		registry.Gauge(strings.Repeat(name, 40), nil).Set(1)
+	}
+
+	e := newExporter(t, config, registry)
+	require.NoError(t, e.Flush())
+
+	var lines []string
+	for len(lines) < 26 {
+		packet := agent.read()
+		assert.LessOrEqual(t, len(strings.Join(packet, "\n")), 512)
+		lines = append(lines, packet...)
+	}
+	assert.Len(t, lines, 26)
+	assert.Equal(t, strings.Repeat("z", 40)+":1|g", lines[25])
+}
+
+func TestExporter_Serve(t *testing.T) {
+	agent := newAgent(t)
+	config := statsd.DefaultConfig()
+	config.Address = agent.conn.LocalAddr().String()
+	config.FlushInterval = 10 * time.Millisecond
+
+	registry := metrics.NewRegistry()
+	jobs := registry.Counter("jobs_total", nil)
+	jobs.Inc()
+
+	e := newExporter(t, config, registry)
+	served := make(chan error, 1)
+	go func() { served <- e.Serve() }()
+	assert.Equal(t, []string{"jobs_total:1|c"}, agent.read())
+
+	// the metrics are flushed a last time when the exporter shuts down
+	jobs.Inc()
+	require.NoError(t, e.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+	assert.Equal(t, []string{"jobs_total:1|c"}, agent.read())
+	assert.EqualError(t, e.Flush(), "statsd: the Exporter is not bound")
+}
@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the usage reporting settings, as in APP_TELEMETRY_ENABLED.
+	DefaultPrefix = "APP_TELEMETRY_"
+
+	// DefaultBatchSize is the number of events sent together.
+	DefaultBatchSize = 20
+
+	// DefaultFlushInterval is how often queued events are sent when the batch is not full.
+	DefaultFlushInterval = 30 * time.Second
+
+	// DefaultFlushTimeout bounds how long Exit waits for queued events to be sent.
+	DefaultFlushTimeout = 2 * time.Second
+)
+
+// Config describes where and how usage events are sent. No events are sent unless Enabled is set and an Endpoint
+// is configured.
+type Config struct {
+	Endpoint      string        // URL receiving batches of events
+	Enabled       bool          // false once the user has opted out
+	BatchSize     int           // events sent together
+	FlushInterval time.Duration // delay before a partial batch is sent
+	FlushTimeout  time.Duration // bound on the final flush during Exit
+}
+
+// DefaultConfig returns an enabled Config batching DefaultBatchSize events. It has no Endpoint, so it sends nothing.
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:       true,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		FlushTimeout:  DefaultFlushTimeout,
+	}
+}
+
+func init() {
+	configschema.Register("telemetry", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the telemetry variables with the prefix, and DO_NOT_TRACK.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "ENDPOINT", Type: "url",
+			Description: "The URL the events are sent to; none are sent if not set."},
+		{Name: prefix + "ENABLED", Type: "bool", Default: "true", Description: "Send usage events."},
+		{Name: prefix + "BATCH_SIZE", Type: "int", Default: strconv.Itoa(DefaultBatchSize),
+			Description: "The events sent together."},
+		{Name: prefix + "FLUSH_INTERVAL", Type: "duration", Default: DefaultFlushInterval.String(),
+			Description: "How long a partial batch waits before it is sent."},
+		{Name: prefix + "FLUSH_TIMEOUT", Type: "duration", Default: DefaultFlushTimeout.String(),
+			Description: "How long the last flush may take on exit."},
+		{Name: "DO_NOT_TRACK", Type: "bool", Description: "Set to anything but 0 or false to send no usage events."},
+	}
+}
+
+// FromEnv reads the ENDPOINT the events go to, whether they are sent, ENABLED, and how they are batched, BATCH_SIZE,
+// FLUSH_INTERVAL, and FLUSH_TIMEOUT, with the prefix or DefaultPrefix. Setting DO_NOT_TRACK to any value other than 0
+// or false disables telemetry whatever ENABLED says (see https://consoledonottrack.com).
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("ENDPOINT"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("telemetry: invalid %sENDPOINT %q", prefix, v)
+		}
+		config.Endpoint = v
+	}
+
+	if v := get("ENABLED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("telemetry: invalid %sENABLED %q", prefix, v)
+		}
+		config.Enabled = b
+	}
+
+	if v := get("BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("telemetry: invalid %sBATCH_SIZE %q", prefix, v)
+		}
+		config.BatchSize = n
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"FLUSH_INTERVAL": &config.FlushInterval,
+		"FLUSH_TIMEOUT":  &config.FlushTimeout,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("telemetry: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if doNotTrack(lookup) {
+		config.Enabled = false
+	}
+
+	return config, nil
+}
+
+func doNotTrack(lookup func(string) (string, bool)) bool {
+	v, _ := lookup("DO_NOT_TRACK")
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "", "0", "false":
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,70 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/telemetry"
+)
+
+func TestFromEnv_Endpoint(t *testing.T) {
+	config, err := telemetry.FromEnv(apptest.Lookup(map[string]string{
+		"USAGE_ENDPOINT": " https://telemetry.example.com/v1/events ",
+	}), "USAGE_")
+	require.NoError(t, err)
+	assert.Equal(t, "https://telemetry.example.com/v1/events", config.Endpoint)
+
+	// without an endpoint telemetry stays enabled but has nowhere to send
+	config, err = telemetry.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, telemetry.DefaultConfig(), config)
+
+	for _, v := range []string{"ftp://example.com", "telemetry.example.com", "/v1/events"} {
+		_, err := telemetry.FromEnv(apptest.Lookup(map[string]string{"APP_TELEMETRY_ENDPOINT": v}), "")
+		assert.EqualError(t, err, `telemetry: invalid APP_TELEMETRY_ENDPOINT "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_DoNotTrack(t *testing.T) {
+	// any value but 0 or false opts out, whatever ENABLED says
+	for value, enabled := range map[string]bool{"1": false, "true": false, "yes": false, " FALSE ": true, "0": true} {
+		config, err := telemetry.FromEnv(apptest.Lookup(map[string]string{
+			"APP_TELEMETRY_ENABLED": "true",
+			"DO_NOT_TRACK":          value,
+		}), "")
+		assert.NoError(t, err)
+		assert.Equal(t, enabled, config.Enabled, value)
+	}
+
+	config, err := telemetry.FromEnv(apptest.Lookup(map[string]string{"APP_TELEMETRY_ENABLED": "false"}), "")
+	assert.NoError(t, err)
+	assert.False(t, config.Enabled)
+}
+
+func TestFromEnv_Batching(t *testing.T) {
+	config, err := telemetry.FromEnv(apptest.Lookup(map[string]string{
+		"APP_TELEMETRY_BATCH_SIZE":     "1",
+		"APP_TELEMETRY_FLUSH_INTERVAL": "1m",
+		"APP_TELEMETRY_FLUSH_TIMEOUT":  "500ms",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.BatchSize)
+	assert.Equal(t, time.Minute, config.FlushInterval)
+	assert.Equal(t, 500*time.Millisecond, config.FlushTimeout)
+
+	for key, values := range map[string][]string{
+		"ENABLED":        {"sometimes"},
+		"BATCH_SIZE":     {"0", "-1"},
+		"FLUSH_INTERVAL": {"0s", "soon"},
+		"FLUSH_TIMEOUT":  {"-1s"},
+	} {
+		for _, v := range values {
+			_, err := telemetry.FromEnv(apptest.Lookup(map[string]string{"APP_TELEMETRY_" + key: v}), "")
+			assert.EqualError(t, err, "telemetry: invalid APP_TELEMETRY_"+key+` "`+v+`"`)
+		}
+	}
+}
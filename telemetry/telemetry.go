@@ -0,0 +1,270 @@
+// Package telemetry reports anonymous usage events, such as which command ran, how long it took, and whether it
+// succeeded, to a collection endpoint. Events carry a random installation ID and never the command arguments.
+// Telemetry honors DO_NOT_TRACK and the opt-out in Config, and Install bounds the time Exit spends sending the final
+// batch by FlushTimeout.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Event is a usage event.
+type Event struct {
+	Command    string
+	Duration   time.Duration
+	Success    bool
+	Time       time.Time         // when the event happened; Track sets the current time if zero
+	Properties map[string]string // additional dimensions; they must not identify the user
+}
+
+// event is the wire form of an Event.
+type event struct {
+	Command    string            `json:"command"`
+	DurationMS int64             `json:"duration_ms"`
+	Success    bool              `json:"success"`
+	Time       time.Time         `json:"time"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// batch is the body posted to the endpoint.
+type batch struct {
+	ID     string  `json:"id"`
+	OS     string  `json:"os"`
+	Arch   string  `json:"arch"`
+	Events []event `json:"events"`
+}
+
+// Client queues events and sends them to the endpoint in batches.
+type Client struct {
+	Client *http.Client // client used to send batches, http.DefaultClient if nil
+
+	config *Config
+	id     string
+	logger gomol.WrappableLogger
+
+	mu      sync.Mutex
+	queue   []event
+	timer   *time.Timer
+	sending sync.WaitGroup
+	ctx     context.Context // canceled when a Flush gives up on the batches in flight
+	cancel  context.CancelFunc
+}
+
+// New returns a Client sending events identified by the installation id.
+func New(config *Config, id string, logger gomol.WrappableLogger) *Client {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Client{config: config, id: id, logger: logger}
+}
+
+// Install creates a Client for the app that reports the run of command when the app exits, with the exit code
+// deciding its success, and the uses of deprecated features, see App.Deprecate, as deprecated.<feature> properties.
+// The command should be the name of the command being run, never its arguments. The installation ID is kept in the
+// app StateDir. DO_NOT_TRACK in the app environment disables the Client, whichever way config was built.
+func Install(a *app.App, config *Config, command string) *Client {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if doNotTrack(a.LookupEnv) {
+		disabled := *config
+		disabled.Enabled = false
+		config = &disabled
+	}
+	c := New(config, "", a.Logger())
+	if !c.Enabled() {
+		// an opted out user leaves no trace, not even an installation ID
+		return c
+	}
+	c.id = installationID(a)
+
+	start := time.Now()
+	a.OnExit(func(code int) {
+		e := Event{Command: command, Duration: time.Since(start), Success: code == 0}
+		for feature, n := range a.Deprecations() {
+			if e.Properties == nil {
+				e.Properties = make(map[string]string)
+			}
+			e.Properties["deprecated."+feature] = strconv.Itoa(n)
+		}
+		c.Track(e)
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.FlushTimeout)
+		defer cancel()
+		_ = c.Flush(ctx)
+	})
+	return c
+}
+
+// installationID returns the random ID stored in the app StateDir, creating it if needed. A new random ID is used
+// for every run if the state directory is unavailable.
+func installationID(a *app.App) string {
+	dir, err := a.StateDir()
+	if err != nil {
+		return newID()
+	}
+
+	path := filepath.Join(dir, "telemetry-id")
+	if b, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(b)); id != "" {
+			return id
+		}
+	}
+
+	id := newID()
+	_ = os.WriteFile(path, []byte(id+"\n"), 0o600)
+	return id
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Enabled reports whether events are sent: an Endpoint is configured and the user has not opted out.
+func (c *Client) Enabled() bool {
+	return c.config.Enabled && c.config.Endpoint != ""
+}
+
+// Track queues the event. A full batch is sent in the background; a partial batch is sent once FlushInterval elapses.
+// Track does nothing when the Client is not Enabled.
+func (c *Client) Track(e Event) {
+	if !c.Enabled() {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queue = append(c.queue, event{
+		Command:    e.Command,
+		DurationMS: e.Duration.Milliseconds(),
+		Success:    e.Success,
+		Time:       e.Time.UTC(),
+		Properties: e.Properties,
+	})
+
+	switch {
+	case len(c.queue) >= c.config.BatchSize:
+		c.sendLocked()
+	case c.timer == nil:
+		c.timer = time.AfterFunc(c.config.FlushInterval, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.sendLocked()
+		})
+	}
+}
+
+// sendLocked sends the queued events in the background. c.mu must be held.
+func (c *Client) sendLocked() {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if len(c.queue) == 0 {
+		return
+	}
+
+	events := c.queue
+	c.queue = nil
+
+	if c.ctx == nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
+	ctx := c.ctx
+
+	c.sending.Add(1)
+	go func() {
+		defer c.sending.Done()
+		// an abandoned batch is not worth reporting, and the logger may be gone
+		if err := c.send(ctx, events); err != nil && ctx.Err() == nil {
+			c.log(gomol.LevelDebug, "unable to send telemetry: %v", err)
+		}
+	}()
+}
+
+// Flush sends the queued events and waits for every batch in flight until the context is done, when the batches
+// still in flight are abandoned.
+func (c *Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	c.sendLocked()
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.sending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		if c.cancel != nil {
+			c.cancel()
+			c.ctx, c.cancel = nil, nil
+		}
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (c *Client) send(ctx context.Context, events []event) error {
+	body, err := json.Marshal(batch{ID: c.id, OS: runtime.GOOS, Arch: runtime.GOARCH, Events: events})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("telemetry: %s", res.Status)
+	}
+	return nil
+}
+
+func (c *Client) log(level gomol.LogLevel, format string, args ...interface{}) {
+	if c.logger != nil {
+		_ = c.logger.Log(level, nil, format, args...)
+	}
+}
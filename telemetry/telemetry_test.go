@@ -0,0 +1,189 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/telemetry"
+)
+
+type collector struct {
+	mu      sync.Mutex
+	batches []map[string]interface{}
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var b map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, b)
+}
+
+func (c *collector) Batches() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]map[string]interface{}(nil), c.batches...)
+}
+
+func newConfig(endpoint string) *telemetry.Config {
+	config := telemetry.DefaultConfig()
+	config.Endpoint = endpoint
+	config.BatchSize = 2
+	return config
+}
+
+func TestClient_Track(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	client := telemetry.New(newConfig(srv.URL), "abc", nil)
+	client.Track(telemetry.Event{Command: "sync", Duration: 1500 * time.Millisecond, Success: true})
+	client.Track(telemetry.Event{Command: "push", Properties: map[string]string{"mode": "dry-run"}})
+	client.Track(telemetry.Event{Command: "pull"})
+	require.NoError(t, client.Flush(context.Background()))
+
+	// the full batch and the flushed one are sent concurrently
+	batches := c.Batches()
+	require.Len(t, batches, 2)
+	sort.Slice(batches, func(i, j int) bool {
+		return len(batches[i]["events"].([]interface{})) > len(batches[j]["events"].([]interface{}))
+	})
+	assert.Equal(t, "abc", batches[0]["id"])
+	assert.NotEmpty(t, batches[0]["os"])
+
+	events := batches[0]["events"].([]interface{})
+	require.Len(t, events, 2)
+	first := events[0].(map[string]interface{})
+	assert.Equal(t, "sync", first["command"])
+	assert.Equal(t, 1500.0, first["duration_ms"])
+	assert.Equal(t, true, first["success"])
+	assert.Equal(t, map[string]interface{}{"mode": "dry-run"}, events[1].(map[string]interface{})["properties"])
+	assert.Len(t, batches[1]["events"], 1)
+}
+
+func TestClient_FlushInterval(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := newConfig(srv.URL)
+	config.FlushInterval = 10 * time.Millisecond
+	telemetry.New(config, "abc", nil).Track(telemetry.Event{Command: "sync"})
+
+	assert.Eventually(t, func() bool { return len(c.Batches()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestClient_Disabled(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	config := newConfig(srv.URL)
+	config.Enabled = false
+	client := telemetry.New(config, "abc", nil)
+	assert.False(t, client.Enabled())
+	client.Track(telemetry.Event{Command: "sync"})
+	require.NoError(t, client.Flush(context.Background()))
+	assert.Empty(t, c.Batches())
+
+	assert.False(t, telemetry.New(nil, "abc", nil).Enabled())
+}
+
+func TestInstall(t *testing.T) {
+	c := new(collector)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	a := &app.App{
+		Environment: []string{"APP_STATE_DIR=" + dir},
+		Context:     context.Background(),
+		Stderr:      io.Discard,
+		ExitHandler: func(int) {},
+	}
+	telemetry.Install(a, newConfig(srv.URL), "deploy")
+	_ = a.Deprecate("the --bind flag", "", "use --addr instead")
+	_ = a.Deprecate("the --bind flag", "", "use --addr instead")
+
+	assert.PanicsWithValue(t, "exit handler returned", func() { a.Exit(2) })
+
+	batches := c.Batches()
+	require.Len(t, batches, 1)
+	event := batches[0]["events"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "deploy", event["command"])
+	assert.Equal(t, false, event["success"])
+	assert.Equal(t, map[string]interface{}{"deprecated.the --bind flag": "2"}, event["properties"])
+
+	id, err := os.ReadFile(filepath.Join(dir, "telemetry-id"))
+	require.NoError(t, err)
+	assert.Equal(t, batches[0]["id"], strings.TrimSpace(string(id)))
+}
+
+func TestInstall_FlushTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	config := newConfig(srv.URL)
+	config.FlushTimeout = 50 * time.Millisecond
+	a := &app.App{
+		Environment: []string{"APP_STATE_DIR=" + t.TempDir()},
+		Context:     context.Background(),
+		Stderr:      io.Discard,
+		ExitHandler: func(int) {},
+	}
+	telemetry.Install(a, config, "deploy")
+
+	start := time.Now()
+	assert.PanicsWithValue(t, "exit handler returned", func() { a.Exit(0) })
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestInstall_OptOut(t *testing.T) {
+	dir := t.TempDir()
+	config := newConfig("http://127.0.0.1:1")
+	config.Enabled = false
+	a := &app.App{Environment: []string{"APP_STATE_DIR=" + dir}, Context: context.Background(), Stderr: io.Discard}
+
+	assert.False(t, telemetry.Install(a, config, "deploy").Enabled())
+	_, err := os.Stat(filepath.Join(dir, "telemetry-id"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInstall_DoNotTrack(t *testing.T) {
+	dir := t.TempDir()
+	config := newConfig("http://127.0.0.1:1")
+	a := &app.App{
+		Environment: []string{"APP_STATE_DIR=" + dir, "DO_NOT_TRACK=1"},
+		Context:     context.Background(),
+		Stderr:      io.Discard,
+	}
+
+	assert.False(t, telemetry.Install(a, config, "deploy").Enabled())
+	assert.True(t, config.Enabled)
+	_, err := os.Stat(filepath.Join(dir, "telemetry-id"))
+	assert.True(t, os.IsNotExist(err))
+}
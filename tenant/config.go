@@ -0,0 +1,100 @@
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes how the tenant of a request is found, as in APP_TENANT_HEADER.
+	DefaultPrefix = "APP_TENANT_"
+
+	// DefaultHeader is the request header the Middleware reads the tenant from.
+	DefaultHeader = "X-Tenant-ID"
+
+	// DefaultCapacity is the number of tenants a Pool keeps resources open for.
+	DefaultCapacity = 100
+)
+
+// Config describes how the Middleware finds the tenant of a request and how many tenants a Pool serves at once.
+type Config struct {
+	Header      string        // the request header naming the tenant
+	Domain      string        // if set, the subdomain of a request host names the tenant, as acme in acme.example.com
+	Required    bool          // whether requests without a tenant are refused with 400 Bad Request
+	Capacity    int           // the tenants a Pool keeps resources open for before closing the least recently used
+	IdleTimeout time.Duration // how long a Pool keeps the resources of a tenant unused; forever if zero
+}
+
+// DefaultConfig returns a Config reading the tenant from the DefaultHeader, with a Pool of DefaultCapacity tenants.
+func DefaultConfig() *Config {
+	return &Config{Header: DefaultHeader, Capacity: DefaultCapacity}
+}
+
+func init() {
+	configschema.Register("tenant", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the tenant variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "HEADER", Type: "string", Default: DefaultHeader,
+			Description: "The request header naming the tenant."},
+		{Name: prefix + "DOMAIN", Type: "string",
+			Description: "The domain whose subdomains name the tenants, such as example.com."},
+		{Name: prefix + "REQUIRED", Type: "bool", Default: "false",
+			Description: "Refuse requests without a tenant with 400 Bad Request."},
+		{Name: prefix + "CAPACITY", Type: "int", Default: strconv.Itoa(DefaultCapacity),
+			Description: "The tenants a Pool keeps resources open for."},
+		{Name: prefix + "IDLE_TIMEOUT", Type: "duration",
+			Description: "How long a Pool keeps the resources of an unused tenant; forever if not set."},
+	}
+}
+
+// FromEnv reads how the tenant of a request is found, HEADER or DOMAIN, whether it is REQUIRED, and how many tenants a
+// Pool keeps resources open for, CAPACITY, and for how long, IDLE_TIMEOUT, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("HEADER"); v != "" {
+		config.Header = v
+	}
+	config.Domain = strings.ToLower(strings.Trim(get("DOMAIN"), "."))
+
+	if v := get("REQUIRED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("tenant: invalid %sREQUIRED %q", prefix, v)
+		}
+		config.Required = b
+	}
+
+	if v := get("CAPACITY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("tenant: invalid %sCAPACITY %q", prefix, v)
+		}
+		config.Capacity = n
+	}
+
+	if v := get("IDLE_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("tenant: invalid %sIDLE_TIMEOUT %q", prefix, v)
+		}
+		config.IdleTimeout = d
+	}
+
+	return config, nil
+}
@@ -0,0 +1,54 @@
+package tenant_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/tenant"
+)
+
+func TestFromEnv_Domain(t *testing.T) {
+	// the domain is matched against hosts, which are case-insensitive and may be written fully qualified
+	config, err := tenant.FromEnv(apptest.Lookup(map[string]string{
+		"ORG_DOMAIN":   " .Example.COM. ",
+		"ORG_HEADER":   "X-Org",
+		"ORG_REQUIRED": "true",
+	}), "ORG_")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", config.Domain)
+	assert.Equal(t, "X-Org", config.Header)
+	assert.True(t, config.Required)
+
+	config, err = tenant.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, tenant.DefaultConfig(), config)
+
+	_, err = tenant.FromEnv(apptest.Lookup(map[string]string{"APP_TENANT_REQUIRED": "maybe"}), "")
+	assert.EqualError(t, err, `tenant: invalid APP_TENANT_REQUIRED "maybe"`)
+}
+
+func TestFromEnv_Pool(t *testing.T) {
+	// zero keeps the resources of a tenant until it is evicted for capacity
+	config, err := tenant.FromEnv(apptest.Lookup(map[string]string{
+		"APP_TENANT_CAPACITY":     "1",
+		"APP_TENANT_IDLE_TIMEOUT": "0s",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.Capacity)
+	assert.Zero(t, config.IdleTimeout)
+
+	config, err = tenant.FromEnv(apptest.Lookup(map[string]string{"APP_TENANT_IDLE_TIMEOUT": "5m"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, config.IdleTimeout)
+
+	for key, values := range map[string][]string{"CAPACITY": {"0", "many"}, "IDLE_TIMEOUT": {"-1s", "300"}} {
+		for _, v := range values {
+			_, err := tenant.FromEnv(apptest.Lookup(map[string]string{"APP_TENANT_" + key: v}), "")
+			assert.EqualError(t, err, "tenant: invalid APP_TENANT_"+key+` "`+v+`"`)
+		}
+	}
+}
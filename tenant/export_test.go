@@ -0,0 +1,8 @@
+package tenant
+
+import "time"
+
+// SetNow replaces the clock of p.
+func SetNow[T any](p *Pool[T], now func() time.Time) {
+	p.now = now
+}
@@ -0,0 +1,249 @@
+package tenant
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Pool keeps a resource of type T for each tenant, such as a *sql.DB connected to the database of the tenant. A
+// resource is opened when a tenant is first served, and dropped when the Pool holds Capacity other tenants served
+// since, or when it has not been used for the IdleTimeout. A dropped resource is closed once the last caller using it
+// has released it.
+//
+// A Pool is an app.Server: registered with App.Register, it closes the resources of every tenant when the app shuts
+// down.
+type Pool[T any] struct {
+	config Config
+	open   func(ctx context.Context, id string) (T, error)
+	close  func(T) error
+	now    func() time.Time
+
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	lru     *list.List // front is the most recently used
+	dropped map[*poolEntry[T]]struct{}
+
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+type poolEntry[T any] struct {
+	id    string
+	used  time.Time
+	ready chan struct{} // closed once open returned
+	value T
+	err   error
+
+	refs     int           // the callers holding the entry, the one opening it included; guarded by Pool.mu
+	dropped  bool          // whether the entry left the Pool; guarded by Pool.mu
+	closed   chan struct{} // closed once the resource is closed, after the entry was dropped and released
+	closeErr error
+}
+
+// NewPool returns a Pool opening the resources of the tenants with open and closing them with close, which may be
+// nil if they need no closing.
+func NewPool[T any](
+	config *Config,
+	open func(ctx context.Context, id string) (T, error),
+	close func(T) error,
+) *Pool[T] {
+	return &Pool[T]{
+		config:   *config,
+		open:     open,
+		close:    close,
+		now:      time.Now,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		dropped:  make(map[*poolEntry[T]]struct{}),
+		stopping: make(chan struct{}),
+	}
+}
+
+// Get returns the resource of the tenant ctx carries, or ErrNoTenant if it carries none, and the function releasing
+// it, as For does.
+func (p *Pool[T]) Get(ctx context.Context) (T, func(), error) {
+	id, ok := FromContext(ctx)
+	if !ok {
+		var zero T
+		return zero, func() {}, ErrNoTenant
+	}
+	return p.For(ctx, id)
+}
+
+// For returns the resource of the tenant id, opening it if needed, and a function to call once done with it; the
+// resource is not closed before. Concurrent calls for a tenant whose resource is being opened wait for it rather than
+// open another. A failure to open is returned to the waiting calls and not kept.
+func (p *Pool[T]) For(ctx context.Context, id string) (T, func(), error) {
+	p.mu.Lock()
+	dropped := p.expire()
+	e, opener := p.entry(id)
+	dropped = append(dropped, p.evict()...)
+	p.mu.Unlock()
+	p.closeAll(dropped)
+
+	var once sync.Once
+	release := func() { once.Do(func() { p.release(e) }) }
+
+	if opener {
+		e.value, e.err = p.open(ctx, id)
+		if e.err != nil {
+			p.mu.Lock()
+			if el, ok := p.items[id]; ok && el.Value == e {
+				p.remove(el)
+			}
+			p.mu.Unlock()
+		}
+		close(e.ready)
+	} else {
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			release()
+			var zero T
+			return zero, func() {}, ctx.Err()
+		}
+	}
+
+	if e.err != nil {
+		release()
+		var zero T
+		return zero, func() {}, e.err
+	}
+	return e.value, release, nil
+}
+
+// Len returns the number of tenants the Pool holds a resource for.
+func (p *Pool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// Close drops the resources of every tenant, closing those not in use; the others are closed as they are released.
+func (p *Pool[T]) Close() error {
+	p.mu.Lock()
+	var dropped []*poolEntry[T]
+	for p.lru.Len() > 0 {
+		dropped = append(dropped, p.remove(p.lru.Back())...)
+	}
+	p.mu.Unlock()
+	return p.closeAll(dropped)
+}
+
+// Bind does nothing; the resources are opened as tenants are served.
+func (p *Pool[T]) Bind(*app.App) error {
+	return nil
+}
+
+// Serve waits until the Pool is shut down.
+func (p *Pool[T]) Serve() error {
+	<-p.stopping
+	return nil
+}
+
+// Shutdown closes the resources of every tenant, waiting until ctx is done for those in use to be released.
+func (p *Pool[T]) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stopping) })
+	errs := []error{p.Close()}
+
+	p.mu.Lock()
+	pending := make([]*poolEntry[T], 0, len(p.dropped))
+	for e := range p.dropped {
+		pending = append(pending, e)
+	}
+	p.mu.Unlock()
+	for _, e := range pending {
+		select {
+		case <-e.closed:
+			errs = append(errs, e.closeErr)
+		case <-ctx.Done():
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// entry returns the entry of id, held for the caller, creating it if needed, and whether it was created. p.mu must be
+// held.
+func (p *Pool[T]) entry(id string) (*poolEntry[T], bool) {
+	now := p.now()
+	if el, ok := p.items[id]; ok {
+		e := el.Value.(*poolEntry[T])
+		e.used = now
+		e.refs++
+		p.lru.MoveToFront(el)
+		return e, false
+	}
+
+	e := &poolEntry[T]{id: id, used: now, ready: make(chan struct{}), refs: 1, closed: make(chan struct{})}
+	p.items[id] = p.lru.PushFront(e)
+	return e, true
+}
+
+// expire drops the entries unused for the IdleTimeout, returning those to close. p.mu must be held.
+func (p *Pool[T]) expire() []*poolEntry[T] {
+	if p.config.IdleTimeout <= 0 {
+		return nil
+	}
+	var dropped []*poolEntry[T]
+	deadline := p.now().Add(-p.config.IdleTimeout)
+	for el := p.lru.Back(); el != nil && el.Value.(*poolEntry[T]).used.Before(deadline); el = p.lru.Back() {
+		dropped = append(dropped, p.remove(el)...)
+	}
+	return dropped
+}
+
+// evict drops the least recently used entries over the Capacity, returning those to close. p.mu must be held.
+func (p *Pool[T]) evict() []*poolEntry[T] {
+	var dropped []*poolEntry[T]
+	for p.config.Capacity > 0 && p.lru.Len() > p.config.Capacity {
+		dropped = append(dropped, p.remove(p.lru.Back())...)
+	}
+	return dropped
+}
+
+// remove drops the entry of el from the Pool, returning it if nobody holds it and it is to be closed now. p.mu must be
+// held.
+func (p *Pool[T]) remove(el *list.Element) []*poolEntry[T] {
+	e := el.Value.(*poolEntry[T])
+	p.lru.Remove(el)
+	delete(p.items, e.id)
+	e.dropped = true
+	p.dropped[e] = struct{}{}
+	if e.refs > 0 {
+		return nil
+	}
+	return []*poolEntry[T]{e}
+}
+
+// release lets go of an entry held by a caller, closing it if it was dropped and nobody else holds it.
+func (p *Pool[T]) release(e *poolEntry[T]) {
+	p.mu.Lock()
+	e.refs--
+	last := e.dropped && e.refs == 0
+	p.mu.Unlock()
+	if last {
+		_ = p.closeAll([]*poolEntry[T]{e})
+	}
+}
+
+// closeAll closes the resources of dropped entries nobody holds, which are all open, without p.mu held.
+func (p *Pool[T]) closeAll(entries []*poolEntry[T]) error {
+	var errs []error
+	for _, e := range entries {
+		if e.err == nil && p.close != nil {
+			e.closeErr = p.close(e.value)
+			errs = append(errs, e.closeErr)
+		}
+		close(e.closed)
+		p.mu.Lock()
+		delete(p.dropped, e)
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,197 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/tenant"
+)
+
+type conn struct {
+	id     string
+	closed bool
+}
+
+// pool returns a Pool of conns and the conns it opened, in order.
+func pool(config *tenant.Config) (*tenant.Pool[*conn], *[]*conn) {
+	var mu sync.Mutex
+	opened := &[]*conn{}
+	p := tenant.NewPool(config, func(_ context.Context, id string) (*conn, error) {
+		if id == "broken" {
+			return nil, errors.New("unreachable")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		c := &conn{id: id}
+		*opened = append(*opened, c)
+		return c, nil
+	}, func(c *conn) error {
+		c.closed = true
+		return nil
+	})
+	return p, opened
+}
+
+func TestPool(t *testing.T) {
+	p, opened := pool(&tenant.Config{Capacity: 2})
+
+	_, release, err := p.Get(context.Background())
+	assert.Equal(t, tenant.ErrNoTenant, err)
+	release()
+
+	acme, release, err := p.Get(tenant.NewContext(context.Background(), "acme"))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", acme.id)
+	release()
+
+	again, release, err := p.For(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Same(t, acme, again)
+	release()
+
+	_, release, err = p.For(context.Background(), "broken")
+	assert.EqualError(t, err, "unreachable")
+	release()
+	assert.Equal(t, 1, p.Len())
+
+	// globex and initech push acme, the least recently used, out
+	globex, release, err := p.For(context.Background(), "globex")
+	require.NoError(t, err)
+	release()
+	_, release, err = p.For(context.Background(), "globex")
+	require.NoError(t, err)
+	release()
+	_, release, err = p.For(context.Background(), "initech")
+	require.NoError(t, err)
+	release()
+	assert.True(t, acme.closed)
+	assert.False(t, globex.closed)
+	assert.Equal(t, 2, p.Len())
+
+	reopened, release, err := p.For(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.NotSame(t, acme, reopened)
+	assert.Len(t, *opened, 4)
+	release()
+
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.Equal(t, 0, p.Len())
+	for _, c := range *opened {
+		assert.True(t, c.closed, c.id)
+	}
+}
+
+func TestPool_InUse(t *testing.T) {
+	p, _ := pool(&tenant.Config{Capacity: 1})
+
+	acme, releaseAcme, err := p.For(context.Background(), "acme")
+	require.NoError(t, err)
+
+	// globex pushes acme out, which is closed only once released
+	_, release, err := p.For(context.Background(), "globex")
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Len())
+	assert.False(t, acme.closed)
+	releaseAcme()
+	assert.True(t, acme.closed)
+	releaseAcme() // a second release does nothing
+
+	// Shutdown waits for the resources in use
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	_, release, err = p.For(context.Background(), "initech")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, p.Shutdown(ctx), context.Canceled)
+	release()
+}
+
+func TestPool_IdleTimeout(t *testing.T) {
+	p, _ := pool(&tenant.Config{Capacity: 10, IdleTimeout: time.Minute})
+	now := time.Now()
+	tenant.SetNow(p, func() time.Time { return now })
+
+	acme, release, err := p.For(context.Background(), "acme")
+	require.NoError(t, err)
+	release()
+	now = now.Add(30 * time.Second)
+	globex, release, err := p.For(context.Background(), "globex")
+	require.NoError(t, err)
+	release()
+
+	now = now.Add(45 * time.Second)
+	_, release, err = p.For(context.Background(), "globex")
+	require.NoError(t, err)
+	release()
+	assert.True(t, acme.closed)
+	assert.False(t, globex.closed)
+	assert.Equal(t, 1, p.Len())
+}
+
+func TestPool_OpensOnce(t *testing.T) {
+	var opens atomic.Int32
+	opening := make(chan struct{})
+	p := tenant.NewPool(tenant.DefaultConfig(), func(_ context.Context, id string) (string, error) {
+		opens.Add(1)
+		<-opening
+		return id, nil
+	}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, release, err := p.For(context.Background(), "acme")
+			assert.NoError(t, err)
+			assert.Equal(t, "acme", id)
+			release()
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(opening)
+	wg.Wait()
+	assert.Equal(t, int32(1), opens.Load())
+}
+
+func TestPool_EvictWhileOpening(t *testing.T) {
+	opening := make(chan struct{})
+	slow := tenant.NewPool(&tenant.Config{Capacity: 1}, func(_ context.Context, id string) (*conn, error) {
+		if id == "slow" {
+			<-opening
+		}
+		return &conn{id: id}, nil
+	}, func(c *conn) error {
+		c.closed = true
+		return nil
+	})
+
+	done := make(chan *conn)
+	go func() {
+		c, release, err := slow.For(context.Background(), "slow")
+		assert.NoError(t, err)
+		release()
+		done <- c
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// pushing the tenant being opened out does not wait for it
+	_, release, err := slow.For(context.Background(), "acme")
+	require.NoError(t, err)
+	release()
+
+	close(opening)
+	assert.True(t, (<-done).closed)
+}
@@ -0,0 +1,137 @@
+// Package tenant carries the tenant a request or job acts for through its context, for multi-tenant services:
+//
+//	mw := tenant.NewMiddleware(config)
+//	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		ctx := r.Context()
+//		logger := tenant.Logger(ctx, a.Logger()) // logs with the "tenant" attribute
+//		a.Metrics().Counter("orders_total", tenant.Labels(ctx, nil)).Inc()
+//		db, release, err := pools.Get(ctx) // the database of the tenant
+//		if err != nil {
+//			...
+//		}
+//		defer release()
+//		...
+//	}))
+//
+// The tenant is not added to logs and metrics by itself: the logger returned by Logger and the labels returned by
+// Labels carry it, as Attrs does for any other sink of attributes.
+//
+// A Pool keeps a resource, such as a database connection pool, for each tenant, closing those of the tenants that
+// have not been served lately.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+// ErrNoTenant is returned when a context carries no tenant.
+var ErrNoTenant = errors.New("tenant: no tenant in context")
+
+// idPattern is what a tenant ID looks like: safe in log attributes, metric labels, host names, and file names.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the tenant id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx carries and whether it carries one.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Valid reports whether id is a valid tenant ID: up to 63 letters, digits, dots, dashes, and underscores, starting
+// with a letter or digit.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Logger returns a logger adding the tenant ctx carries, if any, to the attributes of every message as "tenant".
+func Logger(ctx context.Context, logger gomol.WrappableLogger) *gomol.LogAdapter {
+	return gomol.NewLogAdapterFor(logger, gomol.NewAttrsFromMap(Attrs(ctx)))
+}
+
+// Labels returns a copy of labels with the tenant ctx carries, if any, as the tenant label.
+func Labels(ctx context.Context, labels metrics.Labels) metrics.Labels {
+	copied := make(metrics.Labels, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	if id, ok := FromContext(ctx); ok {
+		copied["tenant"] = id
+	}
+	return copied
+}
+
+// Attrs returns the tenant ctx carries, if any, as attributes.
+func Attrs(ctx context.Context) map[string]interface{} {
+	attrs := make(map[string]interface{}, 1)
+	if id, ok := FromContext(ctx); ok {
+		attrs["tenant"] = id
+	}
+	return attrs
+}
+
+// Middleware is HTTP middleware putting the tenant of each request in its context. The tenant is named by the
+// Header of the request, or else by the subdomain of the Domain the request is for. A request naming an invalid
+// tenant, or none when the tenant is Required, is answered with 400 Bad Request.
+type Middleware struct {
+	config Config
+}
+
+// NewMiddleware returns Middleware finding the tenant as config describes.
+func NewMiddleware(config *Config) *Middleware {
+	return &Middleware{config: *config}
+}
+
+// Wrap returns a handler calling next with the tenant of the request in its context.
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := m.tenant(r)
+		switch {
+		case id == "" && m.config.Required:
+			http.Error(w, "missing tenant", http.StatusBadRequest)
+		case id == "":
+			next.ServeHTTP(w, r)
+		case !Valid(id):
+			http.Error(w, fmt.Sprintf("invalid tenant %q", id), http.StatusBadRequest)
+		default:
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		}
+	})
+}
+
+// tenant returns the tenant r names, empty if it names none.
+func (m *Middleware) tenant(r *http.Request) string {
+	if m.config.Header != "" {
+		if id := strings.TrimSpace(r.Header.Get(m.config.Header)); id != "" {
+			return id
+		}
+	}
+	if m.config.Domain == "" {
+		return ""
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if sub := strings.TrimSuffix(host, "."+m.config.Domain); sub != host && !strings.Contains(sub, ".") {
+		return sub
+	}
+	return ""
+}
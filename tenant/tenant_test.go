@@ -0,0 +1,98 @@
+package tenant_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/tenant"
+)
+
+func TestContext(t *testing.T) {
+	_, ok := tenant.FromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := tenant.NewContext(context.Background(), "acme")
+	id, ok := tenant.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", id)
+
+	assert.Equal(t, map[string]interface{}{"tenant": "acme"}, tenant.Attrs(ctx))
+	assert.Empty(t, tenant.Attrs(context.Background()))
+}
+
+func TestValid(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"acme":          true,
+		"Acme-Corp_2.0": true,
+		"":              false,
+		"-acme":         false,
+		"acme corp":     false,
+		"acme/../etc":   false,
+	} {
+		assert.Equal(t, valid, tenant.Valid(id), id)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	labels := metrics.Labels{"route": "/orders"}
+	ctx := tenant.NewContext(context.Background(), "acme")
+	assert.Equal(t, metrics.Labels{"route": "/orders", "tenant": "acme"}, tenant.Labels(ctx, labels))
+	assert.Equal(t, metrics.Labels{"route": "/orders"}, labels)
+	assert.Equal(t, metrics.Labels{"route": "/orders"}, tenant.Labels(context.Background(), labels))
+}
+
+func TestLogger(t *testing.T) {
+	a := apptest.New(t, nil)
+	ctx := tenant.NewContext(context.Background(), "acme")
+	require.NoError(t, tenant.Logger(ctx, a.Logger()).Info("order placed"))
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	stderr := apptest.Stderr(t, a)
+	assert.Contains(t, stderr, "order placed")
+	assert.Contains(t, stderr, "acme")
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := func(config *tenant.Config) http.Handler {
+		return tenant.NewMiddleware(config).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := tenant.FromContext(r.Context())
+			_, _ = w.Write([]byte(id))
+		}))
+	}
+	config := tenant.DefaultConfig()
+	config.Domain = "example.com"
+
+	for name, tt := range map[string]struct {
+		config *tenant.Config
+		host   string
+		header string
+		code   int
+		body   string
+	}{
+		"header":            {config, "example.com", "acme", http.StatusOK, "acme"},
+		"header over host":  {config, "globex.example.com", "acme", http.StatusOK, "acme"},
+		"subdomain":         {config, "Acme.Example.com:8443", "", http.StatusOK, "acme"},
+		"nested subdomain":  {config, "a.acme.example.com", "", http.StatusOK, ""},
+		"other domain":      {config, "acme.example.org", "", http.StatusOK, ""},
+		"none":              {tenant.DefaultConfig(), "acme.example.com", "", http.StatusOK, ""},
+		"invalid":           {config, "example.com", "../etc", http.StatusBadRequest, "invalid tenant \"../etc\"\n"},
+		"required":          {&tenant.Config{Header: tenant.DefaultHeader, Required: true}, "", "", 400, "missing tenant\n"},
+		"required and sent": {&tenant.Config{Header: tenant.DefaultHeader, Required: true}, "", "acme", 200, "acme"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tt.host
+		if tt.header != "" {
+			r.Header.Set(tenant.DefaultHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler(tt.config).ServeHTTP(w, r)
+		assert.Equal(t, tt.code, w.Code, name)
+		assert.Equal(t, tt.body, w.Body.String(), name)
+	}
+}
@@ -0,0 +1,52 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aphistic/gomol"
+)
+
+// ClientTLSConfig returns a client *tls.Config that always presents the current certificate and, if a CAFile is
+// configured, verifies servers against the CA bundle loaded at the time of the call.
+func (l *Loader) ClientTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: l.config.MinVersion,
+		RootCAs:    l.CertPool(),
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return l.Certificate(), nil
+		},
+	}
+}
+
+// ClientConfig returns a client *tls.Config for config. When config names a certificate, it is loaded with a Loader
+// and presented to servers; otherwise no client certificate is sent. Servers are verified against the CAFile bundle,
+// or the system roots if there is none.
+func ClientConfig(config *Config, logger gomol.WrappableLogger) (*tls.Config, error) {
+	if config.CertFile != "" || config.KeyFile != "" {
+		l, err := NewLoader(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		return l.ClientTLSConfig(), nil
+	}
+
+	c := &tls.Config{MinVersion: config.MinVersion}
+
+	if config.CAFile != "" {
+		data, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tlsconfig: reading CA file: %v", err)
+		}
+
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(data) {
+			return nil, errors.New("tlsconfig: no certificates found in CA file")
+		}
+	}
+
+	return c, nil
+}
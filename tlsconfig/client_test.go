@@ -0,0 +1,65 @@
+package tlsconfig_test
+
+import (
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+)
+
+func TestClientConfig(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	writeCert(t, dir, "server", time.Now().Add(365*24*time.Hour))
+
+	config := &tlsconfig.Config{
+		CertFile:   filepath.Join(dir, "cert.pem"),
+		KeyFile:    filepath.Join(dir, "key.pem"),
+		CAFile:     filepath.Join(dir, "cert.pem"),
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+
+	l, err := tlsconfig.NewLoader(config, nil)
+	require.NoError(t, err)
+
+	clientConfig, err := tlsconfig.ClientConfig(config, nil)
+	require.NoError(t, err)
+	clientConfig.ServerName = "server"
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, l.TLSConfig())
+	client := tls.Client(clientConn, clientConfig)
+
+	errch := make(chan error, 1)
+	go func() { errch <- server.Handshake() }()
+
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-errch)
+	assert.Equal(t, "server", server.ConnectionState().PeerCertificates[0].Subject.CommonName)
+
+	clientConfig, err = tlsconfig.ClientConfig(&tlsconfig.Config{CAFile: config.CAFile}, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, clientConfig.RootCAs)
+	assert.Nil(t, clientConfig.GetClientCertificate)
+
+	clientConfig, err = tlsconfig.ClientConfig(&tlsconfig.Config{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, clientConfig.RootCAs)
+
+	_, err = tlsconfig.ClientConfig(&tlsconfig.Config{CAFile: config.KeyFile}, nil)
+	assert.EqualError(t, err, "tlsconfig: no certificates found in CA file")
+
+	_, err = tlsconfig.ClientConfig(&tlsconfig.Config{CertFile: config.CertFile}, nil)
+	assert.Equal(t, tlsconfig.ErrNoCertificate, err)
+}
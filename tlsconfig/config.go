@@ -0,0 +1,132 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the certificate files and TLS versions, as in APP_TLS_CERT_FILE.
+	DefaultPrefix = "APP_TLS_"
+
+	// DefaultReloadInterval is how often a Loader checks the certificate files for changes.
+	DefaultReloadInterval = time.Minute
+
+	// DefaultExpiryWarning is how far ahead of expiry a Loader starts logging warnings.
+	DefaultExpiryWarning = 30 * 24 * time.Hour
+)
+
+// ErrNoCertificate is returned when a Config does not name both a certificate and a key file.
+var ErrNoCertificate = errors.New("tlsconfig: certificate and key files are required")
+
+// Config describes the certificate files and handshake policy used to build a *tls.Config.
+type Config struct {
+	CertFile       string             // PEM encoded certificate chain
+	KeyFile        string             // PEM encoded private key
+	CAFile         string             // PEM encoded CA bundle used to verify peer certificates
+	MinVersion     uint16             // minimum TLS version, e.g. tls.VersionTLS12
+	ClientAuth     tls.ClientAuthType // client certificate policy for servers
+	ReloadInterval time.Duration      // how often the files are checked for changes
+	ExpiryWarning  time.Duration      // how far ahead of expiry to start logging warnings
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify":             tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+func init() {
+	configschema.Register("tlsconfig", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the TLS variables with the prefix, such as for App.DeclareConfig when the certificates of a
+// listener are read with a prefix of their own.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "CERT_FILE", Type: "path", Description: "The PEM encoded certificate chain."},
+		{Name: prefix + "KEY_FILE", Type: "path", Description: "The PEM encoded private key of the certificate."},
+		{Name: prefix + "CA_FILE", Type: "path",
+			Description: "The PEM encoded CAs that verify the certificates of peers."},
+		{Name: prefix + "MIN_VERSION", Type: "string", Default: "1.2", Description: "The lowest TLS version accepted."},
+		{Name: prefix + "CLIENT_AUTH", Type: "string", Default: "none", Description: "The client certificate policy: " +
+			"none, request, require, verify, or require-and-verify."},
+		{Name: prefix + "RELOAD_INTERVAL", Type: "duration", Default: DefaultReloadInterval.String(),
+			Description: "How often the certificate files are checked for changes."},
+		{Name: prefix + "EXPIRY_WARNING", Type: "duration", Default: DefaultExpiryWarning.String(),
+			Description: "How long before the certificate expires warnings are logged."},
+	}
+}
+
+// FromEnv reads the certificate, CERT_FILE and KEY_FILE, set together, the CA_FILE verifying peers, the MIN_VERSION and
+// CLIENT_AUTH policy of the handshake, and how the files are watched, RELOAD_INTERVAL and EXPIRY_WARNING, with the
+// prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := Config{
+		CertFile:       get("CERT_FILE"),
+		KeyFile:        get("KEY_FILE"),
+		CAFile:         get("CA_FILE"),
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     tls.NoClientCert,
+		ReloadInterval: DefaultReloadInterval,
+		ExpiryWarning:  DefaultExpiryWarning,
+	}
+
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, fmt.Errorf("tlsconfig: %sCERT_FILE and %sKEY_FILE must be set together", prefix, prefix)
+	}
+
+	if v := get("MIN_VERSION"); v != "" {
+		version, ok := versions[v]
+		if !ok {
+			return nil, fmt.Errorf("tlsconfig: invalid %sMIN_VERSION %q", prefix, v)
+		}
+		config.MinVersion = version
+	}
+
+	if v := get("CLIENT_AUTH"); v != "" {
+		auth, ok := clientAuthTypes[strings.ToLower(v)]
+		if !ok {
+			return nil, fmt.Errorf("tlsconfig: invalid %sCLIENT_AUTH %q", prefix, v)
+		}
+		config.ClientAuth = auth
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"RELOAD_INTERVAL": &config.ReloadInterval,
+		"EXPIRY_WARNING":  &config.ExpiryWarning,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("tlsconfig: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,78 @@
+package tlsconfig_test
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+)
+
+func TestFromEnv_Certificate(t *testing.T) {
+	config, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{
+		"TEST_CERT_FILE": " /etc/tls/cert.pem ",
+		"TEST_KEY_FILE":  "/etc/tls/key.pem",
+		"TEST_CA_FILE":   "/etc/tls/ca.pem",
+	}), "TEST_")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/tls/cert.pem", config.CertFile)
+	assert.Equal(t, "/etc/tls/key.pem", config.KeyFile)
+	assert.Equal(t, "/etc/tls/ca.pem", config.CAFile)
+
+	// a CA alone verifies peers without presenting a certificate, as a client does
+	_, err = tlsconfig.FromEnv(apptest.Lookup(map[string]string{"APP_TLS_CA_FILE": "ca.pem"}), "")
+	assert.NoError(t, err)
+
+	for _, key := range []string{"CERT_FILE", "KEY_FILE"} {
+		_, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{"APP_TLS_" + key: "file.pem"}), "")
+		assert.EqualError(t, err, "tlsconfig: APP_TLS_CERT_FILE and APP_TLS_KEY_FILE must be set together", key)
+	}
+}
+
+func TestFromEnv_Handshake(t *testing.T) {
+	config, err := tlsconfig.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+
+	for v, expected := range map[string]tls.ClientAuthType{
+		"none":               tls.NoClientCert,
+		"Request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"VERIFY":             tls.VerifyClientCertIfGiven,
+		"Require-And-Verify": tls.RequireAndVerifyClientCert,
+	} {
+		config, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{"APP_TLS_CLIENT_AUTH": v}), "")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.ClientAuth, v)
+	}
+
+	// the versions are written as numbers only, without a TLS prefix
+	for key, values := range map[string][]string{"MIN_VERSION": {"1.4", "TLS1.3", "1"}, "CLIENT_AUTH": {"always"}} {
+		for _, v := range values {
+			_, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{"APP_TLS_" + key: v}), "")
+			assert.EqualError(t, err, "tlsconfig: invalid APP_TLS_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_Watch(t *testing.T) {
+	config, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{
+		"APP_TLS_RELOAD_INTERVAL": "5s",
+		"APP_TLS_EXPIRY_WARNING":  "72h",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.ReloadInterval)
+	assert.Equal(t, 72*time.Hour, config.ExpiryWarning)
+
+	for _, key := range []string{"RELOAD_INTERVAL", "EXPIRY_WARNING"} {
+		for _, v := range []string{"0s", "-1h", "soon"} {
+			_, err := tlsconfig.FromEnv(apptest.Lookup(map[string]string{"APP_TLS_" + key: v}), "")
+			assert.EqualError(t, err, "tlsconfig: invalid APP_TLS_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+)
+
+// Loader holds the certificate described by a Config and reloads it when the files change on disk. The *tls.Config
+// values returned by a Loader resolve the current certificate on every handshake, so listeners never need restarting.
+type Loader struct {
+	config Config
+	logger gomol.WrappableLogger
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	pool   *x509.CertPool
+	stamp  string
+	warned bool
+}
+
+// NewLoader returns a Loader with the certificate described by config already loaded. The logger receives reload and
+// expiry messages and may be nil.
+func NewLoader(config *Config, logger gomol.WrappableLogger) (*Loader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, ErrNoCertificate
+	}
+
+	l := &Loader{
+		config: *config,
+		logger: logger,
+	}
+
+	if l.config.ReloadInterval <= 0 {
+		l.config.ReloadInterval = DefaultReloadInterval
+	}
+
+	if l.config.ExpiryWarning <= 0 {
+		l.config.ExpiryWarning = DefaultExpiryWarning
+	}
+
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Certificate returns the currently loaded certificate.
+func (l *Loader) Certificate() *tls.Certificate {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.cert
+}
+
+// CertPool returns the currently loaded CA bundle, or nil if the Config has no CAFile.
+func (l *Loader) CertPool() *x509.CertPool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pool
+}
+
+// TLSConfig returns a server *tls.Config that always presents the current certificate and, if a CAFile is configured,
+// verifies client certificates against the current CA bundle.
+func (l *Loader) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: l.config.MinVersion,
+		ClientAuth: l.config.ClientAuth,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return l.Certificate(), nil
+		},
+	}
+
+	if l.config.CAFile != "" {
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := config.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = l.CertPool()
+			return c, nil
+		}
+	}
+
+	return config
+}
+
+// Reload reads the certificate, key, and CA files unconditionally. The previous certificate is kept if any file fails
+// to load.
+func (l *Loader) Reload() error {
+	stamp, err := l.fileStamp()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.config.CertFile, l.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tlsconfig: loading key pair: %v", err)
+	}
+
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("tlsconfig: parsing certificate: %v", err)
+	}
+
+	var pool *x509.CertPool
+	if l.config.CAFile != "" {
+		data, err := os.ReadFile(l.config.CAFile)
+		if err != nil {
+			return fmt.Errorf("tlsconfig: reading CA file: %v", err)
+		}
+
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return errors.New("tlsconfig: no certificates found in CA file")
+		}
+	}
+
+	l.mu.Lock()
+	l.cert = &cert
+	l.pool = pool
+	l.stamp = stamp
+	l.warned = false
+	l.mu.Unlock()
+
+	l.checkExpiry(time.Now())
+	return nil
+}
+
+// Watch checks the files every ReloadInterval, reloading them when they change and logging a warning when the
+// certificate is within ExpiryWarning of expiring. Watch blocks until the context is done.
+func (l *Loader) Watch(ctx context.Context) {
+	ticker := time.NewTicker(l.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			l.poll(now)
+		}
+	}
+}
+
+func (l *Loader) poll(now time.Time) {
+	stamp, err := l.fileStamp()
+	if err != nil {
+		l.log(gomol.LevelError, nil, "unable to check certificate files: %v", err)
+		return
+	}
+
+	l.mu.RLock()
+	changed := stamp != l.stamp
+	l.mu.RUnlock()
+
+	if !changed {
+		l.checkExpiry(now)
+		return
+	}
+
+	if err := l.Reload(); err != nil {
+		l.log(gomol.LevelError, nil, "unable to reload certificate: %v", err)
+		return
+	}
+
+	l.log(gomol.LevelInfo, gomol.NewAttrsFromMap(map[string]interface{}{
+		"cert_file": l.config.CertFile,
+	}), "reloaded certificate")
+}
+
+func (l *Loader) checkExpiry(now time.Time) {
+	l.mu.Lock()
+	leaf := l.cert.Leaf
+	remaining := leaf.NotAfter.Sub(now)
+	if l.warned || remaining > l.config.ExpiryWarning {
+		l.mu.Unlock()
+		return
+	}
+	l.warned = true
+	l.mu.Unlock()
+
+	attrs := gomol.NewAttrsFromMap(map[string]interface{}{
+		"cert_file": l.config.CertFile,
+		"subject":   leaf.Subject.String(),
+		"not_after": leaf.NotAfter.UTC().Format(time.RFC3339),
+	})
+
+	if remaining <= 0 {
+		l.log(gomol.LevelError, attrs, "certificate has expired")
+	} else {
+		l.log(gomol.LevelWarning, attrs, "certificate expires in %s", remaining.Round(time.Minute))
+	}
+}
+
+func (l *Loader) fileStamp() (string, error) {
+	stamp := ""
+	for _, name := range []string{l.config.CertFile, l.config.KeyFile, l.config.CAFile} {
+		if name == "" {
+			continue
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			return "", fmt.Errorf("tlsconfig: %v", err)
+		}
+
+		stamp += fmt.Sprintf("%s:%d:%d;", name, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return stamp, nil
+}
+
+func (l *Loader) log(level gomol.LogLevel, attrs *gomol.Attrs, msg string, a ...interface{}) {
+	if l.logger != nil {
+		_ = l.logger.Log(level, attrs, msg, a...)
+	}
+}
@@ -0,0 +1,165 @@
+package tlsconfig_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/tlsconfig"
+)
+
+func writeCert(t *testing.T, dir, name string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	require.NoError(t, err)
+	return dir, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestNewLoader(t *testing.T) {
+	l, err := tlsconfig.NewLoader(&tlsconfig.Config{}, nil)
+	assert.Nil(t, l)
+	assert.Equal(t, tlsconfig.ErrNoCertificate, err)
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	l, err = tlsconfig.NewLoader(&tlsconfig.Config{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}, nil)
+	assert.Nil(t, l)
+	assert.Error(t, err)
+
+	writeCert(t, dir, "first", time.Now().Add(365*24*time.Hour))
+
+	l, err = tlsconfig.NewLoader(&tlsconfig.Config{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		CAFile:   filepath.Join(dir, "cert.pem"),
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", l.Certificate().Leaf.Subject.CommonName)
+	assert.NotNil(t, l.CertPool())
+}
+
+func TestLoader_TLSConfig(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	writeCert(t, dir, "server", time.Now().Add(365*24*time.Hour))
+
+	l, err := tlsconfig.NewLoader(&tlsconfig.Config{
+		CertFile:   filepath.Join(dir, "cert.pem"),
+		KeyFile:    filepath.Join(dir, "key.pem"),
+		CAFile:     filepath.Join(dir, "cert.pem"),
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}, nil)
+	require.NoError(t, err)
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, l.TLSConfig())
+	client := tls.Client(clientConn, &tls.Config{
+		RootCAs:      l.CertPool(),
+		ServerName:   "server",
+		Certificates: []tls.Certificate{*l.Certificate()},
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	errch := make(chan error, 1)
+	go func() { errch <- server.Handshake() }()
+
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-errch)
+	assert.Equal(t, "server", client.ConnectionState().PeerCertificates[0].Subject.CommonName)
+	assert.Equal(t, "server", server.ConnectionState().PeerCertificates[0].Subject.CommonName)
+}
+
+func TestLoader_Watch(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	writeCert(t, dir, "first", time.Now().Add(time.Hour))
+
+	a := app.App{Stderr: new(bytes.Buffer)}
+	l, err := tlsconfig.NewLoader(&tlsconfig.Config{
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+		ReloadInterval: 10 * time.Millisecond,
+	}, a.Logger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Watch(ctx)
+	}()
+
+	writeCert(t, dir, "second", time.Now().Add(365*24*time.Hour))
+
+	// make sure the change is visible even on file systems with coarse timestamps
+	mtime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "cert.pem"), mtime, mtime))
+
+	deadline := time.Now().Add(time.Second)
+	for l.Certificate().Leaf.Subject.CommonName != "second" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "second", l.Certificate().Leaf.Subject.CommonName)
+
+	cancel()
+	<-done
+
+	require.NoError(t, a.Logger().ShutdownLoggers())
+	output := a.Stderr.(*bytes.Buffer).String()
+	assert.Contains(t, output, "certificate expires in 1h0m0s")
+	assert.Contains(t, output, "reloaded certificate")
+}
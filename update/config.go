@@ -0,0 +1,112 @@
+package update
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the release check settings, as in APP_UPDATE_CHECK.
+	DefaultPrefix = "APP_UPDATE_"
+
+	// DefaultInterval is how often the release endpoint is checked.
+	DefaultInterval = 24 * time.Hour
+
+	// DefaultTimeout bounds the request to the release endpoint.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Config describes where and how often to check for a new release. Nothing is checked unless Enabled is set and a
+// URL is configured.
+type Config struct {
+	URL      string        // release endpoint
+	Enabled  bool          // false when the user has opted out or the environment is offline
+	Interval time.Duration // minimum time between two checks, tracked in the app State
+	Timeout  time.Duration // bound on each check
+}
+
+// DefaultConfig returns an enabled Config checking at most once every DefaultInterval. It has no URL, so it checks
+// nothing.
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:  true,
+		Interval: DefaultInterval,
+		Timeout:  DefaultTimeout,
+	}
+}
+
+func init() {
+	configschema.Register("update", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the update check variables with the prefix, and APP_OFFLINE.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "URL", Type: "url", Description: "The release endpoint."},
+		{Name: prefix + "CHECK", Type: "bool", Default: "true", Description: "Check for updates."},
+		{Name: prefix + "INTERVAL", Type: "duration", Default: DefaultInterval.String(),
+			Description: "The least time between two checks."},
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long a check may take."},
+		{Name: "APP_OFFLINE", Type: "bool", Default: "false",
+			Description: "Never reach out to the network, such as for updates."},
+	}
+}
+
+// FromEnv reads the release URL and the CHECK, INTERVAL, and TIMEOUT of the update checks, with the prefix or
+// DefaultPrefix. CHECK=false opts out, as does APP_OFFLINE=true for offline and air-gapped environments.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	if v := get("URL"); v != "" {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("update: invalid %sURL %q", prefix, v)
+		}
+		config.URL = v
+	}
+
+	if v := get("CHECK"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("update: invalid %sCHECK %q", prefix, v)
+		}
+		config.Enabled = b
+	}
+
+	for key, dst := range map[string]*time.Duration{"INTERVAL": &config.Interval, "TIMEOUT": &config.Timeout} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("update: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v, _ := lookup("APP_OFFLINE"); v != "" {
+		offline, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("update: invalid APP_OFFLINE %q", v)
+		}
+		if offline {
+			config.Enabled = false
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,65 @@
+package update_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/update"
+)
+
+func TestFromEnv_URL(t *testing.T) {
+	config, err := update.FromEnv(apptest.Lookup(map[string]string{
+		"TOOL_UPDATE_URL": " https://api.github.com/repos/acme/tool/releases/latest ",
+	}), "TOOL_UPDATE_")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.github.com/repos/acme/tool/releases/latest", config.URL)
+
+	for _, v := range []string{"releases.json", "file:///releases.json", "https://"} {
+		_, err := update.FromEnv(apptest.Lookup(map[string]string{"APP_UPDATE_URL": v}), "")
+		assert.EqualError(t, err, `update: invalid APP_UPDATE_URL "`+v+`"`, v)
+	}
+}
+
+func TestFromEnv_OptOut(t *testing.T) {
+	for name, tt := range map[string]struct {
+		env     map[string]string
+		enabled bool
+	}{
+		"default":          {nil, true},
+		"check off":        {map[string]string{"APP_UPDATE_CHECK": "false"}, false},
+		"offline":          {map[string]string{"APP_UPDATE_CHECK": "true", "APP_OFFLINE": " 1 "}, false},
+		"online":           {map[string]string{"APP_OFFLINE": "false"}, true},
+		"prefixed offline": {map[string]string{"APP_UPDATE_OFFLINE": "true"}, true},
+	} {
+		config, err := update.FromEnv(apptest.Lookup(tt.env), "")
+		require.NoError(t, err, name)
+		assert.Equal(t, tt.enabled, config.Enabled, name)
+	}
+
+	// APP_OFFLINE is shared by the whole app, so it is named without the prefix
+	for key, v := range map[string]string{"APP_UPDATE_CHECK": "daily", "APP_OFFLINE": "maybe"} {
+		_, err := update.FromEnv(apptest.Lookup(map[string]string{key: v}), "")
+		assert.EqualError(t, err, "update: invalid "+key+` "`+v+`"`)
+	}
+}
+
+func TestFromEnv_Durations(t *testing.T) {
+	config, err := update.FromEnv(apptest.Lookup(map[string]string{
+		"APP_UPDATE_INTERVAL": "168h",
+		"APP_UPDATE_TIMEOUT":  "2s",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 168*time.Hour, config.Interval)
+	assert.Equal(t, 2*time.Second, config.Timeout)
+
+	for key, values := range map[string][]string{"INTERVAL": {"0s", "-1h"}, "TIMEOUT": {"fast", "2"}} {
+		for _, v := range values {
+			_, err := update.FromEnv(apptest.Lookup(map[string]string{"APP_UPDATE_" + key: v}), "")
+			assert.EqualError(t, err, "update: invalid APP_UPDATE_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package update tells users when a newer release of the app is available. Start checks the release endpoint in the
+// background at most once per Interval, remembering the result in the app State, and prints a short notice on
+// ErrOutput when the app exits. A failed check is silent and never delays the app.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+const (
+	checkedKey = "update.checked"
+	latestKey  = "update.latest"
+)
+
+// Release describes the latest release published at the endpoint.
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url,omitempty"`
+}
+
+// Fetch requests the latest release from the endpoint, which returns a JSON object with "version" and "url" fields,
+// or a GitHub release object with "tag_name" and "html_url" fields.
+func Fetch(ctx context.Context, client *http.Client, endpoint string) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("update: %s", res.Status)
+	}
+
+	var body struct {
+		Version string `json:"version"`
+		URL     string `json:"url"`
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("update: invalid response: %v", err)
+	}
+
+	r := &Release{Version: body.Version, URL: body.URL}
+	if r.Version == "" {
+		r.Version, r.URL = body.TagName, body.HTMLURL
+	}
+	if r.Version == "" {
+		return nil, errors.New("update: response has no version")
+	}
+	return r, nil
+}
+
+// Start checks for a newer release than the app Version in the background and registers an OnExit function
+// printing a notice if one is known. Nothing happens when the Config is not enabled, has no URL, or the app has no
+// Version, nor when APP_OFFLINE is true in the app environment, whichever way config was built.
+func Start(a *app.App, config *Config) {
+	if config == nil || !config.Enabled || config.URL == "" || a.Version == "" || offline(a.LookupEnv) {
+		return
+	}
+
+	state, err := a.State()
+	if err != nil {
+		return
+	}
+
+	var mu sync.Mutex
+	var latest Release
+	_, _ = state.Get(latestKey, &latest)
+
+	var checked time.Time
+	_, _ = state.Get(checkedKey, &checked)
+
+	if time.Since(checked) >= config.Interval {
+		ctx := a.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+			defer cancel()
+
+			// a failed check counts too, so an offline machine does not try on every run
+			_ = state.Set(checkedKey, time.Now().UTC())
+
+			r, err := Fetch(ctx, nil, config.URL)
+			if err != nil {
+				return
+			}
+			_ = state.Set(latestKey, r)
+
+			mu.Lock()
+			latest = *r
+			mu.Unlock()
+		}()
+	}
+
+	a.Catalog().AddStrings("en", messages)
+	a.OnExit(func(code int) {
+		mu.Lock()
+		r := latest
+		mu.Unlock()
+
+		if r.Version == "" || !Newer(r.Version, a.Version) {
+			return
+		}
+
+		l := a.Localizer()
+		msg := l.T("update.available", r.Version, a.Version)
+		if r.URL != "" {
+			msg += " " + l.T("update.url", r.URL)
+		}
+		_, _ = fmt.Fprintln(a.ErrOutput(), msg)
+	})
+}
+
+// messages are the notices shown to users, in English.
+var messages = map[string]string{
+	"update.available": "Version %s is available, you have %s.",
+	"update.url":       "See %s",
+}
+
+// offline reports whether APP_OFFLINE is true.
+func offline(lookup func(string) (string, bool)) bool {
+	v, _ := lookup("APP_OFFLINE")
+	b, _ := strconv.ParseBool(strings.TrimSpace(v))
+	return b
+}
+
+// Newer reports whether the version latest is newer than current. Versions are compared as semantic versions, with
+// an optional "v" prefix; a pre-release is older than the release it precedes.
+func Newer(latest, current string) bool {
+	return compare(latest, current) > 0
+}
+
+func compare(a, b string) int {
+	a, aPre := splitVersion(a)
+	b, bPre := splitVersion(b)
+
+	// 1.2 is the same release as 1.2.0
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for len(aParts) < len(bParts) {
+		aParts = append(aParts, "0")
+	}
+	for len(bParts) < len(aParts) {
+		bParts = append(bParts, "0")
+	}
+
+	if c := compareParts(aParts, bParts); c != 0 {
+		return c
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	default:
+		return compareParts(strings.Split(aPre, "."), strings.Split(bPre, "."))
+	}
+}
+
+// splitVersion returns the version without the "v" prefix and build metadata, split from its pre-release.
+func splitVersion(v string) (string, string) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if idx := strings.IndexByte(v, '+'); idx >= 0 {
+		v = v[:idx]
+	}
+	if idx := strings.IndexByte(v, '-'); idx >= 0 {
+		return v[:idx], v[idx+1:]
+	}
+	return v, ""
+}
+
+// compareParts compares dot separated identifiers, numerically when both are numbers. A missing identifier sorts
+// first.
+func compareParts(a, b []string) int {
+	for idx := 0; idx < len(a) || idx < len(b); idx++ {
+		switch {
+		case idx >= len(a):
+			return -1
+		case idx >= len(b):
+			return 1
+		}
+
+		x, xErr := strconv.Atoi(a[idx])
+		y, yErr := strconv.Atoi(b[idx])
+		switch {
+		case xErr == nil && yErr == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		case a[idx] != b[idx]:
+			if a[idx] < b[idx] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
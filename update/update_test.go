@@ -0,0 +1,142 @@
+package update_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/update"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		newer           bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"1.10.0", "v1.9.0", true},
+		{"v1.2", "v1.2.0", false},
+		{"v1.2.0", "v1.2.0-rc.1", true},
+		{"v1.2.0-rc.2", "v1.2.0-rc.1", true},
+		{"v1.2.0-rc.10", "v1.2.0-rc.9", true},
+		{"v1.2.0+build.5", "v1.2.0", false},
+		{"v1.1.0", "v1.2.0", false},
+		{"v2.0.0-alpha", "v1.9.9", true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.newer, update.Newer(test.latest, test.current), "%s > %s", test.latest, test.current)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plain":
+			_, _ = w.Write([]byte(`{"version":"v1.3.0","url":"https://example.com/download"}`))
+		case "/github":
+			_, _ = w.Write([]byte(`{"tag_name":"v1.4.0","html_url":"https://github.com/acme/tool/releases/v1.4.0"}`))
+		case "/empty":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	r, err := update.Fetch(context.Background(), nil, srv.URL+"/plain")
+	require.NoError(t, err)
+	assert.Equal(t, &update.Release{Version: "v1.3.0", URL: "https://example.com/download"}, r)
+
+	r, err = update.Fetch(context.Background(), srv.Client(), srv.URL+"/github")
+	require.NoError(t, err)
+	assert.Equal(t, &update.Release{Version: "v1.4.0", URL: "https://github.com/acme/tool/releases/v1.4.0"}, r)
+
+	_, err = update.Fetch(context.Background(), nil, srv.URL+"/empty")
+	assert.EqualError(t, err, "update: response has no version")
+
+	_, err = update.Fetch(context.Background(), nil, srv.URL+"/missing")
+	assert.EqualError(t, err, "update: 404 Not Found")
+}
+
+// newApp returns an App for a test at version v1.2.0, keeping its state in dir.
+func newApp(t *testing.T, dir string) *app.App {
+	a := apptest.New(t, []string{"APP_STATE_DIR=" + dir})
+	a.Version = "v1.2.0"
+	return a
+}
+
+func exit(t *testing.T, a *app.App) string {
+	assert.PanicsWithValue(t, "system exit 0", func() { a.Exit(0) })
+	return apptest.Stderr(t, a)
+}
+
+func TestStart(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"version":"v1.3.0","url":"https://example.com/download"}`))
+	}))
+	defer srv.Close()
+
+	config := update.DefaultConfig()
+	config.URL = srv.URL
+	dir := t.TempDir()
+
+	a := newApp(t, dir)
+	update.Start(a, config)
+
+	state, err := a.State()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		ok, _ := state.Get("update.latest", new(update.Release))
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "Version v1.3.0 is available, you have v1.2.0. See https://example.com/download\n", exit(t, a))
+
+	// the next run within the interval uses the remembered release
+	a = newApp(t, dir)
+	update.Start(a, config)
+	assert.Equal(t, "Version v1.3.0 is available, you have v1.2.0. See https://example.com/download\n", exit(t, a))
+	assert.Equal(t, int32(1), requests.Load())
+
+	// an up to date app says nothing
+	a = newApp(t, dir)
+	a.Version = "v1.3.0"
+	update.Start(a, config)
+	assert.Empty(t, exit(t, a))
+}
+
+func TestStart_Disabled(t *testing.T) {
+	config := update.DefaultConfig()
+	config.URL = "http://127.0.0.1:1"
+	config.Enabled = false
+
+	a := newApp(t, t.TempDir())
+	update.Start(a, config)
+	assert.Empty(t, exit(t, a))
+
+	a = newApp(t, t.TempDir())
+	a.Version = ""
+	config.Enabled = true
+	update.Start(a, config)
+	assert.Empty(t, exit(t, a))
+
+	dir := t.TempDir()
+	a = newApp(t, dir)
+	a.Environment = append(a.Environment, "APP_OFFLINE=true")
+	update.Start(a, config)
+	assert.Empty(t, exit(t, a))
+	state, err := a.State()
+	require.NoError(t, err)
+	ok, _ := state.Get("update.checked", new(time.Time))
+	assert.False(t, ok)
+}
@@ -0,0 +1,121 @@
+package vcr
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the recording settings, as in APP_VCR_MODE.
+	DefaultPrefix = "APP_VCR_"
+
+	// DefaultDir is the directory the cassettes are kept in, relative to the working directory of the tests: the
+	// directory of the package tested.
+	DefaultDir = "testdata/cassettes"
+)
+
+// DefaultScrubHeaders are the headers whose values are scrubbed from the cassettes.
+var DefaultScrubHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key"}
+
+// DefaultScrubParams are the query parameters, and the members of JSON and form bodies, whose values are scrubbed from
+// the cassettes.
+var DefaultScrubParams = []string{
+	"access_token", "api_key", "client_secret", "code_verifier", "id_token", "password", "refresh_token",
+}
+
+// Mode is what a Recorder does with the requests.
+type Mode string
+
+const (
+	// Off sends the requests, and records nothing.
+	Off Mode = "off"
+
+	// Record sends the requests, and records them, replacing the cassette.
+	Record Mode = "record"
+
+	// Replay answers the requests with the responses of the cassette, and fails those it has no response to.
+	Replay Mode = "replay"
+
+	// Auto replays the cassette if it exists, and records it otherwise.
+	Auto Mode = "auto"
+)
+
+// Config describes where the cassettes are kept, and what a Recorder does with the requests.
+type Config struct {
+	Mode         Mode
+	Dir          string   // the directory the cassettes are kept in
+	ScrubHeaders []string // the headers whose values are scrubbed
+	ScrubParams  []string // the query parameters and body members whose values are scrubbed
+}
+
+// DefaultConfig returns a Config recording nothing.
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:         Off,
+		Dir:          DefaultDir,
+		ScrubHeaders: append([]string(nil), DefaultScrubHeaders...),
+		ScrubParams:  append([]string(nil), DefaultScrubParams...),
+	}
+}
+
+func init() {
+	configschema.Register("vcr", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the recorder variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "MODE", Type: "string", Default: string(Off),
+			Description: "What the recorder does: off, record, replay, or auto."},
+		{Name: prefix + "DIR", Type: "path", Default: DefaultDir,
+			Description: "The directory the cassettes are kept in."},
+		{Name: prefix + "SCRUB_HEADERS", Type: "string",
+			Description: "More headers to scrub, separated by spaces or commas."},
+		{Name: prefix + "SCRUB_PARAMS", Type: "string",
+			Description: "More query parameters and body members to scrub, separated by spaces or commas."},
+	}
+}
+
+// FromEnv reads the MODE of the Recorder (off, record, replay, or auto), the DIR of the cassettes, and the
+// SCRUB_HEADERS and SCRUB_PARAMS, separated by spaces or commas, with the prefix or DefaultPrefix. The headers and
+// parameters scrubbed are added to the default ones.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+	list := func(key string) []string {
+		return strings.FieldsFunc(get(key), func(r rune) bool { return r == ' ' || r == ',' })
+	}
+
+	config := DefaultConfig()
+	switch v := get("MODE"); Mode(strings.ToLower(v)) {
+	case "", Off:
+	case Record, Replay, Auto:
+		config.Mode = Mode(strings.ToLower(v))
+	default:
+		return nil, fmt.Errorf("vcr: invalid %sMODE %q", prefix, v)
+	}
+
+	if v := get("DIR"); v != "" {
+		config.Dir = filepath.Clean(v)
+	}
+
+	for _, h := range list("SCRUB_HEADERS") {
+		if strings.ContainsAny(h, " \t:") {
+			return nil, fmt.Errorf("vcr: invalid %sSCRUB_HEADERS header %q", prefix, h)
+		}
+		config.ScrubHeaders = append(config.ScrubHeaders, http.CanonicalHeaderKey(h))
+	}
+	config.ScrubParams = append(config.ScrubParams, list("SCRUB_PARAMS")...)
+
+	return config, nil
+}
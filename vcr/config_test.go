@@ -0,0 +1,48 @@
+package vcr_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/vcr"
+)
+
+func TestFromEnv_Mode(t *testing.T) {
+	for v, expected := range map[string]vcr.Mode{"": vcr.Off, "OFF": vcr.Off, " Replay ": vcr.Replay, "auto": vcr.Auto} {
+		config, err := vcr.FromEnv(apptest.Lookup(map[string]string{"TEST_VCR_MODE": v}), "TEST_VCR_")
+		require.NoError(t, err, v)
+		assert.Equal(t, expected, config.Mode, v)
+	}
+
+	_, err := vcr.FromEnv(apptest.Lookup(map[string]string{"APP_VCR_MODE": "rewind"}), "")
+	assert.EqualError(t, err, `vcr: invalid APP_VCR_MODE "rewind"`)
+}
+
+func TestFromEnv_Dir(t *testing.T) {
+	config, err := vcr.FromEnv(apptest.Lookup(map[string]string{"APP_VCR_DIR": "fixtures/./http/"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, "fixtures/http", config.Dir)
+}
+
+func TestFromEnv_Scrub(t *testing.T) {
+	// the names are added to the defaults, so a secret is never recorded for want of repeating them
+	config, err := vcr.FromEnv(apptest.Lookup(map[string]string{
+		"APP_VCR_SCRUB_HEADERS": "x-github-token,, x-api-key",
+		"APP_VCR_SCRUB_PARAMS":  "sig signature",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, append(append([]string(nil), vcr.DefaultScrubHeaders...), "X-Github-Token", "X-Api-Key"),
+		config.ScrubHeaders)
+	assert.Equal(t, append(append([]string(nil), vcr.DefaultScrubParams...), "sig", "signature"), config.ScrubParams)
+
+	// a header written with its value would scrub nothing
+	_, err = vcr.FromEnv(apptest.Lookup(map[string]string{"APP_VCR_SCRUB_HEADERS": "X-Key:secret"}), "")
+	assert.EqualError(t, err, `vcr: invalid APP_VCR_SCRUB_HEADERS header "X-Key:secret"`)
+
+	// the defaults are copied, so that one Config cannot change another
+	config.ScrubHeaders[0] = "X-Changed"
+	assert.NotEqual(t, "X-Changed", vcr.DefaultConfig().ScrubHeaders[0])
+}
@@ -0,0 +1,392 @@
+// Package vcr records the HTTP requests the app sends, and their responses, to cassettes, files kept with the tests,
+// and replays them, so that the tests of code calling other services run offline, fast, and deterministically:
+//
+//	func TestSync(t *testing.T) {
+//		a := newApp(t)
+//		vcr.ForTest(t, a)
+//		client, err := restclient.Open(a, config)
+//		...
+//	}
+//
+// The cassette of the test is testdata/cassettes/TestSync.json: recorded on the first run, and replayed by the next
+// ones. Set APP_VCR_MODE=record to record it again, or replay to fail when it is missing, as in CI. The Recorder
+// applies to the clients made with App.HTTPTransport, such as those of restclient. The tests of apps made with
+// apptest.New use apptest.Cassette instead, whose -apptest.vcr flag sets the mode of every test at once.
+//
+// The user information of the URLs, and the values of the headers, query parameters, and members of JSON and form
+// bodies that carry secrets, such as the Authorization header or an access_token, are scrubbed from the cassettes, and
+// from the requests replayed before they are matched against those recorded: a request matches the first interaction
+// recorded, and not replayed yet, with the same method, URL, and body.
+package vcr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/internal/atomicfile"
+)
+
+// ErrNotRecorded is the cause of the errors of the requests replayed that match no interaction of the cassette.
+var ErrNotRecorded = errors.New("vcr: no interaction recorded")
+
+// cassette is the file the interactions are recorded to.
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  request  `json:"request"`
+	Response response `json:"response"`
+}
+
+type request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	body
+}
+
+type response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	body
+}
+
+// body is a body recorded as text, or in base64 if it is not UTF-8.
+type body struct {
+	Body   string `json:"body,omitempty"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+func newBody(b []byte) body {
+	if utf8.Valid(b) {
+		return body{Body: string(b)}
+	}
+	return body{Body: base64.StdEncoding.EncodeToString(b), Base64: true}
+}
+
+func (b body) bytes() []byte {
+	if b.Base64 {
+		data, _ := base64.StdEncoding.DecodeString(b.Body)
+		return data
+	}
+	return []byte(b.Body)
+}
+
+// Recorder records the requests sent by its Transports to a cassette, or replays them.
+type Recorder struct {
+	config Config
+	path   string
+	mode   Mode // Off, Record, or Replay
+
+	mu       sync.Mutex
+	cassette cassette
+	replayed []bool
+}
+
+// New returns a Recorder of the cassette name, a path relative to the Dir of config without the .json extension. The
+// cassette is loaded if it is replayed: in the Replay mode, or in the Auto mode if it exists.
+func New(config *Config, name string) (*Recorder, error) {
+	path := filepath.Join(config.Dir, filepath.FromSlash(name)+".json")
+	r := &Recorder{config: *config, path: path, mode: config.Mode}
+	if r.mode == Auto {
+		r.mode = Replay
+		if _, err := os.Stat(r.path); errors.Is(err, fs.ErrNotExist) {
+			r.mode = Record
+		}
+	}
+	if r.mode != Replay {
+		return r, nil
+	}
+
+	b, err := os.ReadFile(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("vcr: %v", err)
+	}
+	if err := json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("vcr: invalid cassette %s: %v", r.path, err)
+	}
+	r.replayed = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Install returns the Recorder of the cassette name, applying its Transport to the HTTP clients of the app, and saving
+// the cassette when the app exits, unless the Mode of config is Off.
+func Install(a *app.App, config *Config, name string) (*Recorder, error) {
+	r, err := New(config, name)
+	if err != nil || r.mode == Off {
+		return r, err
+	}
+
+	a.WrapHTTPClients(r.Transport)
+	a.OnExit(func(int) {
+		if err := r.Save(); err != nil {
+			_ = a.Logger().Errorf("%v", err)
+		}
+	})
+	return r, nil
+}
+
+// ForTest installs the Recorder of the cassette named after the test in the app, with the Config of the app
+// environment, in the Auto mode unless APP_VCR_MODE is set, and saves the cassette once the test ends. The test fails
+// if the cassette cannot be loaded or saved.
+func ForTest(tb testing.TB, a *app.App) *Recorder {
+	tb.Helper()
+
+	config, err := FromEnv(a.LookupEnv, "")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, ok := a.LookupEnv(DefaultPrefix + "MODE"); !ok {
+		config.Mode = Auto
+	}
+
+	r, err := New(config, tb.Name())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	a.WrapHTTPClients(r.Transport)
+	tb.Cleanup(func() {
+		if err := r.Save(); err != nil {
+			tb.Error(err)
+		}
+	})
+	return r
+}
+
+// Mode returns what the Recorder does with the requests: Off, Record, or Replay.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Save writes the cassette, if it is recorded.
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("vcr: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("vcr: %v", err)
+	}
+	if err := atomicfile.WriteFile(r.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("vcr: %v", err)
+	}
+	return nil
+}
+
+// Transport returns a RoundTripper recording the requests sent with next, or http.DefaultTransport if nil, or
+// replaying them.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if r.mode == Off {
+		return next
+	}
+	return &transport{recorder: r, next: next}
+}
+
+type transport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var b []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		b, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := t.recorder
+	recorded := request{
+		Method: req.Method,
+		URL:    r.scrubURL(req.URL),
+		Header: r.scrubHeader(req.Header),
+		body:   newBody(r.scrubBody(req.Header.Get("Content-Type"), b)),
+	}
+	if r.mode == Replay {
+		res, ok := r.replay(recorded)
+		if !ok {
+			return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, recorded.Method, recorded.URL)
+		}
+		data := res.bytes()
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", res.Status, http.StatusText(res.Status)),
+			StatusCode:    res.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        res.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader(data)),
+			ContentLength: int64(len(data)),
+			Request:       req,
+		}, nil
+	}
+
+	// the request is not modified, its body is sent from the copy read
+	out := req.Clone(req.Context())
+	if b != nil {
+		out.Body = io.NopCloser(bytes.NewReader(b))
+		out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	}
+	res, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(data))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction{Request: recorded, Response: response{
+		Status: res.StatusCode,
+		Header: r.scrubHeader(res.Header),
+		body:   newBody(r.scrubBody(res.Header.Get("Content-Type"), data)),
+	}})
+	r.mu.Unlock()
+	return res, nil
+}
+
+// replay returns the response of the first interaction matching req not replayed yet.
+func (r *Recorder) replay(req request) (response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx, i := range r.cassette.Interactions {
+		if !r.replayed[idx] && i.Request.Method == req.Method && i.Request.URL == req.URL &&
+			bytes.Equal(i.Request.bytes(), req.bytes()) {
+			r.replayed[idx] = true
+			return i.Response, true
+		}
+	}
+	return response{}, false
+}
+
+// scrubbed reports whether the values of the parameter, or body member, name are scrubbed.
+func (r *Recorder) scrubbed(name string) bool {
+	for _, p := range r.config.ScrubParams {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubHeader returns a copy of h with the values of the ScrubHeaders redacted.
+func (r *Recorder) scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	h = h.Clone()
+	for _, name := range r.config.ScrubHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, app.Redacted)
+		}
+	}
+	return h
+}
+
+// scrubURL returns u with its user information, and the values of the ScrubParams, redacted.
+func (r *Recorder) scrubURL(u *url.URL) string {
+	c := *u
+	if _, ok := c.User.Password(); ok {
+		c.User = url.UserPassword(app.Redacted, app.Redacted)
+	} else if c.User != nil {
+		c.User = url.User(app.Redacted)
+	}
+	if q := c.Query(); r.scrubValues(q) {
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// scrubValues redacts the values of the ScrubParams in v, reporting whether there were any.
+func (r *Recorder) scrubValues(v url.Values) bool {
+	changed := false
+	for name := range v {
+		if r.scrubbed(name) {
+			v[name] = []string{app.Redacted}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// scrubBody returns b with the values of the ScrubParams redacted, if it is a JSON document or a form. The body is
+// only encoded again if a value was redacted.
+func (r *Recorder) scrubBody(contentType string, b []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(b))
+		if err != nil || !r.scrubValues(form) {
+			return b
+		}
+		return []byte(form.Encode())
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil || !r.scrubJSON(v) {
+			return b
+		}
+		scrubbed, err := json.Marshal(v)
+		if err != nil {
+			return b
+		}
+		return scrubbed
+	}
+	return b
+}
+
+// scrubJSON redacts the members of the ScrubParams in v, reporting whether there were any.
+func (r *Recorder) scrubJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, member := range v {
+			if r.scrubbed(k) {
+				v[k] = app.Redacted
+				changed = true
+			} else if r.scrubJSON(member) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.scrubJSON(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,183 @@
+package vcr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/vcr"
+)
+
+// newAPI returns a server answering with a token, and the number of requests it received.
+func newAPI(t *testing.T) (*httptest.Server, *int) {
+	hits := new(int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=s3cr3t")
+		_, _ = io.WriteString(w, `{"access_token":"tok3n","expires_in":3600,"id":12345678901234567890}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func send(t *testing.T, rt http.RoundTripper, url, password string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url+"/token?client_id=app&api_key="+password,
+		strings.NewReader(`{"user":"gopher","password":"`+password+`"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+password)
+	return rt.RoundTrip(req)
+}
+
+func body(t *testing.T, res *http.Response) string {
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	return string(b)
+}
+
+func TestRecorder(t *testing.T) {
+	srv, hits := newAPI(t)
+	config := vcr.DefaultConfig()
+	config.Dir = t.TempDir()
+
+	config.Mode = vcr.Record
+	r, err := vcr.New(config, "auth/token")
+	require.NoError(t, err)
+	res, err := send(t, r.Transport(nil), srv.URL, "hunter2")
+	require.NoError(t, err)
+	assert.Equal(t, `{"access_token":"tok3n","expires_in":3600,"id":12345678901234567890}`, body(t, res))
+	require.NoError(t, r.Save())
+	assert.Equal(t, 1, *hits)
+
+	b, err := os.ReadFile(filepath.Join(config.Dir, "auth", "token.json"))
+	require.NoError(t, err)
+	for _, secret := range []string{"hunter2", "tok3n", "s3cr3t"} {
+		assert.NotContains(t, string(b), secret)
+	}
+	assert.Contains(t, string(b), `client_id=app`)
+	assert.Contains(t, string(b), `12345678901234567890`)
+
+	// the secrets of the requests replayed do not matter
+	config.Mode = vcr.Replay
+	r, err = vcr.New(config, "auth/token")
+	require.NoError(t, err)
+	res, err = send(t, r.Transport(nil), srv.URL, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	assert.Equal(t, `{"access_token":"[REDACTED]","expires_in":3600,"id":12345678901234567890}`, body(t, res))
+	assert.Equal(t, 1, *hits)
+
+	// every interaction is replayed once
+	_, err = send(t, r.Transport(nil), srv.URL, "hunter2")
+	assert.True(t, errors.Is(err, vcr.ErrNotRecorded))
+	assert.ErrorContains(t, err, "vcr: no interaction recorded for POST "+srv.URL+"/token?api_key=")
+
+	_, err = vcr.New(config, "missing")
+	assert.Error(t, err)
+}
+
+func TestRecorder_UserInfo(t *testing.T) {
+	srv, _ := newAPI(t)
+	config := vcr.DefaultConfig()
+	config.Dir = t.TempDir()
+	config.Mode = vcr.Record
+
+	r, err := vcr.New(config, "userinfo")
+	require.NoError(t, err)
+	for _, userinfo := range []string{"alice:hunter2@", "bob@"} {
+		_, err = send(t, r.Transport(nil), strings.Replace(srv.URL, "://", "://"+userinfo, 1), "")
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Save())
+
+	// the cassette is written in place of a temporary file
+	entries, err := os.ReadDir(config.Dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "userinfo.json", entries[0].Name())
+
+	b, err := os.ReadFile(filepath.Join(config.Dir, "userinfo.json"))
+	require.NoError(t, err)
+	for _, secret := range []string{"alice", "bob", "hunter2"} {
+		assert.NotContains(t, string(b), secret)
+	}
+	assert.Contains(t, string(b), `"url": "http://%5BREDACTED%5D:%5BREDACTED%5D@`)
+	assert.Contains(t, string(b), `"url": "http://%5BREDACTED%5D@`)
+}
+
+func TestRecorder_Auto(t *testing.T) {
+	srv, hits := newAPI(t)
+	config := vcr.DefaultConfig()
+	config.Dir = t.TempDir()
+	config.Mode = vcr.Auto
+
+	for _, mode := range []vcr.Mode{vcr.Record, vcr.Replay} {
+		r, err := vcr.New(config, "auto")
+		require.NoError(t, err)
+		assert.Equal(t, mode, r.Mode())
+
+		res, err := send(t, r.Transport(nil), srv.URL, "hunter2")
+		require.NoError(t, err)
+		assert.Contains(t, body(t, res), `"expires_in":3600`)
+		require.NoError(t, r.Save())
+	}
+	assert.Equal(t, 1, *hits)
+}
+
+func TestInstall(t *testing.T) {
+	srv, hits := newAPI(t)
+	dir := t.TempDir()
+	newApp := func(mode vcr.Mode) *app.App {
+		a := &app.App{Context: context.Background(), Container: &app.Container{}, Stdout: new(bytes.Buffer),
+			Stderr: new(bytes.Buffer), ExitHandler: func(code int) { panic(code) }}
+		config := vcr.DefaultConfig()
+		config.Dir, config.Mode = dir, mode
+		_, err := vcr.Install(a, config, "install")
+		require.NoError(t, err)
+		return a
+	}
+
+	a := newApp(vcr.Record)
+	_, err := send(t, a.HTTPTransport(nil), srv.URL, "hunter2")
+	require.NoError(t, err)
+	// the cassette is saved when the app exits
+	assert.PanicsWithValue(t, 0, func() { a.Exit(0) })
+	assert.FileExists(t, filepath.Join(dir, "install.json"))
+
+	a = newApp(vcr.Replay)
+	_, err = send(t, a.HTTPTransport(nil), srv.URL, "hunter2")
+	require.NoError(t, err)
+
+	a = newApp(vcr.Off)
+	_, err = send(t, a.HTTPTransport(nil), srv.URL, "hunter2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, *hits)
+}
+
+func TestForTest(t *testing.T) {
+	srv, _ := newAPI(t)
+	dir := t.TempDir()
+
+	t.Run("cassette", func(t *testing.T) {
+		a := &app.App{Environment: []string{"APP_VCR_DIR=" + dir}, Context: context.Background(),
+			Container: &app.Container{}, Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)}
+		assert.Equal(t, vcr.Record, vcr.ForTest(t, a).Mode())
+		_, err := send(t, a.HTTPTransport(nil), srv.URL, "hunter2")
+		require.NoError(t, err)
+	})
+	assert.FileExists(t, filepath.Join(dir, "TestForTest", "cassette.json"))
+}
@@ -0,0 +1,136 @@
+package watch
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the watcher settings, as in APP_WATCH_DEBOUNCE.
+	DefaultPrefix = "APP_WATCH_"
+
+	// DefaultDebounce is how long a Watcher waits for the changes to stop before it reports them.
+	DefaultDebounce = 100 * time.Millisecond
+)
+
+// DefaultExclude are the files ignored when no exclusions are configured: version control directories, dependencies,
+// and the temporary files of editors.
+var DefaultExclude = []string{".git", ".hg", ".svn", "node_modules", "*.swp", "*~", ".#*"}
+
+// Config describes which changes a Watcher reports and when.
+//
+// A pattern without a slash, such as *.go, matches the base name of a file, and excludes the directories it matches
+// with their contents. A pattern with a slash, such as templates/*.html, matches the path of a file relative to the
+// watched directory. The patterns are those of path.Match.
+type Config struct {
+	Debounce time.Duration // how long to wait for the changes to stop before reporting them
+	Include  []string      // the files reported, any if empty
+	Exclude  []string      // the files ignored, even if included
+}
+
+// DefaultConfig returns the Config with DefaultDebounce and DefaultExclude.
+func DefaultConfig() *Config {
+	return &Config{Debounce: DefaultDebounce, Exclude: DefaultExclude}
+}
+
+func init() {
+	configschema.Register("watch", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the file watching variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "DEBOUNCE", Type: "duration", Default: DefaultDebounce.String(),
+			Description: "How long changes must stop before they are reported."},
+		{Name: prefix + "INCLUDE", Type: "string", Description: "The patterns of the files reported, comma separated."},
+		{Name: prefix + "EXCLUDE", Type: "string", Default: strings.Join(DefaultExclude, ","),
+			Description: "The patterns of the files ignored, comma separated."},
+	}
+}
+
+// FromEnv reads the DEBOUNCE of the changes and the comma separated patterns of the files to INCLUDE and EXCLUDE, with
+// the prefix or DefaultPrefix. EXCLUDE replaces DefaultExclude.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+	if v := get("DEBOUNCE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("watch: invalid %sDEBOUNCE %q", prefix, v)
+		}
+		config.Debounce = d
+	}
+
+	config.Include = split(get("INCLUDE"))
+	if v, ok := lookup(prefix + "EXCLUDE"); ok {
+		config.Exclude = split(v)
+	}
+	for key, patterns := range map[string][]string{"INCLUDE": config.Include, "EXCLUDE": config.Exclude} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("watch: invalid %s%s %q", prefix, key, pattern)
+			}
+		}
+	}
+	return config, nil
+}
+
+func split(v string) []string {
+	var res []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// excluded reports whether the file or directory at rel, a slash separated path relative to the watched directory,
+// or one of the directories it is in, matches one of the Exclude patterns.
+func (c *Config) excluded(rel string) bool {
+	for _, pattern := range c.Exclude {
+		if !strings.Contains(pattern, "/") {
+			for _, elem := range strings.Split(rel, "/") {
+				if ok, _ := path.Match(pattern, elem); ok {
+					return true
+				}
+			}
+			continue
+		}
+		for p := rel; p != "."; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// included reports whether the file at rel matches one of the Include patterns, or there are none.
+func (c *Config) included(rel string) bool {
+	if len(c.Include) == 0 {
+		return true
+	}
+	for _, pattern := range c.Include {
+		name := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			name = rel
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
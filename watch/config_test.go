@@ -0,0 +1,50 @@
+package watch_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+func TestFromEnv_Exclude(t *testing.T) {
+	config, err := watch.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, watch.DefaultConfig(), config)
+
+	// EXCLUDE replaces the defaults, and set but empty it ignores nothing
+	config, err = watch.FromEnv(apptest.Lookup(map[string]string{"DEV_EXCLUDE": " "}), "DEV_")
+	require.NoError(t, err)
+	assert.Empty(t, config.Exclude)
+
+	config, err = watch.FromEnv(apptest.Lookup(map[string]string{"DEV_EXCLUDE": "vendor, *_test.go,"}), "DEV_")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vendor", "*_test.go"}, config.Exclude)
+}
+
+func TestFromEnv_Include(t *testing.T) {
+	config, err := watch.FromEnv(apptest.Lookup(map[string]string{"APP_WATCH_INCLUDE": "*.go, templates/*.html"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.go", "templates/*.html"}, config.Include)
+
+	// the patterns are checked up front rather than failing to match every change
+	_, err = watch.FromEnv(apptest.Lookup(map[string]string{"APP_WATCH_INCLUDE": "*.go,[a"}), "")
+	assert.EqualError(t, err, `watch: invalid APP_WATCH_INCLUDE "[a"`)
+	_, err = watch.FromEnv(apptest.Lookup(map[string]string{"APP_WATCH_EXCLUDE": `vendor,\`}), "")
+	assert.EqualError(t, err, `watch: invalid APP_WATCH_EXCLUDE "\\"`)
+}
+
+func TestFromEnv_Debounce(t *testing.T) {
+	// zero reports each change as it happens
+	config, err := watch.FromEnv(apptest.Lookup(map[string]string{"APP_WATCH_DEBOUNCE": "0s"}), "")
+	require.NoError(t, err)
+	assert.Zero(t, config.Debounce)
+
+	for _, v := range []string{"soon", "-1s", "100"} {
+		_, err := watch.FromEnv(apptest.Lookup(map[string]string{"APP_WATCH_DEBOUNCE": v}), "")
+		assert.EqualError(t, err, `watch: invalid APP_WATCH_DEBOUNCE "`+v+`"`, v)
+	}
+}
@@ -0,0 +1,245 @@
+// Package watch reports the changes to files, for commands with a watch mode that rebuild or rerun something when
+// its sources change:
+//
+//	config, err := watch.FromEnv(a.LookupEnv, "")
+//	config.Include = []string{"*.go", "templates/*.html"}
+//	w, err := watch.New(a, config, ".")
+//	if err != nil {
+//		return err
+//	}
+//	w.Run(func(ev watch.Event) {
+//		rebuild(ev.Paths)
+//	})
+//
+// Directories are watched with their subdirectories, including those created later. Changes are debounced: a save
+// that writes several files, or one file several times, is reported once, when the changes stop. A Watcher is closed
+// when the CommandContext it was created in is done or the app Exits.
+package watch
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Event is a batch of changes.
+type Event struct {
+	Paths []string // the files created, written, removed, or renamed, sorted
+}
+
+// Watcher watches files and directories for changes, reporting them as Events.
+type Watcher struct {
+	a      *app.App
+	config Config
+	fsw    *fsnotify.Watcher
+	roots  []string
+	files  map[string]bool // the roots that are files rather than directories
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// New returns a Watcher of the files and directories at paths, reporting the changes config allows. config may be
+// nil for the DefaultConfig.
+func New(a *app.App, config *Config, paths ...string) (*Watcher, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{a: a, config: *config, fsw: fsw, events: make(chan Event), done: make(chan struct{}),
+		files: make(map[string]bool)}
+	for _, p := range paths {
+		root, err := filepath.Abs(p)
+		if err != nil {
+			_ = fsw.Close()
+			return nil, err
+		}
+		w.roots = append(w.roots, root)
+		if err := w.add(root); err != nil {
+			_ = fsw.Close()
+			return nil, err
+		}
+	}
+
+	ctx := a.CommandContext()
+	go w.loop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = w.Close()
+		case <-w.done:
+		}
+	}()
+	a.OnExit(func(int) { _ = w.Close() })
+	return w, nil
+}
+
+// Events returns the channel the batches of changes are sent on, closed when the Watcher is closed.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Run calls fn with each batch of changes until the Watcher is closed. Changes made while fn runs are reported once
+// it returns.
+func (w *Watcher) Run(fn func(Event)) {
+	for ev := range w.events {
+		fn(ev)
+	}
+}
+
+// Close stops watching. It is safe to call more than once.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// add watches the file or directory at p, with its subdirectories, unless they are excluded.
+func (w *Watcher) add(p string) error {
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		w.files[p] = true
+		return w.fsw.Add(p)
+	}
+	return filepath.WalkDir(p, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if rel := w.rel(p); rel != "." && w.config.excluded(rel) {
+			return filepath.SkipDir
+		}
+		return w.fsw.Add(p)
+	})
+}
+
+// rel returns the slash separated path of p relative to the root it is in.
+func (w *Watcher) rel(p string) string {
+	var root string
+	for _, r := range w.roots {
+		if (p == r || strings.HasPrefix(p, r+string(filepath.Separator))) && len(r) > len(root) {
+			root = r
+		}
+	}
+	if root == "" {
+		return filepath.ToSlash(p)
+	}
+	if w.files[root] {
+		return filepath.Base(root)
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// reported reports whether a change to the file at p is reported.
+func (w *Watcher) reported(p string) bool {
+	rel := w.rel(p)
+	return !w.config.excluded(rel) && w.config.included(rel)
+}
+
+// loop collects the changes until they stop for the Debounce and sends them as an Event, merging the changes made
+// while the previous Event waits to be received.
+func (w *Watcher) loop() {
+	defer close(w.events)
+
+	pending := make(map[string]bool)
+	var ready []string
+	var out chan Event
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		select {
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					w.added(ev.Name, pending)
+				}
+			}
+			if w.reported(ev.Name) {
+				pending[ev.Name] = true
+			}
+			if len(pending) > 0 {
+				timer.Reset(w.config.Debounce)
+			}
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"error": err.Error()})
+			_ = w.a.Logger().Warnm(attrs, "unable to watch for changes")
+
+		case <-timer.C:
+			ready = merge(ready, pending)
+			pending = make(map[string]bool)
+			out = w.events
+			attrs := gomol.NewAttrsFromMap(map[string]interface{}{"paths": len(ready)})
+			_ = w.a.Logger().Debugm(attrs, "files changed")
+
+		case out <- Event{Paths: ready}:
+			ready, out = nil, nil
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// added watches the directory at dir, created while watching, and adds the files already in it to pending.
+func (w *Watcher) added(dir string, pending map[string]bool) {
+	if w.config.excluded(w.rel(dir)) {
+		return
+	}
+	if err := w.add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		attrs := gomol.NewAttrsFromMap(map[string]interface{}{"dir": dir, "error": err.Error()})
+		_ = w.a.Logger().Warnm(attrs, "unable to watch directory")
+	}
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && w.reported(p) {
+			pending[p] = true
+		}
+		return nil
+	})
+}
+
+// merge returns the sorted paths of list and set, without duplicates.
+func merge(list []string, set map[string]bool) []string {
+	for _, p := range list {
+		set[p] = true
+	}
+	merged := make([]string, 0, len(set))
+	for p := range set {
+		merged = append(merged, p)
+	}
+	sort.Strings(merged)
+	return merged
+}
@@ -0,0 +1,98 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/watch"
+)
+
+func next(t *testing.T, w *watch.Watcher) watch.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-w.Events():
+		require.True(t, ok, "events closed")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return watch.Event{}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules"), 0o755))
+
+	config := watch.DefaultConfig()
+	config.Debounce = 50 * time.Millisecond
+	config.Include = []string{"*.go", "templates/*.html"}
+	w, err := watch.New(apptest.New(t, nil), config, dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// several writes are reported once, and the files not included or excluded not at all
+	write(t, filepath.Join(dir, "main.go"), "package main")
+	write(t, filepath.Join(dir, "main.go"), "package main\n")
+	write(t, filepath.Join(dir, "pkg", "pkg.go"), "package pkg")
+	write(t, filepath.Join(dir, "README.md"), "# readme")
+	write(t, filepath.Join(dir, "node_modules", "x.go"), "package x")
+	ev := next(t, w)
+	assert.Equal(t, []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "pkg", "pkg.go")}, ev.Paths)
+
+	// directories created later are watched too
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "templates"), 0o755))
+	time.Sleep(100 * time.Millisecond)
+	write(t, filepath.Join(dir, "templates", "index.html"), "<html>")
+	ev = next(t, w)
+	assert.Equal(t, []string{filepath.Join(dir, "templates", "index.html")}, ev.Paths)
+
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+}
+
+func TestWatcher_Context(t *testing.T) {
+	dir := t.TempDir()
+	a := apptest.New(t, nil)
+	ctx, cancel := context.WithCancel(a.Context)
+	a.Context = ctx
+	w, err := watch.New(a, nil, dir)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	var events []watch.Event
+	go func() {
+		defer close(done)
+		w.Run(func(ev watch.Event) { events = append(events, ev) })
+	}()
+
+	write(t, filepath.Join(dir, "a.txt"), "a")
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return when the context was done")
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{filepath.Join(dir, "a.txt")}, events[0].Paths)
+}
+
+func TestNew_Missing(t *testing.T) {
+	_, err := watch.New(apptest.New(t, nil), nil, filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
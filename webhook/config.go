@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the delivery settings of the Dispatcher, as in APP_WEBHOOK_MAX_ATTEMPTS.
+	DefaultPrefix = "APP_WEBHOOK_"
+
+	// DefaultTimeout bounds each delivery attempt.
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxAttempts is the number of attempts made before a delivery is dead-lettered.
+	DefaultMaxAttempts = 5
+
+	// DefaultRetryBackoff is the delay before the first retry; it doubles with every attempt.
+	DefaultRetryBackoff = 5 * time.Second
+
+	// DefaultMaxBackoff caps the delay between attempts.
+	DefaultMaxBackoff = 10 * time.Minute
+
+	// DefaultQueueSize is the number of deliveries buffered before Enqueue refuses more events.
+	DefaultQueueSize = 1000
+
+	// DefaultConcurrency is the number of deliveries attempted at once.
+	DefaultConcurrency = 4
+)
+
+// Config describes the delivery behavior of a Dispatcher. New uses the default of each field left zero.
+type Config struct {
+	Timeout      time.Duration // bound on each attempt
+	MaxAttempts  int           // attempts before a delivery is dead-lettered
+	RetryBackoff time.Duration // delay before the first retry, doubling with every attempt
+	MaxBackoff   time.Duration // cap on the delay between attempts
+	QueueSize    int           // deliveries buffered before Enqueue refuses more events
+	Concurrency  int           // deliveries attempted at once
+}
+
+// DefaultConfig returns a Config making DefaultMaxAttempts at each delivery, DefaultConcurrency at once.
+func DefaultConfig() *Config {
+	return &Config{
+		Timeout:      DefaultTimeout,
+		MaxAttempts:  DefaultMaxAttempts,
+		RetryBackoff: DefaultRetryBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		QueueSize:    DefaultQueueSize,
+		Concurrency:  DefaultConcurrency,
+	}
+}
+
+// withDefaults returns a copy of c with the defaults in place of the fields left zero.
+func (c Config) withDefaults() Config {
+	for dst, def := range map[*time.Duration]time.Duration{
+		&c.Timeout:      DefaultTimeout,
+		&c.RetryBackoff: DefaultRetryBackoff,
+		&c.MaxBackoff:   DefaultMaxBackoff,
+	} {
+		if *dst <= 0 {
+			*dst = def
+		}
+	}
+	for dst, def := range map[*int]int{
+		&c.MaxAttempts: DefaultMaxAttempts,
+		&c.QueueSize:   DefaultQueueSize,
+		&c.Concurrency: DefaultConcurrency,
+	} {
+		if *dst <= 0 {
+			*dst = def
+		}
+	}
+	return c
+}
+
+func init() {
+	configschema.Register("webhook", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the webhook delivery variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "TIMEOUT", Type: "duration", Default: DefaultTimeout.String(),
+			Description: "How long each attempt may take."},
+		{Name: prefix + "MAX_ATTEMPTS", Type: "int", Default: strconv.Itoa(DefaultMaxAttempts),
+			Description: "The attempts made before a delivery is dead-lettered."},
+		{Name: prefix + "RETRY_BACKOFF", Type: "duration", Default: DefaultRetryBackoff.String(),
+			Description: "The wait before the first retry."},
+		{Name: prefix + "MAX_BACKOFF", Type: "duration", Default: DefaultMaxBackoff.String(),
+			Description: "The longest wait between attempts."},
+		{Name: prefix + "QUEUE_SIZE", Type: "int", Default: strconv.Itoa(DefaultQueueSize),
+			Description: "The deliveries buffered before Enqueue refuses more events."},
+		{Name: prefix + "CONCURRENCY", Type: "int", Default: strconv.Itoa(DefaultConcurrency),
+			Description: "The deliveries attempted at once."},
+	}
+}
+
+// FromEnv reads how deliveries are attempted, TIMEOUT, MAX_ATTEMPTS, RETRY_BACKOFF, and MAX_BACKOFF, and how many are
+// queued and sent at once, QUEUE_SIZE and CONCURRENCY, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	for key, dst := range map[string]*time.Duration{
+		"TIMEOUT":       &config.Timeout,
+		"RETRY_BACKOFF": &config.RetryBackoff,
+		"MAX_BACKOFF":   &config.MaxBackoff,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("webhook: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	for key, dst := range map[string]*int{
+		"MAX_ATTEMPTS": &config.MaxAttempts,
+		"QUEUE_SIZE":   &config.QueueSize,
+		"CONCURRENCY":  &config.Concurrency,
+	} {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("webhook: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = n
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,59 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/webhook"
+)
+
+func TestFromEnv_Retries(t *testing.T) {
+	// one attempt turns retries off; the backoffs are then unused but still checked
+	config, err := webhook.FromEnv(apptest.Lookup(map[string]string{
+		"HOOKS_MAX_ATTEMPTS":  "1",
+		"HOOKS_RETRY_BACKOFF": "100ms",
+		"HOOKS_MAX_BACKOFF":   "1m",
+	}), "HOOKS_")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, config.RetryBackoff)
+	assert.Equal(t, time.Minute, config.MaxBackoff)
+
+	for key, values := range map[string][]string{
+		"TIMEOUT":       {"soon", "10"},
+		"RETRY_BACKOFF": {"-1s"},
+		"MAX_BACKOFF":   {"0s"},
+		"MAX_ATTEMPTS":  {"0", "-1"},
+	} {
+		for _, v := range values {
+			_, err := webhook.FromEnv(apptest.Lookup(map[string]string{"APP_WEBHOOK_" + key: v}), "")
+			assert.EqualError(t, err, "webhook: invalid APP_WEBHOOK_"+key+` "`+v+`"`)
+		}
+	}
+}
+
+func TestFromEnv_Queue(t *testing.T) {
+	config, err := webhook.FromEnv(apptest.Lookup(map[string]string{
+		"APP_WEBHOOK_QUEUE_SIZE":  "1",
+		"APP_WEBHOOK_CONCURRENCY": "1",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.QueueSize)
+	assert.Equal(t, 1, config.Concurrency)
+
+	config, err = webhook.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DefaultConfig(), config)
+
+	// an empty queue would refuse every event, and without workers nothing would be delivered
+	for _, key := range []string{"QUEUE_SIZE", "CONCURRENCY"} {
+		for _, v := range []string{"0", "x"} {
+			_, err := webhook.FromEnv(apptest.Lookup(map[string]string{"APP_WEBHOOK_" + key: v}), "")
+			assert.EqualError(t, err, "webhook: invalid APP_WEBHOOK_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/demosdemon/golang-app-framework/webhook"
+)
+
+func TestSign(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":"1"}`)
+
+	assert.Equal(t, "t=1700000000,v1=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54",
+		webhook.Sign(secret, time.Unix(1700000000, 0), body))
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":"1"}`)
+	header := webhook.Sign(secret, time.Now(), body)
+
+	assert.NoError(t, webhook.Verify(secret, header, body, time.Minute))
+	assert.NoError(t, webhook.Verify(secret, "v1=00,"+header, body, time.Minute), "rotated secrets")
+	assert.Equal(t, webhook.ErrInvalidSignature, webhook.Verify([]byte("other"), header, body, 0))
+	assert.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(secret, header, []byte("{}"), 0))
+	assert.Equal(t, webhook.ErrInvalidSignature, webhook.Verify(secret, "garbage", body, 0))
+
+	old := webhook.Sign(secret, time.Now().Add(-time.Hour), body)
+	assert.NoError(t, webhook.Verify(secret, old, body, 0))
+	assert.Equal(t, webhook.ErrSignatureExpired, webhook.Verify(secret, old, body, time.Minute))
+}
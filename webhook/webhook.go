@@ -0,0 +1,438 @@
+// Package webhook delivers events to HTTP endpoints registered by subscribers. Deliveries are signed with the
+// endpoint secret (see Sign and Verify), retried with exponential backoff, and dead-lettered once every attempt has
+// failed. A Dispatcher is an app.Server, so registering it with App.Register drains queued deliveries on shutdown.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aphistic/gomol"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue when the queue cannot hold a delivery to every subscribed endpoint.
+	ErrQueueFull = errors.New("webhook: queue full")
+
+	// ErrClosed is returned by Enqueue once the Dispatcher is shutting down.
+	ErrClosed = errors.New("webhook: closed")
+
+	// ErrShutdown is passed to the DeadLetter function for deliveries still waiting to be retried at shutdown.
+	ErrShutdown = errors.New("webhook: dispatcher shut down")
+)
+
+// Endpoint is a subscriber URL.
+type Endpoint struct {
+	ID     string
+	URL    string
+	Secret []byte   // key of the SignatureHeader HMAC
+	Events []string // event types delivered to the endpoint; every type when empty
+}
+
+// Event is a notification sent to every endpoint subscribed to its type.
+type Event struct {
+	ID   string      `json:"id"`   // generated by Enqueue when empty; receivers use it to discard duplicates
+	Type string      `json:"type"` // e.g. "invoice.paid"
+	Time time.Time   `json:"time"` // set by Enqueue when zero
+	Data interface{} `json:"data"`
+}
+
+// Delivery is an event addressed to one endpoint.
+type Delivery struct {
+	Endpoint Endpoint
+	Event    Event
+	Payload  []byte // the JSON encoded event
+	Attempts int
+}
+
+// Dispatcher delivers events to the registered endpoints.
+type Dispatcher struct {
+	// DeadLetter, if set, receives every delivery that could not be made, for example to persist it for replay. It is
+	// called concurrently.
+	DeadLetter func(d *Delivery, err error)
+
+	// Client sends the deliveries; http.DefaultClient when nil.
+	Client *http.Client
+
+	config   Config
+	logger   gomol.WrappableLogger
+	registry *metrics.Registry
+
+	mu        sync.Mutex
+	endpoints map[string]Endpoint
+	queue     chan *Delivery
+	retries   map[*Delivery]*time.Timer
+	closed    bool
+	started   bool
+	stopping  chan struct{}
+	pending   sync.WaitGroup
+	slots     chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// New returns a Dispatcher. The logger and registry may be nil.
+func New(config *Config, logger gomol.WrappableLogger, registry *metrics.Registry) *Dispatcher {
+	c := config.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		config:    c,
+		logger:    logger,
+		registry:  registry,
+		endpoints: make(map[string]Endpoint),
+		queue:     make(chan *Delivery, c.QueueSize),
+		retries:   make(map[*Delivery]*time.Timer),
+		stopping:  make(chan struct{}),
+		slots:     make(chan struct{}, c.Concurrency),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Register adds the endpoint, replacing any endpoint with the same ID.
+func (d *Dispatcher) Register(e Endpoint) error {
+	if e.ID == "" {
+		return errors.New("webhook: endpoint ID is required")
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook: invalid endpoint URL %q", e.URL)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.endpoints[e.ID] = e
+	return nil
+}
+
+// Unregister removes the endpoint. Deliveries already queued for it are still attempted.
+func (d *Dispatcher) Unregister(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.endpoints, id)
+}
+
+// Endpoints returns the registered endpoints sorted by ID.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]Endpoint, 0, len(d.endpoints))
+	for _, e := range d.endpoints {
+		endpoints = append(endpoints, e)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].ID < endpoints[j].ID })
+	return endpoints
+}
+
+// Enqueue queues a delivery of the event to every subscribed endpoint. Either every delivery is queued or, if the
+// queue lacks room for all of them, none is and ErrQueueFull is returned. Deliveries start once the Dispatcher is
+// bound.
+func (d *Dispatcher) Enqueue(e Event) error {
+	if e.ID == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		e.ID = hex.EncodeToString(b)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+
+	var deliveries []*Delivery
+	for _, endpoint := range d.endpoints {
+		if subscribed(endpoint, e.Type) {
+			deliveries = append(deliveries, &Delivery{Endpoint: endpoint, Event: e, Payload: payload})
+		}
+	}
+
+	// only Enqueue adds to the queue and it holds the lock, so the room cannot shrink before the sends
+	if len(d.queue)+len(deliveries) > cap(d.queue) {
+		return ErrQueueFull
+	}
+
+	d.pending.Add(len(deliveries))
+	for _, delivery := range deliveries {
+		d.queue <- delivery
+	}
+	d.gauge(float64(len(deliveries)))
+	return nil
+}
+
+// Bind uses the App logger if the Dispatcher has none and starts delivering queued events.
+func (d *Dispatcher) Bind(a *app.App) error {
+	if d.logger == nil {
+		d.logger = a.Logger()
+	}
+
+	d.mu.Lock()
+	d.started = true
+	d.mu.Unlock()
+
+	go d.dispatch()
+	return nil
+}
+
+// Serve waits until the Dispatcher is shut down.
+func (d *Dispatcher) Serve() error {
+	<-d.stopping
+	return nil
+}
+
+// Shutdown refuses new events and waits for the queued deliveries to be attempted until the context is done. Failed
+// attempts are no longer retried: those deliveries, the ones waiting to be retried, and, once the context is done,
+// the ones still queued are passed to DeadLetter.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	started := d.started
+	retries := d.retries
+	d.retries = make(map[*Delivery]*time.Timer)
+	d.mu.Unlock()
+
+	for delivery, timer := range retries {
+		timer.Stop()
+		d.deadLetter(delivery, ErrShutdown)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+
+	var err error
+	if !started {
+		// nothing will deliver the queued events
+		err = d.abandon()
+	} else {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			err = ctx.Err()
+			d.cancel()
+			_ = d.abandon()
+		}
+	}
+
+	<-done
+	d.cancel()
+	close(d.stopping)
+	return err
+}
+
+// abandon dead-letters the queued deliveries.
+func (d *Dispatcher) abandon() error {
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.deadLetter(delivery, ErrShutdown)
+		default:
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch() {
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.slots <- struct{}{}
+			go d.run(delivery)
+		case <-d.stopping:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) run(delivery *Delivery) {
+	defer func() { <-d.slots }()
+
+	err := d.attempt(delivery)
+	if err == nil {
+		d.finish()
+		d.log(gomol.LevelDebug, delivery, "delivered event")
+		return
+	}
+
+	d.mu.Lock()
+	if d.closed || delivery.Attempts >= d.config.MaxAttempts {
+		d.mu.Unlock()
+		d.deadLetter(delivery, err)
+		return
+	}
+
+	backoff := d.backoff(delivery.Attempts, err)
+	d.retries[delivery] = time.AfterFunc(backoff, func() { d.retry(delivery) })
+	d.mu.Unlock()
+
+	d.log(gomol.LevelWarning, delivery, "delivery failed, retrying in %s: %v", backoff, err)
+}
+
+// retry attempts the delivery again unless Shutdown has already dead-lettered it.
+func (d *Dispatcher) retry(delivery *Delivery) {
+	d.mu.Lock()
+	_, ok := d.retries[delivery]
+	delete(d.retries, delivery)
+	d.mu.Unlock()
+
+	if ok {
+		d.slots <- struct{}{}
+		d.run(delivery)
+	}
+}
+
+// attempt makes one delivery attempt and returns an error unless the endpoint responded with a 2xx status.
+func (d *Dispatcher) attempt(delivery *Delivery) error {
+	delivery.Attempts++
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint.URL,
+		bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Webhook-Id", delivery.Event.ID)
+	req.Header.Set("Webhook-Attempt", strconv.Itoa(delivery.Attempts))
+	req.Header.Set(SignatureHeader, Sign(delivery.Endpoint.Secret, time.Now(), delivery.Payload))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+		_ = res.Body.Close()
+	}
+
+	if d.registry != nil {
+		labels := metrics.Labels{"endpoint": delivery.Endpoint.ID}
+		d.registry.Histogram("webhook_attempt_duration_seconds", nil, labels).Observe(time.Since(start).Seconds())
+		d.registry.Counter("webhook_attempts_total", metrics.Labels{
+			"endpoint": delivery.Endpoint.ID,
+			"code":     code,
+		}).Inc()
+	}
+
+	if err != nil {
+		return err
+	}
+	if res.StatusCode/100 != 2 {
+		return &statusError{res.StatusCode, res.Header.Get("Retry-After")}
+	}
+	return nil
+}
+
+// backoff returns the delay before the next attempt, honoring a Retry-After response header within MaxBackoff.
+func (d *Dispatcher) backoff(attempts int, err error) time.Duration {
+	backoff := d.config.RetryBackoff
+	for i := 1; i < attempts && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	var serr *statusError
+	if errors.As(err, &serr) {
+		if secs, perr := strconv.Atoi(serr.retryAfter); perr == nil && time.Duration(secs)*time.Second > backoff {
+			backoff = time.Duration(secs) * time.Second
+		}
+	}
+
+	if backoff > d.config.MaxBackoff {
+		backoff = d.config.MaxBackoff
+	}
+	return backoff
+}
+
+func (d *Dispatcher) deadLetter(delivery *Delivery, err error) {
+	defer d.finish()
+
+	d.log(gomol.LevelError, delivery, "dead-lettered event after %d attempts: %v", delivery.Attempts, err)
+	if d.registry != nil {
+		d.registry.Counter("webhook_dead_letters_total", metrics.Labels{"endpoint": delivery.Endpoint.ID}).Inc()
+	}
+	if d.DeadLetter != nil {
+		d.DeadLetter(delivery, err)
+	}
+}
+
+// finish marks a delivery as delivered or dead-lettered.
+func (d *Dispatcher) finish() {
+	d.gauge(-1)
+	d.pending.Done()
+}
+
+func (d *Dispatcher) gauge(delta float64) {
+	if d.registry != nil {
+		d.registry.Gauge("webhook_pending_deliveries", nil).Add(delta)
+	}
+}
+
+func (d *Dispatcher) log(level gomol.LogLevel, delivery *Delivery, format string, args ...interface{}) {
+	if d.logger != nil {
+		_ = d.logger.Log(level, gomol.NewAttrsFromMap(map[string]interface{}{
+			"endpoint": delivery.Endpoint.ID,
+			"event":    delivery.Event.ID,
+			"type":     delivery.Event.Type,
+			"attempts": delivery.Attempts,
+		}), format, args...)
+	}
+}
+
+func subscribed(e Endpoint, eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, t := range e.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type statusError struct {
+	code       int
+	retryAfter string
+}
+
+func (e *statusError) Error() string {
+	return "endpoint responded " + strconv.Itoa(e.code) + " " + http.StatusText(e.code)
+}
@@ -0,0 +1,217 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/metrics"
+	"github.com/demosdemon/golang-app-framework/webhook"
+)
+
+func testConfig() *webhook.Config {
+	config := webhook.DefaultConfig()
+	config.Timeout = time.Second
+	config.MaxAttempts = 3
+	config.RetryBackoff = time.Millisecond
+	config.QueueSize = 2
+	return config
+}
+
+// deadLetters records the deliveries passed to Dispatcher.DeadLetter.
+type deadLetters struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+}
+
+func (dl *deadLetters) add(d *webhook.Delivery, err error) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	dl.events = append(dl.events, d.Event.ID)
+	dl.errs = append(dl.errs, err)
+}
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+func shutdown(t *testing.T, d *webhook.Dispatcher, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.Shutdown(ctx)
+}
+
+func TestDispatcher(t *testing.T) {
+	var calls int32
+	received := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := webhook.Verify([]byte("secret"), r.Header.Get(webhook.SignatureHeader), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// the first attempt fails
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- request{r.Header, body}
+	}))
+	defer server.Close()
+
+	registry := metrics.NewRegistry()
+	d := webhook.New(testConfig(), nil, registry)
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "billing", URL: server.URL, Secret: []byte("secret"),
+		Events: []string{"invoice.paid"}}))
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "other", URL: server.URL, Events: []string{"user.created"}}))
+	require.NoError(t, d.Bind(&app.App{Stderr: io.Discard}))
+
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_1", Type: "invoice.paid", Data: map[string]int{"amount": 5}}))
+
+	r := <-received
+	assert.Equal(t, "evt_1", r.header.Get("Webhook-Id"))
+	assert.Equal(t, "2", r.header.Get("Webhook-Attempt"))
+	assert.Equal(t, "application/json", r.header.Get("Content-Type"))
+
+	var event struct {
+		ID   string
+		Type string
+		Time time.Time
+		Data map[string]int
+	}
+	require.NoError(t, json.Unmarshal(r.body, &event))
+	assert.Equal(t, "evt_1", event.ID)
+	assert.Equal(t, "invoice.paid", event.Type)
+	assert.False(t, event.Time.IsZero())
+	assert.Equal(t, map[string]int{"amount": 5}, event.Data)
+
+	require.NoError(t, shutdown(t, d, time.Second))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1.0, registry.Counter("webhook_attempts_total", metrics.Labels{
+		"endpoint": "billing",
+		"code":     "503",
+	}).Value())
+	assert.Equal(t, 1.0, registry.Counter("webhook_attempts_total", metrics.Labels{
+		"endpoint": "billing",
+		"code":     "200",
+	}).Value())
+	assert.Equal(t, 0.0, registry.Gauge("webhook_pending_deliveries", nil).Value())
+
+	assert.Equal(t, webhook.ErrClosed, d.Enqueue(webhook.Event{Type: "invoice.paid"}))
+}
+
+func TestDispatcher_zeroConfig(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("Webhook-Id")
+	}))
+	defer server.Close()
+
+	// the zero Config stands for the defaults, delivering rather than blocking on no slots
+	d := webhook.New(&webhook.Config{}, nil, nil)
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "billing", URL: server.URL, Events: []string{"invoice.paid"}}))
+	require.NoError(t, d.Bind(&app.App{Stderr: io.Discard}))
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_1", Type: "invoice.paid"}))
+
+	select {
+	case id := <-received:
+		assert.Equal(t, "evt_1", id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not delivered")
+	}
+	require.NoError(t, shutdown(t, d, time.Second))
+}
+
+func TestDispatcher_DeadLetter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	dl := new(deadLetters)
+	registry := metrics.NewRegistry()
+	d := webhook.New(testConfig(), nil, registry)
+	d.DeadLetter = dl.add
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "broken", URL: server.URL}))
+	require.NoError(t, d.Bind(&app.App{Stderr: io.Discard}))
+
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_1", Type: "any"}))
+	assert.Eventually(t, func() bool {
+		dl.mu.Lock()
+		defer dl.mu.Unlock()
+		return len(dl.events) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.EqualError(t, dl.errs[0], "endpoint responded 500 Internal Server Error")
+	assert.Equal(t, 1.0, registry.Counter("webhook_dead_letters_total", metrics.Labels{"endpoint": "broken"}).Value())
+
+	require.NoError(t, shutdown(t, d, time.Second))
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := testConfig()
+	config.Concurrency = 1
+	dl := new(deadLetters)
+	d := webhook.New(config, nil, nil)
+	d.DeadLetter = dl.add
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "slow", URL: server.URL}))
+
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_1"}))
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_2"}))
+	assert.Equal(t, webhook.ErrQueueFull, d.Enqueue(webhook.Event{ID: "evt_3"}))
+
+	require.NoError(t, d.Bind(&app.App{Stderr: io.Discard}))
+	served := make(chan error, 1)
+	go func() { served <- d.Serve() }()
+
+	// the endpoint never answers, so both deliveries are dead-lettered when the deadline passes
+	assert.Equal(t, context.DeadlineExceeded, shutdown(t, d, 50*time.Millisecond))
+	require.NoError(t, <-served)
+	assert.ElementsMatch(t, []string{"evt_1", "evt_2"}, dl.events)
+}
+
+func TestDispatcher_Register(t *testing.T) {
+	d := webhook.New(testConfig(), nil, nil)
+	assert.EqualError(t, d.Register(webhook.Endpoint{URL: "https://example.com"}), "webhook: endpoint ID is required")
+	assert.EqualError(t, d.Register(webhook.Endpoint{ID: "a", URL: "example.com"}),
+		`webhook: invalid endpoint URL "example.com"`)
+
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "b", URL: "https://b.example.com"}))
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "a", URL: "https://a.example.com"}))
+	require.NoError(t, d.Register(webhook.Endpoint{ID: "b", URL: "https://new.example.com"}))
+	d.Unregister("a")
+
+	assert.Equal(t, []webhook.Endpoint{{ID: "b", URL: "https://new.example.com"}}, d.Endpoints())
+
+	// deliveries never attempted are dead-lettered at shutdown
+	dl := new(deadLetters)
+	d.DeadLetter = dl.add
+	require.NoError(t, d.Enqueue(webhook.Event{ID: "evt_1"}))
+	require.NoError(t, shutdown(t, d, time.Second))
+	assert.Equal(t, []error{webhook.ErrShutdown}, dl.errs)
+}
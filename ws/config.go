@@ -0,0 +1,144 @@
+package ws
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/demosdemon/golang-app-framework/configschema"
+)
+
+const (
+	// DefaultPrefix prefixes the connection settings of a Hub, as in APP_WS_PING_INTERVAL.
+	DefaultPrefix = "APP_WS_"
+
+	// DefaultPingInterval is how often connections are pinged.
+	DefaultPingInterval = 30 * time.Second
+
+	// DefaultPongTimeout is how long a connection may go without a message or pong before it is closed.
+	DefaultPongTimeout = 60 * time.Second
+
+	// DefaultWriteTimeout is how long a single write may take.
+	DefaultWriteTimeout = 10 * time.Second
+
+	// DefaultSendQueue is the number of outgoing messages buffered for each connection.
+	DefaultSendQueue = 64
+
+	// DefaultMaxMessageSize is the largest message accepted from a client, in bytes.
+	DefaultMaxMessageSize = 1 << 20
+)
+
+// Config describes the liveness checks and buffering of the connections of a Manager.
+//
+// New uses the default of each field left zero, and twice the PingInterval for a zero PongTimeout.
+type Config struct {
+	PingInterval   time.Duration // how often connections are pinged
+	PongTimeout    time.Duration // how long a connection may be silent before it is closed; longer than PingInterval
+	WriteTimeout   time.Duration // how long a single write may take
+	SendQueue      int           // outgoing messages buffered for each connection
+	MaxMessageSize int64         // largest message accepted from a client, in bytes
+}
+
+// DefaultConfig returns a Config pinging connections every DefaultPingInterval and closing them after
+// DefaultPongTimeout of silence.
+func DefaultConfig() *Config {
+	return &Config{
+		PingInterval:   DefaultPingInterval,
+		PongTimeout:    DefaultPongTimeout,
+		WriteTimeout:   DefaultWriteTimeout,
+		SendQueue:      DefaultSendQueue,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// withDefaults returns a copy of c with the defaults in place of the fields left zero.
+func (c Config) withDefaults() Config {
+	if c.PingInterval <= 0 {
+		c.PingInterval = DefaultPingInterval
+	}
+	if c.PongTimeout <= 0 {
+		c.PongTimeout = 2 * c.PingInterval
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.SendQueue <= 0 {
+		c.SendQueue = DefaultSendQueue
+	}
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = DefaultMaxMessageSize
+	}
+	return c
+}
+
+func init() {
+	configschema.Register("ws", ConfigKeys(DefaultPrefix)...)
+}
+
+// ConfigKeys describes the WebSocket variables with the prefix.
+func ConfigKeys(prefix string) []configschema.Key {
+	return []configschema.Key{
+		{Name: prefix + "PING_INTERVAL", Type: "duration", Default: DefaultPingInterval.String(),
+			Description: "How often connections are pinged."},
+		{Name: prefix + "PONG_TIMEOUT", Type: "duration", Default: DefaultPongTimeout.String(),
+			Description: "How long a connection may be silent before it is closed."},
+		{Name: prefix + "WRITE_TIMEOUT", Type: "duration", Default: DefaultWriteTimeout.String(),
+			Description: "How long a single write may take."},
+		{Name: prefix + "SEND_QUEUE", Type: "int", Default: strconv.Itoa(DefaultSendQueue),
+			Description: "The outgoing messages buffered for each connection."},
+		{Name: prefix + "MAX_MESSAGE_SIZE", Type: "int", Default: strconv.Itoa(DefaultMaxMessageSize),
+			Description: "The largest message accepted from a client, in bytes."},
+	}
+}
+
+// FromEnv reads how connections are kept alive, PING_INTERVAL and PONG_TIMEOUT, the WRITE_TIMEOUT, the SEND_QUEUE of
+// each connection, and the MAX_MESSAGE_SIZE accepted, with the prefix or DefaultPrefix.
+func FromEnv(lookup func(string) (string, bool), prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	get := func(key string) string {
+		v, _ := lookup(prefix + key)
+		return strings.TrimSpace(v)
+	}
+
+	config := DefaultConfig()
+
+	for key, dst := range map[string]*time.Duration{
+		"PING_INTERVAL": &config.PingInterval,
+		"PONG_TIMEOUT":  &config.PongTimeout,
+		"WRITE_TIMEOUT": &config.WriteTimeout,
+	} {
+		if v := get(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("ws: invalid %s%s %q", prefix, key, v)
+			}
+			*dst = d
+		}
+	}
+
+	if v := get("SEND_QUEUE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("ws: invalid %sSEND_QUEUE %q", prefix, v)
+		}
+		config.SendQueue = n
+	}
+
+	if v := get("MAX_MESSAGE_SIZE"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("ws: invalid %sMAX_MESSAGE_SIZE %q", prefix, v)
+		}
+		config.MaxMessageSize = n
+	}
+
+	if config.PongTimeout <= config.PingInterval {
+		return nil, fmt.Errorf("ws: %sPONG_TIMEOUT must be longer than %sPING_INTERVAL", prefix, prefix)
+	}
+
+	return config, nil
+}
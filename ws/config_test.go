@@ -0,0 +1,59 @@
+package ws_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/apptest"
+	"github.com/demosdemon/golang-app-framework/ws"
+)
+
+func TestFromEnv_KeepAlive(t *testing.T) {
+	config, err := ws.FromEnv(apptest.Lookup(map[string]string{
+		"CHAT_PING_INTERVAL": "5s",
+		"CHAT_PONG_TIMEOUT":  "15s",
+	}), "CHAT_")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, config.PingInterval)
+	assert.Equal(t, 15*time.Second, config.PongTimeout)
+
+	// a connection must have time to answer a ping before it is given up on, whichever of the two is set
+	for _, env := range []map[string]string{
+		{"APP_WS_PING_INTERVAL": "2m"},
+		{"APP_WS_PONG_TIMEOUT": "10s"},
+		{"APP_WS_PING_INTERVAL": "15s", "APP_WS_PONG_TIMEOUT": "15s"},
+	} {
+		config, err := ws.FromEnv(apptest.Lookup(env), "")
+		assert.Nil(t, config)
+		assert.EqualError(t, err, "ws: APP_WS_PONG_TIMEOUT must be longer than APP_WS_PING_INTERVAL")
+	}
+
+	for _, key := range []string{"PING_INTERVAL", "PONG_TIMEOUT", "WRITE_TIMEOUT"} {
+		_, err := ws.FromEnv(apptest.Lookup(map[string]string{"APP_WS_" + key: "0s"}), "")
+		assert.EqualError(t, err, "ws: invalid APP_WS_"+key+` "0s"`)
+	}
+}
+
+func TestFromEnv_Sizes(t *testing.T) {
+	config, err := ws.FromEnv(apptest.Lookup(map[string]string{
+		"APP_WS_SEND_QUEUE":       "1",
+		"APP_WS_MAX_MESSAGE_SIZE": "4096",
+	}), "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, config.SendQueue)
+	assert.Equal(t, int64(4096), config.MaxMessageSize)
+
+	config, err = ws.FromEnv(apptest.Lookup(nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, ws.DefaultConfig(), config)
+
+	for key, values := range map[string][]string{"SEND_QUEUE": {"0", "none"}, "MAX_MESSAGE_SIZE": {"-1", "4KiB"}} {
+		for _, v := range values {
+			_, err := ws.FromEnv(apptest.Lookup(map[string]string{"APP_WS_" + key: v}), "")
+			assert.EqualError(t, err, "ws: invalid APP_WS_"+key+` "`+v+`"`)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package ws manages WebSocket connections: it upgrades requests, tracks the connections, queues and broadcasts
+// messages, pings clients to detect dead connections, and closes every connection when the App shuts down.
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aphistic/gomol"
+	"github.com/gorilla/websocket"
+
+	"github.com/demosdemon/golang-app-framework/app"
+)
+
+// Message types, as defined by RFC 6455.
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+var (
+	// ErrQueueFull is returned by Conn.Send when the connection is not keeping up with its messages.
+	ErrQueueFull = errors.New("ws: send queue full")
+
+	// ErrClosed is returned by Conn.Send after the connection is closed.
+	ErrClosed = errors.New("ws: connection closed")
+)
+
+// Handler is called for every message received on a connection. Calls for a connection are sequential.
+type Handler func(c *Conn, messageType int, data []byte)
+
+// Manager is an http.Handler upgrading requests to WebSocket connections. It is also an app.Server, so registering
+// it with App.Register closes every connection with 1001 Going Away when the App shuts down; register it after the
+// HTTP server serving it so it is shut down first.
+type Manager struct {
+	// Upgrader upgrades the requests; set its CheckOrigin to accept cross-origin connections.
+	Upgrader websocket.Upgrader
+
+	config  Config
+	handler Handler
+	logger  gomol.WrappableLogger
+	nextID  uint64
+
+	mu       sync.Mutex
+	conns    map[*Conn]struct{}
+	closed   bool
+	stopped  chan struct{}
+	pumps    sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// New returns a Manager calling handler for every message received. The handler and logger may be nil.
+func New(config *Config, handler Handler, logger gomol.WrappableLogger) *Manager {
+	return &Manager{
+		config:  config.withDefaults(),
+		handler: handler,
+		logger:  logger,
+		conns:   make(map[*Conn]struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request and serves the connection until it is closed.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	closed := m.closed
+	m.mu.Unlock()
+
+	if closed {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	wsc, err := m.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already responded
+		return
+	}
+
+	c := &Conn{
+		ID:      atomic.AddUint64(&m.nextID, 1),
+		Request: r,
+		manager: m,
+		ws:      wsc,
+		send:    make(chan outgoing, m.config.SendQueue),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		_ = wsc.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(m.config.WriteTimeout))
+		_ = wsc.Close()
+		return
+	}
+	m.conns[c] = struct{}{}
+	m.pumps.Add(1)
+	m.mu.Unlock()
+
+	m.log(gomol.LevelDebug, c, "websocket connected")
+
+	go c.writePump()
+	c.readPump()
+}
+
+// Conns returns the open connections.
+func (m *Manager) Conns() []*Conn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]*Conn, 0, len(m.conns))
+	for c := range m.conns {
+		res = append(res, c)
+	}
+	return res
+}
+
+// Broadcast queues the message on every open connection. Connections whose send queue is full are dropped, so a slow
+// client cannot hold back the others.
+func (m *Manager) Broadcast(messageType int, data []byte) {
+	for _, c := range m.Conns() {
+		if err := c.Send(messageType, data); err == ErrQueueFull {
+			m.log(gomol.LevelWarning, c, "dropping slow websocket connection")
+			_ = c.ws.Close()
+		}
+	}
+}
+
+// Bind uses the App logger if the Manager has none.
+func (m *Manager) Bind(a *app.App) error {
+	if m.logger == nil {
+		m.logger = a.Logger()
+	}
+	return nil
+}
+
+// Serve waits until the Manager is shut down.
+func (m *Manager) Serve() error {
+	<-m.stopped
+	return nil
+}
+
+// Shutdown refuses new connections and closes the open ones, waiting for clients to acknowledge until the context
+// is done, after which the remaining connections are dropped.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	defer m.stopOnce.Do(func() { close(m.stopped) })
+
+	for _, c := range m.Conns() {
+		c.Close(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.pumps.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		for _, c := range m.Conns() {
+			_ = c.ws.Close()
+		}
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (m *Manager) remove(c *Conn) {
+	m.mu.Lock()
+	delete(m.conns, c)
+	m.mu.Unlock()
+	m.pumps.Done()
+
+	m.log(gomol.LevelDebug, c, "websocket disconnected")
+}
+
+func (m *Manager) log(level gomol.LogLevel, c *Conn, msg string) {
+	if m.logger != nil {
+		_ = m.logger.Log(level, gomol.NewAttrsFromMap(map[string]interface{}{
+			"conn":   c.ID,
+			"remote": c.Request.RemoteAddr,
+		}), msg)
+	}
+}
+
+// Conn is a WebSocket connection tracked by a Manager.
+type Conn struct {
+	ID      uint64        // unique within the Manager
+	Request *http.Request // the upgraded request
+
+	manager *Manager
+	ws      *websocket.Conn
+	send    chan outgoing
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+	closing     chan struct{}
+	done        chan struct{}
+}
+
+type outgoing struct {
+	messageType int
+	data        []byte
+}
+
+// Send queues a message without blocking.
+func (c *Conn) Send(messageType int, data []byte) error {
+	select {
+	case <-c.closing:
+		return ErrClosed
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.send <- outgoing{messageType: messageType, data: data}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close sends a close frame with the code and reason after the queued messages, then closes the connection once the
+// client acknowledges it.
+func (c *Conn) Close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.closing)
+	})
+}
+
+// Done returns a channel closed when the connection is closed.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Conn) readPump() {
+	config := c.manager.config
+
+	defer func() {
+		_ = c.ws.Close()
+		close(c.done)
+		c.manager.remove(c)
+	}()
+
+	c.ws.SetReadLimit(config.MaxMessageSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	})
+
+	for {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+
+		if c.manager.handler != nil {
+			c.manager.handler(c, messageType, data)
+		}
+	}
+}
+
+func (c *Conn) writePump() {
+	config := c.manager.config
+	ticker := time.NewTicker(config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				_ = c.ws.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.WriteTimeout)); err != nil {
+				_ = c.ws.Close()
+				return
+			}
+		case <-c.closing:
+			c.flush()
+			deadline := time.Now().Add(config.WriteTimeout)
+			_ = c.ws.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeReason), deadline)
+			// the read pump ends when the client echoes the close frame, or at the deadline
+			_ = c.ws.SetReadDeadline(deadline)
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// flush writes the queued messages before the close frame.
+func (c *Conn) flush() {
+	for {
+		select {
+		case msg := <-c.send:
+			_ = c.ws.SetWriteDeadline(time.Now().Add(c.manager.config.WriteTimeout))
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package ws_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/demosdemon/golang-app-framework/app"
+	"github.com/demosdemon/golang-app-framework/ws"
+)
+
+func echo(c *ws.Conn, messageType int, data []byte) {
+	_ = c.Send(messageType, append([]byte("echo "), data...))
+}
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	return c
+}
+
+func read(t *testing.T, c *websocket.Conn) string {
+	require.NoError(t, c.SetReadDeadline(time.Now().Add(time.Second)))
+	_, data, err := c.ReadMessage()
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestManager(t *testing.T) {
+	m := ws.New(ws.DefaultConfig(), echo, nil)
+	require.NoError(t, m.Bind(&app.App{Stderr: io.Discard}))
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	served := make(chan error, 1)
+	go func() { served <- m.Serve() }()
+
+	a, b := dial(t, srv), dial(t, srv)
+	defer a.Close()
+	defer b.Close()
+
+	require.NoError(t, a.WriteMessage(websocket.TextMessage, []byte("hello")))
+	assert.Equal(t, "echo hello", read(t, a))
+	assert.Len(t, m.Conns(), 2)
+
+	m.Broadcast(ws.TextMessage, []byte("news"))
+	assert.Equal(t, "news", read(t, a))
+	assert.Equal(t, "news", read(t, b))
+
+	closed := make(chan error, 2)
+	for _, c := range []*websocket.Conn{a, b} {
+		go func(c *websocket.Conn) {
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			_, _, err := c.ReadMessage()
+			closed <- err
+		}(c)
+	}
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	assert.NoError(t, <-served)
+
+	for i := 0; i < 2; i++ {
+		err := <-closed
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+	}
+	assert.Empty(t, m.Conns())
+
+	_, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.Error(t, err)
+	assert.Equal(t, 503, res.StatusCode)
+}
+
+func TestManager_zeroConfig(t *testing.T) {
+	// the zero Config stands for the defaults
+	m := ws.New(&ws.Config{}, echo, nil)
+	require.NoError(t, m.Bind(&app.App{Stderr: io.Discard}))
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	c := dial(t, srv)
+	defer c.Close()
+	require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte("hello")))
+	assert.Equal(t, "echo hello", read(t, c))
+	require.NoError(t, m.Shutdown(context.Background()))
+}
+
+func TestManager_Liveness(t *testing.T) {
+	config := ws.DefaultConfig()
+	config.PingInterval = 10 * time.Millisecond
+	config.PongTimeout = 50 * time.Millisecond
+	m := ws.New(config, nil, nil)
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	// a client that never reads never answers the pings
+	c := dial(t, srv)
+	defer c.Close()
+
+	assert.Eventually(t, func() bool { return len(m.Conns()) == 1 }, time.Second, time.Millisecond)
+	conn := m.Conns()[0]
+
+	select {
+	case <-conn.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection was not closed")
+	}
+	assert.Equal(t, ws.ErrClosed, conn.Send(ws.TextMessage, []byte("late")))
+	assert.Empty(t, m.Conns())
+}
+
+func TestManager_SlowClient(t *testing.T) {
+	config := ws.DefaultConfig()
+	config.SendQueue = 1
+	m := ws.New(config, nil, nil)
+
+	srv := httptest.NewServer(m)
+	defer srv.Close()
+
+	c := dial(t, srv)
+	defer c.Close()
+	assert.Eventually(t, func() bool { return len(m.Conns()) == 1 }, time.Second, time.Millisecond)
+	conn := m.Conns()[0]
+
+	// a large message fills the socket buffers so the write pump blocks behind it
+	big := make([]byte, 8<<20)
+	for i := 0; i < 4; i++ {
+		m.Broadcast(ws.BinaryMessage, big)
+	}
+
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow connection was not closed")
+	}
+}